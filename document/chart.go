@@ -0,0 +1,67 @@
+package document
+
+// ChartType identifies how a chart series is drawn.
+type ChartType string
+
+const (
+	// ChartColumn draws vertical bars.
+	ChartColumn ChartType = "column"
+	// ChartBar draws horizontal bars.
+	ChartBar ChartType = "bar"
+	// ChartLine draws lines through the data points.
+	ChartLine ChartType = "line"
+	// ChartArea draws filled areas below the data points.
+	ChartArea ChartType = "area"
+	// ChartPie draws the first series as pie slices.
+	ChartPie ChartType = "pie"
+	// ChartScatter plots X/Y value pairs as markers.
+	ChartScatter ChartType = "scatter"
+)
+
+// ChartGrouping controls how bar, column, line and area series are combined.
+type ChartGrouping string
+
+const (
+	// GroupingClustered draws series side by side. It is the default.
+	GroupingClustered ChartGrouping = "clustered"
+	// GroupingStacked stacks series on top of each other.
+	GroupingStacked ChartGrouping = "stacked"
+	// GroupingPercentStacked stacks series scaled to 100%.
+	GroupingPercentStacked ChartGrouping = "percentStacked"
+)
+
+// ChartSeries is a data series read from worksheet ranges.
+// Ranges without a sheet name refer to the sheet the chart is added to.
+type ChartSeries struct {
+	Name       string    // Series name, or a cell reference such as "Sheet1!$B$1"
+	Categories string    // Category labels, or X values for scatter charts, e.g. "A2:A10"
+	Values     string    // Values, e.g. "B2:B10"
+	Type       ChartType // Overrides the chart type to build combo charts
+	Secondary  bool      // Plot against the secondary value axis
+	Color      string    // Hexadecimal fill or line color
+	Smooth     bool      // Smooth lines for line and scatter series
+}
+
+// ChartAxis configures a chart axis.
+type ChartAxis struct {
+	Title          string
+	NumberFormat   string   // e.g., "0%", "#,##0"
+	Min            *float64 // Fixed minimum; nil scales automatically
+	Max            *float64 // Fixed maximum; nil scales automatically
+	MajorUnit      float64  // Distance between major ticks; 0 scales automatically
+	MajorGridlines bool
+}
+
+// ChartSpec describes a chart placed over a range of cells.
+type ChartSpec struct {
+	Type           ChartType
+	Title          string
+	Range          string // Cells covered by the chart, e.g. "E2:L18"
+	Series         []ChartSeries
+	Grouping       ChartGrouping
+	Legend         string    // "right" (default), "left", "top", "bottom" or "none"
+	DataLabels     bool      // Show the value of every data point
+	XAxis          ChartAxis // Category axis, or the X value axis of scatter charts
+	YAxis          ChartAxis
+	SecondaryYAxis ChartAxis // Used by series with Secondary set
+}
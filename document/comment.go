@@ -0,0 +1,12 @@
+package document
+
+// Comment is a note attached to a spreadsheet cell.
+type Comment struct {
+	Cell    string     // Cell reference, e.g. "B2"; filled in when comments are read
+	Author  string     // Defaults to the document author when empty
+	Text    string     // Plain text, used when Runs is empty
+	Runs    []TextSpan // Rich text content
+	Visible bool       // Always show the note instead of only on hover
+	Width   float64    // Box width in points; zero uses the default size
+	Height  float64    // Box height in points; zero uses the default size
+}
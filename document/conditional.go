@@ -0,0 +1,123 @@
+package document
+
+// ConditionalType identifies the kind of a conditional formatting rule.
+type ConditionalType string
+
+const (
+	// ConditionCellIs compares the cell value against Formula using Operator.
+	ConditionCellIs ConditionalType = "cellIs"
+	// ConditionExpression applies the format when Formula evaluates to TRUE.
+	// Relative references are relative to the top-left cell of the range.
+	ConditionExpression ConditionalType = "expression"
+	// ConditionColorScale shades cells along a gradient between Values.
+	ConditionColorScale ConditionalType = "colorScale"
+	// ConditionDataBar draws a bar proportional to the cell value.
+	ConditionDataBar ConditionalType = "dataBar"
+	// ConditionIconSet shows an icon chosen by the thresholds in Values.
+	ConditionIconSet ConditionalType = "iconSet"
+	// ConditionTop formats the Rank highest, or lowest with Bottom, values.
+	ConditionTop ConditionalType = "top10"
+	// ConditionAverage formats values above, or below with BelowAverage, the average.
+	ConditionAverage ConditionalType = "aboveAverage"
+	// ConditionDuplicate formats values that occur more than once in the range.
+	ConditionDuplicate ConditionalType = "duplicateValues"
+	// ConditionUnique formats values that occur once in the range.
+	ConditionUnique ConditionalType = "uniqueValues"
+	// ConditionContainsText formats cells containing Text.
+	ConditionContainsText ConditionalType = "containsText"
+	// ConditionNotContainsText formats cells not containing Text.
+	ConditionNotContainsText ConditionalType = "notContainsText"
+	// ConditionBeginsWith formats cells starting with Text.
+	ConditionBeginsWith ConditionalType = "beginsWith"
+	// ConditionEndsWith formats cells ending with Text.
+	ConditionEndsWith ConditionalType = "endsWith"
+	// ConditionBlanks formats empty cells.
+	ConditionBlanks ConditionalType = "containsBlanks"
+	// ConditionNoBlanks formats non-empty cells.
+	ConditionNoBlanks ConditionalType = "notContainsBlanks"
+	// ConditionErrors formats cells holding an error value.
+	ConditionErrors ConditionalType = "containsErrors"
+	// ConditionNoErrors formats cells not holding an error value.
+	ConditionNoErrors ConditionalType = "notContainsErrors"
+	// ConditionTimePeriod formats dates falling in TimePeriod.
+	ConditionTimePeriod ConditionalType = "timePeriod"
+)
+
+// ConditionalOperator compares a cell value in a ConditionCellIs rule.
+type ConditionalOperator string
+
+const (
+	OperatorBetween            ConditionalOperator = "between"
+	OperatorNotBetween         ConditionalOperator = "notBetween"
+	OperatorEqual              ConditionalOperator = "equal"
+	OperatorNotEqual           ConditionalOperator = "notEqual"
+	OperatorGreaterThan        ConditionalOperator = "greaterThan"
+	OperatorLessThan           ConditionalOperator = "lessThan"
+	OperatorGreaterThanOrEqual ConditionalOperator = "greaterThanOrEqual"
+	OperatorLessThanOrEqual    ConditionalOperator = "lessThanOrEqual"
+)
+
+// TimePeriod is a date range matched by a ConditionTimePeriod rule.
+type TimePeriod string
+
+const (
+	PeriodToday     TimePeriod = "today"
+	PeriodYesterday TimePeriod = "yesterday"
+	PeriodTomorrow  TimePeriod = "tomorrow"
+	PeriodLast7Days TimePeriod = "last7Days"
+	PeriodThisWeek  TimePeriod = "thisWeek"
+	PeriodLastWeek  TimePeriod = "lastWeek"
+	PeriodNextWeek  TimePeriod = "nextWeek"
+	PeriodThisMonth TimePeriod = "thisMonth"
+	PeriodLastMonth TimePeriod = "lastMonth"
+	PeriodNextMonth TimePeriod = "nextMonth"
+)
+
+// ConditionalValueType tells how a ConditionalValue threshold is interpreted.
+type ConditionalValueType string
+
+const (
+	ValueMin        ConditionalValueType = "min"
+	ValueMax        ConditionalValueType = "max"
+	ValueNumber     ConditionalValueType = "num"
+	ValuePercent    ConditionalValueType = "percent"
+	ValuePercentile ConditionalValueType = "percentile"
+	ValueFormula    ConditionalValueType = "formula"
+)
+
+// ConditionalValue is a threshold of a color scale, data bar or icon set.
+type ConditionalValue struct {
+	Type  ConditionalValueType
+	Value string // Number, percent or formula; unused for ValueMin and ValueMax
+	Color string // Hexadecimal color of a color scale stop
+}
+
+// ConditionalRule is a conditional formatting rule. Only the fields used by its
+// Type are read.
+type ConditionalRule struct {
+	Type     ConditionalType
+	Operator ConditionalOperator // ConditionCellIs
+	Formula  []string            // Operands of ConditionCellIs (two for between), or the expression
+	Text     string              // Text rules
+	Period   TimePeriod          // ConditionTimePeriod
+
+	Rank         int  // ConditionTop; defaults to 10
+	Percent      bool // ConditionTop ranks by percentage
+	Bottom       bool // ConditionTop formats the lowest values
+	BelowAverage bool // ConditionAverage
+	EqualAverage bool // ConditionAverage also matches the average itself
+	StdDev       int  // ConditionAverage compares against this many standard deviations
+
+	// Values are the thresholds of a color scale (two or three), data bar (two)
+	// or icon set (one per icon). They default to the range minimum and maximum,
+	// or evenly spaced percentages for icon sets.
+	Values    []ConditionalValue
+	BarColor  string // ConditionDataBar; defaults to "638EC6"
+	IconStyle string // ConditionIconSet, e.g. "3Arrows", "5Rating"; defaults to "3TrafficLights1"
+	Reverse   bool   // ConditionIconSet shows the icons in reverse order
+	HideValue bool   // Show only the bar or icon
+
+	Style      CellStyle // Format applied to matching cells
+	Priority   int       // 1 is evaluated first; 0 appends after the sheet's existing rules
+	StopIfTrue bool      // Skip lower priority rules when this one matches
+}
@@ -0,0 +1,45 @@
+package document
+
+// CSVEncoding is the character encoding of a CSV stream.
+type CSVEncoding string
+
+const (
+	// CSVEncodingUTF8 is plain UTF-8. A leading byte order mark is skipped on import.
+	CSVEncodingUTF8 CSVEncoding = ""
+	// CSVEncodingUTF8BOM is UTF-8 starting with a byte order mark, as expected by Excel.
+	CSVEncodingUTF8BOM CSVEncoding = "utf-8-bom"
+	// CSVEncodingWindows1252 is the Western European code page. Characters it
+	// cannot represent are exported as "?".
+	CSVEncodingWindows1252 CSVEncoding = "windows-1252"
+)
+
+// CSVQuoting selects which exported fields are enclosed in quotes.
+type CSVQuoting int
+
+const (
+	// CSVQuoteMinimal quotes fields holding the delimiter, quotes, line breaks or
+	// leading spaces. It is the default.
+	CSVQuoteMinimal CSVQuoting = iota
+	// CSVQuoteAll quotes every field.
+	CSVQuoteAll
+	// CSVQuoteNonNumeric quotes every field that is not a number.
+	CSVQuoteNonNumeric
+)
+
+// CSVOptions configures Sheet.ImportCSV and Sheet.ExportCSV.
+type CSVOptions struct {
+	Delimiter rune        // Field separator; defaults to ','. Use '\t' for TSV
+	Encoding  CSVEncoding // Character encoding of the CSV stream
+
+	// Import
+	Cell        string     // Top-left cell of the imported data; defaults to "A1"
+	LazyQuotes  bool       // Accept quotes inside unquoted fields and unescaped quotes inside quoted ones
+	InferTypes  bool       // Store numbers, dates and booleans as typed values instead of text
+	DateLayouts []string   // Go time layouts tried when inferring dates; defaults to ISO 8601 dates and times
+	HeaderStyle *CellStyle // Style applied to the first imported row
+
+	// Export
+	Quoting CSVQuoting // Which fields are quoted
+	Raw     bool       // Write stored values instead of values formatted with their number format
+	UseCRLF bool       // End lines with \r\n instead of \n
+}
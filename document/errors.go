@@ -7,6 +7,7 @@ var (
 	ErrUnsupportedFormat = errors.New("unsupported document format")
 	ErrInvalidFormat     = errors.New("invalid document format")
 	ErrEncryptedDocument = errors.New("encrypted document not supported")
+	ErrInvalidPassword   = errors.New("invalid password")
 	ErrSheetNotFound     = errors.New("sheet not found")
 	ErrDocumentNotLoaded = errors.New("document not loaded")
 	ErrSaveFailed        = errors.New("save failed")
@@ -0,0 +1,16 @@
+package document
+
+// PDFOptions configures Spreadsheet.ExportPDF.
+type PDFOptions struct {
+	Sheets          []string // Sheets to export in order; defaults to every visible sheet
+	IgnorePrintArea bool     // Lay out the used range of sheets that define a print area
+}
+
+// HTMLOptions configures Spreadsheet.ExportHTML.
+type HTMLOptions struct {
+	Sheets       []string // Sheets to export in order; defaults to every visible sheet
+	ShowHidden   bool     // Include hidden rows and columns
+	MaxRows      int      // Rows written per sheet, leaving out the pictures below them; 0 writes every row
+	InlineStyles bool     // Style elements with style attributes instead of classes in a <style> element
+	Fragment     bool     // Write only the styles and tables, to embed in a page, instead of a whole document
+}
@@ -0,0 +1,10 @@
+package document
+
+// Hyperlink is a link from a cell to a URL or to a location in the workbook.
+type Hyperlink struct {
+	Ref      string // Cell or range holding the link, e.g. "A1"
+	URL      string // External target; empty for links within the workbook
+	Location string // Target within the workbook, e.g. "Sheet2!A1"
+	Display  string // Text shown for the link, when stored
+	Tooltip  string // Text shown when hovering over the link
+}
@@ -0,0 +1,13 @@
+package document
+
+// Image is a picture placed on a sheet, as listed by Spreadsheet.Images.
+type Image struct {
+	Name     string  // Name of the picture in the drawing; identifies it to DeleteImage and ReplaceImage
+	Sheet    string  // Sheet the picture is placed on
+	Cell     string  // Cell holding the top-left corner of the picture, e.g. "B2"
+	Width    float64 // Displayed width in points
+	Height   float64 // Displayed height in points
+	MIMEType string  // e.g. "image/png"
+	Path     string  // Part of the package holding the image, e.g. "xl/media/image1.png"
+	Data     []byte
+}
@@ -0,0 +1,43 @@
+package document
+
+// PivotAggregation is the function used to summarize a pivot table value field.
+type PivotAggregation string
+
+const (
+	AggregateSum       PivotAggregation = "sum" // The default
+	AggregateCount     PivotAggregation = "count"
+	AggregateAverage   PivotAggregation = "average"
+	AggregateMax       PivotAggregation = "max"
+	AggregateMin       PivotAggregation = "min"
+	AggregateProduct   PivotAggregation = "product"
+	AggregateCountNums PivotAggregation = "countNums"
+	AggregateStdDev    PivotAggregation = "stdDev"
+	AggregateStdDevP   PivotAggregation = "stdDevp"
+	AggregateVar       PivotAggregation = "var"
+	AggregateVarP      PivotAggregation = "varp"
+)
+
+// PivotValue is a summarized field in the data area of a pivot table.
+type PivotValue struct {
+	Field        string // Header of the source column
+	Aggregation  PivotAggregation
+	Name         string // Caption; defaults to e.g. "Sum of Amount"
+	NumberFormat string // e.g., "#,##0.00"
+}
+
+// PivotFilter is a report filter (page field) shown above a pivot table.
+type PivotFilter struct {
+	Field string
+	Items []string // Items to include; empty includes all
+}
+
+// PivotSpec describes the layout of a pivot table. Fields are named by the
+// headers in the first row of the source range.
+type PivotSpec struct {
+	Name    string // Defaults to "PivotTable1", "PivotTable2", ...
+	Rows    []string
+	Columns []string
+	Values  []PivotValue
+	Filters []PivotFilter
+	Style   string // Defaults to "PivotStyleLight16"
+}
@@ -0,0 +1,76 @@
+package document
+
+// ClearMode selects what Range.Clear removes from its cells.
+type ClearMode int
+
+const (
+	// ClearAll removes the cells entirely: values, formulas and styles.
+	ClearAll ClearMode = iota
+	// ClearContents removes values and formulas and keeps the cells' styles.
+	ClearContents
+	// ClearFormats resets the cells' styles and keeps their values and formulas.
+	ClearFormats
+)
+
+// FillDirection selects how Range.Fill extends the first cells of a range.
+type FillDirection int
+
+const (
+	// FillDown copies the first row of the range into the rows below it.
+	FillDown FillDirection = iota
+	// FillRight copies the first column of the range into the columns to its right.
+	FillRight
+	// FillSeries continues a linear series down each column of the range, or
+	// along each row when the range is a single row. The step is the difference
+	// between the first two values, or 1 when only the first cell holds a number.
+	FillSeries
+)
+
+// SortKey is a column that Range.Sort orders rows by.
+type SortKey struct {
+	Column     string // Column letters, e.g. "B", within the sorted range
+	Descending bool
+}
+
+// Range is a fluent handle bound to a rectangular block of cells, e.g. "A1:F200".
+// Its operations visit the block in a single pass over the sheet's rows.
+type Range interface {
+	// Ref returns the normalized reference of the range, e.g. "A1:F200".
+	Ref() string
+
+	// SetValues writes rows of values starting at the top-left cell of the range.
+	// Values outside the range are rejected; nil leaves a cell unchanged.
+	SetValues(values [][]any) Range
+
+	// Values returns the values of every cell of the range by row, with "" for empty cells.
+	Values() ([][]string, error)
+
+	// Style applies a style to every cell of the range.
+	Style(style CellStyle) Range
+
+	// Clear removes the values, styles or both of the cells of the range.
+	Clear(mode ClearMode) Range
+
+	// CopyTo copies the values, formulas and styles of the range to the block
+	// whose top-left cell is target. Relative references in copied formulas are
+	// moved as Excel moves them when pasting.
+	CopyTo(target string) Range
+
+	// MoveTo moves the cells of the range to the block whose top-left cell is
+	// target. Formulas, charts, merges, conditional formats and data validations
+	// that refer to the range follow it. The handle is bound to the new position
+	// afterwards.
+	MoveTo(target string) Range
+
+	// Fill extends the first row, first column or series of the range over it.
+	Fill(direction FillDirection) Range
+
+	// Sort orders the rows of the range by the given columns. Numbers come
+	// before text and booleans, and empty cells come last in either order.
+	Sort(keys ...SortKey) Range
+
+	// AutoFitColumns sets the width of the range's columns to fit their content.
+	AutoFitColumns() Range
+
+	Err() error
+}
@@ -0,0 +1,16 @@
+package document
+
+// RowView is a read-only snapshot of a worksheet row produced by Sheet.Rows.
+type RowView struct {
+	Index int // 1-based row number
+	Cells []CellView
+}
+
+// CellView is a read-only snapshot of a cell with shared strings and styles resolved.
+type CellView struct {
+	Axis         string // e.g. "B3"
+	Value        string // Resolved text or raw stored value
+	Formula      string // Formula without the leading '=', if any
+	StyleID      int    // Index into the workbook's cell formats
+	NumberFormat string // Number format code applied by the cell style
+}
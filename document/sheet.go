@@ -1,27 +1,141 @@
 package document
 
+import (
+	"context"
+	"io"
+	"iter"
+)
+
 // Sheet is a fluent handle bound to a specific worksheet.
 // It provides sheet-scoped operations without repeatedly passing the sheet name or context.
 type Sheet interface {
 	Cell(axis string) Cell
+
+	// Range returns a handle to a block of cells such as "A1:F200" for bulk operations.
+	Range(ref string) Range
 	MergeCells(hRange string) Sheet
 	SetColumnWidth(col int, width float64) Sheet
 	SetRowHeight(row int, height float64) Sheet
+
+	// AutoFitColumns sets the width of 1-based columns to fit the widest value
+	// displayed in them, measured with the cells' fonts. With no columns given,
+	// every column holding a value is sized. Merged cells are not measured.
+	AutoFitColumns(cols ...int) Sheet
+
+	// AutoFitRows sets the height of rows to fit their tallest value, wrapping the
+	// text of cells that wrap it to the column width. With no rows given, every
+	// row holding a value is sized.
+	AutoFitRows(rows ...int) Sheet
 	AutoFilter(ref string) Sheet
 	FreezePanes(col, row int) Sheet
 	InsertImage(path string, x, y float64) Sheet
+
+	// InsertRows inserts count empty rows before row. Cells below move down and
+	// every reference to them in the workbook is updated.
+	InsertRows(row, count int) Sheet
+
+	// DeleteRows removes count rows starting at row and moves the cells below up.
+	// References to deleted cells become #REF!.
+	DeleteRows(row, count int) Sheet
+
+	// InsertCols inserts count empty columns before col (1-based), moving cells right.
+	InsertCols(col, count int) Sheet
+
+	// DeleteCols removes count columns starting at col (1-based), moving cells left.
+	DeleteCols(col, count int) Sheet
+
+	// AddChart draws a native chart whose series reference cell ranges.
+	AddChart(spec ChartSpec) Sheet
+
+	// AddPivotTable summarizes sourceRef, a range whose first row holds the field
+	// names, in a pivot table placed at targetCell. sourceRef may name another sheet.
+	AddPivotTable(sourceRef, targetCell string, spec PivotSpec) Sheet
+
+	// SetDataValidation restricts ref to a dropdown list of options. It returns
+	// an error for an empty list or items containing commas, which Excel would
+	// split into several entries; list such items in cells and use
+	// AddDataValidation with a Source range instead.
 	SetDataValidation(ref string, options ...string) Sheet
+
+	// AddDataValidation restricts the values entered in ref, which may list
+	// several ranges separated by spaces.
+	AddDataValidation(ref string, validation DataValidation) Sheet
+
 	SetConditionalFormatting(ref string, style CellStyle) Sheet
+
+	// AddConditionalFormat applies rules to ref, which may list several ranges
+	// separated by spaces. Rules are evaluated in priority order.
+	AddConditionalFormat(ref string, rules ...ConditionalRule) Sheet
+
+	// AddSparklines draws line, column or win/loss sparklines in cells.
+	AddSparklines(group SparklineGroup) Sheet
+
 	SetPageSettings(settings PageSettings) Sheet
+
+	// SetPrintOptions sets how the sheet is scaled, centred and decorated when printed.
+	SetPrintOptions(opts PrintOptions) Sheet
+
+	// InsertRowBreak starts a new printed page at a 1-based row.
+	InsertRowBreak(row int) Sheet
+
+	// InsertColBreak starts a new printed page at a 1-based column.
+	InsertColBreak(col int) Sheet
+
+	// SetView sets the zoom, gridlines, headings, direction and tab color the
+	// sheet is displayed with.
+	SetView(view SheetView) Sheet
+
+	// View returns the display settings of the sheet, as set by SetView.
+	View() (SheetView, error)
+
+	// SplitPanes splits the window after col columns and row rows into panes
+	// that scroll separately. Unlike frozen panes, the split can be dragged.
+	SplitPanes(col, row int) Sheet
+
 	Protect(password string) Sheet
 	GroupRows(start, end int, level int) Sheet
 	GroupCols(start, end int, level int) Sheet
 	SetHeader(text string) Sheet
 	SetFooter(text string) Sheet
 	AddTable(ref string, name string) Sheet
+
+	// Table returns a handle to the table of the sheet with the given name,
+	// compared case-insensitively.
+	Table(name string) SheetTable
 	SetPrintArea(ref string) Sheet
 	SetPrintTitles(rowRef, colRef string) Sheet
 	GetCellValue(axis string) (string, error)
+
+	// MergedCells returns the merged ranges of the sheet, e.g. "A1:C1".
+	MergedCells() ([]string, error)
+
+	// ColumnWidth returns the width of a 1-based column in characters, or 0 when
+	// the column has the default width.
+	ColumnWidth(col int) (float64, error)
+
+	// RowHeight returns the height of a 1-based row in points, or 0 when the row
+	// has the default height.
+	RowHeight(row int) (float64, error)
+
+	// FrozenPanes returns the number of frozen columns and rows, as set by FreezePanes.
+	FrozenPanes() (col, row int, err error)
+
+	// Hyperlinks returns the sheet's hyperlinks in document order.
+	Hyperlinks() ([]Hyperlink, error)
+
+	// Comments returns the sheet's cell comments ordered by row and column.
+	Comments() ([]Comment, error)
+
+	// Rows iterates over the sheet's rows in order, decoding them from the source
+	// package one at a time when the sheet has not been loaded into memory.
+	Rows(ctx context.Context) iter.Seq2[RowView, error]
+
+	// ImportCSV writes the records of a CSV stream to the sheet, one row per record.
+	ImportCSV(r io.Reader, opts CSVOptions) error
+
+	// ExportCSV writes the sheet's rows to w as CSV, starting at cell A1. Rows end
+	// at their last non-empty cell.
+	ExportCSV(w io.Writer, opts CSVOptions) error
 	Err() error
 }
 
@@ -31,7 +145,28 @@ type Cell interface {
 	Formula(formula string) Cell
 	Hyperlink(url string) Cell
 	Style(style CellStyle) Cell
+
+	// GetStyle returns the cell's formatting: font, fill, borders, alignment and
+	// number format. Colors set through the workbook theme are not resolved.
+	GetStyle() (CellStyle, error)
 	Comment(text string) Cell
+
+	// SetComment adds or replaces the cell's comment.
+	SetComment(comment Comment) Cell
+
+	// GetComment returns the cell's comment, or nil when it has none.
+	GetComment() (*Comment, error)
+	RemoveComment() Cell
+
+	// Get returns the cell's stored value. Reading a formula cell after values
+	// or formulas have changed first recalculates the whole workbook, loading
+	// every sheet and evaluating every formula, so batch writes before reading
+	// results back. Values of cells without formulas are read directly.
 	Get() (string, error)
+
+	// Formatted returns the cell's value as Excel displays it, rendered with the
+	// cell's number format, e.g. "2024-03-15" or "$1,234.50" instead of the stored
+	// "45366" or "1234.5".
+	Formatted() (string, error)
 	Err() error
 }
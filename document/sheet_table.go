@@ -0,0 +1,86 @@
+package document
+
+// TotalsFunction is the function a table's totals row applies to a column.
+type TotalsFunction string
+
+const (
+	TotalsNone      TotalsFunction = ""
+	TotalsSum       TotalsFunction = "sum"
+	TotalsAverage   TotalsFunction = "average"
+	TotalsCount     TotalsFunction = "count" // Counts non-empty cells
+	TotalsCountNums TotalsFunction = "countNums"
+	TotalsMax       TotalsFunction = "max"
+	TotalsMin       TotalsFunction = "min"
+	TotalsStdDev    TotalsFunction = "stdDev"
+	TotalsVar       TotalsFunction = "var"
+)
+
+// TableTotal is the totals row cell of a table column: a function, or a label
+// when Function is TotalsNone.
+type TableTotal struct {
+	Column   string // Header of the column
+	Function TotalsFunction
+	Label    string // e.g. "Total"
+}
+
+// TableStyle selects the look of a table.
+type TableStyle struct {
+	Name          string // e.g. "TableStyleMedium2", "TableStyleLight9"; "" shows no style
+	FirstColumn   bool   // Emphasize the first column
+	LastColumn    bool   // Emphasize the last column
+	RowStripes    bool   // Band alternate rows
+	ColumnStripes bool   // Band alternate columns
+}
+
+// TableInfo describes a table (list object) of a workbook.
+type TableInfo struct {
+	Name      string
+	Sheet     string
+	Ref       string   // Area including the header and totals rows, e.g. "A1:D20"
+	Columns   []string // Column headers in order
+	Style     TableStyle
+	HeaderRow bool
+	TotalsRow bool
+}
+
+// SheetTable is a fluent handle bound to a table (list object) of a sheet, as
+// created by Sheet.AddTable or read from a file.
+type SheetTable interface {
+	// Name returns the name the handle is bound to.
+	Name() string
+
+	// SetStyle sets the table style and its banding options.
+	SetStyle(style TableStyle) SheetTable
+
+	// SetTotalsRow shows a totals row below the data with a function or label
+	// for the listed columns; other columns are left empty. The row below the
+	// data must be empty when the table has no totals row yet. Without totals,
+	// the totals row is removed.
+	SetTotalsRow(totals ...TableTotal) SheetTable
+
+	// SetCalculatedColumn fills a column with a formula for every data row, e.g.
+	// "[@Qty]*[@Price]". A header that is not in the table adds a column on its
+	// right. Rows added by AppendRows get the formula too.
+	SetCalculatedColumn(header, formula string) SheetTable
+
+	// Resize sets the area of the table, e.g. "A1:F50", including its header and
+	// totals rows. The top-left cell cannot change. Headers of added columns are
+	// read from the header row, or named "Column<n>" when it is empty.
+	Resize(ref string) SheetTable
+
+	// AppendRows writes rows of values below the data of the table and extends
+	// the table over them, moving its totals row down. Values are written from
+	// the first column; nil leaves a cell empty. Calculated columns are filled in.
+	AppendRows(values [][]any) SheetTable
+
+	// Info returns the description of the table.
+	Info() (TableInfo, error)
+
+	// Records returns the data rows of the table keyed by column header.
+	Records() ([]map[string]string, error)
+
+	// Column returns the data values of a column named by its header.
+	Column(header string) ([]string, error)
+
+	Err() error
+}
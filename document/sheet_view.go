@@ -0,0 +1,22 @@
+package document
+
+// SheetView controls how a sheet is displayed when the workbook is opened.
+type SheetView struct {
+	Zoom          int // Percent from 10 to 400; 0 shows the sheet at 100%
+	HideGridlines bool
+	HideHeadings  bool   // Hide the row numbers and column letters
+	RightToLeft   bool   // Lay the sheet out from right to left
+	TabColor      string // RGB hex color of the sheet tab, e.g. "FF0000"; "" for none
+}
+
+// PrintOptions controls how a sheet is scaled and laid out when printed.
+type PrintOptions struct {
+	FitToWidth         int  // Shrink the sheet to this many pages wide; 0 leaves the width free
+	FitToHeight        int  // Shrink the sheet to this many pages tall; 0 leaves the height free
+	Scale              int  // Percent from 10 to 400 when not fitting to pages; 0 prints at 100%
+	Gridlines          bool // Print the gridlines
+	Headings           bool // Print the row numbers and column letters
+	CenterHorizontally bool
+	CenterVertically   bool
+	Quality            int // Print resolution in dots per inch, e.g. 600; 0 uses the printer default
+}
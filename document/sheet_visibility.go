@@ -0,0 +1,13 @@
+package document
+
+// SheetVisibility controls whether a sheet tab is shown in the workbook.
+type SheetVisibility string
+
+const (
+	// SheetVisible shows the sheet tab.
+	SheetVisible SheetVisibility = ""
+	// SheetHidden hides the sheet tab; users can unhide it from Excel.
+	SheetHidden SheetVisibility = "hidden"
+	// SheetVeryHidden hides the sheet tab so that it can only be shown again programmatically.
+	SheetVeryHidden SheetVisibility = "veryHidden"
+)
@@ -0,0 +1,56 @@
+package document
+
+// SparklineType is the kind of chart a sparkline draws.
+type SparklineType string
+
+const (
+	SparklineLine    SparklineType = "line"
+	SparklineColumn  SparklineType = "column"
+	SparklineWinLoss SparklineType = "stacked" // Equal bars above or below the axis by sign
+)
+
+// SparklineAxis tells how the minimum or maximum of a sparkline's vertical axis is chosen.
+type SparklineAxis string
+
+const (
+	AxisIndividual SparklineAxis = "individual" // Each sparkline scales to its own values
+	AxisGroup      SparklineAxis = "group"      // All sparklines of the group share one scale
+	AxisCustom     SparklineAxis = "custom"     // The axis is fixed at MinValue or MaxValue
+)
+
+// SparklineGroup draws small charts inside cells. Location is a single cell or
+// a row or column of cells such as "F2:F10"; Data is the range plotted and may
+// name another sheet. With several cells, each one plots the row, or column, of
+// Data at the same position.
+type SparklineGroup struct {
+	Type     SparklineType // Defaults to SparklineLine
+	Location string
+	Data     string
+
+	Markers  bool // Mark every point of a line
+	High     bool // Highlight the highest point
+	Low      bool // Highlight the lowest point
+	First    bool // Highlight the first point
+	Last     bool // Highlight the last point
+	Negative bool // Highlight negative points
+
+	ShowAxis    bool          // Draw the horizontal axis at zero
+	MinAxis     SparklineAxis // Defaults to AxisIndividual
+	MaxAxis     SparklineAxis // Defaults to AxisIndividual
+	MinValue    float64       // Minimum for AxisCustom
+	MaxValue    float64       // Maximum for AxisCustom
+	EmptyCells  string        // "gap" (default), "zero" or "span" to connect the line across them
+	RightToLeft bool
+
+	LineWeight float64 // Points; defaults to 0.75
+
+	// Colors are RGB hex such as "376092"; empty ones use Excel's default style.
+	Color         string
+	NegativeColor string
+	AxisColor     string
+	MarkersColor  string
+	HighColor     string
+	LowColor      string
+	FirstColor    string
+	LastColor     string
+}
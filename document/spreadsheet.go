@@ -1,5 +1,7 @@
 package document
 
+import "io"
+
 // Spreadsheet defines operations specific to spreadsheet documents (Excel).
 type Spreadsheet interface {
 	Document
@@ -9,4 +11,51 @@ type Spreadsheet interface {
 	// Additional spreadsheet-level ops
 	GetSheets() ([]string, error)
 	SetNamedRange(name, ref string) error
+	// ProtectWorkbook locks the workbook structure; SetPassword encrypts the file instead.
+	ProtectWorkbook(password string) error
+
+	// Sheet management. Formulas, defined names, charts and pivot caches that refer
+	// to a renamed or deleted sheet are updated; handles of a renamed sheet must be re-obtained.
+	RenameSheet(oldName, newName string) error
+	// DeleteSheet removes a sheet and the parts it owns. References to it become #REF!.
+	DeleteSheet(name string) error
+	// CopySheet appends a copy of a sheet with its cells, comments, drawings and tables.
+	// Copied tables are renamed; pivot tables are not copied.
+	CopySheet(source, target string) error
+	// MoveSheet moves a sheet to the zero-based tab position index.
+	MoveSheet(name string, index int) error
+	// HideSheet sets the visibility of a sheet; SheetVisible shows it again.
+	HideSheet(name string, visibility SheetVisibility) error
+	// SetActiveSheet selects the sheet shown when the workbook is opened.
+	SetActiveSheet(name string) error
+
+	// Tables lists the tables of every sheet in sheet order.
+	Tables() ([]TableInfo, error)
+
+	// Images lists the pictures of every sheet in sheet order, with their bytes.
+	Images() ([]Image, error)
+	// DeleteImage removes the picture name from a sheet, and its image once nothing else shows it.
+	DeleteImage(sheet, name string) error
+	// ReplaceImage shows data, a PNG, JPEG, GIF or BMP image, in place of the picture
+	// name of a sheet, keeping its position and size.
+	ReplaceImage(sheet, name string, data []byte) error
+
+	// ExportPDF lays out the used range, or print area, of sheets as PDF pages with
+	// their column widths, row heights, merges, styles and number formats. Each
+	// sheet is scaled, split across pages and given repeating titles as its page
+	// setup asks. The exported sheets must share their paper, orientation,
+	// margins, header and footer, which apply to the whole document.
+	ExportPDF(w io.Writer, opts PDFOptions) error
+
+	// ExportHTML writes sheets as HTML tables of their formatted values, styled
+	// from the workbook's cell formats, with merges spanning rows and columns and
+	// images embedded as data URIs.
+	ExportHTML(w io.Writer, opts HTMLOptions) error
+
+	// Recalculate evaluates every formula and stores the results as the cells' cached values.
+	// Formulas are also recalculated on Save and when a formula cell is read after a change.
+	Recalculate() error
+
+	// StreamSheet returns a writer that flushes rows of a new sheet to disk as they are written.
+	StreamSheet(name string, opts ...StreamOptions) (StreamWriter, error)
 }
@@ -0,0 +1,30 @@
+package document
+
+// StreamCell is a cell written through a StreamWriter with an optional formula and style.
+type StreamCell struct {
+	Value   any
+	Formula string
+	Style   *CellStyle
+}
+
+// StreamOptions configures a streaming worksheet writer.
+type StreamOptions struct {
+	InlineStrings bool // Write text inline instead of through the shared string table
+}
+
+// StreamWriter writes worksheet rows sequentially without keeping them in memory.
+// Rows must be written in ascending order; the worksheet part is assembled on save.
+type StreamWriter interface {
+	// SetColumnWidth sets the width of a 1-based column.
+	SetColumnWidth(col int, width float64) error
+
+	// WriteRow writes the values of a 1-based row starting at column A.
+	// A value may be a plain value, a StreamCell, or nil to leave the cell empty.
+	WriteRow(row int, values ...any) error
+
+	// MergeCells merges the given range, e.g. "A1:C1".
+	MergeCells(hRange string) error
+
+	// Flush writes any buffered rows to the underlying storage.
+	Flush() error
+}
@@ -0,0 +1,60 @@
+package document
+
+// ValidationType restricts the values that may be entered in a cell.
+type ValidationType string
+
+const (
+	// ValidationAny accepts any value; use it to show an input prompt only.
+	ValidationAny ValidationType = ""
+	// ValidationList accepts the items of Options or of the Source range.
+	ValidationList ValidationType = "list"
+	// ValidationWhole accepts whole numbers compared against the formulas.
+	ValidationWhole ValidationType = "whole"
+	// ValidationDecimal accepts numbers compared against the formulas.
+	ValidationDecimal ValidationType = "decimal"
+	// ValidationDate accepts dates compared against the formulas.
+	ValidationDate ValidationType = "date"
+	// ValidationTime accepts times compared against the formulas.
+	ValidationTime ValidationType = "time"
+	// ValidationTextLength accepts text whose length is compared against the formulas.
+	ValidationTextLength ValidationType = "textLength"
+	// ValidationCustom accepts values for which Formula1 evaluates to TRUE.
+	ValidationCustom ValidationType = "custom"
+)
+
+// ErrorStyle is the kind of alert shown when an invalid value is entered.
+type ErrorStyle string
+
+const (
+	// ErrorStop rejects the value. It is the default.
+	ErrorStop ErrorStyle = "stop"
+	// ErrorWarning asks whether to keep the value.
+	ErrorWarning ErrorStyle = "warning"
+	// ErrorInfo informs about the value and keeps it.
+	ErrorInfo ErrorStyle = "information"
+)
+
+// DataValidation restricts and describes the values entered in a range.
+//
+// Formula1 and Formula2 are formulas or literals without the leading "=", e.g.
+// "10", "$B$1" or "DATE(2024,1,1)"; dates and times may also be serial numbers.
+type DataValidation struct {
+	Type     ValidationType
+	Operator ConditionalOperator // Defaults to OperatorBetween; unused by lists and custom rules
+	Formula1 string              // Compared value or minimum; the formula of custom rules
+	Formula2 string              // Maximum of between and notBetween
+
+	Options []string // List items
+	Source  string   // Range or defined name holding the list items, e.g. "Lists!$A$1:$A$5"
+
+	AllowBlank   bool // Accept empty cells
+	HideDropDown bool // Hide the in-cell arrow of a list
+
+	PromptTitle string // At most 32 characters
+	Prompt      string // Shown while the cell is selected; at most 255 characters
+
+	ErrorStyle     ErrorStyle
+	ErrorTitle     string // At most 32 characters
+	ErrorMessage   string // At most 255 characters
+	HideErrorAlert bool   // Accept invalid values without an alert
+}
@@ -17,7 +17,7 @@ func main() {
 	defer doc.Close()
 
 	// 1. Workbook Protection
-	_ = doc.SetPassword("secret123")
+	_ = doc.ProtectWorkbook("secret123")
 
 	sheet, _ := doc.Sheet("Strategic Report")
 
@@ -66,8 +66,8 @@ func main() {
 	sheet.SetFooter("&LGenerated by Thoth&RExport Date: &D")
 
 	// 6. Protection
-	doc.SetPassword("supersecret") // Workbook protection
-	sheet.Protect("sheetsecret")   // Worksheet protection
+	doc.ProtectWorkbook("supersecret") // Workbook protection
+	sheet.Protect("sheetsecret")       // Worksheet protection
 
 	// 7. Grouping
 	sheet.GroupRows(2, 6, 1) // Group regional data
@@ -0,0 +1,294 @@
+package excel
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+	"github.com/gsoultan/thoth/internal/fontmetrics"
+)
+
+const (
+	maxColumnWidth     = 255     // Widest column Excel accepts, in characters
+	maxRowHeight       = 409     // Tallest row Excel accepts, in points
+	defaultColumnWidth = 8.43    // Width of columns without a width, in characters
+	cellPadding        = 5.0 / 7 // Excel pads a column by 5 pixels of a 7 pixel digit
+)
+
+// serifFamilies are the font families measured with the widths of Times
+// rather than Helvetica when no metrics are registered for them.
+var serifFamilies = map[string]bool{
+	"times new roman": true, "times": true, "cambria": true, "georgia": true, "garamond": true,
+	"book antiqua": true, "palatino linotype": true, "century schoolbook": true, "constantia": true,
+}
+
+// cellFont is the font a cell is displayed with.
+type cellFont struct {
+	family string
+	size   float64
+	bold   bool
+}
+
+// textMeasurer measures displayed text with the fonts of the workbook styles.
+// Column widths are counted in digits of the default font, as Excel does.
+type textMeasurer struct {
+	*state
+	digit float64 // Width of a digit of the default font in points
+	fonts map[int]cellFont
+}
+
+func (e *state) newTextMeasurer() *textMeasurer {
+	m := &textMeasurer{state: e, fonts: make(map[int]cellFont)}
+	m.digit = m.width("0", m.font(0))
+	return m
+}
+
+// font returns the font of a cellXfs index.
+func (m *textMeasurer) font(style int) cellFont {
+	if f, ok := m.fonts[style]; ok {
+		return f
+	}
+	f := cellFont{family: "Calibri", size: 11}
+	fontID := 0
+	if style > 0 && style < len(m.styles.CellXfs.Items) {
+		fontID = m.styles.CellXfs.Items[style].FontID
+	}
+	if fontID >= 0 && fontID < len(m.styles.Fonts.Items) {
+		font := m.styles.Fonts.Items[fontID]
+		if font.Name != nil && font.Name.Val != "" {
+			f.family = font.Name.Val
+		}
+		if font.Size != nil && font.Size.Val > 0 {
+			f.size = float64(font.Size.Val)
+		}
+		f.bold = font.Bold != nil
+	}
+	m.fonts[style] = f
+	return f
+}
+
+// width returns the width of a line of text in points. Fonts registered with
+// RegisterFont are measured with their own metrics, others with those of a
+// standard font of the same kind.
+func (m *textMeasurer) width(text string, f cellFont) float64 {
+	family := strings.ToLower(f.family)
+	if f.bold {
+		if metrics, ok := m.fontMetrics[family+" bold"]; ok {
+			return metrics.Width(text, f.size)
+		}
+	}
+	if metrics, ok := m.fontMetrics[family]; ok {
+		return metrics.Width(text, f.size)
+	}
+	name := "Helvetica"
+	switch {
+	case serifFamilies[family]:
+		name = "Times-Roman"
+	case f.bold:
+		name = "Helvetica-Bold"
+	}
+	return fontmetrics.Width(text, name, f.size)
+}
+
+// columnWidth returns the width in characters of a column fitting the longest
+// line of text displayed with a style.
+func (m *textMeasurer) columnWidth(text string, style int) float64 {
+	f := m.font(style)
+	widest := 0.0
+	for line := range strings.SplitSeq(text, "\n") {
+		widest = max(widest, m.width(line, f))
+	}
+	if widest == 0 {
+		return 0
+	}
+	return math.Ceil((widest/m.digit+cellPadding)*100) / 100
+}
+
+// lines returns the number of lines text takes in a column of the given width
+// in characters, wrapping words when wrap is set.
+func (m *textMeasurer) lines(text string, style int, wrap bool, columnWidth float64) int {
+	if !wrap {
+		return 1
+	}
+	f := m.font(style)
+	available := max((columnWidth-cellPadding)*m.digit, m.digit)
+	space := m.width(" ", f)
+	count := 0
+	for paragraph := range strings.SplitSeq(text, "\n") {
+		count++
+		used := 0.0
+		for _, word := range strings.Fields(paragraph) {
+			w := m.width(word, f)
+			if used > 0 && used+space+w <= available {
+				used += space + w
+				continue
+			}
+			if used > 0 {
+				count++
+			}
+			// Words wider than the column are broken across lines.
+			for w > available {
+				count++
+				w -= available
+			}
+			used = w
+		}
+	}
+	return count
+}
+
+// wraps reports whether a cellXfs index wraps text.
+func (m *textMeasurer) wraps(style int) bool {
+	if style <= 0 || style >= len(m.styles.CellXfs.Items) {
+		return false
+	}
+	a := m.styles.CellXfs.Items[style].Alignment
+	return a != nil && a.WrapText == 1
+}
+
+// lineHeight returns the height of a line of text in points: 15 for the
+// default 11 point font.
+func lineHeight(f cellFont) float64 {
+	return math.Ceil(f.size*15/11*4) / 4
+}
+
+// fittableSheet returns the worksheet of a sheet whose columns and rows can be
+// sized, with its formulas recalculated so that their results are measured.
+func (e *sheetProcessor) fittableSheet(sheet string) (*xmlstructs.Worksheet, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return nil, fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
+	if e.calcDirty {
+		if err := (&calcProcessor{e.state}).recalculate(); err != nil {
+			return nil, err
+		}
+	}
+	return ws, nil
+}
+
+// mergedArea returns a function reporting whether a cell is part of a merged
+// range. Merged cells are not measured, as in Excel.
+func mergedArea(ws *xmlstructs.Worksheet) func(col, row int) bool {
+	var areas []cellArea
+	if ws.MergeCells != nil {
+		for _, m := range ws.MergeCells.Items {
+			if a, err := parseArea(m.Ref); err == nil {
+				areas = append(areas, a)
+			}
+		}
+	}
+	return func(col, row int) bool {
+		for _, a := range areas {
+			if a.covers(cellArea{col, row, col, row}) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// autoFitColumns sizes columns to the widest value displayed in them. With no
+// columns given, every column holding a value is sized.
+func (e *sheetProcessor) autoFitColumns(sheet string, cols []int) error {
+	for _, col := range cols {
+		if col < 1 || col > formula.MaxColumns {
+			return fmt.Errorf("invalid column %d", col)
+		}
+	}
+	return e.fitColumns(sheet, func(col, _ int) bool {
+		return len(cols) == 0 || slices.Contains(cols, col)
+	})
+}
+
+// fitColumns sizes columns to the widest value of their cells for which
+// include reports true. Columns without such values keep their width.
+func (e *sheetProcessor) fitColumns(sheet string, include func(col, row int) bool) error {
+	ws, err := e.fittableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	m := e.newTextMeasurer()
+	merged := mergedArea(ws)
+	widths := make(map[int]float64)
+	for _, row := range ws.SheetData.Rows {
+		for _, cell := range row.Cells {
+			col, _ := axisPosition(cell.R)
+			if !include(col, row.R) || merged(col, row.R) {
+				continue
+			}
+			if width := m.columnWidth(e.formattedValue(cell), cell.S); width > 0 {
+				widths[col] = max(widths[col], width)
+			}
+		}
+	}
+	for _, col := range slices.Sorted(maps.Keys(widths)) {
+		if err := e.setColumnWidth(sheet, col, min(widths[col], maxColumnWidth)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// autoFitRows sizes rows to the tallest value displayed in them, wrapping the
+// text of cells whose style wraps it to their column width. With no rows given,
+// every row holding a value is sized.
+func (e *sheetProcessor) autoFitRows(sheet string, rows []int) error {
+	for _, row := range rows {
+		if row < 1 || row > formula.MaxRows {
+			return fmt.Errorf("invalid row %d", row)
+		}
+	}
+	ws, err := e.fittableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	m := e.newTextMeasurer()
+	merged := mergedArea(ws)
+	columnWidths := make(map[int]float64)
+	columnWidth := func(col int) float64 {
+		if w, ok := columnWidths[col]; ok {
+			return w
+		}
+		w, _ := e.columnWidth(sheet, col)
+		if w == 0 {
+			w = defaultColumnWidth + cellPadding
+		}
+		columnWidths[col] = w
+		return w
+	}
+
+	wanted := make(map[int]bool, len(rows))
+	for _, row := range rows {
+		wanted[row] = true
+	}
+	// Only rows holding values are sized, so the heights are set in place.
+	for i := range ws.SheetData.Rows {
+		row := &ws.SheetData.Rows[i]
+		if len(rows) > 0 && !wanted[row.R] {
+			continue
+		}
+		height := 0.0
+		for _, cell := range row.Cells {
+			col, _ := axisPosition(cell.R)
+			text := e.formattedValue(cell)
+			if text == "" || merged(col, row.R) {
+				continue
+			}
+			lines := m.lines(text, cell.S, m.wraps(cell.S), columnWidth(col))
+			height = max(height, float64(lines)*lineHeight(m.font(cell.S)))
+		}
+		if height > 0 {
+			row.Ht, row.CustomHeight = min(height, maxRowHeight), 1
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package excel
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestAutoFitColumns(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Cell("A1").Set("Quarterly total")
+	sheet.Cell("B1").Set("Quarterly total").Style(document.CellStyle{Bold: true})
+	sheet.Cell("C1").Set("Quarterly total").Style(document.CellStyle{Size: 16})
+	sheet.Cell("D1").Set(0.5).Style(document.CellStyle{NumberFormat: "0.00%"})
+	sheet.Cell("E1").Set(0.5)
+	sheet.Cell("F1").Set("a merged heading that is very long")
+	sheet.Cell("F2").Set("x")
+	sheet.MergeCells("F1:H1")
+	sheet.AutoFitColumns()
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AutoFitColumns failed: %v", err)
+	}
+
+	widths := make([]float64, 6)
+	for i := range widths {
+		widths[i], _ = sheet.ColumnWidth(i + 1)
+	}
+	if widths[0] < 12 || widths[0] > 18 {
+		t.Errorf("column A width = %v, want about 14", widths[0])
+	}
+	if widths[1] <= widths[0] {
+		t.Errorf("bold column B width = %v, want wider than %v", widths[1], widths[0])
+	}
+	if widths[2] <= widths[1] {
+		t.Errorf("16 point column C width = %v, want wider than %v", widths[2], widths[1])
+	}
+	if widths[3] <= widths[4] {
+		t.Errorf("formatted column D width = %v, want wider than the unformatted %v", widths[3], widths[4])
+	}
+	if widths[5] > 4 {
+		t.Errorf("column F width = %v, want the merged heading to be skipped", widths[5])
+	}
+
+	sheet.SetColumnWidth(1, 40)
+	sheet.AutoFitColumns(2)
+	if width, _ := sheet.ColumnWidth(1); width != 40 {
+		t.Errorf("column A width = %v, want it kept at 40", width)
+	}
+
+	// Fitting a column inside a span of several splits the span.
+	ws := doc.sheets["Data"]
+	ws.Cols.Items = []xmlstructs.Col{{Min: 1, Max: 5, Width: 30, CustomWidth: 1, Hidden: 1}}
+	sheet.AutoFitColumns(3)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AutoFitColumns failed: %v", err)
+	}
+	got := ws.Cols.Items
+	if len(got) != 3 || got[0] != (xmlstructs.Col{Min: 1, Max: 2, Width: 30, CustomWidth: 1, Hidden: 1}) ||
+		got[1].Min != 3 || got[1].Max != 3 || got[1].Width == 30 || got[1].Hidden != 1 ||
+		got[2] != (xmlstructs.Col{Min: 4, Max: 5, Width: 30, CustomWidth: 1, Hidden: 1}) {
+		t.Errorf("cols = %+v, want the span split around a fitted column C", got)
+	}
+
+	if err := sheet.AutoFitColumns(0).Err(); err == nil {
+		t.Error("expected an error for column 0")
+	}
+}
+
+func TestAutoFitRows(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	long := "one two three four five six seven eight nine ten"
+	sheet.Cell("A1").Set(long).Style(document.CellStyle{WrapText: true})
+	sheet.Cell("A2").Set(long)
+	sheet.Cell("A3").Set("first\nsecond\nthird").Style(document.CellStyle{WrapText: true})
+	sheet.Cell("A4").Set("big").Style(document.CellStyle{Size: 22})
+	sheet.AutoFitRows()
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AutoFitRows failed: %v", err)
+	}
+
+	heights := make([]float64, 4)
+	for i := range heights {
+		heights[i], _ = sheet.RowHeight(i + 1)
+	}
+	if heights[1] != 15 {
+		t.Errorf("unwrapped row 2 height = %v, want 15", heights[1])
+	}
+	if heights[0] < 60 {
+		t.Errorf("wrapped row 1 height = %v, want several lines", heights[0])
+	}
+	if heights[2] != 45 {
+		t.Errorf("row 3 height = %v, want 3 lines of 15", heights[2])
+	}
+	if heights[3] <= 15 {
+		t.Errorf("22 point row 4 height = %v, want taller than 15", heights[3])
+	}
+
+	sheet.SetColumnWidth(1, 100).AutoFitRows(1)
+	if height, _ := sheet.RowHeight(1); height != 15 {
+		t.Errorf("row 1 height in a wide column = %v, want 15", height)
+	}
+
+	if err := sheet.AutoFitRows(-1).Err(); err == nil {
+		t.Error("expected an error for row -1")
+	}
+}
+
+func TestRegisterFont_Missing(t *testing.T) {
+	doc := NewDocument().(*Document)
+	defer doc.Close()
+	if err := doc.RegisterFont("Calibri", filepath.Join(t.TempDir(), "missing.ttf")); err == nil {
+		t.Error("expected an error for a missing font file")
+	}
+}
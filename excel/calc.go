@@ -0,0 +1,504 @@
+package excel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// calcProcessor evaluates cell formulas and stores their results as the cells' cached values.
+type calcProcessor struct{ *state }
+
+type cellKey struct {
+	sheet string
+	col   int
+	row   int
+}
+
+const (
+	calcPending = iota
+	calcEvaluating
+	calcDone
+)
+
+// formulaCell is a node of the dependency graph.
+type formulaCell struct {
+	key        cellKey
+	cell       *xmlstructs.Cell
+	node       formula.Node // nil when the formula could not be parsed or calls an unsupported function
+	status     int
+	value      formula.Value
+	waiting    int            // Number of precedents not yet calculated
+	dependents []*formulaCell // Formula cells that read this one
+}
+
+// calculation holds the state of one recalculation pass and implements formula.Context.
+type calculation struct {
+	*state
+	now      time.Time
+	date1904 bool
+	cells    map[cellKey]*formulaCell
+	order    []*formulaCell                    // Formula cells in document order
+	columns  map[string]map[int][]*formulaCell // Sheet -> column -> formula cells sorted by row
+	sheets   map[string]string                 // Upper-cased sheet name -> sheet name
+	dims     map[string][2]int
+	names    map[string]formula.Node
+	tables   []formula.Table // Loaded on first use
+	current  *formulaCell
+}
+
+// recalculate evaluates every formula in the workbook in dependency order.
+// Cells on or downstream of a circular reference evaluate to 0.
+func (e *calcProcessor) recalculate() error {
+	c := &calculation{
+		state:   e.state,
+		now:     time.Now(),
+		cells:   make(map[cellKey]*formulaCell),
+		columns: make(map[string]map[int][]*formulaCell),
+		sheets:  make(map[string]string),
+		dims:    make(map[string][2]int),
+		names:   make(map[string]formula.Node),
+	}
+	if e.workbook.WorkbookPr != nil {
+		c.date1904 = e.workbook.WorkbookPr.Date1904 == 1
+	}
+	if err := c.collect(); err != nil {
+		return err
+	}
+	c.link()
+
+	queue := make([]*formulaCell, 0, len(c.order))
+	for _, fc := range c.order {
+		if fc.waiting == 0 {
+			queue = append(queue, fc)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		fc := queue[i]
+		c.evaluate(fc)
+		for _, dep := range fc.dependents {
+			dep.waiting--
+			if dep.waiting == 0 {
+				queue = append(queue, dep)
+			}
+		}
+	}
+	for _, fc := range c.order {
+		if fc.status != calcDone {
+			fc.status = calcDone
+			fc.value = formula.Number(0)
+			writeResult(fc.cell, fc.value)
+		}
+	}
+
+	e.calcDirty = false
+	return nil
+}
+
+// collect loads every worksheet and gathers its formula cells, expanding shared formulas.
+func (c *calculation) collect() error {
+	type master struct {
+		text     string
+		col, row int
+	}
+	parsed := make(map[string]formula.Node)
+
+	for _, s := range c.workbook.Sheets {
+		c.sheets[strings.ToUpper(s.Name)] = s.Name
+		if _, streamed := c.streams[s.Name]; streamed {
+			continue
+		}
+		ws, ok := c.worksheet(s.Name)
+		if !ok {
+			if c.hasSheet(s.Name) {
+				return fmt.Errorf("load sheet %s for calculation", s.Name)
+			}
+			continue
+		}
+
+		masters := make(map[int]master)
+		for i := range ws.SheetData.Rows {
+			for j := range ws.SheetData.Rows[i].Cells {
+				cell := &ws.SheetData.Rows[i].Cells[j]
+				if f := cell.F; f != nil && f.T == "shared" && f.SI != nil && f.Text != "" {
+					col, row := axisPosition(cell.R)
+					masters[*f.SI] = master{text: f.Text, col: col, row: row}
+				}
+			}
+		}
+
+		columns := make(map[int][]*formulaCell)
+		c.columns[s.Name] = columns
+		for i := range ws.SheetData.Rows {
+			for j := range ws.SheetData.Rows[i].Cells {
+				cell := &ws.SheetData.Rows[i].Cells[j]
+				if cell.F == nil || cell.F.T == "dataTable" {
+					continue
+				}
+				col, row := axisPosition(cell.R)
+				text := cell.F.Text
+				if cell.F.T == "shared" && cell.F.SI != nil && text == "" {
+					m, ok := masters[*cell.F.SI]
+					if !ok {
+						continue
+					}
+					text, _ = formula.Translate(m.text, col-m.col, row-m.row)
+				}
+
+				node, ok := parsed[text]
+				if !ok {
+					node, _ = formula.Parse(text)
+					if node != nil && !formula.IsSupported(node) {
+						node = nil
+					}
+					parsed[text] = node
+				}
+				fc := &formulaCell{key: cellKey{sheet: s.Name, col: col, row: row}, cell: cell, node: node}
+				c.cells[fc.key] = fc
+				c.order = append(c.order, fc)
+				columns[col] = append(columns[col], fc)
+			}
+		}
+	}
+	return nil
+}
+
+// link builds the dependency graph from the references and names in each formula.
+func (c *calculation) link() {
+	for _, fc := range c.order {
+		if fc.node == nil {
+			continue
+		}
+		refs := c.precedents(fc.key.sheet, fc.node, make(map[string]bool))
+		c.current = fc
+		formula.Walk(fc.node, func(child formula.Node) {
+			if n, ok := child.(formula.TableRefNode); ok {
+				if ref, ok := formula.TableReference(n, c); ok {
+					refs = append(refs, ref)
+				}
+			}
+		})
+		c.current = nil
+		for _, ref := range refs {
+			c.formulasIn(ref, func(dep *formulaCell) {
+				dep.dependents = append(dep.dependents, fc)
+				fc.waiting++
+			})
+		}
+	}
+}
+
+// precedents returns the references a formula reads, following defined names.
+func (c *calculation) precedents(sheet string, node formula.Node, seen map[string]bool) []formula.Reference {
+	refs, names := formula.References(node)
+	for i := range refs {
+		if refs[i].Sheet == "" {
+			refs[i].Sheet = sheet
+		}
+	}
+	for _, n := range names {
+		scope := n.Sheet
+		if scope == "" {
+			scope = sheet
+		}
+		key := scope + "!" + strings.ToUpper(n.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if def, ok := c.Name(scope, n.Name); ok {
+			refs = append(refs, c.precedents(scope, def, seen)...)
+		}
+	}
+	return refs
+}
+
+// formulasIn calls fn for each formula cell within ref.
+func (c *calculation) formulasIn(ref formula.Reference, fn func(*formulaCell)) {
+	sheet, ok := c.sheetName(ref.Sheet)
+	if !ok {
+		return
+	}
+	if ref.IsCell() {
+		if fc, ok := c.cells[cellKey{sheet: sheet, col: ref.Col1, row: ref.Row1}]; ok {
+			fn(fc)
+		}
+		return
+	}
+
+	visit := func(list []*formulaCell) {
+		start := 0
+		if ref.Row1 > 0 {
+			start = sort.Search(len(list), func(i int) bool { return list[i].key.row >= ref.Row1 })
+		}
+		for _, fc := range list[start:] {
+			if ref.Row2 > 0 && fc.key.row > ref.Row2 {
+				break
+			}
+			fn(fc)
+		}
+	}
+	columns := c.columns[sheet]
+	if ref.Col1 == 0 || ref.Col2-ref.Col1 > len(columns) {
+		for col, list := range columns {
+			if ref.Col1 == 0 || (col >= ref.Col1 && col <= ref.Col2) {
+				visit(list)
+			}
+		}
+		return
+	}
+	for col := ref.Col1; col <= ref.Col2; col++ {
+		visit(columns[col])
+	}
+}
+
+func (c *calculation) evaluate(fc *formulaCell) formula.Value {
+	switch fc.status {
+	case calcDone:
+		return fc.value
+	case calcEvaluating:
+		return formula.Number(0) // Circular reference
+	}
+
+	if fc.node == nil && (fc.cell.V != "" || fc.cell.IS != nil) {
+		// Keep the value Excel cached rather than replace it with #NAME?.
+		fc.value = c.typedValue(fc.cell, c.date1904)
+		fc.status = calcDone
+		return fc.value
+	}
+
+	fc.status = calcEvaluating
+	prev := c.current
+	c.current = fc
+	v := formula.Error(formula.ErrName)
+	if fc.node != nil {
+		v = formula.Eval(fc.node, c).Scalar()
+	}
+	c.current = prev
+
+	if v.Kind == formula.KindEmpty {
+		v = formula.Number(0)
+	}
+	fc.value = v
+	fc.status = calcDone
+	writeResult(fc.cell, v)
+	return v
+}
+
+// writeResult stores a calculated value as the cell's cached value.
+func writeResult(cell *xmlstructs.Cell, v formula.Value) {
+	cell.IS = nil
+	switch v.Kind {
+	case formula.KindNumber:
+		cell.T = ""
+		cell.V = formatFloat(v.Num)
+	case formula.KindString:
+		cell.T = "str"
+		cell.V = v.Str
+	case formula.KindBool:
+		cell.T = "b"
+		cell.V = strconv.Itoa(boolToInt(v.Bool))
+	case formula.KindError:
+		cell.T = "e"
+		cell.V = v.Str
+	}
+}
+
+// formatFloat formats a number for a cell's <v> element without losing precision.
+func formatFloat(f float64) string {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-9 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'E', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (c *calculation) Origin() (string, int, int) {
+	if c.current == nil {
+		return "", 0, 0
+	}
+	return c.current.key.sheet, c.current.key.col, c.current.key.row
+}
+
+func (c *calculation) Cell(sheet string, col, row int) formula.Value {
+	name, ok := c.sheetName(sheet)
+	if !ok {
+		return formula.Error(formula.ErrRef)
+	}
+	if fc, ok := c.cells[cellKey{sheet: name, col: col, row: row}]; ok {
+		return c.evaluate(fc)
+	}
+	ws, ok := c.worksheet(name)
+	if !ok {
+		return formula.Value{}
+	}
+	cell := findCell(ws, col, row)
+	if cell == nil {
+		return formula.Value{}
+	}
+	return c.typedValue(cell, c.date1904)
+}
+
+// typedValue converts a stored cell into a formula value.
+func (e *state) typedValue(cell *xmlstructs.Cell, date1904 bool) formula.Value {
+	switch cell.T {
+	case "s", "inlineStr", "str":
+		return formula.String(e.resolveValue(*cell))
+	case "b":
+		return formula.Bool(cell.V == "1" || strings.EqualFold(cell.V, "true"))
+	case "e":
+		return formula.Error(cell.V)
+	case "d":
+		if t, err := time.Parse(time.RFC3339, cell.V); err == nil {
+			return formula.Number(formula.TimeToSerial(t, date1904))
+		}
+	}
+	if cell.V == "" {
+		if cell.IS != nil {
+			return formula.String(e.resolveValue(*cell))
+		}
+		return formula.Value{}
+	}
+	if f, err := strconv.ParseFloat(cell.V, 64); err == nil {
+		return formula.Number(f)
+	}
+	return formula.String(cell.V)
+}
+
+func (c *calculation) Dimension(sheet string) (int, int, bool) {
+	name, ok := c.sheetName(sheet)
+	if !ok {
+		return 0, 0, false
+	}
+	if d, ok := c.dims[name]; ok {
+		return d[0], d[1], true
+	}
+	maxCol, maxRow := 0, 0
+	if ws, ok := c.worksheet(name); ok {
+		for _, row := range ws.SheetData.Rows {
+			maxRow = max(maxRow, row.R)
+			if n := len(row.Cells); n > 0 {
+				col, _ := axisPosition(row.Cells[n-1].R)
+				maxCol = max(maxCol, col)
+			}
+		}
+	}
+	c.dims[name] = [2]int{maxCol, maxRow}
+	return maxCol, maxRow, true
+}
+
+// Name resolves a defined name, preferring one scoped to sheet over a global one.
+func (c *calculation) Name(sheet, name string) (formula.Node, bool) {
+	if c.workbook.DefinedNames == nil {
+		return nil, false
+	}
+	sheetIdx := -1
+	for i, s := range c.workbook.Sheets {
+		if strings.EqualFold(s.Name, sheet) {
+			sheetIdx = i
+			break
+		}
+	}
+
+	var global, local *xmlstructs.DefinedName
+	for i := range c.workbook.DefinedNames.Items {
+		dn := &c.workbook.DefinedNames.Items[i]
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		if dn.LocalSheetID == nil {
+			global = dn
+		} else if *dn.LocalSheetID == sheetIdx {
+			local = dn
+		}
+	}
+	dn := local
+	if dn == nil {
+		dn = global
+	}
+	if dn == nil {
+		return nil, false
+	}
+
+	if node, ok := c.names[dn.Ref]; ok {
+		return node, node != nil
+	}
+	node, err := formula.Parse(dn.Ref)
+	if err != nil {
+		node = nil
+	}
+	c.names[dn.Ref] = node
+	return node, node != nil
+}
+
+// Table returns a table by name or, when name is empty, the table containing
+// the cell being calculated.
+func (c *calculation) Table(name string) (formula.Table, bool) {
+	if c.tables == nil {
+		c.tables = []formula.Table{}
+		if tables, err := c.workbookTables(); err == nil {
+			for _, wt := range tables {
+				if t, ok := wt.formulaTable(); ok {
+					c.tables = append(c.tables, t)
+				}
+			}
+		}
+	}
+	sheet, col, row := c.Origin()
+	for _, t := range c.tables {
+		if name == "" && t.Sheet == sheet && col >= t.Col1 && col <= t.Col2 && row >= t.Row1 && row <= t.Row2 ||
+			name != "" && strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return formula.Table{}, false
+}
+
+func (c *calculation) Date1904() bool {
+	return c.date1904
+}
+
+func (c *calculation) Now() time.Time {
+	return c.now
+}
+
+// sheetName resolves a sheet name case-insensitively, as Excel does.
+func (c *calculation) sheetName(name string) (string, bool) {
+	if name == "" && c.current != nil {
+		return c.current.key.sheet, true
+	}
+	canonical, ok := c.sheets[strings.ToUpper(name)]
+	return canonical, ok
+}
+
+// findCell locates a cell in a worksheet whose rows and cells are in ascending order.
+func findCell(ws *xmlstructs.Worksheet, col, row int) *xmlstructs.Cell {
+	rows := ws.SheetData.Rows
+	i := sort.Search(len(rows), func(i int) bool { return rows[i].R >= row })
+	if i == len(rows) || rows[i].R != row {
+		return nil
+	}
+	cells := rows[i].Cells
+	j := sort.Search(len(cells), func(j int) bool {
+		c, _ := axisPosition(cells[j].R)
+		return c >= col
+	})
+	if j == len(cells) {
+		return nil
+	}
+	if c, _ := axisPosition(cells[j].R); c != col {
+		return nil
+	}
+	return &cells[j]
+}
+
+// axisPosition returns the 1-based column and row of a cell reference such as "B3".
+func axisPosition(axis string) (int, int) {
+	row, _ := getRowFromAxis(axis)
+	return colToNum(getColumnFromAxis(axis)), row
+}
@@ -20,71 +20,46 @@ func (e *cellProcessor) setCellValue(sheet, axis string, value any) error {
 		return err
 	}
 
+	e.calcDirty = true
+	return e.writeCellValue(targetCell, value)
+}
+
+// writeCellValue encodes value into cell, storing text in the shared string table.
+func (e *state) writeCellValue(cell *xmlstructs.Cell, value any) error {
 	switch v := value.(type) {
 	case string:
-		if e.sharedStrings == nil {
-			e.sharedStrings = &xmlstructs.SharedStrings{SI: make([]xmlstructs.SI, 0)}
-		}
-		var idx int
-		if cachedIdx, exists := e.sharedStringsIndex[v]; exists {
-			idx = cachedIdx
-		} else {
-			idx = len(e.sharedStrings.SI)
-			e.sharedStrings.SI = append(e.sharedStrings.SI, xmlstructs.SI{T: v})
-			e.sharedStrings.Count++
-			e.sharedStrings.Unique++
-			e.sharedStringsIndex[v] = idx
-		}
-		targetCell.T = "s"
-		targetCell.V = strconv.Itoa(idx)
-		targetCell.F = nil
+		cell.T = "s"
+		cell.V = strconv.Itoa(e.sharedStringIndex(v))
+		cell.IS = nil
+		cell.F = nil
 	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8, float64, float32:
-		targetCell.T = "n"
-		targetCell.V = fmt.Sprintf("%v", v)
-		targetCell.F = nil
+		cell.T = "n"
+		cell.V = fmt.Sprintf("%v", v)
+		cell.F = nil
 	case bool:
-		targetCell.T = "b"
+		cell.T = "b"
 		if v {
-			targetCell.V = "1"
+			cell.V = "1"
 		} else {
-			targetCell.V = "0"
+			cell.V = "0"
 		}
-		targetCell.F = nil
+		cell.F = nil
 	case time.Time:
 		excelBaseDate := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
 		days := v.Sub(excelBaseDate).Hours() / 24
-		targetCell.T = "n" // Dates are numbers in Excel
-		targetCell.V = fmt.Sprintf("%v", days)
-		targetCell.F = nil
+		cell.T = "n" // Dates are numbers in Excel
+		cell.V = fmt.Sprintf("%v", days)
+		cell.F = nil
 	case []document.TextSpan:
-		targetCell.T = "inlineStr"
-		targetCell.IS = &xmlstructs.Rst{
+		cell.T = "inlineStr"
+		cell.IS = &xmlstructs.Rst{
 			R: make([]xmlstructs.Run, 0, len(v)),
 		}
 		for _, span := range v {
-			run := xmlstructs.Run{T: span.Text}
-			if span.Style.Bold || span.Style.Italic || span.Style.Size > 0 || span.Style.Color != "" || span.Style.Font != "" {
-				run.RPr = &xmlstructs.RPr{}
-				if span.Style.Bold {
-					run.RPr.Bold = &struct{}{}
-				}
-				if span.Style.Italic {
-					run.RPr.Italic = &struct{}{}
-				}
-				if span.Style.Size > 0 {
-					run.RPr.Size = &xmlstructs.ValInt{Val: span.Style.Size}
-				}
-				if span.Style.Color != "" {
-					run.RPr.Color = &xmlstructs.Color{RGB: span.Style.Color}
-				}
-				if span.Style.Font != "" {
-					run.RPr.RFont = &xmlstructs.ValString{Val: span.Style.Font}
-				}
-			}
-			targetCell.IS.R = append(targetCell.IS.R, run)
+			cell.IS.R = append(cell.IS.R, textRun(span))
 		}
-		targetCell.V = ""
-		targetCell.F = nil
+		cell.V = ""
+		cell.F = nil
 	default:
 		return fmt.Errorf("unsupported value type: %T", value)
 	}
@@ -92,6 +67,66 @@ func (e *cellProcessor) setCellValue(sheet, axis string, value any) error {
 	return nil
 }
 
+// textRun converts a styled span to a rich text run.
+func textRun(span document.TextSpan) xmlstructs.Run {
+	run := xmlstructs.Run{T: span.Text}
+	if span.Style.Bold || span.Style.Italic || span.Style.Size > 0 || span.Style.Color != "" || span.Style.Font != "" {
+		run.RPr = &xmlstructs.RPr{}
+		if span.Style.Bold {
+			run.RPr.Bold = &struct{}{}
+		}
+		if span.Style.Italic {
+			run.RPr.Italic = &struct{}{}
+		}
+		if span.Style.Size > 0 {
+			run.RPr.Size = &xmlstructs.ValInt{Val: span.Style.Size}
+		}
+		if span.Style.Color != "" {
+			run.RPr.Color = &xmlstructs.Color{RGB: span.Style.Color}
+		}
+		if span.Style.Font != "" {
+			run.RPr.RFont = &xmlstructs.ValString{Val: span.Style.Font}
+		}
+	}
+	return run
+}
+
+// textSpan converts a rich text run back to a styled span.
+func textSpan(run xmlstructs.Run) document.TextSpan {
+	span := document.TextSpan{Text: run.T}
+	if run.RPr == nil {
+		return span
+	}
+	span.Style.Bold = run.RPr.Bold != nil
+	span.Style.Italic = run.RPr.Italic != nil
+	if run.RPr.Size != nil {
+		span.Style.Size = run.RPr.Size.Val
+	}
+	if run.RPr.Color != nil {
+		span.Style.Color = run.RPr.Color.RGB
+	}
+	if run.RPr.RFont != nil {
+		span.Style.Font = run.RPr.RFont.Val
+	}
+	return span
+}
+
+// sharedStringIndex returns the index of v in the shared string table, adding it if needed.
+func (e *state) sharedStringIndex(v string) int {
+	if e.sharedStrings == nil {
+		e.sharedStrings = &xmlstructs.SharedStrings{SI: make([]xmlstructs.SI, 0)}
+	}
+	if idx, exists := e.sharedStringsIndex[v]; exists {
+		return idx
+	}
+	idx := len(e.sharedStrings.SI)
+	e.sharedStrings.SI = append(e.sharedStrings.SI, xmlstructs.SI{T: v})
+	e.sharedStrings.Count++
+	e.sharedStrings.Unique++
+	e.sharedStringsIndex[v] = idx
+	return idx
+}
+
 func (e *cellProcessor) setCellFormula(sheet, axis string, formula string) error {
 	if sheet == "" || axis == "" {
 		return fmt.Errorf("sheet and axis cannot be empty")
@@ -104,9 +139,11 @@ func (e *cellProcessor) setCellFormula(sheet, axis string, formula string) error
 	if formula != "" && formula[0] == '=' {
 		formula = formula[1:]
 	}
-	targetCell.F = &formula
+	targetCell.F = &xmlstructs.Formula{Text: formula}
 	targetCell.T = ""
-	targetCell.V = "0"
+	targetCell.V = ""
+	targetCell.IS = nil
+	e.calcDirty = true
 	return nil
 }
 
@@ -114,7 +151,7 @@ func (e *cellProcessor) setCellHyperlink(sheet, axis string, url string) error {
 	if sheet == "" || axis == "" || url == "" {
 		return fmt.Errorf("parameters cannot be empty")
 	}
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -143,24 +180,36 @@ func (e *cellProcessor) setCellHyperlink(sheet, axis string, url string) error {
 }
 
 func (e *cellProcessor) getCellValue(sheet, axis string) (string, error) {
-	ws, ok := e.sheets[sheet]
-	if !ok {
-		return "", fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	cell, err := e.getCell(sheet, axis)
+	if err != nil || cell == nil {
+		return "", err
 	}
+	return e.resolveValue(*cell), nil
+}
 
-	if e.cellCache[sheet] != nil {
-		if cell, ok := e.cellCache[sheet][axis]; ok {
-			return e.resolveValue(*cell), nil
-		}
+// getFormattedValue returns a cell's value rendered with its number format.
+func (e *cellProcessor) getFormattedValue(sheet, axis string) (string, error) {
+	cell, err := e.getCell(sheet, axis)
+	if err != nil || cell == nil {
+		return "", err
 	}
+	return e.formattedValue(*cell), nil
+}
 
-	for _, row := range ws.SheetData.Rows {
-		for _, cell := range row.Cells {
-			if cell.R == axis {
-				return e.resolveValue(cell), nil
-			}
-		}
+// getCell returns an existing cell, recalculating the workbook first when the
+// cell holds a formula and values have changed. Recalculation is not
+// incremental: it loads every sheet and evaluates every formula, as Save does.
+// It returns nil for empty cells.
+func (e *cellProcessor) getCell(sheet, axis string) (*xmlstructs.Cell, error) {
+	target, err := e.lookupCell(sheet, axis)
+	if err != nil || target == nil {
+		return nil, err
 	}
 
-	return "", nil
+	if target.F != nil && e.calcDirty {
+		if err := (&calcProcessor{e.state}).recalculate(); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
 }
@@ -0,0 +1,393 @@
+package excel
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const (
+	chartRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart"
+	chartContentType = "application/vnd.openxmlformats-officedocument.drawingml.chart+xml"
+)
+
+// Axis IDs of the primary and secondary axis pairs.
+const (
+	primaryCatAxis   = 1
+	primaryValAxis   = 2
+	secondaryCatAxis = 3
+	secondaryValAxis = 4
+)
+
+type chartProcessor struct{ *state }
+
+// chartGroup collects the series drawn with the same chart type against the same axes.
+type chartGroup struct {
+	kind      document.ChartType
+	secondary bool
+	series    []xmlstructs.Series
+}
+
+func (e *chartProcessor) addChart(sheet string, spec document.ChartSpec) error {
+	if len(spec.Series) == 0 {
+		return fmt.Errorf("chart requires at least one series")
+	}
+	area, err := formula.ParseReference(spec.Range)
+	if err != nil || area.Invalid || area.Col1 == 0 || area.Row1 == 0 {
+		return fmt.Errorf("invalid chart range %q", spec.Range)
+	}
+	col1, row1, col2, row2 := area.Col1, area.Row1, area.Col2, area.Row2
+	if !area.IsRange {
+		col2, row2 = col1, row1
+	}
+
+	groups, err := e.chartGroups(sheet, spec)
+	if err != nil {
+		return err
+	}
+	cs := buildChart(spec, groups)
+
+	_, dr, drRels, err := e.sheetDrawing(sheet)
+	if err != nil {
+		return err
+	}
+	chartPath := e.nextPartPath("xl/charts/chart%d.xml")
+	e.charts[chartPath] = cs
+	rID := drRels.AddRelationship(chartRelType, "../charts/"+path.Base(chartPath))
+
+	id := nextShapeID(dr)
+	dr.Anchors = append(dr.Anchors, xmlstructs.Anchor{
+		TwoCellAnchor: &xmlstructs.TwoCellAnchor{
+			From: xmlstructs.Marker{Col: col1 - 1, Row: row1 - 1},
+			To:   xmlstructs.Marker{Col: col2, Row: row2},
+			GraphicFrame: &xmlstructs.GraphicFrame{
+				NvGraphicFramePr: xmlstructs.NvGraphicFramePr{
+					CNvPr: xmlstructs.CNvPr{ID: id, Name: fmt.Sprintf("Chart %d", id)},
+				},
+				Graphic: xmlstructs.Graphic{
+					GraphicData: xmlstructs.GraphicData{
+						URI:   xmlstructs.NSChart,
+						Chart: &xmlstructs.ChartRef{RID: rID},
+					},
+				},
+			},
+		},
+	})
+	return nil
+}
+
+// chartGroups converts the series of spec, grouping them by chart type and axis pair
+// in the order they first appear.
+func (e *chartProcessor) chartGroups(sheet string, spec document.ChartSpec) ([]*chartGroup, error) {
+	var groups []*chartGroup
+	for i, s := range spec.Series {
+		kind := s.Type
+		if kind == "" {
+			kind = spec.Type
+		}
+		if kind == "" {
+			kind = document.ChartColumn
+		}
+		switch kind {
+		case document.ChartColumn, document.ChartBar, document.ChartLine, document.ChartArea, document.ChartPie, document.ChartScatter:
+		default:
+			return nil, fmt.Errorf("unsupported chart type %q", kind)
+		}
+		if s.Values == "" {
+			return nil, fmt.Errorf("chart series %d has no values", i+1)
+		}
+
+		var group *chartGroup
+		for _, g := range groups {
+			if g.kind == kind && g.secondary == s.Secondary {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &chartGroup{kind: kind, secondary: s.Secondary}
+			groups = append(groups, group)
+		}
+
+		ser, err := chartSeries(sheet, i, kind, s)
+		if err != nil {
+			return nil, err
+		}
+		group.series = append(group.series, ser)
+	}
+
+	for _, g := range groups {
+		if (g.kind == document.ChartPie || g.kind == document.ChartScatter) && g.kind != groups[0].kind {
+			return nil, fmt.Errorf("%s charts cannot be combined with other chart types", g.kind)
+		}
+		if g.kind != groups[0].kind && (groups[0].kind == document.ChartPie || groups[0].kind == document.ChartScatter) {
+			return nil, fmt.Errorf("%s charts cannot be combined with other chart types", groups[0].kind)
+		}
+		if g.kind == document.ChartPie && g.secondary {
+			return nil, fmt.Errorf("pie charts have no secondary axis")
+		}
+	}
+	return groups, nil
+}
+
+func chartSeries(sheet string, i int, kind document.ChartType, s document.ChartSeries) (xmlstructs.Series, error) {
+	ser := xmlstructs.Series{
+		Idx:   xmlstructs.ValInt{Val: i},
+		Order: xmlstructs.ValInt{Val: i},
+	}
+	if s.Name != "" {
+		if strings.Contains(s.Name, "!") {
+			ref, err := chartRef(sheet, s.Name)
+			if err != nil {
+				return ser, err
+			}
+			ser.Tx = &xmlstructs.SeriesText{StrRef: &xmlstructs.FormulaRef{F: ref}}
+		} else {
+			ser.Tx = &xmlstructs.SeriesText{V: s.Name}
+		}
+	}
+
+	values, err := chartRef(sheet, s.Values)
+	if err != nil {
+		return ser, err
+	}
+	var categories string
+	if s.Categories != "" {
+		if categories, err = chartRef(sheet, s.Categories); err != nil {
+			return ser, err
+		}
+	}
+
+	color := strings.TrimPrefix(s.Color, "#")
+	switch kind {
+	case document.ChartScatter:
+		if categories != "" {
+			ser.XVal = &xmlstructs.DataSource{NumRef: &xmlstructs.FormulaRef{F: categories}}
+		}
+		ser.YVal = &xmlstructs.DataSource{NumRef: &xmlstructs.FormulaRef{F: values}}
+		ser.SpPr = &xmlstructs.ShapeProperties{Ln: &xmlstructs.Outline{NoFill: &struct{}{}}}
+		if s.Smooth {
+			ser.SpPr.Ln = &xmlstructs.Outline{W: 28575}
+			if color != "" {
+				ser.SpPr.Ln.SolidFill = solidFill(color)
+			}
+		}
+		ser.Marker = &xmlstructs.SeriesMarker{Symbol: xmlstructs.ValString{Val: "circle"}}
+		if color != "" {
+			ser.Marker.SpPr = &xmlstructs.ShapeProperties{SolidFill: solidFill(color)}
+		}
+		ser.Smooth = &xmlstructs.ValInt{Val: boolToInt(s.Smooth)}
+		return ser, nil
+	case document.ChartLine:
+		if color != "" {
+			ser.SpPr = &xmlstructs.ShapeProperties{Ln: &xmlstructs.Outline{W: 28575, SolidFill: solidFill(color)}}
+		}
+		ser.Smooth = &xmlstructs.ValInt{Val: boolToInt(s.Smooth)}
+	case document.ChartColumn, document.ChartBar:
+		ser.InvertIfNegative = &xmlstructs.ValInt{Val: 0}
+		fallthrough
+	default:
+		if color != "" {
+			ser.SpPr = &xmlstructs.ShapeProperties{SolidFill: solidFill(color)}
+		}
+	}
+	if categories != "" {
+		ser.Cat = &xmlstructs.DataSource{StrRef: &xmlstructs.FormulaRef{F: categories}}
+	}
+	ser.Val = &xmlstructs.DataSource{NumRef: &xmlstructs.FormulaRef{F: values}}
+	return ser, nil
+}
+
+// buildChart assembles the chart part from the grouped series.
+func buildChart(spec document.ChartSpec, groups []*chartGroup) *xmlstructs.ChartSpace {
+	cs := &xmlstructs.ChartSpace{}
+	chart := &cs.Chart
+	chart.PlotVisOnly = xmlstructs.ValInt{Val: 1}
+	chart.DispBlanksAs = xmlstructs.ValString{Val: "gap"}
+	if spec.Title != "" {
+		chart.Title = chartTitle(spec.Title)
+	} else {
+		chart.AutoTitleDeleted = xmlstructs.ValInt{Val: 1}
+	}
+
+	switch spec.Legend {
+	case "none":
+	case "left", "top", "bottom":
+		chart.Legend = &xmlstructs.Legend{LegendPos: xmlstructs.ValString{Val: spec.Legend[:1]}}
+	default:
+		chart.Legend = &xmlstructs.Legend{LegendPos: xmlstructs.ValString{Val: "r"}}
+	}
+
+	var dLbls *xmlstructs.DataLabels
+	if spec.DataLabels {
+		dLbls = &xmlstructs.DataLabels{ShowVal: xmlstructs.ValInt{Val: 1}}
+	}
+
+	plot := &chart.PlotArea
+	hasSecondary, horizontal := false, false
+	for _, g := range groups {
+		group := xmlstructs.ChartGroup{Series: g.series, DLbls: dLbls}
+		axes := []xmlstructs.ValInt{{Val: primaryCatAxis}, {Val: primaryValAxis}}
+		if g.secondary {
+			axes = []xmlstructs.ValInt{{Val: secondaryCatAxis}, {Val: secondaryValAxis}}
+			hasSecondary = true
+		}
+		switch g.kind {
+		case document.ChartColumn, document.ChartBar:
+			dir := "col"
+			if g.kind == document.ChartBar {
+				dir = "bar"
+				horizontal = true
+			}
+			grouping := chartGrouping(spec.Grouping, "clustered")
+			group.BarDir = &xmlstructs.ValString{Val: dir}
+			group.Grouping = &xmlstructs.ValString{Val: grouping}
+			group.GapWidth = &xmlstructs.ValInt{Val: 150}
+			if grouping != "clustered" {
+				group.Overlap = &xmlstructs.ValInt{Val: 100}
+			}
+			group.AxIDs = axes
+			plot.BarCharts = append(plot.BarCharts, group)
+		case document.ChartLine:
+			group.Grouping = &xmlstructs.ValString{Val: chartGrouping(spec.Grouping, "standard")}
+			group.Marker = &xmlstructs.ValInt{Val: 1}
+			group.AxIDs = axes
+			plot.LineCharts = append(plot.LineCharts, group)
+		case document.ChartArea:
+			group.Grouping = &xmlstructs.ValString{Val: chartGrouping(spec.Grouping, "standard")}
+			group.AxIDs = axes
+			plot.AreaCharts = append(plot.AreaCharts, group)
+		case document.ChartPie:
+			group.VaryColors = xmlstructs.ValInt{Val: 1}
+			group.FirstSliceAng = &xmlstructs.ValInt{Val: 0}
+			plot.PieCharts = append(plot.PieCharts, group)
+		case document.ChartScatter:
+			group.ScatterStyle = &xmlstructs.ValString{Val: "lineMarker"}
+			group.AxIDs = axes
+			plot.ScatterCharts = append(plot.ScatterCharts, group)
+		}
+	}
+
+	switch groups[0].kind {
+	case document.ChartPie:
+		return cs
+	case document.ChartScatter:
+		plot.ValAx = append(plot.ValAx,
+			valueAxis(primaryCatAxis, primaryValAxis, "b", spec.XAxis, "midCat"),
+			valueAxis(primaryValAxis, primaryCatAxis, "l", spec.YAxis, "midCat"))
+		if hasSecondary {
+			x := valueAxis(secondaryCatAxis, secondaryValAxis, "b", document.ChartAxis{}, "midCat")
+			x.Delete = xmlstructs.ValInt{Val: 1}
+			y := valueAxis(secondaryValAxis, secondaryCatAxis, "r", spec.SecondaryYAxis, "midCat")
+			y.Crosses = xmlstructs.ValString{Val: "max"}
+			plot.ValAx = append(plot.ValAx, x, y)
+		}
+		return cs
+	}
+
+	catPos, valPos := "b", "l"
+	if horizontal {
+		catPos, valPos = "l", "b"
+	}
+	plot.CatAx = append(plot.CatAx, categoryAxis(primaryCatAxis, primaryValAxis, catPos, spec.XAxis))
+	plot.ValAx = append(plot.ValAx, valueAxis(primaryValAxis, primaryCatAxis, valPos, spec.YAxis, "between"))
+	if hasSecondary {
+		cat := categoryAxis(secondaryCatAxis, secondaryValAxis, catPos, document.ChartAxis{})
+		cat.Delete = xmlstructs.ValInt{Val: 1}
+		secondaryPos := "r"
+		if horizontal {
+			secondaryPos = "t"
+		}
+		val := valueAxis(secondaryValAxis, secondaryCatAxis, secondaryPos, spec.SecondaryYAxis, "between")
+		val.Crosses = xmlstructs.ValString{Val: "max"}
+		plot.CatAx = append(plot.CatAx, cat)
+		plot.ValAx = append(plot.ValAx, val)
+	}
+	return cs
+}
+
+func categoryAxis(id, crossID int, pos string, axis document.ChartAxis) xmlstructs.ChartAxis {
+	ax := chartAxis(id, crossID, pos, axis)
+	ax.Auto = &xmlstructs.ValInt{Val: 1}
+	ax.LblAlgn = &xmlstructs.ValString{Val: "ctr"}
+	ax.LblOffset = &xmlstructs.ValInt{Val: 100}
+	return ax
+}
+
+func valueAxis(id, crossID int, pos string, axis document.ChartAxis, crossBetween string) xmlstructs.ChartAxis {
+	ax := chartAxis(id, crossID, pos, axis)
+	if ax.NumFmt == nil {
+		ax.NumFmt = &xmlstructs.ChartNumFmt{FormatCode: "General", SourceLinked: 1}
+	}
+	ax.CrossBetween = &xmlstructs.ValString{Val: crossBetween}
+	if axis.MajorUnit > 0 {
+		ax.MajorUnit = &xmlstructs.ValString{Val: formatFloat(axis.MajorUnit)}
+	}
+	return ax
+}
+
+func chartAxis(id, crossID int, pos string, axis document.ChartAxis) xmlstructs.ChartAxis {
+	ax := xmlstructs.ChartAxis{
+		AxID:          xmlstructs.ValInt{Val: id},
+		Scaling:       xmlstructs.Scaling{Orientation: xmlstructs.ValString{Val: "minMax"}},
+		AxPos:         xmlstructs.ValString{Val: pos},
+		MajorTickMark: xmlstructs.ValString{Val: "out"},
+		MinorTickMark: xmlstructs.ValString{Val: "none"},
+		TickLblPos:    xmlstructs.ValString{Val: "nextTo"},
+		CrossAx:       xmlstructs.ValInt{Val: crossID},
+		Crosses:       xmlstructs.ValString{Val: "autoZero"},
+	}
+	if axis.Max != nil {
+		ax.Scaling.Max = &xmlstructs.ValString{Val: formatFloat(*axis.Max)}
+	}
+	if axis.Min != nil {
+		ax.Scaling.Min = &xmlstructs.ValString{Val: formatFloat(*axis.Min)}
+	}
+	if axis.MajorGridlines {
+		ax.MajorGridlines = &struct{}{}
+	}
+	if axis.Title != "" {
+		ax.Title = chartTitle(axis.Title)
+	}
+	if axis.NumberFormat != "" {
+		ax.NumFmt = &xmlstructs.ChartNumFmt{FormatCode: axis.NumberFormat}
+	}
+	return ax
+}
+
+func chartTitle(text string) *xmlstructs.ChartTitle {
+	title := &xmlstructs.ChartTitle{}
+	title.Tx.Rich.P.R.T = text
+	return title
+}
+
+// chartGrouping maps a grouping onto the values allowed by the chart type.
+func chartGrouping(grouping document.ChartGrouping, fallback string) string {
+	switch grouping {
+	case document.GroupingStacked, document.GroupingPercentStacked:
+		return string(grouping)
+	}
+	return fallback
+}
+
+// chartRef qualifies a range with the chart's sheet and makes it absolute,
+// since chart formulas are not relative to any cell.
+func chartRef(sheet, ref string) (string, error) {
+	r, err := formula.ParseReference(ref)
+	if err != nil || r.Invalid {
+		return "", fmt.Errorf("invalid chart reference %q", ref)
+	}
+	if r.Sheet == "" {
+		r.Sheet = sheet
+	}
+	r.AbsCol1, r.AbsRow1, r.AbsCol2, r.AbsRow2 = true, true, true, true
+	return r.String(), nil
+}
+
+func solidFill(color string) *xmlstructs.SolidFill {
+	return &xmlstructs.SolidFill{SrgbClr: xmlstructs.ValString{Val: strings.ToUpper(color)}}
+}
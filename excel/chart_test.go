@@ -0,0 +1,157 @@
+package excel
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func TestAddChart_ComboWithSecondaryAxis(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("A1").Set("Month")
+	sheet.Cell("B1").Set("Revenue")
+	sheet.Cell("C1").Set("Margin")
+	for i, m := range []string{"Jan", "Feb", "Mar"} {
+		row := string(rune('2' + i))
+		sheet.Cell("A" + row).Set(m)
+		sheet.Cell("B" + row).Set(100 * (i + 1))
+		sheet.Cell("C" + row).Set(0.1 * float64(i+1))
+	}
+
+	imgPath := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(imgPath, []byte("\x89PNG"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	sheet.InsertImage(imgPath, 0, 5)
+	sheet.AddChart(document.ChartSpec{
+		Type:       document.ChartColumn,
+		Title:      "Sales",
+		Range:      "E2:L18",
+		Legend:     "bottom",
+		DataLabels: true,
+		Series: []document.ChartSeries{
+			{Name: "Sheet1!B1", Categories: "A2:A4", Values: "B2:B4", Color: "#4472C4"},
+			{Name: "Margin", Categories: "A2:A4", Values: "C2:C4", Type: document.ChartLine, Secondary: true},
+		},
+		YAxis:          document.ChartAxis{Title: "Revenue", MajorGridlines: true},
+		SecondaryYAxis: document.ChartAxis{NumberFormat: "0%"},
+	})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddChart failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+
+	chart, ok := parts["xl/charts/chart1.xml"]
+	if !ok {
+		t.Fatal("Expected xl/charts/chart1.xml in package")
+	}
+	for _, want := range []string{
+		`<c:chartSpace xmlns:c=`,
+		`<c:barChart><c:barDir val="col">`,
+		`<c:lineChart>`,
+		`<c:f>Sheet1!$B$1</c:f>`,
+		`<c:f>Sheet1!$A$2:$A$4</c:f>`,
+		`<a:t>Sales</a:t>`,
+		`<a:srgbClr val="4472C4">`,
+		`<c:axId val="3"></c:axId><c:axId val="4"></c:axId>`,
+		`<c:numFmt formatCode="0%" sourceLinked="0">`,
+		`<c:legendPos val="b">`,
+		`<c:showVal val="1">`,
+	} {
+		if !strings.Contains(chart, want) {
+			t.Errorf("Expected %s in chart part", want)
+		}
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], "drawingml.chart+xml") {
+		t.Error("Expected chart content type override")
+	}
+
+	drawing := parts["xl/drawings/drawing1.xml"]
+	for _, want := range []string{
+		`<xdr:wsDr xmlns:xdr=`,
+		`<xdr:oneCellAnchor>`,
+		`<a:blip r:embed="rId1">`,
+		`<xdr:twoCellAnchor><xdr:from><xdr:col>4</xdr:col>`,
+		`<c:chart r:id="rId2">`,
+	} {
+		if !strings.Contains(drawing, want) {
+			t.Errorf("Expected %s in drawing part", want)
+		}
+	}
+	if strings.Contains(drawing, `xmlns=""`) {
+		t.Error("Drawing part should not reset the default namespace")
+	}
+	if !strings.Contains(parts["xl/drawings/_rels/drawing1.xml.rels"], "../charts/chart1.xml") {
+		t.Error("Expected chart relationship in drawing rels")
+	}
+
+	// Reopen and add a second chart to the existing drawing.
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	s.AddChart(document.ChartSpec{
+		Type:   document.ChartPie,
+		Range:  "E20:J30",
+		Series: []document.ChartSeries{{Categories: "A2:A4", Values: "B2:B4"}},
+	})
+	if err := s.Err(); err != nil {
+		t.Fatalf("AddChart on reopened document failed: %v", err)
+	}
+	buf.Reset()
+	if err := reopened.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts = zipParts(t, buf.Bytes())
+	if !strings.Contains(parts["xl/charts/chart2.xml"], "<c:pieChart>") {
+		t.Error("Expected pie chart in xl/charts/chart2.xml")
+	}
+	if _, ok := parts["xl/charts/chart1.xml"]; !ok {
+		t.Error("Expected the original chart to be kept")
+	}
+	if n := strings.Count(parts["xl/drawings/drawing1.xml"], "<xdr:graphicFrame"); n != 2 {
+		t.Errorf("Expected 2 graphic frames in drawing, got %d", n)
+	}
+	if _, ok := parts["xl/drawings/drawing2.xml"]; ok {
+		t.Error("Expected the chart to be added to the existing drawing")
+	}
+}
+
+func TestAddChart_Validation(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	tests := []struct {
+		name string
+		spec document.ChartSpec
+	}{
+		{"no series", document.ChartSpec{Range: "A1:D5"}},
+		{"no range", document.ChartSpec{Series: []document.ChartSeries{{Values: "B1:B3"}}}},
+		{"pie combo", document.ChartSpec{Type: document.ChartPie, Range: "A1:D5", Series: []document.ChartSeries{
+			{Values: "B1:B3"}, {Values: "C1:C3", Type: document.ChartLine},
+		}}},
+	}
+	for _, tt := range tests {
+		sheet, _ := doc.Sheet("Sheet1")
+		if err := sheet.AddChart(tt.spec).Err(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
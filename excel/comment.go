@@ -0,0 +1,455 @@
+package excel
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const (
+	commentsRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+	vmlDrawingRelType   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"
+	commentsContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"
+	vmlContentType      = "application/vnd.openxmlformats-officedocument.vmlDrawing"
+	vmlNamespace        = "urn:schemas-microsoft-com:vml"
+	noteShapeType       = "_x0000_t202" // Shape type of note boxes
+
+	// Default note box size in points, as used by Excel.
+	defaultNoteWidth  = 108
+	defaultNoteHeight = 59.25
+)
+
+// sheetComments holds the comments of a sheet together with the note shapes
+// of the legacy VML drawing that displays them.
+type sheetComments struct {
+	path    string // Comments part; empty once the last comment has been removed
+	vmlPath string // VML drawing part rendering the notes
+	part    *xmlstructs.Comments
+	shapes  map[string]noteShape // Cell reference -> box settings
+	others  [][]byte             // Source of the drawing's other shapes, such as form controls
+	otherID map[int]bool         // Shape IDs used by others
+	dirty   bool
+	removed []string // Source parts dropped with the last comment
+}
+
+type noteShape struct {
+	visible       bool
+	width, height float64
+}
+
+type commentProcessor struct{ *state }
+
+func (e *commentProcessor) setComment(sheet, axis string, comment document.Comment) error {
+	ref, err := commentRef(axis)
+	if err != nil {
+		return err
+	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
+	sc, err := e.sheetComments(sheet, true)
+	if err != nil {
+		return err
+	}
+
+	author := comment.Author
+	if author == "" && e.coreProperties != nil {
+		author = e.coreProperties.Creator
+	}
+	item := xmlstructs.Comment{Ref: ref, AuthorID: sc.part.AuthorID(author)}
+	if len(comment.Runs) > 0 {
+		for _, span := range comment.Runs {
+			item.Text.R = append(item.Text.R, textRun(span))
+		}
+	} else {
+		item.Text.T = comment.Text
+	}
+
+	items := sc.part.CommentList.Items
+	i, found := slices.BinarySearchFunc(items, ref, compareRefs)
+	if found {
+		items[i] = item
+	} else {
+		sc.part.CommentList.Items = slices.Insert(items, i, item)
+	}
+
+	shape := noteShape{visible: comment.Visible, width: comment.Width, height: comment.Height}
+	if shape.width <= 0 {
+		shape.width = defaultNoteWidth
+	}
+	if shape.height <= 0 {
+		shape.height = defaultNoteHeight
+	}
+	sc.shapes[ref] = shape
+	sc.dirty = true
+	return nil
+}
+
+func (e *commentProcessor) getComment(sheet, axis string) (*document.Comment, error) {
+	ref, err := commentRef(axis)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := e.sheetComments(sheet, false)
+	if err != nil || sc == nil {
+		return nil, err
+	}
+	i, found := slices.BinarySearchFunc(sc.part.CommentList.Items, ref, compareRefs)
+	if !found {
+		return nil, nil
+	}
+	comment := sc.comment(sc.part.CommentList.Items[i])
+	return &comment, nil
+}
+
+func (e *commentProcessor) removeComment(sheet, axis string) error {
+	ref, err := commentRef(axis)
+	if err != nil {
+		return err
+	}
+	sc, err := e.sheetComments(sheet, false)
+	if err != nil || sc == nil {
+		return err
+	}
+	i, found := slices.BinarySearchFunc(sc.part.CommentList.Items, ref, compareRefs)
+	if !found {
+		return nil
+	}
+	sc.part.CommentList.Items = slices.Delete(sc.part.CommentList.Items, i, i+1)
+	delete(sc.shapes, ref)
+	sc.dirty = true
+	return nil
+}
+
+func (e *commentProcessor) getComments(sheet string) ([]document.Comment, error) {
+	sc, err := e.sheetComments(sheet, false)
+	if err != nil || sc == nil {
+		return nil, err
+	}
+	comments := make([]document.Comment, 0, len(sc.part.CommentList.Items))
+	for _, item := range sc.part.CommentList.Items {
+		comments = append(comments, sc.comment(item))
+	}
+	return comments, nil
+}
+
+// sheetComments returns the comments of a sheet, decoding them from the source package on first access.
+// When the sheet has no comments it returns nil, or attaches an empty comments part if create is set.
+func (e *state) sheetComments(sheet string, create bool) (*sheetComments, error) {
+	if sc, ok := e.comments[sheet]; ok {
+		if sc.path == "" && create {
+			e.attachComments(sheet, sc)
+		}
+		return sc, nil
+	}
+	if _, ok := e.worksheet(sheet); !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+
+	sc := &sheetComments{shapes: make(map[string]noteShape)}
+	if rels := e.sheetRels[sheet]; rels != nil {
+		base := e.sheetPath(sheet)
+		for _, rel := range rels.Rels {
+			switch {
+			case rel.Type == commentsRelType:
+				sc.path = resolveTarget(base, rel.Target)
+			case rel.Type == vmlDrawingRelType:
+				sc.vmlPath = resolveTarget(base, rel.Target)
+			}
+		}
+	}
+
+	if sc.path == "" {
+		if !create {
+			return nil, nil
+		}
+		if sc.vmlPath != "" {
+			// The drawing may already hold form controls that must be kept.
+			e.loadNoteShapes(sc)
+		}
+		sc.part = &xmlstructs.Comments{}
+		e.attachComments(sheet, sc)
+		e.comments[sheet] = sc
+		return sc, nil
+	}
+
+	var part xmlstructs.Comments
+	if err := e.loadXML(sc.path, &part); err != nil {
+		return nil, fmt.Errorf("load comments %s: %w", sc.path, err)
+	}
+	slices.SortStableFunc(part.CommentList.Items, func(a, b xmlstructs.Comment) int {
+		return compareRefs(a, b.Ref)
+	})
+	sc.part = &part
+	for _, item := range part.CommentList.Items {
+		sc.shapes[item.Ref] = noteShape{width: defaultNoteWidth, height: defaultNoteHeight}
+	}
+	if sc.vmlPath != "" {
+		// Box settings are cosmetic, so an unreadable drawing leaves the defaults in place.
+		e.loadNoteShapes(sc)
+	}
+	e.comments[sheet] = sc
+	return sc, nil
+}
+
+// attachComments assigns parts to a new comments collection and links them to the sheet.
+func (e *state) attachComments(sheet string, sc *sheetComments) {
+	ws, _ := e.worksheet(sheet)
+	if e.sheetRels[sheet] == nil {
+		e.sheetRels[sheet] = &xmlstructs.Relationships{}
+	}
+	sRels := e.sheetRels[sheet]
+
+	sc.path = e.nextPartPath("xl/comments%d.xml")
+	sRels.AddRelationship(commentsRelType, "../"+path.Base(sc.path))
+	if sc.vmlPath == "" {
+		sc.vmlPath = e.nextPartPath("xl/drawings/vmlDrawing%d.vml")
+		rID := sRels.AddRelationship(vmlDrawingRelType, "../drawings/"+path.Base(sc.vmlPath))
+		ws.LegacyDrawing = &xmlstructs.WsDrawing{RID: rID}
+	} else if ws.LegacyDrawing == nil {
+		for _, rel := range sRels.Rels {
+			if rel.Type == vmlDrawingRelType {
+				ws.LegacyDrawing = &xmlstructs.WsDrawing{RID: rel.ID}
+			}
+		}
+	}
+	sc.dirty = true
+}
+
+// detachComments unlinks an empty comments collection from its sheet so that its parts are dropped on save.
+func (e *state) detachComments(sheet string, sc *sheetComments) {
+	// A drawing holding other shapes stays, without the notes.
+	keepVML := len(sc.others) > 0
+	if rels := e.sheetRels[sheet]; rels != nil {
+		for _, rel := range slices.Clone(rels.Rels) {
+			if rel.Type == commentsRelType || (rel.Type == vmlDrawingRelType && !keepVML) {
+				rels.RemoveRelationship(rel.ID)
+			}
+		}
+	}
+	e.contentTypes.RemoveOverride("/" + sc.path)
+	sc.removed = append(sc.removed, sc.path)
+	sc.path = ""
+	if keepVML {
+		return
+	}
+	if ws, ok := e.worksheet(sheet); ok {
+		ws.LegacyDrawing = nil
+	}
+	sc.removed = append(sc.removed, sc.vmlPath)
+	sc.vmlPath = ""
+}
+
+// loadNoteShapes reads the visibility and size of note boxes from the sheet's
+// VML drawing, and keeps the source of its other shapes to write them back.
+func (e *state) loadNoteShapes(sc *sheetComments) {
+	data, err := e.partData(sc.vmlPath)
+	if err != nil {
+		return
+	}
+
+	// VML written by Excel is not always well-formed XML.
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	sc.otherID = make(map[int]bool)
+	depth := 0
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth != 1 || t.Name.Space != vmlNamespace {
+				depth++
+				continue
+			}
+			if t.Name.Local == "shapetype" && attrValue(t, "id") == noteShapeType {
+				dec.Skip()
+				continue
+			}
+			var shape xmlstructs.VMLShape
+			if t.Name.Local == "shape" {
+				err = dec.DecodeElement(&shape, &t)
+			} else {
+				err = dec.Skip()
+			}
+			if err != nil {
+				return
+			}
+			if cd := shape.ClientData; cd != nil && cd.ObjectType == "Note" {
+				sc.readNoteShape(shape)
+				continue
+			}
+			sc.others = append(sc.others, data[start:dec.InputOffset()])
+			for _, name := range []string{"id", "spid"} {
+				if n, err := strconv.Atoi(strings.TrimPrefix(attrValue(t, name), "_x0000_s")); err == nil {
+					sc.otherID[n] = true
+				}
+			}
+		}
+	}
+}
+
+// readNoteShape reads the visibility and size of a note box.
+func (sc *sheetComments) readNoteShape(shape xmlstructs.VMLShape) {
+	cd := shape.ClientData
+	ref := numToCol(cd.Column+1) + strconv.Itoa(cd.Row+1)
+	if _, ok := sc.shapes[ref]; !ok {
+		return
+	}
+	ns := noteShape{visible: cd.Visible != nil, width: defaultNoteWidth, height: defaultNoteHeight}
+	for decl := range strings.SplitSeq(shape.Style, ";") {
+		key, value, _ := strings.Cut(decl, ":")
+		switch strings.TrimSpace(key) {
+		case "width":
+			if v, ok := parseVMLLength(value); ok {
+				ns.width = v
+			}
+		case "height":
+			if v, ok := parseVMLLength(value); ok {
+				ns.height = v
+			}
+		}
+	}
+	sc.shapes[ref] = ns
+}
+
+// attrValue returns the value of the attribute of an element with the given local name.
+func attrValue(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// comment converts a stored comment to its document form.
+func (sc *sheetComments) comment(item xmlstructs.Comment) document.Comment {
+	c := document.Comment{Cell: item.Ref}
+	if item.AuthorID >= 0 && item.AuthorID < len(sc.part.Authors.Items) {
+		c.Author = sc.part.Authors.Items[item.AuthorID]
+	}
+	var sb strings.Builder
+	sb.WriteString(item.Text.T)
+	for _, run := range item.Text.R {
+		c.Runs = append(c.Runs, textSpan(run))
+		sb.WriteString(run.T)
+	}
+	c.Text = sb.String()
+	shape := sc.shapes[item.Ref]
+	c.Visible, c.Width, c.Height = shape.visible, shape.width, shape.height
+	return c
+}
+
+// renderVML writes the legacy drawing that displays the note boxes.
+func (sc *sheetComments) renderVML() []byte {
+	// Shape IDs are allocated in blocks of 1024 per drawing, keyed by the idmap.
+	idmap := 1
+	if n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path.Base(sc.vmlPath), "vmlDrawing"), ".vml")); err == nil && n > 0 {
+		idmap = n
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel">`)
+	fmt.Fprintf(&sb, `<o:shapelayout v:ext="edit"><o:idmap v:ext="edit" data="%d"/></o:shapelayout>`, idmap)
+	sb.WriteString(`<v:shapetype id="_x0000_t202" coordsize="21600,21600" o:spt="202" path="m,l,21600r21600,l21600,xe">`)
+	sb.WriteString(`<v:stroke joinstyle="miter"/><v:path gradientshapeok="t" o:connecttype="rect"/></v:shapetype>`)
+	for _, other := range sc.others {
+		sb.Write(other)
+	}
+	id := idmap * 1024
+	for i, item := range sc.part.CommentList.Items {
+		// Notes take the IDs the kept shapes do not use, as controls are bound to theirs.
+		id++
+		for sc.otherID[id] {
+			id++
+		}
+		col, row := axisPosition(item.Ref)
+		shape := sc.shapes[item.Ref]
+		anchor := noteAnchor(col, row, shape.width, shape.height)
+		visibility := "hidden"
+		if shape.visible {
+			visibility = "visible"
+		}
+		fmt.Fprintf(&sb, `<v:shape id="_x0000_s%d" type="#_x0000_t202" style="position:absolute;margin-left:%spt;margin-top:%spt;width:%spt;height:%spt;z-index:%d;visibility:%s" fillcolor="#ffffe1" o:insetmode="auto">`,
+			id, formatFloat(float64(anchor[0]*64+anchor[1])*0.75), formatFloat(float64(anchor[2]*20+anchor[3])*0.75),
+			formatFloat(shape.width), formatFloat(shape.height), i+1, visibility)
+		sb.WriteString(`<v:fill color2="#ffffe1"/><v:shadow on="t" color="black" obscured="t"/><v:path o:connecttype="none"/>`)
+		sb.WriteString(`<v:textbox style="mso-direction-alt:auto"><div style="text-align:left"></div></v:textbox>`)
+		sb.WriteString(`<x:ClientData ObjectType="Note"><x:MoveWithCells/><x:SizeWithCells/>`)
+		fmt.Fprintf(&sb, `<x:Anchor>%d, %d, %d, %d, %d, %d, %d, %d</x:Anchor>`,
+			anchor[0], anchor[1], anchor[2], anchor[3], anchor[4], anchor[5], anchor[6], anchor[7])
+		fmt.Fprintf(&sb, `<x:AutoFill>False</x:AutoFill><x:Row>%d</x:Row><x:Column>%d</x:Column>`, row-1, col-1)
+		if shape.visible {
+			sb.WriteString(`<x:Visible/>`)
+		}
+		sb.WriteString(`</x:ClientData></v:shape>`)
+	}
+	sb.WriteString(`</xml>`)
+	return []byte(sb.String())
+}
+
+// noteAnchor places a note box to the upper right of its 1-based cell. The anchor lists the
+// left column, x offset, top row, y offset, right column, x offset, bottom row and y offset,
+// assuming default column widths of 64px and row heights of 20px.
+func noteAnchor(col, row int, width, height float64) [8]int {
+	left, leftOff := col, 15
+	top, topOff := max(row-2, 0), 10
+	if row == 1 {
+		topOff = 2
+	}
+	right := leftOff + int(width*4/3)
+	bottom := topOff + int(height*4/3)
+	return [8]int{left, leftOff, top, topOff, left + right/64, right % 64, top + bottom/20, bottom % 20}
+}
+
+// parseVMLLength parses a CSS length such as "108pt" or "144px" into points.
+func parseVMLLength(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "pt"):
+		s = strings.TrimSuffix(s, "pt")
+	case strings.HasSuffix(s, "px"):
+		s, scale = strings.TrimSuffix(s, "px"), 0.75
+	case strings.HasSuffix(s, "in"):
+		s, scale = strings.TrimSuffix(s, "in"), 72
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return v * scale, true
+}
+
+// commentRef normalises a cell axis to the reference form used in the comments part.
+func commentRef(axis string) (string, error) {
+	col, row := axisPosition(strings.ReplaceAll(axis, "$", ""))
+	if col < 1 || row < 1 {
+		return "", fmt.Errorf("invalid axis: %s", axis)
+	}
+	return numToCol(col) + strconv.Itoa(row), nil
+}
+
+// compareRefs orders comments by row and then column.
+func compareRefs(item xmlstructs.Comment, ref string) int {
+	c1, r1 := axisPosition(item.Ref)
+	c2, r2 := axisPosition(ref)
+	if r1 != r2 {
+		return r1 - r2
+	}
+	return c1 - c2
+}
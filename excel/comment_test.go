@@ -0,0 +1,244 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func TestComments_RoundTrip(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	doc.SetMetadata(document.Metadata{Author: "Reviewer"})
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("B2").Set("value").Comment("Check this")
+	sheet.Cell("A1").SetComment(document.Comment{
+		Author: "Alice",
+		Runs: []document.TextSpan{
+			{Text: "Alice:", Style: document.CellStyle{Bold: true}},
+			{Text: " looks good"},
+		},
+		Visible: true,
+		Width:   200,
+		Height:  80,
+	})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("SetComment failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	for _, name := range []string{"xl/comments1.xml", "xl/drawings/vmlDrawing1.vml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("Expected part %s in package", name)
+		}
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], "spreadsheetml.comments+xml") {
+		t.Error("Expected comments content type override")
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], "<legacyDrawing") {
+		t.Error("Expected legacyDrawing element in worksheet")
+	}
+
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	comments, err := s.Comments()
+	if err != nil {
+		t.Fatalf("Comments failed: %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(comments))
+	}
+	first := comments[0]
+	if first.Cell != "A1" || first.Author != "Alice" || first.Text != "Alice: looks good" {
+		t.Errorf("Unexpected first comment: %+v", first)
+	}
+	if len(first.Runs) != 2 || !first.Runs[0].Style.Bold {
+		t.Errorf("Expected rich text runs to be preserved, got %+v", first.Runs)
+	}
+	if !first.Visible || first.Width != 200 || first.Height != 80 {
+		t.Errorf("Expected visible 200x80 box, got visible=%v %vx%v", first.Visible, first.Width, first.Height)
+	}
+	if second := comments[1]; second.Cell != "B2" || second.Author != "Reviewer" || second.Text != "Check this" || second.Visible {
+		t.Errorf("Unexpected second comment: %+v", second)
+	}
+
+	// Edit one comment and remove the other.
+	s.Cell("b2").Comment("Fixed")
+	s.Cell("A1").RemoveComment()
+	if c, _ := s.Cell("A1").GetComment(); c != nil {
+		t.Errorf("Expected A1 comment to be removed, got %+v", c)
+	}
+	buf.Reset()
+	if err := reopened.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if parts := zipParts(t, buf.Bytes()); !strings.Contains(parts["xl/worksheets/sheet1.xml"], `<legacyDrawing r:id="rId`) {
+		t.Error("Expected legacyDrawing to keep its relationship ID after reopening")
+	}
+
+	final := NewDocument().(*Document)
+	defer final.Close()
+	if err := final.Open(ctx, &buf); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	fs, _ := final.Sheet("Sheet1")
+	c, err := fs.Cell("B2").GetComment()
+	if err != nil || c == nil || c.Text != "Fixed" {
+		t.Errorf("Expected edited comment, got %+v (err %v)", c, err)
+	}
+	if comments, _ := fs.Comments(); len(comments) != 1 {
+		t.Errorf("Expected 1 comment after removal, got %d", len(comments))
+	}
+}
+
+func TestComments_RemoveLastDropsParts(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("C3").Comment("temporary")
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, &buf); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	if err := s.Cell("C3").RemoveComment().Err(); err != nil {
+		t.Fatalf("RemoveComment failed: %v", err)
+	}
+	var out bytes.Buffer
+	if err := reopened.Save(ctx, &out); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, out.Bytes())
+	if _, ok := parts["xl/comments1.xml"]; ok {
+		t.Error("Expected comments part to be dropped")
+	}
+	if strings.Contains(parts["xl/worksheets/sheet1.xml"], "legacyDrawing") {
+		t.Error("Expected legacyDrawing to be removed")
+	}
+	if strings.Contains(parts["[Content_Types].xml"], "comments1.xml") {
+		t.Error("Expected comments content type override to be removed")
+	}
+}
+
+func zipParts(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader failed: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+	return parts
+}
+
+// replacePart returns a copy of a package with the content of one part replaced.
+func replacePart(t *testing.T, data []byte, name, content string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for part, body := range zipParts(t, data) {
+		if part == name {
+			body = content
+		}
+		w, err := zw.Create(part)
+		if err != nil {
+			t.Fatalf("create %s: %v", part, err)
+		}
+		io.WriteString(w, body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close failed: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestComments_KeepOtherVMLShapes(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("B2").Comment("first")
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A form control button using the ID the first note was written with.
+	const button = `<v:shapetype id="_x0000_t201" coordsize="21600,21600" o:spt="201" path="m,l,21600r21600,l21600,xe"><v:path shadowok="f" o:extrusionok="f" strokeok="f" fillok="f" o:connecttype="rect"/></v:shapetype>` +
+		`<v:shape id="_x0000_s1025" type="#_x0000_t201" style="position:absolute;width:60pt;height:20pt" o:button="t"><v:textbox><div>Run<br></div></v:textbox>` +
+		`<x:ClientData ObjectType="Button"><x:Anchor>4, 0, 1, 0, 5, 0, 2, 0</x:Anchor><x:FmlaMacro>[0]!Run</x:FmlaMacro></x:ClientData></v:shape>`
+	vml := zipParts(t, buf.Bytes())["xl/drawings/vmlDrawing1.vml"]
+	src := replacePart(t, buf.Bytes(), "xl/drawings/vmlDrawing1.vml", strings.Replace(vml, "</xml>", button+"</xml>", 1))
+
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(src)); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	if err := s.Cell("C3").Comment("second").Err(); err != nil {
+		t.Fatalf("Comment failed: %v", err)
+	}
+	var out bytes.Buffer
+	if err := reopened.Save(ctx, &out); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	vml = zipParts(t, out.Bytes())["xl/drawings/vmlDrawing1.vml"]
+	if !strings.Contains(vml, `<x:ClientData ObjectType="Button">`) || !strings.Contains(vml, `id="_x0000_t201"`) {
+		t.Errorf("button lost from the drawing: %s", vml)
+	}
+	if strings.Count(vml, `ObjectType="Note"`) != 2 || strings.Count(vml, `id="_x0000_s1025"`) != 1 || strings.Count(vml, `id="_x0000_t202"`) != 1 {
+		t.Errorf("notes should be rewritten with free shape IDs: %s", vml)
+	}
+
+	s.Cell("B2").RemoveComment()
+	s.Cell("C3").RemoveComment()
+	out.Reset()
+	if err := reopened.Save(ctx, &out); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, out.Bytes())
+	if _, ok := parts["xl/comments1.xml"]; ok {
+		t.Error("Expected comments part to be dropped")
+	}
+	if vml := parts["xl/drawings/vmlDrawing1.vml"]; !strings.Contains(vml, `ObjectType="Button"`) || strings.Contains(vml, `ObjectType="Note"`) {
+		t.Errorf("drawing should keep only the button: %s", vml)
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], "<legacyDrawing") {
+		t.Error("Expected legacyDrawing to stay for the button")
+	}
+}
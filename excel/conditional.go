@@ -0,0 +1,323 @@
+package excel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// iconSetSizes maps the built-in icon sets to their number of icons.
+var iconSetSizes = map[string]int{
+	"3Arrows": 3, "3ArrowsGray": 3, "3Flags": 3, "3TrafficLights1": 3, "3TrafficLights2": 3,
+	"3Signs": 3, "3Symbols": 3, "3Symbols2": 3,
+	"4Arrows": 4, "4ArrowsGray": 4, "4RedToBlack": 4, "4Rating": 4, "4TrafficLights": 4,
+	"5Arrows": 5, "5ArrowsGray": 5, "5Rating": 5, "5Quarters": 5,
+}
+
+// textOperators maps the text rule types to the operator Excel records with them.
+var textOperators = map[document.ConditionalType]string{
+	document.ConditionContainsText:    "containsText",
+	document.ConditionNotContainsText: "notContains",
+	document.ConditionBeginsWith:      "beginsWith",
+	document.ConditionEndsWith:        "endsWith",
+}
+
+// periodFormulas holds the formulas Excel writes for time period rules; %[1]s is
+// the top-left cell of the range.
+var periodFormulas = map[document.TimePeriod]string{
+	document.PeriodToday:     "FLOOR(%[1]s,1)=TODAY()",
+	document.PeriodYesterday: "FLOOR(%[1]s,1)=TODAY()-1",
+	document.PeriodTomorrow:  "FLOOR(%[1]s,1)=TODAY()+1",
+	document.PeriodLast7Days: "AND(TODAY()-FLOOR(%[1]s,1)<=6,FLOOR(%[1]s,1)<=TODAY())",
+	document.PeriodThisWeek:  "AND(TODAY()-ROUNDDOWN(%[1]s,0)<=WEEKDAY(TODAY())-1,ROUNDDOWN(%[1]s,0)-TODAY()<=7-WEEKDAY(TODAY()))",
+	document.PeriodLastWeek:  "AND(TODAY()-ROUNDDOWN(%[1]s,0)>=(WEEKDAY(TODAY())),TODAY()-ROUNDDOWN(%[1]s,0)<(WEEKDAY(TODAY())+7))",
+	document.PeriodNextWeek:  "AND(ROUNDDOWN(%[1]s,0)-TODAY()>(7-WEEKDAY(TODAY())),ROUNDDOWN(%[1]s,0)-TODAY()<(15-WEEKDAY(TODAY())))",
+	document.PeriodThisMonth: "AND(MONTH(%[1]s)=MONTH(TODAY()),YEAR(%[1]s)=YEAR(TODAY()))",
+	document.PeriodLastMonth: "AND(MONTH(%[1]s)=MONTH(EDATE(TODAY(),0-1)),YEAR(%[1]s)=YEAR(EDATE(TODAY(),0-1)))",
+	document.PeriodNextMonth: "AND(MONTH(%[1]s)=MONTH(EDATE(TODAY(),0+1)),YEAR(%[1]s)=YEAR(EDATE(TODAY(),0+1)))",
+}
+
+// addConditionalFormat applies rules to ref, a space separated list of ranges.
+// Rules without a priority are evaluated after the sheet's existing rules.
+func (e *styleProcessor) addConditionalFormat(sheet, ref string, rules []document.ConditionalRule) error {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("conditional format requires at least one rule")
+	}
+	anchor, err := conditionalAnchor(ref)
+	if err != nil {
+		return err
+	}
+
+	built := make([]xmlstructs.CfRule, len(rules))
+	for i, rule := range rules {
+		if built[i], err = conditionalRule(anchor, rule); err != nil {
+			return err
+		}
+	}
+
+	ws.ConditionalFormatting = append(ws.ConditionalFormatting, xmlstructs.ConditionalFormatting{Sqref: ref})
+	cf := &ws.ConditionalFormatting[len(ws.ConditionalFormatting)-1]
+	for i, rule := range rules {
+		r := built[i]
+		if dxf, ok := e.dxfFromStyle(rule.Style); ok && r.ColorScale == nil && r.DataBar == nil && r.IconSet == nil {
+			dxfID := e.styles.AddDxf(dxf)
+			r.DxfID = &dxfID
+		}
+		r.Priority = rule.Priority
+		if r.Priority > 0 {
+			shiftPriorities(ws, r.Priority)
+		} else {
+			r.Priority = nextPriority(ws)
+		}
+		cf.CfRule = append(cf.CfRule, r)
+	}
+	return nil
+}
+
+// conditionalAnchor returns the top-left cell of the first range in ref, which
+// relative references in rule formulas are based on.
+func conditionalAnchor(ref string) (string, error) {
+	fields := strings.Fields(ref)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("invalid conditional format range %q", ref)
+	}
+	area, err := formula.ParseReference(fields[0])
+	if err != nil || area.Invalid || area.Sheet != "" || (area.Col1 == 0 && area.Row1 == 0) {
+		return "", fmt.Errorf("invalid conditional format range %q", ref)
+	}
+	return formula.CellName(max(area.Col1, 1), max(area.Row1, 1)), nil
+}
+
+func conditionalRule(anchor string, rule document.ConditionalRule) (xmlstructs.CfRule, error) {
+	r := xmlstructs.CfRule{Type: string(rule.Type), StopIfTrue: boolToInt(rule.StopIfTrue)}
+	switch rule.Type {
+	case document.ConditionCellIs:
+		want := 1
+		if rule.Operator == document.OperatorBetween || rule.Operator == document.OperatorNotBetween {
+			want = 2
+		}
+		if rule.Operator == "" || len(rule.Formula) != want {
+			return r, fmt.Errorf("cellIs rule with operator %q requires %d formula(s)", rule.Operator, want)
+		}
+		r.Operator = string(rule.Operator)
+		r.Formula = trimFormulas(rule.Formula)
+	case document.ConditionExpression:
+		if len(rule.Formula) != 1 {
+			return r, fmt.Errorf("expression rule requires one formula")
+		}
+		r.Formula = trimFormulas(rule.Formula)
+	case document.ConditionColorScale:
+		values := rule.Values
+		if len(values) == 0 {
+			values = []document.ConditionalValue{
+				{Type: document.ValueMin, Color: "F8696B"},
+				{Type: document.ValueMax, Color: "63BE7B"},
+			}
+		}
+		if len(values) != 2 && len(values) != 3 {
+			return r, fmt.Errorf("color scale requires two or three values")
+		}
+		r.ColorScale = &xmlstructs.ColorScale{}
+		for _, v := range values {
+			if v.Color == "" {
+				return r, fmt.Errorf("color scale value %q has no color", v.Type)
+			}
+			r.ColorScale.Cfvo = append(r.ColorScale.Cfvo, cfvo(v))
+			r.ColorScale.Color = append(r.ColorScale.Color, xmlstructs.Color{RGB: argb(v.Color)})
+		}
+	case document.ConditionDataBar:
+		values := rule.Values
+		if len(values) == 0 {
+			values = []document.ConditionalValue{{Type: document.ValueMin}, {Type: document.ValueMax}}
+		}
+		if len(values) != 2 {
+			return r, fmt.Errorf("data bar requires two values")
+		}
+		color := rule.BarColor
+		if color == "" {
+			color = "638EC6"
+		}
+		r.DataBar = &xmlstructs.DataBar{
+			Cfvo:  []xmlstructs.Cfvo{cfvo(values[0]), cfvo(values[1])},
+			Color: xmlstructs.Color{RGB: argb(color)},
+		}
+		if rule.HideValue {
+			r.DataBar.ShowValue = new(0)
+		}
+	case document.ConditionIconSet:
+		name := rule.IconStyle
+		if name == "" {
+			name = "3TrafficLights1"
+		}
+		n, ok := iconSetSizes[name]
+		if !ok {
+			return r, fmt.Errorf("unknown icon set %q", name)
+		}
+		values := rule.Values
+		if len(values) == 0 {
+			for i := range n {
+				values = append(values, document.ConditionalValue{Type: document.ValuePercent, Value: strconv.Itoa(100 * i / n)})
+			}
+		}
+		if len(values) != n {
+			return r, fmt.Errorf("icon set %s requires %d values", name, n)
+		}
+		r.IconSet = &xmlstructs.IconSet{IconSet: name, Reverse: boolToInt(rule.Reverse)}
+		for _, v := range values {
+			r.IconSet.Cfvo = append(r.IconSet.Cfvo, cfvo(v))
+		}
+		if rule.HideValue {
+			r.IconSet.ShowValue = new(0)
+		}
+	case document.ConditionTop:
+		r.Rank = rule.Rank
+		if r.Rank <= 0 {
+			r.Rank = 10
+		}
+		r.Percent = boolToInt(rule.Percent)
+		r.Bottom = boolToInt(rule.Bottom)
+	case document.ConditionAverage:
+		if rule.BelowAverage {
+			r.AboveAverage = new(0)
+		}
+		r.EqualAverage = boolToInt(rule.EqualAverage)
+		r.StdDev = rule.StdDev
+	case document.ConditionDuplicate, document.ConditionUnique:
+	case document.ConditionContainsText, document.ConditionNotContainsText,
+		document.ConditionBeginsWith, document.ConditionEndsWith:
+		if rule.Text == "" {
+			return r, fmt.Errorf("%s rule requires text", rule.Type)
+		}
+		r.Operator = textOperators[rule.Type]
+		r.Text = rule.Text
+		r.Formula = []string{textFormula(rule.Type, anchor, rule.Text)}
+	case document.ConditionBlanks:
+		r.Formula = []string{"LEN(TRIM(" + anchor + "))=0"}
+	case document.ConditionNoBlanks:
+		r.Formula = []string{"LEN(TRIM(" + anchor + "))>0"}
+	case document.ConditionErrors:
+		r.Formula = []string{"ISERROR(" + anchor + ")"}
+	case document.ConditionNoErrors:
+		r.Formula = []string{"NOT(ISERROR(" + anchor + "))"}
+	case document.ConditionTimePeriod:
+		f, ok := periodFormulas[rule.Period]
+		if !ok {
+			return r, fmt.Errorf("unknown time period %q", rule.Period)
+		}
+		r.TimePeriod = string(rule.Period)
+		r.Formula = []string{fmt.Sprintf(f, anchor)}
+	default:
+		return r, fmt.Errorf("unknown conditional rule type %q", rule.Type)
+	}
+	return r, nil
+}
+
+// textFormula returns the formula Excel stores alongside a text rule.
+func textFormula(kind document.ConditionalType, anchor, text string) string {
+	quoted := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+	switch kind {
+	case document.ConditionNotContainsText:
+		return fmt.Sprintf("ISERROR(SEARCH(%s,%s))", quoted, anchor)
+	case document.ConditionBeginsWith:
+		return fmt.Sprintf("LEFT(%s,LEN(%s))=%s", anchor, quoted, quoted)
+	case document.ConditionEndsWith:
+		return fmt.Sprintf("RIGHT(%s,LEN(%s))=%s", anchor, quoted, quoted)
+	default:
+		return fmt.Sprintf("NOT(ISERROR(SEARCH(%s,%s)))", quoted, anchor)
+	}
+}
+
+func trimFormulas(formulas []string) []string {
+	out := make([]string, len(formulas))
+	for i, f := range formulas {
+		out[i] = strings.TrimPrefix(f, "=")
+	}
+	return out
+}
+
+func cfvo(v document.ConditionalValue) xmlstructs.Cfvo {
+	c := xmlstructs.Cfvo{Type: string(v.Type)}
+	if v.Type != document.ValueMin && v.Type != document.ValueMax {
+		c.Val = strings.TrimPrefix(v.Value, "=")
+	}
+	return c
+}
+
+// argb expands a hexadecimal RGB color to the ARGB form used by SpreadsheetML.
+func argb(color string) string {
+	color = strings.ToUpper(strings.TrimPrefix(color, "#"))
+	if len(color) == 6 {
+		return "FF" + color
+	}
+	return color
+}
+
+// shiftPriorities moves the sheet's rules at priority p or later down one place.
+func shiftPriorities(ws *xmlstructs.Worksheet, p int) {
+	for i := range ws.ConditionalFormatting {
+		for j := range ws.ConditionalFormatting[i].CfRule {
+			if r := &ws.ConditionalFormatting[i].CfRule[j]; r.Priority >= p {
+				r.Priority++
+			}
+		}
+	}
+}
+
+func nextPriority(ws *xmlstructs.Worksheet) int {
+	next := 1
+	for _, cf := range ws.ConditionalFormatting {
+		for _, r := range cf.CfRule {
+			next = max(next, r.Priority+1)
+		}
+	}
+	return next
+}
+
+// dxfFromStyle builds the differential format of a rule, reporting false when
+// style changes nothing.
+func (e *styleProcessor) dxfFromStyle(style document.CellStyle) (xmlstructs.Dxf, bool) {
+	var dxf xmlstructs.Dxf
+	if style.Bold || style.Italic || style.Color != "" {
+		dxf.Font = &xmlstructs.Font{}
+		if style.Bold {
+			dxf.Font.Bold = new(struct{}{})
+		}
+		if style.Italic {
+			dxf.Font.Italic = new(struct{}{})
+		}
+		if style.Color != "" {
+			dxf.Font.Color = &xmlstructs.Color{RGB: argb(style.Color)}
+		}
+	}
+	if style.NumberFormat != "" {
+		dxf.NumFmt = &xmlstructs.NumFmt{NumFmtID: e.getNumFmtID(style.NumberFormat), FormatCode: style.NumberFormat}
+	}
+	if style.Background != "" {
+		color := &xmlstructs.Color{RGB: argb(style.Background)}
+		dxf.Fill = &xmlstructs.Fill{PatternFill: &xmlstructs.PatternFill{PatternType: "solid", FgColor: color, BgColor: color}}
+	}
+	if style.Horizontal != "" || style.Vertical != "" || style.WrapText {
+		dxf.Alignment = &xmlstructs.Alignment{
+			Horizontal: style.Horizontal,
+			Vertical:   style.Vertical,
+			WrapText:   boolToInt(style.WrapText),
+		}
+	}
+	if border, ok := borderFromStyle(style); ok {
+		for _, edge := range []*xmlstructs.BorderEdge{&border.Left, &border.Right, &border.Top, &border.Bottom} {
+			if edge.Color != nil {
+				edge.Color = &xmlstructs.Color{RGB: argb(edge.Color.RGB)}
+			}
+		}
+		dxf.Border = &border
+	}
+	return dxf, dxf != xmlstructs.Dxf{}
+}
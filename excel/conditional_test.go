@@ -0,0 +1,140 @@
+package excel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func TestAddConditionalFormat_RuleTypes(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.SetConditionalFormatting("A1:A10", document.CellStyle{Bold: true, Background: "FFC7CE"})
+	sheet.AddConditionalFormat("B2:B20 D2:D20",
+		document.ConditionalRule{Type: document.ConditionExpression, Formula: []string{"=$C2>100"}, StopIfTrue: true,
+			Style: document.CellStyle{Italic: true, NumberFormat: "0.0%", Border: true, BorderColor: "c00000"}},
+		document.ConditionalRule{Type: document.ConditionCellIs, Operator: document.OperatorBetween, Formula: []string{"1", "5"}},
+		document.ConditionalRule{Type: document.ConditionColorScale, Values: []document.ConditionalValue{
+			{Type: document.ValueMin, Color: "#F8696B"},
+			{Type: document.ValuePercentile, Value: "50", Color: "FFEB84"},
+			{Type: document.ValueMax, Color: "63BE7B"},
+		}},
+		document.ConditionalRule{Type: document.ConditionDataBar, HideValue: true},
+		document.ConditionalRule{Type: document.ConditionIconSet, IconStyle: "4Arrows", Reverse: true},
+		document.ConditionalRule{Type: document.ConditionTop, Rank: 3, Bottom: true, Percent: true},
+		document.ConditionalRule{Type: document.ConditionAverage, BelowAverage: true},
+		document.ConditionalRule{Type: document.ConditionDuplicate, Style: document.CellStyle{Color: "9C0006"}},
+		document.ConditionalRule{Type: document.ConditionBeginsWith, Text: `a"b`},
+		document.ConditionalRule{Type: document.ConditionTimePeriod, Period: document.PeriodLast7Days},
+	)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddConditionalFormat failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	ws := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<cfRule type="cellIs" dxfId="0" priority="1" operator="greaterThan"><formula>0</formula></cfRule>`,
+		`<conditionalFormatting sqref="B2:B20 D2:D20">`,
+		`<cfRule type="expression" dxfId="1" priority="2" stopIfTrue="1"><formula>$C2&gt;100</formula></cfRule>`,
+		`<cfRule type="cellIs" priority="3" operator="between"><formula>1</formula><formula>5</formula></cfRule>`,
+		`<colorScale><cfvo type="min"></cfvo><cfvo type="percentile" val="50"></cfvo><cfvo type="max"></cfvo><color rgb="FFF8696B"></color>`,
+		`<dataBar showValue="0"><cfvo type="min"></cfvo><cfvo type="max"></cfvo><color rgb="FF638EC6"></color></dataBar>`,
+		`<iconSet iconSet="4Arrows" reverse="1"><cfvo type="percent" val="0"></cfvo><cfvo type="percent" val="25"></cfvo>`,
+		`<cfRule type="top10" priority="7" percent="1" bottom="1" rank="3">`,
+		`<cfRule type="aboveAverage" priority="8" aboveAverage="0">`,
+		`<cfRule type="duplicateValues" dxfId="2" priority="9">`,
+		`operator="beginsWith" text="a&#34;b"><formula>LEFT(B2,LEN(&#34;a&#34;&#34;b&#34;))=&#34;a&#34;&#34;b&#34;</formula>`,
+		`timePeriod="last7Days"><formula>AND(TODAY()-FLOOR(B2,1)&lt;=6,FLOOR(B2,1)&lt;=TODAY())</formula>`,
+	} {
+		if !strings.Contains(ws, want) {
+			t.Errorf("Expected %s in worksheet", want)
+		}
+	}
+
+	styles := parts["xl/styles.xml"]
+	for _, want := range []string{
+		`<dxfs count="3"><dxf><font><b></b></font><fill>`,
+		`<fill><patternFill patternType="solid"><fgColor rgb="FFFFC7CE"></fgColor><bgColor rgb="FFFFC7CE"></bgColor></patternFill></fill></dxf>`,
+		`<dxf><font><i></i></font><numFmt numFmtId="164" formatCode="0.0%"></numFmt><border><left style="thin"><color rgb="FFC00000"></color></left>`,
+		`<dxf><font><color rgb="FF9C0006"></color></font></dxf>`,
+	} {
+		if !strings.Contains(styles, want) {
+			t.Errorf("Expected %s in styles", want)
+		}
+	}
+
+	// Differential formats and rules must survive a round trip.
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	buf.Reset()
+	if err := reopened.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts = zipParts(t, buf.Bytes())
+	if !strings.Contains(parts["xl/styles.xml"], `<dxfs count="3">`) {
+		t.Error("Expected the differential formats to be kept on save")
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], `<iconSet iconSet="4Arrows" reverse="1">`) {
+		t.Error("Expected the icon set rule to be kept on save")
+	}
+}
+
+func TestAddConditionalFormat_Priority(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.AddConditionalFormat("A1:A5", document.ConditionalRule{Type: document.ConditionUnique})
+	sheet.AddConditionalFormat("B1:B5", document.ConditionalRule{Type: document.ConditionBlanks})
+	sheet.AddConditionalFormat("C1:C5", document.ConditionalRule{Type: document.ConditionErrors, Priority: 1})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddConditionalFormat failed: %v", err)
+	}
+
+	ws, _ := doc.worksheet("Sheet1")
+	want := map[string]int{"A1:A5": 2, "B1:B5": 3, "C1:C5": 1}
+	for _, cf := range ws.ConditionalFormatting {
+		if got := cf.CfRule[0].Priority; got != want[cf.Sqref] {
+			t.Errorf("%s: expected priority %d, got %d", cf.Sqref, want[cf.Sqref], got)
+		}
+	}
+	if f := ws.ConditionalFormatting[1].CfRule[0].Formula[0]; f != "LEN(TRIM(B1))=0" {
+		t.Errorf("Unexpected blanks formula %q", f)
+	}
+
+	tests := []struct {
+		name string
+		ref  string
+		rule document.ConditionalRule
+	}{
+		{"unknown type", "A1", document.ConditionalRule{Type: "sparkle"}},
+		{"between with one operand", "A1", document.ConditionalRule{Type: document.ConditionCellIs, Operator: document.OperatorBetween, Formula: []string{"1"}}},
+		{"color scale without color", "A1", document.ConditionalRule{Type: document.ConditionColorScale, Values: []document.ConditionalValue{{Type: document.ValueMin}, {Type: document.ValueMax}}}},
+		{"icon count", "A1", document.ConditionalRule{Type: document.ConditionIconSet, IconStyle: "3Arrows", Values: []document.ConditionalValue{{Type: document.ValueMin}}}},
+		{"unknown icon set", "A1", document.ConditionalRule{Type: document.ConditionIconSet, IconStyle: "7Stars"}},
+		{"text rule without text", "A1", document.ConditionalRule{Type: document.ConditionContainsText}},
+		{"unknown period", "A1", document.ConditionalRule{Type: document.ConditionTimePeriod, Period: "someday"}},
+		{"bad range", "Other!A1", document.ConditionalRule{Type: document.ConditionUnique}},
+	}
+	for _, tt := range tests {
+		s, _ := doc.Sheet("Sheet1")
+		if err := s.AddConditionalFormat(tt.ref, tt.rule).Err(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
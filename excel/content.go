@@ -1,6 +1,7 @@
 package excel
 
 import (
+	"context"
 	"strings"
 
 	"github.com/gsoultan/thoth/document"
@@ -9,19 +10,29 @@ import (
 // content handles reading and searching document content.
 type content struct{ *state }
 
-// ReadContent returns the text content of the document.
+// ReadContent returns the text content of the document, with cell values
+// formatted as Excel displays them.
 func (e *content) ReadContent() (string, error) {
 	buf := document.GetBuffer()
 	defer document.PutBuffer(buf)
 
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	sheets, _ := e.GetSheets()
 	for _, sheet := range sheets {
-		ws := e.sheets[sheet]
-		for _, row := range ws.SheetData.Rows {
+		if _, streamed := e.streams[sheet]; streamed || !e.hasSheet(sheet) {
+			continue
+		}
+		for row, err := range e.rawRows(ctx, sheet) {
+			if err != nil {
+				return "", err
+			}
 			for _, cell := range row.Cells {
-				val := e.resolveValue(cell)
-				if val != "" {
-					buf.WriteString(val)
+				if value := e.formattedValue(cell); value != "" {
+					buf.WriteString(value)
 					buf.WriteString(" ")
 				}
 			}
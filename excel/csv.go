@@ -0,0 +1,359 @@
+package excel
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// csvProcessor imports and exports sheets as delimited text.
+type csvProcessor struct{ *state }
+
+const utf8BOM = "\xEF\xBB\xBF"
+
+// Number formats of dates inferred from CSV fields.
+const (
+	csvDateFormat     = "yyyy-mm-dd"
+	csvDateTimeFormat = "yyyy-mm-dd hh:mm:ss"
+)
+
+// csvNumberPattern matches the numbers inferred from CSV fields. Leading zeros,
+// as in postal codes and identifiers, keep a field as text.
+var csvNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+var defaultCSVDateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339}
+
+// importCSV writes the records read from r to sheet. Rows below the sheet's
+// existing rows are appended directly, so large files import in linear time.
+func (e *csvProcessor) importCSV(ctx context.Context, sheet string, r io.Reader, opts document.CSVOptions) error {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
+	cell := opts.Cell
+	if cell == "" {
+		cell = "A1"
+	}
+	col, row := axisPosition(cell)
+	if col == 0 || row == 0 {
+		return fmt.Errorf("invalid cell %q", opts.Cell)
+	}
+
+	src, err := csvSource(r, opts.Encoding)
+	if err != nil {
+		return err
+	}
+	cr := csv.NewReader(src)
+	cr.Comma = csvDelimiter(opts)
+	cr.LazyQuotes = opts.LazyQuotes
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	layouts := opts.DateLayouts
+	if len(layouts) == 0 {
+		layouts = defaultCSVDateLayouts
+	}
+	styles := make(map[string]int)
+	styleID := func(style document.CellStyle) int {
+		key := style.NumberFormat
+		if id, ok := styles[key]; ok {
+			return id
+		}
+		id := (&styleProcessor{e.state}).getStyleID(style)
+		styles[key] = id
+		return id
+	}
+	headerID := 0
+	if opts.HeaderStyle != nil {
+		headerID = (&styleProcessor{e.state}).getStyleID(*opts.HeaderStyle)
+	}
+
+	appending := len(ws.SheetData.Rows) == 0 || ws.SheetData.Rows[len(ws.SheetData.Rows)-1].R < row
+	if appending {
+		// Appended rows may move the row slice and invalidate cached cell pointers.
+		delete(e.cellCache, sheet)
+	}
+	date1904 := e.date1904()
+	e.calcDirty = true
+
+	cur, line := row-1, 0
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read CSV: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// The reader skips blank lines; keep them as empty rows.
+		start, _ := cr.FieldPos(0)
+		cur += start - line
+		end, _ := cr.FieldPos(len(record) - 1)
+		line = end + strings.Count(record[len(record)-1], "\n")
+
+		if cur > formula.MaxRows {
+			return fmt.Errorf("CSV exceeds the last row %d", formula.MaxRows)
+		}
+		if col+len(record)-1 > formula.MaxColumns {
+			return fmt.Errorf("CSV record on row %d exceeds the last column %d", cur, formula.MaxColumns)
+		}
+
+		var cells []xmlstructs.Cell
+		for i, field := range record {
+			if field == "" {
+				continue
+			}
+			var value any = field
+			style := 0
+			switch {
+			case opts.HeaderStyle != nil && cur == row:
+				style = headerID
+			case opts.InferTypes:
+				var format string
+				value, format = inferCSVValue(field, layouts, date1904)
+				if format != "" {
+					style = styleID(document.CellStyle{NumberFormat: format})
+				}
+			}
+
+			target := &xmlstructs.Cell{R: formula.CellName(col+i, cur)}
+			if !appending {
+				if target, err = e.getOrCreateCell(sheet, target.R); err != nil {
+					return err
+				}
+			}
+			if err := e.writeCellValue(target, value); err != nil {
+				return err
+			}
+			if style != 0 {
+				target.S = style
+			}
+			if appending {
+				cells = append(cells, *target)
+			}
+		}
+		if len(cells) > 0 {
+			ws.SheetData.Rows = append(ws.SheetData.Rows, xmlstructs.Row{R: cur, Cells: cells})
+		}
+	}
+}
+
+// inferCSVValue converts a field to a number, boolean or date, returning the
+// number format of dates, or the field itself when it is text.
+func inferCSVValue(field string, layouts []string, date1904 bool) (any, string) {
+	if csvNumberPattern.MatchString(field) && significantDigits(field) <= 15 {
+		if f, err := strconv.ParseFloat(field, 64); err == nil {
+			return f, ""
+		}
+	}
+	switch {
+	case strings.EqualFold(field, "true"):
+		return true, ""
+	case strings.EqualFold(field, "false"):
+		return false, ""
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, field)
+		if err != nil {
+			continue
+		}
+		format := csvDateFormat
+		if t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 {
+			format = csvDateTimeFormat
+		}
+		return formula.TimeToSerial(t, date1904), format
+	}
+	return field, ""
+}
+
+// significantDigits counts the mantissa digits of a number without leading zeros.
+// Numbers with more than 15 cannot be stored exactly and are kept as text.
+func significantDigits(number string) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(number), "e")
+	digits := strings.TrimLeft(strings.NewReplacer("-", "", ".", "").Replace(mantissa), "0")
+	return len(digits)
+}
+
+// exportCSV writes the rows of sheet to w, starting at cell A1.
+func (e *csvProcessor) exportCSV(ctx context.Context, sheet string, w io.Writer, opts document.CSVOptions) error {
+	delimiter := csvDelimiter(opts)
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || !utf8.ValidRune(delimiter) || delimiter == utf8.RuneError {
+		return fmt.Errorf("invalid CSV delimiter %q", delimiter)
+	}
+	var encode func(*bufio.Writer, string)
+	switch opts.Encoding {
+	case document.CSVEncodingUTF8, document.CSVEncodingUTF8BOM:
+		encode = func(bw *bufio.Writer, s string) { bw.WriteString(s) }
+	case document.CSVEncodingWindows1252:
+		encode = writeWindows1252
+	default:
+		return fmt.Errorf("unsupported CSV encoding %q", opts.Encoding)
+	}
+	eol := "\n"
+	if opts.UseCRLF {
+		eol = "\r\n"
+	}
+
+	bw := bufio.NewWriter(w)
+	if opts.Encoding == document.CSVEncodingUTF8BOM {
+		bw.WriteString(utf8BOM)
+	}
+	var fields []string
+	var numeric []bool
+	next := 1
+	for row, err := range e.rawRows(ctx, sheet) {
+		if err != nil {
+			return err
+		}
+		for ; next < row.R; next++ {
+			bw.WriteString(eol)
+		}
+		next = row.R + 1
+
+		fields, numeric = fields[:0], numeric[:0]
+		col := 0
+		for _, cell := range row.Cells {
+			col++
+			if cell.R != "" {
+				col = colToNum(getColumnFromAxis(cell.R))
+			}
+			for len(fields) < col-1 {
+				fields, numeric = append(fields, ""), append(numeric, false)
+			}
+			value := e.resolveValue(cell)
+			if !opts.Raw || cell.T == "b" {
+				value = e.formattedValue(cell)
+			}
+			fields = append(fields, value)
+			numeric = append(numeric, (cell.T == "" || cell.T == "n") && cell.V != "")
+		}
+		for len(fields) > 0 && fields[len(fields)-1] == "" {
+			fields, numeric = fields[:len(fields)-1], numeric[:len(numeric)-1]
+		}
+
+		for i, field := range fields {
+			if i > 0 {
+				encode(bw, string(delimiter))
+			}
+			quote := strings.ContainsRune(field, delimiter) || strings.ContainsAny(field, "\"\r\n") ||
+				strings.HasPrefix(field, " ") || strings.HasPrefix(field, "\t")
+			switch opts.Quoting {
+			case document.CSVQuoteAll:
+				quote = true
+			case document.CSVQuoteNonNumeric:
+				quote = quote || !numeric[i]
+			}
+			if quote {
+				field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+			}
+			encode(bw, field)
+		}
+		bw.WriteString(eol)
+	}
+	return bw.Flush()
+}
+
+func csvDelimiter(opts document.CSVOptions) rune {
+	if opts.Delimiter == 0 {
+		return ','
+	}
+	return opts.Delimiter
+}
+
+// csvSource decodes r to UTF-8 and skips a leading byte order mark.
+func csvSource(r io.Reader, encoding document.CSVEncoding) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	switch encoding {
+	case document.CSVEncodingUTF8, document.CSVEncodingUTF8BOM:
+		if bom, err := br.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+			br.Discard(len(utf8BOM))
+		}
+		return br, nil
+	case document.CSVEncodingWindows1252:
+		return &windows1252Reader{r: br}, nil
+	}
+	return nil, fmt.Errorf("unsupported CSV encoding %q", encoding)
+}
+
+// windows1252High maps the bytes 0x80-0x9F of Windows-1252; the other bytes
+// match their Unicode code points. Unassigned bytes map to the C1 controls.
+var windows1252High = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// windows1252Reader decodes Windows-1252 text to UTF-8.
+type windows1252Reader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (d *windows1252Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(d.pending) > 0 {
+			c := copy(p[n:], d.pending)
+			d.pending = d.pending[c:]
+			n += c
+			continue
+		}
+		if n > 0 && d.r.Buffered() == 0 {
+			break
+		}
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < 0x80 {
+			p[n] = b
+			n++
+			continue
+		}
+		r := rune(b)
+		if b < 0xA0 {
+			r = windows1252High[b-0x80]
+		}
+		d.pending = utf8.AppendRune(d.pending[:0], r)
+	}
+	return n, nil
+}
+
+// writeWindows1252 encodes s as Windows-1252, replacing unmapped characters with "?".
+func writeWindows1252(bw *bufio.Writer, s string) {
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			bw.WriteByte(byte(r))
+		default:
+			b := byte('?')
+			for i, high := range windows1252High {
+				if high == r {
+					b = byte(0x80 + i)
+					break
+				}
+			}
+			bw.WriteByte(b)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package excel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func TestImportCSV(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	input := "\xEF\xBB\xBFid,name,amount,paid,date,zip\n" +
+		"1,\"Smith, Ann\",1234.5,TRUE,2024-03-15,01234\n" +
+		"\n" +
+		"2,\"Say \"\"hi\"\"\",-2e3,false,2024-03-15 08:30:00,1234567890123456789\n"
+	sheet, _ := doc.Sheet("Import")
+	err := sheet.ImportCSV(strings.NewReader(input), document.CSVOptions{
+		InferTypes:  true,
+		HeaderStyle: &document.CellStyle{Bold: true},
+	})
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+
+	for axis, want := range map[string]string{
+		"A1": "id", "B2": "Smith, Ann", "C2": "1234.5", "D2": "1", "E2": "45366", "F2": "01234",
+		"A3": "", "B4": `Say "hi"`, "C4": "-2000", "D4": "0", "E4": "45366.354166666664", "F4": "1234567890123456789",
+	} {
+		if got, _ := sheet.GetCellValue(axis); got != want {
+			t.Errorf("Cell %s: expected %q, got %q", axis, want, got)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := sheet.ExportCSV(&out, document.CSVOptions{}); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	want := "id,name,amount,paid,date,zip\n" +
+		"1,\"Smith, Ann\",1234.5,TRUE,2024-03-15,01234\n" +
+		"\n" +
+		"2,\"Say \"\"hi\"\"\",-2000,FALSE,2024-03-15 08:30:00,1234567890123456789\n"
+	if out.String() != want {
+		t.Errorf("Expected formatted export\n%q\ngot\n%q", want, out.String())
+	}
+
+	out.Reset()
+	if err := sheet.ExportCSV(&out, document.CSVOptions{Raw: true, Delimiter: '\t', Quoting: document.CSVQuoteNonNumeric, UseCRLF: true}); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	if line := strings.Split(out.String(), "\r\n")[1]; line != "1\t\"Smith, Ann\"\t1234.5\t\"TRUE\"\t45366\t\"01234\"" {
+		t.Errorf("Unexpected raw TSV line %q", line)
+	}
+
+	multi, _ := doc.Sheet("Multi")
+	if err := multi.ImportCSV(strings.NewReader("1,\"two\nlines\"\n\n3,x\n"), document.CSVOptions{}); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	for axis, want := range map[string]string{"B1": "two\nlines", "A2": "", "A3": "3"} {
+		if got, _ := multi.GetCellValue(axis); got != want {
+			t.Errorf("Cell %s: expected %q, got %q", axis, want, got)
+		}
+	}
+}
+
+func TestCSV_Windows1252(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("A1").Set("Total")
+	sheet.Cell("B1").Set(1234.5).Style(document.CellStyle{NumberFormat: "#,##0.00"})
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	input := "caf\xe9;\x80 5\n\x93quoted\x94;\n"
+	if err := s.ImportCSV(strings.NewReader(input), document.CSVOptions{Cell: "B1", Delimiter: ';', Encoding: document.CSVEncodingWindows1252}); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	for axis, want := range map[string]string{"A1": "Total", "B1": "café", "C1": "€ 5", "B2": "“quoted”"} {
+		if got, _ := s.GetCellValue(axis); got != want {
+			t.Errorf("Cell %s: expected %q, got %q", axis, want, got)
+		}
+	}
+
+	s.Cell("A3").Set(1234.5).Style(document.CellStyle{NumberFormat: "#,##0.00"})
+	s.Cell("B3").Set("naïve ✓")
+	var out bytes.Buffer
+	if err := s.ExportCSV(&out, document.CSVOptions{Encoding: document.CSVEncodingWindows1252, Quoting: document.CSVQuoteAll}); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	want := "\"Total\",\"caf\xe9\",\"\x80 5\"\n\"\",\"\x93quoted\x94\"\n\"1,234.50\",\"na\xefve ?\"\n"
+	if out.String() != want {
+		t.Errorf("Expected export\n%q\ngot\n%q", want, out.String())
+	}
+}
+
+func TestCSV_Invalid(t *testing.T) {
+	doc := NewDocument().(*Document)
+	defer doc.Close()
+	sheet, _ := doc.Sheet("Sheet1")
+
+	if err := sheet.ImportCSV(strings.NewReader("a,\"b\n"), document.CSVOptions{}); err == nil {
+		t.Error("Expected an error for an unterminated quote")
+	}
+	if err := sheet.ImportCSV(strings.NewReader("a"), document.CSVOptions{Encoding: "latin-9"}); err == nil {
+		t.Error("Expected an error for an unsupported encoding")
+	}
+	if err := sheet.ExportCSV(&bytes.Buffer{}, document.CSVOptions{Delimiter: '"'}); err == nil {
+		t.Error("Expected an error for a quote delimiter")
+	}
+}
@@ -3,9 +3,12 @@ package excel
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/gsoultan/thoth/document"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+	"github.com/gsoultan/thoth/internal/fontmetrics"
 )
 
 // Document implements the document.Document and document.Spreadsheet interfaces.
@@ -20,7 +23,7 @@ type Document struct {
 
 // Fluent API: Sheet returns a sheet-scoped handle, auto-creating the sheet if needed.
 func (d *Document) Sheet(name string) (document.Sheet, error) {
-	if _, ok := d.sheets[name]; !ok {
+	if !d.hasSheet(name) {
 		if err := d.addSheet(name); err != nil {
 			return nil, err
 		}
@@ -28,6 +31,12 @@ func (d *Document) Sheet(name string) (document.Sheet, error) {
 	return &sheetHandle{state: d.state, ctx: d.ctx, name: name}, nil
 }
 
+// StreamSheet returns a row writer for a sheet whose rows are flushed to disk as they are written.
+// The sheet is created if needed and must not contain rows written through the Cell API.
+func (d *Document) StreamSheet(name string, opts ...document.StreamOptions) (document.StreamWriter, error) {
+	return d.streamSheet(name, opts...)
+}
+
 func (d *Document) Export(uri string) error {
 	if d.exportFunc == nil {
 		return fmt.Errorf("export function not configured")
@@ -43,7 +52,18 @@ func (d *Document) SetExportFunc(fn func(doc document.Document, uri string) erro
 	d.exportFunc = fn
 }
 
+// SetPassword sets the password that encrypts the saved workbook with ECMA-376
+// agile encryption (AES-256, SHA-512) and decrypts an encrypted workbook on Open.
+// An empty password saves the workbook unencrypted. ProtectWorkbook locks the
+// workbook structure without encrypting it.
 func (d *Document) SetPassword(password string) error {
+	d.password = password
+	return nil
+}
+
+// ProtectWorkbook locks the workbook structure with a password. Unlike
+// SetPassword it does not encrypt the content.
+func (d *Document) ProtectWorkbook(password string) error {
 	if d.workbook == nil {
 		return fmt.Errorf("workbook not initialized")
 	}
@@ -54,18 +74,135 @@ func (d *Document) SetPassword(password string) error {
 	return nil
 }
 
+// RegisterFont reads the metrics of a TrueType font file used to measure text
+// set in family, e.g. by AutoFitColumns. Register the bold face as family + " Bold".
+// Fonts that are not registered are measured with standard font widths.
+func (d *Document) RegisterFont(family, path string) error {
+	metrics, err := fontmetrics.ParseTTF(path)
+	if err != nil {
+		return fmt.Errorf("register font %s: %w", family, err)
+	}
+	d.fontMetrics[strings.ToLower(family)] = metrics
+	return nil
+}
+
 func (d *Document) SetNamedRange(name, ref string) error {
 	return d.setNamedRange(name, ref)
 }
 
+// RenameSheet renames a sheet and updates the formulas, defined names, charts and
+// pivot caches that refer to it. Handles of the sheet must be re-obtained.
+func (d *Document) RenameSheet(oldName, newName string) error {
+	return d.renameSheet(oldName, newName)
+}
+
+// DeleteSheet removes a sheet with its drawings, comments, tables and pivot tables.
+// References to the sheet become #REF!.
+func (d *Document) DeleteSheet(name string) error {
+	return d.deleteSheet(name)
+}
+
+// CopySheet appends a copy of a sheet. Copied tables are renamed and copied charts
+// refer to the copy; pivot tables are not copied.
+func (d *Document) CopySheet(source, target string) error {
+	return d.copySheet(source, target)
+}
+
+// MoveSheet moves a sheet to the zero-based tab position index.
+func (d *Document) MoveSheet(name string, index int) error {
+	return d.moveSheet(name, index)
+}
+
+// HideSheet hides a sheet, or shows it again with document.SheetVisible.
+// The last visible sheet cannot be hidden.
+func (d *Document) HideSheet(name string, visibility document.SheetVisibility) error {
+	return d.hideSheet(name, visibility)
+}
+
+// SetActiveSheet selects the sheet shown when the workbook is opened.
+func (d *Document) SetActiveSheet(name string) error {
+	return d.setActiveSheet(name)
+}
+
+// Tables lists the tables of every sheet in sheet order.
+func (d *Document) Tables() ([]document.TableInfo, error) {
+	return d.tableInfos()
+}
+
+// Images lists the pictures of every sheet in sheet order, including those of opened files.
+func (d *Document) Images() ([]document.Image, error) {
+	return d.images()
+}
+
+// DeleteImage removes a picture from a sheet. Its image part is dropped once no
+// other part of the package refers to it.
+func (d *Document) DeleteImage(sheet, name string) error {
+	return d.deleteImage(sheet, name)
+}
+
+// ExportPDF writes sheets to w as a PDF document laid out by their page setup.
+func (d *Document) ExportPDF(w io.Writer, opts document.PDFOptions) error {
+	return d.exportPDF(w, opts)
+}
+
+// ExportHTML writes sheets to w as HTML tables styled from their cell formats.
+func (d *Document) ExportHTML(w io.Writer, opts document.HTMLOptions) error {
+	return d.exportHTML(w, opts)
+}
+
+// ReplaceImage swaps the image shown by a picture, keeping its anchor and size.
+func (d *Document) ReplaceImage(sheet, name string, data []byte) error {
+	return d.replaceImage(sheet, name, data)
+}
+
+// Recalculate evaluates every formula in the workbook and stores the results as cached values.
+func (d *Document) Recalculate() error {
+	return d.recalculate()
+}
+
 // NewDocument creates a new instance of an Excel document processor.
 func NewDocument() document.Document {
-	state := &state{
-		sheets:    make(map[string]*xmlstructs.Worksheet),
-		media:     make(map[string][]byte),
-		sheetRels: make(map[string]*xmlstructs.Relationships),
-		drawings:  make(map[string]*xmlstructs.WsDr),
-		tables:    make(map[string]*xmlstructs.Table),
+	state := newState()
+	return &Document{
+		state:     state,
+		lifecycle: lifecycle{state},
+		processor: processor{
+			state:            state,
+			sheetProcessor:   sheetProcessor{state},
+			cellProcessor:    cellProcessor{state},
+			styleProcessor:   styleProcessor{state},
+			mediaProcessor:   mediaProcessor{state},
+			calcProcessor:    calcProcessor{state},
+			commentProcessor: commentProcessor{state},
+			chartProcessor:   chartProcessor{state},
+			pivotProcessor:   pivotProcessor{state},
+			tableProcessor:   tableProcessor{state},
+			pdfProcessor:     pdfProcessor{state},
+			htmlProcessor:    htmlProcessor{state},
+		},
+		metadata: metadata{state},
+		content:  content{state},
+	}
+}
+
+// newState returns the state of an empty workbook.
+func newState() *state {
+	return &state{
+		sheets:       make(map[string]*xmlstructs.Worksheet),
+		sheetPaths:   make(map[string]string),
+		media:        make(map[string][]byte),
+		sheetRels:    make(map[string]*xmlstructs.Relationships),
+		drawings:     make(map[string]*xmlstructs.WsDr),
+		tables:       make(map[string]*xmlstructs.Table),
+		charts:       make(map[string]*xmlstructs.ChartSpace),
+		pivotTables:  make(map[string]*xmlstructs.PivotTableDefinition),
+		pivotCaches:  make(map[string]*xmlstructs.PivotCacheDefinition),
+		pivotRecords: make(map[string]*xmlstructs.PivotCacheRecords),
+		streams:      make(map[string]*streamWriter),
+		comments:     make(map[string]*sheetComments),
+		patched:      make(map[string][]byte),
+		removed:      make(map[string]bool),
+		fontMetrics:  make(map[string]*fontmetrics.Metrics),
 		workbook: &xmlstructs.Workbook{
 			XMLNS_R: "http://schemas.openxmlformats.org/officeDocument/2006/relationships",
 			WorkbookPr: &xmlstructs.WorkbookPr{
@@ -101,17 +238,4 @@ func NewDocument() document.Document {
 		xfsIndex:           make(map[string]int),
 		cellCache:          make(map[string]map[string]*xmlstructs.Cell),
 	}
-	return &Document{
-		state:     state,
-		lifecycle: lifecycle{state},
-		processor: processor{
-			state:          state,
-			sheetProcessor: sheetProcessor{state},
-			cellProcessor:  cellProcessor{state},
-			styleProcessor: styleProcessor{state},
-			mediaProcessor: mediaProcessor{state},
-		},
-		metadata: metadata{state},
-		content:  content{state},
-	}
 }
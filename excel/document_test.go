@@ -70,10 +70,10 @@ func TestDocument_AdvancedFeatures(t *testing.T) {
 	ctx := t.Context()
 	doc.SetContext(ctx)
 
-	// 1. Workbook Password
-	err := doc.SetPassword("workbookpass")
+	// 1. Workbook Protection
+	err := doc.ProtectWorkbook("workbookpass")
 	if err != nil {
-		t.Fatalf("SetPassword failed: %v", err)
+		t.Fatalf("ProtectWorkbook failed: %v", err)
 	}
 
 	sheet, err := doc.Sheet("Advanced")
@@ -0,0 +1,56 @@
+package excel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/cfb"
+	"github.com/gsoultan/thoth/excel/internal/encryption"
+)
+
+// decryptPackage decrypts the package of an encrypted workbook with the
+// document password and replaces the content of tmp with it.
+func (e *state) decryptPackage(cf *cfb.Reader, tmp *os.File) error {
+	if e.password == "" {
+		return fmt.Errorf("%w: password required", document.ErrEncryptedDocument)
+	}
+	info, err := cf.Stream(encryption.InfoStream)
+	if err != nil {
+		return fmt.Errorf("%w: %v", document.ErrInvalidFormat, err)
+	}
+	data, err := cf.Stream(encryption.PackageStream)
+	if err != nil {
+		return fmt.Errorf("%w: %v", document.ErrInvalidFormat, err)
+	}
+	pkg, err := encryption.Decrypt(info, data, e.password)
+	switch {
+	case errors.Is(err, encryption.ErrPassword):
+		return document.ErrInvalidPassword
+	case err != nil:
+		return fmt.Errorf("%w: %v", document.ErrEncryptedDocument, err)
+	}
+	if err := tmp.Truncate(0); err != nil {
+		return fmt.Errorf("buffer decrypted package: %w", err)
+	}
+	if _, err := tmp.WriteAt(pkg, 0); err != nil {
+		return fmt.Errorf("buffer decrypted package: %w", err)
+	}
+	return nil
+}
+
+// writeEncrypted encrypts a saved package with the document password and
+// writes it to w as a compound file.
+func (e *state) writeEncrypted(pkg *bytes.Buffer, w io.Writer) error {
+	info, data, err := encryption.Encrypt(pkg.Bytes(), e.password)
+	if err != nil {
+		return fmt.Errorf("encrypt package: %w", err)
+	}
+	return cfb.Write(w, map[string][]byte{
+		encryption.InfoStream:    info,
+		encryption.PackageStream: data,
+	})
+}
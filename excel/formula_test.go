@@ -0,0 +1,182 @@
+package excel
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestRecalculate_FunctionLibrary(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	data, _ := doc.Sheet("Data")
+	rows := [][]any{
+		{"Item", "Qty", "Price", "Region"},
+		{"Apple", 10, 1.5, "North"},
+		{"Banana", 20, 0.25, "South"},
+		{"Cherry", 5, 4, "North"},
+		{"Date", 8, 3, "East"},
+	}
+	for r, row := range rows {
+		for c, v := range row {
+			data.Cell(numToCol(c+1) + strconv.Itoa(r+1)).Set(v)
+		}
+	}
+	data.Cell("F1").Set(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	if err := doc.SetNamedRange("Quantities", "Data!$B$2:$B$5"); err != nil {
+		t.Fatalf("SetNamedRange failed: %v", err)
+	}
+
+	calc, _ := doc.Sheet("Calc")
+	tests := []struct {
+		formula string
+		want    string
+	}{
+		{"SUM(Data!B2:B5)", "43"},
+		{"SUM(Quantities)*2", "86"},
+		{"AVERAGE(Data!B:B)", "10.75"},
+		{"SUMPRODUCT(Data!B2:B5,Data!C2:C5)", "64"},
+		{`SUMIF(Data!D2:D5,"North",Data!B2:B5)`, "15"},
+		{`COUNTIF(Data!B2:B5,">=8")`, "3"},
+		{`COUNTIFS(Data!D2:D5,"N*",Data!B2:B5,"<10")`, "1"},
+		{`IF(SUM(Data!B2:B5)>40,"big","small")`, "big"},
+		{`IFERROR(1/0,"none")`, "none"},
+		{"1/0", "#DIV/0!"},
+		{`VLOOKUP("Cherry",Data!A2:C5,3,FALSE)`, "4"},
+		{`VLOOKUP("Zed",Data!A2:C5,3,FALSE)`, "#N/A"},
+		{`XLOOKUP("Date",Data!A2:A5,Data!D2:D5)`, "East"},
+		{`XLOOKUP("Zed",Data!A2:A5,Data!D2:D5,"missing")`, "missing"},
+		{`INDEX(Data!C2:C5,MATCH("Banana",Data!A2:A5,0))`, "0.25"},
+		{"INDEX(Data!A1:D5,3,4)", "South"},
+		{"ROUND(2.675,2)", "2.68"},
+		{"-2^2", "4"},
+		{"10%+1", "1.1"},
+		{`"a"&1.5&TRUE`, "a1.5TRUE"},
+		{"AND(1,TRUE,Data!B2>5)", "1"},
+		{"DATE(2024,2,30)", "45352"},
+		{"YEAR(Data!F1)&\"-\"&MONTH(EDATE(Data!F1,1))&\"-\"&DAY(EDATE(Data!F1,1))", "2024-2-29"},
+		{"EOMONTH(Data!F1,1)-Data!F1", "29"},
+		{`TEXT(Data!F1,"yyyy-mm-dd")`, "2024-01-31"},
+		{`TEXT(1234.5,"#,##0.00")`, "1,234.50"},
+		{`UPPER(LEFT(Data!A3,3))&LEN(TRIM("  a  b "))`, "BAN3"},
+		{`SUBSTITUTE("a-b-c","-","+",2)`, "a-b+c"},
+		{`SEARCH("b?n","Banana")`, "1"},
+		{`TEXTJOIN(",",TRUE,Data!A2:A5)`, "Apple,Banana,Cherry,Date"},
+		{"NOSUCHFUNC(1)", "#NAME?"},
+		{"MissingName+1", "#NAME?"},
+	}
+	for i, tt := range tests {
+		axis := "A" + strconv.Itoa(i+1)
+		if err := calc.Cell(axis).Formula(tt.formula).Err(); err != nil {
+			t.Fatalf("Formula %q failed: %v", tt.formula, err)
+		}
+	}
+	if err := doc.Recalculate(); err != nil {
+		t.Fatalf("Recalculate failed: %v", err)
+	}
+	for i, tt := range tests {
+		got, err := calc.GetCellValue("A" + strconv.Itoa(i+1))
+		if err != nil {
+			t.Fatalf("GetCellValue failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.formula, got, tt.want)
+		}
+	}
+}
+
+func TestRecalculate_DependencyOrderAndSave(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	// Formulas are entered before the cells they depend on.
+	sheet.Cell("A3").Formula("=A2*2")
+	sheet.Cell("A2").Formula("=A1+1")
+	sheet.Cell("A1").Set(5)
+	sheet.Cell("B1").Formula("=B2")
+	sheet.Cell("B2").Formula("=B1")
+
+	if val, _ := sheet.GetCellValue("A3"); val != "12" {
+		t.Errorf("Expected A3 to be recalculated on read, got %q", val)
+	}
+	if val, _ := sheet.GetCellValue("B1"); val != "0" {
+		t.Errorf("Expected circular reference to evaluate to 0, got %q", val)
+	}
+
+	sheet.Cell("A1").Set(10)
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, &buf); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	if val, _ := s.GetCellValue("A3"); val != "22" {
+		t.Errorf("Expected cached value 22 after save, got %q", val)
+	}
+	ws, _ := reopened.worksheet("Sheet1")
+	if cell := findCell(ws, 1, 3); cell == nil || cell.F == nil || cell.F.Text != "A2*2" {
+		t.Errorf("Expected formula to be preserved, got %+v", cell)
+	}
+}
+
+func TestRecalculate_SharedFormulas(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	for i := 1; i <= 3; i++ {
+		sheet.Cell("A" + strconv.Itoa(i)).Set(i * 10)
+	}
+	sheet.Cell("B1").Formula("A1*2")
+	ws := doc.sheets["Sheet1"]
+	si := 0
+	master := findCell(ws, 2, 1)
+	master.F.T, master.F.Ref, master.F.SI = "shared", "B1:B3", &si
+	for _, axis := range []string{"B2", "B3"} {
+		cell, _ := doc.getOrCreateCell("Sheet1", axis)
+		cell.F = &xmlstructs.Formula{T: "shared", SI: &si}
+	}
+
+	if err := doc.Recalculate(); err != nil {
+		t.Fatalf("Recalculate failed: %v", err)
+	}
+	if val, _ := sheet.GetCellValue("B3"); val != "60" {
+		t.Errorf("Expected shared formula in B3 to evaluate to 60, got %q", val)
+	}
+}
+
+func TestRecalculate_KeepsCachedValueOfUnsupportedFormulas(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("A1").Set(5)
+	sheet.Cell("D1").Formula("B1*2")
+	for axis, text := range map[string]string{"B1": "_xlfn.NPV(0.1,A1)", "B2": "SUM(A1"} {
+		cell, _ := doc.getOrCreateCell("Sheet1", axis)
+		cell.F = &xmlstructs.Formula{Text: text}
+		cell.V = "4.545"
+	}
+	sheet.Cell("C1").Set(1)
+
+	for axis, want := range map[string]string{"B1": "4.545", "B2": "4.545", "D1": "9.09"} {
+		if val, _ := sheet.GetCellValue(axis); val != want {
+			t.Errorf("%s = %q, want %q", axis, val, want)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package excel
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/base64"
+	"html"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// htmlProcessor writes sheets as HTML tables.
+type htmlProcessor struct{ *state }
+
+// htmlBorders maps border styles to CSS borders.
+var htmlBorders = map[string]string{
+	"hair": "1px solid", "thin": "1px solid", "dotted": "1px dotted", "dashed": "1px dashed", "dashDot": "1px dashed", "dashDotDot": "1px dashed",
+	"medium": "2px solid", "mediumDashed": "2px dashed", "mediumDashDot": "2px dashed", "mediumDashDotDot": "2px dashed", "slantDashDot": "2px dashed",
+	"thick": "3px solid", "double": "3px double",
+}
+
+// Declarations shared by the cells of every table, and the gridlines Excel
+// draws between cells without borders.
+var (
+	htmlCellCSS     = []string{"padding:0 2px", "overflow:hidden", "white-space:pre", "vertical-align:bottom", "text-align:left", "font-weight:normal"}
+	htmlGridlineCSS = []string{"border:1px solid #D4D4D4"}
+	htmlImageCSS    = []string{"position:relative", "overflow:visible"}
+)
+
+func (e *htmlProcessor) exportHTML(w io.Writer, opts document.HTMLOptions) error {
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = e.exportedSheets()
+	}
+	images, err := (&mediaProcessor{e.state}).images()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if !opts.Fragment {
+		title := ""
+		if len(sheets) > 0 {
+			title = sheets[0]
+		}
+		bw.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) + "</title>\n")
+	}
+	if !opts.InlineStyles {
+		e.writeStyles(bw)
+	}
+	if !opts.Fragment {
+		bw.WriteString("</head>\n<body>\n")
+	}
+	for _, name := range sheets {
+		ws, err := (&sheetProcessor{e.state}).fittableSheet(name)
+		if err != nil {
+			return err
+		}
+		var sheetImages []document.Image
+		for _, img := range images {
+			if img.Sheet == name {
+				sheetImages = append(sheetImages, img)
+			}
+		}
+		e.writeSheet(bw, e.newSheetLayout(name, ws), sheetImages, opts)
+	}
+	if !opts.Fragment {
+		bw.WriteString("</body>\n</html>\n")
+	}
+	return bw.Flush()
+}
+
+// writeStyles writes the style element holding the rules of the tables and a
+// class for each cell format of the workbook.
+func (e *htmlProcessor) writeStyles(bw *bufio.Writer) {
+	bw.WriteString("<style>\n")
+	bw.WriteString("table.sheet{" + strings.Join(e.tableCSS(), ";") + "}\n")
+	bw.WriteString("table.sheet caption{text-align:left;font-weight:bold}\n")
+	bw.WriteString("table.sheet td,table.sheet th{" + strings.Join(htmlCellCSS, ";") + "}\n")
+	bw.WriteString("table.sheet.gridlines td,table.sheet.gridlines th{" + strings.Join(htmlGridlineCSS, ";") + "}\n")
+	bw.WriteString("table.sheet .n{text-align:right}\ntable.sheet .c{text-align:center}\n")
+	bw.WriteString("table.sheet .img{" + strings.Join(htmlImageCSS, ";") + "}\n")
+	bw.WriteString("table.sheet .img img{position:absolute;left:0;top:0}\n")
+	if e.styles != nil {
+		for id := 1; id < len(e.styles.CellXfs.Items); id++ {
+			if css := e.formatCSS(id); len(css) > 0 {
+				class := "x" + strconv.Itoa(id)
+				bw.WriteString("table.sheet td." + class + ",table.sheet th." + class + "{" + strings.Join(css, ";") + "}\n")
+			}
+		}
+	}
+	bw.WriteString("</style>\n")
+}
+
+// tableCSS returns the declarations of a table: collapsed borders, fixed
+// column widths and the workbook's default font.
+func (e *htmlProcessor) tableCSS() []string {
+	font := (&styleProcessor{e.state}).styleFromXf(0)
+	css := []string{"border-collapse:collapse", "table-layout:fixed"}
+	if family := cssFontFamily(font.Font); family != "" {
+		css = append(css, "font-family:"+family)
+	}
+	return append(css, "font-size:"+strconv.Itoa(cmp0(font.Size, defaultFontSize))+"pt")
+}
+
+// formatCSS returns the declarations of a cell format from the styles part:
+// its font, fill, borders and alignment.
+func (e *htmlProcessor) formatCSS(id int) []string {
+	style := (&styleProcessor{e.state}).styleFromXf(id)
+	var css []string
+	if style.Bold {
+		css = append(css, "font-weight:bold")
+	}
+	if style.Italic {
+		css = append(css, "font-style:italic")
+	}
+	if style.Size > 0 {
+		css = append(css, "font-size:"+strconv.Itoa(style.Size)+"pt")
+	}
+	if family := cssFontFamily(style.Font); family != "" {
+		css = append(css, "font-family:"+family)
+	}
+	if color := cssColor(style.Color); color != "" {
+		css = append(css, "color:"+color)
+	}
+	if color := cssColor(style.Background); color != "" {
+		css = append(css, "background-color:"+color)
+	}
+
+	if e.styles != nil && id >= 0 && id < len(e.styles.CellXfs.Items) {
+		if xf := e.styles.CellXfs.Items[id]; xf.BorderID >= 0 && xf.BorderID < len(e.styles.Borders.Items) {
+			b := e.styles.Borders.Items[xf.BorderID]
+			for _, edge := range []struct {
+				side string
+				edge xmlstructs.BorderEdge
+			}{{"left", b.Left}, {"right", b.Right}, {"top", b.Top}, {"bottom", b.Bottom}} {
+				if border, ok := htmlBorders[edge.edge.Style]; ok {
+					color := cmp.Or(cssColor(colorHex(edge.edge.Color)), "#000000")
+					css = append(css, "border-"+edge.side+":"+border+" "+color)
+				}
+			}
+		}
+	}
+
+	switch style.Horizontal {
+	case "left", "center", "right", "justify":
+		css = append(css, "text-align:"+style.Horizontal)
+	case "centerContinuous":
+		css = append(css, "text-align:center")
+	case "distributed":
+		css = append(css, "text-align:justify")
+	}
+	switch style.Vertical {
+	case "top", "bottom":
+		css = append(css, "vertical-align:"+style.Vertical)
+	case "center":
+		css = append(css, "vertical-align:middle")
+	case "justify", "distributed":
+		css = append(css, "vertical-align:top")
+	}
+	if style.Indent > 0 {
+		css = append(css, "padding-left:"+cssPoints(style.Indent*pointsPerIndent))
+	}
+	if style.WrapText {
+		css = append(css, "white-space:pre-wrap")
+	}
+	return css
+}
+
+// cssColor returns the CSS color of a style color of 6 hex digits, or of 8
+// with the alpha first, and "" for any other value, which could otherwise
+// end the style element of an uploaded workbook.
+func cssColor(c string) string {
+	if len(c) == 8 {
+		c = c[2:]
+	}
+	if len(c) != 6 || strings.Trim(c, "0123456789ABCDEFabcdef") != "" {
+		return ""
+	}
+	return "#" + c
+}
+
+// cssFontFamily returns the CSS font family of a font name, or "" when the
+// name holds anything but letters, digits, spaces and "-_.".
+func cssFontFamily(name string) string {
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.", r)
+	}) {
+		return ""
+	}
+	return `"` + name + `",sans-serif`
+}
+
+// generalAlignment returns the class aligning a cell without a horizontal
+// alignment as Excel shows it: numbers to the right, booleans and errors in
+// the center and text to the left, which needs no class.
+func (e *htmlProcessor) generalAlignment(cell *xmlstructs.Cell) string {
+	if cell == nil {
+		return ""
+	}
+	if style := (&styleProcessor{e.state}).styleFromXf(cell.S); style.Horizontal != "" && style.Horizontal != "general" {
+		return ""
+	}
+	switch cell.T {
+	case "", "n", "d":
+		if cell.V != "" || cell.F != nil {
+			return "n"
+		}
+	case "b", "e":
+		return "c"
+	}
+	return ""
+}
+
+// writeSheet writes a sheet as a table running from A1 to the last cell in
+// use, with its print title rows as the table head.
+func (e *htmlProcessor) writeSheet(bw *bufio.Writer, s *sheetLayout, images []document.Image, opts document.HTMLOptions) {
+	s.showHidden = opts.ShowHidden
+	gridlines := true
+	if views := s.ws.SheetViews; views != nil && len(views.Items) > 0 && views.Items[0].ShowGridLines != nil {
+		gridlines = *views.Items[0].ShowGridLines != 0
+	}
+
+	last, _ := s.usedRange()
+	for _, img := range images {
+		col, row := axisPosition(img.Cell)
+		last.col2, last.row2 = max(last.col2, col), max(last.row2, row)
+	}
+	rows := visible(1, last.row2, s.rowHeight)
+	cols := visible(1, last.col2, s.colWidth)
+	// Pictures anchored in hidden rows or columns are shown in the next shown
+	// cell, or the last one. Those below the rows written are left out.
+	placed := make([][2]int, len(images))
+	for k, img := range images {
+		col, row := axisPosition(img.Cell)
+		placed[k] = [2]int{nearestShown(rows, row), nearestShown(cols, col)}
+	}
+	if opts.MaxRows > 0 && len(rows) > opts.MaxRows {
+		rows = rows[:opts.MaxRows]
+	}
+	headRows := 0
+	for headRows < len(rows) && slices.Contains(s.titleRows, rows[headRows]) {
+		headRows++
+	}
+
+	width := 0.0
+	for _, col := range cols {
+		width += s.colWidth(col)
+	}
+	class := "sheet"
+	if gridlines {
+		class += " gridlines"
+	}
+	tableCSS := []string{"width:" + cssPoints(width)}
+	if opts.InlineStyles {
+		tableCSS = append(e.tableCSS(), tableCSS...)
+	}
+	bw.WriteString(`<table class="` + class + `" data-sheet="` + html.EscapeString(s.name) + `"` + styleAttr(tableCSS) + ">\n")
+	captionCSS := []string(nil)
+	if opts.InlineStyles {
+		captionCSS = []string{"text-align:left", "font-weight:bold"}
+	}
+	bw.WriteString("<caption" + styleAttr(captionCSS) + ">" + html.EscapeString(s.name) + "</caption>\n")
+	if len(cols) > 0 {
+		bw.WriteString("<colgroup>")
+		for _, col := range cols {
+			bw.WriteString(`<col style="width:` + cssPoints(s.colWidth(col)) + `">`)
+		}
+		bw.WriteString("</colgroup>\n")
+	}
+
+	merges := s.merges(rows, cols)
+	spans := make(map[[2]int]mergeSpan, len(merges.spans))
+	for _, sp := range merges.spans {
+		spans[[2]int{sp.row, sp.col}] = sp
+	}
+	pictures := make(map[[2]int][]document.Image)
+	for k, pos := range placed {
+		if pos[0] < 0 || pos[1] < 0 || pos[0] >= len(rows) {
+			continue
+		}
+		if merges.covered[pos] {
+			// A picture in a merge is shown in the cell showing the merge.
+			for _, sp := range merges.spans {
+				if pos[0] >= sp.row && pos[0] < sp.row+sp.rows && pos[1] >= sp.col && pos[1] < sp.col+sp.cols {
+					pos = [2]int{sp.row, sp.col}
+				}
+			}
+		}
+		pictures[pos] = append(pictures[pos], images[k])
+	}
+	for i, row := range rows {
+		tag := "td"
+		switch {
+		case i == 0 && headRows > 0:
+			bw.WriteString("<thead>\n")
+		case i == headRows:
+			bw.WriteString("<tbody>\n")
+		}
+		if i < headRows {
+			tag = "th"
+		}
+		bw.WriteString(`<tr style="height:` + cssPoints(s.rowHeight(row)) + `">`)
+		for j, col := range cols {
+			if merges.covered[[2]int{i, j}] {
+				continue
+			}
+			key := [2]int{col, row}
+			if anchor, ok := merges.anchors[[2]int{i, j}]; ok {
+				key = anchor
+			}
+			e.writeCell(bw, tag, s.cells[key], pictures[[2]int{i, j}], spans[[2]int{i, j}], gridlines, opts)
+		}
+		bw.WriteString("</tr>\n")
+		if i == headRows-1 {
+			bw.WriteString("</thead>\n")
+		}
+	}
+	if len(rows) > headRows {
+		bw.WriteString("</tbody>\n")
+	}
+	bw.WriteString("</table>\n")
+}
+
+// writeCell writes a cell of a table, spanning the rows and columns of its
+// merge span, with the pictures shown in it.
+func (e *htmlProcessor) writeCell(bw *bufio.Writer, tag string, cell *xmlstructs.Cell, pictures []document.Image, span mergeSpan, gridlines bool, opts document.HTMLOptions) {
+	var classes, css []string
+	if opts.InlineStyles {
+		css = append(css, htmlCellCSS...)
+		if gridlines {
+			css = append(css, htmlGridlineCSS...)
+		}
+	}
+	switch align := e.generalAlignment(cell); {
+	case align == "":
+	case !opts.InlineStyles:
+		classes = append(classes, align)
+	case align == "n":
+		css = append(css, "text-align:right")
+	default:
+		css = append(css, "text-align:center")
+	}
+	if cell != nil && cell.S > 0 {
+		if opts.InlineStyles {
+			css = append(css, e.formatCSS(cell.S)...)
+		} else {
+			classes = append(classes, "x"+strconv.Itoa(cell.S))
+		}
+	}
+	if len(pictures) > 0 {
+		if opts.InlineStyles {
+			css = append(css, htmlImageCSS...)
+		} else {
+			classes = append(classes, "img")
+		}
+	}
+
+	bw.WriteString("<" + tag)
+	if len(classes) > 0 {
+		bw.WriteString(` class="` + strings.Join(classes, " ") + `"`)
+	}
+	if span.cols > 1 {
+		bw.WriteString(` colspan="` + strconv.Itoa(span.cols) + `"`)
+	}
+	if span.rows > 1 {
+		bw.WriteString(` rowspan="` + strconv.Itoa(span.rows) + `"`)
+	}
+	bw.WriteString(styleAttr(css) + ">")
+	if cell != nil {
+		bw.WriteString(html.EscapeString(e.formattedValue(*cell)))
+	}
+	for _, img := range pictures {
+		imgCSS := []string{"width:" + cssPoints(img.Width), "height:" + cssPoints(img.Height)}
+		if opts.InlineStyles {
+			imgCSS = append([]string{"position:absolute", "left:0", "top:0"}, imgCSS...)
+		}
+		bw.WriteString(`<img src="data:` + img.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(img.Data) +
+			`" alt="` + html.EscapeString(img.Name) + `"` + styleAttr(imgCSS) + ">")
+	}
+	bw.WriteString("</" + tag + ">")
+}
+
+// styleAttr returns a style attribute holding declarations, or "" when there are none.
+func styleAttr(css []string) string {
+	if len(css) == 0 {
+		return ""
+	}
+	return ` style="` + html.EscapeString(strings.Join(css, ";")) + `"`
+}
+
+// cssPoints formats a length in points for CSS.
+func cssPoints(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64) + "pt"
+}
@@ -0,0 +1,183 @@
+package excel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestExportHTML_Table(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Cell("A1").Set("Sales <2026>").Style(document.CellStyle{Bold: true, Background: "DDEBF7", Horizontal: "center", BorderBottom: true, BorderWidth: 2})
+	sheet.MergeCells("A1:C1")
+	sheet.Cell("A2").Set("Region")
+	sheet.Cell("B2").Set(1234.5).Style(document.CellStyle{NumberFormat: "#,##0.00"})
+	sheet.Cell("C2").Set(true)
+	sheet.Cell("A3").Set("Hidden row")
+	sheet.Cell("D3").Set("Hidden column")
+	sheet.Cell("A4").Set("Last")
+	sheet.SetColumnWidth(1, 20).SetPrintTitles("$1:$1", "")
+	png := []byte("\x89PNG\r\n\x1a\n logo")
+	imgPath := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(imgPath, png, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	sheet.InsertImage(imgPath, 4, 1)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("sheet setup failed: %v", err)
+	}
+	ws := doc.sheets["Data"]
+	for i := range ws.SheetData.Rows {
+		if ws.SheetData.Rows[i].R == 3 {
+			ws.SheetData.Rows[i].Hidden = 1
+		}
+	}
+	ws.Cols.Items = append(ws.Cols.Items, xmlstructs.Col{Min: 4, Max: 4, Width: 10, Hidden: 1})
+
+	var buf bytes.Buffer
+	if err := doc.ExportHTML(&buf, document.HTMLOptions{}); err != nil {
+		t.Fatalf("ExportHTML failed: %v", err)
+	}
+	out := buf.String()
+	id := ws.SheetData.Rows[0].Cells[0].S
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<table class="sheet gridlines" data-sheet="Data"`,
+		"<caption>Data</caption>",
+		fmt.Sprintf(`<thead>`+"\n"+`<tr style="height:15pt"><th class="x%d" colspan="3">Sales &lt;2026&gt;</th>`, id),
+		fmt.Sprintf("table.sheet td.x%d,table.sheet th.x%d{font-weight:bold;", id, id),
+		"background-color:#DDEBF7",
+		"border-bottom:2px solid #000000",
+		"text-align:center",
+		`<td class="n x`, ">1,234.50</td>",
+		`<td class="c">TRUE</td>`,
+		"<td>Last</td>",
+		`<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `" alt="image1.png"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "Hidden row") || strings.Contains(out, "Hidden column") {
+		t.Error("hidden rows and columns were written")
+	}
+
+	buf.Reset()
+	if err := doc.ExportHTML(&buf, document.HTMLOptions{ShowHidden: true, MaxRows: 3, InlineStyles: true, Fragment: true}); err != nil {
+		t.Fatalf("ExportHTML with options failed: %v", err)
+	}
+	out = buf.String()
+	if strings.Contains(out, "<html>") || strings.Contains(out, "<style>") || strings.Contains(out, " class=\"x") {
+		t.Error("fragment with inline styles should hold neither the document, a style element nor format classes")
+	}
+	if !strings.Contains(out, "Hidden row") || !strings.Contains(out, "Hidden column") || strings.Contains(out, "Last") {
+		t.Error("hidden rows and columns should be shown and rows beyond MaxRows dropped")
+	}
+	if !strings.Contains(out, "background-color:#DDEBF7") || !strings.Contains(out, "text-align:right") {
+		t.Error("inline styles lack the cell formats")
+	}
+}
+
+func TestExportHTML_Pictures(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:C5").SetValues([][]any{{"a1", "b1", "c1"}, {"a2"}, {"a3", "b3", "c3"}, {"a4"}, {"a5"}})
+	sheet.MergeCells("A4:C4")
+	dir := t.TempDir()
+	for k, at := range [][2]float64{{1, 1}, {1, 3}, {2, 4}} {
+		path := filepath.Join(dir, fmt.Sprintf("pic%d.png", k))
+		if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		sheet.InsertImage(path, at[0], at[1])
+	}
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("sheet setup failed: %v", err)
+	}
+	ws := doc.sheets["Data"]
+	ws.SheetData.Rows[1].Hidden = 1
+	ws.Cols = &xmlstructs.Cols{Items: []xmlstructs.Col{{Min: 2, Max: 2, Width: 10, Hidden: 1}}}
+	// A format index past the styles part is written without its format.
+	ws.SheetData.Rows[0].Cells[0].S = 999
+
+	var buf bytes.Buffer
+	if err := doc.ExportHTML(&buf, document.HTMLOptions{MaxRows: 3, InlineStyles: true, Fragment: true}); err != nil {
+		t.Fatalf("ExportHTML failed: %v", err)
+	}
+	out := buf.String()
+	cell := func(text string) string {
+		i := strings.Index(out, ">"+text)
+		if i < 0 {
+			t.Fatalf("HTML lacks %s: %s", text, out)
+		}
+		return out[i : i+strings.Index(out[i:], "</td>")]
+	}
+	if !strings.Contains(cell("c3"), `alt="image1.png"`) {
+		t.Error("picture at B2, in a hidden row and column, should show in C3")
+	}
+	if !strings.Contains(cell("a4"), `alt="image2.png"`) {
+		t.Error("picture at B4, within the merge A4:C4, should show in A4")
+	}
+	if strings.Contains(out, "image3.png") {
+		t.Error("picture at C5, below the rows written, should be left out")
+	}
+	if !strings.Contains(cell("a1"), "a1") {
+		t.Error("cell with an unknown format lost its value")
+	}
+}
+
+func TestExportHTML_HostileStyles(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	const font = "x</style><script>alert(1)</script>"
+	sheet.Cell("A1").Set("a1").Style(document.CellStyle{Font: font, Color: "FF0000;}</style><script>alert(2)</script>", Background: "FFFF00"})
+	sheet.Cell("A2").Set("a2").Style(document.CellStyle{Font: "Times New Roman", Color: "80FF0000"})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("sheet setup failed: %v", err)
+	}
+	doc.styles.Fonts.Items[0].Name = &xmlstructs.ValString{Val: font}
+
+	for _, opts := range []document.HTMLOptions{{}, {InlineStyles: true}} {
+		var buf bytes.Buffer
+		if err := doc.ExportHTML(&buf, opts); err != nil {
+			t.Fatalf("ExportHTML failed: %v", err)
+		}
+		out := buf.String()
+		if strings.Contains(out, "<script") || strings.Contains(out, "alert") {
+			t.Errorf("inline %v: hostile font name or color reached the HTML: %s", opts.InlineStyles, out)
+		}
+		for _, want := range []string{"background-color:#FFFF00", `font-family:`, `"Times New Roman",sans-serif`, "color:#FF0000"} {
+			if !strings.Contains(out, want) && !strings.Contains(out, html.EscapeString(want)) {
+				t.Errorf("inline %v: HTML lacks %q", opts.InlineStyles, want)
+			}
+		}
+	}
+}
+
+func TestExportHTML_Errors(t *testing.T) {
+	doc, _ := rangeTestSheet(t)
+	defer doc.Close()
+	if err := doc.ExportHTML(&bytes.Buffer{}, document.HTMLOptions{Sheets: []string{"Missing"}}); err == nil {
+		t.Error("expected an error for a missing sheet")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.ExportHTML(&buf, document.HTMLOptions{Fragment: true}); err != nil {
+		t.Fatalf("ExportHTML of an empty sheet failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<caption>Data</caption>\n</table>") {
+		t.Errorf("empty sheet should give an empty table, got %s", buf.String())
+	}
+}
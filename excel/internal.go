@@ -6,10 +6,12 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"path"
 	"strconv"
 	"strings"
 
 	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
 )
 
@@ -94,44 +96,18 @@ func (e *state) loadCore(ctx context.Context) error {
 		}
 	}
 
-	// Load sheets
+	// Register sheets. Worksheet parts are decoded lazily on first access
+	// so that opening a large workbook does not unmarshal every sheet.
 	for _, s := range e.workbook.Sheets {
-		target := ""
-		for _, rel := range e.workbookRels.Rels {
-			if rel.ID == s.RID {
-				target = rel.Target
-				break
-			}
-		}
-
-		if target == "" {
-			target = fmt.Sprintf("worksheets/sheet%s.xml", s.SheetID)
-		}
-
-		var path string
-		if strings.HasPrefix(target, "/") {
-			path = target[1:]
-		} else {
-			path = "xl/" + target
-		}
-
-		var ws xmlstructs.Worksheet
-		if err := e.loadXML(path, &ws); err != nil {
+		path := e.sheetPath(s.Name)
+		if e.findFile(path) == nil {
 			continue
 		}
-		e.sheets[s.Name] = &ws
+		e.sheetPaths[s.Name] = path
 
 		// Load sheet rels
-		relPath := ""
-		if strings.Contains(path, "/") {
-			lastSlash := strings.LastIndex(path, "/")
-			relPath = path[:lastSlash] + "/_rels/" + path[lastSlash+1:] + ".rels"
-		} else {
-			relPath = "_rels/" + path + ".rels"
-		}
-
 		var wRels xmlstructs.Relationships
-		if err := e.loadXML(relPath, &wRels); err == nil {
+		if err := e.loadXML(relsPath(path), &wRels); err == nil {
 			e.sheetRels[s.Name] = &wRels
 		} else {
 			e.sheetRels[s.Name] = &xmlstructs.Relationships{}
@@ -142,17 +118,154 @@ func (e *state) loadCore(ctx context.Context) error {
 }
 
 func (e *state) loadXML(name string, target any) error {
+	rc, err := e.openPart(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(target)
+}
+
+// openPart opens a part of the source package for reading.
+func (e *state) openPart(name string) (io.ReadCloser, error) {
+	f := e.findFile(name)
+	if f == nil {
+		return nil, fmt.Errorf("file %s not found in zip", name)
+	}
+	return f.Open()
+}
+
+func (e *state) findFile(name string) *zip.File {
+	if e.reader == nil {
+		return nil
+	}
 	for _, f := range e.reader.File {
 		if f.Name == name {
-			rc, err := f.Open()
-			if err != nil {
-				return err
+			return f
+		}
+	}
+	return nil
+}
+
+// worksheet returns the worksheet for a sheet name, decoding it from the source package on first access.
+func (e *state) worksheet(name string) (*xmlstructs.Worksheet, bool) {
+	if ws, ok := e.sheets[name]; ok {
+		return ws, true
+	}
+	path, ok := e.sheetPaths[name]
+	if !ok {
+		return nil, false
+	}
+	var ws xmlstructs.Worksheet
+	if err := e.loadXML(path, &ws); err != nil {
+		return nil, false
+	}
+	e.sheets[name] = &ws
+	return &ws, true
+}
+
+// hasSheet reports whether a sheet exists without decoding it.
+func (e *state) hasSheet(name string) bool {
+	if _, ok := e.sheets[name]; ok {
+		return true
+	}
+	_, ok := e.sheetPaths[name]
+	return ok
+}
+
+// sheetPath returns the package path of a sheet's worksheet part, resolved through the workbook relationships.
+func (e *state) sheetPath(name string) string {
+	for _, s := range e.workbook.Sheets {
+		if s.Name != name {
+			continue
+		}
+		target := ""
+		if e.workbookRels != nil {
+			for _, rel := range e.workbookRels.Rels {
+				if rel.ID == s.RID {
+					target = rel.Target
+					break
+				}
 			}
-			defer rc.Close()
-			return xml.NewDecoder(rc).Decode(target)
+		}
+		if target == "" {
+			target = fmt.Sprintf("worksheets/sheet%s.xml", s.SheetID)
+		}
+		if strings.HasPrefix(target, "/") {
+			return target[1:]
+		}
+		return "xl/" + target
+	}
+	return ""
+}
+
+// relsPath returns the relationships part path for a package part.
+func relsPath(path string) string {
+	if strings.Contains(path, "/") {
+		lastSlash := strings.LastIndex(path, "/")
+		return path[:lastSlash] + "/_rels/" + path[lastSlash+1:] + ".rels"
+	}
+	return "_rels/" + path + ".rels"
+}
+
+// resolveTarget resolves a relationship target against the part that owns the relationship.
+func resolveTarget(base, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return target[1:]
+	}
+	return path.Join(path.Dir(base), target)
+}
+
+// nextPartPath returns the first path of the form format%d not used by the package.
+func (e *state) nextPartPath(format string) string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf(format, i)
+		if !e.partExists(name) {
+			return name
+		}
+	}
+}
+
+// partExists reports whether a part is present in the source package or created in memory.
+func (e *state) partExists(name string) bool {
+	if e.findFile(name) != nil {
+		return true
+	}
+	if _, ok := e.media[name]; ok {
+		return true
+	}
+	if _, ok := e.patched[name]; ok {
+		return true
+	}
+	if _, ok := e.drawings[name]; ok {
+		return true
+	}
+	if _, ok := e.tables[name]; ok {
+		return true
+	}
+	if _, ok := e.charts[name]; ok {
+		return true
+	}
+	if _, ok := e.pivotTables[name]; ok {
+		return true
+	}
+	if _, ok := e.pivotCaches[name]; ok {
+		return true
+	}
+	if _, ok := e.pivotRecords[name]; ok {
+		return true
+	}
+	for _, sc := range e.comments {
+		if sc.path == name || sc.vmlPath == name {
+			return true
 		}
 	}
-	return fmt.Errorf("file %s not found in zip", name)
+	for _, s := range e.workbook.Sheets {
+		if e.sheetPath(s.Name) == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *state) writeXML(zw *zip.Writer, name string, data any) error {
@@ -164,6 +277,21 @@ func (e *state) writeXML(zw *zip.Writer, name string, data any) error {
 	return xml.NewEncoder(w).Encode(data)
 }
 
+// writePrefixed writes a DrawingML part with its namespaces bound to the conventional prefixes.
+func (e *state) writePrefixed(zw *zip.Writer, name string, data any, namespaces []xmlstructs.Namespace) error {
+	out, err := xmlstructs.MarshalPrefixed(data, namespaces...)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", name, err)
+	}
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(w, xml.Header)
+	_, err = w.Write(out)
+	return err
+}
+
 func (e *state) copyFile(f *zip.File, zw *zip.Writer) error {
 	rc, err := f.Open()
 	if err != nil {
@@ -191,11 +319,27 @@ func (e *state) GetSheets() ([]string, error) {
 	return sheets, nil
 }
 
+// lookupCell returns an existing cell of a sheet, or nil when it is empty.
+func (e *state) lookupCell(sheet, axis string) (*xmlstructs.Cell, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if cell := e.cellCache[sheet][axis]; cell != nil {
+		return cell, nil
+	}
+	col, row := axisPosition(axis)
+	return findCell(ws, col, row), nil
+}
+
 func (e *state) getOrCreateCell(sheet, axis string) (*xmlstructs.Cell, error) {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
 	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return nil, fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
 
 	rowIdx, err := getRowFromAxis(axis)
 	if err != nil {
@@ -250,16 +394,30 @@ func (e *state) getOrCreateCell(sheet, axis string) (*xmlstructs.Cell, error) {
 
 	newCell := xmlstructs.Cell{R: axis}
 	if cellInsertIdx == -1 {
+		oldCap := cap(targetRow.Cells)
 		targetRow.Cells = append(targetRow.Cells, newCell)
+		if cap(targetRow.Cells) != oldCap {
+			e.recacheRow(sheet, targetRow)
+		}
 		cell := &targetRow.Cells[len(targetRow.Cells)-1]
 		e.cellCache[sheet][axis] = cell
 		return cell, nil
 	}
 
 	targetRow.Cells = append(targetRow.Cells[:cellInsertIdx], append([]xmlstructs.Cell{newCell}, targetRow.Cells[cellInsertIdx:]...)...)
-	cell := &targetRow.Cells[cellInsertIdx]
-	e.cellCache[sheet][axis] = cell
-	return cell, nil
+	e.recacheRow(sheet, targetRow)
+	return &targetRow.Cells[cellInsertIdx], nil
+}
+
+// recacheRow refreshes the cached cell pointers of a row after its cells have moved.
+func (e *state) recacheRow(sheet string, row *xmlstructs.Row) {
+	cache := e.cellCache[sheet]
+	if cache == nil {
+		return
+	}
+	for i := range row.Cells {
+		cache[row.Cells[i].R] = &row.Cells[i]
+	}
 }
 
 func (e *state) resolveValue(cell xmlstructs.Cell) string {
@@ -282,6 +440,30 @@ func (e *state) resolveValue(cell xmlstructs.Cell) string {
 	return cell.V
 }
 
+// formattedValue returns a cell's value as displayed: numbers and text are
+// rendered with the cell's number format and booleans as TRUE or FALSE.
+func (e *state) formattedValue(cell xmlstructs.Cell) string {
+	value := e.resolveValue(cell)
+	switch cell.T {
+	case "b":
+		return strings.ToUpper(strconv.FormatBool(value == "1"))
+	case "e":
+		return value
+	case "s", "str", "inlineStr":
+		return formula.FormatText(value, e.numberFormatCode(cell.S))
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	return formula.FormatValue(n, e.numberFormatCode(cell.S), e.date1904())
+}
+
+// date1904 reports whether the workbook counts dates from 1904 instead of 1900.
+func (e *state) date1904() bool {
+	return e.workbook.WorkbookPr != nil && e.workbook.WorkbookPr.Date1904 == 1
+}
+
 func getColumnFromAxis(axis string) string {
 	idx := strings.IndexFunc(axis, func(r rune) bool {
 		return r >= '0' && r <= '9'
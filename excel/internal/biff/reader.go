@@ -0,0 +1,187 @@
+package biff
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math"
+	"unicode/utf16"
+)
+
+// record is a BIFF record with the data of the CONTINUE records that follow it.
+type record struct {
+	id     uint16
+	offset int
+	parts  [][]byte
+}
+
+// records yields the records of stream from offset on.
+func records(stream []byte, offset int) iter.Seq2[record, error] {
+	return func(yield func(record, error) bool) {
+		offset := offset
+		for offset+4 <= len(stream) {
+			rec := record{id: binary.LittleEndian.Uint16(stream[offset:]), offset: offset}
+			for {
+				size := int(binary.LittleEndian.Uint16(stream[offset+2:]))
+				if offset+4+size > len(stream) {
+					yield(rec, fmt.Errorf("record 0x%04X at %d exceeds the stream", rec.id, offset))
+					return
+				}
+				rec.parts = append(rec.parts, stream[offset+4:offset+4+size])
+				offset += 4 + size
+				if offset+4 > len(stream) || binary.LittleEndian.Uint16(stream[offset:]) != recContinue {
+					break
+				}
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// reader decodes the data of a record across its CONTINUE records. Reading
+// past the end sets err and returns zero values.
+type reader struct {
+	parts [][]byte
+	part  int
+	pos   int
+	err   error
+}
+
+func newReader(rec record) *reader {
+	return &reader{parts: rec.parts}
+}
+
+// available returns the bytes left in the current part, moving to the next
+// part when the current one is exhausted.
+func (r *reader) available() int {
+	for r.part < len(r.parts)-1 && r.pos >= len(r.parts[r.part]) {
+		r.part++
+		r.pos = 0
+	}
+	if r.part >= len(r.parts) {
+		return 0
+	}
+	return len(r.parts[r.part]) - r.pos
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n <= r.available() {
+		b := r.parts[r.part][r.pos : r.pos+n]
+		r.pos += n
+		return b
+	}
+	b := make([]byte, 0, n)
+	for len(b) < n {
+		avail := r.available()
+		if avail == 0 {
+			r.err = fmt.Errorf("record data ends after %d of %d bytes", len(b), n)
+			return nil
+		}
+		take := min(avail, n-len(b))
+		b = append(b, r.parts[r.part][r.pos:r.pos+take]...)
+		r.pos += take
+	}
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *reader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) f64() float64 {
+	if b := r.bytes(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// chars reads cch characters stored as single bytes or, when high is set, as
+// UTF-16 code units. Characters continued in the next part are preceded by
+// a new option byte that tells how they are stored.
+func (r *reader) chars(cch int, high bool) string {
+	units := make([]uint16, 0, cch)
+	for len(units) < cch && r.err == nil {
+		if r.pos >= len(r.parts[r.part]) {
+			if r.part >= len(r.parts)-1 {
+				r.err = fmt.Errorf("string data ends after %d of %d characters", len(units), cch)
+				break
+			}
+			r.part++
+			r.pos = 0
+			high = r.u8()&0x01 != 0
+		}
+		size := 1
+		if high {
+			size = 2
+		}
+		take := min(cch-len(units), (len(r.parts[r.part])-r.pos)/size)
+		if take == 0 {
+			r.err = fmt.Errorf("string data ends after %d of %d characters", len(units), cch)
+			break
+		}
+		b := r.bytes(take * size)
+		for i := range take {
+			if high {
+				units = append(units, binary.LittleEndian.Uint16(b[2*i:]))
+			} else {
+				units = append(units, uint16(b[i]))
+			}
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// shortString reads a ShortXLUnicodeString with an 8-bit character count.
+func (r *reader) shortString() string {
+	cch := int(r.u8())
+	return r.chars(cch, r.u8()&0x01 != 0)
+}
+
+// unicodeString reads an XLUnicodeString with a 16-bit character count.
+func (r *reader) unicodeString() string {
+	cch := int(r.u16())
+	return r.chars(cch, r.u8()&0x01 != 0)
+}
+
+// richString reads an XLUnicodeRichExtendedString, skipping its formatting
+// runs and phonetic data.
+func (r *reader) richString() string {
+	cch := int(r.u16())
+	flags := r.u8()
+	runs, ext := 0, 0
+	if flags&0x08 != 0 {
+		runs = int(r.u16())
+	}
+	if flags&0x04 != 0 {
+		ext = int(r.u32())
+	}
+	s := r.chars(cch, flags&0x01 != 0)
+	r.skip(4*runs + ext)
+	return s
+}
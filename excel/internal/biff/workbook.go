@@ -0,0 +1,429 @@
+// Package biff decodes the Workbook stream of legacy Excel 97-2003 (.xls)
+// files, stored in the BIFF8 record format.
+package biff
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+var (
+	// ErrEncrypted is returned for workbooks protected with a password to open.
+	ErrEncrypted = errors.New("workbook is encrypted")
+	// ErrVersion is returned for workbooks written before Excel 97.
+	ErrVersion = errors.New("workbook is not in BIFF8 format")
+)
+
+// Record identifiers.
+const (
+	recFormula    = 0x0006
+	recEOF        = 0x000A
+	recDate1904   = 0x0022
+	recFilePass   = 0x002F
+	recFont       = 0x0031
+	recContinue   = 0x003C
+	recColInfo    = 0x007D
+	recBoundSheet = 0x0085
+	recPalette    = 0x0092
+	recMulRK      = 0x00BD
+	recMulBlank   = 0x00BE
+	recRString    = 0x00D6
+	recXF         = 0x00E0
+	recMergeCells = 0x00E5
+	recSST        = 0x00FC
+	recLabelSST   = 0x00FD
+	recBlank      = 0x0201
+	recNumber     = 0x0203
+	recLabel      = 0x0204
+	recBoolErr    = 0x0205
+	recString     = 0x0207
+	recRow        = 0x0208
+	recRK         = 0x027E
+	recFormat     = 0x041E
+	recBOF        = 0x0809
+)
+
+// Substream types of BOF records.
+const (
+	bofGlobals   = 0x0005
+	bofWorksheet = 0x0010
+)
+
+const biff8 = 0x0600
+
+// Workbook is the content of a BIFF8 workbook stream.
+type Workbook struct {
+	Date1904 bool
+	Sheets   []*Sheet
+	Fonts    []Font
+	Formats  map[int]string // Number format codes by format index
+	XFs      []XF
+	Palette  []string // RRGGBB colors of the color indexes from 8 on, when customized
+}
+
+// Font is a font of the workbook.
+type Font struct {
+	Name   string
+	Height int // Twips
+	Bold   bool
+	Italic bool
+	Color  int // Color index
+}
+
+// XF is a cell or style format.
+type XF struct {
+	Font       int
+	Format     int
+	HAlign     int
+	VAlign     int
+	Wrap       bool
+	Indent     int
+	Borders    [4]Border // Left, right, top and bottom
+	Pattern    int
+	Foreground int // Color index of the pattern
+	Background int // Color index behind the pattern
+}
+
+// Border is an edge of a cell border.
+type Border struct {
+	Style int
+	Color int // Color index
+}
+
+// Sheet is a worksheet of the workbook.
+type Sheet struct {
+	Name       string
+	Visibility int // 0 visible, 1 hidden, 2 very hidden
+	Cells      []Cell
+	Merged     []Range
+	Columns    []Column
+	Heights    map[int]float64 // Custom row heights in points by zero-based row
+}
+
+// Cell is a cell of a worksheet. Value is nil for a blank cell, or a float64,
+// string, bool or Error; formulas hold their cached result.
+type Cell struct {
+	Row, Col int // Zero-based
+	XF       int
+	Value    any
+}
+
+// Error is an error value such as "#DIV/0!".
+type Error string
+
+// Range is a rectangle of cells with zero-based, inclusive bounds.
+type Range struct {
+	FirstRow, LastRow int
+	FirstCol, LastCol int
+}
+
+// Column holds the width of a span of zero-based columns.
+type Column struct {
+	First, Last int
+	Width       float64 // Characters
+}
+
+var errorCodes = map[uint8]Error{
+	0x00: "#NULL!", 0x07: "#DIV/0!", 0x0F: "#VALUE!", 0x17: "#REF!",
+	0x1D: "#NAME?", 0x24: "#NUM!", 0x2A: "#N/A",
+}
+
+// Parse decodes a BIFF8 workbook stream. Sheets other than worksheets, such as
+// chart sheets, are skipped.
+func Parse(stream []byte) (*Workbook, error) {
+	wb := &Workbook{Formats: make(map[int]string)}
+	var sst []string
+	type boundSheet struct {
+		offset int
+		sheet  *Sheet
+	}
+	var bound []boundSheet
+
+	first, ended := true, false
+globals:
+	for rec, err := range records(stream, 0) {
+		if err != nil {
+			return nil, err
+		}
+		r := newReader(rec)
+		if first {
+			if rec.id != recBOF {
+				return nil, fmt.Errorf("workbook stream does not start with a BOF record")
+			}
+			if version, kind := r.u16(), r.u16(); version != biff8 || kind != bofGlobals {
+				return nil, ErrVersion
+			}
+			first = false
+			continue
+		}
+
+		switch rec.id {
+		case recEOF:
+			ended = true
+			break globals
+		case recFilePass:
+			return nil, ErrEncrypted
+		case recDate1904:
+			wb.Date1904 = r.u16() == 1
+		case recFont:
+			height := int(r.u16())
+			flags := r.u16()
+			color := int(r.u16())
+			weight := r.u16()
+			r.skip(6)
+			wb.Fonts = append(wb.Fonts, Font{
+				Name:   r.shortString(),
+				Height: height,
+				Bold:   weight >= 700,
+				Italic: flags&0x0002 != 0,
+				Color:  color,
+			})
+		case recFormat:
+			id := int(r.u16())
+			wb.Formats[id] = r.unicodeString()
+		case recXF:
+			wb.XFs = append(wb.XFs, parseXF(r))
+		case recPalette:
+			n := int(r.u16())
+			wb.Palette = make([]string, 0, n)
+			for range n {
+				rgb := r.bytes(4)
+				if rgb == nil {
+					break
+				}
+				wb.Palette = append(wb.Palette, fmt.Sprintf("%02X%02X%02X", rgb[0], rgb[1], rgb[2]))
+			}
+		case recBoundSheet:
+			offset := int(r.u32())
+			visibility := int(r.u8() & 0x03)
+			kind := r.u8()
+			name := r.shortString()
+			if kind == 0 {
+				sheet := &Sheet{Name: name, Visibility: visibility, Heights: make(map[int]float64)}
+				bound = append(bound, boundSheet{offset, sheet})
+			}
+		case recSST:
+			r.skip(8)
+			for r.err == nil && r.available() > 0 {
+				sst = append(sst, r.richString())
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("record 0x%04X at %d: %w", rec.id, rec.offset, r.err)
+		}
+	}
+	if !ended {
+		return nil, fmt.Errorf("workbook globals have no EOF record")
+	}
+
+	for _, b := range bound {
+		if err := parseSheet(stream, b.offset, b.sheet, sst); err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", b.sheet.Name, err)
+		}
+		wb.Sheets = append(wb.Sheets, b.sheet)
+	}
+	return wb, nil
+}
+
+// Font returns the font at a font index. Index 4 is not used, so the fonts
+// from index 5 on are stored one place earlier.
+func (wb *Workbook) Font(index int) (Font, bool) {
+	if index > 4 {
+		index--
+	}
+	if index < 0 || index >= len(wb.Fonts) {
+		return Font{}, false
+	}
+	return wb.Fonts[index], true
+}
+
+func parseXF(r *reader) XF {
+	font := int(r.u16())
+	format := int(r.u16())
+	r.skip(2)
+	align := r.u8()
+	r.skip(1)
+	indent := r.u8()
+	r.skip(1)
+	border1 := r.u32()
+	border2 := r.u32()
+	colors := r.u16()
+	return XF{
+		Font:   font,
+		Format: format,
+		HAlign: int(align & 0x07),
+		VAlign: int(align >> 4 & 0x07),
+		Wrap:   align&0x08 != 0,
+		Indent: int(indent & 0x0F),
+		Borders: [4]Border{
+			{Style: int(border1 & 0x0F), Color: int(border1 >> 16 & 0x7F)},
+			{Style: int(border1 >> 4 & 0x0F), Color: int(border1 >> 23 & 0x7F)},
+			{Style: int(border1 >> 8 & 0x0F), Color: int(border2 & 0x7F)},
+			{Style: int(border1 >> 12 & 0x0F), Color: int(border2 >> 7 & 0x7F)},
+		},
+		Pattern:    int(border2 >> 26),
+		Foreground: int(colors & 0x7F),
+		Background: int(colors >> 7 & 0x7F),
+	}
+}
+
+// parseSheet decodes the worksheet substream at offset. Substreams nested in
+// it, such as embedded charts, are skipped.
+func parseSheet(stream []byte, offset int, sheet *Sheet, sst []string) error {
+	depth := 0
+	pending := -1 // Formula cell waiting for its STRING record
+	for rec, err := range records(stream, offset) {
+		if err != nil {
+			return err
+		}
+		r := newReader(rec)
+		switch {
+		case rec.id == recBOF:
+			r.skip(2)
+			if depth == 0 && r.u16() != bofWorksheet {
+				return nil // Not a worksheet, e.g. a dialog sheet
+			}
+			depth++
+			continue
+		case depth == 0:
+			return fmt.Errorf("substream does not start with a BOF record")
+		case rec.id == recEOF:
+			depth--
+			if depth == 0 {
+				slices.SortStableFunc(sheet.Cells, func(a, b Cell) int {
+					return cmp.Or(cmp.Compare(a.Row, b.Row), cmp.Compare(a.Col, b.Col))
+				})
+				return nil
+			}
+			continue
+		case depth != 1:
+			continue
+		}
+
+		cell := func(value any) Cell {
+			row, col, xf := int(r.u16()), int(r.u16()), int(r.u16())
+			return Cell{Row: row, Col: col, XF: xf, Value: value}
+		}
+		switch rec.id {
+		case recNumber:
+			c := cell(nil)
+			c.Value = r.f64()
+			sheet.Cells = append(sheet.Cells, c)
+		case recRK:
+			c := cell(nil)
+			c.Value = rkValue(r.u32())
+			sheet.Cells = append(sheet.Cells, c)
+		case recMulRK:
+			row, col := int(r.u16()), int(r.u16())
+			for ; r.available() >= 8; col++ {
+				xf := int(r.u16())
+				sheet.Cells = append(sheet.Cells, Cell{Row: row, Col: col, XF: xf, Value: rkValue(r.u32())})
+			}
+		case recLabelSST:
+			c := cell(nil)
+			index := int(r.u32())
+			if index >= len(sst) {
+				return fmt.Errorf("cell refers to shared string %d of %d", index, len(sst))
+			}
+			c.Value = sst[index]
+			sheet.Cells = append(sheet.Cells, c)
+		case recLabel, recRString:
+			c := cell(nil)
+			c.Value = r.unicodeString()
+			sheet.Cells = append(sheet.Cells, c)
+		case recBlank:
+			sheet.Cells = append(sheet.Cells, cell(nil))
+		case recMulBlank:
+			row, col := int(r.u16()), int(r.u16())
+			for ; r.available() >= 4; col++ {
+				sheet.Cells = append(sheet.Cells, Cell{Row: row, Col: col, XF: int(r.u16())})
+			}
+		case recBoolErr:
+			c := cell(nil)
+			value, isError := r.u8(), r.u8()
+			if isError != 0 {
+				c.Value = errorValue(value)
+			} else {
+				c.Value = value != 0
+			}
+			sheet.Cells = append(sheet.Cells, c)
+		case recFormula:
+			c := cell(nil)
+			result := r.bytes(8)
+			if result == nil {
+				break
+			}
+			sheet.Cells = append(sheet.Cells, c)
+			if result[6] != 0xFF || result[7] != 0xFF {
+				sheet.Cells[len(sheet.Cells)-1].Value = math.Float64frombits(binary.LittleEndian.Uint64(result))
+				break
+			}
+			switch result[0] {
+			case 0: // The string follows in a STRING record
+				pending = len(sheet.Cells) - 1
+			case 1:
+				sheet.Cells[len(sheet.Cells)-1].Value = result[2] != 0
+			case 2:
+				sheet.Cells[len(sheet.Cells)-1].Value = errorValue(result[2])
+			case 3:
+				sheet.Cells[len(sheet.Cells)-1].Value = ""
+			}
+		case recString:
+			if pending >= 0 {
+				sheet.Cells[pending].Value = r.unicodeString()
+				pending = -1
+			}
+		case recMergeCells:
+			n := int(r.u16())
+			for range n {
+				first, last := int(r.u16()), int(r.u16())
+				firstCol, lastCol := int(r.u16()), int(r.u16())
+				if r.err != nil {
+					break
+				}
+				sheet.Merged = append(sheet.Merged, Range{first, last, firstCol, lastCol})
+			}
+		case recColInfo:
+			first, last, width := int(r.u16()), int(r.u16()), r.u16()
+			sheet.Columns = append(sheet.Columns, Column{First: first, Last: last, Width: float64(width) / 256})
+		case recRow:
+			row := int(r.u16())
+			r.skip(4)
+			height := r.u16() & 0x7FFF
+			r.skip(4)
+			if r.u8()&0x40 != 0 {
+				sheet.Heights[row] = float64(height) / 20
+			}
+		}
+		if r.err != nil {
+			return fmt.Errorf("record 0x%04X at %d: %w", rec.id, rec.offset, r.err)
+		}
+	}
+	return fmt.Errorf("worksheet has no EOF record")
+}
+
+// rkValue decodes an RK number: a 30-bit integer or the high bits of a
+// float64, optionally multiplied by 100.
+func rkValue(rk uint32) float64 {
+	var v float64
+	if rk&0x02 != 0 {
+		v = float64(int32(rk) >> 2)
+	} else {
+		v = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		v /= 100
+	}
+	return v
+}
+
+func errorValue(code uint8) Error {
+	if e, ok := errorCodes[code]; ok {
+		return e
+	}
+	return "#N/A"
+}
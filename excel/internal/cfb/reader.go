@@ -0,0 +1,282 @@
+// Package cfb reads and writes OLE2 compound files, the container of legacy
+// Office documents and of encrypted OOXML packages.
+package cfb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Signature starts every compound file.
+var Signature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// ErrNotFound is returned for streams missing from the root storage.
+var ErrNotFound = errors.New("stream not found")
+
+const (
+	headerSize = 512
+	entrySize  = 128
+
+	freeSect   = 0xFFFFFFFF
+	endOfChain = 0xFFFFFFFE
+	noStream   = 0xFFFFFFFF
+
+	typeStream = 2
+	typeRoot   = 5
+)
+
+// IsCompoundFile reports whether data starts with the compound file signature.
+func IsCompoundFile(data []byte) bool {
+	return bytes.HasPrefix(data, Signature)
+}
+
+type entry struct {
+	name        string
+	kind        byte
+	left, right uint32
+	child       uint32
+	start       uint32
+	size        uint64
+}
+
+// Reader gives access to the streams in the root storage of a compound file.
+type Reader struct {
+	r          io.ReaderAt
+	sectorSize int
+	miniSize   int
+	fat        []uint32
+	miniFAT    []uint32
+	entries    []entry
+	miniStream []byte // Decoded on first access
+}
+
+// NewReader parses the header, allocation tables and directory of the compound
+// file of the given size.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("read compound file header: %w", err)
+	}
+	if !IsCompoundFile(header) {
+		return nil, fmt.Errorf("not a compound file")
+	}
+	le := binary.LittleEndian
+	sectorShift := le.Uint16(header[30:])
+	miniShift := le.Uint16(header[32:])
+	if sectorShift != 9 && sectorShift != 12 || miniShift != 6 {
+		return nil, fmt.Errorf("unsupported compound file sector sizes 2^%d and 2^%d", sectorShift, miniShift)
+	}
+	if cutoff := le.Uint32(header[56:]); cutoff != miniCutoff {
+		return nil, fmt.Errorf("unsupported compound file mini stream cutoff %d", cutoff)
+	}
+	c := &Reader{
+		r:          r,
+		sectorSize: 1 << sectorShift,
+		miniSize:   1 << miniShift,
+	}
+	sectors := uint32(max(0, size/int64(c.sectorSize)))
+
+	// The DIFAT lists the FAT sectors: 109 in the header, the rest in a chain of
+	// DIFAT sectors that each end with the number of the next one.
+	numFAT := le.Uint32(header[44:])
+	if numFAT > sectors {
+		return nil, fmt.Errorf("compound file declares %d FAT sectors", numFAT)
+	}
+	difat := make([]uint32, 0, numFAT)
+	for i := range 109 {
+		difat = append(difat, le.Uint32(header[76+4*i:]))
+	}
+	perSector := c.sectorSize/4 - 1
+	next := le.Uint32(header[68:])
+	for n := 0; next != endOfChain && next != freeSect && uint32(len(difat)) < numFAT; n++ {
+		if n >= int(sectors) {
+			return nil, fmt.Errorf("compound file DIFAT chain does not end")
+		}
+		sector, err := c.sector(next)
+		if err != nil {
+			return nil, err
+		}
+		for i := range perSector {
+			difat = append(difat, le.Uint32(sector[4*i:]))
+		}
+		next = le.Uint32(sector[4*perSector:])
+	}
+	if uint32(len(difat)) < numFAT {
+		return nil, fmt.Errorf("compound file DIFAT lists %d of %d FAT sectors", len(difat), numFAT)
+	}
+
+	c.fat = make([]uint32, 0, int(numFAT)*c.sectorSize/4)
+	for _, sid := range difat[:numFAT] {
+		sector, err := c.sector(sid)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(sector); i += 4 {
+			c.fat = append(c.fat, le.Uint32(sector[i:]))
+		}
+	}
+
+	dir, err := c.chain(le.Uint32(header[48:]), 0)
+	if err != nil {
+		return nil, fmt.Errorf("read compound file directory: %w", err)
+	}
+	for i := 0; i+entrySize <= len(dir); i += entrySize {
+		e := parseEntry(dir[i : i+entrySize])
+		if c.sectorSize == 512 {
+			e.size &= 0xFFFFFFFF // Version 3 files may leave garbage in the high bits
+		}
+		c.entries = append(c.entries, e)
+	}
+	if len(c.entries) == 0 || c.entries[0].kind != typeRoot {
+		return nil, fmt.Errorf("compound file has no root entry")
+	}
+	// Check the tree once so later walks need not guard against cycles.
+	if err := c.walk(c.entries[0].child, make([]bool, len(c.entries)), func(*entry) {}); err != nil {
+		return nil, err
+	}
+
+	if numMini := le.Uint32(header[64:]); numMini > 0 {
+		table, err := c.chain(le.Uint32(header[60:]), 0)
+		if err != nil {
+			return nil, fmt.Errorf("read compound file mini FAT: %w", err)
+		}
+		for i := 0; i+4 <= len(table); i += 4 {
+			c.miniFAT = append(c.miniFAT, le.Uint32(table[i:]))
+		}
+	}
+	return c, nil
+}
+
+func parseEntry(b []byte) entry {
+	le := binary.LittleEndian
+	nameLen := min(int(le.Uint16(b[64:])), 64)
+	units := make([]uint16, 0, nameLen/2)
+	for i := 0; i+1 < nameLen; i += 2 {
+		if u := le.Uint16(b[i:]); u != 0 {
+			units = append(units, u)
+		}
+	}
+	return entry{
+		name:  string(utf16.Decode(units)),
+		kind:  b[66],
+		left:  le.Uint32(b[68:]),
+		right: le.Uint32(b[72:]),
+		child: le.Uint32(b[76:]),
+		start: le.Uint32(b[116:]),
+		size:  le.Uint64(b[120:]),
+	}
+}
+
+// Streams returns the names of the streams in the root storage.
+func (c *Reader) Streams() []string {
+	var names []string
+	c.walk(c.entries[0].child, nil, func(e *entry) {
+		if e.kind == typeStream {
+			names = append(names, e.name)
+		}
+	})
+	return names
+}
+
+// Stream returns the content of a stream in the root storage. Names are
+// compared case-insensitively.
+func (c *Reader) Stream(name string) ([]byte, error) {
+	var found *entry
+	c.walk(c.entries[0].child, nil, func(e *entry) {
+		if found == nil && e.kind == typeStream && strings.EqualFold(e.name, name) {
+			found = e
+		}
+	})
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if found.size < miniCutoff {
+		return c.miniChain(found.start, found.size)
+	}
+	return c.chain(found.start, found.size)
+}
+
+// walk visits the red-black tree of directory entries below id in order. When
+// seen is not nil it marks the visited entries and a revisit is an error.
+func (c *Reader) walk(id uint32, seen []bool, visit func(*entry)) error {
+	if id == noStream || int(id) >= len(c.entries) {
+		return nil
+	}
+	if seen != nil {
+		if seen[id] {
+			return fmt.Errorf("compound file directory entry %d is linked twice", id)
+		}
+		seen[id] = true
+	}
+	e := &c.entries[id]
+	if err := c.walk(e.left, seen, visit); err != nil {
+		return err
+	}
+	visit(e)
+	return c.walk(e.right, seen, visit)
+}
+
+func (c *Reader) sector(sid uint32) ([]byte, error) {
+	buf := make([]byte, c.sectorSize)
+	if _, err := c.r.ReadAt(buf, int64(sid+1)*int64(c.sectorSize)); err != nil {
+		return nil, fmt.Errorf("read sector %d: %w", sid, err)
+	}
+	return buf, nil
+}
+
+// chain reads the sectors linked from start in the FAT. A size of 0 reads the
+// whole chain.
+func (c *Reader) chain(start uint32, size uint64) ([]byte, error) {
+	var buf []byte
+	for sid, n := start, 0; sid != endOfChain && (size == 0 || uint64(len(buf)) < size); n++ {
+		if int(sid) >= len(c.fat) || n >= len(c.fat) {
+			return nil, fmt.Errorf("invalid sector chain at %d", sid)
+		}
+		sector, err := c.sector(sid)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, sector...)
+		sid = c.fat[sid]
+	}
+	if uint64(len(buf)) < size {
+		return nil, fmt.Errorf("sector chain holds %d of %d bytes", len(buf), size)
+	}
+	if size > 0 {
+		buf = buf[:size]
+	}
+	return buf, nil
+}
+
+// miniChain reads a stream stored in the mini stream of the root entry.
+func (c *Reader) miniChain(start uint32, size uint64) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+	if c.miniStream == nil {
+		root := c.entries[0]
+		stream, err := c.chain(root.start, root.size)
+		if err != nil {
+			return nil, fmt.Errorf("read compound file mini stream: %w", err)
+		}
+		c.miniStream = stream
+	}
+	buf := make([]byte, 0, size)
+	for sid, n := start, 0; sid != endOfChain && uint64(len(buf)) < size; n++ {
+		offset := int(sid) * c.miniSize
+		if int(sid) >= len(c.miniFAT) || n >= len(c.miniFAT) || offset+c.miniSize > len(c.miniStream) {
+			return nil, fmt.Errorf("invalid mini sector chain at %d", sid)
+		}
+		buf = append(buf, c.miniStream[offset:offset+c.miniSize]...)
+		sid = c.miniFAT[sid]
+	}
+	if uint64(len(buf)) < size {
+		return nil, fmt.Errorf("mini sector chain holds %d of %d bytes", len(buf), size)
+	}
+	return buf[:size], nil
+}
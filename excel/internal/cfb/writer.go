@@ -0,0 +1,239 @@
+package cfb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	sectorSize   = 512
+	miniSize     = 64
+	miniCutoff   = 4096
+	headerDIFAT  = 109
+	perFATSector = sectorSize / 4
+
+	fatSect = 0xFFFFFFFD
+	difSect = 0xFFFFFFFC
+
+	colorRed   = 0
+	colorBlack = 1
+)
+
+// Write writes a version 3 compound file holding streams in its root storage.
+// Streams under 4096 bytes are stored in the mini stream.
+func Write(w io.Writer, streams map[string][]byte) error {
+	names := make([]string, 0, len(streams))
+	for name := range streams {
+		if len(utf16.Encode([]rune(name))) > 31 {
+			return fmt.Errorf("stream name %q is longer than 31 characters", name)
+		}
+		names = append(names, name)
+	}
+	slices.SortFunc(names, compareNames)
+
+	var sectors []byte
+	var fat []uint32
+	alloc := func(data []byte, size int) uint32 {
+		if len(data) == 0 {
+			return endOfChain
+		}
+		start := uint32(len(fat))
+		n := (len(data) + size - 1) / size
+		for i := range n {
+			next := uint32(len(fat) + 1)
+			if i == n-1 {
+				next = endOfChain
+			}
+			fat = append(fat, next)
+		}
+		sectors = append(sectors, data...)
+		sectors = append(sectors, make([]byte, n*size-len(data))...)
+		return start
+	}
+
+	// Small streams go to the mini stream, chained through the mini FAT.
+	var mini []byte
+	var miniFAT []uint32
+	starts := make([]uint32, len(names))
+	for i, name := range names {
+		data := streams[name]
+		if len(data) >= miniCutoff {
+			starts[i] = alloc(data, sectorSize)
+			continue
+		}
+		if len(data) == 0 {
+			starts[i] = endOfChain
+			continue
+		}
+		starts[i] = uint32(len(miniFAT))
+		n := (len(data) + miniSize - 1) / miniSize
+		for j := range n {
+			next := uint32(len(miniFAT) + 1)
+			if j == n-1 {
+				next = endOfChain
+			}
+			miniFAT = append(miniFAT, next)
+		}
+		mini = append(mini, data...)
+		mini = append(mini, make([]byte, n*miniSize-len(data))...)
+	}
+	miniStart := alloc(mini, sectorSize)
+	var miniTable []byte
+	for _, sid := range miniFAT {
+		miniTable = binary.LittleEndian.AppendUint32(miniTable, sid)
+	}
+	miniFATStart := alloc(miniTable, sectorSize)
+
+	entries := make([]entry, 1, len(names)+1)
+	entries[0] = entry{name: "Root Entry", kind: typeRoot, left: noStream, right: noStream, start: miniStart, size: uint64(len(mini))}
+	for i, name := range names {
+		entries = append(entries, entry{name: name, kind: typeStream, child: noStream, start: starts[i], size: uint64(len(streams[name]))})
+	}
+	colors := make([]byte, len(entries))
+	colors[0] = colorBlack
+	entries[0].child = balance(entries, colors, 1, len(entries), 0, treeDepth(len(names)))
+	var dir []byte
+	for i, e := range entries {
+		dir = append(dir, e.encode(colors[i])...)
+	}
+	dirStart := alloc(dir, sectorSize)
+
+	// The FAT also covers its own sectors and the DIFAT sectors listing those
+	// beyond the 109 in the header.
+	numFAT, numDIFAT := 0, 0
+	for {
+		total := len(fat) + numFAT + numDIFAT
+		needFAT := (total + perFATSector - 1) / perFATSector
+		needDIFAT := (max(0, needFAT-headerDIFAT) + perFATSector - 2) / (perFATSector - 1)
+		if needFAT == numFAT && needDIFAT == numDIFAT {
+			break
+		}
+		numFAT, numDIFAT = needFAT, needDIFAT
+	}
+	firstFAT := uint32(len(fat))
+	for range numFAT {
+		fat = append(fat, fatSect)
+	}
+	firstDIFAT := uint32(len(fat))
+	for range numDIFAT {
+		fat = append(fat, difSect)
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, Signature)
+	le := binary.LittleEndian
+	le.PutUint16(header[24:], 0x003E)
+	le.PutUint16(header[26:], 3)
+	le.PutUint16(header[28:], 0xFFFE)
+	le.PutUint16(header[30:], 9)
+	le.PutUint16(header[32:], 6)
+	le.PutUint32(header[44:], uint32(numFAT))
+	le.PutUint32(header[48:], dirStart)
+	le.PutUint32(header[56:], miniCutoff)
+	le.PutUint32(header[60:], miniFATStart)
+	le.PutUint32(header[64:], uint32(len(miniTable)+sectorSize-1)/sectorSize)
+	le.PutUint32(header[68:], endOfChain)
+	if numDIFAT > 0 {
+		le.PutUint32(header[68:], firstDIFAT)
+	}
+	le.PutUint32(header[72:], uint32(numDIFAT))
+	for i := range headerDIFAT {
+		sid := uint32(freeSect)
+		if i < numFAT {
+			sid = firstFAT + uint32(i)
+		}
+		le.PutUint32(header[76+4*i:], sid)
+	}
+
+	table := make([]byte, numFAT*sectorSize)
+	for i := range table {
+		table[i] = 0xFF
+	}
+	for i, sid := range fat {
+		le.PutUint32(table[4*i:], sid)
+	}
+	difat := make([]byte, numDIFAT*sectorSize)
+	for i := range difat {
+		difat[i] = 0xFF
+	}
+	for i := headerDIFAT; i < numFAT; i++ {
+		n := i - headerDIFAT
+		le.PutUint32(difat[(n/(perFATSector-1))*sectorSize+4*(n%(perFATSector-1)):], firstFAT+uint32(i))
+	}
+	for d := range numDIFAT {
+		next := uint32(endOfChain)
+		if d < numDIFAT-1 {
+			next = firstDIFAT + uint32(d+1)
+		}
+		le.PutUint32(difat[d*sectorSize+sectorSize-4:], next)
+	}
+
+	for _, part := range [][]byte{header, sectors, table, difat} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareNames orders directory entries: shorter names first, then by their
+// upper-case code points.
+func compareNames(a, b string) int {
+	ua, ub := utf16.Encode([]rune(strings.ToUpper(a))), utf16.Encode([]rune(strings.ToUpper(b)))
+	if len(ua) != len(ub) {
+		return len(ua) - len(ub)
+	}
+	return slices.Compare(ua, ub)
+}
+
+// balance links the sorted entries [lo, hi) into a balanced binary tree and
+// returns its root. Nodes on the deepest, incomplete level are red and all
+// others black, which keeps the tree a valid red-black tree.
+func balance(entries []entry, colors []byte, lo, hi, depth, maxDepth int) uint32 {
+	if lo >= hi {
+		return noStream
+	}
+	mid := (lo + hi) / 2
+	entries[mid].left = balance(entries, colors, lo, mid, depth+1, maxDepth)
+	entries[mid].right = balance(entries, colors, mid+1, hi, depth+1, maxDepth)
+	colors[mid] = colorBlack
+	if depth == maxDepth {
+		colors[mid] = colorRed
+	}
+	return uint32(mid)
+}
+
+// treeDepth returns the depth of the deepest level of a balanced tree of n
+// nodes when that level is incomplete, and -1 otherwise.
+func treeDepth(n int) int {
+	depth, full := 0, 1
+	for full < n {
+		depth++
+		full = full*2 + 1
+	}
+	if full == n {
+		return -1
+	}
+	return depth
+}
+
+func (e entry) encode(color byte) []byte {
+	b := make([]byte, entrySize)
+	le := binary.LittleEndian
+	units := utf16.Encode([]rune(e.name))
+	for i, u := range units {
+		le.PutUint16(b[2*i:], u)
+	}
+	le.PutUint16(b[64:], uint16(2*len(units)+2))
+	b[66], b[67] = e.kind, color
+	le.PutUint32(b[68:], e.left)
+	le.PutUint32(b[72:], e.right)
+	le.PutUint32(b[76:], e.child)
+	le.PutUint32(b[116:], e.start)
+	le.PutUint64(b[120:], e.size)
+	return b
+}
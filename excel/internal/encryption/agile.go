@@ -0,0 +1,375 @@
+// Package encryption encrypts and decrypts OOXML packages with the agile
+// encryption of ECMA-376 (MS-OFFCRYPTO), stored in the EncryptionInfo and
+// EncryptedPackage streams of a compound file.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+// Names of the compound file streams holding an encrypted package.
+const (
+	InfoStream    = "EncryptionInfo"
+	PackageStream = "EncryptedPackage"
+)
+
+// ErrPassword is returned when a password does not open an encrypted package.
+var ErrPassword = errors.New("incorrect password")
+
+const (
+	segmentSize  = 4096
+	spinCount    = 100000
+	maxSpinCount = 10000000 // Upper bound of MS-OFFCRYPTO
+	agileFlags   = 0x40
+
+	passwordURI = "http://schemas.microsoft.com/office/2006/keyEncryptor/password"
+)
+
+// Block keys that derive the keys and initialization vectors of each value.
+var (
+	blockVerifierInput = []byte{0xfe, 0xa7, 0xd2, 0x76, 0x3b, 0x4b, 0x9e, 0x79}
+	blockVerifierHash  = []byte{0xd7, 0xaa, 0x0f, 0x6d, 0x30, 0x61, 0x34, 0x4e}
+	blockKeyValue      = []byte{0x14, 0x6e, 0x0b, 0xe7, 0xab, 0xac, 0xd0, 0xd6}
+	blockHmacKey       = []byte{0x5f, 0xb2, 0xad, 0x01, 0x0c, 0xb9, 0xe1, 0xf6}
+	blockHmacValue     = []byte{0xa0, 0x67, 0x7f, 0x02, 0xb2, 0x2c, 0x84, 0x33}
+)
+
+var hashes = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA384": sha512.New384,
+	"SHA512": sha512.New,
+}
+
+// params are the cipher and hash settings shared by keyData and encryptedKey.
+type params struct {
+	SaltSize        int    `xml:"saltSize,attr"`
+	BlockSize       int    `xml:"blockSize,attr"`
+	KeyBits         int    `xml:"keyBits,attr"`
+	HashSize        int    `xml:"hashSize,attr"`
+	CipherAlgorithm string `xml:"cipherAlgorithm,attr"`
+	CipherChaining  string `xml:"cipherChaining,attr"`
+	HashAlgorithm   string `xml:"hashAlgorithm,attr"`
+	SaltValue       string `xml:"saltValue,attr"`
+}
+
+type passwordKey struct {
+	params
+	SpinCount                  int    `xml:"spinCount,attr"`
+	EncryptedVerifierHashInput string `xml:"encryptedVerifierHashInput,attr"`
+	EncryptedVerifierHashValue string `xml:"encryptedVerifierHashValue,attr"`
+	EncryptedKeyValue          string `xml:"encryptedKeyValue,attr"`
+}
+
+type descriptor struct {
+	KeyData       params `xml:"keyData"`
+	DataIntegrity *struct {
+		EncryptedHmacKey   string `xml:"encryptedHmacKey,attr"`
+		EncryptedHmacValue string `xml:"encryptedHmacValue,attr"`
+	} `xml:"dataIntegrity"`
+	KeyEncryptors []struct {
+		URI string       `xml:"uri,attr"`
+		Key *passwordKey `xml:"http://schemas.microsoft.com/office/2006/keyEncryptor/password encryptedKey"`
+	} `xml:"keyEncryptors>keyEncryptor"`
+}
+
+// Decrypt returns the package held by the EncryptionInfo stream info and the
+// EncryptedPackage stream data, checking its integrity when the stream
+// records an HMAC.
+func Decrypt(info, data []byte, password string) ([]byte, error) {
+	if len(info) < 8 {
+		return nil, fmt.Errorf("encryption info is truncated")
+	}
+	major, minor := binary.LittleEndian.Uint16(info), binary.LittleEndian.Uint16(info[2:])
+	if major != 4 || minor != 4 {
+		return nil, fmt.Errorf("unsupported encryption version %d.%d; only agile encryption is supported", major, minor)
+	}
+	var desc descriptor
+	if err := xml.Unmarshal(info[8:], &desc); err != nil {
+		return nil, fmt.Errorf("parse encryption info: %w", err)
+	}
+	var pk *passwordKey
+	for _, ke := range desc.KeyEncryptors {
+		if ke.URI == passwordURI && ke.Key != nil {
+			pk = ke.Key
+		}
+	}
+	if pk == nil {
+		return nil, fmt.Errorf("package is not encrypted with a password")
+	}
+	if pk.SpinCount < 0 || pk.SpinCount > maxSpinCount {
+		return nil, fmt.Errorf("invalid spin count %d", pk.SpinCount)
+	}
+	kd := desc.KeyData
+	newHash, err := checkParams(kd)
+	if err != nil {
+		return nil, err
+	}
+	keyHash, err := checkParams(pk.params)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(pk.SaltValue)
+	if err != nil {
+		return nil, fmt.Errorf("decode password salt: %w", err)
+	}
+	h := passwordHash(keyHash, password, salt, pk.SpinCount)
+	decryptKey := func(block []byte, value string, size int) ([]byte, error) {
+		ciphertext, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		key := resize(digest(keyHash, h, block), pk.KeyBits/8)
+		plain, err := crypt(false, key, resize(salt, pk.BlockSize), ciphertext)
+		if err != nil || len(plain) < size {
+			return nil, fmt.Errorf("invalid encrypted key data")
+		}
+		return plain[:size], nil
+	}
+	input, err := decryptKey(blockVerifierInput, pk.EncryptedVerifierHashInput, pk.SaltSize)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := decryptKey(blockVerifierHash, pk.EncryptedVerifierHashValue, pk.HashSize)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(digest(keyHash, input), verifier) {
+		return nil, ErrPassword
+	}
+	secret, err := decryptKey(blockKeyValue, pk.EncryptedKeyValue, pk.KeyBits/8)
+	if err != nil {
+		return nil, err
+	}
+
+	keySalt, err := base64.StdEncoding.DecodeString(kd.SaltValue)
+	if err != nil {
+		return nil, fmt.Errorf("decode key salt: %w", err)
+	}
+	if di := desc.DataIntegrity; di != nil {
+		hmacKey, err := decryptValue(newHash, kd, secret, keySalt, blockHmacKey, di.EncryptedHmacKey)
+		if err != nil {
+			return nil, err
+		}
+		hmacValue, err := decryptValue(newHash, kd, secret, keySalt, blockHmacValue, di.EncryptedHmacValue)
+		if err != nil {
+			return nil, err
+		}
+		mac := hmac.New(newHash, hmacKey[:kd.HashSize])
+		mac.Write(data)
+		if !hmac.Equal(mac.Sum(nil), hmacValue[:kd.HashSize]) {
+			return nil, fmt.Errorf("encrypted package failed its integrity check")
+		}
+	}
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("encrypted package is truncated")
+	}
+	size := binary.LittleEndian.Uint64(data)
+	data = data[8:]
+	plain := make([]byte, 0, len(data))
+	for i := 0; len(data) > 0; i++ {
+		segment := data[:min(segmentSize, len(data))]
+		data = data[len(segment):]
+		iv := resize(digest(newHash, keySalt, binary.LittleEndian.AppendUint32(nil, uint32(i))), kd.BlockSize)
+		out, err := crypt(false, secret, iv, segment)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt package segment %d: %w", i, err)
+		}
+		plain = append(plain, out...)
+	}
+	if size > uint64(len(plain)) {
+		return nil, fmt.Errorf("encrypted package holds %d of %d bytes", len(plain), size)
+	}
+	return plain[:size], nil
+}
+
+// Encrypt encrypts a package with a password using AES-256 and SHA-512,
+// returning the EncryptionInfo and EncryptedPackage streams.
+func Encrypt(pkg []byte, password string) (info, data []byte, err error) {
+	kd := params{
+		SaltSize: 16, BlockSize: aes.BlockSize, KeyBits: 256, HashSize: sha512.Size,
+		CipherAlgorithm: "AES", CipherChaining: "ChainingModeCBC", HashAlgorithm: "SHA512",
+	}
+	keySalt, salt := random(kd.SaltSize), random(kd.SaltSize)
+	secret := random(kd.KeyBits / 8)
+
+	data = binary.LittleEndian.AppendUint64(nil, uint64(len(pkg)))
+	for i := 0; i*segmentSize < len(pkg); i++ {
+		segment := pkg[i*segmentSize : min((i+1)*segmentSize, len(pkg))]
+		if pad := len(segment) % kd.BlockSize; pad != 0 {
+			segment = append(bytes.Clone(segment), make([]byte, kd.BlockSize-pad)...)
+		}
+		iv := resize(digest(sha512.New, keySalt, binary.LittleEndian.AppendUint32(nil, uint32(i))), kd.BlockSize)
+		out, err := crypt(true, secret, iv, segment)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = append(data, out...)
+	}
+
+	hmacKey := random(kd.HashSize)
+	mac := hmac.New(sha512.New, hmacKey)
+	mac.Write(data)
+	encryptValue := func(block, value []byte) (string, error) {
+		iv := resize(digest(sha512.New, keySalt, block), kd.BlockSize)
+		out, err := crypt(true, secret, iv, value)
+		return base64.StdEncoding.EncodeToString(out), err
+	}
+	encryptedHmacKey, err := encryptValue(blockHmacKey, hmacKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedHmacValue, err := encryptValue(blockHmacValue, mac.Sum(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := passwordHash(sha512.New, password, salt, spinCount)
+	encryptKey := func(block, value []byte) (string, error) {
+		key := resize(digest(sha512.New, h, block), kd.KeyBits/8)
+		out, err := crypt(true, key, salt, value)
+		return base64.StdEncoding.EncodeToString(out), err
+	}
+	input := random(kd.SaltSize)
+	encryptedInput, err := encryptKey(blockVerifierInput, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedVerifier, err := encryptKey(blockVerifierHash, digest(sha512.New, input))
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedKey, err := encryptKey(blockKeyValue, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attrs := func(salt []byte) string {
+		return fmt.Sprintf(`saltSize="%d" blockSize="%d" keyBits="%d" hashSize="%d" cipherAlgorithm="%s" cipherChaining="%s" hashAlgorithm="%s" saltValue="%s"`,
+			kd.SaltSize, kd.BlockSize, kd.KeyBits, kd.HashSize, kd.CipherAlgorithm, kd.CipherChaining, kd.HashAlgorithm,
+			base64.StdEncoding.EncodeToString(salt))
+	}
+	descriptor := "<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\r\n" +
+		`<encryption xmlns="http://schemas.microsoft.com/office/2006/encryption" xmlns:p="` + passwordURI + `">` +
+		`<keyData ` + attrs(keySalt) + `/>` +
+		`<dataIntegrity encryptedHmacKey="` + encryptedHmacKey + `" encryptedHmacValue="` + encryptedHmacValue + `"/>` +
+		`<keyEncryptors><keyEncryptor uri="` + passwordURI + `">` +
+		fmt.Sprintf(`<p:encryptedKey spinCount="%d" %s encryptedVerifierHashInput="%s" encryptedVerifierHashValue="%s" encryptedKeyValue="%s"/>`,
+			spinCount, attrs(salt), encryptedInput, encryptedVerifier, encryptedKey) +
+		`</keyEncryptor></keyEncryptors></encryption>`
+
+	info = binary.LittleEndian.AppendUint16(nil, 4)
+	info = binary.LittleEndian.AppendUint16(info, 4)
+	info = binary.LittleEndian.AppendUint32(info, agileFlags)
+	return append(info, descriptor...), data, nil
+}
+
+// checkParams returns the hash function of supported cipher settings.
+func checkParams(p params) (func() hash.Hash, error) {
+	newHash, ok := hashes[p.HashAlgorithm]
+	switch {
+	case p.CipherAlgorithm != "AES" || p.CipherChaining != "ChainingModeCBC":
+		return nil, fmt.Errorf("unsupported cipher %s %s", p.CipherAlgorithm, p.CipherChaining)
+	case p.KeyBits != 128 && p.KeyBits != 192 && p.KeyBits != 256:
+		return nil, fmt.Errorf("unsupported key size %d", p.KeyBits)
+	case p.BlockSize != aes.BlockSize:
+		return nil, fmt.Errorf("unsupported block size %d", p.BlockSize)
+	case !ok:
+		return nil, fmt.Errorf("unsupported hash algorithm %s", p.HashAlgorithm)
+	case p.HashSize != newHash().Size():
+		return nil, fmt.Errorf("hash size %d does not match %s", p.HashSize, p.HashAlgorithm)
+	}
+	return newHash, nil
+}
+
+// passwordHash hashes the salted UTF-16LE password, then rehashes the result
+// once per spin, each time prefixed with the iteration number.
+func passwordHash(newHash func() hash.Hash, password string, salt []byte, spins int) []byte {
+	var encoded []byte
+	for _, u := range utf16.Encode([]rune(password)) {
+		encoded = binary.LittleEndian.AppendUint16(encoded, u)
+	}
+	h := newHash()
+	h.Write(salt)
+	h.Write(encoded)
+	sum := h.Sum(nil)
+	var iteration [4]byte
+	for i := range spins {
+		binary.LittleEndian.PutUint32(iteration[:], uint32(i))
+		h.Reset()
+		h.Write(iteration[:])
+		h.Write(sum)
+		sum = h.Sum(sum[:0])
+	}
+	return sum
+}
+
+// decryptValue decrypts a value encrypted with the secret key and an
+// initialization vector derived from the key salt and a block key.
+func decryptValue(newHash func() hash.Hash, kd params, secret, keySalt, block []byte, value string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := crypt(false, secret, resize(digest(newHash, keySalt, block), kd.BlockSize), ciphertext)
+	if err != nil || len(plain) < kd.HashSize {
+		return nil, fmt.Errorf("invalid data integrity values")
+	}
+	return plain, nil
+}
+
+func digest(newHash func() hash.Hash, parts ...[]byte) []byte {
+	h := newHash()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// resize truncates b to n bytes or pads it with 0x36.
+func resize(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b[:n]
+	}
+	return append(bytes.Clone(b), bytes.Repeat([]byte{0x36}, n-len(b))...)
+}
+
+// crypt encrypts or decrypts data, a multiple of the block size, with AES-CBC.
+func crypt(encrypt bool, key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("data is not a multiple of the cipher block size")
+	}
+	out := make([]byte, len(data))
+	if encrypt {
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, data)
+	} else {
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	}
+	return out, nil
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
@@ -0,0 +1,235 @@
+package formula
+
+import (
+	"slices"
+	"strings"
+)
+
+func init() {
+	register("COUNTIF", eager(2, 2, func(args []Value) Value {
+		return conditional(nil, args, func(matched []float64, n int) Value { return Number(float64(n)) })
+	}))
+	register("COUNTIFS", eager(2, -1, func(args []Value) Value {
+		return conditional(nil, args, func(matched []float64, n int) Value { return Number(float64(n)) })
+	}))
+	register("SUMIF", eager(2, 3, func(args []Value) Value {
+		target := args[0]
+		if len(args) == 3 {
+			target = args[2]
+		}
+		return conditional(&target, args[:2], sumOf)
+	}))
+	register("SUMIFS", eager(3, -1, func(args []Value) Value {
+		return conditional(&args[0], args[1:], sumOf)
+	}))
+	register("AVERAGEIF", eager(2, 3, func(args []Value) Value {
+		target := args[0]
+		if len(args) == 3 {
+			target = args[2]
+		}
+		return conditional(&target, args[:2], averageOf)
+	}))
+	register("AVERAGEIFS", eager(3, -1, func(args []Value) Value {
+		return conditional(&args[0], args[1:], averageOf)
+	}))
+	register("MAXIFS", eager(3, -1, func(args []Value) Value {
+		return conditional(&args[0], args[1:], func(matched []float64, _ int) Value {
+			if len(matched) == 0 {
+				return Number(0)
+			}
+			return Number(slices.Max(matched))
+		})
+	}))
+	register("MINIFS", eager(3, -1, func(args []Value) Value {
+		return conditional(&args[0], args[1:], func(matched []float64, _ int) Value {
+			if len(matched) == 0 {
+				return Number(0)
+			}
+			return Number(slices.Min(matched))
+		})
+	}))
+}
+
+func sumOf(matched []float64, _ int) Value {
+	sum := 0.0
+	for _, n := range matched {
+		sum += n
+	}
+	return Number(sum)
+}
+
+func averageOf(matched []float64, _ int) Value {
+	if len(matched) == 0 {
+		return Error(ErrDiv0)
+	}
+	return Number(sumOf(matched, 0).Num / float64(len(matched)))
+}
+
+// conditional evaluates the *IF and *IFS family. pairs holds range/criterion pairs;
+// cells matching every criterion contribute the numbers at the same position in
+// target, and reduce receives those numbers along with the count of matching cells.
+func conditional(target *Value, pairs []Value, reduce func(matched []float64, n int) Value) Value {
+	if len(pairs)%2 != 0 {
+		return Error(ErrValue)
+	}
+	ranges := make([][][]Value, 0, len(pairs)/2)
+	crits := make([]criterion, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		r := asArray(pairs[i])
+		if len(ranges) > 0 && (len(r) != len(ranges[0]) || len(r[0]) != len(ranges[0][0])) {
+			return Error(ErrValue)
+		}
+		ranges = append(ranges, r)
+		crits = append(crits, parseCriterion(pairs[i+1].Scalar()))
+	}
+
+	var values [][]Value
+	if target != nil {
+		values = asArray(*target)
+	}
+	var matched []float64
+	n := 0
+	for i := range ranges[0] {
+		for j := range ranges[0][i] {
+			ok := true
+			for k, r := range ranges {
+				if !crits[k].match(r[i][j]) {
+					ok = false
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+			n++
+			if values == nil || i >= len(values) || j >= len(values[i]) {
+				continue
+			}
+			v := values[i][j]
+			if v.IsError() {
+				return v
+			}
+			if v.Kind == KindNumber {
+				matched = append(matched, v.Num)
+			}
+		}
+	}
+	return reduce(matched, n)
+}
+
+// criterion is a condition such as ">=10", "<>", "app*" or 42, as used by COUNTIF.
+type criterion struct {
+	op  string
+	val Value
+}
+
+func parseCriterion(v Value) criterion {
+	if v.Kind != KindString {
+		return criterion{op: "=", val: v}
+	}
+	s, op := v.Str, "="
+	for _, candidate := range []string{"<=", ">=", "<>", "=", "<", ">"} {
+		if strings.HasPrefix(s, candidate) {
+			s, op = s[len(candidate):], candidate
+			break
+		}
+	}
+	c := criterion{op: op, val: String(s)}
+	if s == "" {
+		c.val = empty
+	} else if n, ok := parseNumber(s); ok {
+		c.val = Number(n)
+	} else if strings.EqualFold(s, "TRUE") || strings.EqualFold(s, "FALSE") {
+		c.val = Bool(strings.EqualFold(s, "TRUE"))
+	} else if s[0] == '#' {
+		c.val = Error(strings.ToUpper(s))
+	}
+	return c
+}
+
+func (c criterion) match(v Value) bool {
+	if c.val.Kind == KindEmpty {
+		blank := v.Kind == KindEmpty || (v.Kind == KindString && v.Str == "")
+		switch c.op {
+		case "=":
+			return blank
+		case "<>":
+			return !blank
+		}
+		return false
+	}
+	if v.IsError() || c.val.IsError() {
+		equal := v.IsError() && c.val.IsError() && v.Str == c.val.Str
+		return (c.op == "=" && equal) || (c.op == "<>" && !equal)
+	}
+
+	if c.val.Kind == KindNumber && v.Kind == KindString && c.op == "=" {
+		if n, ok := parseNumber(v.Str); ok {
+			v = Number(n)
+		}
+	}
+	if v.Kind != c.val.Kind {
+		return c.op == "<>"
+	}
+	if v.Kind == KindString && (c.op == "=" || c.op == "<>") {
+		return wildcardMatch(c.val.Str, v.Str) == (c.op == "=")
+	}
+
+	cmp := compare(v, c.val)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp >= 0
+}
+
+// wildcardMatch reports whether s matches pattern, where * matches any run of
+// characters, ? matches one character and ~ escapes either. Case is ignored.
+func wildcardMatch(pattern, s string) bool {
+	return glob([]rune(strings.ToLower(pattern)), []rune(strings.ToLower(s)), false)
+}
+
+// wildcardPrefix reports whether a prefix of s matches pattern.
+func wildcardPrefix(pattern, s string) bool {
+	return glob([]rune(strings.ToLower(pattern)), []rune(strings.ToLower(s)), true)
+}
+
+func glob(p, s []rune, prefix bool) bool {
+	pi, si, star, mark := 0, 0, -1, 0
+	for si < len(s) {
+		if prefix && pi == len(p) {
+			return true
+		}
+		switch {
+		case pi+1 < len(p) && p[pi] == '~' && strings.ContainsRune("*?~", p[pi+1]):
+			if p[pi+1] == s[si] {
+				pi, si = pi+2, si+1
+				continue
+			}
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, si
+			pi++
+			continue
+		case pi < len(p) && (p[pi] == '?' || p[pi] == s[si]):
+			pi, si = pi+1, si+1
+			continue
+		}
+		if star < 0 {
+			return false
+		}
+		pi, mark = star+1, mark+1
+		si = mark
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
@@ -0,0 +1,377 @@
+package formula
+
+import (
+	"math"
+	"time"
+)
+
+// maxNameDepth bounds the expansion of defined names that refer to each other.
+const maxNameDepth = 32
+
+// Context supplies the workbook data a formula is evaluated against.
+type Context interface {
+	// Origin returns the sheet, column and row of the cell being calculated.
+	Origin() (sheet string, col, row int)
+
+	// Cell returns the value of a cell.
+	Cell(sheet string, col, row int) Value
+
+	// Dimension returns the last used column and row of a sheet.
+	// It reports false when the sheet does not exist.
+	Dimension(sheet string) (maxCol, maxRow int, ok bool)
+
+	// Name returns the parsed definition of a defined name visible from sheet.
+	Name(sheet, name string) (Node, bool)
+
+	// Table returns the layout of a table by name or, when name is empty, of
+	// the table containing the cell being calculated.
+	Table(name string) (Table, bool)
+
+	// Date1904 reports whether serial dates count from 1904 instead of 1900.
+	Date1904() bool
+
+	// Now returns the current time used by NOW and TODAY.
+	Now() time.Time
+}
+
+type evaluator struct {
+	ctx   Context
+	depth int
+}
+
+// Eval evaluates a parsed formula. The result is a scalar unless the formula yields an array.
+func Eval(n Node, ctx Context) Value {
+	return (&evaluator{ctx: ctx}).eval(n)
+}
+
+func (ev *evaluator) eval(n Node) Value {
+	switch v := n.(type) {
+	case NumberNode:
+		return Number(v.Value)
+	case StringNode:
+		return String(v.Value)
+	case BoolNode:
+		return Bool(v.Value)
+	case ErrorNode:
+		return Error(v.Code)
+	case MissingNode:
+		return empty
+	case RefNode:
+		return ev.refValue(v.Ref)
+	case NameNode:
+		return ev.evalName(v, ev.eval)
+	case TableRefNode:
+		ref, ok := TableReference(v, ev.ctx)
+		if !ok {
+			return Error(ErrRef)
+		}
+		return ev.refValue(ref)
+	case FuncNode:
+		fn, ok := functions[v.Name]
+		if !ok {
+			return Error(ErrName)
+		}
+		return fn(ev, v.Args)
+	case UnaryNode:
+		return ev.unary(v.Op, ev.eval(v.Operand))
+	case BinaryNode:
+		if v.Op == ":" {
+			ref, ok := ev.evalRef(v)
+			if !ok {
+				return Error(ErrRef)
+			}
+			return ev.refValue(ref)
+		}
+		return ev.binary(v.Op, ev.eval(v.Left), ev.eval(v.Right))
+	case ArrayNode:
+		rows := make([][]Value, len(v.Rows))
+		for i, row := range v.Rows {
+			rows[i] = make([]Value, len(row))
+			for j, item := range row {
+				rows[i][j] = ev.eval(item).Scalar()
+			}
+		}
+		return Array(rows)
+	}
+	return Error(ErrValue)
+}
+
+func (ev *evaluator) evalName(n NameNode, eval func(Node) Value) Value {
+	sheet := n.Sheet
+	if sheet == "" {
+		sheet, _, _ = ev.ctx.Origin()
+	}
+	def, ok := ev.ctx.Name(sheet, n.Name)
+	if !ok || ev.depth >= maxNameDepth {
+		return Error(ErrName)
+	}
+	ev.depth++
+	defer func() { ev.depth-- }()
+	return eval(def)
+}
+
+// evalRef resolves n to a reference without reading the cells it covers.
+func (ev *evaluator) evalRef(n Node) (Reference, bool) {
+	switch v := n.(type) {
+	case RefNode:
+		if v.Ref.Invalid {
+			return Reference{}, false
+		}
+		ref := v.Ref
+		if ref.Sheet == "" {
+			ref.Sheet, _, _ = ev.ctx.Origin()
+		}
+		return ref, true
+	case TableRefNode:
+		return TableReference(v, ev.ctx)
+	case NameNode:
+		var ref Reference
+		ok := false
+		ev.evalName(v, func(def Node) Value {
+			ref, ok = ev.evalRef(def)
+			return empty
+		})
+		return ref, ok
+	case BinaryNode:
+		if v.Op != ":" {
+			return Reference{}, false
+		}
+		left, ok1 := ev.evalRef(v.Left)
+		right, ok2 := ev.evalRef(v.Right)
+		if !ok1 || !ok2 || left.Sheet != right.Sheet {
+			return Reference{}, false
+		}
+		return Reference{
+			Sheet:   left.Sheet,
+			Col1:    min(left.Col1, right.Col1),
+			Row1:    min(left.Row1, right.Row1),
+			Col2:    max(left.Col2, right.Col2),
+			Row2:    max(left.Row2, right.Row2),
+			IsRange: true,
+		}, true
+	case FuncNode:
+		if fn, ok := refFunctions[v.Name]; ok {
+			return fn(ev, v.Args)
+		}
+	}
+	return Reference{}, false
+}
+
+// refValue reads the cells covered by a reference. Single cells yield a scalar and ranges an array.
+func (ev *evaluator) refValue(ref Reference) Value {
+	if ref.Invalid {
+		return Error(ErrRef)
+	}
+	sheet := ref.Sheet
+	if sheet == "" {
+		sheet, _, _ = ev.ctx.Origin()
+	}
+	if ref.IsCell() {
+		if _, _, ok := ev.ctx.Dimension(sheet); !ok {
+			return Error(ErrRef)
+		}
+		return ev.ctx.Cell(sheet, ref.Col1, ref.Row1)
+	}
+
+	maxCol, maxRow, ok := ev.ctx.Dimension(sheet)
+	if !ok {
+		return Error(ErrRef)
+	}
+	col1, row1, col2, row2 := ref.Area(maxCol, maxRow)
+	if ref.Row1 == 0 && row2 < row1 || ref.Col1 == 0 && col2 < col1 {
+		return Array([][]Value{{empty}})
+	}
+	rows := make([][]Value, row2-row1+1)
+	for r := range rows {
+		rows[r] = make([]Value, col2-col1+1)
+		for c := range rows[r] {
+			rows[r][c] = ev.ctx.Cell(sheet, col1+c, row1+r)
+		}
+	}
+	return Array(rows)
+}
+
+func (ev *evaluator) unary(op string, v Value) Value {
+	if v.Kind == KindArray {
+		return mapArray(v, func(item Value) Value { return ev.unary(op, item) })
+	}
+	if op == "+" {
+		return v
+	}
+	n, errv := v.ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if op == "%" {
+		return Number(n / 100)
+	}
+	return Number(-n)
+}
+
+func (ev *evaluator) binary(op string, l, r Value) Value {
+	if l.Kind == KindArray || r.Kind == KindArray {
+		return broadcast(l, r, func(a, b Value) Value { return ev.binary(op, a, b) })
+	}
+	if l.IsError() {
+		return l
+	}
+	if r.IsError() {
+		return r
+	}
+
+	switch op {
+	case "&":
+		return String(l.String() + r.String())
+	case "=", "<>", "<", ">", "<=", ">=":
+		c := compare(l, r)
+		switch op {
+		case "=":
+			return Bool(c == 0)
+		case "<>":
+			return Bool(c != 0)
+		case "<":
+			return Bool(c < 0)
+		case ">":
+			return Bool(c > 0)
+		case "<=":
+			return Bool(c <= 0)
+		}
+		return Bool(c >= 0)
+	}
+
+	a, errv := l.ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	b, errv := r.ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	switch op {
+	case "+":
+		return Number(a + b)
+	case "-":
+		return Number(a - b)
+	case "*":
+		return Number(a * b)
+	case "/":
+		if b == 0 {
+			return Error(ErrDiv0)
+		}
+		return Number(a / b)
+	case "^":
+		if a == 0 && b == 0 {
+			return Error(ErrNum)
+		}
+		return Number(math.Pow(a, b))
+	}
+	return Error(ErrValue)
+}
+
+// broadcast applies fn element-wise, expanding single rows and columns to match the other operand.
+func broadcast(l, r Value, fn func(a, b Value) Value) Value {
+	la, ra := asArray(l), asArray(r)
+	rows := max(len(la), len(ra))
+	cols := max(len(la[0]), len(ra[0]))
+	out := make([][]Value, rows)
+	for i := range out {
+		out[i] = make([]Value, cols)
+		for j := range out[i] {
+			a, okA := arrayAt(la, i, j)
+			b, okB := arrayAt(ra, i, j)
+			if !okA || !okB {
+				out[i][j] = Error(ErrNA)
+				continue
+			}
+			out[i][j] = fn(a, b)
+		}
+	}
+	return Array(out)
+}
+
+func asArray(v Value) [][]Value {
+	if v.Kind == KindArray && len(v.Array) > 0 && len(v.Array[0]) > 0 {
+		return v.Array
+	}
+	return [][]Value{{v.Scalar()}}
+}
+
+func arrayAt(a [][]Value, i, j int) (Value, bool) {
+	if len(a) == 1 {
+		i = 0
+	}
+	if len(a[0]) == 1 {
+		j = 0
+	}
+	if i >= len(a) || j >= len(a[i]) {
+		return empty, false
+	}
+	return a[i][j], true
+}
+
+func mapArray(v Value, fn func(Value) Value) Value {
+	out := make([][]Value, len(v.Array))
+	for i, row := range v.Array {
+		out[i] = make([]Value, len(row))
+		for j, item := range row {
+			out[i][j] = fn(item)
+		}
+	}
+	return Array(out)
+}
+
+// References returns the references and names a formula depends on.
+func References(n Node) ([]Reference, []NameNode) {
+	var refs []Reference
+	var names []NameNode
+	Walk(n, func(child Node) {
+		switch v := child.(type) {
+		case RefNode:
+			if !v.Ref.Invalid {
+				refs = append(refs, v.Ref)
+			}
+		case NameNode:
+			names = append(names, v)
+		}
+	})
+	return refs, names
+}
+
+// TableReference resolves a structured reference for the cell being calculated.
+func TableReference(n TableRefNode, ctx Context) (Reference, bool) {
+	t, ok := ctx.Table(n.Table)
+	if !ok {
+		return Reference{}, false
+	}
+	_, _, row := ctx.Origin()
+	return t.Reference(n.Spec, row)
+}
+
+// IsSupported reports whether every function a formula calls is implemented.
+func IsSupported(n Node) bool {
+	supported := true
+	Walk(n, func(child Node) {
+		if fn, ok := child.(FuncNode); ok {
+			if _, ok := functions[fn.Name]; !ok {
+				if _, ok := refFunctions[fn.Name]; !ok {
+					supported = false
+				}
+			}
+		}
+	})
+	return supported
+}
+
+// IsVolatile reports whether a formula must be recalculated on every calculation.
+func IsVolatile(n Node) bool {
+	volatile := false
+	Walk(n, func(child Node) {
+		if fn, ok := child.(FuncNode); ok {
+			switch fn.Name {
+			case "NOW", "TODAY", "RAND", "RANDBETWEEN", "OFFSET", "INDIRECT":
+				volatile = true
+			}
+		}
+	})
+	return volatile
+}
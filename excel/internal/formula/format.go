@@ -0,0 +1,935 @@
+package formula
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+func fnText(ev *evaluator, args []Value) Value {
+	if errv := firstError(args...); errv != nil {
+		return *errv
+	}
+	code := args[1].String()
+	v := args[0]
+	if v.Kind == KindString {
+		n, ok := parseNumber(v.Str)
+		if !ok {
+			return String(FormatText(v.Str, code))
+		}
+		v = Number(n)
+	}
+	n, errv := v.ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	return String(FormatValue(n, code, ev.ctx.Date1904()))
+}
+
+// FormatValue renders a number as Excel displays it with a number format code,
+// e.g. "#,##0.00;[Red]-#,##0.00", "0.0%", "# ?/?", "0.00E+00" or
+// "yyyy-mm-dd hh:mm". Numbers that no section of the code applies to, such as
+// negative dates, are rendered as with the General format.
+func FormatValue(v float64, code string, date1904 bool) string {
+	sec, signed, ok := parseFormat(code).numberSection(v)
+	if !ok {
+		return FormatNumber(v)
+	}
+	if sec.date {
+		if v < 0 {
+			return FormatNumber(v)
+		}
+		return sec.formatDate(v, date1904)
+	}
+	text := sec.formatNumber(math.Abs(v))
+	if signed && v < 0 {
+		return "-" + text
+	}
+	return text
+}
+
+// FormatText renders text with the text section of a number format code, the
+// fourth section or a single section holding "@". Text is returned unchanged
+// by codes without one.
+func FormatText(s, code string) string {
+	f := parseFormat(code)
+	if f.text == nil {
+		return s
+	}
+	var sb strings.Builder
+	for _, tok := range f.text.tokens {
+		switch tok.kind {
+		case tokText:
+			sb.WriteString(s)
+		case tokLiteral:
+			sb.WriteString(tok.text)
+		}
+	}
+	return sb.String()
+}
+
+// FormatColor returns the color of the section a number format code applies to
+// v, such as "Red" or "Color10", or "" when the section has none.
+func FormatColor(v float64, code string) string {
+	sec, _, ok := parseFormat(code).numberSection(v)
+	if !ok {
+		return ""
+	}
+	return sec.color
+}
+
+type tokenKind int
+
+const (
+	tokLiteral tokenKind = iota
+	tokDigit             // Digit placeholder 0, # or ?
+	tokPoint             // Decimal point
+	tokComma             // Thousands separator or scaling comma
+	tokExp               // Exponent marker E+, E-, e+ or e-
+	tokSlash             // Fraction bar
+	tokText              // Text placeholder @
+	tokGeneral           // General number
+	tokDate              // Date or time part, e.g. "yyyy", "mm", "[h]", ".00" or "AM/PM"
+)
+
+type fmtToken struct {
+	kind tokenKind
+	text string
+}
+
+// condition is a section condition such as [>=100].
+type condition struct {
+	op    string
+	value float64
+}
+
+func (c condition) match(v float64) bool {
+	switch c.op {
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	case ">=":
+		return v >= c.value
+	case "<>":
+		return v != c.value
+	}
+	return v == c.value
+}
+
+// section is one of the up to four ';'-separated sections of a format code.
+type section struct {
+	tokens   []fmtToken
+	color    string
+	cond     *condition
+	date     bool // Renders dates and times
+	hour12   bool // Has an AM/PM marker
+	percent  int  // Number of percent signs
+	scale    int  // Number of scaling commas, each dividing by 1000
+	grouping bool // Separates thousands
+}
+
+// numberFormat is a parsed format code.
+type numberFormat struct {
+	sections []*section // Number sections
+	text     *section   // Text section
+}
+
+var formatCache sync.Map // Format code -> *numberFormat
+
+func parseFormat(code string) *numberFormat {
+	if f, ok := formatCache.Load(code); ok {
+		return f.(*numberFormat)
+	}
+	f := &numberFormat{}
+	if code != "" && !strings.EqualFold(code, "General") {
+		parts := splitSections(code)
+		for i, part := range parts {
+			sec := parseSection(part)
+			switch {
+			case i == 3:
+				f.text = sec
+			case len(parts) == 1 && sec.hasKind(tokText) && !sec.hasKind(tokDigit) && !sec.date:
+				f.text = sec
+			case i < 3:
+				f.sections = append(f.sections, sec)
+			}
+		}
+	}
+	formatCache.Store(code, f)
+	return f
+}
+
+// numberSection selects the section rendering v, and reports whether v keeps
+// its sign: the negative section of a code without conditions renders the
+// absolute value.
+func (f *numberFormat) numberSection(v float64) (*section, bool, bool) {
+	secs := f.sections
+	if len(secs) == 0 {
+		return nil, false, false
+	}
+	if secs[0].cond == nil && (len(secs) < 2 || secs[1].cond == nil) {
+		switch {
+		case len(secs) == 1 || v > 0 || (v == 0 && len(secs) == 2):
+			return secs[0], true, true
+		case v < 0:
+			return secs[1], false, true
+		}
+		return secs[2], true, true
+	}
+
+	switch {
+	case secs[0].cond != nil && secs[0].cond.match(v):
+		return secs[0], true, true
+	case secs[0].cond == nil && v >= 0:
+		return secs[0], true, true
+	case len(secs) > 1 && (secs[1].cond == nil || secs[1].cond.match(v)):
+		return secs[1], true, true
+	case len(secs) > 2:
+		return secs[2], true, true
+	}
+	return nil, false, false
+}
+
+// splitSections splits a format code at semicolons outside quotes and brackets.
+func splitSections(code string) []string {
+	var parts []string
+	start := 0
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; {
+		case c == '\\' && !inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '[':
+			inBracket = true
+		case c == ']':
+			inBracket = false
+		case c == ';' && !inBracket:
+			parts = append(parts, code[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, code[start:])
+}
+
+func parseSection(s string) *section {
+	sec := &section{date: isDateSection(s)}
+	literal := func(text string) {
+		if n := len(sec.tokens); n > 0 && sec.tokens[n-1].kind == tokLiteral {
+			sec.tokens[n-1].text += text
+			return
+		}
+		sec.tokens = append(sec.tokens, fmtToken{kind: tokLiteral, text: text})
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			literal(s[i+1 : i+1+end])
+			i += end + 2
+			continue
+		case c == '\\' || c == '_' || c == '*':
+			_, size := utf8.DecodeRuneInString(s[min(i+1, len(s)):])
+			switch c {
+			case '\\':
+				literal(s[i+1 : i+1+size])
+			case '_':
+				literal(" ") // Space as wide as the next character
+			}
+			i += 1 + size // A '*' repeats the next character to fill the cell
+			continue
+		case c == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				literal(rest)
+				i = len(s)
+				continue
+			}
+			sec.bracket(rest[1:end], literal)
+			i += end + 1
+			continue
+		case len(rest) >= 7 && strings.EqualFold(rest[:7], "General"):
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokGeneral})
+			i += 7
+			continue
+		case c == '@':
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokText})
+		case c == '%':
+			sec.percent++
+			literal("%")
+		case sec.date:
+			n := sec.dateToken(rest)
+			if n == 0 {
+				_, n = utf8.DecodeRuneInString(rest)
+				literal(rest[:n])
+			}
+			i += n
+			continue
+		case c == '0' || c == '#' || c == '?':
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokDigit, text: string(c)})
+		case c == '.':
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokPoint})
+		case c == ',':
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokComma})
+		case (c == 'E' || c == 'e') && len(rest) > 1 && (rest[1] == '+' || rest[1] == '-'):
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokExp, text: rest[:2]})
+			i += 2
+			continue
+		case c == '/' && sec.hasKind(tokDigit):
+			sec.tokens = append(sec.tokens, fmtToken{kind: tokSlash})
+		default:
+			_, size := utf8.DecodeRuneInString(rest)
+			literal(rest[:size])
+			i += size
+			continue
+		}
+		i++
+	}
+	sec.classifyCommas()
+	return sec
+}
+
+// isDateSection reports whether a section holds date or time parts outside
+// quotes. A lone "m" is a month unless the section has digit placeholders.
+func isDateSection(s string) bool {
+	hasM := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			if end := strings.IndexByte(s[i+1:], '"'); end >= 0 {
+				i += end + 1
+			}
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return false
+			}
+			if isElapsed(s[i+1 : i+end]) {
+				return true
+			}
+			i += end
+		case len(s)-i >= 7 && strings.EqualFold(s[i:i+7], "General"):
+			i += 6
+		case strings.IndexByte("yYdDhHsS", c) >= 0:
+			return true
+		case c == 'm' || c == 'M':
+			hasM = true
+		case c == '#' || c == '?':
+			return false
+		case c == 'A' || c == 'a':
+			if hasAmPm(s[i:]) > 0 {
+				return true
+			}
+		}
+	}
+	return hasM
+}
+
+func isElapsed(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] != s[0] {
+			return false
+		}
+	}
+	return strings.IndexByte("hHmMsS", s[0]) >= 0
+}
+
+// hasAmPm returns the length of an AM/PM or A/P marker at the start of s.
+func hasAmPm(s string) int {
+	switch {
+	case len(s) >= 5 && strings.EqualFold(s[:5], "AM/PM"):
+		return 5
+	case len(s) >= 3 && strings.EqualFold(s[:3], "A/P"):
+		return 3
+	}
+	return 0
+}
+
+// bracket interprets the content of a [...] element.
+func (sec *section) bracket(content string, literal func(string)) {
+	lower := strings.ToLower(content)
+	switch {
+	case lower == "black" || lower == "blue" || lower == "cyan" || lower == "green" ||
+		lower == "magenta" || lower == "red" || lower == "white" || lower == "yellow":
+		sec.color = strings.ToUpper(lower[:1]) + lower[1:]
+	case strings.HasPrefix(lower, "color"):
+		if _, err := strconv.Atoi(lower[5:]); err == nil {
+			sec.color = "Color" + lower[5:]
+		}
+	case strings.HasPrefix(content, "$"):
+		// Currency and locale, e.g. [$€-407] or [$-409]
+		symbol, _, _ := strings.Cut(content[1:], "-")
+		if symbol != "" {
+			literal(symbol)
+		}
+	case isElapsed(content):
+		sec.tokens = append(sec.tokens, fmtToken{kind: tokDate, text: "[" + lower + "]"})
+	case content != "" && strings.IndexByte("<>=", content[0]) >= 0:
+		op := content[:1]
+		if len(content) > 1 && strings.IndexByte("<>=", content[1]) >= 0 {
+			op = content[:2]
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(content[len(op):]), 64); err == nil {
+			sec.cond = &condition{op: op, value: v}
+		}
+	}
+}
+
+// dateToken reads a date or time part at the start of s and returns its length,
+// or 0 when s does not start with one.
+func (sec *section) dateToken(s string) int {
+	if n := hasAmPm(s); n > 0 {
+		sec.hour12 = true
+		sec.tokens = append(sec.tokens, fmtToken{kind: tokDate, text: s[:n]})
+		return n
+	}
+	c := s[0] | 0x20 // Lower case
+	if s[0] == '.' && len(s) > 1 && s[1] == '0' {
+		n := 1
+		for n < len(s) && s[n] == '0' && n <= 3 {
+			n++
+		}
+		sec.tokens = append(sec.tokens, fmtToken{kind: tokDate, text: s[:n]})
+		return n
+	}
+	if strings.IndexByte("ymdhs", c) < 0 {
+		return 0
+	}
+	n := 1
+	for n < len(s) && s[n]|0x20 == c {
+		n++
+	}
+	sec.tokens = append(sec.tokens, fmtToken{kind: tokDate, text: strings.Repeat(string(c), n)})
+	return n
+}
+
+func (sec *section) hasKind(kind tokenKind) bool {
+	for _, tok := range sec.tokens {
+		if tok.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyCommas turns commas between digit placeholders of the integer part
+// into grouping, and commas after the last placeholder into scaling.
+func (sec *section) classifyCommas() {
+	end := len(sec.tokens)
+	for i, tok := range sec.tokens {
+		if tok.kind == tokExp || tok.kind == tokSlash {
+			end = i
+			break
+		}
+	}
+	digitBefore, pointSeen := false, false
+	for i := range end {
+		switch tok := &sec.tokens[i]; tok.kind {
+		case tokDigit:
+			digitBefore = true
+		case tokPoint:
+			pointSeen = true
+		case tokComma:
+			digitAfter := false
+			for _, next := range sec.tokens[i+1 : end] {
+				if next.kind == tokDigit {
+					digitAfter = true
+					break
+				}
+				if next.kind == tokPoint {
+					break
+				}
+			}
+			switch {
+			case digitBefore && digitAfter && !pointSeen:
+				sec.grouping = true
+			case digitBefore && !digitAfter:
+				sec.scale++
+			}
+			tok.kind, tok.text = tokLiteral, ""
+		}
+	}
+}
+
+// formatNumber renders a non-negative number.
+func (sec *section) formatNumber(v float64) string {
+	for range sec.percent {
+		v *= 100
+	}
+	for range sec.scale {
+		v /= 1000
+	}
+	for i, tok := range sec.tokens {
+		switch tok.kind {
+		case tokSlash:
+			return sec.formatFraction(v, i)
+		case tokExp:
+			return sec.formatScientific(v, i)
+		}
+	}
+	intPH, fracPH := sec.placeholders(0, len(sec.tokens))
+	return sec.render(v, intPH, fracPH, 0, len(sec.tokens))
+}
+
+// placeholders returns the integer and decimal digit placeholders of tokens[from:to].
+func (sec *section) placeholders(from, to int) (intPH, fracPH []byte) {
+	point := false
+	for _, tok := range sec.tokens[from:to] {
+		switch {
+		case tok.kind == tokPoint:
+			point = true
+		case tok.kind == tokDigit && point:
+			fracPH = append(fracPH, tok.text[0])
+		case tok.kind == tokDigit:
+			intPH = append(intPH, tok.text[0])
+		}
+	}
+	return intPH, fracPH
+}
+
+// render writes tokens[from:to] with the integer and decimal digits of v.
+func (sec *section) render(v float64, intPH, fracPH []byte, from, to int) string {
+	p := math.Pow(10, float64(len(fracPH)))
+	text := strconv.FormatFloat(roundHalfAway(v*p)/p, 'f', len(fracPH), 64)
+	intDigits, fracDigits, _ := strings.Cut(text, ".")
+	if intDigits == "0" {
+		intDigits = ""
+	}
+	intSlots := integerSlots(intDigits, intPH, sec.grouping)
+	fracSlots := decimalSlots(fracDigits, fracPH)
+
+	var sb strings.Builder
+	i, f := 0, 0
+	for _, tok := range sec.tokens[from:to] {
+		switch tok.kind {
+		case tokLiteral:
+			sb.WriteString(tok.text)
+		case tokGeneral:
+			sb.WriteString(FormatNumber(v))
+		case tokPoint:
+			if len(intPH) == 0 {
+				sb.WriteString(groupDigits(intDigits, sec.grouping))
+			}
+			sb.WriteByte('.')
+		case tokDigit:
+			if i < len(intSlots) {
+				sb.WriteString(intSlots[i])
+				i++
+			} else if f < len(fracSlots) {
+				sb.WriteString(fracSlots[f])
+				f++
+			}
+		}
+	}
+	return sb.String()
+}
+
+// integerSlots distributes digits over integer placeholders from the right.
+// Digits beyond the placeholders go to the first one; missing digits show as
+// "0" for 0, a space for ? and nothing for #.
+func integerSlots(digits string, ph []byte, grouping bool) []string {
+	n := len(ph)
+	slots := make([]string, n)
+	for i := range n { // Position from the right
+		slot := n - 1 - i
+		var sb strings.Builder
+		if slot == 0 && len(digits) > n {
+			extra := digits[:len(digits)-n]
+			for j := range len(extra) {
+				sb.WriteByte(extra[j])
+				if pos := len(digits) - 1 - j; grouping && pos%3 == 0 {
+					sb.WriteByte(',')
+				}
+			}
+		}
+		shown := true
+		switch {
+		case i < len(digits):
+			sb.WriteByte(digits[len(digits)-1-i])
+		case ph[slot] == '0':
+			sb.WriteByte('0')
+		case ph[slot] == '?':
+			sb.WriteByte(' ')
+			shown = false
+		default:
+			shown = false
+		}
+		if grouping && shown && i > 0 && i%3 == 0 {
+			sb.WriteByte(',')
+		}
+		slots[slot] = sb.String()
+	}
+	return slots
+}
+
+// decimalSlots places decimal digits from the left, dropping trailing zeros
+// shown by # and ? placeholders.
+func decimalSlots(digits string, ph []byte) []string {
+	slots := make([]string, len(ph))
+	trailing := true
+	for i := len(ph) - 1; i >= 0; i-- {
+		d := byte('0')
+		if i < len(digits) {
+			d = digits[i]
+		}
+		if trailing && d == '0' && ph[i] != '0' {
+			if ph[i] == '?' {
+				slots[i] = " "
+			}
+			continue
+		}
+		trailing = false
+		slots[i] = string(d)
+	}
+	return slots
+}
+
+func groupDigits(digits string, grouping bool) string {
+	if !grouping || len(digits) <= 3 {
+		return digits
+	}
+	var sb strings.Builder
+	for i := range len(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(digits[i])
+	}
+	return sb.String()
+}
+
+// formatScientific renders v with the mantissa before tokens[exp] and the
+// exponent placeholders after it. A mantissa with several integer placeholders
+// starting with # uses exponents that are multiples of their count.
+func (sec *section) formatScientific(v float64, exp int) string {
+	intPH, fracPH := sec.placeholders(0, exp)
+	k := len(intPH)
+	step := 1
+	if k > 1 && intPH[0] == '#' {
+		step = k
+	}
+
+	e := 0
+	if v != 0 {
+		e = int(math.Floor(math.Log10(v)))
+		switch {
+		case step > 1:
+			e = int(math.Floor(float64(e)/float64(step))) * step
+		case k > 1:
+			e -= k - 1
+		case k == 0:
+			e++
+		}
+	}
+	limit := math.Pow(10, float64(max(k, 1)))
+	if step > 1 {
+		limit = math.Pow(10, float64(step))
+	}
+	p := math.Pow(10, float64(len(fracPH)))
+	mantissa := v / math.Pow(10, float64(e))
+	if v != 0 && roundHalfAway(mantissa*p)/p >= limit {
+		e += step
+		mantissa = v / math.Pow(10, float64(e))
+	}
+
+	var expPH []byte
+	for _, tok := range sec.tokens[exp+1:] {
+		if tok.kind == tokDigit {
+			expPH = append(expPH, tok.text[0])
+		}
+	}
+	marker := sec.tokens[exp].text
+	sign := ""
+	switch {
+	case e < 0:
+		sign = "-"
+	case marker[1] == '+':
+		sign = "+"
+	}
+	expSlots := integerSlots(strconv.Itoa(abs(e)), expPH, false)
+	if len(expSlots) == 0 {
+		expSlots = []string{strconv.Itoa(abs(e))}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sec.render(mantissa, intPH, fracPH, 0, exp))
+	sb.WriteString(marker[:1] + sign)
+	j := 0
+	for _, tok := range sec.tokens[exp+1:] {
+		switch tok.kind {
+		case tokLiteral:
+			sb.WriteString(tok.text)
+		case tokDigit:
+			if j < len(expSlots) {
+				sb.WriteString(expSlots[j])
+			}
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// formatFraction renders v as a fraction around tokens[slash]. The placeholders
+// right before the bar hold the numerator; earlier ones, separated from them by
+// a literal, hold the whole part. A denominator written as digits is fixed,
+// otherwise the closest fraction with as many denominator digits is used.
+func (sec *section) formatFraction(v float64, slash int) string {
+	numStart := slash
+	for numStart > 0 && sec.tokens[numStart-1].kind == tokDigit {
+		numStart--
+	}
+	var numPH, wholePH []byte
+	for _, tok := range sec.tokens[numStart:slash] {
+		numPH = append(numPH, tok.text[0])
+	}
+	for _, tok := range sec.tokens[:numStart] {
+		if tok.kind == tokDigit {
+			wholePH = append(wholePH, tok.text[0])
+		}
+	}
+
+	denEnd := slash + 1
+	var denPH []byte
+	for denEnd < len(sec.tokens) && sec.tokens[denEnd].kind == tokDigit {
+		denPH = append(denPH, sec.tokens[denEnd].text[0])
+		denEnd++
+	}
+	fixed := 0
+	if len(denPH) == 0 && denEnd < len(sec.tokens) && sec.tokens[denEnd].kind == tokLiteral {
+		lit := sec.tokens[denEnd].text
+		n := 0
+		for n < len(lit) && lit[n] >= '0' && lit[n] <= '9' {
+			n++
+		}
+		fixed, _ = strconv.Atoi(lit[:n])
+	}
+
+	whole, frac := 0.0, v
+	if len(wholePH) > 0 {
+		whole = math.Floor(v)
+		frac = v - whole
+	}
+	num, den := 0.0, 1.0
+	if fixed > 0 {
+		den = float64(fixed)
+		num = roundHalfAway(frac * den)
+	} else {
+		maxDen := math.Pow(10, float64(max(len(denPH), 1))) - 1
+		best := math.Inf(1)
+		for d := 1.0; d <= maxDen; d++ {
+			n := roundHalfAway(frac * d)
+			if diff := math.Abs(frac - n/d); diff < best {
+				best, num, den = diff, n, d
+			}
+		}
+	}
+	if len(wholePH) > 0 && num == den {
+		whole, num = whole+1, 0
+	}
+
+	wholeDigits := strconv.FormatFloat(whole, 'f', 0, 64)
+	if whole == 0 && num != 0 {
+		wholeDigits = ""
+	}
+	wholeSlots := integerSlots(wholeDigits, wholePH, sec.grouping)
+	numSlots := integerSlots(strconv.FormatFloat(num, 'f', 0, 64), numPH, false)
+	denDigits := strconv.FormatFloat(den, 'f', 0, 64)
+	denSlots := make([]string, len(denPH))
+	for i, ph := range denPH {
+		switch {
+		case i < len(denDigits) && i == len(denPH)-1:
+			denSlots[i] = denDigits[i:]
+		case i < len(denDigits):
+			denSlots[i] = denDigits[i : i+1]
+		case ph == '?':
+			denSlots[i] = " "
+		case ph == '0':
+			denSlots[i] = "0"
+		}
+	}
+	blank := len(wholePH) > 0 && num == 0 // A whole number hides the fraction
+
+	var sb strings.Builder
+	w, d := 0, 0
+	for i, tok := range sec.tokens {
+		var text string
+		switch {
+		case tok.kind == tokLiteral && i == denEnd && fixed > 0:
+			text = tok.text
+		case tok.kind == tokLiteral:
+			sb.WriteString(tok.text)
+			continue
+		case tok.kind == tokGeneral:
+			sb.WriteString(FormatNumber(v))
+			continue
+		case tok.kind == tokDigit && i < numStart:
+			sb.WriteString(wholeSlots[w])
+			w++
+			continue
+		case tok.kind == tokDigit && i < slash:
+			text = numSlots[i-numStart]
+		case tok.kind == tokSlash:
+			text = "/"
+		case tok.kind == tokDigit && i < denEnd:
+			text = denSlots[d]
+			d++
+		default:
+			continue
+		}
+		if blank {
+			text = strings.Repeat(" ", utf8.RuneCountInString(text))
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// formatDate renders a serial date with the section's date and time parts.
+// The time is rounded to the smallest unit shown.
+func (sec *section) formatDate(v float64, date1904 bool) string {
+	decimals := 0
+	for _, tok := range sec.tokens {
+		if tok.kind == tokDate && tok.text[0] == '.' {
+			decimals = max(decimals, len(tok.text)-1)
+		}
+	}
+	unit := 86400 * math.Pow(10, float64(decimals))
+	v = roundHalfAway(v*unit) / unit
+	t := SerialToTime(v, date1904)
+
+	var sb strings.Builder
+	for i, tok := range sec.tokens {
+		switch tok.kind {
+		case tokLiteral:
+			sb.WriteString(tok.text)
+			continue
+		case tokGeneral:
+			sb.WriteString(FormatNumber(v))
+			continue
+		case tokDate:
+		default:
+			continue
+		}
+
+		text := tok.text
+		switch c := text[0]; {
+		case c == '[':
+			var elapsed float64
+			switch text[1] {
+			case 'h':
+				elapsed = v * 24
+			case 'm':
+				elapsed = v * 1440
+			default:
+				elapsed = v * 86400
+			}
+			sb.WriteString(padInt(int(math.Floor(elapsed+1e-9)), len(text)-2))
+		case c == '.':
+			frac := t.Nanosecond() / int(math.Pow(10, float64(9-len(text)+1)))
+			sb.WriteByte('.')
+			sb.WriteString(padInt(frac, len(text)-1))
+		case c == 'y':
+			if len(text) <= 2 {
+				sb.WriteString(t.Format("06"))
+			} else {
+				sb.WriteString(strconv.Itoa(t.Year()))
+			}
+		case c == 'm' && len(text) <= 2 && sec.isMinute(i):
+			sb.WriteString(padInt(t.Minute(), len(text)))
+		case c == 'm':
+			switch len(text) {
+			case 1, 2:
+				sb.WriteString(padInt(int(t.Month()), len(text)))
+			case 3:
+				sb.WriteString(t.Format("Jan"))
+			case 5:
+				sb.WriteString(t.Format("Jan")[:1])
+			default:
+				sb.WriteString(t.Format("January"))
+			}
+		case c == 'd':
+			switch len(text) {
+			case 1, 2:
+				sb.WriteString(padInt(t.Day(), len(text)))
+			case 3:
+				sb.WriteString(t.Format("Mon"))
+			default:
+				sb.WriteString(t.Format("Monday"))
+			}
+		case c == 'h':
+			h := t.Hour()
+			if sec.hour12 {
+				h = (h+11)%12 + 1
+			}
+			sb.WriteString(padInt(h, min(len(text), 2)))
+		case c == 's':
+			sb.WriteString(padInt(t.Second(), min(len(text), 2)))
+		default: // AM/PM or A/P
+			marker := "AM"
+			if t.Hour() >= 12 {
+				marker = "PM"
+			}
+			if len(text) == 3 {
+				marker = marker[:1]
+			}
+			if text[0] == 'a' || text[0] == 'p' {
+				marker = strings.ToLower(marker)
+			}
+			sb.WriteString(marker)
+		}
+	}
+	return sb.String()
+}
+
+// isMinute reports whether the m or mm part at tokens[i] means minutes: it
+// follows an hour or precedes a second.
+func (sec *section) isMinute(i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		if tok := sec.tokens[j]; tok.kind == tokDate {
+			if tok.text[0] == 'h' || tok.text == "[h]" || strings.HasPrefix(tok.text, "[h") {
+				return true
+			}
+			break
+		}
+	}
+	for _, tok := range sec.tokens[i+1:] {
+		if tok.kind == tokDate && tok.text[0] != '.' {
+			return tok.text[0] == 's' || strings.HasPrefix(tok.text, "[s")
+		}
+	}
+	return false
+}
+
+func padInt(n, width int) string {
+	s := strconv.Itoa(n)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,150 @@
+package formula
+
+// function evaluates a call from its unevaluated arguments, which lets
+// functions such as IF evaluate only the branch they need.
+type function func(ev *evaluator, args []Node) Value
+
+// refFunction evaluates a call that yields a reference rather than a value.
+type refFunction func(ev *evaluator, args []Node) (Reference, bool)
+
+var (
+	functions    = make(map[string]function)
+	refFunctions = make(map[string]refFunction)
+)
+
+func register(name string, fn function) {
+	functions[name] = fn
+}
+
+// Functions returns the names of all supported functions.
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	return names
+}
+
+// eager adapts fn to receive evaluated arguments after checking their count.
+// Reference arguments arrive as arrays, even for a single cell, so that
+// aggregates can tell them apart from literal values. A maxArgs of -1 means no limit.
+func eager(minArgs, maxArgs int, fn func(args []Value) Value) function {
+	return func(ev *evaluator, nodes []Node) Value {
+		if len(nodes) < minArgs || (maxArgs >= 0 && len(nodes) > maxArgs) {
+			return Error(ErrValue)
+		}
+		args := make([]Value, len(nodes))
+		for i, n := range nodes {
+			args[i] = ev.evalArg(n)
+		}
+		return fn(args)
+	}
+}
+
+// scalar is like eager but reduces every argument to a single value first.
+func scalar(minArgs, maxArgs int, fn func(args []Value) Value) function {
+	return eager(minArgs, maxArgs, func(args []Value) Value {
+		for i := range args {
+			args[i] = args[i].Scalar()
+		}
+		return fn(args)
+	})
+}
+
+func (ev *evaluator) evalArg(n Node) Value {
+	if ref, ok := n.(RefNode); ok && ref.Ref.IsCell() {
+		return Array([][]Value{{ev.eval(n)}})
+	}
+	return ev.eval(n)
+}
+
+// firstError returns the first error among scalar args.
+func firstError(args ...Value) *Value {
+	for i := range args {
+		if args[i].IsError() {
+			return &args[i]
+		}
+	}
+	return nil
+}
+
+// numberArg coerces an optional argument to a number, using def when it is missing.
+func numberArg(args []Value, i int, def float64) (float64, *Value) {
+	if i >= len(args) || args[i].Kind == KindEmpty {
+		return def, nil
+	}
+	return args[i].ToNumber()
+}
+
+// boolArg coerces an optional argument to a boolean, using def when it is missing.
+func boolArg(args []Value, i int, def bool) (bool, *Value) {
+	if i >= len(args) || args[i].Kind == KindEmpty {
+		return def, nil
+	}
+	return args[i].ToBool()
+}
+
+// each calls fn for every value in args, flattening arrays row by row.
+// The second argument tells whether the value came from a range or array.
+func each(args []Value, fn func(v Value, inArray bool) bool) {
+	for _, arg := range args {
+		if arg.Kind != KindArray {
+			if !fn(arg, false) {
+				return
+			}
+			continue
+		}
+		for _, row := range arg.Array {
+			for _, item := range row {
+				if !fn(item, true) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// collectNumbers gathers the numbers in args. Values in ranges and arrays are
+// taken only when numeric; literal arguments are coerced.
+func collectNumbers(args []Value) ([]float64, *Value) {
+	var nums []float64
+	var errv *Value
+	each(args, func(v Value, inArray bool) bool {
+		switch {
+		case v.IsError():
+			errv = &v
+			return false
+		case v.Kind == KindNumber:
+			nums = append(nums, v.Num)
+		case !inArray && v.Kind != KindEmpty:
+			n, e := v.ToNumber()
+			if e != nil {
+				errv = e
+				return false
+			}
+			nums = append(nums, n)
+		}
+		return true
+	})
+	return nums, errv
+}
+
+// flatten returns the values of an array row by row, or the value itself.
+func flatten(v Value) []Value {
+	if v.Kind != KindArray {
+		return []Value{v}
+	}
+	var out []Value
+	for _, row := range v.Array {
+		out = append(out, row...)
+	}
+	return out
+}
+
+// dims returns the number of rows and columns of a value.
+func dims(v Value) (int, int) {
+	if v.Kind != KindArray || len(v.Array) == 0 {
+		return 1, 1
+	}
+	return len(v.Array), len(v.Array[0])
+}
@@ -0,0 +1,349 @@
+package formula
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	epoch1900 = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	epoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// dateLayouts lists the text forms accepted by DATEVALUE and VALUE.
+var dateLayouts = []string{
+	"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05",
+	"2006/01/02", "2006/1/2", "1/2/2006", "01/02/2006", "1/2/2006 15:04", "1/2/2006 15:04:05",
+	"2 Jan 2006", "2-Jan-2006", "02-Jan-06", "Jan 2, 2006", "January 2, 2006", "2 January 2006",
+	"15:04", "15:04:05", "3:04 PM", "3:04:05 PM",
+}
+
+func init() {
+	register("DATE", dated(3, 3, fnDate))
+	register("TIME", scalar(3, 3, fnTime))
+	register("TODAY", dated(0, 0, func(ev *evaluator, _ []Value) Value {
+		return Number(math.Floor(ev.now()))
+	}))
+	register("NOW", dated(0, 0, func(ev *evaluator, _ []Value) Value {
+		return Number(ev.now())
+	}))
+	register("YEAR", datePart(func(t time.Time) int { return t.Year() }))
+	register("MONTH", datePart(func(t time.Time) int { return int(t.Month()) }))
+	register("DAY", datePart(func(t time.Time) int { return t.Day() }))
+	register("HOUR", datePart(func(t time.Time) int { return t.Hour() }))
+	register("MINUTE", datePart(func(t time.Time) int { return t.Minute() }))
+	register("SECOND", datePart(func(t time.Time) int { return t.Second() }))
+	register("WEEKDAY", dated(1, 2, fnWeekday))
+	register("WEEKNUM", dated(1, 2, fnWeekNum))
+	register("EDATE", dated(2, 2, func(ev *evaluator, args []Value) Value {
+		return ev.shiftMonths(args, false)
+	}))
+	register("EOMONTH", dated(2, 2, func(ev *evaluator, args []Value) Value {
+		return ev.shiftMonths(args, true)
+	}))
+	register("DAYS", dated(2, 2, func(ev *evaluator, args []Value) Value {
+		end, errv := ev.dateArg(args[0])
+		if errv != nil {
+			return *errv
+		}
+		start, errv := ev.dateArg(args[1])
+		if errv != nil {
+			return *errv
+		}
+		return Number(math.Floor(end) - math.Floor(start))
+	}))
+	register("DATEDIF", dated(3, 3, fnDateDif))
+	register("DATEVALUE", dated(1, 1, func(ev *evaluator, args []Value) Value {
+		if args[0].Kind != KindString {
+			return Error(ErrValue)
+		}
+		serial, ok := parseDateText(args[0].Str, ev.ctx.Date1904())
+		if !ok {
+			return Error(ErrValue)
+		}
+		return Number(math.Floor(serial))
+	}))
+	register("NETWORKDAYS", func(ev *evaluator, nodes []Node) Value {
+		if len(nodes) < 2 || len(nodes) > 3 {
+			return Error(ErrValue)
+		}
+		args := make([]Value, len(nodes))
+		for i, n := range nodes {
+			args[i] = ev.evalArg(n)
+		}
+		return ev.networkDays(args)
+	})
+}
+
+// dated is like scalar for functions that need the workbook date system.
+func dated(minArgs, maxArgs int, fn func(ev *evaluator, args []Value) Value) function {
+	return func(ev *evaluator, nodes []Node) Value {
+		if len(nodes) < minArgs || (maxArgs >= 0 && len(nodes) > maxArgs) {
+			return Error(ErrValue)
+		}
+		args := make([]Value, len(nodes))
+		for i, n := range nodes {
+			args[i] = ev.eval(n).Scalar()
+		}
+		return fn(ev, args)
+	}
+}
+
+func datePart(part func(time.Time) int) function {
+	return dated(1, 1, func(ev *evaluator, args []Value) Value {
+		t, errv := ev.timeArg(args[0])
+		if errv != nil {
+			return *errv
+		}
+		return Number(float64(part(t)))
+	})
+}
+
+// SerialToTime converts a serial date to a time. In the 1900 date system Excel
+// treats 1900 as a leap year, so serials before March 1900 are shifted by a day.
+func SerialToTime(serial float64, date1904 bool) time.Time {
+	epoch := epoch1900
+	if date1904 {
+		epoch = epoch1904
+	} else if serial < 61 {
+		serial++
+	}
+	ms := math.Round(serial * 86400 * 1000)
+	return epoch.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// TimeToSerial converts a time to a serial date, ignoring its location.
+func TimeToSerial(t time.Time, date1904 bool) float64 {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	epoch := epoch1900
+	if date1904 {
+		epoch = epoch1904
+	}
+	serial := t.Sub(epoch).Hours() / 24
+	if !date1904 && serial < 61 {
+		serial--
+	}
+	return serial
+}
+
+func (ev *evaluator) now() float64 {
+	return TimeToSerial(ev.ctx.Now(), ev.ctx.Date1904())
+}
+
+// dateArg coerces a value to a serial date, parsing text dates.
+func (ev *evaluator) dateArg(v Value) (float64, *Value) {
+	if v.Kind == KindString {
+		if serial, ok := parseDateText(v.Str, ev.ctx.Date1904()); ok {
+			return serial, nil
+		}
+	}
+	n, errv := v.ToNumber()
+	if errv != nil {
+		return 0, errv
+	}
+	if n < 0 {
+		e := Error(ErrNum)
+		return 0, &e
+	}
+	return n, nil
+}
+
+func (ev *evaluator) timeArg(v Value) (time.Time, *Value) {
+	serial, errv := ev.dateArg(v)
+	if errv != nil {
+		return time.Time{}, errv
+	}
+	return SerialToTime(serial, ev.ctx.Date1904()), nil
+}
+
+func fnDate(ev *evaluator, args []Value) Value {
+	var parts [3]int
+	for i := range parts {
+		n, errv := args[i].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		parts[i] = int(math.Trunc(n))
+	}
+	year := parts[0]
+	if year < 1900 {
+		year += 1900
+	}
+	if year < 1900 || year > 9999 {
+		return Error(ErrNum)
+	}
+	t := time.Date(year, time.Month(parts[1]), parts[2], 0, 0, 0, 0, time.UTC)
+	serial := TimeToSerial(t, ev.ctx.Date1904())
+	if serial < 0 {
+		return Error(ErrNum)
+	}
+	return Number(serial)
+}
+
+func fnTime(args []Value) Value {
+	var parts [3]float64
+	for i := range parts {
+		n, errv := args[i].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		parts[i] = math.Trunc(n)
+	}
+	seconds := parts[0]*3600 + parts[1]*60 + parts[2]
+	if seconds < 0 {
+		return Error(ErrNum)
+	}
+	return Number(math.Mod(seconds, 86400) / 86400)
+}
+
+func fnWeekday(ev *evaluator, args []Value) Value {
+	t, errv := ev.timeArg(args[0])
+	if errv != nil {
+		return *errv
+	}
+	kind, errv := numberArg(args, 1, 1)
+	if errv != nil {
+		return *errv
+	}
+	wd := int(t.Weekday()) // Sunday = 0
+	switch int(kind) {
+	case 1, 17:
+		return Number(float64(wd + 1))
+	case 2, 11:
+		return Number(float64((wd+6)%7 + 1))
+	case 3:
+		return Number(float64((wd + 6) % 7))
+	}
+	return Error(ErrNum)
+}
+
+func fnWeekNum(ev *evaluator, args []Value) Value {
+	t, errv := ev.timeArg(args[0])
+	if errv != nil {
+		return *errv
+	}
+	kind, errv := numberArg(args, 1, 1)
+	if errv != nil {
+		return *errv
+	}
+	if int(kind) == 21 {
+		_, week := t.ISOWeek()
+		return Number(float64(week))
+	}
+	startDay := 0 // Sunday
+	if int(kind) == 2 || int(kind) == 11 {
+		startDay = 1
+	}
+	jan1 := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan1.Weekday()) - startDay + 7) % 7
+	return Number(float64((t.YearDay()-1+offset)/7 + 1))
+}
+
+func (ev *evaluator) shiftMonths(args []Value, endOfMonth bool) Value {
+	t, errv := ev.timeArg(args[0])
+	if errv != nil {
+		return *errv
+	}
+	n, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(int(math.Trunc(n))), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	day := min(t.Day(), last)
+	if endOfMonth {
+		day = last
+	}
+	serial := TimeToSerial(first.AddDate(0, 0, day-1), ev.ctx.Date1904())
+	if serial < 0 {
+		return Error(ErrNum)
+	}
+	return Number(serial)
+}
+
+func fnDateDif(ev *evaluator, args []Value) Value {
+	start, errv := ev.timeArg(args[0])
+	if errv != nil {
+		return *errv
+	}
+	end, errv := ev.timeArg(args[1])
+	if errv != nil {
+		return *errv
+	}
+	if end.Before(start) {
+		return Error(ErrNum)
+	}
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if end.Day() < start.Day() {
+		months--
+	}
+	days := func(a, b time.Time) float64 { return math.Floor(b.Sub(a).Hours() / 24) }
+
+	switch strings.ToUpper(args[2].String()) {
+	case "Y":
+		return Number(float64(months / 12))
+	case "M":
+		return Number(float64(months))
+	case "D":
+		return Number(days(start, end))
+	case "MD":
+		anchor := start.AddDate(0, months, 0)
+		return Number(days(anchor, end))
+	case "YM":
+		return Number(float64(months % 12))
+	case "YD":
+		anchor := start.AddDate(months/12, 0, 0)
+		return Number(days(anchor, end))
+	}
+	return Error(ErrNum)
+}
+
+func (ev *evaluator) networkDays(args []Value) Value {
+	start, errv := ev.dateArg(args[0].Scalar())
+	if errv != nil {
+		return *errv
+	}
+	end, errv := ev.dateArg(args[1].Scalar())
+	if errv != nil {
+		return *errv
+	}
+	holidays := make(map[float64]bool)
+	if len(args) > 2 {
+		each(args[2:], func(v Value, _ bool) bool {
+			if v.Kind == KindNumber {
+				holidays[math.Floor(v.Num)] = true
+			}
+			return true
+		})
+	}
+	sign := 1.0
+	from, to := math.Floor(start), math.Floor(end)
+	if from > to {
+		from, to, sign = to, from, -1
+	}
+	count := 0
+	for d := from; d <= to; d++ {
+		wd := SerialToTime(d, ev.ctx.Date1904()).Weekday()
+		if wd != time.Saturday && wd != time.Sunday && !holidays[d] {
+			count++
+		}
+	}
+	return Number(sign * float64(count))
+}
+
+// parseDateText parses a date or time written as text into a serial date.
+func parseDateText(s string, date1904 bool) (float64, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			return float64(t.Hour()*3600+t.Minute()*60+t.Second()) / 86400, true
+		}
+		return TimeToSerial(t, date1904), true
+	}
+	return 0, false
+}
@@ -0,0 +1,181 @@
+package formula
+
+import "math"
+
+func init() {
+	register("IF", fnIf)
+	register("IFS", fnIfs)
+	register("IFERROR", fnIfError(func(v Value) bool { return v.IsError() }))
+	register("IFNA", fnIfError(func(v Value) bool { return v.IsError() && v.Str == ErrNA }))
+	register("SWITCH", fnSwitch)
+	register("CHOOSE", fnChoose)
+	register("AND", eager(1, -1, logical(func(acc, v bool) bool { return acc && v }, true)))
+	register("OR", eager(1, -1, logical(func(acc, v bool) bool { return acc || v }, false)))
+	register("XOR", eager(1, -1, logical(func(acc, v bool) bool { return acc != v }, false)))
+	register("NOT", scalar(1, 1, func(args []Value) Value {
+		b, errv := args[0].ToBool()
+		if errv != nil {
+			return *errv
+		}
+		return Bool(!b)
+	}))
+	register("TRUE", scalar(0, 0, func([]Value) Value { return Bool(true) }))
+	register("FALSE", scalar(0, 0, func([]Value) Value { return Bool(false) }))
+
+	register("ISBLANK", scalar(1, 1, is(func(v Value) bool { return v.Kind == KindEmpty })))
+	register("ISNUMBER", scalar(1, 1, is(func(v Value) bool { return v.Kind == KindNumber })))
+	register("ISTEXT", scalar(1, 1, is(func(v Value) bool { return v.Kind == KindString })))
+	register("ISNONTEXT", scalar(1, 1, is(func(v Value) bool { return v.Kind != KindString })))
+	register("ISLOGICAL", scalar(1, 1, is(func(v Value) bool { return v.Kind == KindBool })))
+	register("ISERROR", scalar(1, 1, is(func(v Value) bool { return v.IsError() })))
+	register("ISERR", scalar(1, 1, is(func(v Value) bool { return v.IsError() && v.Str != ErrNA })))
+	register("ISNA", scalar(1, 1, is(func(v Value) bool { return v.IsError() && v.Str == ErrNA })))
+	register("ISEVEN", scalar(1, 1, parity(0)))
+	register("ISODD", scalar(1, 1, parity(1)))
+	register("ISREF", func(ev *evaluator, args []Node) Value {
+		if len(args) != 1 {
+			return Error(ErrValue)
+		}
+		_, ok := ev.evalRef(args[0])
+		return Bool(ok)
+	})
+	register("NA", scalar(0, 0, func([]Value) Value { return Error(ErrNA) }))
+}
+
+func fnIf(ev *evaluator, args []Node) Value {
+	if len(args) < 2 || len(args) > 3 {
+		return Error(ErrValue)
+	}
+	cond, errv := ev.eval(args[0]).ToBool()
+	if errv != nil {
+		return *errv
+	}
+	if cond {
+		return branch(ev, args[1])
+	}
+	if len(args) < 3 {
+		return Bool(false)
+	}
+	return branch(ev, args[2])
+}
+
+// branch evaluates a result argument, where an omitted argument yields 0.
+func branch(ev *evaluator, n Node) Value {
+	if _, ok := n.(MissingNode); ok {
+		return Number(0)
+	}
+	return ev.eval(n)
+}
+
+func fnIfs(ev *evaluator, args []Node) Value {
+	if len(args) < 2 || len(args)%2 != 0 {
+		return Error(ErrValue)
+	}
+	for i := 0; i < len(args); i += 2 {
+		cond, errv := ev.eval(args[i]).ToBool()
+		if errv != nil {
+			return *errv
+		}
+		if cond {
+			return branch(ev, args[i+1])
+		}
+	}
+	return Error(ErrNA)
+}
+
+func fnIfError(match func(Value) bool) function {
+	return func(ev *evaluator, args []Node) Value {
+		if len(args) != 2 {
+			return Error(ErrValue)
+		}
+		v := ev.eval(args[0])
+		if match(v.Scalar()) {
+			return branch(ev, args[1])
+		}
+		return v
+	}
+}
+
+func fnSwitch(ev *evaluator, args []Node) Value {
+	if len(args) < 3 {
+		return Error(ErrValue)
+	}
+	v := ev.eval(args[0]).Scalar()
+	if v.IsError() {
+		return v
+	}
+	i := 1
+	for ; i+1 < len(args); i += 2 {
+		c := ev.eval(args[i]).Scalar()
+		if c.Kind == v.Kind && compare(v, c) == 0 {
+			return branch(ev, args[i+1])
+		}
+	}
+	if i < len(args) {
+		return branch(ev, args[i])
+	}
+	return Error(ErrNA)
+}
+
+func fnChoose(ev *evaluator, args []Node) Value {
+	if len(args) < 2 {
+		return Error(ErrValue)
+	}
+	idx, errv := ev.eval(args[0]).ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	i := int(idx)
+	if i < 1 || i >= len(args) {
+		return Error(ErrValue)
+	}
+	return branch(ev, args[i])
+}
+
+// logical folds the boolean values in args. Text and blanks in ranges are
+// ignored; literal text that is not TRUE or FALSE is an error.
+func logical(fold func(acc, v bool) bool, init bool) func(args []Value) Value {
+	return func(args []Value) Value {
+		acc, seen := init, false
+		var errv *Value
+		each(args, func(v Value, inArray bool) bool {
+			switch {
+			case v.IsError():
+				errv = &v
+				return false
+			case v.Kind == KindEmpty || (inArray && v.Kind == KindString):
+				return true
+			}
+			b, e := v.ToBool()
+			if e != nil {
+				errv = e
+				return false
+			}
+			acc, seen = fold(acc, b), true
+			return true
+		})
+		if errv != nil {
+			return *errv
+		}
+		if !seen {
+			return Error(ErrValue)
+		}
+		return Bool(acc)
+	}
+}
+
+func is(test func(Value) bool) func(args []Value) Value {
+	return func(args []Value) Value {
+		return Bool(test(args[0]))
+	}
+}
+
+func parity(rem int) func(args []Value) Value {
+	return func(args []Value) Value {
+		n, errv := args[0].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		return Bool(int(math.Abs(math.Trunc(n)))%2 == rem)
+	}
+}
@@ -0,0 +1,435 @@
+package formula
+
+func init() {
+	register("VLOOKUP", eager(3, 4, fnVLookup))
+	register("HLOOKUP", eager(3, 4, fnHLookup))
+	register("XLOOKUP", eager(3, 6, fnXLookup))
+	register("MATCH", eager(2, 3, fnMatch))
+	register("XMATCH", eager(2, 4, fnXMatch))
+	register("INDEX", viaRef(refIndex, eager(2, 3, fnIndex)))
+	register("OFFSET", viaRef(refOffset, nil))
+	register("INDIRECT", viaRef(refIndirect, nil))
+	register("ROW", fnPosition(func(ref Reference) int { return max(ref.Row1, 1) }, func(_, row int) int { return row }))
+	register("COLUMN", fnPosition(func(ref Reference) int { return max(ref.Col1, 1) }, func(col, _ int) int { return col }))
+	register("ROWS", fnDimension(func(ref Reference) int {
+		if ref.Row1 == 0 {
+			return MaxRows
+		}
+		return ref.Row2 - ref.Row1 + 1
+	}, func(v Value) int { r, _ := dims(v); return r }))
+	register("COLUMNS", fnDimension(func(ref Reference) int {
+		if ref.Col1 == 0 {
+			return MaxColumns
+		}
+		return ref.Col2 - ref.Col1 + 1
+	}, func(v Value) int { _, c := dims(v); return c }))
+
+	refFunctions["INDEX"] = refIndex
+	refFunctions["OFFSET"] = refOffset
+	refFunctions["INDIRECT"] = refIndirect
+}
+
+// viaRef evaluates a reference-returning function and reads the cells it points to,
+// falling back to fallback when the arguments do not form a reference.
+func viaRef(fn refFunction, fallback function) function {
+	return func(ev *evaluator, args []Node) Value {
+		if ref, ok := fn(ev, args); ok {
+			return ev.refValue(ref)
+		}
+		if fallback != nil {
+			return fallback(ev, args)
+		}
+		return Error(ErrRef)
+	}
+}
+
+func fnVLookup(args []Value) Value {
+	return lookup(args, func(table [][]Value, i int) []Value { return column(table, i) }, func(table [][]Value) int { return len(table[0]) })
+}
+
+func fnHLookup(args []Value) Value {
+	return lookup(args, func(table [][]Value, i int) []Value { return table[i] }, func(table [][]Value) int { return len(table) })
+}
+
+// lookup implements VLOOKUP and HLOOKUP, where line returns the i-th column or row of the table.
+func lookup(args []Value, line func(table [][]Value, i int) []Value, lines func(table [][]Value) int) Value {
+	v := args[0].Scalar()
+	if v.IsError() {
+		return v
+	}
+	table := asArray(args[1])
+	idx, errv := args[2].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	approx, errv := boolArg(args, 3, true)
+	if errv != nil {
+		return *errv
+	}
+	n := int(idx)
+	if n < 1 {
+		return Error(ErrValue)
+	}
+	if n > lines(table) {
+		return Error(ErrRef)
+	}
+
+	keys := line(table, 0)
+	pos := -1
+	if approx {
+		pos = approxIndex(keys, v)
+	} else {
+		pos = exactIndex(keys, v, v.Kind == KindString, false)
+	}
+	if pos < 0 {
+		return Error(ErrNA)
+	}
+	return line(table, n-1)[pos]
+}
+
+func fnMatch(args []Value) Value {
+	v := args[0].Scalar()
+	if v.IsError() {
+		return v
+	}
+	mode, errv := numberArg(args, 2, 1)
+	if errv != nil {
+		return *errv
+	}
+	items := flatten(args[1])
+	pos := -1
+	switch {
+	case mode == 0:
+		pos = exactIndex(items, v, v.Kind == KindString, false)
+	case mode > 0:
+		pos = approxIndex(items, v)
+	default:
+		for i, item := range items {
+			if item.Kind != v.Kind {
+				continue
+			}
+			if compare(item, v) < 0 {
+				break
+			}
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return Error(ErrNA)
+	}
+	return Number(float64(pos + 1))
+}
+
+func fnXMatch(args []Value) Value {
+	v := args[0].Scalar()
+	if v.IsError() {
+		return v
+	}
+	mode, errv := numberArg(args, 2, 0)
+	if errv != nil {
+		return *errv
+	}
+	search, errv := numberArg(args, 3, 1)
+	if errv != nil {
+		return *errv
+	}
+	pos := find(flatten(args[1]), v, int(mode), search < 0)
+	if pos < 0 {
+		return Error(ErrNA)
+	}
+	return Number(float64(pos + 1))
+}
+
+func fnXLookup(args []Value) Value {
+	v := args[0].Scalar()
+	if v.IsError() {
+		return v
+	}
+	mode, errv := numberArg(args, 4, 0)
+	if errv != nil {
+		return *errv
+	}
+	search, errv := numberArg(args, 5, 1)
+	if errv != nil {
+		return *errv
+	}
+
+	keys := asArray(args[1])
+	results := asArray(args[2])
+	vertical := len(keys[0]) == 1
+	var pos int
+	if vertical {
+		if len(results) != len(keys) {
+			return Error(ErrValue)
+		}
+		pos = find(column(keys, 0), v, int(mode), search < 0)
+	} else {
+		if len(keys) != 1 || len(results[0]) != len(keys[0]) {
+			return Error(ErrValue)
+		}
+		pos = find(keys[0], v, int(mode), search < 0)
+	}
+
+	if pos < 0 {
+		if len(args) > 3 && args[3].Kind != KindEmpty {
+			return args[3]
+		}
+		return Error(ErrNA)
+	}
+	if vertical {
+		if len(results[pos]) == 1 {
+			return results[pos][0]
+		}
+		return Array([][]Value{results[pos]})
+	}
+	col := column(results, pos)
+	if len(col) == 1 {
+		return col[0]
+	}
+	rows := make([][]Value, len(col))
+	for i, item := range col {
+		rows[i] = []Value{item}
+	}
+	return Array(rows)
+}
+
+// fnIndex implements INDEX over arrays that are not references, such as array constants.
+func fnIndex(args []Value) Value {
+	table := asArray(args[0])
+	row, col, errv := indexArgs(args, len(table), len(table[0]))
+	if errv != nil {
+		return *errv
+	}
+	if row > len(table) || col > len(table[0]) {
+		return Error(ErrRef)
+	}
+	switch {
+	case row == 0 && col == 0:
+		return Array(table)
+	case row == 0:
+		c := column(table, col-1)
+		rows := make([][]Value, len(c))
+		for i, item := range c {
+			rows[i] = []Value{item}
+		}
+		return Array(rows)
+	case col == 0:
+		return Array([][]Value{table[row-1]})
+	}
+	return table[row-1][col-1]
+}
+
+// indexArgs reads the row and column numbers of INDEX. A single index into a
+// one-row array selects a column.
+func indexArgs(args []Value, rows, cols int) (int, int, *Value) {
+	r, errv := args[1].ToNumber()
+	if errv != nil {
+		return 0, 0, errv
+	}
+	c, errv := numberArg(args, 2, 0)
+	if errv != nil {
+		return 0, 0, errv
+	}
+	row, col := int(r), int(c)
+	if len(args) < 3 {
+		if rows == 1 && cols > 1 {
+			row, col = 1, row
+		} else if cols == 1 {
+			col = 1
+		}
+	}
+	if row < 0 || col < 0 {
+		e := Error(ErrValue)
+		return 0, 0, &e
+	}
+	return row, col, nil
+}
+
+func refIndex(ev *evaluator, args []Node) (Reference, bool) {
+	if len(args) < 2 || len(args) > 3 {
+		return Reference{}, false
+	}
+	ref, ok := ev.evalRef(args[0])
+	if !ok {
+		return Reference{}, false
+	}
+	col1, row1, col2, row2 := ref.Area(MaxColumns, MaxRows)
+	values := make([]Value, len(args))
+	values[0] = empty
+	for i := 1; i < len(args); i++ {
+		values[i] = ev.eval(args[i]).Scalar()
+	}
+	row, col, errv := indexArgs(values, row2-row1+1, col2-col1+1)
+	if errv != nil || row > row2-row1+1 || col > col2-col1+1 {
+		return Reference{}, false
+	}
+
+	out := Reference{Sheet: ref.Sheet, Col1: ref.Col1, Col2: ref.Col2, Row1: ref.Row1, Row2: ref.Row2, IsRange: true}
+	if row > 0 {
+		out.Row1, out.Row2 = row1+row-1, row1+row-1
+	}
+	if col > 0 {
+		out.Col1, out.Col2 = col1+col-1, col1+col-1
+	}
+	out.IsRange = out.Col1 == 0 || out.Row1 == 0 || out.Col1 != out.Col2 || out.Row1 != out.Row2
+	return out, true
+}
+
+func refOffset(ev *evaluator, args []Node) (Reference, bool) {
+	if len(args) < 3 || len(args) > 5 {
+		return Reference{}, false
+	}
+	ref, ok := ev.evalRef(args[0])
+	if !ok {
+		return Reference{}, false
+	}
+	col1, row1, col2, row2 := ref.Area(MaxColumns, MaxRows)
+	nums := []float64{0, 0, float64(row2 - row1 + 1), float64(col2 - col1 + 1)}
+	for i := 1; i < len(args); i++ {
+		v := ev.eval(args[i])
+		if v.Kind == KindEmpty {
+			continue
+		}
+		n, errv := v.ToNumber()
+		if errv != nil {
+			return Reference{}, false
+		}
+		nums[i-1] = n
+	}
+	height, width := int(nums[2]), int(nums[3])
+	out := Reference{
+		Sheet: ref.Sheet,
+		Row1:  row1 + int(nums[0]),
+		Col1:  col1 + int(nums[1]),
+	}
+	out.Row2, out.Col2 = out.Row1+height-1, out.Col1+width-1
+	if height < 1 || width < 1 || out.Row1 < 1 || out.Col1 < 1 || out.Row2 > MaxRows || out.Col2 > MaxColumns {
+		return Reference{}, false
+	}
+	out.IsRange = height > 1 || width > 1
+	return out, true
+}
+
+func refIndirect(ev *evaluator, args []Node) (Reference, bool) {
+	if len(args) < 1 || len(args) > 2 {
+		return Reference{}, false
+	}
+	text := ev.eval(args[0]).Scalar()
+	if text.Kind != KindString {
+		return Reference{}, false
+	}
+	ref, err := ParseReference(text.Str)
+	if err != nil {
+		return ev.evalRef(NameNode{Name: text.Str})
+	}
+	return ev.evalRef(RefNode{Ref: ref})
+}
+
+// fnPosition implements ROW and COLUMN, which default to the cell being calculated.
+func fnPosition(fromRef func(Reference) int, fromOrigin func(col, row int) int) function {
+	return func(ev *evaluator, args []Node) Value {
+		switch len(args) {
+		case 0:
+			_, col, row := ev.ctx.Origin()
+			return Number(float64(fromOrigin(col, row)))
+		case 1:
+			ref, ok := ev.evalRef(args[0])
+			if !ok {
+				return Error(ErrValue)
+			}
+			return Number(float64(fromRef(ref)))
+		}
+		return Error(ErrValue)
+	}
+}
+
+func fnDimension(fromRef func(Reference) int, fromValue func(Value) int) function {
+	return func(ev *evaluator, args []Node) Value {
+		if len(args) != 1 {
+			return Error(ErrValue)
+		}
+		if ref, ok := ev.evalRef(args[0]); ok {
+			return Number(float64(fromRef(ref)))
+		}
+		v := ev.eval(args[0])
+		if v.IsError() {
+			return v
+		}
+		return Number(float64(fromValue(v)))
+	}
+}
+
+func column(table [][]Value, i int) []Value {
+	out := make([]Value, len(table))
+	for r, row := range table {
+		if i < len(row) {
+			out[r] = row[i]
+		}
+	}
+	return out
+}
+
+// exactIndex returns the position of the first item equal to v, or -1.
+// Text is compared case-insensitively, honouring * and ? when wildcard is set.
+func exactIndex(items []Value, v Value, wildcard bool, reverse bool) int {
+	for k := range items {
+		i := k
+		if reverse {
+			i = len(items) - 1 - k
+		}
+		if matches(items[i], v, wildcard) {
+			return i
+		}
+	}
+	return -1
+}
+
+func matches(item, v Value, wildcard bool) bool {
+	if wildcard && v.Kind == KindString && item.Kind == KindString {
+		return wildcardMatch(v.Str, item.Str)
+	}
+	return item.Kind == v.Kind && compare(item, v) == 0
+}
+
+// approxIndex returns the position of the largest item not greater than v,
+// assuming items of v's type are sorted in ascending order.
+func approxIndex(items []Value, v Value) int {
+	pos := -1
+	for i, item := range items {
+		if item.Kind != v.Kind {
+			continue
+		}
+		if compare(item, v) > 0 {
+			break
+		}
+		pos = i
+	}
+	return pos
+}
+
+// find implements the XLOOKUP and XMATCH match modes: 0 exact, -1 exact or next
+// smaller, 1 exact or next larger, 2 wildcard. The items need not be sorted.
+func find(items []Value, v Value, mode int, reverse bool) int {
+	if pos := exactIndex(items, v, mode == 2, reverse); pos >= 0 || mode == 0 || mode == 2 {
+		return pos
+	}
+	best := -1
+	for k := range items {
+		i := k
+		if reverse {
+			i = len(items) - 1 - k
+		}
+		item := items[i]
+		if item.Kind != v.Kind {
+			continue
+		}
+		c := compare(item, v)
+		if (mode < 0 && c > 0) || (mode > 0 && c < 0) {
+			continue
+		}
+		if best < 0 || (mode < 0 && compare(item, items[best]) > 0) || (mode > 0 && compare(item, items[best]) < 0) {
+			best = i
+		}
+	}
+	return best
+}
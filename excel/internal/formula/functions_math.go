@@ -0,0 +1,416 @@
+package formula
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+)
+
+func init() {
+	register("SUM", eager(1, -1, fnSum))
+	register("PRODUCT", eager(1, -1, fnProduct))
+	register("AVERAGE", eager(1, -1, fnAverage))
+	register("MIN", eager(1, -1, fnMin))
+	register("MAX", eager(1, -1, fnMax))
+	register("MEDIAN", eager(1, -1, fnMedian))
+	register("COUNT", eager(1, -1, fnCount))
+	register("COUNTA", eager(1, -1, fnCountA))
+	register("COUNTBLANK", eager(1, 1, fnCountBlank))
+	register("LARGE", eager(2, 2, fnLarge))
+	register("SMALL", eager(2, 2, fnSmall))
+	register("STDEV", eager(1, -1, variance(true, true)))
+	register("STDEV.S", eager(1, -1, variance(true, true)))
+	register("STDEVP", eager(1, -1, variance(false, true)))
+	register("STDEV.P", eager(1, -1, variance(false, true)))
+	register("VAR", eager(1, -1, variance(true, false)))
+	register("VAR.S", eager(1, -1, variance(true, false)))
+	register("VARP", eager(1, -1, variance(false, false)))
+	register("VAR.P", eager(1, -1, variance(false, false)))
+	register("SUMPRODUCT", eager(1, -1, fnSumProduct))
+	register("SUBTOTAL", eager(2, -1, fnSubtotal))
+
+	register("ROUND", scalar(2, 2, rounding(roundHalfAway)))
+	register("ROUNDUP", scalar(2, 2, rounding(func(x float64) float64 {
+		if x < 0 {
+			return math.Floor(x)
+		}
+		return math.Ceil(x)
+	})))
+	register("ROUNDDOWN", scalar(2, 2, rounding(math.Trunc)))
+	register("TRUNC", scalar(1, 2, rounding(math.Trunc)))
+	register("INT", scalar(1, 1, math1(math.Floor)))
+	register("ABS", scalar(1, 1, math1(math.Abs)))
+	register("SIGN", scalar(1, 1, math1(func(x float64) float64 {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return 0
+	})))
+	register("EXP", scalar(1, 1, math1(math.Exp)))
+	register("SQRT", scalar(1, 1, math1Checked(math.Sqrt, func(x float64) bool { return x >= 0 })))
+	register("LN", scalar(1, 1, math1Checked(math.Log, func(x float64) bool { return x > 0 })))
+	register("LOG10", scalar(1, 1, math1Checked(math.Log10, func(x float64) bool { return x > 0 })))
+	register("LOG", scalar(1, 2, fnLog))
+	register("MOD", scalar(2, 2, fnMod))
+	register("POWER", scalar(2, 2, fnPower))
+	register("CEILING", scalar(1, 2, multiple(math.Ceil)))
+	register("CEILING.MATH", scalar(1, 2, multiple(math.Ceil)))
+	register("FLOOR", scalar(1, 2, multiple(math.Floor)))
+	register("FLOOR.MATH", scalar(1, 2, multiple(math.Floor)))
+	register("PI", scalar(0, 0, func([]Value) Value { return Number(math.Pi) }))
+	register("RAND", scalar(0, 0, func([]Value) Value { return Number(rand.Float64()) }))
+	register("RANDBETWEEN", scalar(2, 2, fnRandBetween))
+}
+
+func fnSum(args []Value) Value {
+	nums, errv := collectNumbers(args)
+	if errv != nil {
+		return *errv
+	}
+	sum := 0.0
+	for _, n := range nums {
+		sum += n
+	}
+	return Number(sum)
+}
+
+func fnProduct(args []Value) Value {
+	nums, errv := collectNumbers(args)
+	if errv != nil {
+		return *errv
+	}
+	if len(nums) == 0 {
+		return Number(0)
+	}
+	product := 1.0
+	for _, n := range nums {
+		product *= n
+	}
+	return Number(product)
+}
+
+func fnAverage(args []Value) Value {
+	nums, errv := collectNumbers(args)
+	if errv != nil {
+		return *errv
+	}
+	if len(nums) == 0 {
+		return Error(ErrDiv0)
+	}
+	sum := 0.0
+	for _, n := range nums {
+		sum += n
+	}
+	return Number(sum / float64(len(nums)))
+}
+
+func fnMin(args []Value) Value {
+	nums, errv := collectNumbers(args)
+	if errv != nil {
+		return *errv
+	}
+	if len(nums) == 0 {
+		return Number(0)
+	}
+	return Number(slices.Min(nums))
+}
+
+func fnMax(args []Value) Value {
+	nums, errv := collectNumbers(args)
+	if errv != nil {
+		return *errv
+	}
+	if len(nums) == 0 {
+		return Number(0)
+	}
+	return Number(slices.Max(nums))
+}
+
+func fnMedian(args []Value) Value {
+	nums, errv := collectNumbers(args)
+	if errv != nil {
+		return *errv
+	}
+	if len(nums) == 0 {
+		return Error(ErrNum)
+	}
+	slices.Sort(nums)
+	mid := len(nums) / 2
+	if len(nums)%2 == 1 {
+		return Number(nums[mid])
+	}
+	return Number((nums[mid-1] + nums[mid]) / 2)
+}
+
+func fnCount(args []Value) Value {
+	count := 0
+	each(args, func(v Value, inArray bool) bool {
+		if v.Kind == KindNumber {
+			count++
+		} else if !inArray && (v.Kind == KindBool || v.Kind == KindString) {
+			if _, errv := v.ToNumber(); errv == nil {
+				count++
+			}
+		}
+		return true
+	})
+	return Number(float64(count))
+}
+
+func fnCountA(args []Value) Value {
+	count := 0
+	each(args, func(v Value, inArray bool) bool {
+		if v.Kind != KindEmpty || !inArray {
+			count++
+		}
+		return true
+	})
+	return Number(float64(count))
+}
+
+func fnCountBlank(args []Value) Value {
+	count := 0
+	each(args, func(v Value, _ bool) bool {
+		if v.Kind == KindEmpty || (v.Kind == KindString && v.Str == "") {
+			count++
+		}
+		return true
+	})
+	return Number(float64(count))
+}
+
+func fnLarge(args []Value) Value {
+	return nth(args, func(nums []float64, k int) float64 { return nums[len(nums)-k] })
+}
+
+func fnSmall(args []Value) Value {
+	return nth(args, func(nums []float64, k int) float64 { return nums[k-1] })
+}
+
+func nth(args []Value, pick func(nums []float64, k int) float64) Value {
+	nums, errv := collectNumbers(args[:1])
+	if errv != nil {
+		return *errv
+	}
+	k, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if int(k) < 1 || int(k) > len(nums) {
+		return Error(ErrNum)
+	}
+	slices.Sort(nums)
+	return Number(pick(nums, int(k)))
+}
+
+// variance returns a sample or population variance function, optionally as standard deviation.
+func variance(sample, deviation bool) func(args []Value) Value {
+	return func(args []Value) Value {
+		nums, errv := collectNumbers(args)
+		if errv != nil {
+			return *errv
+		}
+		n := float64(len(nums))
+		if n == 0 || (sample && n < 2) {
+			return Error(ErrDiv0)
+		}
+		mean := 0.0
+		for _, x := range nums {
+			mean += x
+		}
+		mean /= n
+		sum := 0.0
+		for _, x := range nums {
+			sum += (x - mean) * (x - mean)
+		}
+		if sample {
+			n--
+		}
+		if deviation {
+			return Number(math.Sqrt(sum / n))
+		}
+		return Number(sum / n)
+	}
+}
+
+func fnSumProduct(args []Value) Value {
+	rows, cols := dims(args[0])
+	for _, arg := range args[1:] {
+		if r, c := dims(arg); r != rows || c != cols {
+			return Error(ErrValue)
+		}
+	}
+	sum := 0.0
+	for i := range rows {
+		for j := range cols {
+			product := 1.0
+			for _, arg := range args {
+				v := asArray(arg)[i][j]
+				if v.IsError() {
+					return v
+				}
+				if v.Kind != KindNumber {
+					product = 0
+					continue
+				}
+				product *= v.Num
+			}
+			sum += product
+		}
+	}
+	return Number(sum)
+}
+
+var subtotalFunctions = map[int]func(args []Value) Value{
+	1:  fnAverage,
+	2:  fnCount,
+	3:  fnCountA,
+	4:  fnMax,
+	5:  fnMin,
+	6:  fnProduct,
+	7:  variance(true, true),
+	8:  variance(false, true),
+	9:  fnSum,
+	10: variance(true, false),
+	11: variance(false, false),
+}
+
+func fnSubtotal(args []Value) Value {
+	code, errv := args[0].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	fn, ok := subtotalFunctions[int(code)%100]
+	if !ok {
+		return Error(ErrValue)
+	}
+	return fn(args[1:])
+}
+
+// roundHalfAway rounds half away from zero after trimming binary noise,
+// so that 2.675 rounds to 2.68 as it does in Excel.
+func roundHalfAway(x float64) float64 {
+	return math.Round(trimNoise(x))
+}
+
+// trimNoise rounds x to the 15 significant digits Excel works with.
+func trimNoise(x float64) float64 {
+	x, _ = strconv.ParseFloat(strconv.FormatFloat(x, 'g', 15, 64), 64)
+	return x
+}
+
+func rounding(round func(float64) float64) func(args []Value) Value {
+	return func(args []Value) Value {
+		x, errv := args[0].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		digits, errv := numberArg(args, 1, 0)
+		if errv != nil {
+			return *errv
+		}
+		p := math.Pow(10, math.Trunc(digits))
+		return Number(round(x*p) / p)
+	}
+}
+
+func math1(fn func(float64) float64) func(args []Value) Value {
+	return math1Checked(fn, func(float64) bool { return true })
+}
+
+func math1Checked(fn func(float64) float64, valid func(float64) bool) func(args []Value) Value {
+	return func(args []Value) Value {
+		x, errv := args[0].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		if !valid(x) {
+			return Error(ErrNum)
+		}
+		return Number(fn(x))
+	}
+}
+
+func fnLog(args []Value) Value {
+	x, errv := args[0].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	base, errv := numberArg(args, 1, 10)
+	if errv != nil {
+		return *errv
+	}
+	if x <= 0 || base <= 0 || base == 1 {
+		return Error(ErrNum)
+	}
+	return Number(math.Log(x) / math.Log(base))
+}
+
+func fnPower(args []Value) Value {
+	x, errv := args[0].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	y, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if x == 0 && y == 0 {
+		return Error(ErrNum)
+	}
+	return Number(math.Pow(x, y))
+}
+
+func fnMod(args []Value) Value {
+	n, errv := args[0].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	d, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if d == 0 {
+		return Error(ErrDiv0)
+	}
+	return Number(n - d*math.Floor(n/d))
+}
+
+// multiple rounds to a multiple of the significance argument using round.
+func multiple(round func(float64) float64) func(args []Value) Value {
+	return func(args []Value) Value {
+		x, errv := args[0].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		sig, errv := numberArg(args, 1, 1)
+		if errv != nil {
+			return *errv
+		}
+		if sig == 0 {
+			return Number(0)
+		}
+		sig = math.Abs(sig)
+		return Number(round(trimNoise(x/sig)) * sig)
+	}
+}
+
+func fnRandBetween(args []Value) Value {
+	lo, errv := args[0].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	hi, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	lo, hi = math.Ceil(lo), math.Floor(hi)
+	if hi < lo {
+		return Error(ErrNum)
+	}
+	return Number(lo + float64(rand.IntN(int(hi-lo)+1)))
+}
@@ -0,0 +1,316 @@
+package formula
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	register("CONCATENATE", scalar(1, -1, fnConcatenate))
+	register("CONCAT", eager(1, -1, fnConcat))
+	register("TEXTJOIN", eager(3, -1, fnTextJoin))
+	register("LEFT", scalar(1, 2, fnLeft))
+	register("RIGHT", scalar(1, 2, fnRight))
+	register("MID", scalar(3, 3, fnMid))
+	register("LEN", scalar(1, 1, text1(func(s string) Value { return Number(float64(utf8.RuneCountInString(s))) })))
+	register("UPPER", scalar(1, 1, text1(func(s string) Value { return String(strings.ToUpper(s)) })))
+	register("LOWER", scalar(1, 1, text1(func(s string) Value { return String(strings.ToLower(s)) })))
+	register("PROPER", scalar(1, 1, text1(func(s string) Value { return String(proper(s)) })))
+	register("TRIM", scalar(1, 1, text1(func(s string) Value { return String(strings.Join(strings.Fields(s), " ")) })))
+	register("SUBSTITUTE", scalar(3, 4, fnSubstitute))
+	register("REPLACE", scalar(4, 4, fnReplace))
+	register("FIND", scalar(2, 3, finder(false)))
+	register("SEARCH", scalar(2, 3, finder(true)))
+	register("REPT", scalar(2, 2, fnRept))
+	register("EXACT", scalar(2, 2, func(args []Value) Value {
+		if errv := firstError(args...); errv != nil {
+			return *errv
+		}
+		return Bool(args[0].String() == args[1].String())
+	}))
+	register("VALUE", scalar(1, 1, fnValue))
+	register("TEXT", dated(2, 2, fnText))
+	register("T", scalar(1, 1, func(args []Value) Value {
+		if args[0].Kind == KindString || args[0].IsError() {
+			return args[0]
+		}
+		return String("")
+	}))
+	register("N", scalar(1, 1, func(args []Value) Value {
+		switch args[0].Kind {
+		case KindNumber, KindError:
+			return args[0]
+		case KindBool:
+			n, _ := args[0].ToNumber()
+			return Number(n)
+		}
+		return Number(0)
+	}))
+	register("CHAR", scalar(1, 1, func(args []Value) Value {
+		n, errv := args[0].ToNumber()
+		if errv != nil {
+			return *errv
+		}
+		if n < 1 || n > 255 {
+			return Error(ErrValue)
+		}
+		return String(string(rune(int(n))))
+	}))
+	register("CODE", scalar(1, 1, text1(func(s string) Value {
+		if s == "" {
+			return Error(ErrValue)
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		return Number(float64(r))
+	})))
+}
+
+func text1(fn func(s string) Value) func(args []Value) Value {
+	return func(args []Value) Value {
+		if args[0].IsError() {
+			return args[0]
+		}
+		return fn(args[0].String())
+	}
+}
+
+func fnConcatenate(args []Value) Value {
+	if errv := firstError(args...); errv != nil {
+		return *errv
+	}
+	var sb strings.Builder
+	for _, arg := range args {
+		sb.WriteString(arg.String())
+	}
+	return String(sb.String())
+}
+
+func fnConcat(args []Value) Value {
+	var sb strings.Builder
+	var errv *Value
+	each(args, func(v Value, _ bool) bool {
+		if v.IsError() {
+			errv = &v
+			return false
+		}
+		sb.WriteString(v.String())
+		return true
+	})
+	if errv != nil {
+		return *errv
+	}
+	return String(sb.String())
+}
+
+func fnTextJoin(args []Value) Value {
+	delim := args[0].Scalar()
+	if delim.IsError() {
+		return delim
+	}
+	ignoreEmpty, errv := args[1].ToBool()
+	if errv != nil {
+		return *errv
+	}
+	var parts []string
+	each(args[2:], func(v Value, _ bool) bool {
+		if v.IsError() {
+			errv = &v
+			return false
+		}
+		if s := v.String(); s != "" || !ignoreEmpty {
+			parts = append(parts, s)
+		}
+		return true
+	})
+	if errv != nil {
+		return *errv
+	}
+	return String(strings.Join(parts, delim.String()))
+}
+
+// count reads an optional character count argument.
+func count(args []Value, i int) (int, *Value) {
+	n, errv := numberArg(args, i, 1)
+	if errv != nil {
+		return 0, errv
+	}
+	if n < 0 {
+		e := Error(ErrValue)
+		return 0, &e
+	}
+	return int(n), nil
+}
+
+func fnLeft(args []Value) Value {
+	if args[0].IsError() {
+		return args[0]
+	}
+	n, errv := count(args, 1)
+	if errv != nil {
+		return *errv
+	}
+	r := []rune(args[0].String())
+	return String(string(r[:min(n, len(r))]))
+}
+
+func fnRight(args []Value) Value {
+	if args[0].IsError() {
+		return args[0]
+	}
+	n, errv := count(args, 1)
+	if errv != nil {
+		return *errv
+	}
+	r := []rune(args[0].String())
+	return String(string(r[len(r)-min(n, len(r)):]))
+}
+
+func fnMid(args []Value) Value {
+	if args[0].IsError() {
+		return args[0]
+	}
+	start, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	n, errv := count(args, 2)
+	if errv != nil {
+		return *errv
+	}
+	if start < 1 {
+		return Error(ErrValue)
+	}
+	r := []rune(args[0].String())
+	from := min(int(start)-1, len(r))
+	return String(string(r[from:min(from+n, len(r))]))
+}
+
+func fnSubstitute(args []Value) Value {
+	if errv := firstError(args...); errv != nil {
+		return *errv
+	}
+	text, old, repl := args[0].String(), args[1].String(), args[2].String()
+	if old == "" {
+		return String(text)
+	}
+	if len(args) < 4 {
+		return String(strings.ReplaceAll(text, old, repl))
+	}
+	n, errv := args[3].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if n < 1 {
+		return Error(ErrValue)
+	}
+	idx := 0
+	for i := 1; ; i++ {
+		pos := strings.Index(text[idx:], old)
+		if pos < 0 {
+			return String(text)
+		}
+		idx += pos
+		if i == int(n) {
+			return String(text[:idx] + repl + text[idx+len(old):])
+		}
+		idx += len(old)
+	}
+}
+
+func fnReplace(args []Value) Value {
+	if errv := firstError(args...); errv != nil {
+		return *errv
+	}
+	start, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	n, errv := args[2].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if start < 1 || n < 0 {
+		return Error(ErrValue)
+	}
+	r := []rune(args[0].String())
+	from := min(int(start)-1, len(r))
+	to := min(from+int(n), len(r))
+	return String(string(r[:from]) + args[3].String() + string(r[to:]))
+}
+
+// finder implements FIND, which is case-sensitive, and SEARCH, which is not and supports wildcards.
+func finder(search bool) func(args []Value) Value {
+	return func(args []Value) Value {
+		if errv := firstError(args...); errv != nil {
+			return *errv
+		}
+		start, errv := numberArg(args, 2, 1)
+		if errv != nil {
+			return *errv
+		}
+		needle, within := []rune(args[0].String()), []rune(args[1].String())
+		from := int(start) - 1
+		if from < 0 || from > len(within) {
+			return Error(ErrValue)
+		}
+		for i := from; i+len(needle) <= len(within) || (search && i < len(within)); i++ {
+			if search {
+				if wildcardPrefix(string(needle), string(within[i:])) {
+					return Number(float64(i + 1))
+				}
+				continue
+			}
+			if string(within[i:i+len(needle)]) == string(needle) {
+				return Number(float64(i + 1))
+			}
+		}
+		return Error(ErrValue)
+	}
+}
+
+func fnRept(args []Value) Value {
+	if errv := firstError(args...); errv != nil {
+		return *errv
+	}
+	n, errv := args[1].ToNumber()
+	if errv != nil {
+		return *errv
+	}
+	if n < 0 || int(n)*len(args[0].String()) > 32767 {
+		return Error(ErrValue)
+	}
+	return String(strings.Repeat(args[0].String(), int(n)))
+}
+
+func fnValue(args []Value) Value {
+	v := args[0]
+	switch v.Kind {
+	case KindNumber, KindError:
+		return v
+	case KindEmpty:
+		return Number(0)
+	case KindString:
+		if n, ok := parseNumber(v.Str); ok {
+			return Number(n)
+		}
+		if serial, ok := parseDateText(v.Str, false); ok {
+			return Number(serial)
+		}
+	}
+	return Error(ErrValue)
+}
+
+func proper(s string) string {
+	r := []rune(s)
+	prevLetter := false
+	for i, c := range r {
+		if prevLetter {
+			r[i] = unicode.ToLower(c)
+		} else {
+			r[i] = unicode.ToUpper(c)
+		}
+		prevLetter = unicode.IsLetter(c)
+	}
+	return string(r)
+}
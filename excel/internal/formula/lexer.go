@@ -0,0 +1,375 @@
+package formula
+
+import (
+	"fmt"
+	"strings"
+)
+
+// errorLiterals lists the error values a formula may contain, longest first.
+var errorLiterals = []string{
+	"#GETTING_DATA", "#DIV/0!", "#VALUE!", "#SPILL!", "#CALC!", "#NULL!", "#NAME?", "#REF!", "#NUM!", "#N/A",
+}
+
+type Lexer struct {
+	src string
+	pos int
+}
+
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src}
+}
+
+// Tokenize splits src into tokens, excluding the trailing EOF token.
+func Tokenize(src string) ([]Token, error) {
+	l := NewLexer(src)
+	var tokens []Token
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == TokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func (l *Lexer) NextToken() (Token, error) {
+	l.skipWhitespace()
+	if l.pos >= len(l.src) {
+		return Token{Type: TokenEOF, Pos: l.pos, End: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '"':
+		return l.readString()
+	case c == '#':
+		return l.readErrorLiteral(start)
+	case c == '\'':
+		return l.readQuotedReference()
+	case isDigit(c) || (c == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+		return l.readNumber()
+	case isIdentStart(c):
+		return l.readIdentifier()
+	case c == '[':
+		return l.readStructuredReference(start)
+	case c == '(':
+		return l.single(TokenOpenParen), nil
+	case c == ')':
+		return l.single(TokenCloseParen), nil
+	case c == ',':
+		return l.single(TokenComma), nil
+	case c == ';':
+		return l.single(TokenSemicolon), nil
+	case c == ':':
+		return l.single(TokenColon), nil
+	case c == '{':
+		return l.single(TokenOpenBrace), nil
+	case c == '}':
+		return l.single(TokenCloseBrace), nil
+	case c == '<' || c == '>':
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '=' || (c == '<' && l.src[l.pos] == '>')) {
+			l.pos++
+		}
+		return l.token(TokenOperator, start), nil
+	case strings.IndexByte("+-*/^&=%", c) >= 0:
+		return l.single(TokenOperator), nil
+	}
+	return Token{Type: TokenError, Pos: start, End: start + 1}, fmt.Errorf("unexpected character %q at position %d", c, start)
+}
+
+func (l *Lexer) skipWhitespace() {
+	for l.pos < len(l.src) && strings.IndexByte(" \t\r\n", l.src[l.pos]) >= 0 {
+		l.pos++
+	}
+}
+
+func (l *Lexer) single(t TokenType) Token {
+	l.pos++
+	return l.token(t, l.pos-1)
+}
+
+func (l *Lexer) token(t TokenType, start int) Token {
+	return Token{Type: t, Value: l.src[start:l.pos], Pos: start, End: l.pos}
+}
+
+func (l *Lexer) readString() (Token, error) {
+	start := l.pos
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+		if c == '"' {
+			if l.pos < len(l.src) && l.src[l.pos] == '"' {
+				sb.WriteByte('"')
+				l.pos++
+				continue
+			}
+			return Token{Type: TokenString, Value: sb.String(), Pos: start, End: l.pos}, nil
+		}
+		sb.WriteByte(c)
+	}
+	return Token{Type: TokenError, Pos: start, End: l.pos}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func (l *Lexer) readErrorLiteral(start int) (Token, error) {
+	rest := strings.ToUpper(l.src[l.pos:])
+	for _, lit := range errorLiterals {
+		if strings.HasPrefix(rest, lit) {
+			l.pos += len(lit)
+			return Token{Type: TokenErrorLiteral, Value: lit, Pos: start, End: l.pos}, nil
+		}
+	}
+	return Token{Type: TokenError, Pos: start, End: start + 1}, fmt.Errorf("unknown error literal at position %d", start)
+}
+
+func (l *Lexer) readNumber() (Token, error) {
+	start := l.pos
+	l.readDigits()
+	// A run of digits followed by ':' and another row number is a row range, e.g. 1:3.
+	if l.pos < len(l.src) && l.src[l.pos] == ':' && isRowPart(l.src[start:l.pos]) {
+		if end := l.matchPart(l.pos+1, isRowPart); end > 0 {
+			l.pos = end
+			return l.token(TokenReference, start), nil
+		}
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		l.readDigits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		save := l.pos
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.readDigits()
+		} else {
+			l.pos = save
+		}
+	}
+	return l.token(TokenNumber, start), nil
+}
+
+func (l *Lexer) readDigits() {
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *Lexer) readIdentifier() (Token, error) {
+	start := l.pos
+	l.readWord()
+	word := l.src[start:l.pos]
+
+	if l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '!':
+			l.pos++
+			return l.readSheetReference(start)
+		case '(':
+			return l.token(TokenFunction, start), nil
+		case '[':
+			return l.readStructuredReference(start)
+		case ':':
+			if isCellPart(word) {
+				if end := l.matchPart(l.pos+1, isCellPart); end > 0 {
+					l.pos = end
+				}
+				return l.token(TokenReference, start), nil
+			}
+			for _, part := range []func(string) bool{isColumnPart, isRowPart} {
+				if part(word) {
+					if end := l.matchPart(l.pos+1, part); end > 0 {
+						l.pos = end
+						return l.token(TokenReference, start), nil
+					}
+				}
+			}
+		}
+	}
+
+	switch {
+	case isCellPart(word):
+		return l.token(TokenReference, start), nil
+	case strings.EqualFold(word, "TRUE") || strings.EqualFold(word, "FALSE"):
+		return Token{Type: TokenBool, Value: strings.ToUpper(word), Pos: start, End: l.pos}, nil
+	}
+	return l.token(TokenName, start), nil
+}
+
+// readWord consumes the characters allowed in names, cell references and function names.
+func (l *Lexer) readWord() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if !isIdentStart(c) && !isDigit(c) && c != '.' && c != '?' {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *Lexer) readQuotedReference() (Token, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		if l.src[l.pos] == '\'' {
+			if l.pos+1 < len(l.src) && l.src[l.pos+1] == '\'' {
+				l.pos += 2
+				continue
+			}
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '!' {
+				l.pos++
+				return l.readSheetReference(start)
+			}
+			break
+		}
+		l.pos++
+	}
+	return Token{Type: TokenError, Pos: start, End: l.pos}, fmt.Errorf("invalid sheet reference at position %d", start)
+}
+
+// readSheetReference reads the part of a reference following "Sheet!".
+func (l *Lexer) readSheetReference(start int) (Token, error) {
+	if l.pos < len(l.src) && l.src[l.pos] == '#' {
+		if _, err := l.readErrorLiteral(l.pos); err != nil {
+			return Token{Type: TokenError, Pos: start, End: l.pos}, err
+		}
+		return l.token(TokenReference, start), nil
+	}
+	if end := l.matchRange(l.pos); end > 0 {
+		l.pos = end
+		return l.token(TokenReference, start), nil
+	}
+	partStart := l.pos
+	l.readWord()
+	if l.pos == partStart {
+		return Token{Type: TokenError, Pos: start, End: l.pos}, fmt.Errorf("invalid reference at position %d", start)
+	}
+	return l.token(TokenName, start), nil
+}
+
+// readStructuredReference reads a table reference such as Table1[Amount] or Table1[[#This Row],[Qty]].
+func (l *Lexer) readStructuredReference(start int) (Token, error) {
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+		switch c {
+		case '\'':
+			l.pos++ // Escaped character
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return l.token(TokenStructuredReference, start), nil
+			}
+		}
+	}
+	return Token{Type: TokenError, Pos: start, End: l.pos}, fmt.Errorf("unterminated table reference at position %d", start)
+}
+
+// matchRange returns the end of a cell, column or row reference starting at pos, or 0.
+// Column and row references are only valid as ranges, e.g. A:C or 2:5.
+func (l *Lexer) matchRange(pos int) int {
+	if end := l.matchPart(pos, isCellPart); end > 0 {
+		if end < len(l.src) && l.src[end] == ':' {
+			if end2 := l.matchPart(end+1, isCellPart); end2 > 0 {
+				return end2
+			}
+		}
+		return end
+	}
+	for _, part := range []func(string) bool{isColumnPart, isRowPart} {
+		if end := l.matchPart(pos, part); end > 0 && end < len(l.src) && l.src[end] == ':' {
+			if end2 := l.matchPart(end+1, part); end2 > 0 {
+				return end2
+			}
+		}
+	}
+	return 0
+}
+
+// matchPart returns the end of the longest word starting at pos accepted by part, or 0.
+func (l *Lexer) matchPart(pos int, part func(string) bool) int {
+	end := pos
+	for end < len(l.src) && (isWordChar(l.src[end]) || l.src[end] == '$') {
+		end++
+	}
+	if end > pos && part(l.src[pos:end]) {
+		return end
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isIdentStart(c byte) bool {
+	return isLetter(c) || c == '_' || c == '\\' || c == '$' || c >= 0x80
+}
+
+func isWordChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_' || c == '.'
+}
+
+// isCellPart reports whether s is a single cell reference such as A1 or $B$2.
+func isCellPart(s string) bool {
+	col, rest := splitColumn(s)
+	if col == "" {
+		return false
+	}
+	return isRowPart(rest)
+}
+
+// isColumnPart reports whether s is a column reference such as A or $XFD.
+func isColumnPart(s string) bool {
+	col, rest := splitColumn(s)
+	return col != "" && rest == ""
+}
+
+// isRowPart reports whether s is a row reference such as 7 or $12.
+func isRowPart(s string) bool {
+	s = strings.TrimPrefix(s, "$")
+	if s == "" || len(s) > 7 || s[0] == '0' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitColumn splits an optional '$' and up to three column letters from the front of s.
+func splitColumn(s string) (string, string) {
+	i := 0
+	if i < len(s) && s[i] == '$' {
+		i++
+	}
+	j := i
+	for j < len(s) && isLetter(s[j]) {
+		j++
+	}
+	if j == i || j-i > 3 {
+		return "", s
+	}
+	if colNumber(s[i:j]) > MaxColumns {
+		return "", s
+	}
+	return s[:j], s[j:]
+}
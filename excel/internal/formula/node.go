@@ -0,0 +1,97 @@
+package formula
+
+// Node is an element of a parsed formula.
+type Node interface {
+	node()
+}
+
+type NumberNode struct {
+	Value float64
+}
+
+type StringNode struct {
+	Value string
+}
+
+type BoolNode struct {
+	Value bool
+}
+
+type ErrorNode struct {
+	Code string
+}
+
+// MissingNode is an omitted function argument, as in IF(A1,,1).
+type MissingNode struct{}
+
+type RefNode struct {
+	Ref Reference
+}
+
+// NameNode is a defined name, optionally qualified by a sheet.
+type NameNode struct {
+	Sheet string
+	Name  string
+}
+
+// TableRefNode is a structured reference into a table, e.g. Sales[Amount].
+type TableRefNode struct {
+	Table string
+	Spec  string // Text between the outer brackets
+}
+
+type FuncNode struct {
+	Name string
+	Args []Node
+}
+
+type UnaryNode struct {
+	Op      string
+	Operand Node
+}
+
+type BinaryNode struct {
+	Op    string
+	Left  Node
+	Right Node
+}
+
+// ArrayNode is an array constant such as {1,2;3,4}.
+type ArrayNode struct {
+	Rows [][]Node
+}
+
+func (NumberNode) node()   {}
+func (StringNode) node()   {}
+func (BoolNode) node()     {}
+func (ErrorNode) node()    {}
+func (MissingNode) node()  {}
+func (RefNode) node()      {}
+func (NameNode) node()     {}
+func (TableRefNode) node() {}
+func (FuncNode) node()     {}
+func (UnaryNode) node()    {}
+func (BinaryNode) node()   {}
+func (ArrayNode) node()    {}
+
+// Walk calls fn for n and each of its descendants in depth-first order.
+func Walk(n Node, fn func(Node)) {
+	fn(n)
+	switch v := n.(type) {
+	case FuncNode:
+		for _, arg := range v.Args {
+			Walk(arg, fn)
+		}
+	case UnaryNode:
+		Walk(v.Operand, fn)
+	case BinaryNode:
+		Walk(v.Left, fn)
+		Walk(v.Right, fn)
+	case ArrayNode:
+		for _, row := range v.Rows {
+			for _, item := range row {
+				Walk(item, fn)
+			}
+		}
+	}
+}
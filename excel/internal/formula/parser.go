@@ -0,0 +1,265 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Parser struct {
+	tokens []Token
+	pos    int
+}
+
+func NewParser(tokens []Token) *Parser {
+	return &Parser{tokens: tokens}
+}
+
+// Parse parses a formula, with or without its leading '=', into a syntax tree.
+func Parse(src string) (Node, error) {
+	src = strings.TrimPrefix(src, "=")
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty formula")
+	}
+	return NewParser(tokens).Parse()
+}
+
+func (p *Parser) Parse() (Node, error) {
+	n, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Type != TokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.Value, tok.Pos)
+	}
+	return n, nil
+}
+
+func (p *Parser) peek() Token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	end := 0
+	if len(p.tokens) > 0 {
+		end = p.tokens[len(p.tokens)-1].End
+	}
+	return Token{Type: TokenEOF, Pos: end, End: end}
+}
+
+func (p *Parser) next() Token {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *Parser) isOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.Type != TokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.Value == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// binary parses a left-associative chain of operators at one precedence level.
+func (p *Parser) binary(operand func() (Node, error), ops ...string) (Node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOperator(ops...)
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *Parser) parseComparison() (Node, error) {
+	return p.binary(p.parseConcat, "=", "<>", "<", ">", "<=", ">=")
+}
+
+func (p *Parser) parseConcat() (Node, error) {
+	return p.binary(p.parseAdditive, "&")
+}
+
+func (p *Parser) parseAdditive() (Node, error) {
+	return p.binary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *Parser) parseMultiplicative() (Node, error) {
+	return p.binary(p.parseExponent, "*", "/")
+}
+
+func (p *Parser) parseExponent() (Node, error) {
+	return p.binary(p.parsePercent, "^")
+}
+
+func (p *Parser) parsePercent() (Node, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOperator("%"); !ok {
+			return n, nil
+		}
+		p.next()
+		n = UnaryNode{Op: "%", Operand: n}
+	}
+}
+
+// parseUnary parses prefix signs, which bind tighter than '^' so that -2^2 is 4.
+func (p *Parser) parseUnary() (Node, error) {
+	if op, ok := p.isOperator("-", "+"); ok {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return UnaryNode{Op: op, Operand: operand}, nil
+	}
+	return p.parseRange()
+}
+
+// parseRange parses the ':' operator between references that the lexer
+// could not join, e.g. A1:INDEX(B:B,5).
+func (p *Parser) parseRange() (Node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().Type == TokenColon {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{Op: ":", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.Type {
+	case TokenNumber:
+		v, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.Value, tok.Pos)
+		}
+		return NumberNode{Value: v}, nil
+	case TokenString:
+		return StringNode{Value: tok.Value}, nil
+	case TokenBool:
+		return BoolNode{Value: tok.Value == "TRUE"}, nil
+	case TokenErrorLiteral:
+		return ErrorNode{Code: tok.Value}, nil
+	case TokenReference:
+		ref, err := ParseReference(tok.Value)
+		if err != nil {
+			return nil, err
+		}
+		return RefNode{Ref: ref}, nil
+	case TokenName:
+		sheet, name := "", tok.Value
+		if idx := strings.LastIndexByte(name, '!'); idx >= 0 {
+			sheet, name = unquoteSheet(name[:idx]), name[idx+1:]
+		}
+		return NameNode{Sheet: sheet, Name: name}, nil
+	case TokenStructuredReference:
+		idx := strings.IndexByte(tok.Value, '[')
+		return TableRefNode{Table: tok.Value[:idx], Spec: tok.Value[idx+1 : len(tok.Value)-1]}, nil
+	case TokenFunction:
+		return p.parseFunction(tok)
+	case TokenOpenParen:
+		n, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.Type != TokenCloseParen {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.Pos)
+		}
+		return n, nil
+	case TokenOpenBrace:
+		return p.parseArray()
+	case TokenEOF:
+		return nil, fmt.Errorf("unexpected end of formula")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.Value, tok.Pos)
+}
+
+func (p *Parser) parseFunction(tok Token) (Node, error) {
+	p.next() // '('
+	fn := FuncNode{Name: normalizeFunctionName(tok.Value)}
+	if p.peek().Type == TokenCloseParen {
+		p.next()
+		return fn, nil
+	}
+	for {
+		var arg Node = MissingNode{}
+		if t := p.peek().Type; t != TokenComma && t != TokenCloseParen {
+			var err error
+			if arg, err = p.parseComparison(); err != nil {
+				return nil, err
+			}
+		}
+		fn.Args = append(fn.Args, arg)
+
+		switch sep := p.next(); sep.Type {
+		case TokenComma:
+			continue
+		case TokenCloseParen:
+			return fn, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or ')' in %s at position %d", fn.Name, sep.Pos)
+		}
+	}
+}
+
+func (p *Parser) parseArray() (Node, error) {
+	arr := ArrayNode{Rows: [][]Node{{}}}
+	for {
+		item, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		arr.Rows[len(arr.Rows)-1] = append(arr.Rows[len(arr.Rows)-1], item)
+
+		switch sep := p.next(); sep.Type {
+		case TokenComma:
+		case TokenSemicolon:
+			arr.Rows = append(arr.Rows, nil)
+		case TokenCloseBrace:
+			return arr, nil
+		default:
+			return nil, fmt.Errorf("expected ',', ';' or '}' in array at position %d", sep.Pos)
+		}
+	}
+}
+
+// normalizeFunctionName upper-cases a function name and strips the prefixes
+// Excel stores in front of functions added after the 2007 file format.
+func normalizeFunctionName(name string) string {
+	name = strings.ToUpper(name)
+	for _, prefix := range []string{"_XLFN._XLWS.", "_XLFN.", "_XLWS."} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
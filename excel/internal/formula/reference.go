@@ -0,0 +1,213 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size of the worksheet grid.
+const (
+	MaxColumns = 16384
+	MaxRows    = 1048576
+)
+
+// Reference is a cell, area, column range or row range, optionally qualified by a sheet.
+// Column ranges (A:C) have Row1 and Row2 set to 0; row ranges (2:5) have Col1 and Col2 set to 0.
+type Reference struct {
+	Sheet   string
+	Col1    int
+	Row1    int
+	Col2    int
+	Row2    int
+	AbsCol1 bool
+	AbsRow1 bool
+	AbsCol2 bool
+	AbsRow2 bool
+	IsRange bool // Written with ':' even when it covers a single cell
+	Invalid bool // #REF!
+}
+
+// ParseReference parses a reference such as A1, $B$2:C10, Sheet1!A:A or 'My Sheet'!3:5.
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+	if idx := strings.LastIndexByte(s, '!'); idx >= 0 {
+		ref.Sheet = unquoteSheet(s[:idx])
+		s = s[idx+1:]
+	}
+	if strings.EqualFold(s, "#REF!") {
+		ref.Invalid = true
+		return ref, nil
+	}
+
+	first, second, isRange := strings.Cut(s, ":")
+	var err error
+	ref.Col1, ref.Row1, ref.AbsCol1, ref.AbsRow1, err = parsePart(first)
+	if err != nil {
+		return Reference{}, err
+	}
+	if !isRange {
+		if ref.Col1 == 0 || ref.Row1 == 0 {
+			return Reference{}, fmt.Errorf("invalid reference %q", s)
+		}
+		ref.Col2, ref.Row2, ref.AbsCol2, ref.AbsRow2 = ref.Col1, ref.Row1, ref.AbsCol1, ref.AbsRow1
+		return ref, nil
+	}
+
+	ref.IsRange = true
+	ref.Col2, ref.Row2, ref.AbsCol2, ref.AbsRow2, err = parsePart(second)
+	if err != nil {
+		return Reference{}, err
+	}
+	if (ref.Col1 == 0) != (ref.Col2 == 0) || (ref.Row1 == 0) != (ref.Row2 == 0) {
+		return Reference{}, fmt.Errorf("invalid reference %q", s)
+	}
+	if ref.Col1 > ref.Col2 {
+		ref.Col1, ref.Col2 = ref.Col2, ref.Col1
+		ref.AbsCol1, ref.AbsCol2 = ref.AbsCol2, ref.AbsCol1
+	}
+	if ref.Row1 > ref.Row2 {
+		ref.Row1, ref.Row2 = ref.Row2, ref.Row1
+		ref.AbsRow1, ref.AbsRow2 = ref.AbsRow2, ref.AbsRow1
+	}
+	return ref, nil
+}
+
+// parsePart parses one side of a reference: a cell, a column or a row.
+func parsePart(s string) (col, row int, absCol, absRow bool, err error) {
+	colPart, rowPart := splitColumn(s)
+	if colPart == "" && rowPart == "" {
+		return 0, 0, false, false, fmt.Errorf("invalid reference %q", s)
+	}
+	if colPart != "" {
+		absCol = colPart[0] == '$'
+		col = colNumber(strings.TrimPrefix(colPart, "$"))
+	}
+	if rowPart != "" {
+		if !isRowPart(rowPart) {
+			return 0, 0, false, false, fmt.Errorf("invalid reference %q", s)
+		}
+		absRow = rowPart[0] == '$'
+		row, _ = strconv.Atoi(strings.TrimPrefix(rowPart, "$"))
+		if row > MaxRows {
+			return 0, 0, false, false, fmt.Errorf("row out of range in %q", s)
+		}
+	}
+	return col, row, absCol, absRow, nil
+}
+
+// IsCell reports whether the reference denotes a single cell written without ':'.
+func (r Reference) IsCell() bool {
+	return !r.IsRange && !r.Invalid
+}
+
+// Area returns the bounds of the reference, resolving whole columns and rows
+// against the given used range.
+func (r Reference) Area(maxCol, maxRow int) (col1, row1, col2, row2 int) {
+	col1, row1, col2, row2 = r.Col1, r.Row1, r.Col2, r.Row2
+	if col1 == 0 {
+		col1, col2 = 1, min(maxCol, MaxColumns)
+	}
+	if row1 == 0 {
+		row1, row2 = 1, min(maxRow, MaxRows)
+	}
+	return col1, row1, col2, row2
+}
+
+// Contains reports whether the cell at col and row lies within the reference.
+func (r Reference) Contains(col, row int) bool {
+	if r.Invalid {
+		return false
+	}
+	if r.Col1 != 0 && (col < r.Col1 || col > r.Col2) {
+		return false
+	}
+	if r.Row1 != 0 && (row < r.Row1 || row > r.Row2) {
+		return false
+	}
+	return true
+}
+
+func (r Reference) String() string {
+	var sb strings.Builder
+	if r.Sheet != "" {
+		sb.WriteString(QuoteSheet(r.Sheet))
+		sb.WriteByte('!')
+	}
+	if r.Invalid {
+		sb.WriteString("#REF!")
+		return sb.String()
+	}
+	writePart(&sb, r.Col1, r.Row1, r.AbsCol1, r.AbsRow1)
+	if r.IsRange {
+		sb.WriteByte(':')
+		writePart(&sb, r.Col2, r.Row2, r.AbsCol2, r.AbsRow2)
+	}
+	return sb.String()
+}
+
+func writePart(sb *strings.Builder, col, row int, absCol, absRow bool) {
+	if col > 0 {
+		if absCol {
+			sb.WriteByte('$')
+		}
+		sb.WriteString(ColumnName(col))
+	}
+	if row > 0 {
+		if absRow {
+			sb.WriteByte('$')
+		}
+		sb.WriteString(strconv.Itoa(row))
+	}
+}
+
+// CellName returns the A1-style name of a cell, e.g. CellName(2, 3) is "B3".
+func CellName(col, row int) string {
+	return ColumnName(col) + strconv.Itoa(row)
+}
+
+// ColumnName converts a 1-based column number to letters, e.g. 28 is "AB".
+func ColumnName(n int) string {
+	var buf [3]byte
+	i := len(buf)
+	for n > 0 && i > 0 {
+		n--
+		i--
+		buf[i] = byte('A' + n%26)
+		n /= 26
+	}
+	return string(buf[i:])
+}
+
+// colNumber converts column letters to a 1-based column number.
+func colNumber(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		n = n*26 + int(c-'A'+1)
+	}
+	return n
+}
+
+// QuoteSheet quotes a sheet name for use in a reference when required.
+func QuoteSheet(name string) string {
+	needsQuote := name == "" || isDigit(name[0]) || isCellPart(name) || strings.EqualFold(name, "TRUE") || strings.EqualFold(name, "FALSE")
+	for i := 0; i < len(name) && !needsQuote; i++ {
+		c := name[i]
+		needsQuote = !isLetter(c) && !isDigit(c) && c != '_' && c != '.' && c < 0x80
+	}
+	if !needsQuote {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+func unquoteSheet(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
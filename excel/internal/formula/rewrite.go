@@ -0,0 +1,140 @@
+package formula
+
+import "strings"
+
+// RewriteReferences returns src with every cell reference replaced by the result of fn.
+// Everything other than references, including spacing, is preserved.
+func RewriteReferences(src string, fn func(Reference) Reference) (string, error) {
+	prefix := ""
+	if strings.HasPrefix(src, "=") {
+		prefix, src = "=", src[1:]
+	}
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	last := 0
+	for _, tok := range tokens {
+		if tok.Type != TokenReference {
+			continue
+		}
+		ref, err := ParseReference(tok.Value)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(src[last:tok.Pos])
+		sb.WriteString(fn(ref).String())
+		last = tok.End
+	}
+	sb.WriteString(src[last:])
+	return sb.String(), nil
+}
+
+// Translate moves the relative parts of every reference in src by the given
+// number of columns and rows, as when a formula is copied to another cell.
+// References moved off the grid become #REF!.
+func Translate(src string, dCol, dRow int) (string, error) {
+	if dCol == 0 && dRow == 0 {
+		return src, nil
+	}
+	return RewriteReferences(src, func(ref Reference) Reference {
+		return ref.Translate(dCol, dRow)
+	})
+}
+
+// Translate moves the relative parts of the reference by the given offsets.
+func (r Reference) Translate(dCol, dRow int) Reference {
+	if r.Invalid {
+		return r
+	}
+	move := func(v, d int, abs bool, limit int) int {
+		if v == 0 || abs {
+			return v
+		}
+		v += d
+		if v < 1 || v > limit {
+			return -1
+		}
+		return v
+	}
+	r.Col1 = move(r.Col1, dCol, r.AbsCol1, MaxColumns)
+	r.Col2 = move(r.Col2, dCol, r.AbsCol2, MaxColumns)
+	r.Row1 = move(r.Row1, dRow, r.AbsRow1, MaxRows)
+	r.Row2 = move(r.Row2, dRow, r.AbsRow2, MaxRows)
+	if r.Col1 < 0 || r.Col2 < 0 || r.Row1 < 0 || r.Row2 < 0 {
+		return Reference{Sheet: r.Sheet, Invalid: true}
+	}
+	return r
+}
+
+// Shift adjusts the reference for count rows, or columns when cols is set,
+// inserted before index at. A negative count deletes -count rows or columns
+// starting at at: ranges shrink, and references left without cells become #REF!.
+// Absolute and relative parts move alike, as they do when Excel edits the grid.
+func (r Reference) Shift(cols bool, at, count int) Reference {
+	if count == 0 {
+		return r
+	}
+	limit := MaxRows
+	if cols {
+		limit = MaxColumns
+	}
+	return r.ShiftBy(cols, func(lo, hi int) (int, int, bool) {
+		return ShiftSpan(lo, hi, at, count, limit)
+	})
+}
+
+// ShiftBy is Shift with the rows or columns moved by span, which maps the
+// indexes lo through hi as ShiftSpan does.
+func (r Reference) ShiftBy(cols bool, span func(lo, hi int) (int, int, bool)) Reference {
+	if r.Invalid {
+		return r
+	}
+	lo, hi := &r.Row1, &r.Row2
+	if cols {
+		lo, hi = &r.Col1, &r.Col2
+	}
+	if *lo == 0 {
+		// Whole columns are not affected by rows, nor whole rows by columns.
+		return r
+	}
+	if *lo > *hi {
+		*lo, *hi = *hi, *lo
+	}
+	var ok bool
+	if *lo, *hi, ok = span(*lo, *hi); !ok {
+		return Reference{Sheet: r.Sheet, Invalid: true}
+	}
+	return r
+}
+
+// ShiftSpan applies an insertion or deletion, as described for Shift, to the
+// indexes lo through hi. It reports false when no index is left.
+func ShiftSpan(lo, hi, at, count, limit int) (int, int, bool) {
+	if count > 0 {
+		if lo >= at {
+			lo += count
+		}
+		if hi >= at {
+			hi += count
+		}
+		return lo, min(hi, limit), lo <= limit
+	}
+	end := at - count - 1
+	switch {
+	case lo > end:
+		lo += count
+	case lo >= at:
+		lo = at
+	}
+	switch {
+	case hi > end:
+		hi += count
+	case hi >= at:
+		hi = at - 1
+	}
+	return lo, hi, lo <= hi
+}
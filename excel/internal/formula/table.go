@@ -0,0 +1,251 @@
+package formula
+
+import "strings"
+
+// Table is the layout of a table that structured references resolve against.
+type Table struct {
+	Name      string
+	Sheet     string
+	Col1      int // Area of the table including its header and totals rows
+	Row1      int
+	Col2      int
+	Row2      int
+	Columns   []string // Column headers in order
+	HeaderRow bool
+	TotalsRow bool
+}
+
+// tableSpec is the parsed specifier of a structured reference, e.g.
+// [[#This Row],[Qty]] or [#All].
+type tableSpec struct {
+	items []string // Special items such as "#Data"; none selects the data rows
+	col1  string   // First column, "" for every column
+	col2  string
+}
+
+// parseTableSpec parses the text between the outer brackets of a structured reference.
+func parseTableSpec(spec string) (tableSpec, bool) {
+	var s tableSpec
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@"); ok {
+		s.items = []string{"#this row"}
+		rest = strings.TrimSpace(rest)
+		if inner, ok := bracketed(rest); ok {
+			rest = inner
+		}
+		s.col1, s.col2 = unescapeColumn(rest), unescapeColumn(rest)
+		return s, true
+	}
+	if !strings.HasPrefix(spec, "[") {
+		if strings.HasPrefix(spec, "#") {
+			s.items = []string{strings.ToLower(spec)}
+		} else {
+			s.col1, s.col2 = unescapeColumn(spec), unescapeColumn(spec)
+		}
+		return s, true
+	}
+
+	for _, part := range splitSpec(spec, ',') {
+		columns := splitSpec(part, ':')
+		if len(columns) > 2 {
+			return s, false
+		}
+		first, ok := bracketed(columns[0])
+		if !ok {
+			return s, false
+		}
+		if len(columns) == 1 && strings.HasPrefix(first, "#") {
+			s.items = append(s.items, strings.ToLower(first))
+			continue
+		}
+		if s.col1 != "" {
+			return s, false
+		}
+		s.col1, s.col2 = unescapeColumn(first), unescapeColumn(first)
+		if len(columns) == 2 {
+			last, ok := bracketed(columns[1])
+			if !ok {
+				return s, false
+			}
+			s.col2 = unescapeColumn(last)
+		}
+	}
+	return s, true
+}
+
+// splitSpec splits a specifier at sep outside brackets, trimming the parts.
+func splitSpec(spec string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(spec); i++ {
+		switch spec[i] {
+		case '\'':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(spec[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(spec[start:]))
+}
+
+// bracketed returns the text within s when it is enclosed in brackets.
+func bracketed(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return "", false
+	}
+	return s[1 : len(s)-1], true
+}
+
+// unescapeColumn removes the ' escapes of special characters in a column name.
+func unescapeColumn(s string) string {
+	if !strings.Contains(s, "'") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// column returns the 1-based sheet column of a table column header.
+func (t Table) column(name string) (int, bool) {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, name) {
+			return t.Col1 + i, true
+		}
+	}
+	return 0, false
+}
+
+// Reference resolves the specifier of a structured reference into the table,
+// e.g. "[#This Row],[Qty]", for a formula in row. It reports false when the
+// specifier names a missing column or row, or a row of another part of the table.
+func (t Table) Reference(spec string, row int) (Reference, bool) {
+	s, ok := parseTableSpec(spec)
+	if !ok {
+		return Reference{}, false
+	}
+	ref := Reference{Sheet: t.Sheet, Col1: t.Col1, Col2: t.Col2, IsRange: true}
+	if s.col1 != "" {
+		c1, ok1 := t.column(s.col1)
+		c2, ok2 := t.column(s.col2)
+		if !ok1 || !ok2 {
+			return Reference{}, false
+		}
+		ref.Col1, ref.Col2 = min(c1, c2), max(c1, c2)
+	}
+
+	header, first, last := t.Row1, t.Row1, t.Row2
+	if t.HeaderRow {
+		first++
+	}
+	if t.TotalsRow {
+		last--
+	}
+	if len(s.items) == 0 {
+		s.items = []string{"#data"}
+	}
+	ref.Row1, ref.Row2 = MaxRows+1, 0
+	for _, item := range s.items {
+		r1, r2 := 0, 0
+		switch item {
+		case "#all":
+			r1, r2 = t.Row1, t.Row2
+		case "#data":
+			r1, r2 = first, last
+		case "#headers":
+			if !t.HeaderRow {
+				return Reference{}, false
+			}
+			r1, r2 = header, header
+		case "#totals":
+			if !t.TotalsRow {
+				return Reference{}, false
+			}
+			r1, r2 = t.Row2, t.Row2
+		case "#this row":
+			if row < first || row > last {
+				return Reference{}, false
+			}
+			r1, r2 = row, row
+		default:
+			return Reference{}, false
+		}
+		ref.Row1, ref.Row2 = min(ref.Row1, r1), max(ref.Row2, r2)
+	}
+	if ref.Row2 < ref.Row1 {
+		return Reference{}, false
+	}
+	return ref, true
+}
+
+// QualifyTableReferences returns src with its structured references written as
+// Excel stores them in files: references without a table name are qualified
+// with table, and the @ shorthand becomes [#This Row], e.g. [@Qty] becomes
+// Sales[[#This Row],[Qty]].
+func QualifyTableReferences(src, table string) (string, error) {
+	prefix := ""
+	if strings.HasPrefix(src, "=") {
+		prefix, src = "=", src[1:]
+	}
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	last := 0
+	for _, tok := range tokens {
+		if tok.Type != TokenStructuredReference {
+			continue
+		}
+		idx := strings.IndexByte(tok.Value, '[')
+		name, spec := tok.Value[:idx], tok.Value[idx+1:len(tok.Value)-1]
+		if name == "" {
+			name = table
+		}
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(spec), "@"); ok {
+			rest = strings.TrimSpace(rest)
+			switch {
+			case rest == "":
+				spec = "[#This Row]"
+			case strings.HasPrefix(rest, "["):
+				spec = "[#This Row]," + rest
+			default:
+				spec = "[#This Row],[" + rest + "]"
+			}
+		}
+		sb.WriteString(src[last:tok.Pos])
+		sb.WriteString(name + "[" + spec + "]")
+		last = tok.End
+	}
+	sb.WriteString(src[last:])
+	return sb.String(), nil
+}
+
+// ColumnReference returns the structured reference to the data of a table
+// column, e.g. Sales[Unit Price], escaping the characters brackets treat specially.
+func ColumnReference(table, column string) string {
+	var sb strings.Builder
+	sb.WriteString(table + "[")
+	for _, r := range column {
+		if strings.ContainsRune("[]#'", r) {
+			sb.WriteByte('\'')
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
@@ -0,0 +1,9 @@
+package formula
+
+// Token is a lexical unit of a formula. Pos and End delimit the token in the source text.
+type Token struct {
+	Type  TokenType
+	Value string
+	Pos   int
+	End   int
+}
@@ -0,0 +1,24 @@
+package formula
+
+type TokenType int
+
+const (
+	TokenError TokenType = iota
+	TokenEOF
+	TokenNumber
+	TokenString
+	TokenBool
+	TokenErrorLiteral        // #N/A, #DIV/0!, ...
+	TokenReference           // A1, $A$1:$B$2, Sheet1!A:A, 'My Sheet'!1:3
+	TokenName                // Defined name
+	TokenStructuredReference // Table1[Column], [@Column]
+	TokenFunction            // Identifier followed by '('
+	TokenOperator            // + - * / ^ & = <> < > <= >= %
+	TokenOpenParen           // (
+	TokenCloseParen          // )
+	TokenComma               // ,
+	TokenSemicolon           // ;
+	TokenColon               // :
+	TokenOpenBrace           // {
+	TokenCloseBrace          // }
+)
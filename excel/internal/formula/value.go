@@ -0,0 +1,233 @@
+package formula
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Error values produced by formulas.
+const (
+	ErrNull  = "#NULL!"
+	ErrDiv0  = "#DIV/0!"
+	ErrValue = "#VALUE!"
+	ErrRef   = "#REF!"
+	ErrName  = "#NAME?"
+	ErrNum   = "#NUM!"
+	ErrNA    = "#N/A"
+)
+
+type Kind int
+
+const (
+	KindEmpty Kind = iota
+	KindNumber
+	KindString
+	KindBool
+	KindError
+	KindArray
+)
+
+// Value is the result of evaluating a formula or an operand within one.
+type Value struct {
+	Kind  Kind
+	Num   float64
+	Str   string // Text, or the error code for KindError
+	Bool  bool
+	Array [][]Value
+}
+
+func Number(v float64) Value {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return Error(ErrNum)
+	}
+	return Value{Kind: KindNumber, Num: v}
+}
+
+func String(v string) Value {
+	return Value{Kind: KindString, Str: v}
+}
+
+func Bool(v bool) Value {
+	return Value{Kind: KindBool, Bool: v}
+}
+
+func Error(code string) Value {
+	return Value{Kind: KindError, Str: code}
+}
+
+func Array(rows [][]Value) Value {
+	return Value{Kind: KindArray, Array: rows}
+}
+
+var empty = Value{}
+
+func (v Value) IsError() bool {
+	return v.Kind == KindError
+}
+
+// Scalar returns the top-left element of an array, or v itself.
+func (v Value) Scalar() Value {
+	if v.Kind != KindArray {
+		return v
+	}
+	if len(v.Array) == 0 || len(v.Array[0]) == 0 {
+		return Error(ErrValue)
+	}
+	return v.Array[0][0].Scalar()
+}
+
+// ToNumber coerces v to a number. The second result is an error value when coercion fails.
+func (v Value) ToNumber() (float64, *Value) {
+	switch v.Kind {
+	case KindNumber:
+		return v.Num, nil
+	case KindBool:
+		if v.Bool {
+			return 1, nil
+		}
+		return 0, nil
+	case KindEmpty:
+		return 0, nil
+	case KindString:
+		if f, ok := parseNumber(v.Str); ok {
+			return f, nil
+		}
+		e := Error(ErrValue)
+		return 0, &e
+	case KindArray:
+		return v.Scalar().ToNumber()
+	}
+	return 0, &v
+}
+
+// ToBool coerces v to a boolean. The second result is an error value when coercion fails.
+func (v Value) ToBool() (bool, *Value) {
+	switch v.Kind {
+	case KindBool:
+		return v.Bool, nil
+	case KindNumber:
+		return v.Num != 0, nil
+	case KindEmpty:
+		return false, nil
+	case KindString:
+		switch strings.ToUpper(v.Str) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+		e := Error(ErrValue)
+		return false, &e
+	case KindArray:
+		return v.Scalar().ToBool()
+	}
+	return false, &v
+}
+
+// String returns the text form of v as used by concatenation and text functions.
+func (v Value) String() string {
+	switch v.Kind {
+	case KindNumber:
+		return FormatNumber(v.Num)
+	case KindString, KindError:
+		return v.Str
+	case KindBool:
+		if v.Bool {
+			return "TRUE"
+		}
+		return "FALSE"
+	case KindArray:
+		return v.Scalar().String()
+	}
+	return ""
+}
+
+// FormatNumber formats f with at most 15 significant digits, as Excel displays numbers in text.
+func FormatNumber(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	abs := math.Abs(rounded)
+	if abs >= 1e21 || abs < 1e-9 {
+		return strings.ToUpper(strconv.FormatFloat(rounded, 'g', -1, 64))
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	percent := strings.HasSuffix(s, "%")
+	s = strings.TrimSuffix(s, "%")
+	s = strings.ReplaceAll(s, ",", "")
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if percent {
+		f /= 100
+	}
+	return f, true
+}
+
+// compare orders two scalars the way Excel does: numbers < text < booleans,
+// with text compared case-insensitively. Empty values compare as 0 or "".
+func compare(a, b Value) int {
+	if a.Kind == KindEmpty {
+		a = emptyLike(b)
+	}
+	if b.Kind == KindEmpty {
+		b = emptyLike(a)
+	}
+	ra, rb := kindRank(a.Kind), kindRank(b.Kind)
+	if ra != rb {
+		return ra - rb
+	}
+	switch a.Kind {
+	case KindNumber:
+		switch {
+		case a.Num < b.Num:
+			return -1
+		case a.Num > b.Num:
+			return 1
+		}
+		return 0
+	case KindString:
+		return strings.Compare(strings.ToLower(a.Str), strings.ToLower(b.Str))
+	case KindBool:
+		switch {
+		case a.Bool == b.Bool:
+			return 0
+		case !a.Bool:
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func emptyLike(v Value) Value {
+	switch v.Kind {
+	case KindString:
+		return String("")
+	case KindBool:
+		return Bool(false)
+	}
+	return Number(0)
+}
+
+func kindRank(k Kind) int {
+	switch k {
+	case KindNumber:
+		return 0
+	case KindString:
+		return 1
+	case KindBool:
+		return 2
+	}
+	return 3
+}
@@ -2,12 +2,26 @@ package xmlstructs
 
 // Cell defines a cell in a row
 type Cell struct {
-	R  string  `xml:"r,attr"`
-	S  int     `xml:"s,attr,omitempty"`
-	T  string  `xml:"t,attr,omitempty"`
-	F  *string `xml:"f,omitempty"`
-	V  string  `xml:"v,omitempty"`
-	IS *Rst    `xml:"is,omitempty"` // Inline string/Rich text
+	R  string   `xml:"r,attr"`
+	S  int      `xml:"s,attr,omitempty"`
+	T  string   `xml:"t,attr,omitempty"`
+	F  *Formula `xml:"f,omitempty"`
+	V  string   `xml:"v,omitempty"`
+	IS *Rst     `xml:"is,omitempty"` // Inline string/Rich text
+}
+
+// Formula defines a cell formula. Shared formulas keep their text on the
+// master cell only; the other cells of the group reference it through SI.
+type Formula struct {
+	Text string `xml:",chardata"`
+	T    string `xml:"t,attr,omitempty"` // "normal", "shared", "array" or "dataTable"
+	Ref  string `xml:"ref,attr,omitempty"`
+	SI   *int   `xml:"si,attr,omitempty"`
+	CA   string `xml:"ca,attr,omitempty"`
+	Dt2D string `xml:"dt2D,attr,omitempty"`
+	Dtr  string `xml:"dtr,attr,omitempty"`
+	R1   string `xml:"r1,attr,omitempty"`
+	R2   string `xml:"r2,attr,omitempty"`
 }
 
 // Rst represents a rich text run or inline string.
@@ -0,0 +1,206 @@
+package xmlstructs
+
+import "encoding/xml"
+
+// ChartSpace defines the structure of xl/charts/chart[n].xml.
+// Like WsDr it is written with MarshalPrefixed.
+type ChartSpace struct {
+	XMLName        xml.Name `xml:"http://schemas.openxmlformats.org/drawingml/2006/chart chartSpace"`
+	RoundedCorners ValInt   `xml:"roundedCorners"`
+	Chart          Chart    `xml:"chart"`
+}
+
+// Prefixes returns the namespace prefixes the chart is written with.
+func (c *ChartSpace) Prefixes() []Namespace {
+	return []Namespace{
+		{Prefix: "c", URI: NSChart},
+		{Prefix: "a", URI: NSDrawingML},
+		{Prefix: "r", URI: NSRelationships},
+	}
+}
+
+type Chart struct {
+	Title            *ChartTitle `xml:"title,omitempty"`
+	AutoTitleDeleted ValInt      `xml:"autoTitleDeleted"`
+	PlotArea         PlotArea    `xml:"plotArea"`
+	Legend           *Legend     `xml:"legend,omitempty"`
+	PlotVisOnly      ValInt      `xml:"plotVisOnly"`
+	DispBlanksAs     ValString   `xml:"dispBlanksAs"`
+}
+
+type ChartTitle struct {
+	Tx      ChartText `xml:"tx"`
+	Overlay ValInt    `xml:"overlay"`
+}
+
+type ChartText struct {
+	Rich RichText `xml:"rich"`
+}
+
+// RichText is a DrawingML text body holding a single run.
+type RichText struct {
+	BodyPr struct{}      `xml:"http://schemas.openxmlformats.org/drawingml/2006/main bodyPr"`
+	P      TextParagraph `xml:"http://schemas.openxmlformats.org/drawingml/2006/main p"`
+}
+
+type TextParagraph struct {
+	R TextRun `xml:"r"`
+}
+
+type TextRun struct {
+	T string `xml:"t"`
+}
+
+// PlotArea holds one chart group per chart type and axis pair, followed by the axes.
+type PlotArea struct {
+	Layout        struct{}     `xml:"layout"`
+	AreaCharts    []ChartGroup `xml:"areaChart"`
+	BarCharts     []ChartGroup `xml:"barChart"`
+	LineCharts    []ChartGroup `xml:"lineChart"`
+	PieCharts     []ChartGroup `xml:"pieChart"`
+	ScatterCharts []ChartGroup `xml:"scatterChart"`
+	CatAx         []ChartAxis  `xml:"catAx"`
+	ValAx         []ChartAxis  `xml:"valAx"`
+}
+
+// ChartGroup is a set of series drawn with the same chart type.
+// Fields follow the element order shared by the chart type schemas.
+type ChartGroup struct {
+	BarDir        *ValString  `xml:"barDir,omitempty"`
+	ScatterStyle  *ValString  `xml:"scatterStyle,omitempty"`
+	Grouping      *ValString  `xml:"grouping,omitempty"`
+	VaryColors    ValInt      `xml:"varyColors"`
+	Series        []Series    `xml:"ser"`
+	DLbls         *DataLabels `xml:"dLbls,omitempty"`
+	GapWidth      *ValInt     `xml:"gapWidth,omitempty"`
+	Overlap       *ValInt     `xml:"overlap,omitempty"`
+	Marker        *ValInt     `xml:"marker,omitempty"`
+	FirstSliceAng *ValInt     `xml:"firstSliceAng,omitempty"`
+	AxIDs         []ValInt    `xml:"axId"`
+}
+
+type Series struct {
+	Idx              ValInt           `xml:"idx"`
+	Order            ValInt           `xml:"order"`
+	Tx               *SeriesText      `xml:"tx,omitempty"`
+	SpPr             *ShapeProperties `xml:"spPr,omitempty"`
+	InvertIfNegative *ValInt          `xml:"invertIfNegative,omitempty"`
+	Marker           *SeriesMarker    `xml:"marker,omitempty"`
+	DLbls            *DataLabels      `xml:"dLbls,omitempty"`
+	Cat              *DataSource      `xml:"cat,omitempty"`
+	Val              *DataSource      `xml:"val,omitempty"`
+	XVal             *DataSource      `xml:"xVal,omitempty"`
+	YVal             *DataSource      `xml:"yVal,omitempty"`
+	Smooth           *ValInt          `xml:"smooth,omitempty"`
+}
+
+type SeriesText struct {
+	StrRef *FormulaRef `xml:"strRef,omitempty"`
+	V      string      `xml:"v,omitempty"`
+}
+
+type SeriesMarker struct {
+	Symbol ValString        `xml:"symbol"`
+	SpPr   *ShapeProperties `xml:"spPr,omitempty"`
+}
+
+type DataSource struct {
+	NumRef *FormulaRef `xml:"numRef,omitempty"`
+	StrRef *FormulaRef `xml:"strRef,omitempty"`
+}
+
+// FormulaRef references worksheet cells, e.g. Sheet1!$B$2:$B$10.
+type FormulaRef struct {
+	F string `xml:"f"`
+}
+
+type DataLabels struct {
+	ShowLegendKey  ValInt `xml:"showLegendKey"`
+	ShowVal        ValInt `xml:"showVal"`
+	ShowCatName    ValInt `xml:"showCatName"`
+	ShowSerName    ValInt `xml:"showSerName"`
+	ShowPercent    ValInt `xml:"showPercent"`
+	ShowBubbleSize ValInt `xml:"showBubbleSize"`
+}
+
+// ShapeProperties holds the DrawingML fill and outline of a chart element.
+type ShapeProperties struct {
+	SolidFill *SolidFill `xml:"http://schemas.openxmlformats.org/drawingml/2006/main solidFill,omitempty"`
+	Ln        *Outline   `xml:"http://schemas.openxmlformats.org/drawingml/2006/main ln,omitempty"`
+}
+
+type SolidFill struct {
+	SrgbClr ValString `xml:"srgbClr"`
+}
+
+type Outline struct {
+	W         int        `xml:"w,attr,omitempty"`
+	NoFill    *struct{}  `xml:"noFill,omitempty"`
+	SolidFill *SolidFill `xml:"solidFill,omitempty"`
+}
+
+// ChartAxis defines a category (catAx) or value (valAx) axis.
+// Fields follow the element order shared by both schemas.
+type ChartAxis struct {
+	AxID           ValInt       `xml:"axId"`
+	Scaling        Scaling      `xml:"scaling"`
+	Delete         ValInt       `xml:"delete"`
+	AxPos          ValString    `xml:"axPos"`
+	MajorGridlines *struct{}    `xml:"majorGridlines,omitempty"`
+	Title          *ChartTitle  `xml:"title,omitempty"`
+	NumFmt         *ChartNumFmt `xml:"numFmt,omitempty"`
+	MajorTickMark  ValString    `xml:"majorTickMark"`
+	MinorTickMark  ValString    `xml:"minorTickMark"`
+	TickLblPos     ValString    `xml:"tickLblPos"`
+	CrossAx        ValInt       `xml:"crossAx"`
+	Crosses        ValString    `xml:"crosses"`
+	Auto           *ValInt      `xml:"auto,omitempty"`
+	LblAlgn        *ValString   `xml:"lblAlgn,omitempty"`
+	LblOffset      *ValInt      `xml:"lblOffset,omitempty"`
+	CrossBetween   *ValString   `xml:"crossBetween,omitempty"`
+	MajorUnit      *ValString   `xml:"majorUnit,omitempty"`
+}
+
+type Scaling struct {
+	Orientation ValString  `xml:"orientation"`
+	Max         *ValString `xml:"max,omitempty"`
+	Min         *ValString `xml:"min,omitempty"`
+}
+
+type ChartNumFmt struct {
+	FormatCode   string `xml:"formatCode,attr"`
+	SourceLinked int    `xml:"sourceLinked,attr"`
+}
+
+type Legend struct {
+	LegendPos ValString `xml:"legendPos"`
+	Overlay   ValInt    `xml:"overlay"`
+}
+
+// Formulas returns the cell references of every series in the chart.
+func (c *ChartSpace) Formulas() []*string {
+	var refs []*string
+	add := func(ref *FormulaRef) {
+		if ref != nil {
+			refs = append(refs, &ref.F)
+		}
+	}
+	pa := &c.Chart.PlotArea
+	for _, groups := range [][]ChartGroup{pa.AreaCharts, pa.BarCharts, pa.LineCharts, pa.PieCharts, pa.ScatterCharts} {
+		for i := range groups {
+			for j := range groups[i].Series {
+				s := &groups[i].Series[j]
+				if s.Tx != nil {
+					add(s.Tx.StrRef)
+				}
+				for _, ds := range []*DataSource{s.Cat, s.Val, s.XVal, s.YVal} {
+					if ds != nil {
+						add(ds.NumRef)
+						add(ds.StrRef)
+					}
+				}
+			}
+		}
+	}
+	return refs
+}
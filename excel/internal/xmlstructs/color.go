@@ -2,5 +2,8 @@ package xmlstructs
 
 // Color is a color struct
 type Color struct {
-	RGB string `xml:"rgb,attr"`
+	RGB     string  `xml:"rgb,attr,omitempty"`
+	Indexed *int    `xml:"indexed,attr,omitempty"`
+	Theme   *int    `xml:"theme,attr,omitempty"`
+	Tint    float64 `xml:"tint,attr,omitempty"`
 }
@@ -0,0 +1,36 @@
+package xmlstructs
+
+import "encoding/xml"
+
+// Comments defines the structure of xl/comments[n].xml
+type Comments struct {
+	XMLName     xml.Name    `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main comments"`
+	Authors     Authors     `xml:"authors"`
+	CommentList CommentList `xml:"commentList"`
+}
+
+type Authors struct {
+	Items []string `xml:"author"`
+}
+
+type CommentList struct {
+	Items []Comment `xml:"comment"`
+}
+
+// Comment defines a note attached to a single cell.
+type Comment struct {
+	Ref      string `xml:"ref,attr"`
+	AuthorID int    `xml:"authorId,attr"`
+	Text     Rst    `xml:"text"`
+}
+
+// AuthorID returns the index of author in the author list, adding it if needed.
+func (c *Comments) AuthorID(author string) int {
+	for i, a := range c.Authors.Items {
+		if a == author {
+			return i
+		}
+	}
+	c.Authors.Items = append(c.Authors.Items, author)
+	return len(c.Authors.Items) - 1
+}
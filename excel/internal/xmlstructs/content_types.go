@@ -56,3 +56,13 @@ func (ct *ContentTypes) AddDefault(extension, contentType string) {
 	}
 	ct.Defaults = append(ct.Defaults, Default{Extension: extension, ContentType: contentType})
 }
+
+// RemoveOverride removes the override for a part, if any.
+func (ct *ContentTypes) RemoveOverride(partName string) {
+	for i, o := range ct.Override {
+		if o.PartName == partName {
+			ct.Override = append(ct.Override[:i], ct.Override[i+1:]...)
+			return
+		}
+	}
+}
@@ -2,33 +2,162 @@ package xmlstructs
 
 import "encoding/xml"
 
-// WsDr defines the worksheet drawing
+// WsDr defines the worksheet drawing xl/drawings/drawing[n].xml.
+// Child elements inherit the namespace of their parent unless tagged otherwise;
+// the part is written with MarshalPrefixed using the namespaces returned by Prefixes.
 type WsDr struct {
-	XMLName xml.Name `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing wsDr"`
-	XMLNS_R string   `xml:"xmlns:r,attr"`
-	Anchors []Anchor `xml:",any"`
+	XMLName    xml.Name    `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing wsDr"`
+	Anchors    []Anchor    `xml:",any"`
+	Namespaces []Namespace `xml:"-"` // Prefixes declared by the source part, used by raw inner XML
 }
 
+// Prefixes returns the namespace prefixes the drawing is written with.
+func (w *WsDr) Prefixes() []Namespace {
+	prefixes := []Namespace{
+		{Prefix: "xdr", URI: NSSpreadsheetDrawing},
+		{Prefix: "a", URI: NSDrawingML},
+		{Prefix: "r", URI: NSRelationships},
+		{Prefix: "c", URI: NSChart},
+	}
+	for _, ns := range w.Namespaces {
+		declared := false
+		for _, p := range prefixes {
+			declared = declared || p.Prefix == ns.Prefix
+		}
+		if !declared {
+			prefixes = append(prefixes, ns)
+		}
+	}
+	return prefixes
+}
+
+func (w *WsDr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain WsDr
+	if err := d.DecodeElement((*plain)(w), &start); err != nil {
+		return err
+	}
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" {
+			w.Namespaces = append(w.Namespaces, Namespace{Prefix: a.Name.Local, URI: a.Value})
+		}
+	}
+	return nil
+}
+
+// Anchor positions a drawing object. Anchors other than one- and two-cell and
+// absolute anchors are kept as raw XML.
 type Anchor struct {
-	TwoCellAnchor *TwoCellAnchor `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing twoCellAnchor,omitempty"`
-	OneCellAnchor *OneCellAnchor `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing oneCellAnchor,omitempty"`
+	TwoCellAnchor  *TwoCellAnchor
+	OneCellAnchor  *OneCellAnchor
+	AbsoluteAnchor *AbsoluteAnchor
+	Raw            *Any
+}
+
+func (a Anchor) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	switch {
+	case a.TwoCellAnchor != nil:
+		return e.EncodeElement(a.TwoCellAnchor, xml.StartElement{Name: xml.Name{Space: NSSpreadsheetDrawing, Local: "twoCellAnchor"}})
+	case a.OneCellAnchor != nil:
+		return e.EncodeElement(a.OneCellAnchor, xml.StartElement{Name: xml.Name{Space: NSSpreadsheetDrawing, Local: "oneCellAnchor"}})
+	case a.AbsoluteAnchor != nil:
+		return e.EncodeElement(a.AbsoluteAnchor, xml.StartElement{Name: xml.Name{Space: NSSpreadsheetDrawing, Local: "absoluteAnchor"}})
+	case a.Raw != nil:
+		return e.Encode(a.Raw)
+	}
+	return nil
+}
+
+func (a *Anchor) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "twoCellAnchor":
+		a.TwoCellAnchor = &TwoCellAnchor{}
+		return d.DecodeElement(a.TwoCellAnchor, &start)
+	case "oneCellAnchor":
+		a.OneCellAnchor = &OneCellAnchor{}
+		return d.DecodeElement(a.OneCellAnchor, &start)
+	case "absoluteAnchor":
+		a.AbsoluteAnchor = &AbsoluteAnchor{}
+		return d.DecodeElement(a.AbsoluteAnchor, &start)
+	}
+	a.Raw = &Any{}
+	return d.DecodeElement(a.Raw, &start)
 }
 
 type TwoCellAnchor struct {
-	EditAs     string `xml:"editAs,attr,omitempty"`
-	From       Marker `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing from"`
-	To         Marker `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing to"`
-	Pic        *Pic   `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing pic,omitempty"`
-	Sp         *Any   `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing sp,omitempty"`
-	ClientData *Any   `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing clientData"`
+	EditAs       string        `xml:"editAs,attr,omitempty"`
+	From         Marker        `xml:"from"`
+	To           Marker        `xml:"to"`
+	Sp           *Any          `xml:"sp,omitempty"`
+	GrpSp        *GrpSp        `xml:"grpSp,omitempty"`
+	GraphicFrame *GraphicFrame `xml:"graphicFrame,omitempty"`
+	CxnSp        *Any          `xml:"cxnSp,omitempty"`
+	Pic          *Pic          `xml:"pic,omitempty"`
+	Extra        []Any         `xml:",any"` // Content parts and alternate content
+	ClientData   ClientData    `xml:"clientData"`
 }
 
 type OneCellAnchor struct {
-	From       Marker `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing from"`
-	Ext        Extent `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing ext"`
-	Pic        *Pic   `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing pic,omitempty"`
-	Sp         *Any   `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing sp,omitempty"`
-	ClientData *Any   `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing clientData"`
+	From         Marker        `xml:"from"`
+	Ext          Extent        `xml:"ext"`
+	Sp           *Any          `xml:"sp,omitempty"`
+	GrpSp        *GrpSp        `xml:"grpSp,omitempty"`
+	GraphicFrame *GraphicFrame `xml:"graphicFrame,omitempty"`
+	CxnSp        *Any          `xml:"cxnSp,omitempty"`
+	Pic          *Pic          `xml:"pic,omitempty"`
+	Extra        []Any         `xml:",any"`
+	ClientData   ClientData    `xml:"clientData"`
+}
+
+// AbsoluteAnchor places a drawing object at a position in EMU from the top-left corner of the sheet.
+type AbsoluteAnchor struct {
+	Pos          Point         `xml:"pos"`
+	Ext          Extent        `xml:"ext"`
+	Sp           *Any          `xml:"sp,omitempty"`
+	GrpSp        *GrpSp        `xml:"grpSp,omitempty"`
+	GraphicFrame *GraphicFrame `xml:"graphicFrame,omitempty"`
+	CxnSp        *Any          `xml:"cxnSp,omitempty"`
+	Pic          *Pic          `xml:"pic,omitempty"`
+	Extra        []Any         `xml:",any"`
+	ClientData   ClientData    `xml:"clientData"`
+}
+
+// GrpSp is a group of drawing objects. Members other than pictures and groups are kept as raw XML.
+type GrpSp struct {
+	NvGrpSpPr Any           `xml:"nvGrpSpPr"`
+	GrpSpPr   Any           `xml:"grpSpPr"`
+	Members   []GroupMember `xml:",any"`
+}
+
+// GroupMember is a drawing object within a group.
+type GroupMember struct {
+	Pic   *Pic
+	GrpSp *GrpSp
+	Raw   *Any
+}
+
+func (m GroupMember) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	switch {
+	case m.Pic != nil:
+		return e.EncodeElement(m.Pic, xml.StartElement{Name: xml.Name{Space: NSSpreadsheetDrawing, Local: "pic"}})
+	case m.GrpSp != nil:
+		return e.EncodeElement(m.GrpSp, xml.StartElement{Name: xml.Name{Space: NSSpreadsheetDrawing, Local: "grpSp"}})
+	case m.Raw != nil:
+		return e.Encode(m.Raw)
+	}
+	return nil
+}
+
+func (m *GroupMember) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "pic":
+		m.Pic = &Pic{}
+		return d.DecodeElement(m.Pic, &start)
+	case "grpSp":
+		m.GrpSp = &GrpSp{}
+		return d.DecodeElement(m.GrpSp, &start)
+	}
+	m.Raw = &Any{}
+	return d.DecodeElement(m.Raw, &start)
 }
 
 type Marker struct {
@@ -43,44 +172,91 @@ type Extent struct {
 	Cy int64 `xml:"cy,attr"`
 }
 
+type ClientData struct {
+	LocksWithSheet  string `xml:"fLocksWithSheet,attr,omitempty"`
+	PrintsWithSheet string `xml:"fPrintsWithSheet,attr,omitempty"`
+}
+
+// Pic is a picture. The picture elements keep the attributes and children they
+// do not model, such as a crop, an outline or a hyperlink, as raw XML in their
+// place in the schema sequence, so that pictures read from a part are written
+// back whole. A picture read from a part is written back as it was read until
+// Inner is cleared, which changes to its fields must do.
 type Pic struct {
-	NvPicPr  NvPicPr  `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing nvPicPr"`
-	BlipFill BlipFill `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing blipFill"`
-	SpPr     SpPr     `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing spPr"`
+	NvPicPr  NvPicPr    `xml:"nvPicPr"`
+	BlipFill BlipFill   `xml:"blipFill"`
+	SpPr     SpPr       `xml:"spPr"`
+	Style    *Any       `xml:"style,omitempty"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Inner    string     `xml:",innerxml"` // Source XML of the children
+}
+
+func (p Pic) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if p.Inner == "" {
+		type plain Pic
+		return e.EncodeElement(plain(p), start)
+	}
+	start.Attr = p.Attrs
+	return e.EncodeElement(struct {
+		Inner string `xml:",innerxml"`
+	}{p.Inner}, start)
 }
 
 type NvPicPr struct {
-	CNvPr    CNvPr `xml:"cNvPr"`
-	CNvPicPr Any   `xml:"cNvPicPr"`
+	CNvPr    CNvPr    `xml:"cNvPr"`
+	CNvPicPr CNvPicPr `xml:"cNvPicPr"`
 }
 
 type CNvPr struct {
-	ID    int    `xml:"id,attr"`
-	Name  string `xml:"name,attr"`
-	Descr string `xml:"descr,attr,omitempty"`
+	ID    int        `xml:"id,attr"`
+	Name  string     `xml:"name,attr"`
+	Descr string     `xml:"descr,attr,omitempty"`
+	Attrs []xml.Attr `xml:",any,attr"`
+	Extra []Any      `xml:",any"` // Hyperlinks and extensions
+}
+
+type CNvPicPr struct {
+	PicLocks *PicLocks  `xml:"http://schemas.openxmlformats.org/drawingml/2006/main picLocks,omitempty"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Extra    []Any      `xml:",any"`
+}
+
+type PicLocks struct {
+	NoChangeAspect int        `xml:"noChangeAspect,attr,omitempty"`
+	Attrs          []xml.Attr `xml:",any,attr"`
 }
 
 type BlipFill struct {
-	Blip    Blip    `xml:"blip"`
-	Stretch Stretch `xml:"stretch"`
+	Blip    Blip       `xml:"http://schemas.openxmlformats.org/drawingml/2006/main blip"`
+	SrcRect *Any       `xml:"http://schemas.openxmlformats.org/drawingml/2006/main srcRect,omitempty"`
+	Tile    *Any       `xml:"http://schemas.openxmlformats.org/drawingml/2006/main tile,omitempty"`
+	Stretch *Stretch   `xml:"http://schemas.openxmlformats.org/drawingml/2006/main stretch,omitempty"`
+	Attrs   []xml.Attr `xml:",any,attr"`
 }
 
+// Blip refers to the image of a picture. Its effects and extensions are kept as raw XML.
 type Blip struct {
-	Embed string `xml:"r:embed,attr"`
+	Embed   string     `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships embed,attr"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type Stretch struct {
-	FillRect Any `xml:"fillRect"`
+	FillRect struct{} `xml:"fillRect"`
 }
 
 type SpPr struct {
-	Xfrm     Xfrm     `xml:"xfrm"`
-	PrstGeom PrstGeom `xml:"prstGeom"`
+	Xfrm     *Xfrm      `xml:"http://schemas.openxmlformats.org/drawingml/2006/main xfrm,omitempty"`
+	CustGeom *Any       `xml:"http://schemas.openxmlformats.org/drawingml/2006/main custGeom,omitempty"`
+	PrstGeom *PrstGeom  `xml:"http://schemas.openxmlformats.org/drawingml/2006/main prstGeom,omitempty"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Extra    []Any      `xml:",any"` // Fill, outline, effects and extensions, which follow the geometry
 }
 
 type Xfrm struct {
-	Off Point  `xml:"off"`
-	Ext Extent `xml:"ext"`
+	Off   Point      `xml:"http://schemas.openxmlformats.org/drawingml/2006/main off"`
+	Ext   Extent     `xml:"http://schemas.openxmlformats.org/drawingml/2006/main ext"`
+	Attrs []xml.Attr `xml:",any,attr"`
 }
 
 type Point struct {
@@ -90,7 +266,37 @@ type Point struct {
 
 type PrstGeom struct {
 	Prst  string `xml:"prst,attr"`
-	AvLst Any    `xml:"avLst"`
+	AvLst struct {
+		Content string `xml:",innerxml"` // Shape guides
+	} `xml:"avLst"`
+}
+
+// GraphicFrame holds a chart or other graphic object in a drawing.
+type GraphicFrame struct {
+	Macro            string           `xml:"macro,attr"`
+	NvGraphicFramePr NvGraphicFramePr `xml:"nvGraphicFramePr"`
+	Xfrm             Xfrm             `xml:"xfrm"`
+	Graphic          Graphic          `xml:"http://schemas.openxmlformats.org/drawingml/2006/main graphic"`
+}
+
+type NvGraphicFramePr struct {
+	CNvPr             CNvPr    `xml:"cNvPr"`
+	CNvGraphicFramePr struct{} `xml:"cNvGraphicFramePr"`
+}
+
+type Graphic struct {
+	GraphicData GraphicData `xml:"graphicData"`
+}
+
+type GraphicData struct {
+	URI   string    `xml:"uri,attr"`
+	Chart *ChartRef `xml:"http://schemas.openxmlformats.org/drawingml/2006/chart chart,omitempty"`
+	Other []Any     `xml:",any"`
+}
+
+// ChartRef links a graphic frame to its chart part.
+type ChartRef struct {
+	RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
 }
 
 type Any struct {
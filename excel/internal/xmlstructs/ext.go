@@ -0,0 +1,119 @@
+package xmlstructs
+
+import "encoding/xml"
+
+// Namespaces of the Excel 2010 extensions.
+const (
+	NSX14 = "http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"
+	NSXM  = "http://schemas.microsoft.com/office/excel/2006/main"
+)
+
+// SparklineExtURI identifies the extension holding the sparklines of a worksheet.
+const SparklineExtURI = "{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"
+
+// ExtLst holds the extensions of a worksheet that the 2006 schema has no room for.
+type ExtLst struct {
+	Items []Ext `xml:"ext"`
+}
+
+// Ext is one extension. Sparklines are decoded; other extensions are kept as
+// raw XML together with the prefixes declared on the ext element.
+type Ext struct {
+	URI             string
+	Namespaces      []xml.Attr
+	Content         string
+	SparklineGroups *SparklineGroups
+}
+
+func (x *Ext) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*x = Ext{}
+	for _, a := range start.Attr {
+		switch {
+		case a.Name.Space == "" && a.Name.Local == "uri":
+			x.URI = a.Value
+		case a.Name.Space == "xmlns":
+			x.Namespaces = append(x.Namespaces, xml.Attr{Name: xml.Name{Local: "xmlns:" + a.Name.Local}, Value: a.Value})
+		}
+	}
+	if x.URI == SparklineExtURI {
+		var v struct {
+			Groups *SparklineGroups `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main sparklineGroups"`
+		}
+		if err := d.DecodeElement(&v, &start); err != nil {
+			return err
+		}
+		x.SparklineGroups = v.Groups
+		return nil
+	}
+	var raw struct {
+		Content string `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	x.Content = raw.Content
+	return nil
+}
+
+func (x Ext) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	content := x.Content
+	if x.SparklineGroups != nil {
+		data, err := MarshalPrefixed(x.SparklineGroups, Namespace{Prefix: "x14", URI: NSX14}, Namespace{Prefix: "xm", URI: NSXM})
+		if err != nil {
+			return err
+		}
+		content = string(data)
+	}
+	start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "uri"}, Value: x.URI}}, x.Namespaces...)
+	return e.EncodeElement(struct {
+		Content string `xml:",innerxml"`
+	}{content}, start)
+}
+
+// SparklineGroups defines the sparklines of a worksheet.
+type SparklineGroups struct {
+	XMLName xml.Name         `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main sparklineGroups"`
+	Items   []SparklineGroup `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main sparklineGroup"`
+}
+
+// SparklineGroup defines sparklines that share a type and formatting.
+type SparklineGroup struct {
+	ManualMax           *float64   `xml:"manualMax,attr,omitempty"`
+	ManualMin           *float64   `xml:"manualMin,attr,omitempty"`
+	LineWeight          float64    `xml:"lineWeight,attr,omitempty"` // Points; defaults to 0.75
+	Type                string     `xml:"type,attr,omitempty"`       // line (default), column or stacked
+	DateAxis            int        `xml:"dateAxis,attr,omitempty"`
+	DisplayEmptyCellsAs string     `xml:"displayEmptyCellsAs,attr,omitempty"` // gap, zero (default) or span
+	Markers             int        `xml:"markers,attr,omitempty"`
+	High                int        `xml:"high,attr,omitempty"`
+	Low                 int        `xml:"low,attr,omitempty"`
+	First               int        `xml:"first,attr,omitempty"`
+	Last                int        `xml:"last,attr,omitempty"`
+	Negative            int        `xml:"negative,attr,omitempty"`
+	DisplayXAxis        int        `xml:"displayXAxis,attr,omitempty"`
+	DisplayHidden       int        `xml:"displayHidden,attr,omitempty"`
+	MinAxisType         string     `xml:"minAxisType,attr,omitempty"` // individual (default), group or custom
+	MaxAxisType         string     `xml:"maxAxisType,attr,omitempty"`
+	RightToLeft         int        `xml:"rightToLeft,attr,omitempty"`
+	ColorSeries         *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorSeries,omitempty"`
+	ColorNegative       *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorNegative,omitempty"`
+	ColorAxis           *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorAxis,omitempty"`
+	ColorMarkers        *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorMarkers,omitempty"`
+	ColorFirst          *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorFirst,omitempty"`
+	ColorLast           *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorLast,omitempty"`
+	ColorHigh           *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorHigh,omitempty"`
+	ColorLow            *Color     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main colorLow,omitempty"`
+	F                   string     `xml:"http://schemas.microsoft.com/office/excel/2006/main f,omitempty"` // Date axis range
+	Sparklines          Sparklines `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main sparklines"`
+}
+
+// Sparklines lists the sparklines of a group.
+type Sparklines struct {
+	Items []Sparkline `xml:"http://schemas.microsoft.com/office/spreadsheetml/2009/9/main sparkline"`
+}
+
+// Sparkline draws the values of range F in cell Sqref.
+type Sparkline struct {
+	F     string `xml:"http://schemas.microsoft.com/office/excel/2006/main f"`
+	Sqref string `xml:"http://schemas.microsoft.com/office/excel/2006/main sqref"`
+}
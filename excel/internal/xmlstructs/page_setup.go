@@ -2,6 +2,33 @@ package xmlstructs
 
 // PageSetup defines worksheet page settings
 type PageSetup struct {
-	Orientation string `xml:"orientation,attr,omitempty"`
-	PaperSize   int    `xml:"paperSize,attr,omitempty"`
+	PaperSize     int    `xml:"paperSize,attr,omitempty"`
+	Scale         int    `xml:"scale,attr,omitempty"`       // Percent; defaults to 100
+	FitToWidth    *int   `xml:"fitToWidth,attr,omitempty"`  // Defaults to 1; 0 leaves the width unconstrained
+	FitToHeight   *int   `xml:"fitToHeight,attr,omitempty"` // Defaults to 1; 0 leaves the height unconstrained
+	Orientation   string `xml:"orientation,attr,omitempty"`
+	HorizontalDpi int    `xml:"horizontalDpi,attr,omitempty"`
+	VerticalDpi   int    `xml:"verticalDpi,attr,omitempty"`
+}
+
+// PrintOptions defines what is printed with a worksheet and where.
+type PrintOptions struct {
+	HorizontalCentered int `xml:"horizontalCentered,attr,omitempty"`
+	VerticalCentered   int `xml:"verticalCentered,attr,omitempty"`
+	Headings           int `xml:"headings,attr,omitempty"`
+	GridLines          int `xml:"gridLines,attr,omitempty"`
+}
+
+// PageBreaks lists the manual row or column page breaks of a worksheet.
+type PageBreaks struct {
+	Count            int     `xml:"count,attr"`
+	ManualBreakCount int     `xml:"manualBreakCount,attr"`
+	Items            []Break `xml:"brk"`
+}
+
+// Break is a page break after the 1-based row or column ID.
+type Break struct {
+	ID  int `xml:"id,attr"`
+	Max int `xml:"max,attr,omitempty"` // Last column or row the break spans
+	Man int `xml:"man,attr,omitempty"` // 1 for a manual break
 }
@@ -1,6 +1,7 @@
 package xmlstructs
 
 type PatternFill struct {
-	PatternType string `xml:"patternType,attr"`
+	PatternType string `xml:"patternType,attr,omitempty"`
 	FgColor     *Color `xml:"fgColor,omitempty"`
+	BgColor     *Color `xml:"bgColor,omitempty"`
 }
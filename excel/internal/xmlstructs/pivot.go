@@ -0,0 +1,205 @@
+package xmlstructs
+
+import "encoding/xml"
+
+// PivotCacheDefinition defines the structure of xl/pivotCache/pivotCacheDefinition[n].xml.
+type PivotCacheDefinition struct {
+	XMLName               xml.Name    `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotCacheDefinition"`
+	XMLNS_R               string      `xml:"xmlns:r,attr"`
+	RID                   string      `xml:"r:id,attr,omitempty"`
+	RefreshOnLoad         int         `xml:"refreshOnLoad,attr,omitempty"`
+	CreatedVersion        int         `xml:"createdVersion,attr,omitempty"`
+	RefreshedVersion      int         `xml:"refreshedVersion,attr,omitempty"`
+	MinRefreshableVersion int         `xml:"minRefreshableVersion,attr,omitempty"`
+	RecordCount           int         `xml:"recordCount,attr"`
+	CacheSource           CacheSource `xml:"cacheSource"`
+	CacheFields           CacheFields `xml:"cacheFields"`
+}
+
+type CacheSource struct {
+	Type            string           `xml:"type,attr"`
+	WorksheetSource *WorksheetSource `xml:"worksheetSource,omitempty"`
+}
+
+type WorksheetSource struct {
+	Ref   string `xml:"ref,attr,omitempty"`
+	Sheet string `xml:"sheet,attr,omitempty"`
+}
+
+type CacheFields struct {
+	Count int          `xml:"count,attr"`
+	Items []CacheField `xml:"cacheField"`
+}
+
+type CacheField struct {
+	Name        string      `xml:"name,attr"`
+	NumFmtID    int         `xml:"numFmtId,attr"`
+	SharedItems SharedItems `xml:"sharedItems"`
+}
+
+// SharedItems describes the values of a cache field. Items are only listed for
+// fields placed on an axis; records refer to them by index.
+type SharedItems struct {
+	ContainsSemiMixedTypes *int        `xml:"containsSemiMixedTypes,attr,omitempty"` // Defaults to 1
+	ContainsString         *int        `xml:"containsString,attr,omitempty"`         // Defaults to 1
+	ContainsBlank          int         `xml:"containsBlank,attr,omitempty"`
+	ContainsMixedTypes     int         `xml:"containsMixedTypes,attr,omitempty"`
+	ContainsNumber         int         `xml:"containsNumber,attr,omitempty"`
+	ContainsInteger        int         `xml:"containsInteger,attr,omitempty"`
+	MinValue               string      `xml:"minValue,attr,omitempty"`
+	MaxValue               string      `xml:"maxValue,attr,omitempty"`
+	Count                  int         `xml:"count,attr,omitempty"`
+	Items                  []CacheItem `xml:",any"`
+}
+
+// CacheItem is a typed cache value: <s>, <n>, <b>, <e> or <m> (missing), or an <x> shared item index.
+type CacheItem struct {
+	XMLName xml.Name
+	V       string `xml:"v,attr,omitempty"`
+}
+
+// PivotCacheRecords defines the structure of xl/pivotCache/pivotCacheRecords[n].xml.
+type PivotCacheRecords struct {
+	XMLName xml.Name      `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotCacheRecords"`
+	Count   int           `xml:"count,attr"`
+	Records []CacheRecord `xml:"r"`
+}
+
+type CacheRecord struct {
+	Items []CacheItem `xml:",any"`
+}
+
+// PivotTableDefinition defines the structure of xl/pivotTables/pivotTable[n].xml.
+type PivotTableDefinition struct {
+	XMLName               xml.Name             `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotTableDefinition"`
+	Name                  string               `xml:"name,attr"`
+	CacheID               int                  `xml:"cacheId,attr"`
+	DataCaption           string               `xml:"dataCaption,attr"`
+	UpdatedVersion        int                  `xml:"updatedVersion,attr,omitempty"`
+	MinRefreshableVersion int                  `xml:"minRefreshableVersion,attr,omitempty"`
+	UseAutoFormatting     int                  `xml:"useAutoFormatting,attr,omitempty"`
+	ItemPrintTitles       int                  `xml:"itemPrintTitles,attr,omitempty"`
+	CreatedVersion        int                  `xml:"createdVersion,attr,omitempty"`
+	Indent                int                  `xml:"indent,attr"`
+	Compact               int                  `xml:"compact,attr"`
+	CompactData           int                  `xml:"compactData,attr"`
+	Location              PivotLocation        `xml:"location"`
+	PivotFields           PivotFields          `xml:"pivotFields"`
+	RowFields             *PivotAxisFields     `xml:"rowFields,omitempty"`
+	RowItems              *PivotAxisItems      `xml:"rowItems,omitempty"`
+	ColFields             *PivotAxisFields     `xml:"colFields,omitempty"`
+	ColItems              *PivotAxisItems      `xml:"colItems,omitempty"`
+	PageFields            *PageFields          `xml:"pageFields,omitempty"`
+	DataFields            *DataFields          `xml:"dataFields,omitempty"`
+	PivotTableStyleInfo   *PivotTableStyleInfo `xml:"pivotTableStyleInfo,omitempty"`
+}
+
+// PivotLocation is the range of the pivot table body. Offsets are relative to its top-left cell.
+type PivotLocation struct {
+	Ref            string `xml:"ref,attr"`
+	FirstHeaderRow int    `xml:"firstHeaderRow,attr"`
+	FirstDataRow   int    `xml:"firstDataRow,attr"`
+	FirstDataCol   int    `xml:"firstDataCol,attr"`
+	RowPageCount   int    `xml:"rowPageCount,attr,omitempty"`
+	ColPageCount   int    `xml:"colPageCount,attr,omitempty"`
+}
+
+type PivotFields struct {
+	Count int          `xml:"count,attr"`
+	Items []PivotField `xml:"pivotField"`
+}
+
+// PivotField holds the settings of a cache field within the pivot table.
+type PivotField struct {
+	Axis                         string      `xml:"axis,attr,omitempty"` // axisRow, axisCol or axisPage
+	DataField                    int         `xml:"dataField,attr,omitempty"`
+	Compact                      int         `xml:"compact,attr"`
+	Outline                      int         `xml:"outline,attr"`
+	MultipleItemSelectionAllowed int         `xml:"multipleItemSelectionAllowed,attr,omitempty"`
+	ShowAll                      int         `xml:"showAll,attr"`
+	Items                        *PivotItems `xml:"items,omitempty"`
+}
+
+type PivotItems struct {
+	Count int         `xml:"count,attr"`
+	Items []PivotItem `xml:"item"`
+}
+
+// PivotItem refers to a shared item of the field's cache field, or is a subtotal item when T is set.
+type PivotItem struct {
+	X *int   `xml:"x,attr,omitempty"`
+	H int    `xml:"h,attr,omitempty"`
+	T string `xml:"t,attr,omitempty"`
+}
+
+type PivotAxisFields struct {
+	Count int              `xml:"count,attr"`
+	Items []PivotAxisField `xml:"field"`
+}
+
+// PivotAxisField is a field index on an axis; -2 stands for the Values field.
+type PivotAxisField struct {
+	X int `xml:"x,attr"`
+}
+
+type PivotAxisItems struct {
+	Count int             `xml:"count,attr"`
+	Items []PivotAxisItem `xml:"i"`
+}
+
+// PivotAxisItem is a line of the row or column area. R is the number of leading
+// members repeated from the previous line; X lists the remaining members.
+type PivotAxisItem struct {
+	T string        `xml:"t,attr,omitempty"`
+	R int           `xml:"r,attr,omitempty"`
+	I int           `xml:"i,attr,omitempty"`
+	X []PivotMember `xml:"x"`
+}
+
+type PivotMember struct {
+	V int `xml:"v,attr,omitempty"`
+}
+
+type PageFields struct {
+	Count int         `xml:"count,attr"`
+	Items []PageField `xml:"pageField"`
+}
+
+type PageField struct {
+	Fld  int  `xml:"fld,attr"`
+	Item *int `xml:"item,attr,omitempty"`
+	Hier int  `xml:"hier,attr"`
+}
+
+type DataFields struct {
+	Count int         `xml:"count,attr"`
+	Items []DataField `xml:"dataField"`
+}
+
+type DataField struct {
+	Name      string `xml:"name,attr"`
+	Fld       int    `xml:"fld,attr"`
+	Subtotal  string `xml:"subtotal,attr,omitempty"`
+	BaseField int    `xml:"baseField,attr"`
+	BaseItem  int    `xml:"baseItem,attr"`
+	NumFmtID  int    `xml:"numFmtId,attr,omitempty"`
+}
+
+type PivotTableStyleInfo struct {
+	Name           string `xml:"name,attr"`
+	ShowRowHeaders int    `xml:"showRowHeaders,attr"`
+	ShowColHeaders int    `xml:"showColHeaders,attr"`
+	ShowRowStripes int    `xml:"showRowStripes,attr"`
+	ShowColStripes int    `xml:"showColStripes,attr"`
+	ShowLastColumn int    `xml:"showLastColumn,attr"`
+}
+
+// PivotCaches lists the pivot caches of a workbook.
+type PivotCaches struct {
+	Items []PivotCache `xml:"pivotCache"`
+}
+
+type PivotCache struct {
+	CacheID int    `xml:"cacheId,attr"`
+	RID     string `xml:"r:id,attr"`
+}
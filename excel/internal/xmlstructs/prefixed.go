@@ -0,0 +1,155 @@
+package xmlstructs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Namespaces of the DrawingML parts.
+const (
+	NSSpreadsheetDrawing = "http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"
+	NSDrawingML          = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	NSChart              = "http://schemas.openxmlformats.org/drawingml/2006/chart"
+	NSRelationships      = relationshipsNS
+)
+
+const xmlNS = "http://www.w3.org/XML/1998/namespace"
+
+// rawXML is an element of raw inner XML, copied to the output as it is.
+type rawXML []byte
+
+// Namespace is an XML namespace bound to the prefix it is written with.
+type Namespace struct {
+	Prefix string
+	URI    string
+}
+
+// MarshalPrefixed marshals v and rewrites the result so that elements and attributes in the given
+// namespaces use their prefixes, declared once on the root element. encoding/xml redeclares the
+// default namespace on every element instead, which Office applications do not expect in DrawingML.
+// Raw inner XML, whose prefixes encoding/xml leaves unbound, is copied as it is.
+func MarshalPrefixed(v any, namespaces ...Namespace) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes := map[string]string{xmlNS: "xml"}
+	known := map[string]bool{"xml": true}
+	for _, ns := range namespaces {
+		if _, ok := prefixes[ns.URI]; !ok {
+			prefixes[ns.URI] = ns.Prefix
+		}
+		known[ns.Prefix] = true
+	}
+
+	var tokens []xml.Token
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	rawStart, rawDepth := int64(0), 0
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if rawDepth > 0 || t.Name.Space != "" && !strings.Contains(t.Name.Space, ":") {
+				if rawDepth == 0 {
+					rawStart = offset
+				}
+				rawDepth++
+				continue
+			}
+		case xml.EndElement:
+			if rawDepth > 0 {
+				if rawDepth--; rawDepth == 0 {
+					tokens = append(tokens, rawXML(data[rawStart:dec.InputOffset()]))
+				}
+				continue
+			}
+		}
+		if rawDepth == 0 {
+			tokens = append(tokens, xml.CopyToken(tok))
+		}
+	}
+
+	// Bind namespaces that have no prefix yet to generated ones.
+	var extra []Namespace
+	bind := func(name xml.Name) {
+		if known[name.Space] || !strings.Contains(name.Space, ":") {
+			return
+		}
+		if _, ok := prefixes[name.Space]; !ok {
+			prefix := fmt.Sprintf("ns%d", len(extra))
+			prefixes[name.Space] = prefix
+			extra = append(extra, Namespace{Prefix: prefix, URI: name.Space})
+		}
+	}
+	for _, tok := range tokens {
+		if se, ok := tok.(xml.StartElement); ok {
+			bind(se.Name)
+			for _, a := range se.Attr {
+				bind(a.Name)
+			}
+		}
+	}
+
+	qualify := func(name xml.Name) string {
+		if name.Space == "" {
+			return name.Local
+		}
+		if prefix, ok := prefixes[name.Space]; ok {
+			return prefix + ":" + name.Local
+		}
+		return name.Space + ":" + name.Local
+	}
+
+	var buf bytes.Buffer
+	root := true
+	for _, tok := range tokens {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			buf.WriteByte('<')
+			buf.WriteString(qualify(t.Name))
+			if root {
+				for _, ns := range append(namespaces, extra...) {
+					fmt.Fprintf(&buf, ` xmlns:%s="%s"`, ns.Prefix, ns.URI)
+				}
+				root = false
+			}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+					continue
+				}
+				buf.WriteByte(' ')
+				buf.WriteString(qualify(a.Name))
+				buf.WriteString(`="`)
+				xml.EscapeText(&buf, []byte(a.Value))
+				buf.WriteByte('"')
+			}
+			buf.WriteByte('>')
+		case xml.EndElement:
+			buf.WriteString("</")
+			buf.WriteString(qualify(t.Name))
+			buf.WriteByte('>')
+		case xml.CharData:
+			xml.EscapeText(&buf, t)
+		case rawXML:
+			buf.Write(t)
+		case xml.Comment:
+			if !strings.Contains(string(t), "--") {
+				buf.WriteString("<!--")
+				buf.Write(t)
+				buf.WriteString("-->")
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
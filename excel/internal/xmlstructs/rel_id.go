@@ -0,0 +1,55 @@
+package xmlstructs
+
+import "encoding/xml"
+
+const relationshipsNS = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+// RID fields are tagged "r:id" so that they marshal with the r prefix declared on the
+// part's root element. encoding/xml matches attributes by local name only when the tag
+// has no namespace, so the decoders below restore the ID from the namespaced attribute.
+
+func relID(start xml.StartElement) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == "id" && (a.Name.Space == relationshipsNS || a.Name.Space == "r") {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (s *Sheet) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Sheet
+	if err := d.DecodeElement((*plain)(s), &start); err != nil {
+		return err
+	}
+	s.RID = relID(start)
+	return nil
+}
+
+func (w *WsDrawing) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	w.RID = relID(start)
+	return d.Skip()
+}
+
+func (t *TablePart) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	t.RID = relID(start)
+	return d.Skip()
+}
+
+func (h *Hyperlink) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Hyperlink
+	if err := d.DecodeElement((*plain)(h), &start); err != nil {
+		return err
+	}
+	h.RID = relID(start)
+	return nil
+}
+
+func (p *PivotCache) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain PivotCache
+	if err := d.DecodeElement((*plain)(p), &start); err != nil {
+		return err
+	}
+	p.RID = relID(start)
+	return nil
+}
@@ -63,3 +63,13 @@ func (r *Relationships) AddRelationshipMode(relType, target, mode string) string
 	})
 	return newID
 }
+
+// RemoveRelationship removes the relationship with the given ID, if any.
+func (r *Relationships) RemoveRelationship(id string) {
+	for i, rel := range r.Rels {
+		if rel.ID == id {
+			r.Rels = append(r.Rels[:i], r.Rels[i+1:]...)
+			return
+		}
+	}
+}
@@ -6,6 +6,7 @@ type Row struct {
 	Cells        []Cell  `xml:"c"`
 	Ht           float64 `xml:"ht,attr,omitempty"`
 	CustomHeight int     `xml:"customHeight,attr,omitempty"`
+	Hidden       int     `xml:"hidden,attr,omitempty"`
 	OutlineLevel uint8   `xml:"outlineLevel,attr,omitempty"`
 	Collapsed    bool    `xml:"collapsed,attr,omitempty"`
 }
@@ -3,5 +3,6 @@ package xmlstructs
 type Sheet struct {
 	Name    string `xml:"name,attr"`
 	SheetID string `xml:"sheetId,attr"`
+	State   string `xml:"state,attr,omitempty"` // "hidden" or "veryHidden"
 	RID     string `xml:"r:id,attr"`
 }
@@ -15,8 +15,18 @@ type Styles struct {
 }
 
 type Dxfs struct {
-	Count int  `xml:"count,attr"`
-	Items []Xf `xml:"xf"`
+	Count int   `xml:"count,attr"`
+	Items []Dxf `xml:"dxf"`
+}
+
+// Dxf is a differential format applied on top of a cell's own format, e.g. by a
+// conditional formatting rule.
+type Dxf struct {
+	Font      *Font      `xml:"font,omitempty"`
+	NumFmt    *NumFmt    `xml:"numFmt,omitempty"`
+	Fill      *Fill      `xml:"fill,omitempty"`
+	Alignment *Alignment `xml:"alignment,omitempty"`
+	Border    *Border    `xml:"border,omitempty"`
 }
 
 type CellStyleXfs struct {
@@ -86,11 +96,11 @@ func (s *Styles) AddBorder(border Border) int {
 	return len(s.Borders.Items) - 1
 }
 
-func (s *Styles) AddDxf(xf Xf) int {
+func (s *Styles) AddDxf(dxf Dxf) int {
 	if s.Dxfs == nil {
-		s.Dxfs = &Dxfs{Items: make([]Xf, 0)}
+		s.Dxfs = &Dxfs{Items: make([]Dxf, 0)}
 	}
-	s.Dxfs.Items = append(s.Dxfs.Items, xf)
+	s.Dxfs.Items = append(s.Dxfs.Items, dxf)
 	s.Dxfs.Count = len(s.Dxfs.Items)
 	return len(s.Dxfs.Items) - 1
 }
@@ -9,6 +9,8 @@ type Table struct {
 	Name           string          `xml:"name,attr"`
 	DisplayName    string          `xml:"displayName,attr"`
 	Ref            string          `xml:"ref,attr"`
+	HeaderRowCount *int            `xml:"headerRowCount,attr,omitempty"` // Defaults to 1
+	TotalsRowCount int             `xml:"totalsRowCount,attr,omitempty"`
 	TotalsRowShown int             `xml:"totalsRowShown,attr,omitempty"`
 	AutoFilter     *AutoFilter     `xml:"autoFilter,omitempty"`
 	TableColumns   TableColumns    `xml:"tableColumns"`
@@ -21,8 +23,19 @@ type TableColumns struct {
 }
 
 type TableColumn struct {
-	ID   int    `xml:"id,attr"`
-	Name string `xml:"name,attr"`
+	ID                      int           `xml:"id,attr"`
+	Name                    string        `xml:"name,attr"`
+	TotalsRowFunction       string        `xml:"totalsRowFunction,attr,omitempty"`
+	TotalsRowLabel          string        `xml:"totalsRowLabel,attr,omitempty"`
+	DataDxfID               *int          `xml:"dataDxfId,attr,omitempty"`
+	CalculatedColumnFormula *TableFormula `xml:"calculatedColumnFormula,omitempty"`
+	TotalsRowFormula        *TableFormula `xml:"totalsRowFormula,omitempty"`
+}
+
+// TableFormula is the formula of a calculated column or of a custom totals row cell.
+type TableFormula struct {
+	Array int    `xml:"array,attr,omitempty"`
+	Text  string `xml:",chardata"`
 }
 
 type TableStyleInfo struct {
@@ -0,0 +1,19 @@
+package xmlstructs
+
+// VMLDrawing is the subset of a legacy VML drawing part needed to read note shapes.
+// VML parts are written as text because their parsers expect fixed namespace prefixes.
+type VMLDrawing struct {
+	Shapes []VMLShape `xml:"urn:schemas-microsoft-com:vml shape"`
+}
+
+type VMLShape struct {
+	Style      string         `xml:"style,attr"`
+	ClientData *VMLClientData `xml:"urn:schemas-microsoft-com:office:excel ClientData"`
+}
+
+type VMLClientData struct {
+	ObjectType string    `xml:"ObjectType,attr"`
+	Visible    *struct{} `xml:"urn:schemas-microsoft-com:office:excel Visible"`
+	Row        int       `xml:"urn:schemas-microsoft-com:office:excel Row"`
+	Column     int       `xml:"urn:schemas-microsoft-com:office:excel Column"`
+}
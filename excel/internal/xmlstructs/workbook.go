@@ -12,6 +12,7 @@ type Workbook struct {
 	Sheets             []Sheet             `xml:"sheets>sheet"`
 	DefinedNames       *DefinedNames       `xml:"definedNames,omitempty"`
 	CalcPr             *CalcPr             `xml:"calcPr,omitempty"`
+	PivotCaches        *PivotCaches        `xml:"pivotCaches,omitempty"`
 }
 
 type WorkbookPr struct {
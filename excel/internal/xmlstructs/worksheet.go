@@ -18,11 +18,16 @@ type Worksheet struct {
 	ConditionalFormatting []ConditionalFormatting `xml:"conditionalFormatting,omitempty"`
 	DataValidations       *DataValidations        `xml:"dataValidations,omitempty"`
 	Hyperlinks            *Hyperlinks             `xml:"hyperlinks,omitempty"`
+	PrintOptions          *PrintOptions           `xml:"printOptions,omitempty"`
 	PageMargins           *PageMargins            `xml:"pageMargins,omitempty"`
 	PageSetup             *PageSetup              `xml:"pageSetup,omitempty"`
 	HeaderFooter          *HeaderFooter           `xml:"headerFooter,omitempty"`
+	RowBreaks             *PageBreaks             `xml:"rowBreaks,omitempty"`
+	ColBreaks             *PageBreaks             `xml:"colBreaks,omitempty"`
 	Drawing               *WsDrawing              `xml:"drawing,omitempty"`
+	LegacyDrawing         *WsDrawing              `xml:"legacyDrawing,omitempty"`
 	TableParts            *TableParts             `xml:"tableParts,omitempty"`
+	ExtLst                *ExtLst                 `xml:"extLst,omitempty"`
 }
 
 type TableParts struct {
@@ -45,15 +50,62 @@ type ConditionalFormatting struct {
 }
 
 type CfRule struct {
-	Type     string   `xml:"type,attr"`
-	DxfID    *int     `xml:"dxfId,attr,omitempty"`
-	Priority int      `xml:"priority,attr"`
-	Operator string   `xml:"operator,attr,omitempty"`
-	Formula  []string `xml:"formula,omitempty"`
+	Type         string      `xml:"type,attr"`
+	DxfID        *int        `xml:"dxfId,attr,omitempty"`
+	Priority     int         `xml:"priority,attr"`
+	StopIfTrue   int         `xml:"stopIfTrue,attr,omitempty"`
+	AboveAverage *int        `xml:"aboveAverage,attr,omitempty"` // Defaults to 1
+	Percent      int         `xml:"percent,attr,omitempty"`
+	Bottom       int         `xml:"bottom,attr,omitempty"`
+	Operator     string      `xml:"operator,attr,omitempty"`
+	Text         string      `xml:"text,attr,omitempty"`
+	TimePeriod   string      `xml:"timePeriod,attr,omitempty"`
+	Rank         int         `xml:"rank,attr,omitempty"`
+	StdDev       int         `xml:"stdDev,attr,omitempty"`
+	EqualAverage int         `xml:"equalAverage,attr,omitempty"`
+	Formula      []string    `xml:"formula,omitempty"`
+	ColorScale   *ColorScale `xml:"colorScale,omitempty"`
+	DataBar      *DataBar    `xml:"dataBar,omitempty"`
+	IconSet      *IconSet    `xml:"iconSet,omitempty"`
+}
+
+// Cfvo is a conditional format value object: a threshold of a color scale, data bar or icon set.
+type Cfvo struct {
+	Type string `xml:"type,attr"`
+	Val  string `xml:"val,attr,omitempty"`
+	Gte  *int   `xml:"gte,attr,omitempty"` // Defaults to 1
+}
+
+// ColorScale lists its thresholds followed by one color per threshold.
+type ColorScale struct {
+	Cfvo  []Cfvo  `xml:"cfvo"`
+	Color []Color `xml:"color"`
+}
+
+type DataBar struct {
+	MinLength *int   `xml:"minLength,attr,omitempty"` // Defaults to 10
+	MaxLength *int   `xml:"maxLength,attr,omitempty"` // Defaults to 90
+	ShowValue *int   `xml:"showValue,attr,omitempty"` // Defaults to 1
+	Cfvo      []Cfvo `xml:"cfvo"`
+	Color     Color  `xml:"color"`
+}
+
+type IconSet struct {
+	IconSet   string `xml:"iconSet,attr,omitempty"`   // Defaults to 3TrafficLights1
+	ShowValue *int   `xml:"showValue,attr,omitempty"` // Defaults to 1
+	Percent   *int   `xml:"percent,attr,omitempty"`   // Defaults to 1
+	Reverse   int    `xml:"reverse,attr,omitempty"`
+	Cfvo      []Cfvo `xml:"cfvo"`
 }
 
 type SheetPr struct {
-	OutlinePr *OutlinePr `xml:"outlinePr,omitempty"`
+	TabColor    *Color       `xml:"tabColor,omitempty"`
+	OutlinePr   *OutlinePr   `xml:"outlinePr,omitempty"`
+	PageSetUpPr *PageSetUpPr `xml:"pageSetUpPr,omitempty"`
+}
+
+type PageSetUpPr struct {
+	FitToPage int `xml:"fitToPage,attr,omitempty"`
 }
 
 type OutlinePr struct {
@@ -77,9 +129,16 @@ type DataValidations struct {
 
 type DataValidation struct {
 	Type         string `xml:"type,attr,omitempty"`
+	ErrorStyle   string `xml:"errorStyle,attr,omitempty"`
+	Operator     string `xml:"operator,attr,omitempty"`
 	AllowBlank   int    `xml:"allowBlank,attr,omitempty"`
+	ShowDropDown int    `xml:"showDropDown,attr,omitempty"` // 1 hides the list arrow
 	ShowInputMsg int    `xml:"showInputMessage,attr,omitempty"`
 	ShowErrorMsg int    `xml:"showErrorMessage,attr,omitempty"`
+	ErrorTitle   string `xml:"errorTitle,attr,omitempty"`
+	Error        string `xml:"error,attr,omitempty"`
+	PromptTitle  string `xml:"promptTitle,attr,omitempty"`
+	Prompt       string `xml:"prompt,attr,omitempty"`
 	Sqref        string `xml:"sqref,attr"`
 	Formula1     string `xml:"formula1,omitempty"`
 	Formula2     string `xml:"formula2,omitempty"`
@@ -94,6 +153,7 @@ type Hyperlink struct {
 	RID      string `xml:"r:id,attr,omitempty"`
 	Location string `xml:"location,attr,omitempty"`
 	Display  string `xml:"display,attr,omitempty"`
+	Tooltip  string `xml:"tooltip,attr,omitempty"`
 }
 
 type SheetViews struct {
@@ -101,17 +161,24 @@ type SheetViews struct {
 }
 
 type SheetView struct {
-	TabSelected    int   `xml:"tabSelected,attr"`
-	WorkbookViewID int   `xml:"workbookViewId,attr"`
-	Pane           *Pane `xml:"pane,omitempty"`
-}
-
+	ShowGridLines     *int  `xml:"showGridLines,attr,omitempty"`     // Defaults to 1
+	ShowRowColHeaders *int  `xml:"showRowColHeaders,attr,omitempty"` // Defaults to 1
+	RightToLeft       int   `xml:"rightToLeft,attr,omitempty"`
+	TabSelected       int   `xml:"tabSelected,attr"`
+	ZoomScale         int   `xml:"zoomScale,attr,omitempty"` // Percent; defaults to 100
+	ZoomScaleNormal   int   `xml:"zoomScaleNormal,attr,omitempty"`
+	WorkbookViewID    int   `xml:"workbookViewId,attr"`
+	Pane              *Pane `xml:"pane,omitempty"`
+}
+
+// Pane splits a sheet view. Frozen panes split at a number of columns and
+// rows; other splits are positioned in twentieths of a point.
 type Pane struct {
-	XSplit      int    `xml:"xSplit,attr,omitempty"`
-	YSplit      int    `xml:"ySplit,attr,omitempty"`
-	TopLeftCell string `xml:"topLeftCell,attr,omitempty"`
-	ActivePane  string `xml:"activePane,attr,omitempty"`
-	State       string `xml:"state,attr,omitempty"` // "frozen"
+	XSplit      float64 `xml:"xSplit,attr,omitempty"`
+	YSplit      float64 `xml:"ySplit,attr,omitempty"`
+	TopLeftCell string  `xml:"topLeftCell,attr,omitempty"`
+	ActivePane  string  `xml:"activePane,attr,omitempty"`
+	State       string  `xml:"state,attr,omitempty"` // "frozen", "frozenSplit" or "split"
 }
 
 type AutoFilter struct {
@@ -127,6 +194,7 @@ type Col struct {
 	Max          int     `xml:"max,attr"`
 	Width        float64 `xml:"width,attr"`
 	CustomWidth  int     `xml:"customWidth,attr,omitempty"`
+	Hidden       int     `xml:"hidden,attr,omitempty"`
 	OutlineLevel uint8   `xml:"outlineLevel,attr,omitempty"`
 	Collapsed    bool    `xml:"collapsed,attr,omitempty"`
 }
@@ -13,9 +13,6 @@ type Xf struct {
 	ApplyBorder       int        `xml:"applyBorder,attr,omitempty"`
 	ApplyAlignment    int        `xml:"applyAlignment,attr,omitempty"`
 	Alignment         *Alignment `xml:"alignment,omitempty"`
-	Font              *Font      `xml:"font,omitempty"`   // For DXF
-	Fill              *Fill      `xml:"fill,omitempty"`   // For DXF
-	Border            *Border    `xml:"border,omitempty"` // For DXF
 }
 
 type Alignment struct {
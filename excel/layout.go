@@ -0,0 +1,237 @@
+package excel
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const pointsPerPixel = 0.75 // At 96 dpi
+
+// sheetLayout is a sheet being laid out as a table: its cells and the sizes
+// of its rows and columns in points.
+type sheetLayout struct {
+	name       string
+	ws         *xmlstructs.Worksheet
+	cells      map[[2]int]*xmlstructs.Cell // {col, row} -> cell
+	colWidths  map[int]float64             // Columns with a width
+	rowHeights map[int]float64             // Rows with a height
+	hiddenCols map[int]bool
+	hiddenRows map[int]bool
+	defaultCol float64
+	defaultRow float64
+	titleRows  []int
+	titleCols  []int
+	scale      float64 // Factor the sheet is drawn at
+	showHidden bool    // Give hidden rows and columns their size instead of 0
+}
+
+// exportedSheets lists the sheets exports write by default: the visible ones
+// not written through a stream writer.
+func (e *state) exportedSheets() []string {
+	var sheets []string
+	for _, sh := range e.workbook.Sheets {
+		if _, streamed := e.streams[sh.Name]; sh.State == "" && !streamed {
+			sheets = append(sheets, sh.Name)
+		}
+	}
+	return sheets
+}
+
+// newSheetLayout gathers the cells, sizes and print titles of a sheet.
+func (e *state) newSheetLayout(name string, ws *xmlstructs.Worksheet) *sheetLayout {
+	s := &sheetLayout{
+		name:       name,
+		ws:         ws,
+		cells:      make(map[[2]int]*xmlstructs.Cell),
+		colWidths:  make(map[int]float64),
+		rowHeights: make(map[int]float64),
+		hiddenCols: make(map[int]bool),
+		hiddenRows: make(map[int]bool),
+		defaultCol: columnPixels(defaultColumnWidth+cellPadding) * pointsPerPixel,
+		defaultRow: defaultRowHeight,
+		scale:      1,
+	}
+	if ws.SheetFormatPr != nil && ws.SheetFormatPr.DefaultRowHeight > 0 {
+		s.defaultRow = ws.SheetFormatPr.DefaultRowHeight
+	}
+	if ws.Cols != nil {
+		for _, c := range ws.Cols.Items {
+			for col := c.Min; col <= c.Max; col++ {
+				if c.Width > 0 {
+					s.colWidths[col] = columnPixels(c.Width) * pointsPerPixel
+				}
+				s.hiddenCols[col] = c.Hidden == 1 || c.Width == 0
+			}
+		}
+	}
+	for i := range ws.SheetData.Rows {
+		row := &ws.SheetData.Rows[i]
+		if row.Ht > 0 {
+			s.rowHeights[row.R] = row.Ht
+		}
+		s.hiddenRows[row.R] = row.Hidden == 1
+		for j := range row.Cells {
+			col, _ := axisPosition(row.Cells[j].R)
+			s.cells[[2]int{col, row.R}] = &row.Cells[j]
+		}
+	}
+	for _, ref := range e.sheetNameRefs("_xlnm.Print_Titles", name) {
+		switch {
+		case ref.Col1 == 0:
+			s.titleRows = append(s.titleRows, visible(ref.Row1, ref.Row2, s.rowHeight)...)
+		case ref.Row1 == 0:
+			s.titleCols = append(s.titleCols, visible(ref.Col1, ref.Col2, s.colWidth)...)
+		}
+	}
+	return s
+}
+
+// colWidth returns the scaled width of a column, 0 when it is hidden.
+func (s *sheetLayout) colWidth(col int) float64 {
+	if s.hiddenCols[col] && !s.showHidden {
+		return 0
+	}
+	if w, ok := s.colWidths[col]; ok {
+		return w * s.scale
+	}
+	return s.defaultCol * s.scale
+}
+
+// rowHeight returns the scaled height of a row, 0 when it is hidden.
+func (s *sheetLayout) rowHeight(row int) float64 {
+	if s.hiddenRows[row] && !s.showHidden {
+		return 0
+	}
+	if h, ok := s.rowHeights[row]; ok {
+		return h * s.scale
+	}
+	return s.defaultRow * s.scale
+}
+
+// cellAt returns the column and row holding a position given in EMU from the
+// top-left corner of the sheet.
+func (s *sheetLayout) cellAt(p xmlstructs.Point) (int, int) {
+	col, x := 1, float64(p.X)/emuPerPoint
+	for ; col < formula.MaxColumns && x >= s.colWidth(col); col++ {
+		x -= s.colWidth(col)
+	}
+	row, y := 1, float64(p.Y)/emuPerPoint
+	for ; row < formula.MaxRows && y >= s.rowHeight(row); row++ {
+		y -= s.rowHeight(row)
+	}
+	return col, row
+}
+
+// usedRange returns the range holding the values, formulas and formatted
+// cells of the sheet, and its merges. found is false for an empty sheet.
+func (s *sheetLayout) usedRange() (used cellArea, found bool) {
+	used = cellArea{formula.MaxColumns, formula.MaxRows, 0, 0}
+	for key, cell := range s.cells {
+		if cell.V != "" || cell.IS != nil || cell.F != nil || cell.S != 0 {
+			used = cellArea{min(used.col1, key[0]), min(used.row1, key[1]), max(used.col2, key[0]), max(used.row2, key[1])}
+			found = true
+		}
+	}
+	if mc := s.ws.MergeCells; mc != nil {
+		for _, m := range mc.Items {
+			if a, err := parseArea(m.Ref); err == nil {
+				used = cellArea{min(used.col1, a.col1), min(used.row1, a.row1), max(used.col2, a.col2), max(used.row2, a.row2)}
+				found = true
+			}
+		}
+	}
+	return used, found
+}
+
+// mergeSpan is a merged range over the rows and columns of a table.
+type mergeSpan struct{ row, col, rows, cols int }
+
+// tableMerges places the merged ranges of a sheet over a table.
+type tableMerges struct {
+	spans   []mergeSpan
+	anchors map[[2]int][2]int // Table position -> sheet cell shown there
+	covered map[[2]int]bool   // Table positions in a merge; false for the one showing it
+}
+
+// merges places the merged ranges of the sheet over a table showing rows and
+// columns, which are sorted. A merge shows the cell at its top-left corner
+// across the rows and columns of the table it covers, even when that cell is
+// not shown itself.
+func (s *sheetLayout) merges(rows, cols []int) *tableMerges {
+	m := &tableMerges{anchors: make(map[[2]int][2]int), covered: make(map[[2]int]bool)}
+	if s.ws.MergeCells == nil {
+		return m
+	}
+	for _, mc := range s.ws.MergeCells.Items {
+		a, err := parseArea(mc.Ref)
+		if err != nil {
+			continue
+		}
+		r1, r2 := indexRange(rows, a.row1, a.row2)
+		c1, c2 := indexRange(cols, a.col1, a.col2)
+		if r1 > r2 || c1 > c2 {
+			continue
+		}
+		m.anchors[[2]int{r1, c1}] = [2]int{a.col1, a.row1}
+		for i := r1; i <= r2; i++ {
+			for j := c1; j <= c2; j++ {
+				m.covered[[2]int{i, j}] = i != r1 || j != c1
+			}
+		}
+		if r2 > r1 || c2 > c1 {
+			m.spans = append(m.spans, mergeSpan{r1, c1, r2 - r1 + 1, c2 - c1 + 1})
+		}
+	}
+	return m
+}
+
+// visible lists the shown rows, or columns, from first to last.
+func visible(first, last int, size func(int) float64) []int {
+	var items []int
+	for i := first; i <= last; i++ {
+		if size(i) > 0 {
+			items = append(items, i)
+		}
+	}
+	return items
+}
+
+// nearestShown returns the position in shown, which is sorted, of index i or
+// of the first index after it, or of the last index when none is after it. It
+// returns -1 when nothing is shown.
+func nearestShown(shown []int, i int) int {
+	k, _ := slices.BinarySearch(shown, i)
+	return min(k, len(shown)-1)
+}
+
+// indexRange returns the first and last positions of items, which are sorted,
+// holding values from first to last. first > last when there are none.
+func indexRange(items []int, first, last int) (int, int) {
+	i, _ := slices.BinarySearch(items, first)
+	j, _ := slices.BinarySearch(items, last+1)
+	return i, j - 1
+}
+
+// sheetNameRefs returns the references of a built-in defined name of a sheet,
+// such as its print area.
+func (e *state) sheetNameRefs(name, sheet string) []formula.Reference {
+	if e.workbook.DefinedNames == nil {
+		return nil
+	}
+	idx := (&sheetProcessor{e}).getSheetIndex(sheet)
+	var refs []formula.Reference
+	for _, dn := range e.workbook.DefinedNames.Items {
+		if strings.EqualFold(dn.Name, name) && dn.LocalSheetID != nil && *dn.LocalSheetID == idx {
+			rewriteFormula(dn.Ref, func(r formula.Reference) formula.Reference {
+				if !r.Invalid {
+					refs = append(refs, r)
+				}
+				return r
+			})
+		}
+	}
+	return refs
+}
@@ -2,6 +2,7 @@ package excel
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -9,13 +10,18 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/cfb"
+	"github.com/gsoultan/thoth/excel/internal/encryption"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
 )
 
 // lifecycle handles document lifecycle operations.
 type lifecycle struct{ *state }
 
-// Open loads a document from a reader.
+// Open loads a document from a reader. Both xlsx packages and legacy .xls
+// (BIFF8) workbooks are accepted; the latter are saved as xlsx. Encrypted
+// workbooks are decrypted with the password set by SetPassword.
 func (e *lifecycle) Open(ctx context.Context, reader io.Reader) error {
 	// zip.NewReader requires ReaderAt, so we buffer to temp file
 	tmp, err := os.CreateTemp("", "thoth-excel-*.xlsx")
@@ -24,11 +30,26 @@ func (e *lifecycle) Open(ctx context.Context, reader io.Reader) error {
 	}
 	e.tempFile = tmp
 
-	_, err = io.Copy(tmp, reader)
+	size, err := io.Copy(tmp, reader)
 	if err != nil {
 		return fmt.Errorf("buffer reader: %w", err)
 	}
 
+	// Encrypted and legacy .xls workbooks are compound files rather than zip packages.
+	signature := make([]byte, len(cfb.Signature))
+	if _, err := tmp.ReadAt(signature, 0); err == nil && cfb.IsCompoundFile(signature) {
+		cf, err := cfb.NewReader(tmp, size)
+		if err != nil {
+			return fmt.Errorf("%w: %v", document.ErrInvalidFormat, err)
+		}
+		if _, err := cf.Stream(encryption.InfoStream); err != nil {
+			return e.loadXLS(ctx, cf)
+		}
+		if err := e.decryptPackage(cf, tmp); err != nil {
+			return err
+		}
+	}
+
 	zr, err := zip.OpenReader(tmp.Name())
 	if err != nil {
 		return fmt.Errorf("open zip reader: %w", err)
@@ -38,9 +59,26 @@ func (e *lifecycle) Open(ctx context.Context, reader io.Reader) error {
 	return e.loadCore(ctx)
 }
 
-// Save writes the document to a writer.
+// Save writes the document to a writer, encrypted when a password is set.
 func (e *lifecycle) Save(ctx context.Context, writer io.Writer) error {
+	if e.password == "" {
+		return e.savePackage(writer)
+	}
+	var pkg bytes.Buffer
+	if err := e.savePackage(&pkg); err != nil {
+		return err
+	}
+	return e.writeEncrypted(&pkg, writer)
+}
+
+func (e *lifecycle) savePackage(writer io.Writer) error {
+	if e.calcDirty {
+		if err := (&calcProcessor{e.state}).recalculate(); err != nil {
+			return err
+		}
+	}
 	e.prepareSheets()
+	e.prepareComments()
 	e.prepareContentTypes()
 	zw := zip.NewWriter(writer)
 	defer zw.Close()
@@ -68,11 +106,31 @@ func (e *lifecycle) Save(ctx context.Context, writer io.Writer) error {
 		return err
 	}
 
+	// Save charts
+	if err := e.saveCharts(zw, handled); err != nil {
+		return err
+	}
+
+	// Save pivot tables and their caches
+	if err := e.savePivotTables(zw, handled); err != nil {
+		return err
+	}
+
 	// Save tables
 	if err := e.saveTables(zw, handled); err != nil {
 		return err
 	}
 
+	// Save comments and the VML drawings that display them
+	if err := e.saveComments(zw, handled); err != nil {
+		return err
+	}
+
+	// Save source parts rewritten as text
+	if err := e.savePatchedParts(zw, handled); err != nil {
+		return err
+	}
+
 	// Copy remaining files from original reader
 	return e.copyRemainingFiles(zw, handled)
 }
@@ -168,16 +226,22 @@ func (e *lifecycle) saveSheets(zw *zip.Writer, handled map[string]bool) error {
 		if ws.XMLNS_R == "" {
 			ws.XMLNS_R = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
 		}
-		for i, s := range e.workbook.Sheets {
-			if s.Name == name {
-				path := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
-				if err := e.writeXML(zw, path, ws); err != nil {
-					return err
-				}
-				handled[path] = true
-				break
+		path := e.sheetPath(name)
+		if path == "" {
+			continue
+		}
+		if sw, ok := e.streams[name]; ok {
+			w, err := zw.Create(path)
+			if err != nil {
+				return err
 			}
+			if err := sw.writeTo(w); err != nil {
+				return fmt.Errorf("write streamed sheet %s: %w", name, err)
+			}
+		} else if err := e.writeXML(zw, path, ws); err != nil {
+			return err
 		}
+		handled[path] = true
 	}
 	for key, rels := range e.sheetRels {
 		if strings.HasSuffix(key, ".rels") {
@@ -189,16 +253,15 @@ func (e *lifecycle) saveSheets(zw *zip.Writer, handled map[string]bool) error {
 			continue
 		}
 		// This is a sheet name
-		for i, s := range e.workbook.Sheets {
-			if s.Name == key {
-				path := fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", i+1)
-				if err := e.writeXML(zw, path, rels); err != nil {
-					return err
-				}
-				handled[path] = true
-				break
-			}
+		sheetPath := e.sheetPath(key)
+		if sheetPath == "" {
+			continue
 		}
+		path := relsPath(sheetPath)
+		if err := e.writeXML(zw, path, rels); err != nil {
+			return err
+		}
+		handled[path] = true
 	}
 	return nil
 }
@@ -220,10 +283,39 @@ func (e *lifecycle) saveMedia(zw *zip.Writer, handled map[string]bool) error {
 
 func (e *lifecycle) saveDrawings(zw *zip.Writer, handled map[string]bool) error {
 	for path, dr := range e.drawings {
-		if dr.XMLNS_R == "" {
-			dr.XMLNS_R = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+		if err := e.writePrefixed(zw, path, dr, dr.Prefixes()); err != nil {
+			return err
+		}
+		handled[path] = true
+	}
+	return nil
+}
+
+func (e *lifecycle) saveCharts(zw *zip.Writer, handled map[string]bool) error {
+	for path, cs := range e.charts {
+		if err := e.writePrefixed(zw, path, cs, cs.Prefixes()); err != nil {
+			return err
+		}
+		handled[path] = true
+	}
+	return nil
+}
+
+func (e *lifecycle) savePivotTables(zw *zip.Writer, handled map[string]bool) error {
+	for path, def := range e.pivotCaches {
+		if err := e.writeXML(zw, path, def); err != nil {
+			return err
 		}
-		if err := e.writeXML(zw, path, dr); err != nil {
+		handled[path] = true
+	}
+	for path, records := range e.pivotRecords {
+		if err := e.writeXML(zw, path, records); err != nil {
+			return err
+		}
+		handled[path] = true
+	}
+	for path, table := range e.pivotTables {
+		if err := e.writeXML(zw, path, table); err != nil {
 			return err
 		}
 		handled[path] = true
@@ -241,12 +333,57 @@ func (e *lifecycle) saveTables(zw *zip.Writer, handled map[string]bool) error {
 	return nil
 }
 
+func (e *lifecycle) saveComments(zw *zip.Writer, handled map[string]bool) error {
+	for _, sc := range e.comments {
+		for _, path := range sc.removed {
+			handled[path] = true
+		}
+		if !sc.dirty || sc.vmlPath == "" {
+			continue
+		}
+		// Without comments, the drawing is kept only for its other shapes.
+		if sc.path != "" {
+			if err := e.writeXML(zw, sc.path, sc.part); err != nil {
+				return err
+			}
+			handled[sc.path] = true
+		}
+
+		w, err := zw.Create(sc.vmlPath)
+		if err != nil {
+			return fmt.Errorf("create vml drawing %s: %w", sc.vmlPath, err)
+		}
+		if _, err := w.Write(sc.renderVML()); err != nil {
+			return fmt.Errorf("write vml drawing %s: %w", sc.vmlPath, err)
+		}
+		handled[sc.vmlPath] = true
+	}
+	return nil
+}
+
+func (e *lifecycle) savePatchedParts(zw *zip.Writer, handled map[string]bool) error {
+	for name, data := range e.patched {
+		if handled[name] {
+			continue
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("create part %s: %w", name, err)
+		}
+		if _, err := f.Write(data); err != nil {
+			return fmt.Errorf("write part %s: %w", name, err)
+		}
+		handled[name] = true
+	}
+	return nil
+}
+
 func (e *lifecycle) copyRemainingFiles(zw *zip.Writer, handled map[string]bool) error {
 	if e.reader == nil {
 		return nil
 	}
 	for _, f := range e.reader.File {
-		if handled[f.Name] {
+		if handled[f.Name] || e.removed[f.Name] {
 			continue
 		}
 		if err := e.copyFile(f, zw); err != nil {
@@ -282,17 +419,38 @@ func (e *lifecycle) prepareContentTypes() {
 		e.contentTypes.AddOverride("/"+name, "application/vnd.openxmlformats-officedocument.drawing+xml")
 	}
 
+	for path := range e.charts {
+		e.contentTypes.AddOverride("/"+path, chartContentType)
+	}
+
+	for path := range e.pivotCaches {
+		e.contentTypes.AddOverride("/"+path, pivotCacheContentType)
+	}
+
+	for path := range e.pivotRecords {
+		e.contentTypes.AddOverride("/"+path, pivotRecordsContentType)
+	}
+
+	for path := range e.pivotTables {
+		e.contentTypes.AddOverride("/"+path, pivotTableContentType)
+	}
+
 	for path := range e.tables {
-		e.contentTypes.AddOverride("/"+path, "application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml")
+		e.contentTypes.AddOverride("/"+path, tableContentType)
+	}
+
+	for _, sc := range e.comments {
+		if sc.path != "" {
+			e.contentTypes.AddOverride("/"+sc.path, commentsContentType)
+		}
+		if sc.vmlPath != "" {
+			e.contentTypes.AddDefault("vml", vmlContentType)
+		}
 	}
 
 	for name := range e.sheets {
-		for i, s := range e.workbook.Sheets {
-			if s.Name == name {
-				path := fmt.Sprintf("/xl/worksheets/sheet%d.xml", i+1)
-				e.contentTypes.AddOverride(path, "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml")
-				break
-			}
+		if path := e.sheetPath(name); path != "" {
+			e.contentTypes.AddOverride("/"+path, "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml")
 		}
 	}
 
@@ -302,23 +460,17 @@ func (e *lifecycle) prepareContentTypes() {
 		if strings.HasPrefix(ext, ".") {
 			ext = ext[1:]
 		}
-		switch ext {
-		case "png":
-			e.contentTypes.AddDefault(ext, "image/png")
-		case "jpg", "jpeg":
-			e.contentTypes.AddDefault(ext, "image/jpeg")
-		case "gif":
-			e.contentTypes.AddDefault(ext, "image/gif")
-		case "bmp":
-			e.contentTypes.AddDefault(ext, "image/bmp")
-		case "tif", "tiff":
-			e.contentTypes.AddDefault(ext, "image/tiff")
+		if ct, ok := imageContentTypes[ext]; ok {
+			e.contentTypes.AddDefault(ext, ct)
 		}
 	}
 }
 
 func (e *lifecycle) prepareSheets() {
-	for _, ws := range e.sheets {
+	for name, ws := range e.sheets {
+		if sw, ok := e.streams[name]; ok {
+			ws.Dimension = &xmlstructs.Dimension{Ref: sw.dimension()}
+		}
 		if ws.Dimension == nil {
 			ws.Dimension = &xmlstructs.Dimension{Ref: e.calculateDimension(ws)}
 		}
@@ -340,6 +492,15 @@ func (e *lifecycle) prepareSheets() {
 	}
 }
 
+// prepareComments unlinks the comment parts of sheets whose last comment was removed.
+func (e *lifecycle) prepareComments() {
+	for sheet, sc := range e.comments {
+		if sc.dirty && sc.path != "" && len(sc.part.CommentList.Items) == 0 {
+			e.detachComments(sheet, sc)
+		}
+	}
+}
+
 func (e *lifecycle) calculateDimension(ws *xmlstructs.Worksheet) string {
 	if len(ws.SheetData.Rows) == 0 {
 		return "A1"
@@ -378,6 +539,10 @@ func (e *lifecycle) calculateDimension(ws *xmlstructs.Worksheet) string {
 }
 
 func (e *lifecycle) Close() error {
+	for name, sw := range e.streams {
+		sw.close()
+		delete(e.streams, name)
+	}
 	if e.reader != nil {
 		e.reader.Close()
 	}
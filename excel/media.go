@@ -1,129 +1,479 @@
 package excel
 
 import (
+	"encoding/xml"
 	"fmt"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
 )
 
+const (
+	drawingRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	imageRelType   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+)
+
+const emuPerPoint = 12700
+
+// imageContentTypes maps image file extensions to their content types.
+var imageContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+	"bmp":  "image/bmp",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"emf":  "image/x-emf",
+	"wmf":  "image/x-wmf",
+	"svg":  "image/svg+xml",
+}
+
 type mediaProcessor struct{ *state }
 
-func (e *mediaProcessor) insertImage(sheet string, path string, x, y float64) error {
-	data, err := os.ReadFile(path)
+func (e *mediaProcessor) insertImage(sheet string, imagePath string, x, y float64) error {
+	data, err := os.ReadFile(imagePath)
 	if err != nil {
 		return fmt.Errorf("read image file: %w", err)
 	}
 
-	ext := strings.ToLower(filepath.Ext(path))
+	ext := strings.ToLower(filepath.Ext(imagePath))
 	if ext == "" {
 		ext = ".png"
 	}
-	imgName := fmt.Sprintf("image%d%s", len(e.media)+1, ext)
-	mediaPath := "xl/media/" + imgName
-	e.media[mediaPath] = data
 
-	ws, ok := e.sheets[sheet]
-	if !ok {
-		return fmt.Errorf("sheet %s not found", sheet)
-	}
+	// For now, simple OneCellAnchor at (x, y) cells
+	// In Excel, x and y here represent Col and Row
+	return e.addPicture(sheet, data, ext, func(pic *xmlstructs.Pic) xmlstructs.Anchor {
+		return xmlstructs.Anchor{
+			OneCellAnchor: &xmlstructs.OneCellAnchor{
+				From: xmlstructs.Marker{
+					Col: int(x),
+					Row: int(y),
+				},
+				Ext: xmlstructs.Extent{
+					Cx: 600000, // Default size
+					Cy: 600000,
+				},
+				Pic: pic,
+			},
+		}
+	})
+}
 
+// addPicture stores an image and adds a picture showing it to the drawing of a
+// sheet, positioned by the anchor that place returns for it.
+func (e *mediaProcessor) addPicture(sheet string, data []byte, ext string, place func(*xmlstructs.Pic) xmlstructs.Anchor) error {
 	// 1. Get or create drawing for this sheet
-	drawingPath, err := e.getOrCreateDrawing(sheet)
+	_, dr, drRels, err := e.sheetDrawing(sheet)
 	if err != nil {
 		return err
 	}
-	dr := e.drawings[drawingPath]
 
-	// 2. Get or create drawing relationships
-	drRelsPath := "xl/drawings/_rels/" + filepath.Base(drawingPath) + ".rels"
-	if e.sheetRels[drRelsPath] == nil {
-		e.sheetRels[drRelsPath] = &xmlstructs.Relationships{}
-	}
-	drRels := e.sheetRels[drRelsPath]
+	mediaPath := e.nextPartPath("xl/media/image%d" + ext)
+	imgName := path.Base(mediaPath)
+	e.media[mediaPath] = data
 
-	// 3. Add image to drawing relationships
-	rID := drRels.AddRelationship(
-		"http://schemas.openxmlformats.org/officeDocument/2006/relationships/image",
-		"../media/"+imgName,
-	)
+	// 2. Add image to drawing relationships
+	rID := drRels.AddRelationship(imageRelType, "../media/"+imgName)
 
-	// 4. Add anchor to drawing
-	// For now, simple OneCellAnchor at (x, y) cells
-	// In Excel, x and y here represent Col and Row
-	anchor := xmlstructs.Anchor{
-		OneCellAnchor: &xmlstructs.OneCellAnchor{
-			From: xmlstructs.Marker{
-				Col: int(x),
-				Row: int(y),
+	// 3. Add anchor to drawing
+	pic := &xmlstructs.Pic{
+		NvPicPr: xmlstructs.NvPicPr{
+			CNvPr: xmlstructs.CNvPr{
+				ID:   nextShapeID(dr),
+				Name: imgName,
 			},
-			Ext: xmlstructs.Extent{
-				Cx: 600000, // Default size
-				Cy: 600000,
+			CNvPicPr: xmlstructs.CNvPicPr{
+				PicLocks: &xmlstructs.PicLocks{NoChangeAspect: 1},
 			},
-			Pic: &xmlstructs.Pic{
-				NvPicPr: xmlstructs.NvPicPr{
-					CNvPr: xmlstructs.CNvPr{
-						ID:   len(dr.Anchors) + 1,
-						Name: imgName,
-					},
-				},
-				BlipFill: xmlstructs.BlipFill{
-					Blip: xmlstructs.Blip{
-						Embed: rID,
-					},
-				},
-				SpPr: xmlstructs.SpPr{
-					PrstGeom: xmlstructs.PrstGeom{
-						Prst: "rect",
-					},
-				},
-			},
-			ClientData: &xmlstructs.Any{},
 		},
+		BlipFill: xmlstructs.BlipFill{
+			Blip:    xmlstructs.Blip{Embed: rID},
+			Stretch: &xmlstructs.Stretch{},
+		},
+		SpPr: xmlstructs.SpPr{
+			PrstGeom: &xmlstructs.PrstGeom{Prst: "rect"},
+		},
+	}
+	dr.Anchors = append(dr.Anchors, place(pic))
+	return nil
+}
+
+// sheetDrawing returns the drawing part of a sheet and its relationships, decoding the
+// drawing from the source package on first access or creating it when the sheet has none.
+func (e *state) sheetDrawing(sheet string) (string, *xmlstructs.WsDr, *xmlstructs.Relationships, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return "", nil, nil, fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
+	if e.sheetRels[sheet] == nil {
+		e.sheetRels[sheet] = &xmlstructs.Relationships{}
+	}
+	sRels := e.sheetRels[sheet]
+
+	drawingPath := ""
+	for _, rel := range sRels.Rels {
+		if rel.Type == drawingRelType && (ws.Drawing == nil || ws.Drawing.RID == "" || rel.ID == ws.Drawing.RID) {
+			drawingPath = resolveTarget(e.sheetPath(sheet), rel.Target)
+			if ws.Drawing == nil || ws.Drawing.RID == "" {
+				ws.Drawing = &xmlstructs.WsDrawing{RID: rel.ID}
+			}
+			break
+		}
 	}
-	dr.Anchors = append(dr.Anchors, anchor)
 
-	// Ensure worksheet has drawing reference
-	if ws.Drawing == nil {
-		// Need to add relationship from sheet to drawing
-		if e.sheetRels[sheet] == nil {
-			e.sheetRels[sheet] = &xmlstructs.Relationships{}
+	if drawingPath == "" {
+		drawingPath = e.nextPartPath("xl/drawings/drawing%d.xml")
+		rID := sRels.AddRelationship(drawingRelType, "../drawings/"+path.Base(drawingPath))
+		ws.Drawing = &xmlstructs.WsDrawing{RID: rID}
+		e.drawings[drawingPath] = &xmlstructs.WsDr{}
+	}
+
+	dr, ok := e.drawings[drawingPath]
+	if !ok {
+		dr = &xmlstructs.WsDr{}
+		if err := e.loadXML(drawingPath, dr); err != nil {
+			return "", nil, nil, fmt.Errorf("load drawing %s: %w", drawingPath, err)
+		}
+		e.drawings[drawingPath] = dr
+	}
+
+	drRelsPath := relsPath(drawingPath)
+	if e.sheetRels[drRelsPath] == nil {
+		var drRels xmlstructs.Relationships
+		if err := e.loadXML(drRelsPath, &drRels); err != nil {
+			drRels = xmlstructs.Relationships{}
+		}
+		e.sheetRels[drRelsPath] = &drRels
+	}
+	return drawingPath, dr, e.sheetRels[drRelsPath], nil
+}
+
+// nextShapeID returns an unused drawing object ID.
+func nextShapeID(dr *xmlstructs.WsDr) int {
+	id := 1
+	for _, p := range drawingPictures(dr) {
+		id = max(id, p.pic.NvPicPr.CNvPr.ID+1)
+	}
+	for _, a := range dr.Anchors {
+		var frame *xmlstructs.GraphicFrame
+		switch {
+		case a.TwoCellAnchor != nil:
+			frame = a.TwoCellAnchor.GraphicFrame
+		case a.OneCellAnchor != nil:
+			frame = a.OneCellAnchor.GraphicFrame
+		case a.AbsoluteAnchor != nil:
+			frame = a.AbsoluteAnchor.GraphicFrame
+		}
+		if frame != nil {
+			id = max(id, frame.NvGraphicFramePr.CNvPr.ID+1)
 		}
-		sRels := e.sheetRels[sheet]
+	}
+	return id
+}
 
-		// Find sheet index
-		sheetIdx := 1
-		for i, s := range e.workbook.Sheets {
-			if s.Name == sheet {
-				sheetIdx = i + 1
-				break
+// drawingPicture is a picture of a drawing, alone in its anchor or within a group.
+type drawingPicture struct {
+	pic    *xmlstructs.Pic
+	anchor int               // Index of the anchor placing the picture
+	group  *xmlstructs.GrpSp // Group holding the picture, nil when it is alone in its anchor
+}
+
+// drawingPictures returns the pictures of a drawing in document order,
+// including those within groups.
+func drawingPictures(dr *xmlstructs.WsDr) []drawingPicture {
+	var pics []drawingPicture
+	var walk func(i int, g *xmlstructs.GrpSp)
+	walk = func(i int, g *xmlstructs.GrpSp) {
+		for _, m := range g.Members {
+			if m.Pic != nil {
+				pics = append(pics, drawingPicture{pic: m.Pic, anchor: i, group: g})
+			} else if m.GrpSp != nil {
+				walk(i, m.GrpSp)
 			}
 		}
+	}
+	for i, a := range dr.Anchors {
+		var pic *xmlstructs.Pic
+		var group *xmlstructs.GrpSp
+		switch {
+		case a.TwoCellAnchor != nil:
+			pic, group = a.TwoCellAnchor.Pic, a.TwoCellAnchor.GrpSp
+		case a.OneCellAnchor != nil:
+			pic, group = a.OneCellAnchor.Pic, a.OneCellAnchor.GrpSp
+		case a.AbsoluteAnchor != nil:
+			pic, group = a.AbsoluteAnchor.Pic, a.AbsoluteAnchor.GrpSp
+		}
+		if pic != nil {
+			pics = append(pics, drawingPicture{pic: pic, anchor: i})
+		}
+		if group != nil {
+			walk(i, group)
+		}
+	}
+	return pics
+}
+
+// picturePlacement returns the cell holding the top-left corner of the anchor
+// of a picture on sheet, and the size of the picture in EMU. Pictures within
+// groups give the size they have in the group.
+func (e *state) picturePlacement(sheet string, ws *xmlstructs.Worksheet, dr *xmlstructs.WsDr, p drawingPicture) (cell string, cx, cy int64) {
+	if xfrm := p.pic.SpPr.Xfrm; xfrm != nil {
+		cx, cy = xfrm.Ext.Cx, xfrm.Ext.Cy
+	}
+	var ext xmlstructs.Extent
+	var col, row int
+	switch a := dr.Anchors[p.anchor]; {
+	case a.TwoCellAnchor != nil:
+		col, row = a.TwoCellAnchor.From.Col+1, a.TwoCellAnchor.From.Row+1
+	case a.OneCellAnchor != nil:
+		col, row = a.OneCellAnchor.From.Col+1, a.OneCellAnchor.From.Row+1
+		ext = a.OneCellAnchor.Ext
+	case a.AbsoluteAnchor != nil:
+		col, row = e.newSheetLayout(sheet, ws).cellAt(a.AbsoluteAnchor.Pos)
+		ext = a.AbsoluteAnchor.Ext
+	}
+	if p.group == nil && ext != (xmlstructs.Extent{}) {
+		cx, cy = ext.Cx, ext.Cy
+	}
+	return formula.CellName(col, row), cx, cy
+}
 
-		drRID := sRels.AddRelationship(
-			"http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing",
-			fmt.Sprintf("../drawings/drawing%d.xml", sheetIdx),
-		)
-		ws.Drawing = &xmlstructs.WsDrawing{RID: drRID}
+// imagePart returns the image part a drawing relationship points to. Linked
+// images, which are not stored in the package, are reported as missing.
+func imagePart(drawingPath string, rels *xmlstructs.Relationships, rID string) (string, bool) {
+	for _, rel := range rels.Rels {
+		if rel.ID == rID && rel.Type == imageRelType && rel.TargetMode != "External" {
+			return resolveTarget(drawingPath, rel.Target), true
+		}
 	}
+	return "", false
+}
 
+func (e *mediaProcessor) images() ([]document.Image, error) {
+	var images []document.Image
+	for _, sh := range e.workbook.Sheets {
+		if _, streamed := e.streams[sh.Name]; streamed {
+			continue
+		}
+		ws, ok := e.worksheet(sh.Name)
+		if !ok {
+			if e.hasSheet(sh.Name) {
+				return nil, fmt.Errorf("load sheet %s", sh.Name)
+			}
+			continue
+		}
+		if ws.Drawing == nil {
+			continue
+		}
+		drPath, dr, rels, err := e.sheetDrawing(sh.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range drawingPictures(dr) {
+			name, ok := imagePart(drPath, rels, p.pic.BlipFill.Blip.Embed)
+			if !ok {
+				continue
+			}
+			data, err := e.mediaData(name)
+			if err != nil {
+				return nil, err
+			}
+			cell, cx, cy := e.picturePlacement(sh.Name, ws, dr, p)
+			images = append(images, document.Image{
+				Name:     p.pic.NvPicPr.CNvPr.Name,
+				Sheet:    sh.Name,
+				Cell:     cell,
+				Width:    float64(cx) / emuPerPoint,
+				Height:   float64(cy) / emuPerPoint,
+				MIMEType: e.mediaType(name),
+				Path:     name,
+				Data:     data,
+			})
+		}
+	}
+	return images, nil
+}
+
+// mediaData returns the bytes of an image part, added in this session or read from the source package.
+func (e *state) mediaData(name string) ([]byte, error) {
+	if data, ok := e.media[name]; ok {
+		return data, nil
+	}
+	return e.partData(name)
+}
+
+// mediaType returns the content type of an image part, as declared by the
+// package or else implied by its extension.
+func (e *state) mediaType(name string) string {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+	if e.contentTypes != nil {
+		for _, o := range e.contentTypes.Override {
+			if o.PartName == "/"+name {
+				return o.ContentType
+			}
+		}
+		for _, d := range e.contentTypes.Defaults {
+			if strings.EqualFold(d.Extension, ext) {
+				return d.ContentType
+			}
+		}
+	}
+	return imageContentTypes[ext]
+}
+
+// findPicture returns the drawing of a sheet and its picture name.
+func (e *mediaProcessor) findPicture(sheet, name string) (string, *xmlstructs.WsDr, *xmlstructs.Relationships, drawingPicture, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return "", nil, nil, drawingPicture{}, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if ws.Drawing != nil {
+		drPath, dr, rels, err := e.sheetDrawing(sheet)
+		if err != nil {
+			return "", nil, nil, drawingPicture{}, err
+		}
+		for _, p := range drawingPictures(dr) {
+			if p.pic.NvPicPr.CNvPr.Name == name {
+				return drPath, dr, rels, p, nil
+			}
+		}
+	}
+	return "", nil, nil, drawingPicture{}, fmt.Errorf("image %s not found on sheet %s", name, sheet)
+}
+
+// deleteImage removes picture name from the drawing of a sheet, along with
+// its anchor unless the picture is within a group.
+func (e *mediaProcessor) deleteImage(sheet, name string) error {
+	drPath, dr, rels, p, err := e.findPicture(sheet, name)
+	if err != nil {
+		return err
+	}
+	if p.group == nil {
+		dr.Anchors = slices.Delete(dr.Anchors, p.anchor, p.anchor+1)
+	} else {
+		p.group.Members = slices.DeleteFunc(p.group.Members, func(m xmlstructs.GroupMember) bool { return m.Pic == p.pic })
+	}
+	e.releaseImage(drPath, dr, rels, p.pic.BlipFill.Blip.Embed)
 	return nil
 }
 
-func (e *mediaProcessor) getOrCreateDrawing(sheet string) (string, error) {
-	sheetIdx := 1
-	for i, s := range e.workbook.Sheets {
-		if s.Name == sheet {
-			sheetIdx = i + 1
-			break
+// imageExtension returns the file extension of a PNG, JPEG, GIF or BMP image.
+func imageExtension(data []byte) (string, error) {
+	switch ct := http.DetectContentType(data); ct {
+	case "image/png":
+		return ".png", nil
+	case "image/jpeg":
+		return ".jpeg", nil
+	case "image/gif":
+		return ".gif", nil
+	case "image/bmp":
+		return ".bmp", nil
+	default:
+		return "", fmt.Errorf("unsupported image type %s", ct)
+	}
+}
+
+func (e *mediaProcessor) replaceImage(sheet, name string, data []byte) error {
+	ext, err := imageExtension(data)
+	if err != nil {
+		return err
+	}
+	drPath, dr, rels, p, err := e.findPicture(sheet, name)
+	if err != nil {
+		return err
+	}
+
+	mediaPath := e.nextPartPath("xl/media/image%d" + ext)
+	e.media[mediaPath] = data
+	rID := p.pic.BlipFill.Blip.Embed
+	p.pic.BlipFill.Blip.Embed = rels.AddRelationship(imageRelType, "../media/"+path.Base(mediaPath))
+	p.pic.Inner = ""
+	e.releaseImage(drPath, dr, rels, rID)
+	return nil
+}
+
+// releaseImage removes the relationship rID of a drawing once none of its
+// pictures shows it, and the image part once no part of the package refers to it.
+func (e *state) releaseImage(drawingPath string, dr *xmlstructs.WsDr, rels *xmlstructs.Relationships, rID string) {
+	for _, p := range drawingPictures(dr) {
+		if p.pic.BlipFill.Blip.Embed == rID {
+			return
 		}
 	}
-	path := fmt.Sprintf("xl/drawings/drawing%d.xml", sheetIdx)
-	if _, ok := e.drawings[path]; !ok {
-		e.drawings[path] = &xmlstructs.WsDr{}
+	name, ok := imagePart(drawingPath, rels, rID)
+	rels.RemoveRelationship(rID)
+	if !ok || e.partReferenced(name) {
+		return
+	}
+	delete(e.media, name)
+	if e.findFile(name) != nil {
+		e.removed[name] = true
 	}
-	return path, nil
+}
+
+// partReferenced reports whether a relationship of any part in the package targets name.
+func (e *state) partReferenced(name string) bool {
+	targets := func(owner string, rels *xmlstructs.Relationships) bool {
+		for _, rel := range rels.Rels {
+			if rel.TargetMode != "External" && resolveTarget(owner, rel.Target) == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Relationships held in memory are keyed by sheet name or by the path of their part.
+	seen := make(map[string]bool)
+	for key, rels := range e.sheetRels {
+		relsName := key
+		if !strings.HasSuffix(key, ".rels") {
+			relsName = relsPath(e.sheetPath(key))
+		}
+		seen[relsName] = true
+		if !e.removed[relsName] && targets(relsOwner(relsName), rels) {
+			return true
+		}
+	}
+	var parts []string
+	for part := range e.patched {
+		parts = append(parts, part)
+	}
+	if e.reader != nil {
+		for _, f := range e.reader.File {
+			parts = append(parts, f.Name)
+		}
+	}
+	for _, part := range parts {
+		if seen[part] || e.removed[part] || !strings.HasSuffix(part, ".rels") {
+			continue
+		}
+		seen[part] = true
+		data, err := e.partData(part)
+		if err != nil {
+			continue
+		}
+		var rels xmlstructs.Relationships
+		if xml.Unmarshal(data, &rels) == nil && targets(relsOwner(part), &rels) {
+			return true
+		}
+	}
+	return false
+}
+
+// relsOwner returns the part whose relationships are stored in the relationships part name.
+func relsOwner(name string) string {
+	dir, base := path.Split(name)
+	return path.Join(path.Dir(path.Clean(dir)), strings.TrimSuffix(base, ".rels"))
 }
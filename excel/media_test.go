@@ -0,0 +1,236 @@
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImages_ReadReplaceDelete(t *testing.T) {
+	ctx := t.Context()
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+	png := []byte("\x89PNG\r\n\x1a\n logo")
+	imgPath := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(imgPath, png, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	sheet.InsertImage(imgPath, 1, 1).InsertImage(imgPath, 3, 4)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("InsertImage failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	images, err := reopened.Images()
+	if err != nil {
+		t.Fatalf("Images failed: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("got %d images, want 2", len(images))
+	}
+	img := images[1]
+	if img.Name != "image2.png" || img.Sheet != "Data" || img.Cell != "D5" || img.MIMEType != "image/png" ||
+		img.Path != "xl/media/image2.png" || !bytes.Equal(img.Data, png) || img.Width < 47 || img.Width > 48 {
+		t.Errorf("unexpected image %+v", img)
+	}
+
+	// The copy of a sheet shares its images, which must survive edits of the original.
+	if err := reopened.CopySheet("Data", "Copy"); err != nil {
+		t.Fatalf("CopySheet failed: %v", err)
+	}
+	gif := []byte("GIF89a new logo")
+	if err := reopened.ReplaceImage("Data", "image1.png", gif); err != nil {
+		t.Fatalf("ReplaceImage failed: %v", err)
+	}
+	if err := reopened.DeleteImage("Data", "image2.png"); err != nil {
+		t.Fatalf("DeleteImage failed: %v", err)
+	}
+	if err := reopened.DeleteImage("Copy", "image2.png"); err != nil {
+		t.Fatalf("DeleteImage failed: %v", err)
+	}
+	images, _ = reopened.Images()
+	if len(images) != 2 || images[0].MIMEType != "image/gif" || !bytes.Equal(images[0].Data, gif) ||
+		images[0].Cell != "B2" || images[1].Sheet != "Copy" || images[1].Path != "xl/media/image1.png" {
+		t.Errorf("unexpected images after edits %+v", images)
+	}
+
+	buf.Reset()
+	if err := reopened.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	if _, ok := parts["xl/media/image2.png"]; ok {
+		t.Error("expected the unused image to be removed")
+	}
+	if _, ok := parts["xl/media/image1.png"]; !ok {
+		t.Error("expected the image still shown by the copy to be kept")
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], `Extension="gif" ContentType="image/gif"`) {
+		t.Error("expected a content type for the replacement image")
+	}
+
+	if err := reopened.DeleteImage("Data", "missing.png"); err == nil {
+		t.Error("expected an error for a missing image")
+	}
+	if err := reopened.ReplaceImage("Copy", "image1.png", []byte("not an image")); err == nil {
+		t.Error("expected an error for data that is not an image")
+	}
+}
+
+// croppedPicture is a picture as Excel writes it, with a crop, an outline, a
+// hyperlink and attributes and extensions the drawing structs do not model.
+const croppedPicture = `<xdr:pic><xdr:nvPicPr><xdr:cNvPr id="1" name="Cropped" descr="Company logo" title="Logo" hidden="1"><a:hlinkClick xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId3"/></xdr:cNvPr><xdr:cNvPicPr preferRelativeResize="0"><a:picLocks noChangeAspect="1" noCrop="1"/></xdr:cNvPicPr></xdr:nvPicPr>` +
+	`<xdr:blipFill><a:blip xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:embed="rId1" cstate="print"><a:extLst><a:ext uri="{28A0092B-C50C-407E-A947-70E740481C1C}"><a14:useLocalDpi xmlns:a14="http://schemas.microsoft.com/office/drawing/2010/main" val="0"/></a:ext></a:extLst></a:blip><a:srcRect l="25000"/><a:stretch><a:fillRect/></a:stretch></xdr:blipFill>` +
+	`<xdr:spPr bwMode="auto"><a:xfrm rot="5400000"><a:off x="0" y="0"/><a:ext cx="600000" cy="600000"/></a:xfrm><a:prstGeom prst="roundRect"><a:avLst><a:gd name="adj" fmla="val 8000"/></a:avLst></a:prstGeom><a:ln w="9525"><a:solidFill><a:srgbClr val="FF0000"/></a:solidFill></a:ln></xdr:spPr></xdr:pic>`
+
+// croppedPictureDocument returns a saved workbook whose sheet Data shows the
+// cropped picture at B2 and the picture image2.png at D5.
+func croppedPictureDocument(t *testing.T) []byte {
+	t.Helper()
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+	imgPath := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(imgPath, []byte("\x89PNG\r\n\x1a\n logo"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	sheet.InsertImage(imgPath, 1, 1).InsertImage(imgPath, 3, 4)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("InsertImage failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Save(t.Context(), &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	parts := zipParts(t, buf.Bytes())
+	drawing := parts["xl/drawings/drawing1.xml"]
+	start := strings.Index(drawing, "<xdr:pic>")
+	end := strings.Index(drawing, "</xdr:pic>") + len("</xdr:pic>")
+	if start < 0 || end < start {
+		t.Fatalf("drawing lacks a picture: %s", drawing)
+	}
+	data := replacePart(t, buf.Bytes(), "xl/drawings/drawing1.xml", drawing[:start]+croppedPicture+drawing[end:])
+	rels := parts["xl/drawings/_rels/drawing1.xml.rels"]
+	link := `<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com" TargetMode="External"></Relationship>`
+	return replacePart(t, data, "xl/drawings/_rels/drawing1.xml.rels", strings.Replace(rels, "</Relationships>", link+"</Relationships>", 1))
+}
+
+func TestImages_KeepUnmodelledPictureXML(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	if err := doc.Open(ctx, bytes.NewReader(croppedPictureDocument(t))); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := doc.ReplaceImage("Data", "Cropped", []byte("GIF89a new logo")); err != nil {
+		t.Fatalf("ReplaceImage failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	drawing := zipParts(t, buf.Bytes())["xl/drawings/drawing1.xml"]
+	for _, want := range []string{
+		`descr="Company logo" title="Logo" hidden="1"`,
+		`<a:hlinkClick r:id="rId3"`,
+		`<xdr:cNvPicPr preferRelativeResize="0"><a:picLocks noChangeAspect="1" noCrop="1"`,
+		`cstate="print"><a:extLst><a:ext uri="{28A0092B-C50C-407E-A947-70E740481C1C}">`,
+		`</a:blip><a:srcRect l="25000"`,
+		`<xdr:spPr bwMode="auto"><a:xfrm rot="5400000">`,
+		`<a:avLst><a:gd name="adj" fmla="val 8000"`,
+		`</a:prstGeom><a:ln w="9525"><a:solidFill><a:srgbClr val="FF0000"`,
+	} {
+		if !strings.Contains(drawing, want) {
+			t.Errorf("replaced picture lost %s: %s", want, drawing)
+		}
+	}
+}
+
+func TestImages_DeleteKeepsOtherPicturesIntact(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	if err := doc.Open(ctx, bytes.NewReader(croppedPictureDocument(t))); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := doc.DeleteImage("Data", "image2.png"); err != nil {
+		t.Fatalf("DeleteImage failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	drawing := zipParts(t, buf.Bytes())["xl/drawings/drawing1.xml"]
+	if !strings.Contains(drawing, croppedPicture) {
+		t.Errorf("cropped picture changed:\n%s\nwant\n%s", drawing, croppedPicture)
+	}
+	if strings.Contains(drawing, "image2.png") {
+		t.Error("deleted picture was written")
+	}
+}
+
+func TestImages_GroupedAndAbsolutePictures(t *testing.T) {
+	ctx := t.Context()
+	data := croppedPictureDocument(t)
+	picture := func(id int, name, rID string) string {
+		return fmt.Sprintf(`<xdr:pic><xdr:nvPicPr><xdr:cNvPr id="%d" name="%s"/><xdr:cNvPicPr/></xdr:nvPicPr><xdr:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></xdr:blipFill>`+
+			`<xdr:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="254000" cy="127000"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></xdr:spPr></xdr:pic>`, id, name, rID)
+	}
+	drawing := `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<xdr:twoCellAnchor><xdr:from><xdr:col>2</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>0</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from><xdr:to><xdr:col>4</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>3</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to>` +
+		`<xdr:grpSp><xdr:nvGrpSpPr><xdr:cNvPr id="5" name="Group"/><xdr:cNvGrpSpPr/></xdr:nvGrpSpPr><xdr:grpSpPr/>` +
+		`<xdr:sp><xdr:nvSpPr><xdr:cNvPr id="6" name="Label"/><xdr:cNvSpPr/></xdr:nvSpPr><xdr:spPr/></xdr:sp>` + picture(7, "Grouped", "rId1") + `</xdr:grpSp><xdr:clientData/></xdr:twoCellAnchor>` +
+		`<xdr:absoluteAnchor><xdr:pos x="700000" y="400000"/><xdr:ext cx="381000" cy="190500"/>` + picture(8, "Absolute", "rId2") + `<xdr:clientData/></xdr:absoluteAnchor></xdr:wsDr>`
+	data = replacePart(t, data, "xl/drawings/drawing1.xml", drawing)
+
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	if err := doc.Open(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	images, err := doc.Images()
+	if err != nil {
+		t.Fatalf("Images failed: %v", err)
+	}
+	if len(images) != 2 || images[0].Name != "Grouped" || images[0].Cell != "C1" || images[0].Width != 20 ||
+		images[1].Name != "Absolute" || images[1].Cell != "B3" || images[1].Width != 30 || images[1].Height != 15 {
+		t.Fatalf("unexpected images %+v", images)
+	}
+
+	if err := doc.DeleteImage("Data", "Grouped"); err != nil {
+		t.Fatalf("DeleteImage failed: %v", err)
+	}
+	if err := doc.ReplaceImage("Data", "Absolute", []byte("GIF89a new logo")); err != nil {
+		t.Fatalf("ReplaceImage failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	out := zipParts(t, buf.Bytes())["xl/drawings/drawing1.xml"]
+	if strings.Contains(out, "Grouped") || !strings.Contains(out, `<xdr:cNvPr id="6" name="Label"/>`) {
+		t.Errorf("expected the picture alone to leave its group: %s", out)
+	}
+	if !strings.Contains(out, `<xdr:absoluteAnchor><xdr:pos x="700000" y="400000"></xdr:pos>`) || !strings.Contains(out, `name="Absolute"`) {
+		t.Errorf("expected the absolute anchor to be kept: %s", out)
+	}
+	images, _ = doc.Images()
+	if len(images) != 1 || images[0].MIMEType != "image/gif" {
+		t.Errorf("unexpected images after edits %+v", images)
+	}
+}
@@ -0,0 +1,108 @@
+package excel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		code  string
+		want  string
+	}{
+		{1234.5, "General", "1234.5"},
+		{1234.5, "0", "1235"},
+		{-1234.5, "#,##0.00", "-1,234.50"},
+		{0.5, "#.00", ".50"},
+		{1234567.891, "#,##0.0,,\"M\"", "1.2M"},
+		{12, "000-00", "000-12"},
+		{5, "?0.0?", " 5.0 "},
+		{0.125, "0.0%", "12.5%"},
+		{-5, "0.00;[Red](0.00)", "(5.00)"},
+		{0, "0.00;(0.00);\"zero\"", "zero"},
+		{150, "[>100][Blue]\"big\";[<=100]0", "big"},
+		{-150, "[>100]\"big\";0", "-150"},
+		{1234.5, `"$"#,##0.00_);\("$"#,##0.00\)`, "$1,234.50 "},
+		{-1234.5, `"$"#,##0.00_);\("$"#,##0.00\)`, "($1,234.50)"},
+		{1234.5, "[$€-407]#,##0.00", "€1,234.50"},
+		{0, `_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`, " - "},
+		{12345.678, "0.00E+00", "1.23E+04"},
+		{0.000123, "0.0E+0", "1.2E-4"},
+		{12345, "##0.0E+0", "12.3E+3"},
+		{1.5, "# ?/?", "1 1/2"},
+		{0.3333, "# ???/???", "   1/3  "},
+		{3, "# ?/?", "3    "},
+		{2.7, "# ?/4", "2 3/4"},
+		{0.75, "?/?", "3/4"},
+		{45366, "yyyy-mm-dd", "2024-03-15"},
+		{45366, "mm-dd-yy", "03-15-24"},
+		{45366, "dddd, mmmm d, yyyy", "Friday, March 15, 2024"},
+		{45366, "d-mmm-yy", "15-Mar-24"},
+		{45366, "mmmmm", "M"},
+		{45366.75, "h:mm AM/PM", "6:00 PM"},
+		{45366.354166666664, "yyyy-mm-dd hh:mm:ss", "2024-03-15 08:30:00"},
+		{0.0208333, "[h]:mm:ss", "0:30:00"},
+		{1.5, "[h]:mm", "36:00"},
+		{0.00001, "mm:ss.00", "00:00.86"},
+		{45366.5, "m/d/yy h:mm", "3/15/24 12:00"},
+		{45366, "yyyy\"年\"m\"月\"d\"日\"", "2024年3月15日"},
+		{-1, "yyyy-mm-dd", "-1"},
+	}
+	for _, tt := range tests {
+		if got := formula.FormatValue(tt.value, tt.code, false); got != tt.want {
+			t.Errorf("FormatValue(%v, %q) = %q, want %q", tt.value, tt.code, got, tt.want)
+		}
+	}
+
+	if got := formula.FormatValue(0, "yyyy-mm-dd", true); got != "1904-01-01" {
+		t.Errorf("Expected the 1904 date system to start on 1904-01-01, got %q", got)
+	}
+	if got := formula.FormatText("Ann", `0;-0;0;"Name: "@`); got != "Name: Ann" {
+		t.Errorf("Expected the text section to apply, got %q", got)
+	}
+	if got := formula.FormatColor(-1, "0;[Red]-0"); got != "Red" {
+		t.Errorf("Expected the negative section color, got %q", got)
+	}
+}
+
+func TestCellFormatted(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("A1").Set(45366.5).Style(document.CellStyle{NumberFormat: "yyyy-mm-dd hh:mm"})
+	sheet.Cell("A2").Set(1234.5).Style(document.CellStyle{NumberFormat: "#,##0.00"})
+	sheet.Cell("A3").Formula("A2*2").Style(document.CellStyle{NumberFormat: `"$"#,##0`})
+	sheet.Cell("A4").Set(true)
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+	for axis, want := range map[string]string{"A1": "2024-03-15 12:00", "A2": "1,234.50", "A3": "$2,469", "A4": "TRUE", "A5": ""} {
+		if got, err := s.Cell(axis).Formatted(); err != nil || got != want {
+			t.Errorf("Cell %s: expected %q, got %q (%v)", axis, want, got, err)
+		}
+	}
+
+	content, err := reopened.ReadContent()
+	if err != nil {
+		t.Fatalf("ReadContent failed: %v", err)
+	}
+	if want := "2024-03-15 12:00 1,234.50 $2,469 TRUE"; content != want {
+		t.Errorf("Expected content %q, got %q", want, content)
+	}
+}
@@ -0,0 +1,561 @@
+package excel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+	"github.com/gsoultan/thoth/internal/fontmetrics"
+	"github.com/gsoultan/thoth/pdf"
+)
+
+const (
+	pointsPerIndent = 9.0 // Width of an indent level, three digits of the default font
+	defaultFontSize = 11
+)
+
+// paperTypes maps the paperSize of a page setup to the papers the PDF writer lays out.
+var paperTypes = map[int]document.PaperType{1: document.PaperLetter, 5: document.PaperLegal, 9: document.PaperA4}
+
+// paperPoints holds the portrait width and height of each paper in points.
+var paperPoints = map[document.PaperType][2]float64{
+	document.PaperLetter: {612, 792},
+	document.PaperLegal:  {612, 1008},
+	document.PaperA4:     {595, 842},
+}
+
+// pdfProcessor lays sheets out as PDF tables.
+type pdfProcessor struct{ *state }
+
+func (e *pdfProcessor) exportPDF(w io.Writer, opts document.PDFOptions) error {
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = e.exportedSheets()
+	}
+
+	doc := pdf.NewDocument()
+	wp := doc.(document.WordProcessor)
+	pages := 0
+	now := time.Now()
+	var first pdfPage
+	for i, name := range sheets {
+		ws, err := (&sheetProcessor{e.state}).fittableSheet(name)
+		if err != nil {
+			return err
+		}
+		// The PDF writer has one page setup, header and footer for the whole document.
+		page := newPDFPage(ws, name, now)
+		if i == 0 {
+			if err := setPDFPage(wp, page); err != nil {
+				return err
+			}
+			first = page
+		} else if !reflect.DeepEqual(page, first) {
+			return fmt.Errorf("sheet %s: page setup, header or footer differs from sheet %s; export the sheets separately", name, sheets[0])
+		}
+		width := page.width - page.settings.Margins.Left - page.settings.Margins.Right
+		height := page.height - page.settings.Margins.Top - page.settings.Margins.Bottom
+		n, err := e.addSheetPages(wp, name, ws, opts, width, height, pages > 0)
+		if err != nil {
+			return fmt.Errorf("sheet %s: %w", name, err)
+		}
+		pages += n
+	}
+
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return doc.Save(ctx, w)
+}
+
+// pdfPageSettings returns the paper, orientation and margins of a sheet, and
+// the size of its pages in points.
+func pdfPageSettings(ws *xmlstructs.Worksheet) (document.PageSettings, float64, float64) {
+	settings := document.PageSettings{
+		PaperType:   document.PaperLetter,
+		Orientation: document.OrientationPortrait,
+		// Excel's normal margins, in inches
+		Margins: document.Margins{Left: 0.7, Right: 0.7, Top: 0.75, Bottom: 0.75},
+	}
+	if ps := ws.PageSetup; ps != nil {
+		if paper, ok := paperTypes[ps.PaperSize]; ok {
+			settings.PaperType = paper
+		}
+		if ps.Orientation == "landscape" {
+			settings.Orientation = document.OrientationLandscape
+		}
+	}
+	if pm := ws.PageMargins; pm != nil {
+		settings.Margins = document.Margins{Left: pm.Left, Right: pm.Right, Top: pm.Top, Bottom: pm.Bottom}
+	}
+	m := &settings.Margins
+	m.Left, m.Right, m.Top, m.Bottom = m.Left*72, m.Right*72, m.Top*72, m.Bottom*72
+
+	size := paperPoints[settings.PaperType]
+	if settings.Orientation == document.OrientationLandscape {
+		size[0], size[1] = size[1], size[0]
+	}
+	return settings, size[0], size[1]
+}
+
+// pdfPage is the page setup of a sheet: its paper, orientation and margins,
+// the size of its pages in points, and its header and footer.
+type pdfPage struct {
+	settings       document.PageSettings
+	width, height  float64
+	header, footer []headerSection
+}
+
+func newPDFPage(ws *xmlstructs.Worksheet, sheet string, now time.Time) pdfPage {
+	var page pdfPage
+	page.settings, page.width, page.height = pdfPageSettings(ws)
+	if ws.HeaderFooter != nil {
+		page.header = headerFooterSections(ws.HeaderFooter.OddHeader, sheet, now)
+		page.footer = headerFooterSections(ws.HeaderFooter.OddFooter, sheet, now)
+	}
+	return page
+}
+
+// setPDFPage applies a page setup, header and footer to the PDF document.
+func setPDFPage(wp document.WordProcessor, page pdfPage) error {
+	if err := wp.SetPageSettings(page.settings); err != nil {
+		return err
+	}
+	for _, section := range page.header {
+		if err := wp.SetHeader(section.text, section.style); err != nil {
+			return err
+		}
+	}
+	for _, section := range page.footer {
+		if err := wp.SetFooter(section.text, section.style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type headerSection struct {
+	text  string
+	style document.CellStyle
+}
+
+// headerFooterSections splits an Excel header or footer into its left, center
+// and right sections. Page numbers, the page count, the date, the time and the
+// sheet name replace their codes, and the font codes set the section's style.
+func headerFooterSections(text, sheet string, now time.Time) []headerSection {
+	sections := make([]headerSection, 3)
+	for i, align := range []string{"left", "center", "right"} {
+		sections[i].style = document.CellStyle{Horizontal: align, Size: defaultFontSize}
+	}
+	current := 1
+	var sb [3]strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '&' || i+1 == len(text) {
+			sb[current].WriteByte(text[i])
+			continue
+		}
+		i++
+		style := &sections[current].style
+		switch code := text[i]; {
+		case code == '&':
+			sb[current].WriteByte('&')
+		case code == 'L' || code == 'C' || code == 'R':
+			current = strings.IndexByte("LCR", code)
+		case code == 'P':
+			sb[current].WriteString("{n}")
+		case code == 'N':
+			sb[current].WriteString("{nb}")
+		case code == 'D':
+			sb[current].WriteString(now.Format(time.DateOnly))
+		case code == 'T':
+			sb[current].WriteString(now.Format("15:04"))
+		case code == 'A':
+			sb[current].WriteString(sheet)
+		case code == 'B':
+			style.Bold = !style.Bold
+		case code == 'I':
+			style.Italic = !style.Italic
+		case code == 'K':
+			if i+6 < len(text) {
+				if _, err := strconv.ParseUint(text[i+1:i+7], 16, 32); err == nil {
+					style.Color = text[i+1 : i+7]
+				}
+				i += 6
+			}
+		case code == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				end = len(text) - i - 1
+			}
+			if _, font, ok := strings.Cut(text[i+1:i+1+end], ","); ok {
+				style.Bold = strings.Contains(font, "Bold")
+				style.Italic = strings.Contains(font, "Italic")
+			}
+			i += end + 1
+		case code >= '0' && code <= '9':
+			j := i
+			for j < len(text) && text[j] >= '0' && text[j] <= '9' {
+				j++
+			}
+			style.Size, _ = strconv.Atoi(text[i:j])
+			i = j - 1
+		}
+		// Other codes, such as the file name, pictures and underlines, are dropped.
+	}
+	var out []headerSection
+	for i := range sections {
+		if t := strings.TrimSpace(sb[i].String()); t != "" {
+			sections[i].text = t
+			out = append(out, sections[i])
+		}
+	}
+	return out
+}
+
+// printAreas returns the areas of a sheet to print: its print areas, or the
+// range holding its values and formatted cells.
+func (e *pdfProcessor) printAreas(s *sheetLayout, opts document.PDFOptions) []cellArea {
+	used, found := s.usedRange()
+
+	var areas []cellArea
+	if !opts.IgnorePrintArea {
+		for _, ref := range e.sheetNameRefs("_xlnm.Print_Area", s.name) {
+			a := cellArea{ref.Col1, ref.Row1, ref.Col2, ref.Row2}
+			if a.col1 == 0 {
+				a.col1, a.col2 = 1, max(used.col2, 1)
+			}
+			if a.row1 == 0 {
+				a.row1, a.row2 = 1, max(used.row2, 1)
+			}
+			areas = append(areas, a)
+		}
+	}
+	if len(areas) == 0 && found {
+		areas = append(areas, used)
+	}
+	return areas
+}
+
+// sheetScale returns the factor a sheet is printed at: its print scale, or the
+// factor that fits its areas into the pages wide and tall its page setup asks for.
+func sheetScale(s *sheetLayout, areas []cellArea, width, height float64) float64 {
+	ps := s.ws.PageSetup
+	if ps == nil {
+		return 1
+	}
+	fit := s.ws.SheetPr != nil && s.ws.SheetPr.PageSetUpPr != nil && s.ws.SheetPr.PageSetUpPr.FitToPage == 1
+	if !fit {
+		if ps.Scale > 0 {
+			return float64(ps.Scale) / 100
+		}
+		return 1
+	}
+
+	totalWidth, totalHeight := 0.0, 0.0
+	for _, a := range areas {
+		w := 0.0
+		for _, col := range s.titleCols {
+			if col < a.col1 {
+				w += s.colWidth(col)
+			}
+		}
+		for col := a.col1; col <= a.col2; col++ {
+			w += s.colWidth(col)
+		}
+		totalWidth = max(totalWidth, w)
+		for row := a.row1; row <= a.row2; row++ {
+			totalHeight += s.rowHeight(row)
+		}
+	}
+	scale := 1.0
+	if pages := fitPages(ps.FitToWidth); pages > 0 && totalWidth > 0 {
+		scale = min(scale, float64(pages)*width/totalWidth)
+	}
+	if pages := fitPages(ps.FitToHeight); pages > 0 && totalHeight > 0 {
+		scale = min(scale, float64(pages)*height/totalHeight)
+	}
+	return scale
+}
+
+// fitPages returns the pages a sheet is fitted to, which default to one; 0
+// leaves that direction unconstrained.
+func fitPages(pages *int) int {
+	if pages == nil {
+		return 1
+	}
+	return *pages
+}
+
+// splitBreaks splits rows, or columns, after each manual page break.
+func splitBreaks(items []int, breaks *xmlstructs.PageBreaks) [][]int {
+	var groups [][]int
+	start := 0
+	for i := range items {
+		if i > start && breaks != nil && slices.ContainsFunc(breaks.Items, func(b xmlstructs.Break) bool {
+			return b.ID >= items[i-1] && b.ID < items[i]
+		}) {
+			groups = append(groups, items[start:i])
+			start = i
+		}
+	}
+	return append(groups, items[start:])
+}
+
+// addSheetPages lays the print areas of a sheet out as tables, down each
+// column of pages and then across, as Excel prints. It returns the number of
+// tables added; the first starts a new page when newPage is set.
+func (e *pdfProcessor) addSheetPages(wp document.WordProcessor, name string, ws *xmlstructs.Worksheet, opts document.PDFOptions, width, height float64, newPage bool) (int, error) {
+	s := e.newSheetLayout(name, ws)
+	areas := e.printAreas(s, opts)
+	s.scale = sheetScale(s, areas, width, height)
+
+	added := 0
+	for _, a := range areas {
+		rows := visible(a.row1, a.row2, s.rowHeight)
+		cols := visible(a.col1, a.col2, s.colWidth)
+		if len(rows) == 0 || len(cols) == 0 {
+			continue
+		}
+		for _, colGroup := range splitBreaks(cols, ws.ColBreaks) {
+			for _, pageCols := range s.fitColumns(colGroup, width) {
+				for _, pageRows := range splitBreaks(rows, ws.RowBreaks) {
+					if newPage || added > 0 {
+						if err := wp.AddPageBreak(); err != nil {
+							return added, err
+						}
+					}
+					if err := e.addSheetTable(wp, s, pageRows, pageCols); err != nil {
+						return added, err
+					}
+					added++
+				}
+			}
+		}
+	}
+	return added, nil
+}
+
+// fitColumns splits columns into runs that fit the width of a page next to the
+// title columns printed before them.
+func (s *sheetLayout) fitColumns(cols []int, width float64) [][]int {
+	var pages [][]int
+	for len(cols) > 0 {
+		used := 0.0
+		for _, col := range s.titleCols {
+			if col < cols[0] {
+				used += s.colWidth(col)
+			}
+		}
+		n := 0
+		for n < len(cols) && (n == 0 || used+s.colWidth(cols[n]) <= width+0.5) {
+			used += s.colWidth(cols[n])
+			n++
+		}
+		pages = append(pages, cols[:n])
+		cols = cols[n:]
+	}
+	return pages
+}
+
+// withTitles puts the titles printed before items in front of them, and
+// returns how many of the leading items are titles.
+func withTitles(titles, items []int) ([]int, int) {
+	var out []int
+	for _, t := range titles {
+		if t < items[0] {
+			out = append(out, t)
+		}
+	}
+	out = append(out, items...)
+	n := 0
+	for n < len(out) && slices.Contains(titles, out[n]) {
+		n++
+	}
+	return out, n
+}
+
+// rowHeightSetter is implemented by PDF tables, which take fixed row heights
+// outside of document.Table.
+type rowHeightSetter interface {
+	SetRowHeights(heights ...float64) document.Table
+}
+
+// addSheetTable adds a table holding rows and columns of a sheet, with the sheet's
+// title rows and columns.
+func (e *pdfProcessor) addSheetTable(wp document.WordProcessor, s *sheetLayout, rows, cols []int) error {
+	rows, headerRows := withTitles(s.titleRows, rows)
+	cols, _ = withTitles(s.titleCols, cols)
+
+	tbl, err := wp.AddTable(len(rows), len(cols))
+	if err != nil {
+		return err
+	}
+	widths := make([]float64, len(cols))
+	for j, col := range cols {
+		widths[j] = s.colWidth(col)
+	}
+	heights := make([]float64, len(rows))
+	for i, row := range rows {
+		heights[i] = s.rowHeight(row)
+	}
+	tbl.SetColumnWidths(widths...).SetHeaderRows(headerRows)
+	if fixed, ok := tbl.(rowHeightSetter); ok {
+		fixed.SetRowHeights(heights...)
+	}
+
+	merges := s.merges(rows, cols)
+	covered := merges.covered
+
+	type content struct {
+		text  string
+		style document.CellStyle
+		cell  *xmlstructs.Cell
+	}
+	grid := make([][]content, len(rows))
+	for i, row := range rows {
+		grid[i] = make([]content, len(cols))
+		for j, col := range cols {
+			key := [2]int{col, row}
+			if anchor, ok := merges.anchors[[2]int{i, j}]; ok {
+				key = anchor
+			}
+			c := content{cell: s.cells[key]}
+			c.style = e.pdfCellStyle(s, c.cell)
+			if c.cell != nil {
+				c.text = e.formattedValue(*c.cell)
+			}
+			grid[i][j] = c
+		}
+	}
+
+	// The table wraps text to its cells, so text that Excel lets run on into
+	// the empty cells to its right spans them instead.
+	for i := range grid {
+		for j := 0; j < len(grid[i]); j++ {
+			c := grid[i][j]
+			if _, merged := covered[[2]int{i, j}]; merged || c.text == "" || c.style.WrapText || c.style.Horizontal != "left" || slices.Contains([]string{"", "n", "d"}, c.cell.T) {
+				continue
+			}
+			need := fontmetrics.Width(c.text, pdfFontName(c.style), float64(c.style.Size)) + 2*c.style.Padding + c.style.Indent
+			have, n := widths[j], 1
+			for have < need && j+n < len(cols) {
+				next := grid[i][j+n]
+				if _, merged := covered[[2]int{i, j + n}]; merged || next.text != "" || hasFill(next.style) {
+					break
+				}
+				have += widths[j+n]
+				n++
+			}
+			if n > 1 {
+				for k := 1; k < n; k++ {
+					covered[[2]int{i, j + k}] = true
+				}
+				merges.spans = append(merges.spans, mergeSpan{i, j, 1, n})
+			}
+			j += n - 1
+		}
+	}
+
+	for i := range grid {
+		for j, c := range grid[i] {
+			if covered[[2]int{i, j}] {
+				continue
+			}
+			tc := tbl.Row(i).Cell(j)
+			switch {
+			case c.text != "":
+				tc.AddParagraph(c.text, c.style)
+			case hasFill(c.style):
+				tc.Style(c.style)
+			}
+			if err := tc.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, sp := range merges.spans {
+		tbl.MergeCells(sp.row, sp.col, sp.rows, sp.cols)
+	}
+	return tbl.Err()
+}
+
+// hasFill reports whether a cell style draws a background or borders.
+func hasFill(style document.CellStyle) bool {
+	return style.Background != "" || style.Border || style.BorderTop || style.BorderBottom || style.BorderLeft || style.BorderRight
+}
+
+// pdfFontName returns the standard font the PDF writer prints a style with.
+func pdfFontName(style document.CellStyle) string {
+	switch {
+	case style.Bold && style.Italic:
+		return "Helvetica-BoldOblique"
+	case style.Bold:
+		return "Helvetica-Bold"
+	case style.Italic:
+		return "Helvetica-Oblique"
+	}
+	return "Helvetica"
+}
+
+// pdfCellStyle converts the format of a cell to the style of a PDF table cell,
+// scaled as the sheet is printed. Cells without an alignment are aligned as
+// Excel shows them: numbers to the right and at the bottom.
+func (e *pdfProcessor) pdfCellStyle(s *sheetLayout, cell *xmlstructs.Cell) document.CellStyle {
+	var style document.CellStyle
+	if cell != nil {
+		style = (&styleProcessor{e.state}).styleFromXf(cell.S)
+	}
+	// The PDF writer prints with its own fonts.
+	style.Font, style.NumberFormat = "", ""
+	style.Size = max(1, int(math.Round(float64(cmp0(style.Size, defaultFontSize))*s.scale)))
+	style.BorderWidth *= 0.5 * s.scale
+	style.Indent *= pointsPerIndent * s.scale
+	style.Padding = 2 * s.scale
+
+	switch style.Horizontal {
+	case "", "general":
+		style.Horizontal = "left"
+		if cell != nil {
+			switch cell.T {
+			case "", "n", "d":
+				style.Horizontal = "right"
+			case "b", "e":
+				style.Horizontal = "center"
+			}
+		}
+	case "centerContinuous":
+		style.Horizontal = "center"
+	case "distributed":
+		style.Horizontal = "justify"
+	case "fill":
+		style.Horizontal = "left"
+	}
+	switch style.Vertical {
+	case "":
+		style.Vertical = "bottom"
+	case "justify", "distributed":
+		style.Vertical = "top"
+	}
+
+	if po := s.ws.PrintOptions; po != nil && po.GridLines == 1 && !style.BorderTop && !style.BorderBottom && !style.BorderLeft && !style.BorderRight {
+		style.Border, style.BorderColor, style.BorderWidth = true, "C0C0C0", 0.25*s.scale
+	}
+	return style
+}
+
+// cmp0 returns v, or def when v is 0.
+func cmp0(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,189 @@
+package excel
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/pdf"
+)
+
+// pdfPages returns the decompressed content streams of a PDF, one per page.
+func pdfPages(t *testing.T, data []byte) []string {
+	t.Helper()
+	var pages []string
+	for {
+		start := bytes.Index(data, []byte("stream\n"))
+		if start < 0 {
+			return pages
+		}
+		data = data[start+len("stream\n"):]
+		end := bytes.Index(data, []byte("\nendstream"))
+		if end < 0 {
+			t.Fatal("unterminated stream")
+		}
+		if r, err := zlib.NewReader(bytes.NewReader(data[:end])); err == nil {
+			content, _ := io.ReadAll(r)
+			if bytes.Contains(content, []byte("BT")) {
+				pages = append(pages, string(content))
+			}
+		}
+		data = data[end+len("\nendstream"):]
+	}
+}
+
+func TestExportPDF_Layout(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	bold := document.CellStyle{Bold: true, Background: "DDEBF7", Border: true, BorderWidth: 1}
+	sheet.Cell("A1").Set("Quarterly Sales")
+	sheet.MergeCells("A1:C1")
+	sheet.Cell("A2").Set("Region").Style(bold)
+	sheet.Cell("B2").Set("Units").Style(bold)
+	sheet.Cell("C2").Set("Revenue").Style(bold)
+	for i := range 60 {
+		row := i + 3
+		sheet.Cell(fmt.Sprintf("A%d", row)).Set(fmt.Sprintf("Region %d", i+1))
+		sheet.Cell(fmt.Sprintf("B%d", row)).Set(i * 10)
+		sheet.Cell(fmt.Sprintf("C%d", row)).Set(float64(i) * 2.5).Style(document.CellStyle{NumberFormat: "#,##0.00"})
+	}
+	sheet.Cell("E1").Set("Outside the print area")
+	sheet.SetColumnWidth(1, 20).
+		SetPrintArea("A1:C62").
+		SetPrintTitles("$2:$2", "").
+		InsertRowBreak(33).
+		SetHeader("&L&BSales&R&A").
+		SetFooter("&CPage &P of &N")
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("sheet setup failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.ExportPDF(&buf, document.PDFOptions{}); err != nil {
+		t.Fatalf("ExportPDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Fatalf("output is not a PDF: %q", buf.Bytes()[:min(buf.Len(), 16)])
+	}
+	pages := pdfPages(t, buf.Bytes())
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2 split at the row break", len(pages))
+	}
+	for _, want := range []string{"(Quarterly Sales)", "(Region 1)", "(Region 30)", "(72.50)", "(Sales)", "(Data)", "(Page 1 of 2)"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("first page lacks %s", want)
+		}
+	}
+	for _, want := range []string{"(Revenue)", "(Region 31)", "(Region 60)", "(147.50)", "(Page 2 of 2)"} {
+		if !strings.Contains(pages[1], want) {
+			t.Errorf("second page lacks %s", want)
+		}
+	}
+	if strings.Contains(pages[1], "(Region 30)") || strings.Contains(pages[0]+pages[1], "Outside") {
+		t.Error("cells outside the page or the print area were printed")
+	}
+
+	buf.Reset()
+	if err := doc.ExportPDF(&buf, document.PDFOptions{IgnorePrintArea: true}); err != nil {
+		t.Fatalf("ExportPDF without the print area failed: %v", err)
+	}
+	if pages := pdfPages(t, buf.Bytes()); !strings.Contains(strings.Join(pages, ""), "(Outside") {
+		t.Error("the used range was not printed when ignoring the print area")
+	}
+
+	if err := doc.ExportPDF(&buf, document.PDFOptions{Sheets: []string{"Missing"}}); err == nil {
+		t.Error("expected an error for a missing sheet")
+	}
+
+	// Row heights reach the PDF tables through rowHeightSetter only.
+	tbl, err := pdf.NewDocument().(document.WordProcessor).AddTable(1, 1)
+	if _, ok := tbl.(rowHeightSetter); err != nil || !ok {
+		t.Errorf("PDF table %T (%v) does not take row heights", tbl, err)
+	}
+}
+
+func TestExportPDF_SheetsShareThePageSetup(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+	other, _ := doc.Sheet("Other")
+	for _, s := range []document.Sheet{sheet, other} {
+		s.Cell("A1").Set("value")
+		s.SetFooter("&CPage &P")
+	}
+	if err := doc.ExportPDF(&bytes.Buffer{}, document.PDFOptions{}); err != nil {
+		t.Fatalf("ExportPDF of sheets with the same page setup failed: %v", err)
+	}
+
+	landscape := document.PageSettings{Orientation: document.OrientationLandscape, PaperType: document.PaperA4}
+	other.SetPageSettings(landscape)
+	if err := doc.ExportPDF(&bytes.Buffer{}, document.PDFOptions{}); err == nil || !strings.Contains(err.Error(), "Other") {
+		t.Errorf("expected an error for sheets with different page setups, got %v", err)
+	}
+	if err := doc.ExportPDF(&bytes.Buffer{}, document.PDFOptions{Sheets: []string{"Other"}}); err != nil {
+		t.Errorf("ExportPDF of one sheet failed: %v", err)
+	}
+
+	sheet.SetPageSettings(landscape)
+	if err := doc.ExportPDF(&bytes.Buffer{}, document.PDFOptions{}); err != nil {
+		t.Fatalf("ExportPDF of sheets with the same page setup failed: %v", err)
+	}
+	sheet.SetHeader("&A")
+	other.SetHeader("&A")
+	if err := doc.ExportPDF(&bytes.Buffer{}, document.PDFOptions{}); err == nil {
+		t.Error("expected an error for headers naming different sheets")
+	}
+}
+
+func TestExportPDF_FitToWidth(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+	for col := 1; col <= 30; col++ {
+		sheet.Cell(formula.CellName(col, 1)).Set(fmt.Sprintf("Column %d", col))
+	}
+	sheet.SetColumnWidth(30, 0)
+
+	export := func() []string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := doc.ExportPDF(&buf, document.PDFOptions{}); err != nil {
+			t.Fatalf("ExportPDF failed: %v", err)
+		}
+		return pdfPages(t, buf.Bytes())
+	}
+	if pages := export(); len(pages) < 3 {
+		t.Errorf("got %d pages at full size, want the columns spread over several", len(pages))
+	}
+
+	sheet.SetPrintOptions(document.PrintOptions{FitToWidth: 1})
+	pages := export()
+	if len(pages) != 1 {
+		t.Fatalf("got %d pages fitted to one page wide, want 1", len(pages))
+	}
+	if !strings.Contains(pages[0], "(Column 29)") || strings.Contains(pages[0], "(Column 30)") {
+		t.Error("fitted page should hold every shown column and skip the hidden one")
+	}
+}
+
+func TestHeaderFooterSections(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	got := headerFooterSections(`&L&"Arial,Bold"&14Report && Notes&C&D &T&R&KFF0000&I&A &P/&N&F`, "Data", now)
+	want := []headerSection{
+		{"Report & Notes", document.CellStyle{Horizontal: "left", Size: 14, Bold: true}},
+		{"2026-03-14 09:30", document.CellStyle{Horizontal: "center", Size: defaultFontSize}},
+		{"Data {n}/{nb}", document.CellStyle{Horizontal: "right", Size: defaultFontSize, Color: "FF0000", Italic: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("headerFooterSections = %+v, want %+v", got, want)
+	}
+	if got := headerFooterSections("Plain", "Data", now); len(got) != 1 || got[0].text != "Plain" || got[0].style.Horizontal != "center" {
+		t.Errorf("text without sections = %+v, want it centered", got)
+	}
+}
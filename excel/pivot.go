@@ -0,0 +1,956 @@
+package excel
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const (
+	pivotTableRelType        = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable"
+	pivotCacheRelType        = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition"
+	pivotRecordsRelType      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords"
+	pivotTableContentType    = "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotTable+xml"
+	pivotCacheContentType    = "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheDefinition+xml"
+	pivotRecordsContentType  = "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheRecords+xml"
+	defaultPivotStyle        = "PivotStyleLight16"
+	valuesField              = -2 // Field index of the Values pseudo-field on an axis
+	pivotVersion             = 6  // Excel 2016
+	pivotMinRefreshVersion   = 3
+	pivotBlankLabel          = "(blank)"
+	pivotGrandTotalLabel     = "Grand Total"
+	pivotAllItemsLabel       = "(All)"
+	pivotMultipleItemsLabel  = "(Multiple Items)"
+	pivotSubtotalLabelSuffix = " Total"
+)
+
+type pivotProcessor struct{ *state }
+
+// pivotField is a source column with its distinct values. Shared items keep the order in
+// which values first appear in the source; the pivot table lists them sorted.
+type pivotField struct {
+	name     string
+	values   []formula.Value // Column values, one per record
+	items    []formula.Value // Shared items; only enumerated for fields on an axis
+	shared   []int           // Shared item index per record
+	order    []int           // Shared item indexes in display order
+	position []int           // Shared item index -> display position
+}
+
+// pivotData is a value field of the pivot table.
+type pivotData struct {
+	field   int
+	fn      document.PivotAggregation
+	caption string
+	styleID int
+}
+
+// pivotLine is a line of the row or column area: a combination of item positions,
+// a subtotal of a prefix of it, or the grand total.
+type pivotLine struct {
+	kind    string // "", "default" or "grand"
+	members []int
+	data    int
+}
+
+// pivotAggregate accumulates the values of a data field for one cell of the pivot table.
+type pivotAggregate struct {
+	count, nums      int
+	sum, sumSq, prod float64
+	minimum, maximum float64
+}
+
+func (e *pivotProcessor) addPivotTable(sheet, sourceRef, targetCell string, spec document.PivotSpec) error {
+	if !e.hasSheet(sheet) {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
+	if len(spec.Rows) == 0 {
+		return fmt.Errorf("pivot table requires at least one row field")
+	}
+	if len(spec.Values) == 0 {
+		return fmt.Errorf("pivot table requires at least one value field")
+	}
+
+	src, err := formula.ParseReference(sourceRef)
+	if err != nil || !src.IsRange || src.Col1 == 0 || src.Row1 == 0 {
+		return fmt.Errorf("invalid pivot source range %q", sourceRef)
+	}
+	if src.Sheet == "" {
+		src.Sheet = sheet
+	}
+	target, err := formula.ParseReference(targetCell)
+	if err != nil || !target.IsCell() || target.Col1 == 0 || target.Row1 == 0 {
+		return fmt.Errorf("invalid pivot target cell %q", targetCell)
+	}
+	fields, err := e.pivotFields(src)
+	if err != nil {
+		return err
+	}
+	lookup := func(name string) (int, error) {
+		for i, f := range fields {
+			if strings.EqualFold(f.name, name) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("pivot field %q not found in %s", name, sourceRef)
+	}
+
+	// Resolve the fields placed on each axis.
+	axis := make(map[int]string)
+	place := func(names []string, kind string) ([]int, error) {
+		var idx []int
+		for _, name := range names {
+			i, err := lookup(name)
+			if err != nil {
+				return nil, err
+			}
+			if axis[i] != "" {
+				return nil, fmt.Errorf("pivot field %q is used on more than one axis", name)
+			}
+			axis[i] = kind
+			idx = append(idx, i)
+		}
+		return idx, nil
+	}
+	rowFields, err := place(spec.Rows, "axisRow")
+	if err != nil {
+		return err
+	}
+	colFields, err := place(spec.Columns, "axisCol")
+	if err != nil {
+		return err
+	}
+	var filterNames []string
+	for _, f := range spec.Filters {
+		filterNames = append(filterNames, f.Field)
+	}
+	pageFields, err := place(filterNames, "axisPage")
+	if err != nil {
+		return err
+	}
+	for i := range axis {
+		fields[i].enumerate()
+	}
+
+	data := make([]pivotData, len(spec.Values))
+	captions := make(map[string]bool)
+	for _, f := range fields {
+		captions[strings.ToLower(f.name)] = true
+	}
+	for i, v := range spec.Values {
+		fld, err := lookup(v.Field)
+		if err != nil {
+			return err
+		}
+		fn := v.Aggregation
+		if fn == "" {
+			fn = document.AggregateSum
+		}
+		label, ok := aggregationLabels[fn]
+		if !ok {
+			return fmt.Errorf("unsupported pivot aggregation %q", fn)
+		}
+		caption := v.Name
+		if caption == "" {
+			caption = label + " of " + fields[fld].name
+		}
+		for n := 2; captions[strings.ToLower(caption)]; n++ {
+			caption = strings.TrimRight(caption, "0123456789") + strconv.Itoa(n)
+		}
+		captions[strings.ToLower(caption)] = true
+		data[i] = pivotData{field: fld, fn: fn, caption: caption}
+		if v.NumberFormat != "" {
+			data[i].styleID = e.processor().getStyleID(document.CellStyle{NumberFormat: v.NumberFormat})
+		}
+	}
+
+	// Report filters select the records that are summarized.
+	hidden := make(map[int][]bool)
+	selected := make([]bool, len(fields[0].values))
+	for i := range selected {
+		selected[i] = true
+	}
+	for i, filter := range spec.Filters {
+		if len(filter.Items) == 0 {
+			continue
+		}
+		f := &fields[pageFields[i]]
+		keep := make([]bool, len(f.items))
+		for _, name := range filter.Items {
+			found := false
+			for j, item := range f.items {
+				if strings.EqualFold(pivotLabel(item), name) {
+					keep[j], found = true, true
+				}
+			}
+			if !found {
+				return fmt.Errorf("pivot filter item %q not found in field %q", name, f.name)
+			}
+		}
+		for r := range selected {
+			selected[r] = selected[r] && keep[f.shared[r]]
+		}
+		hide := make([]bool, len(keep))
+		for j := range keep {
+			hide[j] = !keep[j]
+		}
+		hidden[pageFields[i]] = hide
+	}
+
+	// Lay out the row and column areas.
+	multiData := len(data) > 1
+	rowTuples := axisTuples(fields, rowFields, selected)
+	colTuples := axisTuples(fields, colFields, selected)
+	rowLines := axisLines(rowTuples, len(rowFields), 0)
+	var colLines []pivotLine
+	switch {
+	case len(colFields) > 0 && multiData:
+		colLines = axisLines(colTuples, len(colFields), len(data))
+	case len(colFields) > 0:
+		colLines = axisLines(colTuples, len(colFields), 0)
+	case multiData:
+		for k := range data {
+			colLines = append(colLines, pivotLine{data: k})
+		}
+	default:
+		colLines = []pivotLine{{}}
+	}
+
+	colLevels := len(colFields)
+	if multiData {
+		colLevels++
+	}
+	headerRows, firstHeaderRow := 1, 1
+	switch {
+	case len(colFields) > 0:
+		headerRows = 1 + colLevels
+	case multiData:
+		firstHeaderRow = 0
+	}
+
+	originCol, originRow := target.Col1, target.Row1
+	if len(pageFields) > 0 {
+		originRow += len(pageFields) + 1
+	}
+	lastCol := originCol + len(rowFields) + len(colLines) - 1
+	lastRow := originRow + headerRows + len(rowLines) - 1
+	if src.Sheet == sheet && target.Row1 <= src.Row2 && lastRow >= src.Row1 && originCol <= src.Col2 && lastCol >= src.Col1 {
+		return fmt.Errorf("pivot table at %s overlaps its source range %s", targetCell, sourceRef)
+	}
+
+	// Summarize the selected records for every combination of row and column prefixes.
+	aggregates := make(map[string][]pivotAggregate)
+	for r := range selected {
+		if !selected[r] {
+			continue
+		}
+		for rp := 0; rp <= len(rowFields); rp++ {
+			for cp := 0; cp <= len(colFields); cp++ {
+				key := recordKey(fields, rowFields[:rp], colFields[:cp], r)
+				aggs := aggregates[key]
+				if aggs == nil {
+					aggs = make([]pivotAggregate, len(data))
+					aggregates[key] = aggs
+				}
+				for k, d := range data {
+					aggs[k].add(fields[d.field].values[r])
+				}
+			}
+		}
+	}
+
+	// Write the report filters and the table into the worksheet.
+	set := func(col, row int, value any) error {
+		return e.processor().setCellValue(sheet, formula.CellName(col, row), value)
+	}
+	for i, filter := range spec.Filters {
+		f := fields[pageFields[i]]
+		label := pivotAllItemsLabel
+		switch {
+		case len(filter.Items) == 1:
+			label = pivotLabel(f.items[slices.Index(hidden[pageFields[i]], false)])
+		case len(filter.Items) > 1:
+			label = pivotMultipleItemsLabel
+		}
+		if err := set(originCol, target.Row1+i, f.name); err != nil {
+			return err
+		}
+		if err := set(originCol+1, target.Row1+i, label); err != nil {
+			return err
+		}
+	}
+
+	dataCol := originCol + len(rowFields)
+	captionRow := originRow + headerRows - 1
+	for i, fld := range rowFields {
+		if err := set(originCol+i, captionRow, fields[fld].name); err != nil {
+			return err
+		}
+	}
+	switch {
+	case len(colFields) > 0:
+		if !multiData {
+			if err := set(originCol, originRow, data[0].caption); err != nil {
+				return err
+			}
+		}
+		for i, fld := range colFields {
+			if err := set(dataCol+i, originRow, fields[fld].name); err != nil {
+				return err
+			}
+		}
+		for j, line := range colLines {
+			for level, label := range lineLabels(fields, colFields, line, prevLine(colLines, j), data, multiData) {
+				if label == nil {
+					continue
+				}
+				if err := set(dataCol+j, originRow+1+level, label); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		for j, line := range colLines {
+			if err := set(dataCol+j, originRow, data[line.data].caption); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, line := range rowLines {
+		row := originRow + headerRows + i
+		for level, label := range lineLabels(fields, rowFields, line, prevLine(rowLines, i), nil, false) {
+			if label == nil {
+				continue
+			}
+			if err := set(originCol+level, row, label); err != nil {
+				return err
+			}
+		}
+		for j, col := range colLines {
+			d := data[col.data]
+			aggs := aggregates[lineKey(line)+"|"+lineKey(col)]
+			if aggs == nil {
+				continue
+			}
+			v, ok := aggs[col.data].result(d.fn)
+			if !ok {
+				continue
+			}
+			axis := formula.CellName(dataCol+j, row)
+			if err := e.processor().setCellValue(sheet, axis, v); err != nil {
+				return err
+			}
+			if d.styleID > 0 {
+				cell, err := e.getOrCreateCell(sheet, axis)
+				if err != nil {
+					return err
+				}
+				cell.S = d.styleID
+			}
+		}
+	}
+
+	// Build the package parts.
+	def, records := pivotCache(src, fields, axis)
+	table := &xmlstructs.PivotTableDefinition{
+		Name:                  e.pivotTableName(spec.Name),
+		DataCaption:           "Values",
+		UpdatedVersion:        pivotVersion,
+		MinRefreshableVersion: pivotMinRefreshVersion,
+		UseAutoFormatting:     1,
+		ItemPrintTitles:       1,
+		CreatedVersion:        pivotVersion,
+		Location: xmlstructs.PivotLocation{
+			Ref:            formula.CellName(originCol, originRow) + ":" + formula.CellName(lastCol, lastRow),
+			FirstHeaderRow: firstHeaderRow,
+			FirstDataRow:   headerRows,
+			FirstDataCol:   len(rowFields),
+		},
+		PivotTableStyleInfo: &xmlstructs.PivotTableStyleInfo{
+			Name:           cmp.Or(spec.Style, defaultPivotStyle),
+			ShowRowHeaders: 1,
+			ShowColHeaders: 1,
+			ShowLastColumn: 1,
+		},
+	}
+	if len(pageFields) > 0 {
+		table.Location.RowPageCount = len(pageFields)
+		table.Location.ColPageCount = 1
+	}
+
+	isData := make(map[int]bool)
+	for _, d := range data {
+		isData[d.field] = true
+	}
+	for i, f := range fields {
+		pf := xmlstructs.PivotField{Axis: axis[i], DataField: boolToInt(isData[i])}
+		if axis[i] != "" {
+			items := &xmlstructs.PivotItems{}
+			for _, shared := range f.order {
+				item := xmlstructs.PivotItem{X: &shared}
+				if hidden[i] != nil && hidden[i][shared] {
+					item.H = 1
+				}
+				items.Items = append(items.Items, item)
+			}
+			items.Items = append(items.Items, xmlstructs.PivotItem{T: "default"})
+			items.Count = len(items.Items)
+			pf.Items = items
+		}
+		if hidden[i] != nil {
+			pf.MultipleItemSelectionAllowed = 1
+		}
+		table.PivotFields.Items = append(table.PivotFields.Items, pf)
+	}
+	table.PivotFields.Count = len(fields)
+
+	table.RowFields = axisFields(rowFields, false)
+	table.RowItems = axisItems(rowLines, false)
+	if len(colFields) > 0 || multiData {
+		table.ColFields = axisFields(colFields, multiData)
+	}
+	table.ColItems = axisItems(colLines, multiData)
+
+	if len(pageFields) > 0 {
+		table.PageFields = &xmlstructs.PageFields{Count: len(pageFields)}
+		for i, fld := range pageFields {
+			pf := xmlstructs.PageField{Fld: fld, Hier: -1}
+			if len(spec.Filters[i].Items) == 1 {
+				pos := fields[fld].position[slices.Index(hidden[fld], false)]
+				pf.Item = &pos
+			}
+			table.PageFields.Items = append(table.PageFields.Items, pf)
+		}
+	}
+
+	table.DataFields = &xmlstructs.DataFields{Count: len(data)}
+	for _, d := range data {
+		df := xmlstructs.DataField{Name: d.caption, Fld: d.field}
+		if d.fn != document.AggregateSum {
+			df.Subtotal = string(d.fn)
+		}
+		if d.styleID > 0 {
+			df.NumFmtID = e.styles.CellXfs.Items[d.styleID].NumFmtID
+		}
+		table.DataFields.Items = append(table.DataFields.Items, df)
+	}
+
+	e.attachPivotTable(sheet, table, def, records)
+	return nil
+}
+
+// attachPivotTable stores the parts of a new pivot table and links them to the
+// workbook and the sheet.
+func (e *state) attachPivotTable(sheet string, table *xmlstructs.PivotTableDefinition, def *xmlstructs.PivotCacheDefinition, records *xmlstructs.PivotCacheRecords) {
+	defPath := e.nextPartPath("xl/pivotCache/pivotCacheDefinition%d.xml")
+	e.pivotCaches[defPath] = def
+	recordsPath := e.nextPartPath("xl/pivotCache/pivotCacheRecords%d.xml")
+	e.pivotRecords[recordsPath] = records
+	tablePath := e.nextPartPath("xl/pivotTables/pivotTable%d.xml")
+	e.pivotTables[tablePath] = table
+
+	defRels := &xmlstructs.Relationships{}
+	def.RID = defRels.AddRelationship(pivotRecordsRelType, path.Base(recordsPath))
+	e.sheetRels[relsPath(defPath)] = defRels
+
+	tableRels := &xmlstructs.Relationships{}
+	tableRels.AddRelationship(pivotCacheRelType, "../pivotCache/"+path.Base(defPath))
+	e.sheetRels[relsPath(tablePath)] = tableRels
+
+	if e.sheetRels[sheet] == nil {
+		e.sheetRels[sheet] = &xmlstructs.Relationships{}
+	}
+	e.sheetRels[sheet].AddRelationship(pivotTableRelType, "../pivotTables/"+path.Base(tablePath))
+
+	if e.workbook.PivotCaches == nil {
+		e.workbook.PivotCaches = &xmlstructs.PivotCaches{}
+	}
+	cacheID := 1
+	for _, pc := range e.workbook.PivotCaches.Items {
+		cacheID = max(cacheID, pc.CacheID+1)
+	}
+	rID := e.workbookRels.AddRelationship(pivotCacheRelType, strings.TrimPrefix(defPath, "xl/"))
+	e.workbook.PivotCaches.Items = append(e.workbook.PivotCaches.Items, xmlstructs.PivotCache{CacheID: cacheID, RID: rID})
+	table.CacheID = cacheID
+}
+
+// pivotTableName returns name, or the first unused default pivot table name.
+func (e *state) pivotTableName(name string) string {
+	if name != "" {
+		return name
+	}
+	for i := 1; ; i++ {
+		name = fmt.Sprintf("PivotTable%d", i)
+		used := false
+		for _, t := range e.pivotTables {
+			used = used || t.Name == name
+		}
+		if !used {
+			return name
+		}
+	}
+}
+
+// pivotFields reads the source range: the first row names the fields and
+// every further row is a record.
+func (e *state) pivotFields(src formula.Reference) ([]pivotField, error) {
+	ws, ok := e.worksheet(src.Sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, src.Sheet)
+	}
+	if src.Row2 <= src.Row1 {
+		return nil, fmt.Errorf("pivot source range %s has no data rows", src)
+	}
+	date1904 := e.workbook.WorkbookPr != nil && e.workbook.WorkbookPr.Date1904 == 1
+	value := func(col, row int) formula.Value {
+		if cell := findCell(ws, col, row); cell != nil {
+			return e.typedValue(cell, date1904)
+		}
+		return formula.Value{}
+	}
+
+	fields := make([]pivotField, 0, src.Col2-src.Col1+1)
+	names := make(map[string]bool)
+	for col := src.Col1; col <= src.Col2; col++ {
+		name := pivotLabel(value(col, src.Row1))
+		if name == "" || name == pivotBlankLabel {
+			return nil, fmt.Errorf("pivot source column %s has no header", formula.ColumnName(col))
+		}
+		base := name
+		for n := 2; names[strings.ToLower(name)]; n++ {
+			name = base + strconv.Itoa(n)
+		}
+		names[strings.ToLower(name)] = true
+
+		f := pivotField{name: name, values: make([]formula.Value, 0, src.Row2-src.Row1)}
+		for row := src.Row1 + 1; row <= src.Row2; row++ {
+			f.values = append(f.values, value(col, row))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// itemKey identifies a pivot item. Text items are matched case-insensitively.
+type itemKey struct {
+	kind formula.Kind
+	num  float64
+	str  string
+	b    bool
+}
+
+// enumerate collects the distinct values of the field as shared items.
+func (f *pivotField) enumerate() {
+	index := make(map[itemKey]int)
+	f.shared = make([]int, len(f.values))
+	for r, v := range f.values {
+		key := itemKey{kind: v.Kind, num: v.Num, str: strings.ToLower(v.Str), b: v.Bool}
+		i, ok := index[key]
+		if !ok {
+			i = len(f.items)
+			index[key] = i
+			f.items = append(f.items, v)
+		}
+		f.shared[r] = i
+	}
+	f.order = make([]int, len(f.items))
+	for i := range f.order {
+		f.order[i] = i
+	}
+	slices.SortStableFunc(f.order, func(a, b int) int { return compareItems(f.items[a], f.items[b]) })
+	f.position = make([]int, len(f.items))
+	for pos, i := range f.order {
+		f.position[i] = pos
+	}
+}
+
+// compareItems orders pivot items as Excel sorts them: numbers, text, booleans, errors, then blanks.
+func compareItems(a, b formula.Value) int {
+	if c := cmp.Compare(itemRank[a.Kind], itemRank[b.Kind]); c != 0 {
+		return c
+	}
+	switch a.Kind {
+	case formula.KindNumber:
+		return cmp.Compare(a.Num, b.Num)
+	case formula.KindBool:
+		return cmp.Compare(boolToInt(a.Bool), boolToInt(b.Bool))
+	}
+	return cmp.Compare(strings.ToLower(a.Str), strings.ToLower(b.Str))
+}
+
+var itemRank = map[formula.Kind]int{
+	formula.KindNumber: 0,
+	formula.KindString: 1,
+	formula.KindBool:   2,
+	formula.KindError:  3,
+	formula.KindEmpty:  4,
+}
+
+// pivotLabel returns the text shown for an item.
+func pivotLabel(v formula.Value) string {
+	switch v.Kind {
+	case formula.KindEmpty:
+		return pivotBlankLabel
+	case formula.KindBool:
+		return strings.ToUpper(strconv.FormatBool(v.Bool))
+	}
+	return v.String()
+}
+
+// pivotCellValue returns the value written to a label cell.
+func pivotCellValue(v formula.Value) any {
+	switch v.Kind {
+	case formula.KindNumber:
+		return v.Num
+	case formula.KindBool:
+		return v.Bool
+	}
+	return pivotLabel(v)
+}
+
+// pivotCache builds the cache definition and records of the source range.
+func pivotCache(src formula.Reference, fields []pivotField, axis map[int]string) (*xmlstructs.PivotCacheDefinition, *xmlstructs.PivotCacheRecords) {
+	ref := src
+	ref.Sheet = ""
+	ref.AbsCol1, ref.AbsRow1, ref.AbsCol2, ref.AbsRow2 = false, false, false, false
+	def := &xmlstructs.PivotCacheDefinition{
+		XMLNS_R:               xmlstructs.NSRelationships,
+		RefreshOnLoad:         1,
+		CreatedVersion:        pivotVersion,
+		RefreshedVersion:      pivotVersion,
+		MinRefreshableVersion: pivotMinRefreshVersion,
+		RecordCount:           len(fields[0].values),
+		CacheSource: xmlstructs.CacheSource{
+			Type:            "worksheet",
+			WorksheetSource: &xmlstructs.WorksheetSource{Ref: ref.String(), Sheet: src.Sheet},
+		},
+	}
+	for i, f := range fields {
+		cf := xmlstructs.CacheField{Name: f.name, SharedItems: sharedItems(f.values)}
+		if axis[i] != "" {
+			for _, v := range f.items {
+				cf.SharedItems.Items = append(cf.SharedItems.Items, cacheItem(v))
+			}
+			cf.SharedItems.Count = len(f.items)
+		}
+		def.CacheFields.Items = append(def.CacheFields.Items, cf)
+	}
+	def.CacheFields.Count = len(fields)
+
+	records := &xmlstructs.PivotCacheRecords{Count: len(fields[0].values)}
+	records.Records = make([]xmlstructs.CacheRecord, len(fields[0].values))
+	for r := range records.Records {
+		items := make([]xmlstructs.CacheItem, len(fields))
+		for i, f := range fields {
+			if axis[i] != "" {
+				items[i] = xmlstructs.CacheItem{XMLName: xml.Name{Local: "x"}, V: strconv.Itoa(f.shared[r])}
+			} else {
+				items[i] = cacheItem(f.values[r])
+			}
+		}
+		records.Records[r].Items = items
+	}
+	return def, records
+}
+
+// sharedItems describes the types of values found in a cache field.
+func sharedItems(values []formula.Value) xmlstructs.SharedItems {
+	var text, blank, number, other bool
+	integer := true
+	minimum, maximum := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		switch v.Kind {
+		case formula.KindString:
+			text = true
+		case formula.KindEmpty:
+			blank = true
+		case formula.KindNumber:
+			number = true
+			integer = integer && v.Num == math.Trunc(v.Num)
+			minimum, maximum = min(minimum, v.Num), max(maximum, v.Num)
+		default:
+			other = true
+		}
+	}
+	var si xmlstructs.SharedItems
+	zero := 0
+	if !text {
+		si.ContainsString = &zero
+		if !blank {
+			si.ContainsSemiMixedTypes = &zero
+		}
+	}
+	si.ContainsBlank = boolToInt(blank)
+	if number {
+		si.ContainsNumber = 1
+		si.ContainsInteger = boolToInt(integer)
+		si.MinValue, si.MaxValue = formatFloat(minimum), formatFloat(maximum)
+		si.ContainsMixedTypes = boolToInt(text || other)
+	} else {
+		si.ContainsMixedTypes = boolToInt(text && other)
+	}
+	return si
+}
+
+func cacheItem(v formula.Value) xmlstructs.CacheItem {
+	switch v.Kind {
+	case formula.KindNumber:
+		return xmlstructs.CacheItem{XMLName: xml.Name{Local: "n"}, V: formatFloat(v.Num)}
+	case formula.KindString:
+		return xmlstructs.CacheItem{XMLName: xml.Name{Local: "s"}, V: v.Str}
+	case formula.KindBool:
+		return xmlstructs.CacheItem{XMLName: xml.Name{Local: "b"}, V: strconv.Itoa(boolToInt(v.Bool))}
+	case formula.KindError:
+		return xmlstructs.CacheItem{XMLName: xml.Name{Local: "e"}, V: v.Str}
+	}
+	return xmlstructs.CacheItem{XMLName: xml.Name{Local: "m"}}
+}
+
+// axisTuples returns the distinct combinations of item positions of the selected
+// records, in display order.
+func axisTuples(fields []pivotField, axis []int, selected []bool) [][]int {
+	if len(axis) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var tuples [][]int
+	for r := range selected {
+		if !selected[r] {
+			continue
+		}
+		tuple := make([]int, len(axis))
+		for i, fld := range axis {
+			tuple[i] = fields[fld].position[fields[fld].shared[r]]
+		}
+		key := fmt.Sprint(tuple)
+		if !seen[key] {
+			seen[key] = true
+			tuples = append(tuples, tuple)
+		}
+	}
+	slices.SortFunc(tuples, slices.Compare)
+	return tuples
+}
+
+// axisLines expands the tuples of an axis into its lines, adding a subtotal after each
+// group of an outer field and the grand total. When the Values field is the innermost
+// field of the axis, every line is repeated for each of the dataCount data fields.
+func axisLines(tuples [][]int, depth, dataCount int) []pivotLine {
+	repeat := func(line pivotLine, lines []pivotLine) []pivotLine {
+		for k := range max(dataCount, 1) {
+			line.data = k
+			lines = append(lines, line)
+		}
+		return lines
+	}
+	var lines []pivotLine
+	for i, t := range tuples {
+		lines = repeat(pivotLine{members: t}, lines)
+		for level := depth - 2; level >= 0; level-- {
+			if i+1 == len(tuples) || !slices.Equal(t[:level+1], tuples[i+1][:level+1]) {
+				lines = repeat(pivotLine{kind: "default", members: t[:level+1]}, lines)
+			}
+		}
+	}
+	return repeat(pivotLine{kind: "grand"}, lines)
+}
+
+func prevLine(lines []pivotLine, i int) *pivotLine {
+	if i == 0 {
+		return nil
+	}
+	return &lines[i-1]
+}
+
+// lineLabels returns the label cell values of a line per axis level. Items repeated
+// from the previous line are left empty, as in Excel's tabular layout.
+func lineLabels(fields []pivotField, axis []int, line pivotLine, prev *pivotLine, data []pivotData, multiData bool) []any {
+	labels := make([]any, len(axis)+boolToInt(multiData))
+	item := func(level, pos int) formula.Value {
+		f := fields[axis[level]]
+		return f.items[f.order[pos]]
+	}
+	switch line.kind {
+	case "grand":
+		labels[0] = pivotGrandTotalLabel
+		if multiData {
+			labels[0] = "Total " + data[line.data].caption
+		}
+	case "default":
+		level := len(line.members) - 1
+		labels[level] = pivotLabel(item(level, line.members[level])) + pivotSubtotalLabelSuffix
+		if multiData {
+			labels[level] = pivotLabel(item(level, line.members[level])) + " " + data[line.data].caption
+		}
+	default:
+		same := prev != nil && prev.kind == ""
+		for level, pos := range line.members {
+			same = same && prev.members[level] == pos
+			if !same {
+				labels[level] = pivotCellValue(item(level, pos))
+			}
+		}
+		if multiData {
+			labels[len(axis)] = data[line.data].caption
+		}
+	}
+	return labels
+}
+
+// lineKey returns the aggregate key part of a line, matching recordKey.
+func lineKey(l pivotLine) string {
+	if len(l.members) == 0 {
+		return ""
+	}
+	return fmt.Sprint(l.members)
+}
+
+// recordKey returns the aggregate key of a record for the given row and column field prefixes.
+func recordKey(fields []pivotField, rows, cols []int, r int) string {
+	tuple := func(axis []int) string {
+		if len(axis) == 0 {
+			return ""
+		}
+		t := make([]int, len(axis))
+		for i, fld := range axis {
+			t[i] = fields[fld].position[fields[fld].shared[r]]
+		}
+		return fmt.Sprint(t)
+	}
+	return tuple(rows) + "|" + tuple(cols)
+}
+
+func axisFields(axis []int, values bool) *xmlstructs.PivotAxisFields {
+	af := &xmlstructs.PivotAxisFields{}
+	for _, fld := range axis {
+		af.Items = append(af.Items, xmlstructs.PivotAxisField{X: fld})
+	}
+	if values {
+		af.Items = append(af.Items, xmlstructs.PivotAxisField{X: valuesField})
+	}
+	af.Count = len(af.Items)
+	return af
+}
+
+// axisItems encodes the lines of an axis, listing only the members that differ from the previous line.
+func axisItems(lines []pivotLine, values bool) *xmlstructs.PivotAxisItems {
+	items := &xmlstructs.PivotAxisItems{}
+	var prev []int
+	for _, line := range lines {
+		item := xmlstructs.PivotAxisItem{T: line.kind, I: line.data}
+		var members []int
+		switch line.kind {
+		case "":
+			members = slices.Clone(line.members)
+			if values {
+				members = append(members, line.data)
+			}
+			if prev != nil {
+				for item.R < len(members)-1 && members[item.R] == prev[item.R] {
+					item.R++
+				}
+			}
+			prev = members
+		case "default":
+			members, prev = line.members, nil
+		default:
+			members, prev = []int{0}, nil
+			if values {
+				members[0] = line.data
+			}
+		}
+		for _, m := range members[item.R:] {
+			item.X = append(item.X, xmlstructs.PivotMember{V: m})
+		}
+		items.Items = append(items.Items, item)
+	}
+	items.Count = len(items.Items)
+	return items
+}
+
+var aggregationLabels = map[document.PivotAggregation]string{
+	document.AggregateSum:       "Sum",
+	document.AggregateCount:     "Count",
+	document.AggregateAverage:   "Average",
+	document.AggregateMax:       "Max",
+	document.AggregateMin:       "Min",
+	document.AggregateProduct:   "Product",
+	document.AggregateCountNums: "Count",
+	document.AggregateStdDev:    "StdDev",
+	document.AggregateStdDevP:   "StdDevp",
+	document.AggregateVar:       "Var",
+	document.AggregateVarP:      "Varp",
+}
+
+func (a *pivotAggregate) add(v formula.Value) {
+	if v.Kind == formula.KindEmpty {
+		return
+	}
+	a.count++
+	if v.Kind != formula.KindNumber {
+		return
+	}
+	if a.nums == 0 {
+		a.prod, a.minimum, a.maximum = 1, v.Num, v.Num
+	}
+	a.nums++
+	a.sum += v.Num
+	a.sumSq += v.Num * v.Num
+	a.prod *= v.Num
+	a.minimum, a.maximum = min(a.minimum, v.Num), max(a.maximum, v.Num)
+}
+
+// result returns the aggregated value, or false when the cell is left empty.
+func (a *pivotAggregate) result(fn document.PivotAggregation) (float64, bool) {
+	if a.count == 0 {
+		return 0, false
+	}
+	n := float64(a.nums)
+	switch fn {
+	case document.AggregateCount:
+		return float64(a.count), true
+	case document.AggregateCountNums:
+		return n, true
+	}
+	if a.nums == 0 {
+		return 0, fn == document.AggregateSum
+	}
+	variance := (a.sumSq - a.sum*a.sum/n)
+	switch fn {
+	case document.AggregateAverage:
+		return a.sum / n, true
+	case document.AggregateMax:
+		return a.maximum, true
+	case document.AggregateMin:
+		return a.minimum, true
+	case document.AggregateProduct:
+		return a.prod, true
+	case document.AggregateStdDev, document.AggregateVar:
+		if a.nums < 2 {
+			return 0, false
+		}
+		variance /= n - 1
+	case document.AggregateStdDevP, document.AggregateVarP:
+		variance /= n
+	default:
+		return a.sum, true
+	}
+	variance = max(variance, 0)
+	if fn == document.AggregateStdDev || fn == document.AggregateStdDevP {
+		return math.Sqrt(variance), true
+	}
+	return variance, true
+}
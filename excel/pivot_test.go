@@ -0,0 +1,174 @@
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func newPivotSource(t *testing.T) (*Document, document.Sheet) {
+	t.Helper()
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	data := [][]any{
+		{"Region", "Product", "Year", "Amount"},
+		{"East", "A", 2020, 10},
+		{"West", "B", 2021, 5},
+		{"East", "B", 2020, 7},
+		{"West", "A", 2020, 3},
+		{"East", "A", 2021, 1},
+	}
+	sheet, _ := doc.Sheet("Sheet1")
+	for i, row := range data {
+		for j, v := range row {
+			sheet.Cell(fmt.Sprintf("%c%d", 'A'+j, i+1)).Set(v)
+		}
+	}
+	return doc, sheet
+}
+
+func TestAddPivotTable_RowsAndColumns(t *testing.T) {
+	ctx := t.Context()
+	doc, sheet := newPivotSource(t)
+	defer doc.Close()
+
+	sheet.AddPivotTable("A1:D6", "G3", document.PivotSpec{
+		Rows:    []string{"Region", "Product"},
+		Columns: []string{"Year"},
+		Values:  []document.PivotValue{{Field: "Amount"}},
+	})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddPivotTable failed: %v", err)
+	}
+
+	want := map[string]string{
+		"G3":  "Sum of Amount",
+		"I3":  "Year",
+		"G4":  "Region",
+		"I4":  "2020",
+		"K4":  "Grand Total",
+		"G5":  "East",
+		"H5":  "A",
+		"K5":  "11",
+		"J6":  "",
+		"G7":  "East Total",
+		"K7":  "18",
+		"J9":  "5",
+		"G11": "Grand Total",
+		"K11": "26",
+	}
+	for axis, v := range want {
+		got, _ := sheet.GetCellValue(axis)
+		if got != v {
+			t.Errorf("Cell %s: expected %q, got %q", axis, v, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	table := parts["xl/pivotTables/pivotTable1.xml"]
+	for _, s := range []string{
+		`<location ref="G3:K11" firstHeaderRow="1" firstDataRow="2" firstDataCol="2">`,
+		`<rowItems count="7">`,
+		`<i r="1"><x v="1"></x></i>`,
+		`<i t="default"><x v="1"></x></i>`,
+		`<dataField name="Sum of Amount" fld="3"`,
+	} {
+		if !strings.Contains(table, s) {
+			t.Errorf("Expected %s in pivot table part", s)
+		}
+	}
+	if !strings.Contains(parts["xl/pivotCache/pivotCacheDefinition1.xml"], `refreshOnLoad="1"`) {
+		t.Error("Expected the cache to refresh on load")
+	}
+	if n := strings.Count(parts["xl/pivotCache/pivotCacheRecords1.xml"], "<r>"); n != 5 {
+		t.Errorf("Expected 5 cache records, got %d", n)
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `<pivotCache cacheId="1" r:id="`) {
+		t.Error("Expected the pivot cache to be listed in the workbook")
+	}
+	if !strings.Contains(parts["xl/_rels/workbook.xml.rels"], "pivotCache/pivotCacheDefinition1.xml") {
+		t.Error("Expected a workbook relationship to the pivot cache")
+	}
+	if !strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], "../pivotTables/pivotTable1.xml") {
+		t.Error("Expected a sheet relationship to the pivot table")
+	}
+	for _, ct := range []string{"pivotTable+xml", "pivotCacheDefinition+xml", "pivotCacheRecords+xml"} {
+		if !strings.Contains(parts["[Content_Types].xml"], ct) {
+			t.Errorf("Expected %s content type", ct)
+		}
+	}
+
+	// The pivot cache must survive a round trip of the workbook.
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	buf.Reset()
+	if err := reopened.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts = zipParts(t, buf.Bytes())
+	if !strings.Contains(parts["xl/workbook.xml"], `<pivotCache cacheId="1" r:id="rId`) {
+		t.Error("Expected the pivot cache reference to be kept on save")
+	}
+}
+
+func TestAddPivotTable_FiltersAndValues(t *testing.T) {
+	doc, sheet := newPivotSource(t)
+	defer doc.Close()
+
+	summary, _ := doc.Sheet("Summary")
+	summary.AddPivotTable("Sheet1!A1:D6", "A1", document.PivotSpec{
+		Rows:    []string{"Product"},
+		Filters: []document.PivotFilter{{Field: "Region", Items: []string{"East"}}},
+		Values: []document.PivotValue{
+			{Field: "Amount"},
+			{Field: "Amount", Aggregation: document.AggregateCount},
+		},
+	})
+	if err := summary.Err(); err != nil {
+		t.Fatalf("AddPivotTable failed: %v", err)
+	}
+
+	want := map[string]string{
+		"A1": "Region",
+		"B1": "East",
+		"A3": "Product",
+		"B3": "Sum of Amount",
+		"C3": "Count of Amount",
+		"A4": "A",
+		"B4": "11",
+		"C4": "2",
+		"B5": "7",
+		"A6": "Grand Total",
+		"B6": "18",
+		"C6": "3",
+	}
+	for axis, v := range want {
+		got, _ := summary.GetCellValue(axis)
+		if got != v {
+			t.Errorf("Cell %s: expected %q, got %q", axis, v, got)
+		}
+	}
+
+	if err := sheet.AddPivotTable("A1:D6", "B2", document.PivotSpec{
+		Rows:   []string{"Region"},
+		Values: []document.PivotValue{{Field: "Amount"}},
+	}).Err(); err == nil {
+		t.Error("Expected an error for a pivot table overlapping its source")
+	}
+	if err := summary.AddPivotTable("Sheet1!A1:D6", "H1", document.PivotSpec{
+		Rows:   []string{"Missing"},
+		Values: []document.PivotValue{{Field: "Amount"}},
+	}).Err(); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
@@ -8,4 +8,13 @@ type processor struct {
 	cellProcessor
 	styleProcessor
 	mediaProcessor
+	calcProcessor
+	commentProcessor
+	chartProcessor
+	pivotProcessor
+	csvProcessor
+	rangeProcessor
+	tableProcessor
+	pdfProcessor
+	htmlProcessor
 }
@@ -0,0 +1,701 @@
+package excel
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// rangeProcessor implements the bulk operations of document.Range. Each
+// operation visits the sheet's rows once instead of looking up cell by cell.
+type rangeProcessor struct{ *state }
+
+// cellArea is a block of cells with 1-based, inclusive bounds.
+type cellArea struct{ col1, row1, col2, row2 int }
+
+// parseArea parses a range such as "B2:D10", or a single cell, of the sheet itself.
+func parseArea(ref string) (cellArea, error) {
+	r, err := formula.ParseReference(ref)
+	if err != nil || r.Invalid || r.Sheet != "" || r.Col1 == 0 || r.Row1 == 0 {
+		return cellArea{}, fmt.Errorf("invalid range %q", ref)
+	}
+	return cellArea{min(r.Col1, r.Col2), min(r.Row1, r.Row2), max(r.Col1, r.Col2), max(r.Row1, r.Row2)}, nil
+}
+
+func (a cellArea) String() string {
+	if a.col1 == a.col2 && a.row1 == a.row2 {
+		return formula.CellName(a.col1, a.row1)
+	}
+	return formula.CellName(a.col1, a.row1) + ":" + formula.CellName(a.col2, a.row2)
+}
+
+func (a cellArea) width() int  { return a.col2 - a.col1 + 1 }
+func (a cellArea) height() int { return a.row2 - a.row1 + 1 }
+
+// covers reports whether every cell of b lies within a.
+func (a cellArea) covers(b cellArea) bool {
+	return b.col1 >= a.col1 && b.col2 <= a.col2 && b.row1 >= a.row1 && b.row2 <= a.row2
+}
+
+// moveTo returns the block of the same size whose top-left cell is target.
+func (a cellArea) moveTo(target string) (cellArea, error) {
+	r, err := formula.ParseReference(target)
+	if err != nil || !r.IsCell() || r.Sheet != "" {
+		return cellArea{}, fmt.Errorf("invalid target cell %q", target)
+	}
+	b := cellArea{r.Col1, r.Row1, r.Col1 + a.width() - 1, r.Row1 + a.height() - 1}
+	if b.col2 > formula.MaxColumns || b.row2 > formula.MaxRows {
+		return cellArea{}, fmt.Errorf("range %s at %s would extend off the grid", a, target)
+	}
+	return b, nil
+}
+
+// newCellGrid returns an empty grid of cells of the size of a.
+func newCellGrid(a cellArea) [][]*xmlstructs.Cell {
+	grid := make([][]*xmlstructs.Cell, a.height())
+	for i := range grid {
+		grid[i] = make([]*xmlstructs.Cell, a.width())
+	}
+	return grid
+}
+
+// editableSheet returns the worksheet of a sheet whose cells can be edited.
+func (e *rangeProcessor) editableSheet(sheet string) (*xmlstructs.Worksheet, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	if _, streamed := e.streams[sheet]; streamed {
+		return nil, fmt.Errorf("sheet %s is written through a stream writer", sheet)
+	}
+	return ws, nil
+}
+
+// areaCells returns the cells of a by row and column, nil where a cell is
+// empty. Missing cells for which create reports true are first merged into the
+// sheet's rows in a single pass.
+func (e *state) areaCells(ws *xmlstructs.Worksheet, sheet string, a cellArea, create func(col, row int) bool) [][]*xmlstructs.Cell {
+	if create != nil {
+		rows := ws.SheetData.Rows
+		merged := make([]xmlstructs.Row, 0, len(rows))
+		i := 0
+		for r := a.row1; r <= a.row2; r++ {
+			for i < len(rows) && rows[i].R < r {
+				merged = append(merged, rows[i])
+				i++
+			}
+			row, exists := xmlstructs.Row{R: r}, false
+			if i < len(rows) && rows[i].R == r {
+				row, exists = rows[i], true
+				i++
+			}
+			row.Cells = mergeRowCells(row.Cells, r, a.col1, a.col2, create)
+			if exists || len(row.Cells) > 0 {
+				merged = append(merged, row)
+			}
+		}
+		ws.SheetData.Rows = append(merged, rows[i:]...)
+		// Cached cell pointers refer to the replaced rows.
+		delete(e.cellCache, sheet)
+	}
+
+	grid := newCellGrid(a)
+	rows := ws.SheetData.Rows
+	for i := sort.Search(len(rows), func(i int) bool { return rows[i].R >= a.row1 }); i < len(rows) && rows[i].R <= a.row2; i++ {
+		cells := rows[i].Cells
+		j := sort.Search(len(cells), func(j int) bool {
+			c, _ := axisPosition(cells[j].R)
+			return c >= a.col1
+		})
+		for ; j < len(cells); j++ {
+			col, _ := axisPosition(cells[j].R)
+			if col > a.col2 {
+				break
+			}
+			grid[rows[i].R-a.row1][col-a.col1] = &cells[j]
+		}
+	}
+	return grid
+}
+
+// mergeRowCells returns the cells of a row with the missing cells of columns
+// col1 to col2 for which create reports true added in column order.
+func mergeRowCells(cells []xmlstructs.Cell, row, col1, col2 int, create func(col, row int) bool) []xmlstructs.Cell {
+	j := sort.Search(len(cells), func(j int) bool {
+		c, _ := axisPosition(cells[j].R)
+		return c >= col1
+	})
+	merged := append(make([]xmlstructs.Cell, 0, len(cells)), cells[:j]...)
+	for col := col1; col <= col2; col++ {
+		if j < len(cells) {
+			if c, _ := axisPosition(cells[j].R); c == col {
+				merged = append(merged, cells[j])
+				j++
+				continue
+			}
+		}
+		if create(col, row) {
+			merged = append(merged, xmlstructs.Cell{R: formula.CellName(col, row)})
+		}
+	}
+	return append(merged, cells[j:]...)
+}
+
+// filterCells removes the cells of a for which drop reports true in a single
+// pass over the sheet's rows; drop may modify the cells it keeps. Rows left
+// without cells or row settings are removed too.
+func (e *state) filterCells(ws *xmlstructs.Worksheet, sheet string, a cellArea, drop func(*xmlstructs.Cell) bool) {
+	rows := ws.SheetData.Rows[:0]
+	for _, row := range ws.SheetData.Rows {
+		if row.R >= a.row1 && row.R <= a.row2 {
+			cells := row.Cells[:0]
+			for k := range row.Cells {
+				cell := &row.Cells[k]
+				if col, _ := axisPosition(cell.R); col >= a.col1 && col <= a.col2 && drop(cell) {
+					continue
+				}
+				cells = append(cells, *cell)
+			}
+			row.Cells = cells
+			if len(cells) == 0 && row.Ht == 0 && row.CustomHeight == 0 && row.Hidden == 0 && row.OutlineLevel == 0 && !row.Collapsed {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+	ws.SheetData.Rows = rows
+	delete(e.cellCache, sheet)
+}
+
+// pasteCells replaces the cells of dst with cells, a grid of the size of dst
+// holding nil for the cells left empty.
+func (e *state) pasteCells(ws *xmlstructs.Worksheet, sheet string, dst cellArea, cells [][]*xmlstructs.Cell) {
+	e.filterCells(ws, sheet, dst, func(*xmlstructs.Cell) bool { return true })
+	grid := e.areaCells(ws, sheet, dst, func(col, row int) bool {
+		return cells[row-dst.row1][col-dst.col1] != nil
+	})
+	for i, row := range grid {
+		for j, cell := range row {
+			if cell != nil {
+				*cell = *cells[i][j]
+			}
+		}
+	}
+	e.calcDirty = true
+}
+
+// currentCells returns the cells of a like areaCells, recalculating the
+// workbook first when a has formulas and values have changed.
+func (e *state) currentCells(ws *xmlstructs.Worksheet, sheet string, a cellArea) ([][]*xmlstructs.Cell, error) {
+	grid := e.areaCells(ws, sheet, a, nil)
+	if !e.calcDirty {
+		return grid, nil
+	}
+	for _, row := range grid {
+		for _, cell := range row {
+			if cell != nil && cell.F != nil {
+				if err := (&calcProcessor{e}).recalculate(); err != nil {
+					return nil, err
+				}
+				return e.areaCells(ws, sheet, a, nil), nil
+			}
+		}
+	}
+	return grid, nil
+}
+
+// cloneCell returns a copy of a cell that shares no formula or text with it.
+func cloneCell(c xmlstructs.Cell) *xmlstructs.Cell {
+	if c.F != nil {
+		f := *c.F
+		c.F = &f
+	}
+	if c.IS != nil {
+		is := *c.IS
+		is.R = slices.Clone(is.R)
+		c.IS = &is
+	}
+	return &c
+}
+
+// placeCell returns a copy of cell placed at col and row, with the relative
+// references of its formula moved by dCol and dRow.
+func placeCell(cell *xmlstructs.Cell, col, row, dCol, dRow int) *xmlstructs.Cell {
+	c := cloneCell(*cell)
+	c.R = formula.CellName(col, row)
+	if c.F != nil && (dCol != 0 || dRow != 0) {
+		if text, err := formula.Translate(c.F.Text, dCol, dRow); err == nil {
+			c.F.Text = text
+		}
+		if c.F.Ref != "" {
+			if ref, err := formula.Translate(c.F.Ref, dCol, dRow); err == nil {
+				c.F.Ref = ref
+			}
+		}
+	}
+	return c
+}
+
+func (e *rangeProcessor) setRangeValues(sheet string, a cellArea, values [][]any) error {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	if len(values) > a.height() {
+		return fmt.Errorf("%d rows of values exceed range %s", len(values), a)
+	}
+	for i, row := range values {
+		if len(row) > a.width() {
+			return fmt.Errorf("%d values in row %d exceed range %s", len(row), i+1, a)
+		}
+	}
+
+	grid := e.areaCells(ws, sheet, a, func(col, row int) bool {
+		i, j := row-a.row1, col-a.col1
+		return i < len(values) && j < len(values[i]) && values[i][j] != nil
+	})
+	e.calcDirty = true
+	for i, row := range values {
+		for j, v := range row {
+			if v == nil {
+				continue
+			}
+			if err := e.writeCellValue(grid[i][j], v); err != nil {
+				return fmt.Errorf("cell %s: %w", grid[i][j].R, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *rangeProcessor) rangeValues(sheet string, a cellArea) ([][]string, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	grid, err := e.currentCells(ws, sheet, a)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]string, len(grid))
+	for i, row := range grid {
+		values[i] = make([]string, len(row))
+		for j, cell := range row {
+			if cell != nil {
+				values[i][j] = e.resolveValue(*cell)
+			}
+		}
+	}
+	return values, nil
+}
+
+func (e *rangeProcessor) setRangeStyle(sheet string, a cellArea, style document.CellStyle) error {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	id := (&styleProcessor{e.state}).getStyleID(style)
+	for _, row := range e.areaCells(ws, sheet, a, func(int, int) bool { return true }) {
+		for _, cell := range row {
+			cell.S = id
+		}
+	}
+	return nil
+}
+
+func (e *rangeProcessor) clearRange(sheet string, a cellArea, mode document.ClearMode) error {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	if mode != document.ClearFormats {
+		e.calcDirty = true
+	}
+	e.filterCells(ws, sheet, a, func(c *xmlstructs.Cell) bool {
+		switch mode {
+		case document.ClearContents:
+			c.T, c.V, c.F, c.IS = "", "", nil, nil
+		case document.ClearFormats:
+			c.S = 0
+		default:
+			return true
+		}
+		return c.S == 0 && c.V == "" && c.F == nil && c.IS == nil
+	})
+	return nil
+}
+
+func (e *rangeProcessor) copyRange(sheet string, a cellArea, target string) error {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	dst, err := a.moveTo(target)
+	if err != nil {
+		return err
+	}
+	if err := (&sheetProcessor{e.state}).unshareFormulas(); err != nil {
+		return err
+	}
+	dCol, dRow := dst.col1-a.col1, dst.row1-a.row1
+	cells := newCellGrid(dst)
+	for i, row := range e.areaCells(ws, sheet, a, nil) {
+		for j, cell := range row {
+			if cell != nil {
+				cells[i][j] = placeCell(cell, dst.col1+j, dst.row1+i, dCol, dRow)
+			}
+		}
+	}
+	e.pasteCells(ws, sheet, dst, cells)
+	return nil
+}
+
+// moveRange moves the cells of a to the block at target and returns that block.
+func (e *rangeProcessor) moveRange(sheet string, a cellArea, target string) (cellArea, error) {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return a, err
+	}
+	dst, err := a.moveTo(target)
+	if err != nil {
+		return a, err
+	}
+	if err := (&sheetProcessor{e.state}).unshareFormulas(); err != nil {
+		return a, err
+	}
+	// Moved formulas keep their references; moveReferences then updates those
+	// that point into the moved block, wherever they are.
+	cells := newCellGrid(dst)
+	for i, row := range e.areaCells(ws, sheet, a, nil) {
+		for j, cell := range row {
+			if cell != nil {
+				cells[i][j] = placeCell(cell, dst.col1+j, dst.row1+i, 0, 0)
+			}
+		}
+	}
+	e.filterCells(ws, sheet, a, func(*xmlstructs.Cell) bool { return true })
+	e.pasteCells(ws, sheet, dst, cells)
+	if err := e.moveReferences(sheet, a, dst); err != nil {
+		return a, err
+	}
+	return dst, nil
+}
+
+// moveReferences points the references to cells within src at the block dst
+// they were moved to. References to cells that dst overwrote become #REF!.
+// The merges, conditional formats, data validations and hyperlinks of cells
+// within src move with them; those of the overwritten cells are dropped.
+func (e *rangeProcessor) moveReferences(sheet string, src, dst cellArea) error {
+	dCol, dRow := dst.col1-src.col1, dst.row1-src.row1
+	err := (&sheetProcessor{e.state}).rewriteSheetReferences(func(home string) func(formula.Reference) formula.Reference {
+		return func(r formula.Reference) formula.Reference {
+			name := r.Sheet
+			if name == "" {
+				name = home
+			}
+			if r.Invalid || r.Col1 == 0 || r.Row1 == 0 || !strings.EqualFold(name, sheet) {
+				return r
+			}
+			ref := cellArea{min(r.Col1, r.Col2), min(r.Row1, r.Row2), max(r.Col1, r.Col2), max(r.Row1, r.Row2)}
+			switch {
+			case src.covers(ref):
+				r.Col1, r.Col2 = r.Col1+dCol, r.Col2+dCol
+				r.Row1, r.Row2 = r.Row1+dRow, r.Row2+dRow
+			case dst.covers(ref):
+				return formula.Reference{Sheet: r.Sheet, Invalid: true}
+			}
+			return r
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if ws, ok := e.worksheet(sheet); ok {
+		moveSheetRanges(ws, src, dst)
+	}
+	return nil
+}
+
+// moveArea moves ref by the offset from src to dst when it lies within src,
+// reporting false when it lies within the overwritten block dst instead.
+func moveArea(ref string, src, dst cellArea) (string, bool) {
+	r, err := formula.ParseReference(ref)
+	if err != nil || r.Col1 == 0 || r.Row1 == 0 {
+		return ref, true
+	}
+	area := cellArea{min(r.Col1, r.Col2), min(r.Row1, r.Row2), max(r.Col1, r.Col2), max(r.Row1, r.Row2)}
+	switch {
+	case src.covers(area):
+		r.Col1, r.Col2 = r.Col1+dst.col1-src.col1, r.Col2+dst.col1-src.col1
+		r.Row1, r.Row2 = r.Row1+dst.row1-src.row1, r.Row2+dst.row1-src.row1
+		return r.String(), true
+	case dst.covers(area):
+		return "", false
+	}
+	return ref, true
+}
+
+// moveSqref applies moveArea to a space separated list of ranges.
+func moveSqref(ref string, src, dst cellArea) (string, bool) {
+	var kept []string
+	for _, f := range strings.Fields(ref) {
+		if area, ok := moveArea(f, src, dst); ok {
+			kept = append(kept, area)
+		}
+	}
+	return strings.Join(kept, " "), len(kept) > 0
+}
+
+// moveSheetRanges moves the ranges stored in the sheet of a moved block.
+func moveSheetRanges(ws *xmlstructs.Worksheet, src, dst cellArea) {
+	if mc := ws.MergeCells; mc != nil {
+		items := mc.Items[:0]
+		for _, m := range mc.Items {
+			if ref, ok := moveArea(m.Ref, src, dst); ok {
+				items = append(items, xmlstructs.MergeCell{Ref: ref})
+			}
+		}
+		mc.Items, mc.Count = items, len(items)
+		if len(items) == 0 {
+			ws.MergeCells = nil
+		}
+	}
+
+	if hl := ws.Hyperlinks; hl != nil {
+		items := hl.Items[:0]
+		for _, h := range hl.Items {
+			if ref, ok := moveArea(h.Ref, src, dst); ok {
+				h.Ref = ref
+				items = append(items, h)
+			}
+		}
+		hl.Items = items
+		if len(items) == 0 {
+			ws.Hyperlinks = nil
+		}
+	}
+
+	cfs := ws.ConditionalFormatting[:0]
+	for _, cf := range ws.ConditionalFormatting {
+		if ref, ok := moveSqref(cf.Sqref, src, dst); ok {
+			cf.Sqref = ref
+			cfs = append(cfs, cf)
+		}
+	}
+	ws.ConditionalFormatting = cfs
+
+	if dvs := ws.DataValidations; dvs != nil {
+		items := dvs.Items[:0]
+		for _, dv := range dvs.Items {
+			if ref, ok := moveSqref(dv.Sqref, src, dst); ok {
+				dv.Sqref = ref
+				items = append(items, dv)
+			}
+		}
+		dvs.Items, dvs.Count = items, len(items)
+		if len(items) == 0 {
+			ws.DataValidations = nil
+		}
+	}
+}
+
+func (e *rangeProcessor) fillRange(sheet string, a cellArea, direction document.FillDirection) error {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	if err := (&sheetProcessor{e.state}).unshareFormulas(); err != nil {
+		return err
+	}
+	src := e.areaCells(ws, sheet, a, nil)
+	cells := newCellGrid(a)
+	switch direction {
+	case document.FillDown, document.FillRight:
+		for i := range cells {
+			for j := range cells[i] {
+				si, sj := i, 0
+				if direction == document.FillDown {
+					si, sj = 0, j
+				}
+				if src[si][sj] != nil {
+					cells[i][j] = placeCell(src[si][sj], a.col1+j, a.row1+i, j-sj, i-si)
+				}
+			}
+		}
+	case document.FillSeries:
+		fillSeries(src, cells, a)
+	default:
+		return fmt.Errorf("unsupported fill direction %d", direction)
+	}
+	e.pasteCells(ws, sheet, a, cells)
+	return nil
+}
+
+// fillSeries writes to cells the cells of src with a linear series continued
+// down each column of a, or along its row when a is a single row. Lines whose
+// first cell does not hold a number are kept as they are.
+func fillSeries(src, cells [][]*xmlstructs.Cell, a cellArea) {
+	down := a.height() > 1
+	lines, length := a.width(), a.height()
+	if !down {
+		lines, length = a.height(), a.width()
+	}
+	at := func(line, k int) (int, int) {
+		if down {
+			return k, line
+		}
+		return line, k
+	}
+	number := func(c *xmlstructs.Cell) (float64, bool) {
+		if c == nil || c.F != nil || (c.T != "" && c.T != "n") {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(c.V, 64)
+		return v, err == nil
+	}
+
+	for i, row := range src {
+		for j, cell := range row {
+			if cell != nil {
+				cells[i][j] = placeCell(cell, a.col1+j, a.row1+i, 0, 0)
+			}
+		}
+	}
+	for line := range lines {
+		i, j := at(line, 0)
+		first := src[i][j]
+		start, ok := number(first)
+		if !ok {
+			continue
+		}
+		step, from := 1.0, 1
+		if length > 2 {
+			i, j = at(line, 1)
+			if second, ok := number(src[i][j]); ok {
+				step, from = second-start, 2
+			}
+		}
+		for k := from; k < length; k++ {
+			i, j = at(line, k)
+			cells[i][j] = &xmlstructs.Cell{
+				R: formula.CellName(a.col1+j, a.row1+i),
+				S: first.S,
+				T: "n",
+				V: formula.FormatNumber(start + float64(k)*step),
+			}
+		}
+	}
+}
+
+func (e *rangeProcessor) sortRange(sheet string, a cellArea, keys []document.SortKey) error {
+	ws, err := e.editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		keys = []document.SortKey{{Column: formula.ColumnName(a.col1)}}
+	}
+	cols := make([]int, len(keys))
+	for k, key := range keys {
+		col := colToNum(strings.ToUpper(key.Column))
+		if key.Column == "" || strings.TrimFunc(key.Column, isLetter) != "" || col < a.col1 || col > a.col2 {
+			return fmt.Errorf("sort column %q is not a column of range %s", key.Column, a)
+		}
+		cols[k] = col - a.col1
+	}
+	if err := (&sheetProcessor{e.state}).unshareFormulas(); err != nil {
+		return err
+	}
+	src, err := e.currentCells(ws, sheet, a)
+	if err != nil {
+		return err
+	}
+
+	date1904 := e.date1904()
+	values := make([][]formula.Value, len(src))
+	for i, row := range src {
+		values[i] = make([]formula.Value, len(cols))
+		for k, col := range cols {
+			if cell := row[col]; cell != nil {
+				values[i][k] = e.typedValue(cell, date1904)
+			}
+		}
+	}
+	order := make([]int, len(src))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		for k, key := range keys {
+			if c := compareSortValues(values[x][k], values[y][k], key.Descending); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+
+	// Sorted formulas move like copied ones, so references to their own row follow them.
+	cells := newCellGrid(a)
+	for i, from := range order {
+		for j, cell := range src[from] {
+			if cell != nil {
+				cells[i][j] = placeCell(cell, a.col1+j, a.row1+i, 0, i-from)
+			}
+		}
+	}
+	e.pasteCells(ws, sheet, a, cells)
+	return nil
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+}
+
+// compareSortValues orders numbers before text, booleans and errors, as Excel
+// sorts ascending. Empty cells come last in either order.
+func compareSortValues(a, b formula.Value, descending bool) int {
+	if a.Kind == formula.KindEmpty || b.Kind == formula.KindEmpty {
+		return boolToInt(a.Kind == formula.KindEmpty) - boolToInt(b.Kind == formula.KindEmpty)
+	}
+	rank := func(v formula.Value) int {
+		switch v.Kind {
+		case formula.KindNumber:
+			return 0
+		case formula.KindString:
+			return 1
+		case formula.KindBool:
+			return 2
+		}
+		return 3
+	}
+	c := rank(a) - rank(b)
+	if c == 0 {
+		switch a.Kind {
+		case formula.KindNumber:
+			c = cmp.Compare(a.Num, b.Num)
+		case formula.KindString:
+			c = strings.Compare(strings.ToLower(a.Str), strings.ToLower(b.Str))
+		case formula.KindBool:
+			c = boolToInt(a.Bool) - boolToInt(b.Bool)
+		}
+	}
+	if descending {
+		return -c
+	}
+	return c
+}
+
+// autoFitRange sizes the columns of a to the widest value displayed in their
+// cells within a. Columns without values keep their width.
+func (e *rangeProcessor) autoFitRange(sheet string, a cellArea) error {
+	return (&sheetProcessor{e.state}).fitColumns(sheet, func(col, row int) bool {
+		return a.covers(cellArea{col, row, col, row})
+	})
+}
@@ -0,0 +1,285 @@
+package excel
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func rangeTestSheet(t *testing.T) (*Document, document.Sheet) {
+	t.Helper()
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	sheet, err := doc.Sheet("Data")
+	if err != nil {
+		t.Fatalf("Sheet failed: %v", err)
+	}
+	return doc, sheet
+}
+
+func checkValues(t *testing.T, r document.Range, want [][]string) {
+	t.Helper()
+	got, err := r.Values()
+	if err != nil {
+		t.Fatalf("Values(%s) failed: %v", r.Ref(), err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Values(%s) = %q, want %q", r.Ref(), got, want)
+	}
+}
+
+func TestRange_SetValues(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Cell("B2").Set("kept")
+	sheet.Cell("A5").Set("below")
+	r := sheet.Range("C3:A1")
+	if r.Ref() != "A1:C3" {
+		t.Errorf("Ref = %q, want A1:C3", r.Ref())
+	}
+	r.SetValues([][]any{
+		{"Name", "Qty", true},
+		{"Apple", nil, 1.5},
+	})
+	if err := r.Err(); err != nil {
+		t.Fatalf("SetValues failed: %v", err)
+	}
+	checkValues(t, r, [][]string{
+		{"Name", "Qty", "1"},
+		{"Apple", "kept", "1.5"},
+		{"", "", ""},
+	})
+	if got, _ := sheet.Cell("A5").Get(); got != "below" {
+		t.Errorf("A5 = %q, want below", got)
+	}
+	sheet.Cell("B2").Set(7)
+	if got, _ := sheet.Cell("B2").Get(); got != "7" {
+		t.Errorf("B2 = %q after Set, want 7", got)
+	}
+
+	if err := sheet.Range("A1:B1").SetValues([][]any{{1, 2, 3}}).Err(); err == nil {
+		t.Error("expected an error for values wider than the range")
+	}
+	if err := sheet.Range("A1:B1").SetValues([][]any{{1}, {2}}).Err(); err == nil {
+		t.Error("expected an error for values taller than the range")
+	}
+	if err := sheet.Range("Other!A1:B2").Err(); err == nil {
+		t.Error("expected an error for a range on another sheet")
+	}
+}
+
+func TestRange_StyleAndClear(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	r := sheet.Range("A1:B2").SetValues([][]any{{1, 2}, {3, 4}}).Style(document.CellStyle{Bold: true})
+	if err := r.Err(); err != nil {
+		t.Fatalf("Style failed: %v", err)
+	}
+	if style, _ := sheet.Cell("B2").GetStyle(); !style.Bold {
+		t.Error("expected B2 to be bold")
+	}
+
+	sheet.Range("A1:A2").Clear(document.ClearContents)
+	checkValues(t, r, [][]string{{"", "2"}, {"", "4"}})
+	if style, _ := sheet.Cell("A1").GetStyle(); !style.Bold {
+		t.Error("expected ClearContents to keep the style of A1")
+	}
+
+	sheet.Range("B1").Clear(document.ClearFormats)
+	if style, _ := sheet.Cell("B1").GetStyle(); style.Bold {
+		t.Error("expected ClearFormats to reset the style of B1")
+	}
+	if got, _ := sheet.Cell("B1").Get(); got != "2" {
+		t.Errorf("B1 = %q after ClearFormats, want 2", got)
+	}
+
+	sheet.Range("A1:B2").Clear(document.ClearAll)
+	if rows := doc.sheets["Data"].SheetData.Rows; len(rows) != 0 {
+		t.Errorf("expected no rows after ClearAll, got %d", len(rows))
+	}
+
+	// An emptied row that is hidden stays hidden.
+	sheet.Cell("A3").Set("x")
+	doc.sheets["Data"].SheetData.Rows[0].Hidden = 1
+	sheet.Range("A3").Clear(document.ClearAll)
+	if rows := doc.sheets["Data"].SheetData.Rows; len(rows) != 1 || rows[0].Hidden != 1 {
+		t.Errorf("rows after clearing a hidden row = %+v, want it kept", rows)
+	}
+}
+
+func TestRange_CopyAndMove(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:B2").SetValues([][]any{{1, 2}, {3, nil}})
+	sheet.Cell("B2").Formula("A2*$A$1")
+	sheet.Cell("E2").Set("overwritten")
+	sheet.Range("A1:B2").CopyTo("D1")
+	if err := sheet.Err(); err != nil {
+		t.Fatal(err)
+	}
+	checkValues(t, sheet.Range("D1:E2"), [][]string{{"1", "2"}, {"3", "3"}})
+	if f := doc.sheets["Data"].SheetData.Rows[1].Cells[3].F; f == nil || f.Text != "D2*$A$1" {
+		t.Errorf("copied formula = %+v, want D2*$A$1", f)
+	}
+
+	sheet.Cell("G1").Formula("SUM(A1:B2)+A1")
+	sheet.Cell("G2").Formula("H5")
+	sheet.Cell("H5").Set(10)
+	r := sheet.Range("A1:B2").MoveTo("H4")
+	if err := r.Err(); err != nil {
+		t.Fatalf("MoveTo failed: %v", err)
+	}
+	if r.Ref() != "H4:I5" {
+		t.Errorf("moved Ref = %q, want H4:I5", r.Ref())
+	}
+	checkValues(t, r, [][]string{{"1", "2"}, {"3", "3"}})
+	checkValues(t, sheet.Range("A1:B2"), [][]string{{"", ""}, {"", ""}})
+	for axis, want := range map[string]string{"G1": "SUM(H4:I5)+H4", "G2": "#REF!", "I5": "H5*$H$4"} {
+		cell, _ := doc.lookupCell("Data", axis)
+		if cell == nil || cell.F == nil || cell.F.Text != want {
+			t.Errorf("%s formula = %+v, want %s", axis, cell, want)
+		}
+	}
+	if got, _ := sheet.Cell("G1").Get(); got != "10" {
+		t.Errorf("G1 = %q, want 10", got)
+	}
+
+	if err := sheet.Range("A1:B2").CopyTo("XFD1").Err(); err == nil {
+		t.Error("expected an error for a copy off the grid")
+	}
+}
+
+func TestRange_MoveKeepsRanges(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:B3").SetValues([][]any{{"Title"}, {1, 2}, {3, 4}})
+	sheet.MergeCells("A1:B1").
+		SetConditionalFormatting("A2:B3", document.CellStyle{Bold: true}).
+		SetConditionalFormatting("I5", document.CellStyle{Italic: true}).
+		SetDataValidation("A3", "x", "y").
+		AddChart(document.ChartSpec{
+			Type:   document.ChartColumn,
+			Range:  "D10:H20",
+			Series: []document.ChartSeries{{Categories: "A2:A3", Values: "B2:B3"}},
+		})
+	sheet.Range("A1:B3").MoveTo("H4")
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("MoveTo failed: %v", err)
+	}
+
+	ws := doc.sheets["Data"]
+	if ws.MergeCells == nil || len(ws.MergeCells.Items) != 1 || ws.MergeCells.Items[0].Ref != "H4:I4" {
+		t.Errorf("merges = %+v, want H4:I4", ws.MergeCells)
+	}
+	if len(ws.ConditionalFormatting) != 1 || ws.ConditionalFormatting[0].Sqref != "H5:I6" {
+		t.Errorf("conditional formats = %+v, want only H5:I6, the one on the overwritten I5 dropped", ws.ConditionalFormatting)
+	}
+	if ws.DataValidations == nil || len(ws.DataValidations.Items) != 1 || ws.DataValidations.Items[0].Sqref != "H6" {
+		t.Errorf("data validations = %+v, want H6", ws.DataValidations)
+	}
+	var formulas []string
+	for _, cs := range doc.charts {
+		for _, f := range cs.Formulas() {
+			formulas = append(formulas, *f)
+		}
+	}
+	if got := strings.Join(formulas, " "); !strings.Contains(got, "$H$5:$H$6") || !strings.Contains(got, "$I$5:$I$6") {
+		t.Errorf("chart series = %s, want them moved to H5:I6", got)
+	}
+}
+
+func TestRange_Fill(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:B1").SetValues([][]any{{5, "x"}})
+	sheet.Cell("C1").Formula("A1*2")
+	sheet.Range("A1:C3").Fill(document.FillDown)
+	checkValues(t, sheet.Range("A1:C3"), [][]string{{"5", "x", "10"}, {"5", "x", "10"}, {"5", "x", "10"}})
+	if cell, _ := doc.lookupCell("Data", "C3"); cell.F.Text != "A3*2" {
+		t.Errorf("filled formula = %q, want A3*2", cell.F.Text)
+	}
+
+	sheet.Cell("E1").Set("r")
+	sheet.Range("E1:G1").Fill(document.FillRight)
+	checkValues(t, sheet.Range("E1:G1"), [][]string{{"r", "r", "r"}})
+
+	sheet.Range("A5:B6").SetValues([][]any{{1, 10}, {nil, 7.5}})
+	sheet.Range("A5:B9").Fill(document.FillSeries)
+	checkValues(t, sheet.Range("A5:B9"), [][]string{{"1", "10"}, {"2", "7.5"}, {"3", "5"}, {"4", "2.5"}, {"5", "0"}})
+
+	sheet.Range("A11:D11").SetValues([][]any{{0.1, 0.2}})
+	sheet.Range("A11:D11").Fill(document.FillSeries)
+	checkValues(t, sheet.Range("A11:D11"), [][]string{{"0.1", "0.2", "0.3", "0.4"}})
+}
+
+func TestRange_Sort(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:B6").SetValues([][]any{
+		{"pear", 3},
+		{"Apple", 1},
+		{nil, 9},
+		{"apple", 2},
+		{7, 0},
+		{true, 5},
+	})
+	sheet.Cell("C1").Formula("B1*10")
+	r := sheet.Range("A1:C6").Sort(document.SortKey{Column: "A"}, document.SortKey{Column: "B", Descending: true})
+	if err := r.Err(); err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	checkValues(t, r, [][]string{
+		{"7", "0", ""},
+		{"apple", "2", ""},
+		{"Apple", "1", ""},
+		{"pear", "3", "30"},
+		{"1", "5", ""},
+		{"", "9", ""},
+	})
+
+	r.Sort(document.SortKey{Column: "B", Descending: true})
+	checkValues(t, sheet.Range("B1:B6"), [][]string{{"9"}, {"5"}, {"3"}, {"2"}, {"1"}, {"0"}})
+
+	if err := sheet.Range("A1:B2").Sort(document.SortKey{Column: "D"}).Err(); err == nil {
+		t.Error("expected an error for a sort column outside the range")
+	}
+}
+
+func TestRange_AutoFitColumnsAndSave(t *testing.T) {
+	ctx := t.Context()
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:B2").SetValues([][]any{{"short", nil}, {"a much longer value", nil}})
+	sheet.Range("A1:B2").AutoFitColumns()
+	if err := sheet.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if width, _ := sheet.ColumnWidth(1); width < 15 {
+		t.Errorf("column A width = %v, want at least 15", width)
+	}
+	if width, _ := sheet.ColumnWidth(2); width != 0 {
+		t.Errorf("empty column B width = %v, want the default", width)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Data")
+	checkValues(t, s.Range("A1:A2"), [][]string{{"short"}, {"a much longer value"}})
+}
@@ -2,21 +2,29 @@ package excel
 
 import (
 	"fmt"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gsoultan/thoth/document"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
 )
 
+const (
+	worksheetRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	tableRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/table"
+	tableContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml"
+
+	maxSheetName = 31 // Characters
+)
+
 type sheetProcessor struct{ *state }
 
 func (e *sheetProcessor) addSheet(name string) error {
-	if name == "" {
-		return fmt.Errorf("sheet name cannot be empty")
-	}
-	if len(name) > 31 {
-		return fmt.Errorf("sheet name cannot exceed 31 characters")
+	if err := e.checkSheetName(name, ""); err != nil {
+		return err
 	}
 	if e.workbook == nil {
 		e.workbook = &xmlstructs.Workbook{
@@ -24,19 +32,20 @@ func (e *sheetProcessor) addSheet(name string) error {
 		}
 	}
 
-	for _, s := range e.workbook.Sheets {
-		if s.Name == name {
-			return fmt.Errorf("sheet %s already exists", name)
-		}
-	}
-
 	maxSheetID := 0
 	for _, s := range e.workbook.Sheets {
 		sid, _ := strconv.Atoi(s.SheetID)
 		maxSheetID = max(maxSheetID, sid)
 	}
 	sheetID := maxSheetID + 1
-	rID := fmt.Sprintf("rId%d", sheetID+100)
+
+	if e.workbookRels == nil {
+		e.workbookRels = &xmlstructs.Relationships{}
+	}
+	// Parts of deleted sheets may still be in the source package, so the
+	// part name is not derived from the sheet ID.
+	target := strings.TrimPrefix(e.nextPartPath("xl/worksheets/sheet%d.xml"), "xl/")
+	rID := e.workbookRels.AddRelationship(worksheetRelType, target)
 
 	e.workbook.Sheets = append(e.workbook.Sheets, xmlstructs.Sheet{
 		Name:    name,
@@ -44,16 +53,6 @@ func (e *sheetProcessor) addSheet(name string) error {
 		RID:     rID,
 	})
 
-	if e.workbookRels == nil {
-		e.workbookRels = &xmlstructs.Relationships{}
-	}
-	target := fmt.Sprintf("worksheets/sheet%d.xml", sheetID)
-	e.workbookRels.Rels = append(e.workbookRels.Rels, xmlstructs.Relationship{
-		ID:     rID,
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet",
-		Target: target,
-	})
-
 	tabSelected := 0
 	if len(e.workbook.Sheets) == 1 {
 		tabSelected = 1
@@ -74,11 +73,35 @@ func (e *sheetProcessor) addSheet(name string) error {
 	return nil
 }
 
+// checkSheetName rejects names Excel does not accept for a sheet and names used by
+// another sheet than current. Sheet names are compared case-insensitively.
+func (e *sheetProcessor) checkSheetName(name, current string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("sheet name cannot be empty")
+	case utf8.RuneCountInString(name) > maxSheetName:
+		return fmt.Errorf("sheet name cannot exceed %d characters", maxSheetName)
+	case strings.ContainsAny(name, `:\/?*[]`):
+		return fmt.Errorf("sheet name %q cannot contain any of : \\ / ? * [ ]", name)
+	case strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'"):
+		return fmt.Errorf("sheet name %q cannot start or end with an apostrophe", name)
+	}
+	if e.workbook == nil {
+		return nil
+	}
+	for _, s := range e.workbook.Sheets {
+		if strings.EqualFold(s.Name, name) && s.Name != current {
+			return fmt.Errorf("sheet %s already exists", name)
+		}
+	}
+	return nil
+}
+
 func (e *sheetProcessor) mergeCells(sheet, hRange string) error {
 	if sheet == "" || hRange == "" {
 		return fmt.Errorf("sheet and range cannot be empty")
 	}
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -97,7 +120,7 @@ func (e *sheetProcessor) setColumnWidth(sheet string, col int, width float64) er
 	if sheet == "" || col < 1 || width < 0 {
 		return fmt.Errorf("invalid parameters for column width")
 	}
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -106,17 +129,35 @@ func (e *sheetProcessor) setColumnWidth(sheet string, col int, width float64) er
 		ws.Cols = &xmlstructs.Cols{Items: make([]xmlstructs.Col, 0)}
 	}
 
-	for i := range ws.Cols.Items {
-		if ws.Cols.Items[i].Min <= col && ws.Cols.Items[i].Max >= col {
-			if ws.Cols.Items[i].Min == col && ws.Cols.Items[i].Max == col {
-				ws.Cols.Items[i].Width = width
-				ws.Cols.Items[i].CustomWidth = 1
-				return nil
-			}
+	for i, c := range ws.Cols.Items {
+		if c.Min > col || c.Max < col {
+			continue
+		}
+		// A span of several columns is split so that only col changes and the
+		// spans do not overlap.
+		var spans []xmlstructs.Col
+		if c.Min < col {
+			before := c
+			before.Max = col - 1
+			spans = append(spans, before)
 		}
+		own := c
+		own.Min, own.Max, own.Width, own.CustomWidth = col, col, width, 1
+		spans = append(spans, own)
+		if c.Max > col {
+			after := c
+			after.Min = col + 1
+			spans = append(spans, after)
+		}
+		ws.Cols.Items = slices.Replace(ws.Cols.Items, i, i+1, spans...)
+		return nil
 	}
 
-	ws.Cols.Items = append(ws.Cols.Items, xmlstructs.Col{
+	at := slices.IndexFunc(ws.Cols.Items, func(c xmlstructs.Col) bool { return c.Min > col })
+	if at < 0 {
+		at = len(ws.Cols.Items)
+	}
+	ws.Cols.Items = slices.Insert(ws.Cols.Items, at, xmlstructs.Col{
 		Min:         col,
 		Max:         col,
 		Width:       width,
@@ -130,7 +171,7 @@ func (e *sheetProcessor) setRowHeight(sheet string, row int, height float64) err
 	if sheet == "" || row < 1 || height < 0 {
 		return fmt.Errorf("invalid parameters for row height")
 	}
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -170,7 +211,7 @@ func (e *sheetProcessor) setRowHeight(sheet string, row int, height float64) err
 }
 
 func (e *sheetProcessor) autoFilter(sheet, ref string) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -179,7 +220,7 @@ func (e *sheetProcessor) autoFilter(sheet, ref string) error {
 }
 
 func (e *sheetProcessor) freezePanes(sheet string, col, row int) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -198,8 +239,8 @@ func (e *sheetProcessor) freezePanes(sheet string, col, row int) error {
 	}
 
 	ws.SheetViews.Items[0].Pane = &xmlstructs.Pane{
-		XSplit:      col,
-		YSplit:      row,
+		XSplit:      float64(col),
+		YSplit:      float64(row),
 		TopLeftCell: fmt.Sprintf("%s%d", string(rune('A'+col)), row+1),
 		ActivePane:  activePane,
 		State:       "frozen",
@@ -217,6 +258,8 @@ func (e *sheetProcessor) setNamedRange(name, ref string) error {
 		e.workbook.DefinedNames = &xmlstructs.DefinedNames{Items: make([]xmlstructs.DefinedName, 0)}
 	}
 
+	e.calcDirty = true
+
 	// Check if name already exists
 	for i, dn := range e.workbook.DefinedNames.Items {
 		if dn.Name == name {
@@ -234,7 +277,7 @@ func (e *sheetProcessor) setNamedRange(name, ref string) error {
 }
 
 func (e *sheetProcessor) setPageSettings(sheet string, settings document.PageSettings) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -253,16 +296,18 @@ func (e *sheetProcessor) setPageSettings(sheet string, settings document.PageSet
 		orientation = "landscape"
 	}
 
-	ws.PageSetup = &xmlstructs.PageSetup{
-		Orientation: orientation,
-		PaperSize:   paperSizeToInt(settings.PaperType),
+	// Scaling and print quality set by SetPrintOptions are kept.
+	if ws.PageSetup == nil {
+		ws.PageSetup = &xmlstructs.PageSetup{}
 	}
+	ws.PageSetup.Orientation = orientation
+	ws.PageSetup.PaperSize = paperSizeToInt(settings.PaperType)
 
 	return nil
 }
 
 func (e *sheetProcessor) protect(sheet string, password string) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -283,7 +328,7 @@ func (e *sheetProcessor) protect(sheet string, password string) error {
 }
 
 func (e *sheetProcessor) groupRows(sheet string, start, end int, level int) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -338,7 +383,7 @@ func (e *sheetProcessor) groupRows(sheet string, start, end int, level int) erro
 }
 
 func (e *sheetProcessor) groupCols(sheet string, start, end int, level int) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -400,7 +445,7 @@ func paperSizeToInt(p document.PaperType) int {
 }
 
 func (e *sheetProcessor) setHeader(sheet string, text string) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -413,7 +458,7 @@ func (e *sheetProcessor) setHeader(sheet string, text string) error {
 }
 
 func (e *sheetProcessor) setFooter(sheet string, text string) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
@@ -425,31 +470,6 @@ func (e *sheetProcessor) setFooter(sheet string, text string) error {
 	return nil
 }
 
-func (e *sheetProcessor) setDataValidation(sheet, ref string, options ...string) error {
-	ws, ok := e.sheets[sheet]
-	if !ok {
-		return fmt.Errorf("sheet %s not found", sheet)
-	}
-
-	if ws.DataValidations == nil {
-		ws.DataValidations = &xmlstructs.DataValidations{Items: make([]xmlstructs.DataValidation, 0)}
-	}
-
-	dv := xmlstructs.DataValidation{
-		Type:         "list",
-		AllowBlank:   1,
-		ShowInputMsg: 1,
-		ShowErrorMsg: 1,
-		Sqref:        ref,
-		Formula1:     fmt.Sprintf("\"%s\"", strings.Join(options, ",")),
-	}
-
-	ws.DataValidations.Items = append(ws.DataValidations.Items, dv)
-	ws.DataValidations.Count = len(ws.DataValidations.Items)
-
-	return nil
-}
-
 func (e *sheetProcessor) SetPageSettings(settings document.PageSettings) error {
 	if e.workbook == nil {
 		e.workbook = &xmlstructs.Workbook{
@@ -457,7 +477,11 @@ func (e *sheetProcessor) SetPageSettings(settings document.PageSettings) error {
 		}
 	}
 
-	for _, ws := range e.sheets {
+	for _, s := range e.workbook.Sheets {
+		ws, ok := e.worksheet(s.Name)
+		if !ok {
+			continue
+		}
 		if ws.PageSetup == nil {
 			ws.PageSetup = &xmlstructs.PageSetup{}
 		}
@@ -473,6 +497,8 @@ func (e *sheetProcessor) SetPageSettings(settings document.PageSettings) error {
 			ws.PageSetup.PaperSize = 9
 		case document.PaperLetter:
 			ws.PageSetup.PaperSize = 1
+		case document.PaperLegal:
+			ws.PageSetup.PaperSize = 5
 		}
 
 		ws.PageMargins = &xmlstructs.PageMargins{
@@ -606,13 +632,17 @@ func numToCol(num int) string {
 }
 
 func (e *sheetProcessor) addTable(sheet, ref, name string) error {
-	ws, ok := e.sheets[sheet]
+	ws, ok := e.worksheet(sheet)
 	if !ok {
 		return fmt.Errorf("sheet %s not found", sheet)
 	}
 
-	tableID := len(e.tables) + 1
-	tablePath := fmt.Sprintf("xl/tables/table%d.xml", tableID)
+	_, tableID, err := e.tableNames()
+	if err != nil {
+		return err
+	}
+	tableID++
+	tablePath := e.nextPartPath("xl/tables/table%d.xml")
 
 	table := &xmlstructs.Table{
 		ID:          tableID,
@@ -666,8 +696,7 @@ func (e *sheetProcessor) addTable(sheet, ref, name string) error {
 	}
 	sRels := e.sheetRels[sheet]
 
-	relPath := fmt.Sprintf("../tables/table%d.xml", tableID)
-	rID := sRels.AddRelationship("http://schemas.openxmlformats.org/officeDocument/2006/relationships/table", relPath)
+	rID := sRels.AddRelationship(tableRelType, "../tables/"+path.Base(tablePath))
 
 	ws.TableParts.Items = append(ws.TableParts.Items, xmlstructs.TablePart{RID: rID})
 	ws.TableParts.Count = len(ws.TableParts.Items)
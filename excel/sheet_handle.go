@@ -2,6 +2,8 @@ package excel
 
 import (
 	"context"
+	"io"
+	"iter"
 
 	"github.com/gsoultan/thoth/document"
 )
@@ -14,10 +16,23 @@ type sheetHandle struct {
 	err  error
 }
 
+// context returns the document context, or a background context when none was set.
+func (s *sheetHandle) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 func (s *sheetHandle) Cell(axis string) document.Cell {
 	return &cellHandle{sheet: s, axis: axis}
 }
 
+func (s *sheetHandle) Range(ref string) document.Range {
+	area, err := parseArea(ref)
+	return &rangeHandle{sheet: s, area: area, err: err}
+}
+
 func (s *sheetHandle) MergeCells(hRange string) document.Sheet {
 	if s.err != nil {
 		return s
@@ -42,6 +57,22 @@ func (s *sheetHandle) SetRowHeight(row int, height float64) document.Sheet {
 	return s
 }
 
+func (s *sheetHandle) AutoFitColumns(cols ...int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().autoFitColumns(s.name, cols)
+	return s
+}
+
+func (s *sheetHandle) AutoFitRows(rows ...int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().autoFitRows(s.name, rows)
+	return s
+}
+
 func (s *sheetHandle) AutoFilter(ref string) document.Sheet {
 	if s.err != nil {
 		return s
@@ -66,11 +97,71 @@ func (s *sheetHandle) InsertImage(path string, x, y float64) document.Sheet {
 	return s
 }
 
+func (s *sheetHandle) InsertRows(row, count int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().insertRows(s.name, row, count)
+	return s
+}
+
+func (s *sheetHandle) DeleteRows(row, count int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().deleteRows(s.name, row, count)
+	return s
+}
+
+func (s *sheetHandle) InsertCols(col, count int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().insertCols(s.name, col, count)
+	return s
+}
+
+func (s *sheetHandle) DeleteCols(col, count int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().deleteCols(s.name, col, count)
+	return s
+}
+
+func (s *sheetHandle) AddChart(spec document.ChartSpec) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().addChart(s.name, spec)
+	return s
+}
+
+func (s *sheetHandle) AddPivotTable(sourceRef, targetCell string, spec document.PivotSpec) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().addPivotTable(s.name, sourceRef, targetCell, spec)
+	return s
+}
+
 func (s *sheetHandle) SetDataValidation(ref string, options ...string) document.Sheet {
 	if s.err != nil {
 		return s
 	}
-	s.err = s.processor().sheetProcessor.setDataValidation(s.name, ref, options...)
+	s.err = s.processor().setDataValidation(s.name, ref, document.DataValidation{
+		Type:       document.ValidationList,
+		Options:    options,
+		AllowBlank: true,
+	})
+	return s
+}
+
+func (s *sheetHandle) AddDataValidation(ref string, validation document.DataValidation) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().setDataValidation(s.name, ref, validation)
 	return s
 }
 
@@ -78,7 +169,28 @@ func (s *sheetHandle) SetConditionalFormatting(ref string, style document.CellSt
 	if s.err != nil {
 		return s
 	}
-	s.err = s.processor().setConditionalFormatting(s.name, ref, "cellIs", "greaterThan", "0", style)
+	s.err = s.processor().addConditionalFormat(s.name, ref, []document.ConditionalRule{{
+		Type:     document.ConditionCellIs,
+		Operator: document.OperatorGreaterThan,
+		Formula:  []string{"0"},
+		Style:    style,
+	}})
+	return s
+}
+
+func (s *sheetHandle) AddConditionalFormat(ref string, rules ...document.ConditionalRule) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().addConditionalFormat(s.name, ref, rules)
+	return s
+}
+
+func (s *sheetHandle) AddSparklines(group document.SparklineGroup) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().addSparklines(s.name, group)
 	return s
 }
 
@@ -90,6 +202,53 @@ func (s *sheetHandle) SetPageSettings(settings document.PageSettings) document.S
 	return s
 }
 
+func (s *sheetHandle) SetPrintOptions(opts document.PrintOptions) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().setPrintOptions(s.name, opts)
+	return s
+}
+
+func (s *sheetHandle) InsertRowBreak(row int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().insertPageBreak(s.name, row, false)
+	return s
+}
+
+func (s *sheetHandle) InsertColBreak(col int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().insertPageBreak(s.name, col, true)
+	return s
+}
+
+func (s *sheetHandle) SetView(view document.SheetView) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().setSheetView(s.name, view)
+	return s
+}
+
+func (s *sheetHandle) SplitPanes(col, row int) document.Sheet {
+	if s.err != nil {
+		return s
+	}
+	s.err = s.processor().splitPanes(s.name, col, row)
+	return s
+}
+
+func (s *sheetHandle) View() (document.SheetView, error) {
+	if s.err != nil {
+		return document.SheetView{}, s.err
+	}
+	return s.processor().sheetViewSettings(s.name)
+}
+
 func (s *sheetHandle) Protect(password string) document.Sheet {
 	if s.err != nil {
 		return s
@@ -138,6 +297,10 @@ func (s *sheetHandle) AddTable(ref string, name string) document.Sheet {
 	return s
 }
 
+func (s *sheetHandle) Table(name string) document.SheetTable {
+	return &tableHandle{sheet: s, name: name}
+}
+
 func (s *sheetHandle) SetPrintArea(ref string) document.Sheet {
 	if s.err != nil {
 		return s
@@ -161,6 +324,71 @@ func (s *sheetHandle) GetCellValue(axis string) (string, error) {
 	return s.processor().getCellValue(s.name, axis)
 }
 
+func (s *sheetHandle) MergedCells() ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.processor().mergedCells(s.name)
+}
+
+func (s *sheetHandle) ColumnWidth(col int) (float64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.processor().columnWidth(s.name, col)
+}
+
+func (s *sheetHandle) RowHeight(row int) (float64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.processor().rowHeight(s.name, row)
+}
+
+func (s *sheetHandle) FrozenPanes() (int, int, error) {
+	if s.err != nil {
+		return 0, 0, s.err
+	}
+	return s.processor().frozenPanes(s.name)
+}
+
+func (s *sheetHandle) Hyperlinks() ([]document.Hyperlink, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.processor().hyperlinks(s.name)
+}
+
+func (s *sheetHandle) Comments() ([]document.Comment, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.processor().getComments(s.name)
+}
+
+func (s *sheetHandle) Rows(ctx context.Context) iter.Seq2[document.RowView, error] {
+	if s.err != nil {
+		return func(yield func(document.RowView, error) bool) {
+			yield(document.RowView{}, s.err)
+		}
+	}
+	return s.rows(ctx, s.name)
+}
+
+func (s *sheetHandle) ImportCSV(r io.Reader, opts document.CSVOptions) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.processor().importCSV(s.context(), s.name, r, opts)
+}
+
+func (s *sheetHandle) ExportCSV(w io.Writer, opts document.CSVOptions) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.processor().exportCSV(s.context(), s.name, w, opts)
+}
+
 func (s *sheetHandle) Err() error {
 	return s.err
 }
@@ -221,6 +449,10 @@ func (c *cellHandle) Style(style document.CellStyle) document.Cell {
 }
 
 func (c *cellHandle) Comment(text string) document.Cell {
+	return c.SetComment(document.Comment{Text: text})
+}
+
+func (c *cellHandle) SetComment(comment document.Comment) document.Cell {
 	if c.err != nil {
 		return c
 	}
@@ -228,8 +460,29 @@ func (c *cellHandle) Comment(text string) document.Cell {
 		c.err = c.sheet.err
 		return c
 	}
-	// TODO: Implement actual comments. For now, we'll store it as Alt text in the cell for metadata purposes,
-	// or just a placeholder as VML comments are complex to implement correctly without more infra.
+	c.err = c.sheet.processor().setComment(c.sheet.name, c.axis, comment)
+	return c
+}
+
+func (c *cellHandle) GetComment() (*document.Comment, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.sheet.err != nil {
+		return nil, c.sheet.err
+	}
+	return c.sheet.processor().getComment(c.sheet.name, c.axis)
+}
+
+func (c *cellHandle) RemoveComment() document.Cell {
+	if c.err != nil {
+		return c
+	}
+	if c.sheet.err != nil {
+		c.err = c.sheet.err
+		return c
+	}
+	c.err = c.sheet.processor().removeComment(c.sheet.name, c.axis)
 	return c
 }
 
@@ -243,9 +496,252 @@ func (c *cellHandle) Get() (string, error) {
 	return c.sheet.processor().getCellValue(c.sheet.name, c.axis)
 }
 
+func (c *cellHandle) GetStyle() (document.CellStyle, error) {
+	if c.err != nil {
+		return document.CellStyle{}, c.err
+	}
+	if c.sheet.err != nil {
+		return document.CellStyle{}, c.sheet.err
+	}
+	return c.sheet.processor().getCellStyle(c.sheet.name, c.axis)
+}
+
+func (c *cellHandle) Formatted() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	if c.sheet.err != nil {
+		return "", c.sheet.err
+	}
+	return c.sheet.processor().getFormattedValue(c.sheet.name, c.axis)
+}
+
 func (c *cellHandle) Err() error {
 	if c.err != nil {
 		return c.err
 	}
 	return c.sheet.err
 }
+
+// rangeHandle is a fluent, range-scoped helper implementing document.Range.
+type rangeHandle struct {
+	sheet *sheetHandle
+	area  cellArea
+	err   error
+}
+
+func (r *rangeHandle) Ref() string {
+	return r.area.String()
+}
+
+func (r *rangeHandle) SetValues(values [][]any) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().setRangeValues(r.sheet.name, r.area, values)
+	return r
+}
+
+func (r *rangeHandle) Values() ([][]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.sheet.err != nil {
+		return nil, r.sheet.err
+	}
+	return r.sheet.processor().rangeValues(r.sheet.name, r.area)
+}
+
+func (r *rangeHandle) Style(style document.CellStyle) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().setRangeStyle(r.sheet.name, r.area, style)
+	return r
+}
+
+func (r *rangeHandle) Clear(mode document.ClearMode) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().clearRange(r.sheet.name, r.area, mode)
+	return r
+}
+
+func (r *rangeHandle) CopyTo(target string) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().copyRange(r.sheet.name, r.area, target)
+	return r
+}
+
+func (r *rangeHandle) MoveTo(target string) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.area, r.err = r.sheet.processor().moveRange(r.sheet.name, r.area, target)
+	return r
+}
+
+func (r *rangeHandle) Fill(direction document.FillDirection) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().fillRange(r.sheet.name, r.area, direction)
+	return r
+}
+
+func (r *rangeHandle) Sort(keys ...document.SortKey) document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().sortRange(r.sheet.name, r.area, keys)
+	return r
+}
+
+func (r *rangeHandle) AutoFitColumns() document.Range {
+	if r.err != nil {
+		return r
+	}
+	if r.sheet.err != nil {
+		r.err = r.sheet.err
+		return r
+	}
+	r.err = r.sheet.processor().autoFitRange(r.sheet.name, r.area)
+	return r
+}
+
+func (r *rangeHandle) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.sheet.err
+}
+
+// tableHandle is a fluent, table-scoped helper implementing document.SheetTable.
+type tableHandle struct {
+	sheet *sheetHandle
+	name  string
+	err   error
+}
+
+func (t *tableHandle) Name() string {
+	return t.name
+}
+
+func (t *tableHandle) SetStyle(style document.TableStyle) document.SheetTable {
+	if t.err != nil {
+		return t
+	}
+	if t.sheet.err != nil {
+		t.err = t.sheet.err
+		return t
+	}
+	t.err = t.sheet.processor().setTableStyle(t.sheet.name, t.name, style)
+	return t
+}
+
+func (t *tableHandle) SetTotalsRow(totals ...document.TableTotal) document.SheetTable {
+	if t.err != nil {
+		return t
+	}
+	if t.sheet.err != nil {
+		t.err = t.sheet.err
+		return t
+	}
+	t.err = t.sheet.processor().setTotalsRow(t.sheet.name, t.name, totals)
+	return t
+}
+
+func (t *tableHandle) SetCalculatedColumn(header, formula string) document.SheetTable {
+	if t.err != nil {
+		return t
+	}
+	if t.sheet.err != nil {
+		t.err = t.sheet.err
+		return t
+	}
+	t.err = t.sheet.processor().setCalculatedColumn(t.sheet.name, t.name, header, formula)
+	return t
+}
+
+func (t *tableHandle) Resize(ref string) document.SheetTable {
+	if t.err != nil {
+		return t
+	}
+	if t.sheet.err != nil {
+		t.err = t.sheet.err
+		return t
+	}
+	t.err = t.sheet.processor().resizeTable(t.sheet.name, t.name, ref)
+	return t
+}
+
+func (t *tableHandle) AppendRows(values [][]any) document.SheetTable {
+	if t.err != nil {
+		return t
+	}
+	if t.sheet.err != nil {
+		t.err = t.sheet.err
+		return t
+	}
+	t.err = t.sheet.processor().appendTableRows(t.sheet.name, t.name, values)
+	return t
+}
+
+func (t *tableHandle) Info() (document.TableInfo, error) {
+	if err := t.Err(); err != nil {
+		return document.TableInfo{}, err
+	}
+	return t.sheet.processor().tableInfo(t.sheet.name, t.name)
+}
+
+func (t *tableHandle) Records() ([]map[string]string, error) {
+	if err := t.Err(); err != nil {
+		return nil, err
+	}
+	return t.sheet.processor().tableRecords(t.sheet.name, t.name)
+}
+
+func (t *tableHandle) Column(header string) ([]string, error) {
+	if err := t.Err(); err != nil {
+		return nil, err
+	}
+	return t.sheet.processor().tableColumn(t.sheet.name, t.name, header)
+}
+
+func (t *tableHandle) Err() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.sheet.err
+}
@@ -0,0 +1,98 @@
+package excel
+
+import (
+	"fmt"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// sheetWorksheet returns the worksheet of a sheet for reading its structure.
+func (e *sheetProcessor) sheetWorksheet(sheet string) (*xmlstructs.Worksheet, error) {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	return ws, nil
+}
+
+func (e *sheetProcessor) mergedCells(sheet string) ([]string, error) {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil || ws.MergeCells == nil {
+		return nil, err
+	}
+	refs := make([]string, 0, len(ws.MergeCells.Items))
+	for _, mc := range ws.MergeCells.Items {
+		refs = append(refs, mc.Ref)
+	}
+	return refs, nil
+}
+
+func (e *sheetProcessor) columnWidth(sheet string, col int) (float64, error) {
+	if col < 1 {
+		return 0, fmt.Errorf("invalid column %d", col)
+	}
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil || ws.Cols == nil {
+		return 0, err
+	}
+	for _, c := range ws.Cols.Items {
+		if c.Min <= col && col <= c.Max {
+			return c.Width, nil
+		}
+	}
+	return 0, nil
+}
+
+func (e *sheetProcessor) rowHeight(sheet string, row int) (float64, error) {
+	if row < 1 {
+		return 0, fmt.Errorf("invalid row %d", row)
+	}
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range ws.SheetData.Rows {
+		if r.R == row {
+			return r.Ht, nil
+		}
+	}
+	return 0, nil
+}
+
+// frozenPanes returns the split of the first frozen pane of the sheet's views.
+func (e *sheetProcessor) frozenPanes(sheet string) (int, int, error) {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil || ws.SheetViews == nil {
+		return 0, 0, err
+	}
+	for _, view := range ws.SheetViews.Items {
+		if p := view.Pane; p != nil && (p.State == "frozen" || p.State == "frozenSplit") {
+			return int(p.XSplit), int(p.YSplit), nil
+		}
+	}
+	return 0, 0, nil
+}
+
+// hyperlinks returns the sheet's hyperlinks, resolving external targets through
+// the sheet relationships.
+func (e *sheetProcessor) hyperlinks(sheet string) ([]document.Hyperlink, error) {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil || ws.Hyperlinks == nil {
+		return nil, err
+	}
+	links := make([]document.Hyperlink, 0, len(ws.Hyperlinks.Items))
+	for _, h := range ws.Hyperlinks.Items {
+		link := document.Hyperlink{Ref: h.Ref, Location: h.Location, Display: h.Display, Tooltip: h.Tooltip}
+		if rels := e.sheetRels[sheet]; h.RID != "" && rels != nil {
+			for _, rel := range rels.Rels {
+				if rel.ID == h.RID {
+					link.URL = rel.Target
+					break
+				}
+			}
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -0,0 +1,104 @@
+package excel
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestSheetStructureGetters(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	style := document.CellStyle{
+		Bold: true, Italic: true, Size: 14, Color: "FF0000", Font: "Arial",
+		Background: "FFFF00", Border: true, BorderTop: true, BorderBottom: true, BorderLeft: true, BorderRight: true,
+		BorderWidth: 2, BorderColor: "0000FF", Horizontal: "center", Vertical: "top", WrapText: true,
+		NumberFormat: "#,##0.00",
+	}
+	sheet, _ := doc.Sheet("Sheet1")
+	sheet.Cell("B2").Set(1234.5).Style(style)
+	sheet.Cell("C3").Set("link").Hyperlink("https://example.com")
+	sheet.MergeCells("A5:C5").MergeCells("D1:D3").SetColumnWidth(2, 18.5).SetRowHeight(4, 30).FreezePanes(1, 2)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("Building sheet failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+
+	got, err := s.Cell("B2").GetStyle()
+	if err != nil {
+		t.Fatalf("GetStyle failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, style) {
+		t.Errorf("Expected style %+v, got %+v", style, got)
+	}
+	if plain, _ := s.Cell("Z99").GetStyle(); plain.Bold || plain.Size != 11 || plain.Font != "Calibri" || plain.NumberFormat != "" {
+		t.Errorf("Expected the default style for an empty cell, got %+v", plain)
+	}
+
+	if merged, _ := s.MergedCells(); !slices.Equal(merged, []string{"A5:C5", "D1:D3"}) {
+		t.Errorf("Unexpected merged cells %v", merged)
+	}
+	if w, _ := s.ColumnWidth(2); w != 18.5 {
+		t.Errorf("Expected column width 18.5, got %v", w)
+	}
+	if w, _ := s.ColumnWidth(3); w != 0 {
+		t.Errorf("Expected the default column width, got %v", w)
+	}
+	if h, _ := s.RowHeight(4); h != 30 {
+		t.Errorf("Expected row height 30, got %v", h)
+	}
+	if col, row, _ := s.FrozenPanes(); col != 1 || row != 2 {
+		t.Errorf("Expected frozen panes 1,2, got %d,%d", col, row)
+	}
+	links, err := s.Hyperlinks()
+	if err != nil {
+		t.Fatalf("Hyperlinks failed: %v", err)
+	}
+	if want := []document.Hyperlink{{Ref: "C3", URL: "https://example.com"}}; !slices.Equal(links, want) {
+		t.Errorf("Expected hyperlinks %+v, got %+v", want, links)
+	}
+
+	// Copy the formatting into another workbook.
+	target := NewDocument().(*Document)
+	defer target.Close()
+	ts, _ := target.Sheet("Copy")
+	if copied, _ := ts.Cell("A1").Style(got).GetStyle(); !reflect.DeepEqual(copied, style) {
+		t.Errorf("Expected the copied style %+v, got %+v", style, copied)
+	}
+}
+
+func TestColorHex(t *testing.T) {
+	indexed, theme := 10, 1
+	for _, tt := range []struct {
+		color *xmlstructs.Color
+		want  string
+	}{
+		{&xmlstructs.Color{RGB: "FF00B050"}, "00B050"},
+		{&xmlstructs.Color{RGB: "00B050"}, "00B050"},
+		{&xmlstructs.Color{Indexed: &indexed}, "FF0000"},
+		{&xmlstructs.Color{Theme: &theme}, ""},
+		{nil, ""},
+	} {
+		if got := colorHex(tt.color); got != tt.want {
+			t.Errorf("colorHex(%+v) = %q, want %q", tt.color, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package excel
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const (
+	defaultRowHeight = 15.0 // Height of rows without a height, in points
+	twipsPerPixel    = 15   // Twentieths of a point in a pixel at 96 dpi
+)
+
+// sheetView returns the first view of a sheet, adding one when it has none.
+func sheetView(ws *xmlstructs.Worksheet) *xmlstructs.SheetView {
+	if ws.SheetViews == nil || len(ws.SheetViews.Items) == 0 {
+		ws.SheetViews = &xmlstructs.SheetViews{Items: []xmlstructs.SheetView{{}}}
+	}
+	return &ws.SheetViews.Items[0]
+}
+
+// sheetPr returns the properties of a sheet, adding them when it has none.
+func sheetPr(ws *xmlstructs.Worksheet) *xmlstructs.SheetPr {
+	if ws.SheetPr == nil {
+		ws.SheetPr = &xmlstructs.SheetPr{}
+	}
+	return ws.SheetPr
+}
+
+// shownFlag returns the value of a view attribute that defaults to shown.
+func shownFlag(hide bool) *int {
+	if hide {
+		return new(0)
+	}
+	return nil
+}
+
+func (e *sheetProcessor) setSheetView(sheet string, view document.SheetView) error {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return err
+	}
+	if view.Zoom != 0 && (view.Zoom < 10 || view.Zoom > 400) {
+		return fmt.Errorf("zoom %d%% is outside 10%% to 400%%", view.Zoom)
+	}
+	v := sheetView(ws)
+	v.ZoomScale, v.ZoomScaleNormal = view.Zoom, 0
+	if view.Zoom == 100 {
+		v.ZoomScale = 0
+	}
+	v.ShowGridLines = shownFlag(view.HideGridlines)
+	v.ShowRowColHeaders = shownFlag(view.HideHeadings)
+	v.RightToLeft = boolToInt(view.RightToLeft)
+
+	if view.TabColor != "" {
+		sheetPr(ws).TabColor = &xmlstructs.Color{RGB: argb(view.TabColor)}
+	} else if ws.SheetPr != nil {
+		ws.SheetPr.TabColor = nil
+	}
+	return nil
+}
+
+func (e *sheetProcessor) sheetViewSettings(sheet string) (document.SheetView, error) {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return document.SheetView{}, err
+	}
+	var view document.SheetView
+	if ws.SheetViews != nil && len(ws.SheetViews.Items) > 0 {
+		v := ws.SheetViews.Items[0]
+		view.Zoom = v.ZoomScale
+		view.HideGridlines = v.ShowGridLines != nil && *v.ShowGridLines == 0
+		view.HideHeadings = v.ShowRowColHeaders != nil && *v.ShowRowColHeaders == 0
+		view.RightToLeft = v.RightToLeft == 1
+	}
+	if view.Zoom == 0 {
+		view.Zoom = 100
+	}
+	if ws.SheetPr != nil {
+		view.TabColor = colorHex(ws.SheetPr.TabColor)
+	}
+	return view, nil
+}
+
+// splitPanes splits the first view of a sheet after col columns and row rows
+// into panes that scroll separately. Excel positions the split in twentieths
+// of a point, which are computed from the widths and heights of the columns
+// and rows before it.
+func (e *sheetProcessor) splitPanes(sheet string, col, row int) error {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return err
+	}
+	if col < 0 || row < 0 || col == 0 && row == 0 || col >= formula.MaxColumns || row >= formula.MaxRows {
+		return fmt.Errorf("invalid split after %d columns and %d rows", col, row)
+	}
+
+	x := 0.0
+	for c := 1; c <= col; c++ {
+		width, _ := e.columnWidth(sheet, c)
+		if width == 0 {
+			width = defaultColumnWidth + cellPadding
+		}
+		x += columnPixels(width) * twipsPerPixel
+	}
+	y := 0.0
+	for _, r := range ws.SheetData.Rows {
+		if r.R <= row && r.Ht > 0 {
+			y += (r.Ht - defaultRowHeight) * 20
+		}
+	}
+	y += float64(row) * defaultRowHeight * 20
+
+	activePane := "bottomRight"
+	if col > 0 && row == 0 {
+		activePane = "topRight"
+	} else if col == 0 && row > 0 {
+		activePane = "bottomLeft"
+	}
+	sheetView(ws).Pane = &xmlstructs.Pane{
+		XSplit:      x,
+		YSplit:      y,
+		TopLeftCell: formula.CellName(col+1, row+1),
+		ActivePane:  activePane,
+		State:       "split",
+	}
+	return nil
+}
+
+// columnPixels converts a column width in characters of the default font to
+// pixels, as Excel does for a 7 pixel digit.
+func columnPixels(width float64) float64 {
+	return math.Trunc((256*width + math.Trunc(128.0/7)) / 256 * 7)
+}
+
+// insertPageBreak starts a new printed page at a 1-based row, or column when
+// cols is set. A break that already exists is kept.
+func (e *sheetProcessor) insertPageBreak(sheet string, index int, cols bool) error {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return err
+	}
+	limit, span := formula.MaxRows, formula.MaxColumns
+	breaks := &ws.RowBreaks
+	if cols {
+		limit, span = formula.MaxColumns, formula.MaxRows
+		breaks = &ws.ColBreaks
+	}
+	if index < 2 || index > limit {
+		return fmt.Errorf("invalid page break at %d", index)
+	}
+	if *breaks == nil {
+		*breaks = &xmlstructs.PageBreaks{}
+	}
+	pb := *breaks
+	// A break's ID is the last row or column of the page before it.
+	at := len(pb.Items)
+	for i, brk := range pb.Items {
+		if brk.ID == index-1 {
+			return nil
+		}
+		if brk.ID > index-1 {
+			at = i
+			break
+		}
+	}
+	pb.Items = append(pb.Items[:at], append([]xmlstructs.Break{{ID: index - 1, Max: span - 1, Man: 1}}, pb.Items[at:]...)...)
+	countBreaks(pb)
+	return nil
+}
+
+// countBreaks updates the counts of a list of page breaks.
+func countBreaks(pb *xmlstructs.PageBreaks) {
+	pb.Count, pb.ManualBreakCount = len(pb.Items), 0
+	for _, brk := range pb.Items {
+		pb.ManualBreakCount += brk.Man
+	}
+}
+
+func (e *sheetProcessor) setPrintOptions(sheet string, opts document.PrintOptions) error {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return err
+	}
+	switch {
+	case opts.FitToWidth < 0 || opts.FitToHeight < 0:
+		return fmt.Errorf("invalid fit to %d by %d pages", opts.FitToWidth, opts.FitToHeight)
+	case opts.Scale != 0 && (opts.Scale < 10 || opts.Scale > 400):
+		return fmt.Errorf("print scale %d%% is outside 10%% to 400%%", opts.Scale)
+	case opts.Quality < 0:
+		return fmt.Errorf("invalid print quality %d", opts.Quality)
+	}
+
+	if ws.PageSetup == nil {
+		ws.PageSetup = &xmlstructs.PageSetup{}
+	}
+	ps := ws.PageSetup
+	ps.Scale, ps.FitToWidth, ps.FitToHeight = opts.Scale, nil, nil
+	fit := opts.FitToWidth > 0 || opts.FitToHeight > 0
+	if fit {
+		ps.FitToWidth, ps.FitToHeight = new(opts.FitToWidth), new(opts.FitToHeight)
+		sheetPr(ws).PageSetUpPr = &xmlstructs.PageSetUpPr{FitToPage: 1}
+	} else if ws.SheetPr != nil {
+		ws.SheetPr.PageSetUpPr = nil
+	}
+	ps.HorizontalDpi, ps.VerticalDpi = opts.Quality, opts.Quality
+
+	po := xmlstructs.PrintOptions{
+		HorizontalCentered: boolToInt(opts.CenterHorizontally),
+		VerticalCentered:   boolToInt(opts.CenterVertically),
+		Headings:           boolToInt(opts.Headings),
+		GridLines:          boolToInt(opts.Gridlines),
+	}
+	ws.PrintOptions = nil
+	if po != (xmlstructs.PrintOptions{}) {
+		ws.PrintOptions = &po
+	}
+	return nil
+}
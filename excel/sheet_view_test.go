@@ -0,0 +1,120 @@
+package excel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestSheetView_RoundTrip(t *testing.T) {
+	ctx := t.Context()
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	view := document.SheetView{Zoom: 85, HideGridlines: true, HideHeadings: true, RightToLeft: true, TabColor: "00B050"}
+	sheet.SetView(view).SplitPanes(2, 3)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("SetView failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Data")
+	if got, err := s.View(); err != nil || got != view {
+		t.Errorf("View = %+v, %v, want %+v", got, err, view)
+	}
+	pane := reopened.sheets["Data"].SheetViews.Items[0].Pane
+	want := xmlstructs.Pane{XSplit: 1920, YSplit: 900, TopLeftCell: "C4", ActivePane: "bottomRight", State: "split"}
+	if pane == nil || *pane != want {
+		t.Errorf("pane = %+v, want %+v", pane, want)
+	}
+	if col, row, _ := s.FrozenPanes(); col != 0 || row != 0 {
+		t.Errorf("FrozenPanes = %d, %d for a split, want 0, 0", col, row)
+	}
+
+	s.SetView(document.SheetView{})
+	if got, _ := s.View(); got != (document.SheetView{Zoom: 100}) {
+		t.Errorf("View after reset = %+v", got)
+	}
+
+	if err := sheet.SetView(document.SheetView{Zoom: 5}).Err(); err == nil {
+		t.Error("expected an error for zoom 5%")
+	}
+	if err := s.SplitPanes(0, 0).Err(); err == nil {
+		t.Error("expected an error for an empty split")
+	}
+}
+
+func TestSheet_PageBreaks(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.InsertRowBreak(20).InsertRowBreak(10).InsertRowBreak(20).InsertColBreak(5)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("InsertRowBreak failed: %v", err)
+	}
+	ws := doc.sheets["Data"]
+	if rb := ws.RowBreaks; rb == nil || rb.Count != 2 || rb.ManualBreakCount != 2 || rb.Items[0].ID != 9 || rb.Items[1].ID != 19 {
+		t.Fatalf("row breaks = %+v, want after rows 9 and 19", rb)
+	}
+	if cb := ws.ColBreaks; cb == nil || cb.Count != 1 || cb.Items[0].ID != 4 || cb.Items[0].Max != 1048575 {
+		t.Errorf("column breaks = %+v, want one after column 4", cb)
+	}
+
+	sheet.InsertRows(5, 2).DeleteRows(25, 1)
+	if rb := ws.RowBreaks; rb.Items[0].ID != 11 || rb.Items[1].ID != 21 {
+		t.Errorf("row breaks after inserting rows = %+v, want after rows 11 and 21", rb.Items)
+	}
+	sheet.DeleteRows(12, 1)
+	if rb := ws.RowBreaks; rb.Count != 1 || rb.Items[0].ID != 20 {
+		t.Errorf("row breaks after deleting the first row of a page = %+v", rb)
+	}
+
+	if err := sheet.InsertRowBreak(1).Err(); err == nil {
+		t.Error("expected an error for a break before row 1")
+	}
+}
+
+func TestSheet_PrintOptions(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.SetPrintOptions(document.PrintOptions{
+		FitToWidth: 1, Gridlines: true, Headings: true, CenterHorizontally: true, Quality: 600,
+	})
+	sheet.SetPageSettings(document.PageSettings{Orientation: document.OrientationLandscape, PaperType: document.PaperA4})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("SetPrintOptions failed: %v", err)
+	}
+	ws := doc.sheets["Data"]
+	ps := ws.PageSetup
+	if ps.FitToWidth == nil || *ps.FitToWidth != 1 || ps.FitToHeight == nil || *ps.FitToHeight != 0 ||
+		ps.HorizontalDpi != 600 || ps.VerticalDpi != 600 || ps.Orientation != "landscape" || ps.PaperSize != 9 {
+		t.Errorf("unexpected page setup %+v", ps)
+	}
+	if ws.SheetPr == nil || ws.SheetPr.PageSetUpPr == nil || ws.SheetPr.PageSetUpPr.FitToPage != 1 {
+		t.Error("expected the sheet to fit to pages")
+	}
+	want := xmlstructs.PrintOptions{HorizontalCentered: 1, Headings: 1, GridLines: 1}
+	if ws.PrintOptions == nil || *ws.PrintOptions != want {
+		t.Errorf("print options = %+v, want %+v", ws.PrintOptions, want)
+	}
+
+	sheet.SetPrintOptions(document.PrintOptions{Scale: 75})
+	if ws.PageSetup.Scale != 75 || ws.PageSetup.FitToWidth != nil || ws.SheetPr.PageSetUpPr != nil || ws.PrintOptions != nil {
+		t.Errorf("expected scaling without fitting, got %+v", ws.PageSetup)
+	}
+	if err := sheet.SetPrintOptions(document.PrintOptions{Scale: 500}).Err(); err == nil {
+		t.Error("expected an error for a 500% scale")
+	}
+}
@@ -0,0 +1,667 @@
+package excel
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+var (
+	refAttrPattern    = regexp.MustCompile(`(\sref=")([^"]*)(")`)
+	chartRefPattern   = regexp.MustCompile(`(<c:f>)([^<]*)(</c:f>)`)
+	xmlEntityReplacer = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#34;", `"`, "&apos;", "'", "&#39;", "'", "&amp;", "&")
+	xmlEscapeReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+)
+
+// cellShift describes count rows, or columns when cols is set, inserted before
+// index at of a sheet. A negative count deletes -count of them starting at at.
+// A shift made by gapShift deletes several runs of them at once instead.
+type cellShift struct {
+	sheet string
+	cols  bool
+	at    int
+	count int
+	gaps  []gap
+}
+
+// gap is a run of count rows or columns deleted from index at, after before
+// rows or columns were deleted above it.
+type gap struct {
+	at, count int
+	before    int
+}
+
+// gapShift returns the deletion of the rows in gaps, which are sorted and
+// disjoint. Its at and count span the gaps.
+func gapShift(sheet string, gaps []gap) cellShift {
+	s := cellShift{sheet: sheet, at: gaps[0].at, gaps: gaps}
+	for i := range gaps {
+		gaps[i].before = -s.count
+		s.count -= gaps[i].count
+	}
+	return s
+}
+
+func (s cellShift) limit() int {
+	if s.cols {
+		return formula.MaxColumns
+	}
+	return formula.MaxRows
+}
+
+// span moves the indexes lo through hi as formula.ShiftSpan does, reporting
+// false when none of them is left.
+func (s cellShift) span(lo, hi int) (int, int, bool) {
+	if s.gaps == nil {
+		return formula.ShiftSpan(lo, hi, s.at, s.count, s.limit())
+	}
+	lo, hi = s.gapIndex(lo, true), s.gapIndex(hi, false)
+	return lo, hi, lo <= hi
+}
+
+// gapIndex moves an index past the gaps above it. An index within a gap moves
+// to the first index after the gap, or the last before it.
+func (s cellShift) gapIndex(i int, after bool) int {
+	j := sort.Search(len(s.gaps), func(j int) bool { return s.gaps[j].at > i })
+	if j == 0 {
+		return i
+	}
+	g := s.gaps[j-1]
+	switch {
+	case i >= g.at+g.count:
+		return i - g.before - g.count
+	case after:
+		return g.at - g.before
+	}
+	return g.at - g.before - 1
+}
+
+// index moves a row or column index, reporting false when it was deleted.
+func (s cellShift) index(i int) (int, bool) {
+	i, _, ok := s.span(i, i)
+	return i, ok
+}
+
+// cell moves a cell reference such as "B7", reporting false when it was deleted.
+func (s cellShift) cell(axis string) (string, bool) {
+	col, row := axisPosition(axis)
+	var ok bool
+	if s.cols {
+		col, ok = s.index(col)
+	} else {
+		row, ok = s.index(row)
+	}
+	return formula.CellName(col, row), ok
+}
+
+// area moves a range such as "B2:D10", reporting false when no cell of it is left.
+// Text that is not a reference is returned unchanged.
+func (s cellShift) area(ref string) (string, bool) {
+	r, err := formula.ParseReference(ref)
+	if err != nil {
+		return ref, true
+	}
+	if r = r.ShiftBy(s.cols, s.span); r.Invalid {
+		return "", false
+	}
+	return r.String(), true
+}
+
+// sqref moves a space separated list of ranges, dropping those that were deleted.
+func (s cellShift) sqref(ref string) (string, bool) {
+	var kept []string
+	for _, f := range strings.Fields(ref) {
+		if area, ok := s.area(f); ok {
+			kept = append(kept, area)
+		}
+	}
+	return strings.Join(kept, " "), len(kept) > 0
+}
+
+// reference returns a rewriter that moves the references to the shifted sheet
+// in formulas that live on sheet home. Unqualified references are ignored when
+// home is empty.
+func (s cellShift) reference(home string) func(formula.Reference) formula.Reference {
+	return func(r formula.Reference) formula.Reference {
+		sheet := r.Sheet
+		if sheet == "" {
+			sheet = home
+		}
+		if !strings.EqualFold(sheet, s.sheet) {
+			return r
+		}
+		return r.ShiftBy(s.cols, s.span)
+	}
+}
+
+// marker moves a drawing anchor. Anchors in deleted cells move to the first cell after them.
+func (s cellShift) marker(m *xmlstructs.Marker) {
+	i, off := &m.Row, &m.RowOff
+	if s.cols {
+		i, off = &m.Col, &m.ColOff
+	}
+	if moved, ok := s.index(*i + 1); ok {
+		*i = moved - 1
+		return
+	}
+	next, _, _ := s.span(*i+1, s.limit())
+	*i, *off = next-1, 0
+}
+
+func (e *sheetProcessor) insertRows(sheet string, row, count int) error {
+	if row < 1 || count < 1 {
+		return fmt.Errorf("invalid insertion of %d rows at row %d", count, row)
+	}
+	return e.shiftCells(cellShift{sheet: sheet, at: row, count: count})
+}
+
+func (e *sheetProcessor) deleteRows(sheet string, row, count int) error {
+	if row < 1 || count < 1 {
+		return fmt.Errorf("invalid deletion of %d rows at row %d", count, row)
+	}
+	return e.shiftCells(cellShift{sheet: sheet, at: row, count: -count})
+}
+
+func (e *sheetProcessor) insertCols(sheet string, col, count int) error {
+	if col < 1 || count < 1 {
+		return fmt.Errorf("invalid insertion of %d columns at column %d", count, col)
+	}
+	return e.shiftCells(cellShift{sheet: sheet, cols: true, at: col, count: count})
+}
+
+func (e *sheetProcessor) deleteCols(sheet string, col, count int) error {
+	if col < 1 || count < 1 {
+		return fmt.Errorf("invalid deletion of %d columns at column %d", count, col)
+	}
+	return e.shiftCells(cellShift{sheet: sheet, cols: true, at: col, count: -count})
+}
+
+// shiftCells inserts or deletes rows or columns and updates everything in the
+// workbook that refers to the moved cells.
+func (e *sheetProcessor) shiftCells(s cellShift) error {
+	ws, ok := e.worksheet(s.sheet)
+	if !ok {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, s.sheet)
+	}
+	if _, streamed := e.streams[s.sheet]; streamed {
+		return fmt.Errorf("sheet %s is written through a stream writer", s.sheet)
+	}
+	if err := e.checkShift(ws, s); err != nil {
+		return err
+	}
+	tables, err := e.sheetTables(s.sheet)
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := t.checkShift(s); err != nil {
+			return err
+		}
+	}
+	if err := e.unshareFormulas(); err != nil {
+		return err
+	}
+
+	shiftSheetData(ws, s)
+	shiftSheetRanges(ws, s)
+	shiftSparklines(ws, s)
+	if err := e.rewriteSheetReferences(s.reference); err != nil {
+		return err
+	}
+	if err := e.shiftDrawing(ws, s); err != nil {
+		return err
+	}
+	if err := e.shiftComments(s); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := e.shiftTable(t, s); err != nil {
+			return err
+		}
+	}
+	e.shiftPivotTables(s)
+
+	delete(e.cellCache, s.sheet)
+	e.calcDirty = true
+	return nil
+}
+
+// checkShift rejects insertions that would push cells off the grid.
+func (e *sheetProcessor) checkShift(ws *xmlstructs.Worksheet, s cellShift) error {
+	if s.count < 0 {
+		return nil
+	}
+	last := 0
+	for _, row := range ws.SheetData.Rows {
+		if !s.cols {
+			last = max(last, row.R)
+			continue
+		}
+		for _, cell := range row.Cells {
+			col, _ := axisPosition(cell.R)
+			last = max(last, col)
+		}
+	}
+	if last >= s.at && last+s.count > s.limit() {
+		return fmt.Errorf("insertion would move cells of sheet %s off the grid", s.sheet)
+	}
+	return nil
+}
+
+// unshareFormulas replaces shared formulas with their expanded text in every
+// sheet, so that references can be rewritten cell by cell.
+func (e *sheetProcessor) unshareFormulas() error {
+	type master struct {
+		text     string
+		col, row int
+	}
+	for _, sh := range e.workbook.Sheets {
+		if _, streamed := e.streams[sh.Name]; streamed {
+			continue
+		}
+		ws, ok := e.worksheet(sh.Name)
+		if !ok {
+			if e.hasSheet(sh.Name) {
+				return fmt.Errorf("load sheet %s", sh.Name)
+			}
+			continue
+		}
+
+		masters := make(map[int]master)
+		for i := range ws.SheetData.Rows {
+			for _, cell := range ws.SheetData.Rows[i].Cells {
+				if f := cell.F; f != nil && f.T == "shared" && f.SI != nil && f.Text != "" {
+					col, row := axisPosition(cell.R)
+					masters[*f.SI] = master{text: f.Text, col: col, row: row}
+				}
+			}
+		}
+		if len(masters) == 0 {
+			continue
+		}
+		for i := range ws.SheetData.Rows {
+			for j := range ws.SheetData.Rows[i].Cells {
+				cell := &ws.SheetData.Rows[i].Cells[j]
+				if cell.F == nil || cell.F.T != "shared" || cell.F.SI == nil {
+					continue
+				}
+				m, ok := masters[*cell.F.SI]
+				if !ok {
+					continue
+				}
+				col, row := axisPosition(cell.R)
+				text, err := formula.Translate(m.text, col-m.col, row-m.row)
+				if err != nil {
+					text = m.text
+				}
+				cell.F = &xmlstructs.Formula{Text: text, CA: cell.F.CA}
+			}
+		}
+	}
+	return nil
+}
+
+// shiftSheetData moves the rows or cells of the shifted sheet, dropping deleted ones.
+func shiftSheetData(ws *xmlstructs.Worksheet, s cellShift) {
+	rows := ws.SheetData.Rows[:0]
+	for _, row := range ws.SheetData.Rows {
+		if !s.cols {
+			r, ok := s.index(row.R)
+			if !ok {
+				continue
+			}
+			row.R = r
+		}
+		cells := row.Cells[:0]
+		for _, cell := range row.Cells {
+			axis, ok := s.cell(cell.R)
+			if !ok {
+				continue
+			}
+			cell.R = axis
+			if f := cell.F; f != nil && f.Ref != "" {
+				if ref, ok := s.area(f.Ref); ok {
+					f.Ref = ref
+				}
+			}
+			cells = append(cells, cell)
+		}
+		row.Cells = cells
+		rows = append(rows, row)
+	}
+	ws.SheetData.Rows = rows
+	ws.Dimension = nil
+}
+
+// shiftSheetRanges moves the ranges stored in the shifted sheet itself. The
+// formulas they hold are moved with those of the other sheets.
+func shiftSheetRanges(ws *xmlstructs.Worksheet, s cellShift) {
+	if mc := ws.MergeCells; mc != nil {
+		items := mc.Items[:0]
+		for _, m := range mc.Items {
+			// A merge reduced to a single cell is dropped.
+			if ref, ok := s.area(m.Ref); ok && !singleCell(ref) {
+				items = append(items, xmlstructs.MergeCell{Ref: ref})
+			}
+		}
+		mc.Items, mc.Count = items, len(items)
+		if len(items) == 0 {
+			ws.MergeCells = nil
+		}
+	}
+
+	if hl := ws.Hyperlinks; hl != nil {
+		items := hl.Items[:0]
+		for _, h := range hl.Items {
+			if ref, ok := s.area(h.Ref); ok {
+				h.Ref = ref
+				items = append(items, h)
+			}
+		}
+		hl.Items = items
+		if len(items) == 0 {
+			ws.Hyperlinks = nil
+		}
+	}
+
+	cfs := ws.ConditionalFormatting[:0]
+	for _, cf := range ws.ConditionalFormatting {
+		ref, ok := s.sqref(cf.Sqref)
+		if !ok {
+			continue
+		}
+		cf.Sqref = ref
+		cfs = append(cfs, cf)
+	}
+	ws.ConditionalFormatting = cfs
+
+	if dvs := ws.DataValidations; dvs != nil {
+		items := dvs.Items[:0]
+		for _, dv := range dvs.Items {
+			ref, ok := s.sqref(dv.Sqref)
+			if !ok {
+				continue
+			}
+			dv.Sqref = ref
+			items = append(items, dv)
+		}
+		dvs.Items, dvs.Count = items, len(items)
+		if len(items) == 0 {
+			ws.DataValidations = nil
+		}
+	}
+
+	if af := ws.AutoFilter; af != nil {
+		if ref, ok := s.area(af.Ref); ok {
+			af.Ref = ref
+		} else {
+			ws.AutoFilter = nil
+		}
+	}
+
+	if ws.Cols != nil && s.cols {
+		items := ws.Cols.Items[:0]
+		for _, c := range ws.Cols.Items {
+			var ok bool
+			if c.Min, c.Max, ok = s.span(c.Min, c.Max); ok {
+				items = append(items, c)
+			}
+		}
+		ws.Cols.Items = items
+		if len(items) == 0 {
+			ws.Cols = nil
+		}
+	}
+
+	breaks := &ws.RowBreaks
+	if s.cols {
+		breaks = &ws.ColBreaks
+	}
+	if pb := *breaks; pb != nil {
+		items := pb.Items[:0]
+		for _, brk := range pb.Items {
+			// The page after a break starts at ID+1, which moves with its row or column.
+			if start, ok := s.index(brk.ID + 1); ok && start > 1 {
+				brk.ID = start - 1
+				items = append(items, brk)
+			}
+		}
+		pb.Items = items
+		countBreaks(pb)
+		if len(items) == 0 {
+			*breaks = nil
+		}
+	}
+}
+
+func singleCell(ref string) bool {
+	r, err := formula.ParseReference(ref)
+	return err == nil && r.Col1 == r.Col2 && r.Row1 == r.Row2
+}
+
+// shiftDrawing moves the anchors of the sheet's images, charts and shapes.
+func (e *sheetProcessor) shiftDrawing(ws *xmlstructs.Worksheet, s cellShift) error {
+	if ws.Drawing == nil {
+		return nil
+	}
+	_, dr, _, err := e.sheetDrawing(s.sheet)
+	if err != nil {
+		return err
+	}
+	for _, a := range dr.Anchors {
+		switch {
+		case a.TwoCellAnchor != nil:
+			s.marker(&a.TwoCellAnchor.From)
+			s.marker(&a.TwoCellAnchor.To)
+		case a.OneCellAnchor != nil:
+			s.marker(&a.OneCellAnchor.From)
+		}
+	}
+	return nil
+}
+
+// shiftComments moves the notes of the shifted sheet, dropping those of deleted cells.
+func (e *sheetProcessor) shiftComments(s cellShift) error {
+	sc, err := e.sheetComments(s.sheet, false)
+	if err != nil || sc == nil {
+		return err
+	}
+	shapes := make(map[string]noteShape, len(sc.shapes))
+	items := sc.part.CommentList.Items[:0]
+	for _, item := range sc.part.CommentList.Items {
+		ref, ok := s.cell(item.Ref)
+		if !ok {
+			continue
+		}
+		shapes[ref] = sc.shapes[item.Ref]
+		item.Ref = ref
+		items = append(items, item)
+	}
+	slices.SortStableFunc(items, func(a, b xmlstructs.Comment) int {
+		return compareRefs(a, b.Ref)
+	})
+	sc.part.CommentList.Items = items
+	sc.shapes = shapes
+	sc.dirty = true
+	return nil
+}
+
+// sheetTable is a table of the shifted sheet. Tables read from the source
+// package are rewritten as text so that settings this package does not model are kept.
+type sheetTable struct {
+	path  string
+	name  string
+	ref   string
+	table *xmlstructs.Table // nil for a table kept as source text
+}
+
+func (e *sheetProcessor) sheetTables(sheet string) ([]sheetTable, error) {
+	rels := e.sheetRels[sheet]
+	if rels == nil {
+		return nil, nil
+	}
+	var tables []sheetTable
+	for _, rel := range rels.Rels {
+		if rel.Type != tableRelType {
+			continue
+		}
+		path := resolveTarget(e.sheetPath(sheet), rel.Target)
+		if t, ok := e.tables[path]; ok {
+			tables = append(tables, sheetTable{path: path, name: t.Name, ref: t.Ref, table: t})
+			continue
+		}
+		var t xmlstructs.Table
+		if err := e.loadXML(path, &t); err != nil {
+			return nil, fmt.Errorf("load table %s: %w", path, err)
+		}
+		tables = append(tables, sheetTable{path: path, name: t.Name, ref: t.Ref})
+	}
+	return tables, nil
+}
+
+// checkShift rejects edits that would change the columns of the table or
+// remove its header row, which would require rewriting its column definitions.
+func (t sheetTable) checkShift(s cellShift) error {
+	r, err := formula.ParseReference(t.ref)
+	if err != nil {
+		return nil
+	}
+	lo, hi := r.Row1, r.Row2
+	if s.cols {
+		lo, hi = r.Col1, r.Col2
+	}
+	newLo, newHi, ok := s.span(lo, hi)
+	switch {
+	case s.cols && (!ok || newHi-newLo != hi-lo):
+		return fmt.Errorf("cannot change the columns of table %s", t.name)
+	case !s.cols && s.count < 0:
+		if _, kept := s.index(lo); !ok || !kept || newHi == newLo {
+			return fmt.Errorf("cannot delete the header row or every data row of table %s", t.name)
+		}
+	}
+	return nil
+}
+
+func (e *sheetProcessor) shiftTable(t sheetTable, s cellShift) error {
+	if t.table != nil {
+		if ref, ok := s.area(t.table.Ref); ok {
+			t.table.Ref = ref
+		}
+		if af := t.table.AutoFilter; af != nil {
+			if ref, ok := s.area(af.Ref); ok {
+				af.Ref = ref
+			}
+		}
+		return nil
+	}
+	return e.patchPart(t.path, func(text string) string {
+		return refAttrPattern.ReplaceAllStringFunc(text, func(m string) string {
+			parts := refAttrPattern.FindStringSubmatch(m)
+			ref, ok := s.area(parts[2])
+			if !ok {
+				return m
+			}
+			return parts[1] + ref + parts[3]
+		})
+	})
+}
+
+// rewriteChartRefs applies fn to the series references of a chart part kept as text.
+func rewriteChartRefs(text string, fn func(string) string) string {
+	return chartRefPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := chartRefPattern.FindStringSubmatch(m)
+		ref := fn(xmlEntityReplacer.Replace(parts[2]))
+		return parts[1] + xmlEscapeReplacer.Replace(ref) + parts[3]
+	})
+}
+
+// shiftPivotTables moves the pivot tables created on the shifted sheet and the
+// sources of the pivot caches created from it.
+func (e *sheetProcessor) shiftPivotTables(s cellShift) {
+	for _, def := range e.pivotCaches {
+		src := def.CacheSource.WorksheetSource
+		if src == nil || !strings.EqualFold(src.Sheet, s.sheet) {
+			continue
+		}
+		if ref, ok := s.area(src.Ref); ok {
+			src.Ref = ref
+		}
+	}
+	rels := e.sheetRels[s.sheet]
+	if rels == nil {
+		return
+	}
+	for _, rel := range rels.Rels {
+		if rel.Type != pivotTableRelType {
+			continue
+		}
+		if table, ok := e.pivotTables[resolveTarget(e.sheetPath(s.sheet), rel.Target)]; ok {
+			if ref, ok := s.area(table.Location.Ref); ok {
+				table.Location.Ref = ref
+			}
+		}
+	}
+}
+
+// patchPart rewrites a part of the source package as text. The result replaces
+// the source part on save.
+func (e *state) patchPart(name string, fn func(string) string) error {
+	data, err := e.partData(name)
+	if err != nil {
+		return err
+	}
+	if text := fn(string(data)); text != string(data) {
+		e.patched[name] = []byte(text)
+	}
+	return nil
+}
+
+// partData returns the content of a part kept as text, patched or as in the source package.
+func (e *state) partData(name string) ([]byte, error) {
+	if data, ok := e.patched[name]; ok {
+		return data, nil
+	}
+	rc, err := e.openPart(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// textParts returns the names of the parts prefix*.xml that are kept as text:
+// source parts not decoded into decoded, and copies made as text.
+func textParts[V any](e *state, prefix string, decoded map[string]V) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if seen[name] || e.removed[name] || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".xml") {
+			return
+		}
+		if _, ok := decoded[name]; ok {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if e.reader != nil {
+		for _, f := range e.reader.File {
+			add(f.Name)
+		}
+	}
+	for name := range e.patched {
+		add(name)
+	}
+	return names
+}
@@ -0,0 +1,172 @@
+package excel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestInsertRows_ShiftsReferences(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Data")
+	sheet.Cell("A1").Set("Item")
+	sheet.Cell("B1").Set("Qty")
+	for i, v := range []int{3, 4, 5} {
+		row := string(rune('2' + i))
+		sheet.Cell("A" + row).Set("x")
+		sheet.Cell("B" + row).Set(v)
+	}
+	sheet.Cell("B5").Formula("SUM(B2:B4)")
+	sheet.Cell("C5").Formula("$B$5*2")
+	sheet.Cell("A6").Set("note").Comment("check")
+	sheet.Cell("A7").Hyperlink("https://example.com")
+	sheet.MergeCells("A8:B8")
+	sheet.AddTable("A1:B4", "Items")
+	sheet.SetPrintArea("$A$1:$B$5")
+	sheet.AddConditionalFormat("B2:B4", document.ConditionalRule{Type: document.ConditionExpression, Formula: []string{"B2>$B$5"}})
+	sheet.AddDataValidation("B2:B4", document.DataValidation{Type: document.ValidationWhole, Operator: document.OperatorLessThan, Formula1: "$B$5"})
+	sheet.AddChart(document.ChartSpec{Range: "D2:H10", Series: []document.ChartSeries{{Categories: "A2:A4", Values: "B2:B4"}}})
+
+	summary, _ := doc.Sheet("Summary")
+	summary.Cell("A1").Formula("Data!B5+SUM(Data!B:B)")
+	if err := doc.SetNamedRange("Total", "Data!$B$5"); err != nil {
+		t.Fatalf("SetNamedRange failed: %v", err)
+	}
+
+	sheet.InsertRows(3, 2)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("InsertRows failed: %v", err)
+	}
+
+	for axis, want := range map[string]string{"B2": "3", "B3": "", "B5": "4", "B7": "12", "A8": "note"} {
+		if got, _ := sheet.GetCellValue(axis); got != want {
+			t.Errorf("Cell %s: expected %q, got %q", axis, want, got)
+		}
+	}
+	if got, _ := summary.GetCellValue("A1"); got != "36" {
+		t.Errorf("Expected Summary!A1 to recalculate to 36, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	for part, wants := range map[string][]string{
+		"xl/worksheets/sheet1.xml": {
+			`<c r="B7"><f>SUM(B2:B6)</f>`,
+			`<c r="C7"><f>$B$7*2</f>`,
+			`<mergeCell ref="A10:B10">`,
+			`<conditionalFormatting sqref="B2:B6"><cfRule type="expression" priority="1"><formula>B2&gt;$B$7</formula>`,
+			`sqref="B2:B6"><formula1>$B$7</formula1>`,
+			`<hyperlink ref="A9"`,
+		},
+		"xl/worksheets/sheet2.xml": {`<f>Data!B7+SUM(Data!B:B)</f>`},
+		"xl/workbook.xml":          {`>Data!A1:B7</definedName>`, `Data!$B$7</definedName>`},
+		"xl/tables/table1.xml":     {`ref="A1:B6"`},
+		"xl/comments1.xml":         {`<comment ref="A8"`},
+		"xl/charts/chart1.xml":     {`<c:f>Data!$B$2:$B$6</c:f>`},
+		"xl/drawings/drawing1.xml": {`<xdr:to><xdr:col>8</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>12</xdr:row>`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(parts[part], want) {
+				t.Errorf("Expected %s in %s", want, part)
+			}
+		}
+	}
+}
+
+func TestInsertRows_ShiftsReferencesFromOtherSheets(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	data, _ := doc.Sheet("Data")
+	data.Range("A1:A5").SetValues([][]any{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}})
+	form, _ := doc.Sheet("Form")
+	form.AddDataValidation("B2", document.DataValidation{Type: document.ValidationList, Source: "Data!$A$1:$A$5"})
+	form.AddConditionalFormat("B2", document.ConditionalRule{Type: document.ConditionExpression, Formula: []string{"Data!$A$1<>\"\""}})
+	if err := form.Err(); err != nil {
+		t.Fatalf("sheet setup failed: %v", err)
+	}
+	ws := doc.sheets["Form"]
+	ws.Hyperlinks = &xmlstructs.Hyperlinks{Items: []xmlstructs.Hyperlink{{Ref: "C2", Location: "Data!A3"}}}
+
+	data.InsertRows(1, 2)
+	if err := data.Err(); err != nil {
+		t.Fatalf("InsertRows failed: %v", err)
+	}
+	if got := ws.DataValidations.Items[0].Formula1; got != "Data!$A$3:$A$7" {
+		t.Errorf("validation list = %q, want Data!$A$3:$A$7", got)
+	}
+	if got := ws.ConditionalFormatting[0].CfRule[0].Formula[0]; got != `Data!$A$3<>""` {
+		t.Errorf("conditional format formula = %q, want Data!$A$3<>\"\"", got)
+	}
+	if got := ws.Hyperlinks.Items[0].Location; got != "Data!A5" {
+		t.Errorf("hyperlink location = %q, want Data!A5", got)
+	}
+}
+
+func TestDeleteCols_SourceParts(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Sheet1")
+	for i, h := range []string{"A", "B", "C", "D", "E"} {
+		sheet.Cell(h + "1").Set(i + 1)
+	}
+	sheet.Cell("F1").Formula("SUM(A1:E1)")
+	sheet.Cell("G1").Formula("C1")
+	sheet.Cell("H1").Formula("E1*2")
+	sheet.SetColumnWidth(8, 20)
+	sheet.AddTable("A3:B5", "Left")
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sheet1")
+
+	if err := s.DeleteCols(1, 1).Err(); err == nil {
+		t.Error("Expected an error when deleting a column of a table")
+	}
+	s, _ = reopened.Sheet("Sheet1")
+	s.InsertRows(1, 1).DeleteCols(3, 1)
+	if err := s.Err(); err != nil {
+		t.Fatalf("DeleteCols failed: %v", err)
+	}
+	for axis, want := range map[string]string{"C2": "4", "E2": "12", "F2": "#REF!", "G2": "10"} {
+		if got, _ := s.GetCellValue(axis); got != want {
+			t.Errorf("Cell %s: expected %q, got %q", axis, want, got)
+		}
+	}
+
+	buf.Reset()
+	if err := reopened.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	ws := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{`<f>SUM(A2:D2)</f>`, `<f>#REF!</f>`, `<f>D2*2</f>`, `<col min="7" max="7" width="20"`} {
+		if !strings.Contains(ws, want) {
+			t.Errorf("Expected %s in worksheet", want)
+		}
+	}
+	if !strings.Contains(parts["xl/tables/table1.xml"], `ref="A4:B6"`) {
+		t.Error("Expected the source table to move down one row")
+	}
+}
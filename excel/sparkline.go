@@ -0,0 +1,221 @@
+package excel
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// Colors of Excel's default sparkline style.
+const (
+	sparklineSeriesColor = "376092"
+	sparklineAccentColor = "D00000"
+	sparklineAxisColor   = "000000"
+)
+
+func (e *sheetProcessor) addSparklines(sheet string, group document.SparklineGroup) error {
+	ws, err := e.sheetWorksheet(sheet)
+	if err != nil {
+		return err
+	}
+	sparklines, err := e.sparklineRanges(sheet, group)
+	if err != nil {
+		return err
+	}
+
+	g := xmlstructs.SparklineGroup{
+		Markers:      boolToInt(group.Markers),
+		High:         boolToInt(group.High),
+		Low:          boolToInt(group.Low),
+		First:        boolToInt(group.First),
+		Last:         boolToInt(group.Last),
+		Negative:     boolToInt(group.Negative),
+		DisplayXAxis: boolToInt(group.ShowAxis),
+		RightToLeft:  boolToInt(group.RightToLeft),
+		Sparklines:   xmlstructs.Sparklines{Items: sparklines},
+	}
+	switch group.Type {
+	case "", document.SparklineLine:
+	case document.SparklineColumn, document.SparklineWinLoss:
+		g.Type = string(group.Type)
+	default:
+		return fmt.Errorf("unknown sparkline type %q", group.Type)
+	}
+	switch group.EmptyCells {
+	case "":
+		g.DisplayEmptyCellsAs = "gap"
+	case "gap", "zero", "span":
+		g.DisplayEmptyCellsAs = group.EmptyCells
+	default:
+		return fmt.Errorf("unknown display of empty cells %q", group.EmptyCells)
+	}
+	if group.LineWeight < 0 {
+		return fmt.Errorf("invalid sparkline weight %g", group.LineWeight)
+	}
+	g.LineWeight = group.LineWeight
+
+	if g.MinAxisType, err = sparklineAxis(group.MinAxis); err != nil {
+		return err
+	}
+	if g.MaxAxisType, err = sparklineAxis(group.MaxAxis); err != nil {
+		return err
+	}
+	if group.MinAxis == document.AxisCustom {
+		g.ManualMin = new(group.MinValue)
+	}
+	if group.MaxAxis == document.AxisCustom {
+		g.ManualMax = new(group.MaxValue)
+	}
+	if g.ManualMin != nil && g.ManualMax != nil && *g.ManualMin >= *g.ManualMax {
+		return fmt.Errorf("sparkline axis minimum %g is not below its maximum %g", *g.ManualMin, *g.ManualMax)
+	}
+
+	color := func(c, fallback string) *xmlstructs.Color {
+		if c == "" {
+			c = fallback
+		}
+		return &xmlstructs.Color{RGB: argb(c)}
+	}
+	g.ColorSeries = color(group.Color, sparklineSeriesColor)
+	g.ColorNegative = color(group.NegativeColor, sparklineAccentColor)
+	g.ColorAxis = color(group.AxisColor, sparklineAxisColor)
+	g.ColorMarkers = color(group.MarkersColor, sparklineAccentColor)
+	g.ColorFirst = color(group.FirstColor, sparklineAccentColor)
+	g.ColorLast = color(group.LastColor, sparklineAccentColor)
+	g.ColorHigh = color(group.HighColor, sparklineAccentColor)
+	g.ColorLow = color(group.LowColor, sparklineAccentColor)
+
+	groups := sparklineGroups(ws, true)
+	groups.Items = append(groups.Items, g)
+	return nil
+}
+
+func sparklineAxis(axis document.SparklineAxis) (string, error) {
+	switch axis {
+	case "", document.AxisIndividual:
+		return "", nil
+	case document.AxisGroup, document.AxisCustom:
+		return string(axis), nil
+	}
+	return "", fmt.Errorf("unknown sparkline axis %q", axis)
+}
+
+// sparklineRanges pairs each cell of a group's location with the row or
+// column of its data range that it plots. A location laid out as a column
+// prefers the rows of the data, and one laid out as a row its columns.
+func (e *sheetProcessor) sparklineRanges(sheet string, group document.SparklineGroup) ([]xmlstructs.Sparkline, error) {
+	loc, err := parseArea(group.Location)
+	if err != nil {
+		return nil, err
+	}
+	if loc.width() > 1 && loc.height() > 1 {
+		return nil, fmt.Errorf("sparkline location %s is not a single row or column", group.Location)
+	}
+	data, err := formula.ParseReference(group.Data)
+	if err != nil || data.Invalid || data.Col1 == 0 || data.Row1 == 0 {
+		return nil, fmt.Errorf("invalid sparkline data range %q", group.Data)
+	}
+	if data.Sheet == "" {
+		data.Sheet = sheet
+	} else if !e.hasSheet(data.Sheet) {
+		return nil, fmt.Errorf("%w: %s", document.ErrSheetNotFound, data.Sheet)
+	}
+
+	n := max(loc.width(), loc.height())
+	rows, cols := data.Row2-data.Row1+1, data.Col2-data.Col1+1
+	byRow := rows == n && (loc.height() > 1 || cols != n)
+	if n > 1 && !byRow && cols != n {
+		return nil, fmt.Errorf("sparkline data %s has no row or column for each of the %d cells of %s", group.Data, n, group.Location)
+	}
+
+	sparklines := make([]xmlstructs.Sparkline, n)
+	for i := range n {
+		r := data
+		switch {
+		case n == 1:
+		case byRow:
+			r.Row1, r.Row2 = data.Row1+i, data.Row1+i
+		default:
+			r.Col1, r.Col2 = data.Col1+i, data.Col1+i
+		}
+		r.IsRange = r.Col1 != r.Col2 || r.Row1 != r.Row2
+		r.AbsCol1, r.AbsRow1, r.AbsCol2, r.AbsRow2 = false, false, false, false
+		col, row := loc.col1, loc.row1
+		if loc.height() > 1 {
+			row += i
+		} else {
+			col += i
+		}
+		sparklines[i] = xmlstructs.Sparkline{F: r.String(), Sqref: formula.CellName(col, row)}
+	}
+	return sparklines, nil
+}
+
+// sparklineGroups returns the sparkline groups of a sheet, adding the
+// extension that holds them when create is set.
+func sparklineGroups(ws *xmlstructs.Worksheet, create bool) *xmlstructs.SparklineGroups {
+	if ws.ExtLst != nil {
+		for i := range ws.ExtLst.Items {
+			if x := &ws.ExtLst.Items[i]; x.URI == xmlstructs.SparklineExtURI {
+				if x.SparklineGroups == nil && create {
+					x.SparklineGroups = &xmlstructs.SparklineGroups{}
+				}
+				return x.SparklineGroups
+			}
+		}
+	}
+	if !create {
+		return nil
+	}
+	if ws.ExtLst == nil {
+		ws.ExtLst = &xmlstructs.ExtLst{}
+	}
+	ws.ExtLst.Items = append(ws.ExtLst.Items, xmlstructs.Ext{
+		URI:             xmlstructs.SparklineExtURI,
+		Namespaces:      []xml.Attr{{Name: xml.Name{Local: "xmlns:x14"}, Value: xmlstructs.NSX14}},
+		SparklineGroups: &xmlstructs.SparklineGroups{},
+	})
+	return ws.ExtLst.Items[len(ws.ExtLst.Items)-1].SparklineGroups
+}
+
+// shiftSparklines moves the sparklines of the shifted sheet. Sparklines in
+// deleted cells are dropped, along with the extension once none is left.
+func shiftSparklines(ws *xmlstructs.Worksheet, s cellShift) {
+	groups := sparklineGroups(ws, false)
+	if groups == nil {
+		return
+	}
+	kept := groups.Items[:0]
+	for _, g := range groups.Items {
+		items := g.Sparklines.Items[:0]
+		for _, sp := range g.Sparklines.Items {
+			ref, ok := s.area(sp.Sqref)
+			if !ok {
+				continue
+			}
+			sp.Sqref = ref
+			items = append(items, sp)
+		}
+		g.Sparklines.Items = items
+		if len(items) > 0 {
+			kept = append(kept, g)
+		}
+	}
+	groups.Items = kept
+	if len(kept) > 0 {
+		return
+	}
+	exts := ws.ExtLst.Items[:0]
+	for _, x := range ws.ExtLst.Items {
+		if x.URI != xmlstructs.SparklineExtURI {
+			exts = append(exts, x)
+		}
+	}
+	ws.ExtLst.Items = exts
+	if len(exts) == 0 {
+		ws.ExtLst = nil
+	}
+}
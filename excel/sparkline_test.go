@@ -0,0 +1,123 @@
+package excel
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+func TestSparklines_RoundTrip(t *testing.T) {
+	ctx := t.Context()
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+	sheet.Range("A1:D3").SetValues([][]any{{1, 3, 2, 5}, {4, -1, 2, 0}, {-2, 1, -3, 4}})
+
+	sheet.AddSparklines(document.SparklineGroup{
+		Location: "E1:E3", Data: "A1:D3",
+		High: true, Low: true, Markers: true, Color: "00B050", HighColor: "#ff0000",
+	}).AddSparklines(document.SparklineGroup{
+		Type: document.SparklineWinLoss, Location: "A5:D5", Data: "A1:D3",
+		ShowAxis: true, MinAxis: document.AxisCustom, MinValue: -1, MaxAxis: document.AxisGroup,
+	})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddSparklines failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	sheetXML := zipParts(t, buf.Bytes())["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<extLst><ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main">`,
+		`<x14:sparklineGroups xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">`,
+		`<x14:colorSeries rgb="FF00B050"></x14:colorSeries>`,
+		`<x14:colorHigh rgb="FFFF0000"></x14:colorHigh>`,
+		`<x14:sparkline><xm:f>Data!A2:D2</xm:f><xm:sqref>E2</xm:sqref></x14:sparkline>`,
+		`<x14:sparkline><xm:f>Data!C1:C3</xm:f><xm:sqref>C5</xm:sqref></x14:sparkline>`,
+		`manualMin="-1" type="stacked"`,
+	} {
+		if !strings.Contains(sheetXML, want) {
+			t.Errorf("sheet XML does not contain %s", want)
+		}
+	}
+
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Data")
+	if err := s.InsertRows(2, 1).Err(); err != nil {
+		t.Fatalf("InsertRows failed: %v", err)
+	}
+	if err := reopened.RenameSheet("Data", "Stats"); err != nil {
+		t.Fatalf("RenameSheet failed: %v", err)
+	}
+	s, _ = reopened.Sheet("Stats")
+	groups := sparklineGroups(reopened.sheets["Stats"], false)
+	if groups == nil || len(groups.Items) != 2 {
+		t.Fatalf("sparkline groups = %+v, want 2", groups)
+	}
+	line, winLoss := groups.Items[0], groups.Items[1]
+	if line.High != 1 || line.Markers != 1 || line.Type != "" || line.ColorSeries.RGB != "FF00B050" {
+		t.Errorf("unexpected line group %+v", line)
+	}
+	if got := line.Sparklines.Items[2]; got != (xmlstructs.Sparkline{F: "Stats!A4:D4", Sqref: "E4"}) {
+		t.Errorf("shifted sparkline = %+v", got)
+	}
+	if winLoss.MaxAxisType != "group" || winLoss.ManualMin == nil || *winLoss.ManualMin != -1 || winLoss.Sparklines.Items[0].Sqref != "A6" {
+		t.Errorf("unexpected win/loss group %+v", winLoss)
+	}
+
+	s.DeleteRows(6, 1).DeleteRows(1, 4)
+	if ws := reopened.sheets["Stats"]; ws.ExtLst != nil {
+		t.Errorf("expected the sparkline extension to be removed with its cells, got %+v", ws.ExtLst)
+	}
+}
+
+func TestSparklines_Errors(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	for _, group := range []document.SparklineGroup{
+		{Location: "E1:F2", Data: "A1:D2"},
+		{Location: "E1:E3", Data: "A1:D2"},
+		{Location: "E1", Data: "A:A"},
+		{Location: "E1", Data: "Missing!A1:D1"},
+		{Location: "E1", Data: "A1:D1", Type: "pie"},
+		{Location: "E1", Data: "A1:D1", MinAxis: document.AxisCustom, MinValue: 5, MaxAxis: document.AxisCustom, MaxValue: 1},
+	} {
+		if err := sheet.AddSparklines(group).Err(); err == nil {
+			t.Errorf("expected an error for %+v", group)
+		}
+		doc, sheet = rangeTestSheet(t)
+		defer doc.Close()
+	}
+}
+
+func TestExtLst_KeepsOtherExtensions(t *testing.T) {
+	src := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/><extLst>` +
+		`<ext uri="{78C0D931-6437-407d-A8EE-F0AAD7539E65}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main">` +
+		`<x14:conditionalFormattings><x14:conditionalFormatting/></x14:conditionalFormattings></ext></extLst></worksheet>`
+	var ws xmlstructs.Worksheet
+	if err := xml.Unmarshal([]byte(src), &ws); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	sparklineGroups(&ws, true).Items = append(sparklineGroups(&ws, true).Items, xmlstructs.SparklineGroup{})
+	data, err := xml.Marshal(&ws)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `<extLst><ext uri="{78C0D931-6437-407d-A8EE-F0AAD7539E65}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main">` +
+		`<x14:conditionalFormattings><x14:conditionalFormatting/></x14:conditionalFormattings></ext>` +
+		`<ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("extensions not kept:\n%s", data)
+	}
+}
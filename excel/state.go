@@ -7,6 +7,7 @@ import (
 
 	"github.com/gsoultan/thoth/document"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+	"github.com/gsoultan/thoth/internal/fontmetrics"
 )
 
 // state holds the shared internal state for the Excel document.
@@ -18,6 +19,7 @@ type state struct {
 	workbook       *xmlstructs.Workbook
 	sharedStrings  *xmlstructs.SharedStrings
 	sheets         map[string]*xmlstructs.Worksheet
+	sheetPaths     map[string]string // Sheet name -> worksheet part in the source package
 	coreProperties *xmlstructs.CoreProperties
 	workbookRels   *xmlstructs.Relationships
 	styles         *xmlstructs.Styles
@@ -29,6 +31,17 @@ type state struct {
 	sheetRels      map[string]*xmlstructs.Relationships
 	drawings       map[string]*xmlstructs.WsDr
 	tables         map[string]*xmlstructs.Table
+	charts         map[string]*xmlstructs.ChartSpace
+	pivotTables    map[string]*xmlstructs.PivotTableDefinition
+	pivotCaches    map[string]*xmlstructs.PivotCacheDefinition
+	pivotRecords   map[string]*xmlstructs.PivotCacheRecords
+	streams        map[string]*streamWriter
+	comments       map[string]*sheetComments       // Sheet name -> comments, decoded on first access
+	patched        map[string][]byte               // Source parts rewritten as text, e.g. to shift references, and copies of them
+	removed        map[string]bool                 // Source parts dropped from the package, e.g. with a deleted sheet
+	calcDirty      bool                            // Formulas or their inputs changed since the last recalculation
+	password       string                          // Encrypts the saved package and decrypts an encrypted source
+	fontMetrics    map[string]*fontmetrics.Metrics // Lower-case font family -> metrics registered to measure text
 	// Optimization caches
 	sharedStringsIndex map[string]int
 	fontsIndex         map[string]int
@@ -40,10 +53,19 @@ type state struct {
 
 func (e *state) processor() *processor {
 	return &processor{
-		state:          e,
-		sheetProcessor: sheetProcessor{e},
-		cellProcessor:  cellProcessor{e},
-		styleProcessor: styleProcessor{e},
-		mediaProcessor: mediaProcessor{e},
+		state:            e,
+		sheetProcessor:   sheetProcessor{e},
+		cellProcessor:    cellProcessor{e},
+		styleProcessor:   styleProcessor{e},
+		mediaProcessor:   mediaProcessor{e},
+		calcProcessor:    calcProcessor{e},
+		commentProcessor: commentProcessor{e},
+		chartProcessor:   chartProcessor{e},
+		pivotProcessor:   pivotProcessor{e},
+		csvProcessor:     csvProcessor{e},
+		rangeProcessor:   rangeProcessor{e},
+		tableProcessor:   tableProcessor{e},
+		pdfProcessor:     pdfProcessor{e},
+		htmlProcessor:    htmlProcessor{e},
 	}
 }
@@ -0,0 +1,158 @@
+package excel
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// rows returns a pull-based iterator over a sheet's rows.
+// Sheets already held in memory are iterated directly; otherwise <sheetData> is
+// decoded token by token from the source package without materialising the worksheet.
+func (e *state) rows(ctx context.Context, sheet string) iter.Seq2[document.RowView, error] {
+	return func(yield func(document.RowView, error) bool) {
+		for row, err := range e.rawRows(ctx, sheet) {
+			if err != nil {
+				yield(document.RowView{}, err)
+				return
+			}
+			if !yield(e.rowView(row), nil) {
+				return
+			}
+		}
+	}
+}
+
+// rawRows iterates over a sheet's decoded rows like rows, numbering rows whose
+// reference is omitted.
+func (e *state) rawRows(ctx context.Context, sheet string) iter.Seq2[xmlstructs.Row, error] {
+	return func(yield func(xmlstructs.Row, error) bool) {
+		if _, streamed := e.streams[sheet]; streamed {
+			yield(xmlstructs.Row{}, fmt.Errorf("sheet %s is written through a stream writer", sheet))
+			return
+		}
+
+		if ws, ok := e.sheets[sheet]; ok {
+			prevRow := 0
+			for _, row := range ws.SheetData.Rows {
+				if err := ctx.Err(); err != nil {
+					yield(xmlstructs.Row{}, err)
+					return
+				}
+				if row.R == 0 {
+					row.R = prevRow + 1
+				}
+				prevRow = row.R
+				if !yield(row, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		path, ok := e.sheetPaths[sheet]
+		if !ok {
+			yield(xmlstructs.Row{}, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet))
+			return
+		}
+		rc, err := e.openPart(path)
+		if err != nil {
+			yield(xmlstructs.Row{}, err)
+			return
+		}
+		defer rc.Close()
+
+		dec := xml.NewDecoder(rc)
+		inData := false
+		prevRow := 0
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(xmlstructs.Row{}, fmt.Errorf("decode sheet %s: %w", sheet, err))
+				return
+			}
+
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "sheetData" {
+					inData = true
+					continue
+				}
+				if !inData || t.Name.Local != "row" {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					yield(xmlstructs.Row{}, err)
+					return
+				}
+				var row xmlstructs.Row
+				if err := dec.DecodeElement(&row, &t); err != nil {
+					yield(xmlstructs.Row{}, fmt.Errorf("decode row in sheet %s: %w", sheet, err))
+					return
+				}
+				if row.R == 0 {
+					row.R = prevRow + 1
+				}
+				prevRow = row.R
+				if !yield(row, nil) {
+					return
+				}
+			case xml.EndElement:
+				if t.Name.Local == "sheetData" {
+					return
+				}
+			}
+		}
+	}
+}
+
+// rowView converts a decoded row into a RowView, filling in the optional
+// cell references that some producers omit.
+func (e *state) rowView(row xmlstructs.Row) document.RowView {
+	index := row.R
+	view := document.RowView{Index: index, Cells: make([]document.CellView, 0, len(row.Cells))}
+	col := 0
+	for _, cell := range row.Cells {
+		axis := cell.R
+		if axis == "" {
+			axis = fmt.Sprintf("%s%d", numToCol(col+1), index)
+		}
+		col = colToNum(getColumnFromAxis(axis))
+
+		cv := document.CellView{
+			Axis:         axis,
+			Value:        e.resolveValue(cell),
+			StyleID:      cell.S,
+			NumberFormat: e.numberFormatCode(cell.S),
+		}
+		if cell.F != nil {
+			cv.Formula = cell.F.Text
+		}
+		view.Cells = append(view.Cells, cv)
+	}
+	return view
+}
+
+// numberFormatCode returns the number format code applied by a cell format index.
+func (e *state) numberFormatCode(styleID int) string {
+	if e.styles == nil || styleID < 0 || styleID >= len(e.styles.CellXfs.Items) {
+		return ""
+	}
+	id := e.styles.CellXfs.Items[styleID].NumFmtID
+	if e.styles.NumFmts != nil {
+		for _, nf := range e.styles.NumFmts.Items {
+			if nf.NumFmtID == id {
+				return nf.FormatCode
+			}
+		}
+	}
+	return builtinNumFmts[id]
+}
@@ -0,0 +1,170 @@
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func TestStreamWriter_SaveAndReopen(t *testing.T) {
+	doc := NewDocument().(*Document)
+	ctx := t.Context()
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sw, err := doc.StreamSheet("Export")
+	if err != nil {
+		t.Fatalf("StreamSheet failed: %v", err)
+	}
+
+	bold := document.CellStyle{Bold: true}
+	if err := sw.WriteRow(1, document.StreamCell{Value: "ID", Style: &bold}, document.StreamCell{Value: "Name", Style: &bold}); err != nil {
+		t.Fatalf("WriteRow header failed: %v", err)
+	}
+	for i := 2; i <= 2000; i++ {
+		if err := sw.WriteRow(i, i-1, fmt.Sprintf("item-%d", i%10)); err != nil {
+			t.Fatalf("WriteRow %d failed: %v", i, err)
+		}
+	}
+	if err := sw.WriteRow(5, "late"); err == nil {
+		t.Error("Expected error for out-of-order row")
+	}
+	if err := sw.MergeCells("A2001:B2001"); err != nil {
+		t.Fatalf("MergeCells failed: %v", err)
+	}
+	if err := sw.SetColumnWidth(2, 20); err != nil {
+		t.Fatalf("SetColumnWidth failed: %v", err)
+	}
+
+	if len(doc.sheets["Export"].SheetData.Rows) != 0 {
+		t.Error("Expected streamed rows to stay out of memory")
+	}
+	if len(doc.sharedStrings.SI) != 12 {
+		t.Errorf("Expected 12 unique shared strings, got %d", len(doc.sharedStrings.SI))
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened := NewDocument().(*Document)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, &buf); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	sheet, err := reopened.Sheet("Export")
+	if err != nil {
+		t.Fatalf("Sheet failed: %v", err)
+	}
+	if val, _ := sheet.GetCellValue("B1"); val != "Name" {
+		t.Errorf("Expected B1 'Name', got %q", val)
+	}
+	if val, _ := sheet.GetCellValue("A2000"); val != "1999" {
+		t.Errorf("Expected A2000 '1999', got %q", val)
+	}
+	ws := reopened.sheets["Export"]
+	if ws.MergeCells == nil || ws.MergeCells.Items[0].Ref != "A2001:B2001" {
+		t.Error("Expected merged range to be preserved")
+	}
+	if ws.Dimension == nil || ws.Dimension.Ref != "A1:B2000" {
+		t.Errorf("Unexpected dimension %+v", ws.Dimension)
+	}
+}
+
+func TestStreamWriter_InlineStrings(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	sw, err := doc.StreamSheet("Inline", document.StreamOptions{InlineStrings: true})
+	if err != nil {
+		t.Fatalf("StreamSheet failed: %v", err)
+	}
+	if err := sw.WriteRow(1, "a", "b"); err != nil {
+		t.Fatalf("WriteRow failed: %v", err)
+	}
+	if doc.sharedStrings != nil {
+		t.Error("Expected inline strings to bypass the shared string table")
+	}
+
+	sheet, _ := doc.Sheet("Inline")
+	if err := sheet.Cell("A1").Set("x").Err(); err == nil {
+		t.Error("Expected Cell API to reject a streamed sheet")
+	}
+}
+
+func TestSheetRows_DecodesLazily(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Data")
+	sheet.Cell("A1").Set("Name").Style(document.CellStyle{Bold: true})
+	sheet.Cell("B1").Set("Amount")
+	for i := 2; i <= 50; i++ {
+		sheet.Cell(fmt.Sprintf("A%d", i)).Set(fmt.Sprintf("row-%d", i))
+		sheet.Cell(fmt.Sprintf("B%d", i)).Set(float64(i) * 1.5).Style(document.CellStyle{NumberFormat: "0.00"})
+	}
+	sheet.Cell("C2").Formula("B2*2")
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, &buf); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if len(reopened.sheets) != 0 {
+		t.Fatalf("Expected worksheets to be decoded lazily, got %d loaded", len(reopened.sheets))
+	}
+
+	handle, err := reopened.Sheet("Data")
+	if err != nil {
+		t.Fatalf("Sheet failed: %v", err)
+	}
+
+	count := 0
+	for row, err := range handle.Rows(ctx) {
+		if err != nil {
+			t.Fatalf("Rows failed: %v", err)
+		}
+		count++
+		switch row.Index {
+		case 1:
+			if row.Cells[0].Value != "Name" || row.Cells[1].Value != "Amount" {
+				t.Errorf("Unexpected header row %+v", row.Cells)
+			}
+		case 2:
+			if row.Cells[0].Value != "row-2" || row.Cells[1].NumberFormat != "0.00" {
+				t.Errorf("Unexpected row 2 %+v", row.Cells)
+			}
+			if row.Cells[2].Formula != "B2*2" {
+				t.Errorf("Expected formula B2*2, got %q", row.Cells[2].Formula)
+			}
+		}
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("Expected to stop after 10 rows, got %d", count)
+	}
+	if len(reopened.sheets) != 0 {
+		t.Error("Expected Rows not to load the worksheet into memory")
+	}
+
+	content, err := reopened.ReadContent()
+	if err != nil || !strings.Contains(content, "row-50") {
+		t.Errorf("Expected ReadContent to include streamed rows, got err=%v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package excel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// streamWriter implements document.StreamWriter.
+// Rows are encoded as <row> elements into a temporary file as soon as they are written,
+// and the worksheet part is assembled around that file when the document is saved.
+type streamWriter struct {
+	*state
+	name    string
+	ws      *xmlstructs.Worksheet
+	opts    document.StreamOptions
+	file    *os.File
+	buf     *bufio.Writer
+	enc     *xml.Encoder
+	size    int64
+	lastRow int
+	minRow  int
+	maxCol  int
+}
+
+var rowElement = xml.StartElement{Name: xml.Name{Local: "row"}}
+
+func (e *sheetProcessor) streamSheet(name string, opts ...document.StreamOptions) (*streamWriter, error) {
+	if sw, ok := e.streams[name]; ok {
+		return sw, nil
+	}
+	ws, ok := e.worksheet(name)
+	if !ok {
+		if err := e.addSheet(name); err != nil {
+			return nil, err
+		}
+		ws = e.sheets[name]
+	} else if len(ws.SheetData.Rows) > 0 {
+		return nil, fmt.Errorf("sheet %s already contains rows", name)
+	}
+
+	tmp, err := os.CreateTemp("", "thoth-excel-stream-*.xml")
+	if err != nil {
+		return nil, fmt.Errorf("create stream file: %w", err)
+	}
+
+	sw := &streamWriter{
+		state: e.state,
+		name:  name,
+		ws:    ws,
+		file:  tmp,
+	}
+	if len(opts) > 0 {
+		sw.opts = opts[0]
+	}
+	sw.buf = bufio.NewWriterSize(sw, 64*1024)
+	sw.enc = xml.NewEncoder(sw.buf)
+	e.streams[name] = sw
+	delete(e.cellCache, name)
+	return sw, nil
+}
+
+// Write appends encoded rows to the temporary file, tracking its size.
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	n, err := sw.file.Write(p)
+	sw.size += int64(n)
+	return n, err
+}
+
+func (sw *streamWriter) SetColumnWidth(col int, width float64) error {
+	return (&sheetProcessor{sw.state}).setColumnWidth(sw.name, col, width)
+}
+
+func (sw *streamWriter) WriteRow(row int, values ...any) error {
+	if row <= sw.lastRow {
+		return fmt.Errorf("row %d must be greater than previously written row %d", row, sw.lastRow)
+	}
+
+	r := xmlstructs.Row{R: row, Cells: make([]xmlstructs.Cell, 0, len(values))}
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		cell := xmlstructs.Cell{R: fmt.Sprintf("%s%d", numToCol(i+1), row)}
+		if sc, ok := v.(document.StreamCell); ok {
+			if sc.Style != nil {
+				cell.S = (&styleProcessor{sw.state}).getStyleID(*sc.Style)
+			}
+			if sc.Formula != "" {
+				formula := sc.Formula
+				if formula[0] == '=' {
+					formula = formula[1:]
+				}
+				cell.F = &xmlstructs.Formula{Text: formula}
+			}
+			v = sc.Value
+		}
+		if v != nil {
+			if err := sw.writeValue(&cell, v); err != nil {
+				return fmt.Errorf("cell %s: %w", cell.R, err)
+			}
+		}
+		r.Cells = append(r.Cells, cell)
+	}
+
+	if err := sw.enc.EncodeElement(r, rowElement); err != nil {
+		return fmt.Errorf("encode row %d: %w", row, err)
+	}
+
+	if sw.minRow == 0 {
+		sw.minRow = row
+	}
+	sw.lastRow = row
+	sw.maxCol = max(sw.maxCol, len(values))
+	return nil
+}
+
+func (sw *streamWriter) writeValue(cell *xmlstructs.Cell, v any) error {
+	formula := cell.F
+	if s, ok := v.(string); ok && sw.opts.InlineStrings {
+		cell.T = "inlineStr"
+		cell.IS = &xmlstructs.Rst{T: s}
+		return nil
+	}
+	if err := sw.writeCellValue(cell, v); err != nil {
+		return err
+	}
+	cell.F = formula
+	return nil
+}
+
+func (sw *streamWriter) MergeCells(hRange string) error {
+	return (&sheetProcessor{sw.state}).mergeCells(sw.name, hRange)
+}
+
+func (sw *streamWriter) Flush() error {
+	if err := sw.enc.Flush(); err != nil {
+		return err
+	}
+	return sw.buf.Flush()
+}
+
+// dimension returns the used range covered by the written rows.
+func (sw *streamWriter) dimension() string {
+	if sw.lastRow == 0 {
+		return "A1"
+	}
+	return fmt.Sprintf("A%d:%s%d", sw.minRow, numToCol(max(sw.maxCol, 1)), sw.lastRow)
+}
+
+// writeTo writes the complete worksheet part, splicing the streamed rows into <sheetData>.
+func (sw *streamWriter) writeTo(w io.Writer) error {
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+
+	data, err := xml.Marshal(sw.ws)
+	if err != nil {
+		return err
+	}
+	marker := []byte("<sheetData></sheetData>")
+	idx := bytes.Index(data, marker)
+	if idx == -1 {
+		return fmt.Errorf("sheet %s: sheetData element not found", sw.name)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data[:idx]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "<sheetData>"); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, io.NewSectionReader(sw.file, 0, sw.size)); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "</sheetData>"); err != nil {
+		return err
+	}
+	_, err = w.Write(data[idx+len(marker):])
+	return err
+}
+
+func (sw *streamWriter) close() error {
+	name := sw.file.Name()
+	sw.file.Close()
+	return os.Remove(name)
+}
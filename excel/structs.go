@@ -0,0 +1,514 @@
+package excel
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+)
+
+// structTag is the struct tag read by WriteStructs and ReadStructs, e.g.
+// `thoth:"header=Amount,format=#,##0.00,width=14"`. The tag "-" skips a field.
+const structTag = "thoth"
+
+// Defaults applied to struct fields without explicit settings.
+const (
+	defaultDateFormat = "yyyy-mm-dd"
+	defaultStructCell = "A1"
+)
+
+// CellMarshaler is implemented by field types that convert themselves to a cell
+// value: a string, number, bool or time.Time. A nil result leaves the cell empty.
+type CellMarshaler interface {
+	MarshalCell() (any, error)
+}
+
+// CellUnmarshaler is implemented by field types that parse themselves from the
+// text of a cell. It is not called for empty cells.
+type CellUnmarshaler interface {
+	UnmarshalCell(value string) error
+}
+
+// StructOptions controls where WriteStructs and ReadStructs place the records.
+type StructOptions struct {
+	Cell        string              // Top-left cell of the header row, or of the first record without one; defaults to "A1"
+	NoHeader    bool                // Records have no header row; fields map to consecutive columns
+	HeaderStyle *document.CellStyle // Style of the header cells; bold by default
+}
+
+// structField is an exported field of a record type with its tag settings.
+type structField struct {
+	index  []int
+	name   string
+	header string
+	format string
+	width  float64
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	cellMarshalerType = reflect.TypeFor[CellMarshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// WriteStructs writes records, one per row, below a header row holding the field
+// headers. T is a struct or a pointer to one; fields are written in declaration
+// order and formatted according to their thoth tags.
+func WriteStructs[T any](sheet document.Sheet, records []T, opts ...StructOptions) error {
+	s, opt, fields, err := structSheet[T](sheet, opts)
+	if err != nil {
+		return err
+	}
+	col, row, err := structOrigin(opt)
+	if err != nil {
+		return err
+	}
+	p := s.processor()
+	date1904 := s.date1904()
+
+	if !opt.NoHeader {
+		style := document.CellStyle{Bold: true}
+		if opt.HeaderStyle != nil {
+			style = *opt.HeaderStyle
+		}
+		for i, f := range fields {
+			axis := formula.CellName(col+i, row)
+			if err := p.setCellValue(s.name, axis, f.header); err != nil {
+				return err
+			}
+			if err := p.setCellStyle(s.name, axis, style); err != nil {
+				return err
+			}
+		}
+		row++
+	}
+
+	for _, record := range records {
+		rv := reflect.New(reflect.TypeFor[T]()).Elem()
+		rv.Set(reflect.ValueOf(record))
+		if rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				row++
+				continue
+			}
+			rv = rv.Elem()
+		}
+		for i, f := range fields {
+			fv, ok := fieldByIndex(rv, f.index, false)
+			if !ok {
+				continue
+			}
+			value, err := cellValue(fv, date1904)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+			if value == nil {
+				continue
+			}
+			axis := formula.CellName(col+i, row)
+			if err := p.setCellValue(s.name, axis, value); err != nil {
+				return err
+			}
+			format := f.format
+			if format == "" && isTimeField(fv.Type()) {
+				format = defaultDateFormat
+			}
+			if format != "" {
+				if err := p.setCellStyle(s.name, axis, document.CellStyle{NumberFormat: format}); err != nil {
+					return err
+				}
+			}
+		}
+		row++
+	}
+
+	for i, f := range fields {
+		if f.width > 0 {
+			if err := p.setColumnWidth(s.name, col+i, f.width); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadStructs reads the records of a sheet into values of T, a struct or a pointer
+// to one. The header row is the first row at or below StructOptions.Cell holding
+// the header of a field; columns are matched to fields by header, ignoring case,
+// so their order does not matter and other columns are ignored. Empty rows are skipped.
+func ReadStructs[T any](sheet document.Sheet, opts ...StructOptions) ([]T, error) {
+	s, opt, fields, err := structSheet[T](sheet, opts)
+	if err != nil {
+		return nil, err
+	}
+	col, row, err := structOrigin(opt)
+	if err != nil {
+		return nil, err
+	}
+	date1904 := s.date1904()
+
+	var columns map[int]int // Field -> column
+	if opt.NoHeader {
+		columns = make(map[int]int, len(fields))
+		for i := range fields {
+			columns[i] = col + i
+		}
+	}
+
+	var records []T
+	for view, err := range s.rows(s.context(), s.name) {
+		if err != nil {
+			return nil, err
+		}
+		if view.Index < row {
+			continue
+		}
+		values := make(map[int]string, len(view.Cells))
+		for _, cell := range view.Cells {
+			if c, _ := axisPosition(cell.Axis); c >= col {
+				values[c] = cell.Value
+			}
+		}
+
+		if columns == nil {
+			columns = headerColumns(fields, values)
+			continue
+		}
+		empty := true
+		for _, c := range columns {
+			empty = empty && strings.TrimSpace(values[c]) == ""
+		}
+		if empty {
+			continue
+		}
+
+		rv := reflect.New(reflect.TypeFor[T]()).Elem()
+		target := rv
+		if target.Kind() == reflect.Pointer {
+			target.Set(reflect.New(target.Type().Elem()))
+			target = target.Elem()
+		}
+		for i, f := range fields {
+			c, ok := columns[i]
+			if !ok {
+				continue
+			}
+			fv, ok := fieldByIndex(target, f.index, true)
+			if !ok {
+				continue
+			}
+			if err := setField(fv, values[c], date1904); err != nil {
+				return nil, fmt.Errorf("cell %s: field %s: %w", formula.CellName(c, view.Index), f.name, err)
+			}
+		}
+		records = append(records, rv.Interface().(T))
+	}
+	if columns == nil {
+		return nil, fmt.Errorf("sheet %s has no header row for %s", s.name, reflect.TypeFor[T]())
+	}
+	return records, nil
+}
+
+// headerColumns maps fields to the columns holding their headers, or returns nil
+// when the row holds none of them.
+func headerColumns(fields []structField, values map[int]string) map[int]int {
+	var columns map[int]int
+	for i, f := range fields {
+		for c, v := range values {
+			if strings.EqualFold(strings.TrimSpace(v), f.header) {
+				if columns == nil {
+					columns = make(map[int]int)
+				}
+				columns[i] = c
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func structSheet[T any](sheet document.Sheet, opts []StructOptions) (*sheetHandle, StructOptions, []structField, error) {
+	var opt StructOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	s, ok := sheet.(*sheetHandle)
+	if !ok {
+		return nil, opt, nil, fmt.Errorf("sheet is not an Excel worksheet")
+	}
+	if s.err != nil {
+		return nil, opt, nil, s.err
+	}
+	t := reflect.TypeFor[T]()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, opt, nil, fmt.Errorf("records must be structs, got %s", reflect.TypeFor[T]())
+	}
+	fields, err := structFields(t)
+	if err != nil {
+		return nil, opt, nil, err
+	}
+	if len(fields) == 0 {
+		return nil, opt, nil, fmt.Errorf("%s has no exported fields", t)
+	}
+	return s, opt, fields, nil
+}
+
+func structOrigin(opt StructOptions) (int, int, error) {
+	cell := opt.Cell
+	if cell == "" {
+		cell = defaultStructCell
+	}
+	col, row := axisPosition(cell)
+	if col == 0 || row == 0 {
+		return 0, 0, fmt.Errorf("invalid cell %q", opt.Cell)
+	}
+	return col, row, nil
+}
+
+// structFields lists the exported fields of a struct type, including those
+// promoted from embedded structs, with their tag settings.
+func structFields(t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && ft.Kind() == reflect.Struct && !isCellType(sf.Type) {
+			continue
+		}
+		tag := sf.Tag.Get(structTag)
+		if tag == "-" {
+			continue
+		}
+		f, err := parseStructTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		f.index, f.name = sf.Index, sf.Name
+		if f.header == "" {
+			f.header = sf.Name
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseStructTag reads the key=value settings of a thoth tag. Values may contain
+// commas, as number formats do: text up to the next known key belongs to the value.
+func parseStructTag(tag string) (structField, error) {
+	var f structField
+	var keys []string
+	var values []string
+	for part := range strings.SplitSeq(tag, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		switch key = strings.TrimSpace(key); {
+		case ok && (key == "header" || key == "format" || key == "width"):
+			keys, values = append(keys, key), append(values, value)
+		case len(values) > 0:
+			values[len(values)-1] += "," + part
+		case strings.TrimSpace(part) != "":
+			return f, fmt.Errorf("invalid %s tag %q", structTag, tag)
+		}
+	}
+	for i, key := range keys {
+		switch key {
+		case "header":
+			f.header = strings.TrimSpace(values[i])
+		case "format":
+			f.format = values[i]
+		case "width":
+			w, err := strconv.ParseFloat(strings.TrimSpace(values[i]), 64)
+			if err != nil || w < 0 {
+				return f, fmt.Errorf("invalid width %q", values[i])
+			}
+			f.width = w
+		}
+	}
+	return f, nil
+}
+
+// fieldByIndex returns a possibly promoted field. Nil embedded pointers are
+// allocated when alloc is set, and otherwise report false.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isCellType reports whether values of t are written to a single cell even though t is a struct.
+func isCellType(t reflect.Type) bool {
+	return isTimeField(t) || t.Implements(cellMarshalerType) || reflect.PointerTo(t).Implements(cellMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+func isTimeField(t reflect.Type) bool {
+	return t == timeType || (t.Kind() == reflect.Pointer && t.Elem() == timeType)
+}
+
+// cellValue converts a field to a value accepted by setCellValue, or nil for an empty cell.
+func cellValue(v reflect.Value, date1904 bool) (any, error) {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	if m, ok := asInterface[CellMarshaler](v); ok {
+		value, err := m.MarshalCell()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := value.(time.Time); ok {
+			return formula.TimeToSerial(t, date1904), nil
+		}
+		return value, nil
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return nil, nil
+		}
+		return formula.TimeToSerial(t, date1904), nil
+	}
+	if m, ok := asInterface[encoding.TextMarshaler](v); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return cellValue(v.Elem(), date1904)
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// setField parses the text of a cell into a field. Empty cells leave the field unset.
+func setField(v reflect.Value, text string, date1904 bool) error {
+	if text == "" {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), text, date1904)
+	}
+	if u, ok := asInterface[CellUnmarshaler](v); ok {
+		return u.UnmarshalCell(text)
+	}
+	if v.Type() == timeType {
+		t, err := parseCellTime(text, date1904)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if u, ok := asInterface[encoding.TextUnmarshaler](v); ok {
+		return u.UnmarshalText([]byte(text))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return fmt.Errorf("invalid integer %q", text)
+			}
+			n = int64(f)
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("%s overflows %s", text, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return fmt.Errorf("invalid unsigned integer %q", text)
+			}
+			n = uint64(f)
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("%s overflows %s", text, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseCellTime reads a date stored as a serial number, or written as text.
+func parseCellTime(text string, date1904 bool) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(text, 64); err == nil {
+		return formula.SerialToTime(serial, date1904), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
+
+// asInterface returns v, or its address when addressable, as an I.
+func asInterface[I any](v reflect.Value) (I, bool) {
+	if v.CanAddr() {
+		if i, ok := v.Addr().Interface().(I); ok {
+			return i, true
+		}
+	}
+	if v.CanInterface() {
+		i, ok := v.Interface().(I)
+		return i, ok
+	}
+	var zero I
+	return zero, false
+}
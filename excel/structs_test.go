@@ -0,0 +1,160 @@
+package excel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type grade int
+
+func (g grade) MarshalCell() (any, error) { return string(rune('A' + g)), nil }
+
+func (g *grade) UnmarshalCell(value string) error {
+	*g = grade(value[0] - 'A')
+	return nil
+}
+
+type audit struct {
+	Owner string `thoth:"header=Owner"`
+}
+
+type sale struct {
+	audit
+	Item     string    `thoth:"header=Item,width=20"`
+	Amount   float64   `thoth:"header=Amount,format=#,##0.00,width=14"`
+	Qty      int       `thoth:"header=Qty"`
+	Date     time.Time `thoth:"header=Date"`
+	Discount *float64  `thoth:"header=Discount"`
+	Grade    grade     `thoth:"header=Grade"`
+	Paid     bool
+	internal string
+	Note     string `thoth:"-"`
+}
+
+func TestStructs_RoundTrip(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	day := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	sales := []sale{
+		{audit: audit{"ann"}, Item: "Widget", Amount: 1234.5, Qty: 3, Date: day, Discount: new(0.1), Grade: 1, Paid: true, Note: "skip"},
+		{Item: "Gadget", Amount: 20, Qty: 1, Grade: 2},
+	}
+	sheet, _ := doc.Sheet("Sales")
+	sheet.Cell("A1").Set("Report")
+	if err := WriteStructs(sheet, sales, StructOptions{Cell: "B3"}); err != nil {
+		t.Fatalf("WriteStructs failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	for part, wants := range map[string][]string{
+		"xl/worksheets/sheet1.xml": {`<col min="3" max="3" width="20"`, `<col min="4" max="4" width="14"`},
+		"xl/styles.xml":            {`numFmtId="4"`, `formatCode="yyyy-mm-dd"`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(parts[part], want) {
+				t.Errorf("Expected %s in %s", want, part)
+			}
+		}
+	}
+
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s, _ := reopened.Sheet("Sales")
+	got, err := ReadStructs[*sale](s)
+	if err != nil {
+		t.Fatalf("ReadStructs failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(got))
+	}
+	first, second := got[0], got[1]
+	if first.Owner != "ann" || first.Item != "Widget" || first.Amount != 1234.5 || first.Qty != 3 ||
+		!first.Date.Equal(day) || first.Discount == nil || *first.Discount != 0.1 || first.Grade != 1 || !first.Paid || first.Note != "" {
+		t.Errorf("Unexpected first record %+v", first)
+	}
+	if second.Discount != nil || !second.Date.IsZero() || second.Grade != 2 || second.Paid {
+		t.Errorf("Unexpected second record %+v", second)
+	}
+}
+
+func TestReadStructs_HeaderDetection(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	type row struct {
+		Name string `thoth:"header=Name"`
+		Age  uint8  `thoth:"header=Age"`
+	}
+	sheet, _ := doc.Sheet("People")
+	sheet.Cell("A1").Set("People export")
+	sheet.Cell("A3").Set("id")
+	sheet.Cell("B3").Set("AGE")
+	sheet.Cell("C3").Set("name")
+	for i, r := range [][]any{{1, 30, "Ann"}, nil, {2, 41, "Bob"}} {
+		for j, v := range r {
+			sheet.Cell(string(rune('A'+j)) + string(rune('4'+i))).Set(v)
+		}
+	}
+
+	got, err := ReadStructs[row](sheet)
+	if err != nil {
+		t.Fatalf("ReadStructs failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != (row{"Ann", 30}) || got[1] != (row{"Bob", 41}) {
+		t.Errorf("Unexpected records %+v", got)
+	}
+
+	sheet.Cell("B6").Set("old")
+	if _, err := ReadStructs[row](sheet); err == nil || !strings.Contains(err.Error(), "B6") {
+		t.Errorf("Expected an error naming cell B6, got %v", err)
+	}
+	if _, err := ReadStructs[row](sheet, StructOptions{Cell: "A5"}); err == nil {
+		t.Error("Expected an error without a header row")
+	}
+}
+
+func TestStructs_Invalid(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+	sheet, _ := doc.Sheet("Sheet1")
+
+	type badTag struct {
+		A int `thoth:"colour=red"`
+	}
+	type badWidth struct {
+		A int `thoth:"width=wide"`
+	}
+	type badType struct {
+		A []int
+	}
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"unknown key", func() error { return WriteStructs(sheet, []badTag{{}}) }},
+		{"invalid width", func() error { return WriteStructs(sheet, []badWidth{{}}) }},
+		{"unsupported type", func() error { return WriteStructs(sheet, []badType{{A: []int{1}}}) }},
+		{"not a struct", func() error { return WriteStructs(sheet, []int{1}) }},
+		{"invalid cell", func() error { return WriteStructs(sheet, []sale{}, StructOptions{Cell: "1A"}) }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package excel
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/gsoultan/thoth/document"
 	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
@@ -10,53 +9,118 @@ import (
 
 type styleProcessor struct{ *state }
 
-func (e *styleProcessor) setConditionalFormatting(sheet, ref, ruleType, operator, formula string, style document.CellStyle) error {
-	ws, ok := e.sheets[sheet]
-	if !ok {
-		return fmt.Errorf("sheet %s not found", sheet)
+func (e *styleProcessor) setCellStyle(sheet, axis string, style document.CellStyle) error {
+	xfID := e.getStyleID(style)
+
+	cell, err := e.getOrCreateCell(sheet, axis)
+	if err != nil {
+		return err
 	}
+	cell.S = xfID
 
-	// 1. Create DXF (Differential Formatting)
-	dxf := xmlstructs.Xf{}
-	if style.Bold {
-		dxf.Font = &xmlstructs.Font{Bold: &struct{}{}}
+	return nil
+}
+
+func (e *styleProcessor) getCellStyle(sheet, axis string) (document.CellStyle, error) {
+	cell, err := e.lookupCell(sheet, axis)
+	if err != nil || cell == nil {
+		return e.styleFromXf(0), err
 	}
-	if style.Color != "" {
-		if dxf.Font == nil {
-			dxf.Font = &xmlstructs.Font{}
-		}
-		dxf.Font.Color = &xmlstructs.Color{RGB: style.Color}
+	return e.styleFromXf(cell.S), nil
+}
+
+// styleFromXf resolves a cellXfs index to a CellStyle, the inverse of getStyleID.
+func (e *styleProcessor) styleFromXf(id int) document.CellStyle {
+	var style document.CellStyle
+	if e.styles == nil || id < 0 || id >= len(e.styles.CellXfs.Items) {
+		return style
 	}
-	if style.Background != "" {
-		dxf.Fill = &xmlstructs.Fill{
-			PatternFill: &xmlstructs.PatternFill{
-				PatternType: "solid",
-				FgColor:     &xmlstructs.Color{RGB: style.Background},
-			},
+	xf := e.styles.CellXfs.Items[id]
+
+	if xf.FontID >= 0 && xf.FontID < len(e.styles.Fonts.Items) {
+		f := e.styles.Fonts.Items[xf.FontID]
+		style.Bold = f.Bold != nil
+		style.Italic = f.Italic != nil
+		style.Size = valOr(f.Size, 0).(int)
+		style.Color = colorHex(f.Color)
+		style.Font = valOr(f.Name, "").(string)
+	}
+
+	if xf.FillID >= 0 && xf.FillID < len(e.styles.Fills.Items) {
+		if pf := e.styles.Fills.Items[xf.FillID].PatternFill; pf != nil && pf.PatternType == "solid" {
+			style.Background = colorHex(pf.FgColor)
 		}
 	}
 
-	dxfID := e.styles.AddDxf(dxf)
+	if xf.BorderID >= 0 && xf.BorderID < len(e.styles.Borders.Items) {
+		b := e.styles.Borders.Items[xf.BorderID]
+		for _, edge := range []struct {
+			edge *xmlstructs.BorderEdge
+			flag *bool
+		}{{&b.Left, &style.BorderLeft}, {&b.Right, &style.BorderRight}, {&b.Top, &style.BorderTop}, {&b.Bottom, &style.BorderBottom}} {
+			if edge.edge.Style == "" || edge.edge.Style == "none" {
+				continue
+			}
+			*edge.flag = true
+			style.BorderWidth = max(style.BorderWidth, borderWidths[edge.edge.Style])
+			if style.BorderColor == "" {
+				style.BorderColor = colorHex(edge.edge.Color)
+			}
+		}
+		style.Border = style.BorderLeft && style.BorderRight && style.BorderTop && style.BorderBottom
+	}
 
-	// 2. Add Conditional Formatting Rule
-	cf := xmlstructs.ConditionalFormatting{
-		Sqref: ref,
-		CfRule: []xmlstructs.CfRule{
-			{
-				Type:     ruleType,
-				Operator: operator,
-				Priority: len(ws.ConditionalFormatting) + 1,
-				DxfID:    &dxfID,
-				Formula:  []string{formula},
-			},
-		},
+	if a := xf.Alignment; a != nil {
+		style.Horizontal = a.Horizontal
+		style.Vertical = a.Vertical
+		style.Indent = float64(a.Indent)
+		style.WrapText = a.WrapText == 1
 	}
+	if xf.NumFmtID != 0 {
+		style.NumberFormat = e.numberFormatCode(id)
+	}
+	return style
+}
 
-	ws.ConditionalFormatting = append(ws.ConditionalFormatting, cf)
-	return nil
+// borderWidths maps border styles to the BorderWidth that getStyleID writes them for.
+var borderWidths = map[string]float64{
+	"hair": 1, "thin": 1, "dotted": 1, "dashed": 1, "dashDot": 1, "dashDotDot": 1,
+	"medium": 2, "mediumDashed": 2, "mediumDashDot": 2, "mediumDashDotDot": 2, "slantDashDot": 2,
+	"thick": 3, "double": 3,
 }
 
-func (e *styleProcessor) setCellStyle(sheet, axis string, style document.CellStyle) error {
+// colorHex returns a color as RRGGBB, dropping the alpha of ARGB values.
+// Theme colors are not resolved and return "".
+func colorHex(c *xmlstructs.Color) string {
+	switch {
+	case c == nil:
+		return ""
+	case len(c.RGB) == 8:
+		return c.RGB[2:]
+	case c.RGB != "":
+		return c.RGB
+	case c.Indexed != nil && *c.Indexed >= 0 && *c.Indexed < len(indexedColors):
+		return indexedColors[*c.Indexed]
+	}
+	return ""
+}
+
+// indexedColors is the default legacy palette, followed by the system
+// foreground and background colors 64 and 65.
+var indexedColors = []string{
+	"000000", "FFFFFF", "FF0000", "00FF00", "0000FF", "FFFF00", "FF00FF", "00FFFF",
+	"000000", "FFFFFF", "FF0000", "00FF00", "0000FF", "FFFF00", "FF00FF", "00FFFF",
+	"800000", "008000", "000080", "808000", "800080", "008080", "C0C0C0", "808080",
+	"9999FF", "993366", "FFFFCC", "CCFFFF", "660066", "FF8080", "0066CC", "CCCCFF",
+	"000080", "FF00FF", "FFFF00", "00FFFF", "800080", "800000", "008080", "0000FF",
+	"00CCFF", "CCFFFF", "CCFFCC", "FFFF99", "99CCFF", "FF99CC", "CC99FF", "FFCC99",
+	"3366FF", "33CCCC", "99CC00", "FFCC00", "FF9900", "FF6600", "666699", "969696",
+	"003366", "339966", "003300", "333300", "993300", "993366", "333399", "333333",
+	"000000", "FFFFFF",
+}
+
+// getStyleID resolves a CellStyle to a cellXfs index, registering fonts, fills and borders as needed.
+func (e *styleProcessor) getStyleID(style document.CellStyle) int {
 	// 1. Font
 	f := xmlstructs.Font{
 		Size: &xmlstructs.ValInt{Val: 11},
@@ -93,33 +157,7 @@ func (e *styleProcessor) setCellStyle(sheet, axis string, style document.CellSty
 
 	// 3. Border
 	borderID := 0
-	if style.Border || style.BorderTop || style.BorderBottom || style.BorderLeft || style.BorderRight {
-		border := xmlstructs.Border{}
-		borderStyle := "thin"
-		if style.BorderWidth > 1 {
-			borderStyle = "medium"
-		}
-		if style.BorderWidth > 2 {
-			borderStyle = "thick"
-		}
-
-		borderColor := &xmlstructs.Color{RGB: style.BorderColor}
-		if style.BorderColor == "" {
-			borderColor = nil
-		}
-
-		if style.Border || style.BorderLeft {
-			border.Left = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
-		}
-		if style.Border || style.BorderRight {
-			border.Right = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
-		}
-		if style.Border || style.BorderTop {
-			border.Top = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
-		}
-		if style.Border || style.BorderBottom {
-			border.Bottom = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
-		}
+	if border, ok := borderFromStyle(style); ok {
 		borderID = e.getBorderID(border)
 	}
 
@@ -149,7 +187,7 @@ func (e *styleProcessor) setCellStyle(sheet, axis string, style document.CellSty
 		xf.ApplyBorder = 1
 	}
 
-	if style.Horizontal != "" || style.Vertical != "" || style.Padding > 0 {
+	if style.Horizontal != "" || style.Vertical != "" || style.Padding > 0 || style.WrapText {
 		xf.ApplyAlignment = 1
 		xf.Alignment = &xmlstructs.Alignment{
 			Horizontal: style.Horizontal,
@@ -159,21 +197,45 @@ func (e *styleProcessor) setCellStyle(sheet, axis string, style document.CellSty
 		}
 	}
 
-	xfID := e.getXfID(xf)
+	return e.getXfID(xf)
+}
 
-	cell, err := e.getOrCreateCell(sheet, axis)
-	if err != nil {
-		return err
+// borderFromStyle builds the border of style, reporting false when it has no edges.
+func borderFromStyle(style document.CellStyle) (xmlstructs.Border, bool) {
+	border := xmlstructs.Border{}
+	if !style.Border && !style.BorderTop && !style.BorderBottom && !style.BorderLeft && !style.BorderRight {
+		return border, false
+	}
+	borderStyle := "thin"
+	if style.BorderWidth > 1 {
+		borderStyle = "medium"
+	}
+	if style.BorderWidth > 2 {
+		borderStyle = "thick"
 	}
-	cell.S = xfID
 
-	return nil
+	borderColor := &xmlstructs.Color{RGB: style.BorderColor}
+	if style.BorderColor == "" {
+		borderColor = nil
+	}
+
+	if style.Border || style.BorderLeft {
+		border.Left = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
+	}
+	if style.Border || style.BorderRight {
+		border.Right = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
+	}
+	if style.Border || style.BorderTop {
+		border.Top = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
+	}
+	if style.Border || style.BorderBottom {
+		border.Bottom = xmlstructs.BorderEdge{Style: borderStyle, Color: borderColor}
+	}
+	return border, true
 }
 
 func (e *styleProcessor) getFontID(f xmlstructs.Font) int {
-	key := fmt.Sprintf("b:%v|i:%v|s:%v|c:%v|n:%v",
-		f.Bold != nil, f.Italic != nil,
-		valOr(f.Size, 0), valOr(f.Color, ""), valOr(f.Name, ""))
+	key := fontKey(f)
 	if id, ok := e.fontsIndex[key]; ok {
 		return id
 	}
@@ -182,6 +244,13 @@ func (e *styleProcessor) getFontID(f xmlstructs.Font) int {
 	return id
 }
 
+// fontKey identifies a font in the fontsIndex.
+func fontKey(f xmlstructs.Font) string {
+	return fmt.Sprintf("b:%v|i:%v|s:%v|c:%v|n:%v",
+		f.Bold != nil, f.Italic != nil,
+		valOr(f.Size, 0), valOr(f.Color, ""), valOr(f.Name, ""))
+}
+
 func (e *styleProcessor) getFillID(f xmlstructs.Fill) int {
 	key := "none"
 	if f.PatternFill != nil {
@@ -281,27 +350,43 @@ var standardNumFmts = map[string]int{
 	"@":             49,
 }
 
-func (e *styleProcessor) setDataValidation(sheet string, ref string, options ...string) error {
-	ws, ok := e.sheets[sheet]
-	if !ok {
-		return fmt.Errorf("sheet %s not found", sheet)
-	}
-
-	if ws.DataValidations == nil {
-		ws.DataValidations = &xmlstructs.DataValidations{Items: make([]xmlstructs.DataValidation, 0)}
-	}
-
-	dv := xmlstructs.DataValidation{
-		Type:         "list",
-		AllowBlank:   1,
-		ShowInputMsg: 1,
-		ShowErrorMsg: 1,
-		Sqref:        ref,
-		Formula1:     fmt.Sprintf("\"%s\"", strings.Join(options, ",")),
-	}
-
-	ws.DataValidations.Items = append(ws.DataValidations.Items, dv)
-	ws.DataValidations.Count = len(ws.DataValidations.Items)
-
-	return nil
+// builtinNumFmts are the number formats implied by the built-in numFmtId values,
+// which workbooks reference without declaring them in styles.xml.
+var builtinNumFmts = map[int]string{
+	0:  "General",
+	1:  "0",
+	2:  "0.00",
+	3:  "#,##0",
+	4:  "#,##0.00",
+	5:  `"$"#,##0_);\("$"#,##0\)`,
+	6:  `"$"#,##0_);[Red]\("$"#,##0\)`,
+	7:  `"$"#,##0.00_);\("$"#,##0.00\)`,
+	8:  `"$"#,##0.00_);[Red]\("$"#,##0.00\)`,
+	9:  "0%",
+	10: "0.00%",
+	11: "0.00E+00",
+	12: "# ?/?",
+	13: "# ??/??",
+	14: "mm-dd-yy",
+	15: "d-mmm-yy",
+	16: "d-mmm",
+	17: "mmm-yy",
+	18: "h:mm AM/PM",
+	19: "h:mm:ss AM/PM",
+	20: "h:mm",
+	21: "h:mm:ss",
+	22: "m/d/yy h:mm",
+	37: "#,##0 ;(#,##0)",
+	38: "#,##0 ;[Red](#,##0)",
+	39: "#,##0.00;(#,##0.00)",
+	40: "#,##0.00;[Red](#,##0.00)",
+	41: `_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`,
+	42: `_("$"* #,##0_);_("$"* \(#,##0\);_("$"* "-"_);_(@_)`,
+	43: `_(* #,##0.00_);_(* \(#,##0.00\);_(* "-"??_);_(@_)`,
+	44: `_("$"* #,##0.00_);_("$"* \(#,##0.00\);_("$"* "-"??_);_(@_)`,
+	45: "mm:ss",
+	46: "[h]:mm:ss",
+	47: "mmss.0",
+	48: "##0.0E+0",
+	49: "@",
 }
@@ -0,0 +1,603 @@
+package excel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// tableProcessor edits and reads the tables (list objects) of the workbook.
+// Tables read from the source package are decoded into e.tables when edited
+// and written back from the settings this package models.
+type tableProcessor struct{ *state }
+
+// subtotalFunctions maps totals row functions to the SUBTOTAL function numbers
+// Excel writes for them, which ignore rows hidden by a filter.
+var subtotalFunctions = map[document.TotalsFunction]int{
+	document.TotalsAverage:   101,
+	document.TotalsCountNums: 102,
+	document.TotalsCount:     103,
+	document.TotalsMax:       104,
+	document.TotalsMin:       105,
+	document.TotalsStdDev:    107,
+	document.TotalsSum:       109,
+	document.TotalsVar:       110,
+}
+
+// workbookTable is a table with the sheet and part it belongs to.
+type workbookTable struct {
+	sheet string
+	path  string
+	table *xmlstructs.Table
+}
+
+// tableLayout is the position of a table's rows.
+type tableLayout struct {
+	area      cellArea // Including the header and totals rows
+	headerRow bool
+	totalsRow bool
+}
+
+func newTableLayout(t *xmlstructs.Table) (tableLayout, error) {
+	area, err := parseArea(t.Ref)
+	if err != nil {
+		return tableLayout{}, fmt.Errorf("table %s: %w", t.Name, err)
+	}
+	return tableLayout{
+		area:      area,
+		headerRow: t.HeaderRowCount == nil || *t.HeaderRowCount > 0,
+		totalsRow: t.TotalsRowCount > 0,
+	}, nil
+}
+
+// data returns the data rows of the table.
+func (l tableLayout) data() cellArea {
+	a := l.area
+	if l.headerRow {
+		a.row1++
+	}
+	if l.totalsRow {
+		a.row2--
+	}
+	return a
+}
+
+// formulaTable returns the layout structured references of formulas resolve against.
+func (wt workbookTable) formulaTable() (formula.Table, bool) {
+	l, err := newTableLayout(wt.table)
+	if err != nil {
+		return formula.Table{}, false
+	}
+	columns := make([]string, len(wt.table.TableColumns.Items))
+	for i, c := range wt.table.TableColumns.Items {
+		columns[i] = c.Name
+	}
+	return formula.Table{
+		Name: wt.table.Name, Sheet: wt.sheet,
+		Col1: l.area.col1, Row1: l.area.row1, Col2: l.area.col2, Row2: l.area.row2,
+		Columns: columns, HeaderRow: l.headerRow, TotalsRow: l.totalsRow,
+	}, true
+}
+
+// workbookTables returns the tables of every sheet in sheet order.
+func (e *state) workbookTables() ([]workbookTable, error) {
+	var tables []workbookTable
+	for _, sh := range e.workbook.Sheets {
+		found, err := (&sheetProcessor{e}).sheetTables(sh.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range found {
+			table := t.table
+			if table == nil {
+				table = &xmlstructs.Table{}
+				if err := e.loadXML(t.path, table); err != nil {
+					return nil, fmt.Errorf("load table %s: %w", t.path, err)
+				}
+			}
+			tables = append(tables, workbookTable{sheet: sh.Name, path: t.path, table: table})
+		}
+	}
+	return tables, nil
+}
+
+// findTable returns a table of a sheet by name. Tables to be edited are moved
+// into e.tables so that they are saved from their decoded settings.
+func (e *tableProcessor) findTable(sheet, name string, edit bool) (workbookTable, error) {
+	if !e.hasSheet(sheet) {
+		return workbookTable{}, fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	found, err := (&sheetProcessor{e.state}).sheetTables(sheet)
+	if err != nil {
+		return workbookTable{}, err
+	}
+	for _, t := range found {
+		if !strings.EqualFold(t.name, name) {
+			continue
+		}
+		table := t.table
+		if table == nil {
+			table = &xmlstructs.Table{}
+			if err := e.loadXML(t.path, table); err != nil {
+				return workbookTable{}, fmt.Errorf("load table %s: %w", t.path, err)
+			}
+			if edit {
+				e.tables[t.path] = table
+			}
+		}
+		return workbookTable{sheet: sheet, path: t.path, table: table}, nil
+	}
+	return workbookTable{}, fmt.Errorf("table %s not found on sheet %s", name, sheet)
+}
+
+// tableInfo describes a table.
+func tableInfo(wt workbookTable) document.TableInfo {
+	t := wt.table
+	info := document.TableInfo{Name: t.Name, Sheet: wt.sheet, Ref: t.Ref}
+	for _, c := range t.TableColumns.Items {
+		info.Columns = append(info.Columns, c.Name)
+	}
+	if si := t.TableStyleInfo; si != nil {
+		info.Style = document.TableStyle{
+			Name:          si.Name,
+			FirstColumn:   si.ShowFirstColumn == 1,
+			LastColumn:    si.ShowLastColumn == 1,
+			RowStripes:    si.ShowRowStripes == 1,
+			ColumnStripes: si.ShowColumnStripes == 1,
+		}
+	}
+	info.HeaderRow = t.HeaderRowCount == nil || *t.HeaderRowCount > 0
+	info.TotalsRow = t.TotalsRowCount > 0
+	return info
+}
+
+func (e *tableProcessor) tableInfos() ([]document.TableInfo, error) {
+	tables, err := e.workbookTables()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]document.TableInfo, len(tables))
+	for i, wt := range tables {
+		infos[i] = tableInfo(wt)
+	}
+	return infos, nil
+}
+
+func (e *tableProcessor) tableInfo(sheet, name string) (document.TableInfo, error) {
+	wt, err := e.findTable(sheet, name, false)
+	if err != nil {
+		return document.TableInfo{}, err
+	}
+	return tableInfo(wt), nil
+}
+
+// columnIndex returns the 0-based index of a table column by header.
+func columnIndex(t *xmlstructs.Table, header string) int {
+	for i, c := range t.TableColumns.Items {
+		if strings.EqualFold(c.Name, header) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setTableArea updates the area of a table and of its filter, which excludes the totals row.
+func setTableArea(t *xmlstructs.Table, l tableLayout) {
+	t.Ref = l.area.String()
+	if t.AutoFilter != nil {
+		filter := l.area
+		if l.totalsRow {
+			filter.row2--
+		}
+		t.AutoFilter.Ref = filter.String()
+	}
+}
+
+// checkTableSpace rejects growing a table into cells that hold values or
+// belong to another table of the sheet.
+func (e *tableProcessor) checkTableSpace(ws *xmlstructs.Worksheet, wt workbookTable, a cellArea) error {
+	for _, row := range e.areaCells(ws, wt.sheet, a, nil) {
+		for _, cell := range row {
+			if cell != nil && (cell.V != "" || cell.F != nil || cell.IS != nil) {
+				return fmt.Errorf("cell %s next to table %s is not empty", cell.R, wt.table.Name)
+			}
+		}
+	}
+	return e.checkTableOverlap(wt, a)
+}
+
+// checkTableOverlap rejects an area of a table that overlaps another table of its sheet.
+func (e *tableProcessor) checkTableOverlap(wt workbookTable, a cellArea) error {
+	found, err := (&sheetProcessor{e.state}).sheetTables(wt.sheet)
+	if err != nil {
+		return err
+	}
+	for _, t := range found {
+		if t.path == wt.path {
+			continue
+		}
+		other, err := parseArea(t.ref)
+		if err == nil && other.col1 <= a.col2 && a.col1 <= other.col2 && other.row1 <= a.row2 && a.row1 <= other.row2 {
+			return fmt.Errorf("table %s would overlap table %s", wt.table.Name, t.name)
+		}
+	}
+	return nil
+}
+
+func (e *tableProcessor) setTableStyle(sheet, name string, style document.TableStyle) error {
+	wt, err := e.findTable(sheet, name, true)
+	if err != nil {
+		return err
+	}
+	wt.table.TableStyleInfo = &xmlstructs.TableStyleInfo{
+		Name:              style.Name,
+		ShowFirstColumn:   boolToInt(style.FirstColumn),
+		ShowLastColumn:    boolToInt(style.LastColumn),
+		ShowRowStripes:    boolToInt(style.RowStripes),
+		ShowColumnStripes: boolToInt(style.ColumnStripes),
+	}
+	return nil
+}
+
+func (e *tableProcessor) setTotalsRow(sheet, name string, totals []document.TableTotal) error {
+	ws, err := (&rangeProcessor{e.state}).editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	wt, err := e.findTable(sheet, name, true)
+	if err != nil {
+		return err
+	}
+	t := wt.table
+	for _, total := range totals {
+		if columnIndex(t, total.Column) < 0 {
+			return fmt.Errorf("table %s has no column %s", t.Name, total.Column)
+		}
+		if _, ok := subtotalFunctions[total.Function]; !ok && total.Function != document.TotalsNone {
+			return fmt.Errorf("unknown totals function %q", total.Function)
+		}
+	}
+	l, err := newTableLayout(t)
+	if err != nil {
+		return err
+	}
+
+	if !l.totalsRow {
+		if len(totals) == 0 {
+			return nil
+		}
+		row := cellArea{l.area.col1, l.area.row2 + 1, l.area.col2, l.area.row2 + 1}
+		if row.row1 > formula.MaxRows {
+			return fmt.Errorf("table %s ends at the last row", t.Name)
+		}
+		if err := e.checkTableSpace(ws, wt, row); err != nil {
+			return err
+		}
+		l.area.row2++
+		l.totalsRow = true
+	}
+	row := cellArea{l.area.col1, l.area.row2, l.area.col2, l.area.row2}
+	e.filterCells(ws, sheet, row, func(c *xmlstructs.Cell) bool {
+		c.T, c.V, c.F, c.IS = "", "", nil, nil
+		return c.S == 0
+	})
+	for i := range t.TableColumns.Items {
+		c := &t.TableColumns.Items[i]
+		c.TotalsRowFunction, c.TotalsRowLabel, c.TotalsRowFormula = "", "", nil
+	}
+	e.calcDirty = true
+
+	if len(totals) == 0 {
+		l.area.row2--
+		l.totalsRow = false
+		t.TotalsRowCount = 0
+		setTableArea(t, l)
+		return nil
+	}
+	t.TotalsRowCount = 1
+	setTableArea(t, l)
+
+	byColumn := make(map[int]document.TableTotal, len(totals))
+	for _, total := range totals {
+		byColumn[l.area.col1+columnIndex(t, total.Column)] = total
+	}
+	cells := e.areaCells(ws, sheet, row, func(col, _ int) bool {
+		_, ok := byColumn[col]
+		return ok
+	})
+	for j, cell := range cells[0] {
+		total, ok := byColumn[row.col1+j]
+		if !ok {
+			continue
+		}
+		column := &t.TableColumns.Items[j]
+		if total.Function == document.TotalsNone {
+			column.TotalsRowLabel = total.Label
+			if err := e.writeCellValue(cell, total.Label); err != nil {
+				return err
+			}
+			continue
+		}
+		column.TotalsRowFunction = string(total.Function)
+		text := fmt.Sprintf("SUBTOTAL(%d,%s)", subtotalFunctions[total.Function], formula.ColumnReference(t.Name, column.Name))
+		cell.F = &xmlstructs.Formula{Text: text}
+	}
+	return nil
+}
+
+func (e *tableProcessor) setCalculatedColumn(sheet, name, header, text string) error {
+	ws, err := (&rangeProcessor{e.state}).editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	wt, err := e.findTable(sheet, name, true)
+	if err != nil {
+		return err
+	}
+	t := wt.table
+	text, err = formula.QualifyTableReferences(strings.TrimPrefix(text, "="), t.Name)
+	if err != nil {
+		return fmt.Errorf("invalid formula for column %s: %w", header, err)
+	}
+	if _, err := formula.Parse(text); err != nil {
+		return fmt.Errorf("invalid formula for column %s: %w", header, err)
+	}
+	l, err := newTableLayout(t)
+	if err != nil {
+		return err
+	}
+
+	index := columnIndex(t, header)
+	if index < 0 {
+		if header == "" {
+			return fmt.Errorf("calculated column of table %s needs a header", t.Name)
+		}
+		col := cellArea{l.area.col2 + 1, l.area.row1, l.area.col2 + 1, l.area.row2}
+		if col.col1 > formula.MaxColumns {
+			return fmt.Errorf("table %s ends at the last column", t.Name)
+		}
+		if err := e.checkTableSpace(ws, wt, col); err != nil {
+			return err
+		}
+		l.area.col2++
+		index = len(t.TableColumns.Items)
+		e.addTableColumn(t, header)
+		setTableArea(t, l)
+		if l.headerRow {
+			cell, err := e.getOrCreateCell(sheet, formula.CellName(l.area.col2, l.area.row1))
+			if err != nil {
+				return err
+			}
+			if err := e.writeCellValue(cell, header); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.TableColumns.Items[index].CalculatedColumnFormula = &xmlstructs.TableFormula{Text: text}
+	data := l.data()
+	data.col1 += index
+	data.col2 = data.col1
+	for _, row := range e.areaCells(ws, sheet, data, func(int, int) bool { return true }) {
+		row[0].T, row[0].V, row[0].IS = "", "", nil
+		row[0].F = &xmlstructs.Formula{Text: text}
+	}
+	e.calcDirty = true
+	return nil
+}
+
+// addTableColumn appends a column to the column definitions of a table,
+// numbering its header when another column has the same one, as Excel does.
+func (e *tableProcessor) addTableColumn(t *xmlstructs.Table, header string) string {
+	id := 0
+	for _, c := range t.TableColumns.Items {
+		id = max(id, c.ID)
+	}
+	name := header
+	for n := 2; columnIndex(t, name) >= 0; n++ {
+		name = header + strconv.Itoa(n)
+	}
+	t.TableColumns.Items = append(t.TableColumns.Items, xmlstructs.TableColumn{ID: id + 1, Name: name})
+	t.TableColumns.Count = len(t.TableColumns.Items)
+	return name
+}
+
+// fillCalculatedColumns writes the formulas of the calculated columns of a
+// table into the empty cells of rows.
+func (e *tableProcessor) fillCalculatedColumns(ws *xmlstructs.Worksheet, wt workbookTable, l tableLayout, rows cellArea) {
+	for i, c := range wt.table.TableColumns.Items {
+		if c.CalculatedColumnFormula == nil {
+			continue
+		}
+		col := cellArea{l.area.col1 + i, rows.row1, l.area.col1 + i, rows.row2}
+		for _, row := range e.areaCells(ws, wt.sheet, col, func(int, int) bool { return true }) {
+			if row[0].V == "" && row[0].F == nil && row[0].IS == nil {
+				row[0].F = &xmlstructs.Formula{Text: c.CalculatedColumnFormula.Text}
+			}
+		}
+		e.calcDirty = true
+	}
+}
+
+func (e *tableProcessor) resizeTable(sheet, name, ref string) error {
+	ws, err := (&rangeProcessor{e.state}).editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	wt, err := e.findTable(sheet, name, true)
+	if err != nil {
+		return err
+	}
+	t := wt.table
+	area, err := parseArea(ref)
+	if err != nil {
+		return err
+	}
+	l, err := newTableLayout(t)
+	if err != nil {
+		return err
+	}
+	if area.col1 != l.area.col1 || area.row1 != l.area.row1 {
+		return fmt.Errorf("table %s must keep its top-left cell %s", t.Name, formula.CellName(l.area.col1, l.area.row1))
+	}
+	resized := l
+	resized.area = area
+	if data := resized.data(); data.row2 < data.row1 {
+		return fmt.Errorf("table %s needs at least one data row", t.Name)
+	}
+	if err := e.checkTableOverlap(wt, area); err != nil {
+		return err
+	}
+
+	if l.totalsRow && area.row2 != l.area.row2 {
+		totals := cellArea{l.area.col1, l.area.row2, min(l.area.col2, area.col2), l.area.row2}
+		if _, err := (&rangeProcessor{e.state}).moveRange(sheet, totals, formula.CellName(area.col1, area.row2)); err != nil {
+			return err
+		}
+	}
+
+	if n := area.width(); n < len(t.TableColumns.Items) {
+		t.TableColumns.Items = t.TableColumns.Items[:n]
+		t.TableColumns.Count = n
+	}
+	for col := l.area.col2 + 1; col <= area.col2; col++ {
+		header := ""
+		if resized.headerRow {
+			header, _ = (&cellProcessor{e.state}).getCellValue(sheet, formula.CellName(col, area.row1))
+		}
+		if header == "" {
+			header = "Column" + strconv.Itoa(col-area.col1+1)
+		}
+		header = e.addTableColumn(t, header)
+		if resized.headerRow {
+			cell, err := e.getOrCreateCell(sheet, formula.CellName(col, area.row1))
+			if err != nil {
+				return err
+			}
+			if err := e.writeCellValue(cell, header); err != nil {
+				return err
+			}
+		}
+	}
+	setTableArea(t, resized)
+
+	if data := resized.data(); data.row2 > l.data().row2 {
+		data.row1 = l.data().row2 + 1
+		e.fillCalculatedColumns(ws, wt, resized, data)
+	}
+	e.calcDirty = true
+	return nil
+}
+
+func (e *tableProcessor) appendTableRows(sheet, name string, values [][]any) error {
+	ws, err := (&rangeProcessor{e.state}).editableSheet(sheet)
+	if err != nil {
+		return err
+	}
+	wt, err := e.findTable(sheet, name, true)
+	if err != nil {
+		return err
+	}
+	t := wt.table
+	if len(values) == 0 {
+		return nil
+	}
+	l, err := newTableLayout(t)
+	if err != nil {
+		return err
+	}
+	for i, row := range values {
+		if len(row) > l.area.width() {
+			return fmt.Errorf("%d values in row %d exceed the %d columns of table %s", len(row), i+1, l.area.width(), t.Name)
+		}
+	}
+	if l.area.row2+len(values) > formula.MaxRows {
+		return fmt.Errorf("%d rows appended to table %s would extend off the grid", len(values), t.Name)
+	}
+
+	added := cellArea{l.area.col1, l.area.row2 + 1, l.area.col2, l.area.row2 + len(values)}
+	if err := e.checkTableSpace(ws, wt, added); err != nil {
+		return err
+	}
+	if l.totalsRow {
+		totals := cellArea{l.area.col1, l.area.row2, l.area.col2, l.area.row2}
+		if _, err := (&rangeProcessor{e.state}).moveRange(sheet, totals, formula.CellName(l.area.col1, added.row2)); err != nil {
+			return err
+		}
+		added.row1--
+		added.row2--
+	}
+	if err := (&rangeProcessor{e.state}).setRangeValues(sheet, added, values); err != nil {
+		return err
+	}
+	l.area.row2 += len(values)
+	setTableArea(t, l)
+	e.fillCalculatedColumns(ws, wt, l, added)
+	return nil
+}
+
+// tableData returns the headers and data values of a table by row.
+func (e *tableProcessor) tableData(sheet, name string) ([]string, [][]string, error) {
+	wt, err := e.findTable(sheet, name, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := newTableLayout(wt.table)
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := (&rangeProcessor{e.state}).rangeValues(sheet, l.data())
+	if err != nil {
+		return nil, nil, err
+	}
+	headers := make([]string, len(wt.table.TableColumns.Items))
+	for i, c := range wt.table.TableColumns.Items {
+		headers[i] = c.Name
+	}
+	return headers, values, nil
+}
+
+func (e *tableProcessor) tableRecords(sheet, name string) ([]map[string]string, error) {
+	headers, values, err := e.tableData(sheet, name)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]map[string]string, len(values))
+	for i, row := range values {
+		records[i] = make(map[string]string, len(headers))
+		for j, header := range headers {
+			if j < len(row) {
+				records[i][header] = row[j]
+			}
+		}
+	}
+	return records, nil
+}
+
+func (e *tableProcessor) tableColumn(sheet, name, header string) ([]string, error) {
+	headers, values, err := e.tableData(sheet, name)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, h := range headers {
+		if strings.EqualFold(h, header) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("table %s has no column %s", name, header)
+	}
+	column := make([]string, len(values))
+	for i, row := range values {
+		if index < len(row) {
+			column[i] = row[index]
+		}
+	}
+	return column, nil
+}
@@ -0,0 +1,163 @@
+package excel
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func tableTestSheet(t *testing.T) (*Document, document.Sheet) {
+	t.Helper()
+	doc, sheet := rangeTestSheet(t)
+	sheet.Range("A1:C3").SetValues([][]any{
+		{"Item", "Qty", "Price"},
+		{"Apple", 3, 0.5},
+		{"Pear", 2, 1.25},
+	})
+	sheet.AddTable("A1:C3", "Sales")
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddTable failed: %v", err)
+	}
+	return doc, sheet
+}
+
+func TestTable_CalculatedColumnAndTotals(t *testing.T) {
+	doc, sheet := tableTestSheet(t)
+	defer doc.Close()
+
+	table := sheet.Table("sales").
+		SetCalculatedColumn("Total", "=[@Qty]*[@Price]").
+		SetTotalsRow(
+			document.TableTotal{Column: "Item", Label: "Total"},
+			document.TableTotal{Column: "Qty", Function: document.TotalsSum},
+			document.TableTotal{Column: "Total", Function: document.TotalsSum},
+		)
+	if err := table.Err(); err != nil {
+		t.Fatalf("building table failed: %v", err)
+	}
+	checkValues(t, sheet.Range("A1:D4"), [][]string{
+		{"Item", "Qty", "Price", "Total"},
+		{"Apple", "3", "0.5", "1.5"},
+		{"Pear", "2", "1.25", "2.5"},
+		{"Total", "5", "", "4"},
+	})
+	if cell, _ := doc.lookupCell("Data", "D2"); cell.F.Text != "Sales[[#This Row],[Qty]]*Sales[[#This Row],[Price]]" {
+		t.Errorf("calculated formula = %q", cell.F.Text)
+	}
+	if cell, _ := doc.lookupCell("Data", "B4"); cell.F.Text != "SUBTOTAL(109,Sales[Qty])" {
+		t.Errorf("totals formula = %q", cell.F.Text)
+	}
+
+	table.AppendRows([][]any{{"Plum", 4, 2}, {"Fig", 1}})
+	if err := table.Err(); err != nil {
+		t.Fatalf("AppendRows failed: %v", err)
+	}
+	checkValues(t, sheet.Range("A4:D6"), [][]string{
+		{"Plum", "4", "2", "8"},
+		{"Fig", "1", "", "0"},
+		{"Total", "10", "", "12"},
+	})
+	info, err := table.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Ref != "A1:D6" || !info.TotalsRow || !reflect.DeepEqual(info.Columns, []string{"Item", "Qty", "Price", "Total"}) {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if af := doc.tables["xl/tables/table1.xml"].AutoFilter; af == nil || af.Ref != "A1:D5" {
+		t.Errorf("table filter = %+v, want A1:D5", af)
+	}
+
+	table.SetTotalsRow()
+	checkValues(t, sheet.Range("A6:D6"), [][]string{{"", "", "", ""}})
+	if info, _ := table.Info(); info.Ref != "A1:D5" || info.TotalsRow {
+		t.Errorf("info after removing the totals row = %+v", info)
+	}
+}
+
+func TestTable_Errors(t *testing.T) {
+	doc, sheet := tableTestSheet(t)
+	defer doc.Close()
+
+	if err := sheet.Table("Missing").SetStyle(document.TableStyle{}).Err(); err == nil {
+		t.Error("expected an error for a missing table")
+	}
+	if err := sheet.Table("Sales").SetTotalsRow(document.TableTotal{Column: "Nope"}).Err(); err == nil {
+		t.Error("expected an error for a missing totals column")
+	}
+	sheet.Cell("B4").Set("in the way")
+	if err := sheet.Table("Sales").SetTotalsRow(document.TableTotal{Column: "Qty", Function: document.TotalsSum}).Err(); err == nil {
+		t.Error("expected an error for a totals row over values")
+	}
+	if err := sheet.Table("Sales").Resize("B1:C5").Err(); err == nil {
+		t.Error("expected an error for moving the top-left cell")
+	}
+	if err := sheet.Table("Sales").Resize("A1:C1").Err(); err == nil {
+		t.Error("expected an error for a table without data rows")
+	}
+	sheet.AddTable("E1:F3", "Other")
+	if err := sheet.Table("Sales").Resize("A1:E3").Err(); err == nil {
+		t.Error("expected an error for overlapping tables")
+	}
+	if err := sheet.Table("Sales").SetCalculatedColumn("Bad", "SUM(").Err(); err == nil {
+		t.Error("expected an error for an invalid formula")
+	}
+}
+
+func TestTable_ReadAndEditSaved(t *testing.T) {
+	ctx := t.Context()
+	doc, sheet := tableTestSheet(t)
+	defer doc.Close()
+	sheet.Table("Sales").SetStyle(document.TableStyle{Name: "TableStyleLight9", FirstColumn: true, ColumnStripes: true})
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	tables, err := reopened.Tables()
+	if err != nil {
+		t.Fatalf("Tables failed: %v", err)
+	}
+	want := []document.TableInfo{{
+		Name: "Sales", Sheet: "Data", Ref: "A1:C3", Columns: []string{"Item", "Qty", "Price"},
+		Style:     document.TableStyle{Name: "TableStyleLight9", FirstColumn: true, ColumnStripes: true},
+		HeaderRow: true,
+	}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("Tables = %+v, want %+v", tables, want)
+	}
+
+	s, _ := reopened.Sheet("Data")
+	records, err := s.Table("Sales").Records()
+	if err != nil {
+		t.Fatalf("Records failed: %v", err)
+	}
+	if len(records) != 2 || records[1]["Item"] != "Pear" || records[1]["Price"] != "1.25" {
+		t.Errorf("unexpected records %v", records)
+	}
+	if qty, _ := s.Table("Sales").Column("qty"); !reflect.DeepEqual(qty, []string{"3", "2"}) {
+		t.Errorf("Column(qty) = %v", qty)
+	}
+	if _, err := s.Table("Sales").Column("Missing"); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+
+	s.Cell("D1").Set("Notes")
+	s.Cell("A4").Formula("SUM(Sales[Qty])")
+	table := s.Table("Sales").Resize("A1:D3")
+	if info, err := table.Info(); err != nil || info.Ref != "A1:D3" || info.Columns[3] != "Notes" {
+		t.Errorf("Info after Resize = %+v, %v", info, err)
+	}
+	if got, _ := s.Cell("A4").Get(); got != "5" {
+		t.Errorf("SUM(Sales[Qty]) = %q, want 5", got)
+	}
+}
@@ -0,0 +1,667 @@
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // Decoders for sizing pictures placed by templates
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const emuPerPixel = 9525
+
+// templateMarkerPattern matches a template marker such as {{.Customer.Name}}.
+var templateMarkerPattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// RenderTemplate fills the markers in the text cells of every sheet of doc from data:
+//
+//   - {{.Customer.Name}} is replaced by a field, or a map value, of data. A cell
+//     holding only the marker takes the typed value, so numbers stay numbers and
+//     dates are written as dates; markers within text are written as text.
+//   - {{range .Items}} starts a block of rows that ends with the row holding
+//     {{end}}. The block is repeated for each element of the slice, with its
+//     styles, row heights and merged cells, and "." then refers to the element;
+//     $ refers to data itself. Rows below move down, and ranges that end on the
+//     last row of the block, such as a total's SUM(D5:D5), grow to cover the
+//     new rows. An empty slice removes the block.
+//   - {{if .Paid}} ... {{end}} keeps its rows when the value is set, that is
+//     not false, zero, nil or empty, and removes them otherwise. {{if not .Paid}}
+//     inverts the test.
+//   - {{image .Logo}} places a picture, given as PNG, JPEG, GIF or BMP bytes or
+//     the path of an image file, at the cell. It fills the cell's merged range,
+//     or keeps the image's own size in a single cell.
+//
+// Block markers may share their rows with other cells and markers; they are
+// removed from the cells holding them.
+func RenderTemplate(doc document.Spreadsheet, data any) error {
+	d, ok := doc.(*Document)
+	if !ok {
+		return fmt.Errorf("document is not an Excel workbook")
+	}
+	t := &templateRenderer{state: d.state, root: reflect.ValueOf(data)}
+	if err := (&sheetProcessor{d.state}).unshareFormulas(); err != nil {
+		return err
+	}
+	for _, sh := range slices.Clone(d.workbook.Sheets) {
+		if _, streamed := d.streams[sh.Name]; streamed {
+			continue
+		}
+		ws, ok := d.worksheet(sh.Name)
+		if !ok {
+			if d.hasSheet(sh.Name) {
+				return fmt.Errorf("load sheet %s", sh.Name)
+			}
+			continue
+		}
+		last := 0
+		for _, row := range ws.SheetData.Rows {
+			last = max(last, row.R)
+		}
+		t.gaps = nil
+		if _, err := t.renderRows(sh.Name, 1, last, t.root); err != nil {
+			return fmt.Errorf("sheet %s: %w", sh.Name, err)
+		}
+		if len(t.gaps) > 0 {
+			if err := (&sheetProcessor{d.state}).shiftCells(gapShift(sh.Name, t.gaps)); err != nil {
+				return fmt.Errorf("sheet %s: %w", sh.Name, err)
+			}
+		}
+		delete(d.cellCache, sh.Name)
+	}
+	d.calcDirty = true
+	return nil
+}
+
+// templateRenderer fills the markers of a workbook from Go data.
+type templateRenderer struct {
+	*state
+	root reflect.Value
+	gaps []gap // Rows of removed blocks, deleted once the sheet is rendered
+}
+
+// templateMarker is a block marker: range, if or end.
+type templateMarker struct {
+	col, row int
+	index    int // Position among the markers of its cell
+	action   string
+	arg      string
+}
+
+// parseMarker splits the text between the braces of a marker into its action,
+// which is empty for a value, and its argument.
+func parseMarker(text string) (action, arg string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	switch fields[0] {
+	case "range", "if", "end", "image":
+		return fields[0], strings.Join(fields[1:], " ")
+	}
+	return "", text
+}
+
+// cellText returns the text of a cell holding a string, or "" for other cells.
+func (t *templateRenderer) cellText(cell *xmlstructs.Cell) string {
+	switch cell.T {
+	case "s", "str", "inlineStr":
+		return t.resolveValue(*cell)
+	}
+	return ""
+}
+
+// templateRow returns a row of a sheet, or nil when it has no cells or settings.
+func templateRow(ws *xmlstructs.Worksheet, r int) *xmlstructs.Row {
+	rows := ws.SheetData.Rows
+	i := sort.Search(len(rows), func(i int) bool { return rows[i].R >= r })
+	if i < len(rows) && rows[i].R == r {
+		return &rows[i]
+	}
+	return nil
+}
+
+// blockMarkers lists the range, if and end markers of a row in column order.
+func (t *templateRenderer) blockMarkers(ws *xmlstructs.Worksheet, r int) []templateMarker {
+	row := templateRow(ws, r)
+	if row == nil {
+		return nil
+	}
+	var markers []templateMarker
+	for j := range row.Cells {
+		text := t.cellText(&row.Cells[j])
+		if !strings.Contains(text, "{{") {
+			continue
+		}
+		col, _ := axisPosition(row.Cells[j].R)
+		for i, m := range templateMarkerPattern.FindAllStringSubmatch(text, -1) {
+			if action, arg := parseMarker(m[1]); action == "range" || action == "if" || action == "end" {
+				markers = append(markers, templateMarker{col: col, row: r, index: i, action: action, arg: arg})
+			}
+		}
+	}
+	return markers
+}
+
+// renderRows fills rows first to last of a sheet with dot and returns the row
+// that last has become once the blocks among them were expanded. The rows of
+// removed blocks are recorded in gaps and stay until the sheet is rendered.
+func (t *templateRenderer) renderRows(sheet string, first, last int, dot reflect.Value) (int, error) {
+	ws, _ := t.worksheet(sheet)
+	for r := first; r <= last; {
+		markers := t.blockMarkers(ws, r)
+		if len(markers) == 0 {
+			if err := t.fillRow(sheet, r, dot); err != nil {
+				return 0, err
+			}
+			r++
+			continue
+		}
+		open := markers[0]
+		if open.action == "end" {
+			return 0, fmt.Errorf("%s: {{end}} without {{range}} or {{if}}", formula.CellName(open.col, open.row))
+		}
+		end, err := t.blockEnd(ws, open, last)
+		if err != nil {
+			return 0, err
+		}
+		// The end marker comes after the open marker when they share a cell.
+		if err := t.stripMarker(sheet, end); err != nil {
+			return 0, err
+		}
+		if err := t.stripMarker(sheet, open); err != nil {
+			return 0, err
+		}
+
+		height := end.row - r + 1
+		var items []reflect.Value
+		switch open.action {
+		case "if":
+			negate := false
+			arg := open.arg
+			if rest, ok := strings.CutPrefix(arg, "not "); ok {
+				negate, arg = true, strings.TrimSpace(rest)
+			}
+			v, err := t.lookup(arg, dot)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", formula.CellName(open.col, open.row), err)
+			}
+			if truthy(v) != negate {
+				items = []reflect.Value{dot}
+			}
+		case "range":
+			v, err := t.lookup(open.arg, dot)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", formula.CellName(open.col, open.row), err)
+			}
+			if items, err = rangeItems(v); err != nil {
+				return 0, fmt.Errorf("%s: %w", formula.CellName(open.col, open.row), err)
+			}
+		}
+
+		if len(items) == 0 {
+			// Deleting rows rewrites the whole workbook, so the rows of every
+			// removed block are deleted together at the end. Rendering goes
+			// down the sheet and only inserts rows below r, so they stay put.
+			t.gaps = append(t.gaps, gap{at: r, count: height})
+			r += height
+			continue
+		}
+		if len(items) > 1 {
+			if err := t.repeatRows(sheet, r, end.row, len(items)-1); err != nil {
+				return 0, err
+			}
+		}
+		start := r
+		for _, item := range items {
+			blockLast, err := t.renderRows(sheet, start, start+height-1, item)
+			if err != nil {
+				return 0, err
+			}
+			start = blockLast + 1
+		}
+		last += start - 1 - end.row
+		r = start
+	}
+	return last, nil
+}
+
+// blockEnd finds the end marker that closes open, at or before row last.
+func (t *templateRenderer) blockEnd(ws *xmlstructs.Worksheet, open templateMarker, last int) (templateMarker, error) {
+	depth := 0
+	for r := open.row; r <= last; r++ {
+		for _, m := range t.blockMarkers(ws, r) {
+			if r == open.row && (m.col < open.col || m.col == open.col && m.index <= open.index) {
+				continue
+			}
+			switch {
+			case m.action != "end":
+				depth++
+			case depth > 0:
+				depth--
+			default:
+				return m, nil
+			}
+		}
+	}
+	return templateMarker{}, fmt.Errorf("%s: {{%s}} without {{end}}", formula.CellName(open.col, open.row), open.action)
+}
+
+// stripMarker removes a marker from the text of its cell. A cell left without
+// text keeps its style but no value.
+func (t *templateRenderer) stripMarker(sheet string, m templateMarker) error {
+	cell, err := t.lookupCell(sheet, formula.CellName(m.col, m.row))
+	if err != nil || cell == nil {
+		return err
+	}
+	text := t.cellText(cell)
+	loc := templateMarkerPattern.FindAllStringIndex(text, -1)[m.index]
+	return t.setText(cell, text[:loc[0]]+text[loc[1]:])
+}
+
+// setText writes text to a cell, or clears its value when text is blank.
+func (t *templateRenderer) setText(cell *xmlstructs.Cell, text string) error {
+	if strings.TrimSpace(text) == "" {
+		cell.T, cell.V, cell.IS, cell.F = "", "", nil, nil
+		return nil
+	}
+	return t.writeCellValue(cell, text)
+}
+
+// fillRow replaces the value and image markers in the cells of a row.
+func (t *templateRenderer) fillRow(sheet string, r int, dot reflect.Value) error {
+	ws, _ := t.worksheet(sheet)
+	row := templateRow(ws, r)
+	if row == nil {
+		return nil
+	}
+	for j := range row.Cells {
+		cell := &row.Cells[j]
+		text := t.cellText(cell)
+		if !strings.Contains(text, "{{") {
+			continue
+		}
+		if err := t.fillCell(sheet, cell, text, dot); err != nil {
+			return fmt.Errorf("%s: %w", cell.R, err)
+		}
+	}
+	return nil
+}
+
+func (t *templateRenderer) fillCell(sheet string, cell *xmlstructs.Cell, text string, dot reflect.Value) error {
+	matches := templateMarkerPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 1 && strings.TrimSpace(text) == text[matches[0][0]:matches[0][1]] {
+		action, arg := parseMarker(text[matches[0][2]:matches[0][3]])
+		v, err := t.lookup(arg, dot)
+		if err != nil {
+			return err
+		}
+		switch action {
+		case "image":
+			if err := t.placeImage(sheet, cell.R, v); err != nil {
+				return err
+			}
+			return t.setText(cell, "")
+		case "":
+			return t.setValue(cell, v)
+		}
+		return fmt.Errorf("unexpected {{%s}}", action)
+	}
+
+	var sb strings.Builder
+	prev := 0
+	for _, m := range matches {
+		sb.WriteString(text[prev:m[0]])
+		prev = m[1]
+		action, arg := parseMarker(text[m[2]:m[3]])
+		if action != "" {
+			return fmt.Errorf("unexpected {{%s}} within text", action)
+		}
+		v, err := t.lookup(arg, dot)
+		if err != nil {
+			return err
+		}
+		s, err := t.text(v)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(s)
+	}
+	sb.WriteString(text[prev:])
+	return t.setText(cell, sb.String())
+}
+
+// lookup evaluates a marker argument: "." for dot, ".Field.Key" for fields of
+// structs and keys of maps below dot, and "$" or "$.Field" to start from the
+// data passed to RenderTemplate. A nil pointer along the way yields no value.
+func (t *templateRenderer) lookup(expr string, dot reflect.Value) (reflect.Value, error) {
+	v := dot
+	switch {
+	case expr == "." || expr == "$":
+		if expr == "$" {
+			return t.root, nil
+		}
+		return dot, nil
+	case strings.HasPrefix(expr, "$."):
+		v, expr = t.root, expr[1:]
+	case !strings.HasPrefix(expr, "."):
+		return reflect.Value{}, fmt.Errorf("invalid template expression %q", expr)
+	}
+	for name := range strings.SplitSeq(expr[1:], ".") {
+		if v = indirect(v); !v.IsValid() {
+			return v, nil
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok || !f.IsExported() {
+				return reflect.Value{}, fmt.Errorf("no field %s in %s", name, v.Type())
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, fmt.Errorf("cannot read key %s of %s", name, v.Type())
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}, fmt.Errorf("cannot read %s of %s", name, v.Type())
+		}
+	}
+	return v, nil
+}
+
+// indirect follows pointers and interfaces, returning the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// truthy reports whether a value is set: not false, zero, nil or empty.
+func truthy(v reflect.Value) bool {
+	if v = indirect(v); !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() > 0
+	}
+	return !v.IsZero()
+}
+
+func rangeItems(v reflect.Value) ([]reflect.Value, error) {
+	if v = indirect(v); !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot range over %s", v.Type())
+	}
+	items := make([]reflect.Value, v.Len())
+	for i := range items {
+		items[i] = v.Index(i)
+	}
+	return items, nil
+}
+
+// scalar converts a value to one accepted by writeCellValue, or nil for none.
+func (t *templateRenderer) scalar(v reflect.Value) (any, error) {
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	return cellValue(v, t.date1904())
+}
+
+// setValue writes a typed value to a cell. Dates in cells without a number
+// format are formatted as dates.
+func (t *templateRenderer) setValue(cell *xmlstructs.Cell, v reflect.Value) error {
+	value, err := t.scalar(v)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return t.setText(cell, "")
+	}
+	cell.IS = nil
+	if err := t.writeCellValue(cell, value); err != nil {
+		return err
+	}
+	if iv := indirect(v); iv.IsValid() && iv.Type() == timeType && t.numberFormatCode(cell.S) == "General" {
+		sp := &styleProcessor{t.state}
+		style := sp.styleFromXf(cell.S)
+		style.NumberFormat = defaultDateFormat
+		cell.S = sp.getStyleID(style)
+	}
+	return nil
+}
+
+// text formats a value written within other text.
+func (t *templateRenderer) text(v reflect.Value) (string, error) {
+	if iv := indirect(v); iv.IsValid() && iv.Type() == timeType {
+		return iv.Interface().(time.Time).Format(time.DateOnly), nil
+	}
+	value, err := t.scalar(v)
+	switch x := value.(type) {
+	case nil:
+		return "", err
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// placeImage places the picture given by an {{image}} marker at a cell.
+func (t *templateRenderer) placeImage(sheet, axis string, v reflect.Value) error {
+	var data []byte
+	switch v = indirect(v); {
+	case !v.IsValid():
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		data = v.Bytes()
+	case v.Kind() == reflect.String:
+		var err error
+		if data, err = os.ReadFile(v.String()); err != nil {
+			return fmt.Errorf("read image file: %w", err)
+		}
+	default:
+		return fmt.Errorf("cannot place %s as an image", v.Type())
+	}
+	ext, err := imageExtension(data)
+	if err != nil {
+		return err
+	}
+
+	col, row := axisPosition(axis)
+	area := cellArea{col, row, col, row}
+	ws, _ := t.worksheet(sheet)
+	if ws.MergeCells != nil {
+		for _, mc := range ws.MergeCells.Items {
+			if a, err := parseArea(mc.Ref); err == nil && a.covers(area) {
+				area = a
+				break
+			}
+		}
+	}
+	from := xmlstructs.Marker{Col: area.col1 - 1, Row: area.row1 - 1}
+	return (&mediaProcessor{t.state}).addPicture(sheet, data, ext, func(pic *xmlstructs.Pic) xmlstructs.Anchor {
+		if area.width() > 1 || area.height() > 1 {
+			return xmlstructs.Anchor{TwoCellAnchor: &xmlstructs.TwoCellAnchor{
+				EditAs: "oneCell",
+				From:   from,
+				To:     xmlstructs.Marker{Col: area.col2, Row: area.row2},
+				Pic:    pic,
+			}}
+		}
+		size := xmlstructs.Extent{Cx: 600000, Cy: 600000}
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			size = xmlstructs.Extent{Cx: int64(cfg.Width) * emuPerPixel, Cy: int64(cfg.Height) * emuPerPixel}
+		}
+		return xmlstructs.Anchor{OneCellAnchor: &xmlstructs.OneCellAnchor{From: from, Ext: size, Pic: pic}}
+	})
+}
+
+// repeatRows inserts copies of rows first to last of a sheet below them, with
+// their cells, styles, row heights and merged cells.
+func (t *templateRenderer) repeatRows(sheet string, first, last, copies int) error {
+	height := last - first + 1
+	count := copies * height
+	sp := &sheetProcessor{t.state}
+	if err := sp.insertRows(sheet, last+1, count); err != nil {
+		return err
+	}
+	ws, _ := t.worksheet(sheet)
+	t.extendRanges(sheet, first, last, count)
+
+	// The block is copied after the insertion, which has already moved its
+	// references to the rows below it.
+	type rowCells struct {
+		r     int
+		ht    float64
+		cells []xmlstructs.Cell
+	}
+	var block []rowCells
+	col1, col2 := formula.MaxColumns, 0
+	for _, row := range ws.SheetData.Rows {
+		if row.R < first || row.R > last {
+			continue
+		}
+		block = append(block, rowCells{r: row.R, ht: row.Ht * float64(row.CustomHeight), cells: slices.Clone(row.Cells)})
+		for _, c := range row.Cells {
+			col, _ := axisPosition(c.R)
+			col1, col2 = min(col1, col), max(col2, col)
+		}
+	}
+	if col2 > 0 {
+		dst := cellArea{col1, last + 1, col2, last + count}
+		cells := newCellGrid(dst)
+		for _, row := range block {
+			for _, c := range row.cells {
+				col, _ := axisPosition(c.R)
+				for i := 1; i <= copies; i++ {
+					r := row.r + i*height
+					cells[r-dst.row1][col-col1] = placeCell(&c, col, r, 0, i*height)
+				}
+			}
+		}
+		t.pasteCells(ws, sheet, dst, cells)
+	}
+	for _, row := range block {
+		if row.ht > 0 {
+			for i := 1; i <= copies; i++ {
+				if err := sp.setRowHeight(sheet, row.r+i*height, row.ht); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	delete(t.cellCache, sheet)
+
+	if mc := ws.MergeCells; mc != nil {
+		for _, m := range slices.Clone(mc.Items) {
+			a, err := parseArea(m.Ref)
+			if err != nil || a.row1 < first || a.row2 > last {
+				continue
+			}
+			for i := 1; i <= copies; i++ {
+				moved := cellArea{a.col1, a.row1 + i*height, a.col2, a.row2 + i*height}
+				mc.Items = append(mc.Items, xmlstructs.MergeCell{Ref: moved.String()})
+			}
+		}
+		mc.Count = len(mc.Items)
+	}
+	return nil
+}
+
+// extendRanges grows the ranges of a sheet that end on row last, and start at
+// or above row first, by count rows, so that totals and formats below a block
+// cover its copies. Formulas within the block and its copies are left alone.
+func (t *templateRenderer) extendRanges(sheet string, first, last, count int) {
+	grow := func(home string) func(formula.Reference) formula.Reference {
+		return func(r formula.Reference) formula.Reference {
+			name := r.Sheet
+			if name == "" {
+				name = home
+			}
+			if r.IsRange && !r.Invalid && r.Row1 != 0 && r.Row1 <= first && r.Row2 == last && strings.EqualFold(name, sheet) {
+				r.Row2 += count
+			}
+			return r
+		}
+	}
+
+	for _, sh := range t.workbook.Sheets {
+		if _, streamed := t.streams[sh.Name]; streamed {
+			continue
+		}
+		ws, ok := t.worksheet(sh.Name)
+		if !ok {
+			continue
+		}
+		inSheet := strings.EqualFold(sh.Name, sheet)
+		for i := range ws.SheetData.Rows {
+			row := &ws.SheetData.Rows[i]
+			if inSheet && row.R >= first && row.R <= last+count {
+				continue
+			}
+			for j := range row.Cells {
+				if f := row.Cells[j].F; f != nil {
+					f.Text = rewriteFormula(f.Text, grow(sh.Name))
+				}
+			}
+		}
+		if !inSheet {
+			continue
+		}
+		sqref := func(ref string) string {
+			fields := strings.Fields(ref)
+			for k, f := range fields {
+				fields[k] = rewriteFormula(f, grow(sh.Name))
+			}
+			return strings.Join(fields, " ")
+		}
+		for k := range ws.ConditionalFormatting {
+			ws.ConditionalFormatting[k].Sqref = sqref(ws.ConditionalFormatting[k].Sqref)
+		}
+		if dvs := ws.DataValidations; dvs != nil {
+			for k := range dvs.Items {
+				dvs.Items[k].Sqref = sqref(dvs.Items[k].Sqref)
+			}
+		}
+		// Merges within the block are copied with it; those reaching into it from above grow.
+		if mc := ws.MergeCells; mc != nil {
+			for k, m := range mc.Items {
+				if a, err := parseArea(m.Ref); err == nil && a.row1 < first && a.row2 == last {
+					a.row2 += count
+					mc.Items[k].Ref = a.String()
+				}
+			}
+		}
+	}
+	if t.workbook.DefinedNames != nil {
+		for i := range t.workbook.DefinedNames.Items {
+			dn := &t.workbook.DefinedNames.Items[i]
+			dn.Ref = rewriteFormula(dn.Ref, grow(""))
+		}
+	}
+}
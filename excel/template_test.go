@@ -0,0 +1,239 @@
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+type templateItem struct {
+	Name  string
+	Qty   int
+	Price float64
+}
+
+type templateInvoice struct {
+	Number   int
+	Customer *struct{ Name string }
+	Date     time.Time
+	Items    []templateItem
+	Paid     bool
+	Notes    []string
+	Extra    map[string]any
+}
+
+func invoiceTemplate(t *testing.T) (*Document, document.Sheet) {
+	t.Helper()
+	doc, sheet := rangeTestSheet(t)
+	sheet.Range("A1:F10").SetValues([][]any{
+		{"Invoice {{ .Number }} for {{.Customer.Name}}"},
+		{"{{.Date}}", "{{.Extra.Ref}}"},
+		{},
+		{"Item", "Qty", "Price", "Total"},
+		{"{{range .Items}}{{.Name}}", "{{.Qty}}", "{{.Price}}", nil, "{{$.Customer.Name}}{{end}}"},
+		{"Total"},
+		{"{{if .Paid}}Paid in full{{end}}"},
+		{"{{if not .Paid}}Payment due{{end}}"},
+		{"{{range .Notes}}{{.}}{{end}}"},
+		{"Thanks"},
+	})
+	sheet.Cell("B5").Style(document.CellStyle{Bold: true})
+	sheet.Cell("D5").Formula("B5*C5")
+	sheet.Cell("D6").Formula("SUM(D5:D5)")
+	sheet.MergeCells("E5:F5").SetRowHeight(5, 20)
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("building template failed: %v", err)
+	}
+	return doc, sheet
+}
+
+func TestRenderTemplate_Invoice(t *testing.T) {
+	doc, sheet := invoiceTemplate(t)
+	defer doc.Close()
+
+	data := templateInvoice{
+		Number:   42,
+		Customer: &struct{ Name string }{"Acme"},
+		Date:     time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC),
+		Items:    []templateItem{{"Apple", 3, 0.5}, {"Pear", 2, 1.25}, {"Plum", 4, 2}},
+		Paid:     true,
+		Extra:    map[string]any{"Ref": "PO-7"},
+	}
+	if err := RenderTemplate(doc, data); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	checkValues(t, sheet.Range("A1:E11"), [][]string{
+		{"Invoice 42 for Acme", "", "", "", ""},
+		{"46095", "PO-7", "", "", ""},
+		{"", "", "", "", ""},
+		{"Item", "Qty", "Price", "Total", ""},
+		{"Apple", "3", "0.5", "1.5", "Acme"},
+		{"Pear", "2", "1.25", "2.5", "Acme"},
+		{"Plum", "4", "2", "8", "Acme"},
+		{"Total", "", "", "12", ""},
+		{"Paid in full", "", "", "", ""},
+		{"Thanks", "", "", "", ""},
+		{"", "", "", "", ""},
+	})
+
+	if cell, _ := doc.lookupCell("Data", "D8"); cell.F.Text != "SUM(D5:D7)" {
+		t.Errorf("total formula = %q, want SUM(D5:D7)", cell.F.Text)
+	}
+	if cell, _ := doc.lookupCell("Data", "D7"); cell.F.Text != "B7*C7" {
+		t.Errorf("item formula = %q, want B7*C7", cell.F.Text)
+	}
+	if date, _ := sheet.Cell("A2").Formatted(); date != "2026-03-14" {
+		t.Errorf("formatted date = %q, want 2026-03-14", date)
+	}
+	if cell, _ := doc.lookupCell("Data", "B2"); cell.T != "s" {
+		t.Errorf("B2 type = %q, want a string", cell.T)
+	}
+	first, _ := doc.lookupCell("Data", "B5")
+	for _, ref := range []string{"B6", "B7"} {
+		if cell, _ := doc.lookupCell("Data", ref); cell.S != first.S || cell.T != "n" {
+			t.Errorf("%s = style %d type %q, want style %d and a number", ref, cell.S, cell.T, first.S)
+		}
+	}
+	if h, _ := sheet.RowHeight(7); h != 20 {
+		t.Errorf("RowHeight(7) = %v, want 20", h)
+	}
+	merged, _ := sheet.MergedCells()
+	if !reflect.DeepEqual(merged, []string{"E5:F5", "E6:F6", "E7:F7"}) {
+		t.Errorf("MergedCells = %v", merged)
+	}
+}
+
+func TestRenderTemplate_EmptyAndUnpaid(t *testing.T) {
+	doc, sheet := invoiceTemplate(t)
+	defer doc.Close()
+
+	data := map[string]any{
+		"Number":   "7",
+		"Customer": map[string]string{"Name": "Bolt"},
+		"Date":     nil,
+		"Extra":    nil,
+		"Items":    []templateItem{},
+		"Paid":     false,
+		"Notes":    []string{"Net 30", "Thank you"},
+	}
+	if err := RenderTemplate(doc, data); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	checkValues(t, sheet.Range("A1:B9"), [][]string{
+		{"Invoice 7 for Bolt", ""},
+		{"", ""},
+		{"", ""},
+		{"Item", "Qty"},
+		{"Total", ""},
+		{"Payment due", ""},
+		{"Net 30", ""},
+		{"Thank you", ""},
+		{"Thanks", ""},
+	})
+	if merged, _ := sheet.MergedCells(); len(merged) != 0 {
+		t.Errorf("MergedCells = %v, want none", merged)
+	}
+}
+
+func TestRenderTemplate_ManyRemovedBlocks(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	sheet.Range("A1:B3").SetValues([][]any{
+		{"{{range .}}{{.Name}}", "{{.Qty}}"},
+		{"{{if .Paid}}{{.Name}} paid{{end}}{{end}}"},
+		{"Done"},
+	})
+	sheet.Cell("B3").Formula("SUM(B1:B2)")
+	const n = 3000
+	items := make([]map[string]any, n)
+	for i := range items {
+		items[i] = map[string]any{"Name": fmt.Sprintf("Item %d", i+1), "Qty": 1, "Paid": i%10 == 9}
+	}
+	if err := RenderTemplate(doc, items); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	// Each item keeps its first row, and its second when paid.
+	rows := n + n/10
+	checkValues(t, sheet.Range("A1:A11"), [][]string{
+		{"Item 1"}, {"Item 2"}, {"Item 3"}, {"Item 4"}, {"Item 5"}, {"Item 6"},
+		{"Item 7"}, {"Item 8"}, {"Item 9"}, {"Item 10"}, {"Item 10 paid"},
+	})
+	last := fmt.Sprintf("A%d:A%d", rows, rows+1)
+	checkValues(t, sheet.Range(last), [][]string{{"Item 3000 paid"}, {"Done"}})
+	total, _ := doc.lookupCell("Data", fmt.Sprintf("B%d", rows+1))
+	if want := fmt.Sprintf("SUM(B1:B%d)", rows); total == nil || total.F == nil || total.F.Text != want {
+		t.Errorf("total = %+v, want %s", total, want)
+	}
+}
+
+func TestRenderTemplate_Image(t *testing.T) {
+	doc, sheet := rangeTestSheet(t)
+	defer doc.Close()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.Black)
+	var pic bytes.Buffer
+	if err := png.Encode(&pic, img); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	sheet.Cell("A1").Set("{{image .Logo}}")
+	sheet.Cell("C3").Set("{{ image .Logo }}")
+	sheet.MergeCells("C3:E6")
+	if err := RenderTemplate(doc, map[string][]byte{"Logo": pic.Bytes()}); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	images, err := doc.Images()
+	if err != nil || len(images) != 2 {
+		t.Fatalf("Images = %d, %v, want 2", len(images), err)
+	}
+	if images[0].Cell != "A1" || images[0].Width != 3 || images[0].Height != 1.5 || !bytes.Equal(images[0].Data, pic.Bytes()) {
+		t.Errorf("unexpected image %+v", images[0])
+	}
+	anchor := doc.drawings["xl/drawings/drawing1.xml"].Anchors[1].TwoCellAnchor
+	if images[1].Cell != "C3" || anchor == nil || anchor.To.Col != 5 || anchor.To.Row != 6 {
+		t.Errorf("merged image %+v anchored %+v, want C3 to the end of E6", images[1], anchor)
+	}
+	if v, _ := sheet.Cell("A1").Get(); v != "" {
+		t.Errorf("A1 = %q after placing the image, want empty", v)
+	}
+}
+
+func TestRenderTemplate_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells map[string]string
+		data  any
+		want  string
+	}{
+		{"unclosed", map[string]string{"A1": "{{range .Items}}"}, templateInvoice{}, "without {{end}}"},
+		{"stray end", map[string]string{"A1": "{{end}}"}, templateInvoice{}, "without {{range}}"},
+		{"unknown field", map[string]string{"B2": "{{.Missing}}"}, templateInvoice{}, "B2: no field Missing"},
+		{"range over scalar", map[string]string{"A1": "{{range .Number}}{{end}}"}, templateInvoice{}, "cannot range"},
+		{"bad expression", map[string]string{"A1": "{{Number}}"}, templateInvoice{}, "invalid template expression"},
+		{"bad image", map[string]string{"A1": "{{image .Number}}"}, templateInvoice{}, "cannot place int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, sheet := rangeTestSheet(t)
+			defer doc.Close()
+			for ref, text := range tt.cells {
+				sheet.Cell(ref).Set(text)
+			}
+			err := RenderTemplate(doc, tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("RenderTemplate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
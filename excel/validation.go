@@ -0,0 +1,148 @@
+package excel
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// Limits Excel enforces on data validation text.
+const (
+	maxValidationTitle = 32
+	maxValidationText  = 255
+)
+
+func (e *sheetProcessor) setDataValidation(sheet, ref string, v document.DataValidation) error {
+	ws, ok := e.worksheet(sheet)
+	if !ok {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, sheet)
+	}
+	fields := strings.Fields(ref)
+	if len(fields) == 0 {
+		return fmt.Errorf("invalid data validation range %q", ref)
+	}
+	for _, f := range fields {
+		if area, err := formula.ParseReference(f); err != nil || area.Invalid || area.Sheet != "" {
+			return fmt.Errorf("invalid data validation range %q", ref)
+		}
+	}
+
+	dv, err := dataValidation(v)
+	if err != nil {
+		return err
+	}
+	dv.Sqref = strings.Join(fields, " ")
+
+	if ws.DataValidations == nil {
+		ws.DataValidations = &xmlstructs.DataValidations{Items: make([]xmlstructs.DataValidation, 0)}
+	}
+	ws.DataValidations.Items = append(ws.DataValidations.Items, dv)
+	ws.DataValidations.Count = len(ws.DataValidations.Items)
+	return nil
+}
+
+func dataValidation(v document.DataValidation) (xmlstructs.DataValidation, error) {
+	dv := xmlstructs.DataValidation{
+		Type:         string(v.Type),
+		AllowBlank:   boolToInt(v.AllowBlank),
+		ShowDropDown: boolToInt(v.HideDropDown),
+		ShowInputMsg: 1,
+		ShowErrorMsg: boolToInt(!v.HideErrorAlert),
+		ErrorTitle:   v.ErrorTitle,
+		Error:        v.ErrorMessage,
+		PromptTitle:  v.PromptTitle,
+		Prompt:       v.Prompt,
+	}
+
+	switch v.Type {
+	case document.ValidationAny:
+	case document.ValidationList:
+		switch {
+		case len(v.Options) > 0 && v.Source != "":
+			return dv, fmt.Errorf("list validation takes either options or a source, not both")
+		case v.Source != "":
+			dv.Formula1 = strings.TrimPrefix(v.Source, "=")
+		case len(v.Options) > 0:
+			list, err := listFormula(v.Options)
+			if err != nil {
+				return dv, err
+			}
+			dv.Formula1 = list
+		default:
+			return dv, fmt.Errorf("list validation requires options or a source")
+		}
+	case document.ValidationWhole, document.ValidationDecimal, document.ValidationDate,
+		document.ValidationTime, document.ValidationTextLength:
+		op := v.Operator
+		if op == "" {
+			op = document.OperatorBetween
+		}
+		if v.Formula1 == "" {
+			return dv, fmt.Errorf("%s validation requires a value", v.Type)
+		}
+		dv.Formula1 = strings.TrimPrefix(v.Formula1, "=")
+		switch op {
+		case document.OperatorBetween, document.OperatorNotBetween:
+			if v.Formula2 == "" {
+				return dv, fmt.Errorf("%s validation with operator %s requires a maximum", v.Type, op)
+			}
+			dv.Formula2 = strings.TrimPrefix(v.Formula2, "=")
+		case document.OperatorEqual, document.OperatorNotEqual, document.OperatorGreaterThan,
+			document.OperatorLessThan, document.OperatorGreaterThanOrEqual, document.OperatorLessThanOrEqual:
+		default:
+			return dv, fmt.Errorf("unknown validation operator %q", op)
+		}
+		if op != document.OperatorBetween {
+			dv.Operator = string(op)
+		}
+	case document.ValidationCustom:
+		if v.Formula1 == "" {
+			return dv, fmt.Errorf("custom validation requires a formula")
+		}
+		dv.Formula1 = strings.TrimPrefix(v.Formula1, "=")
+	default:
+		return dv, fmt.Errorf("unknown validation type %q", v.Type)
+	}
+
+	switch v.ErrorStyle {
+	case "", document.ErrorStop:
+	case document.ErrorWarning, document.ErrorInfo:
+		dv.ErrorStyle = string(v.ErrorStyle)
+	default:
+		return dv, fmt.Errorf("unknown error style %q", v.ErrorStyle)
+	}
+
+	for _, t := range []struct {
+		text  string
+		limit int
+	}{
+		{v.PromptTitle, maxValidationTitle},
+		{v.Prompt, maxValidationText},
+		{v.ErrorTitle, maxValidationTitle},
+		{v.ErrorMessage, maxValidationText},
+	} {
+		if utf8.RuneCountInString(t.text) > t.limit {
+			return dv, fmt.Errorf("validation text %q exceeds %d characters", t.text, t.limit)
+		}
+	}
+	return dv, nil
+}
+
+// listFormula quotes the items of an explicit list. Excel separates the items
+// with commas and limits the list to 255 characters.
+func listFormula(options []string) (string, error) {
+	for _, o := range options {
+		if strings.Contains(o, ",") {
+			return "", fmt.Errorf("list item %q contains a comma; use a source range instead", o)
+		}
+	}
+	list := strings.Join(options, ",")
+	if utf8.RuneCountInString(list) > maxValidationText {
+		return "", fmt.Errorf("list items exceed %d characters; use a source range instead", maxValidationText)
+	}
+	return `"` + strings.ReplaceAll(list, `"`, `""`) + `"`, nil
+}
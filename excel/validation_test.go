@@ -0,0 +1,105 @@
+package excel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func TestAddDataValidation(t *testing.T) {
+	ctx := t.Context()
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+
+	sheet, _ := doc.Sheet("Input")
+	sheet.SetDataValidation("A2:A10", "Yes", "No")
+	sheet.AddDataValidation("B2:B10", document.DataValidation{
+		Type:         document.ValidationWhole,
+		Formula1:     "1",
+		Formula2:     "=$Z$1",
+		PromptTitle:  "Quantity",
+		Prompt:       "Enter 1 to the maximum in Z1",
+		ErrorStyle:   document.ErrorWarning,
+		ErrorTitle:   "Out of range",
+		ErrorMessage: "The quantity is outside the allowed range.",
+	})
+	sheet.AddDataValidation("C2:C10 E2:E10", document.DataValidation{
+		Type:     document.ValidationDate,
+		Operator: document.OperatorGreaterThanOrEqual,
+		Formula1: "DATE(2024,1,1)",
+	})
+	sheet.AddDataValidation("D2", document.DataValidation{
+		Type:         document.ValidationList,
+		Source:       "=Lists!$A$1:$A$5",
+		HideDropDown: true,
+	})
+	sheet.AddDataValidation("F2", document.DataValidation{Type: document.ValidationTextLength, Operator: document.OperatorLessThanOrEqual, Formula1: "20"})
+	sheet.AddDataValidation("G2", document.DataValidation{Type: document.ValidationCustom, Formula1: "ISNUMBER(G2)", HideErrorAlert: true})
+	sheet.AddDataValidation("H2", document.DataValidation{Type: document.ValidationList, Options: []string{`12" pipe`, "Valve"}})
+	if err := sheet.Err(); err != nil {
+		t.Fatalf("AddDataValidation failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	ws := zipParts(t, buf.Bytes())["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<dataValidations count="7">`,
+		`<dataValidation type="list" allowBlank="1" showInputMessage="1" showErrorMessage="1" sqref="A2:A10"><formula1>&#34;Yes,No&#34;</formula1>`,
+		`<dataValidation type="whole" errorStyle="warning" showInputMessage="1" showErrorMessage="1" errorTitle="Out of range" error="The quantity is outside the allowed range." promptTitle="Quantity" prompt="Enter 1 to the maximum in Z1" sqref="B2:B10"><formula1>1</formula1><formula2>$Z$1</formula2>`,
+		`<dataValidation type="date" operator="greaterThanOrEqual" showInputMessage="1" showErrorMessage="1" sqref="C2:C10 E2:E10"><formula1>DATE(2024,1,1)</formula1></dataValidation>`,
+		`<dataValidation type="list" showDropDown="1" showInputMessage="1" showErrorMessage="1" sqref="D2"><formula1>Lists!$A$1:$A$5</formula1>`,
+		`<dataValidation type="textLength" operator="lessThanOrEqual"`,
+		`<dataValidation type="custom" showInputMessage="1" sqref="G2"><formula1>ISNUMBER(G2)</formula1>`,
+		`<formula1>&#34;12&#34;&#34; pipe,Valve&#34;</formula1>`,
+	} {
+		if !strings.Contains(ws, want) {
+			t.Errorf("Expected %s in worksheet", want)
+		}
+	}
+}
+
+func TestAddDataValidation_Invalid(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+
+	tests := []struct {
+		name string
+		ref  string
+		v    document.DataValidation
+	}{
+		{"bad range", "Other!A1", document.DataValidation{Type: document.ValidationCustom, Formula1: "TRUE"}},
+		{"unknown type", "A1", document.DataValidation{Type: "color"}},
+		{"empty list", "A1", document.DataValidation{Type: document.ValidationList}},
+		{"options and source", "A1", document.DataValidation{Type: document.ValidationList, Options: []string{"a"}, Source: "B1:B3"}},
+		{"comma in option", "A1", document.DataValidation{Type: document.ValidationList, Options: []string{"a,b"}}},
+		{"long list", "A1", document.DataValidation{Type: document.ValidationList, Options: []string{strings.Repeat("x", 256)}}},
+		{"between without maximum", "A1", document.DataValidation{Type: document.ValidationDecimal, Formula1: "0"}},
+		{"missing value", "A1", document.DataValidation{Type: document.ValidationTime, Operator: document.OperatorLessThan}},
+		{"unknown operator", "A1", document.DataValidation{Type: document.ValidationWhole, Operator: "near", Formula1: "1"}},
+		{"unknown error style", "A1", document.DataValidation{ErrorStyle: "panic"}},
+		{"long title", "A1", document.DataValidation{PromptTitle: strings.Repeat("t", 33)}},
+	}
+	for _, tt := range tests {
+		sheet, _ := doc.Sheet("Sheet1")
+		if err := sheet.AddDataValidation(tt.ref, tt.v).Err(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	for _, options := range [][]string{nil, {"Yes", "No, never"}} {
+		sheet, _ := doc.Sheet("Sheet1")
+		if err := sheet.SetDataValidation("A1", options...).Err(); err == nil {
+			t.Errorf("SetDataValidation(%q): expected an error", options)
+		}
+	}
+	ws, _ := doc.worksheet("Sheet1")
+	if ws.DataValidations != nil {
+		t.Error("Expected invalid validations not to be added")
+	}
+}
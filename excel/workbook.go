@@ -0,0 +1,699 @@
+package excel
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+const calcChainRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain"
+
+var (
+	worksheetSourcePattern = regexp.MustCompile(`(<worksheetSource\b[^>]*?\ssheet=")([^"]*)(")`)
+	tableStartPattern      = regexp.MustCompile(`<table\b[^>]*>`)
+	tableAttrPattern       = regexp.MustCompile(`(\s(?:id|name|displayName)=")([^"]*)(")`)
+)
+
+func (e *sheetProcessor) renameSheet(oldName, newName string) error {
+	idx := e.getSheetIndex(oldName)
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, oldName)
+	}
+	if err := e.checkSheetName(newName, oldName); err != nil {
+		return err
+	}
+	if newName == oldName {
+		return nil
+	}
+	if err := e.rewriteReferences(retarget(oldName, newName)); err != nil {
+		return err
+	}
+
+	for _, def := range e.pivotCaches {
+		if src := def.CacheSource.WorksheetSource; src != nil && strings.EqualFold(src.Sheet, oldName) {
+			src.Sheet = newName
+		}
+	}
+	for _, name := range textParts(e.state, "xl/pivotCache/pivotCacheDefinition", e.pivotCaches) {
+		err := e.patchPart(name, func(text string) string {
+			return worksheetSourcePattern.ReplaceAllStringFunc(text, func(m string) string {
+				parts := worksheetSourcePattern.FindStringSubmatch(m)
+				if !strings.EqualFold(xmlEntityReplacer.Replace(parts[2]), oldName) {
+					return m
+				}
+				return parts[1] + xmlEscapeReplacer.Replace(newName) + parts[3]
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	e.workbook.Sheets[idx].Name = newName
+	renameKey(e.sheets, oldName, newName)
+	renameKey(e.sheetPaths, oldName, newName)
+	renameKey(e.sheetRels, oldName, newName)
+	renameKey(e.streams, oldName, newName)
+	renameKey(e.comments, oldName, newName)
+	renameKey(e.cellCache, oldName, newName)
+	if sw, ok := e.streams[newName]; ok {
+		sw.name = newName
+	}
+	return nil
+}
+
+func renameKey[V any](m map[string]V, from, to string) {
+	if v, ok := m[from]; ok {
+		delete(m, from)
+		m[to] = v
+	}
+}
+
+func (e *sheetProcessor) deleteSheet(name string) error {
+	idx := e.getSheetIndex(name)
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, name)
+	}
+	if e.workbook.Sheets[idx].State == "" && e.visibleSheets() == 1 {
+		return fmt.Errorf("cannot delete %s, the last visible sheet", name)
+	}
+	err := e.rewriteReferences(func(r formula.Reference) formula.Reference {
+		if strings.EqualFold(r.Sheet, name) {
+			return formula.Reference{Invalid: true}
+		}
+		return r
+	})
+	if err != nil {
+		return err
+	}
+
+	e.dropPart(e.sheetPath(name), e.sheetRels[name])
+	if sc := e.comments[name]; sc != nil {
+		for _, p := range sc.removed {
+			e.removed[p] = true
+		}
+	}
+	if sw, ok := e.streams[name]; ok {
+		sw.close()
+		delete(e.streams, name)
+	}
+	e.workbookRels.RemoveRelationship(e.workbook.Sheets[idx].RID)
+	e.workbook.Sheets = slices.Delete(e.workbook.Sheets, idx, idx+1)
+	delete(e.sheets, name)
+	delete(e.sheetPaths, name)
+	delete(e.sheetRels, name)
+	delete(e.comments, name)
+	delete(e.cellCache, name)
+
+	e.remapLocalNames(func(i int) (int, bool) {
+		switch {
+		case i == idx:
+			return 0, false
+		case i > idx:
+			return i - 1, true
+		}
+		return i, true
+	})
+	e.dropCalcChain()
+
+	views := e.workbookViews()
+	switch active := views.Items[0].ActiveTab; {
+	case active > idx:
+		views.Items[0].ActiveTab--
+	case active == idx:
+		e.selectSheet(e.nearestVisible(min(idx, len(e.workbook.Sheets)-1)))
+	}
+	e.calcDirty = true
+	return nil
+}
+
+func (e *sheetProcessor) copySheet(source, target string) error {
+	srcIdx := e.getSheetIndex(source)
+	if srcIdx == -1 {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, source)
+	}
+	if _, streamed := e.streams[source]; streamed {
+		return fmt.Errorf("sheet %s is written through a stream writer", source)
+	}
+	if err := e.checkSheetName(target, ""); err != nil {
+		return err
+	}
+	src, ok := e.worksheet(source)
+	if !ok {
+		return fmt.Errorf("load sheet %s", source)
+	}
+	data, err := xml.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("copy sheet %s: %w", source, err)
+	}
+	ws := &xmlstructs.Worksheet{}
+	if err := xml.Unmarshal(data, ws); err != nil {
+		return fmt.Errorf("copy sheet %s: %w", source, err)
+	}
+
+	if err := e.addSheet(target); err != nil {
+		return err
+	}
+	setTabSelected(ws, false)
+	ws.Drawing, ws.LegacyDrawing, ws.TableParts = nil, nil, nil
+	e.sheets[target] = ws
+	rels := &xmlstructs.Relationships{}
+	e.sheetRels[target] = rels
+
+	// External hyperlinks keep their targets under new relationship IDs.
+	if hl := ws.Hyperlinks; hl != nil {
+		srcRels := e.sheetRels[source]
+		for i := range hl.Items {
+			h := &hl.Items[i]
+			if h.RID == "" {
+				continue
+			}
+			id := h.RID
+			h.RID = ""
+			for _, rel := range srcRels.Rels {
+				if rel.ID == id {
+					h.RID = rels.AddRelationshipMode(rel.Type, rel.Target, rel.TargetMode)
+				}
+			}
+		}
+	}
+
+	if err := e.copyComments(source, target); err != nil {
+		return err
+	}
+	if src.Drawing != nil {
+		if err := e.copyDrawing(source, target, ws); err != nil {
+			return err
+		}
+	}
+	if err := e.copyTables(source, target, ws); err != nil {
+		return err
+	}
+
+	if e.workbook.DefinedNames != nil {
+		idx := len(e.workbook.Sheets) - 1
+		for _, dn := range e.workbook.DefinedNames.Items {
+			if dn.LocalSheetID != nil && *dn.LocalSheetID == srcIdx {
+				e.workbook.DefinedNames.Items = append(e.workbook.DefinedNames.Items, xmlstructs.DefinedName{
+					Name:         dn.Name,
+					LocalSheetID: &idx,
+					Ref:          rewriteFormula(dn.Ref, retarget(source, target)),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func (e *sheetProcessor) copyComments(source, target string) error {
+	sc, err := e.sheetComments(source, false)
+	if err != nil || sc == nil || len(sc.part.CommentList.Items) == 0 {
+		return err
+	}
+	copied, err := e.sheetComments(target, true)
+	if err != nil {
+		return err
+	}
+	copied.part.Authors.Items = slices.Clone(sc.part.Authors.Items)
+	copied.part.CommentList.Items = slices.Clone(sc.part.CommentList.Items)
+	maps.Copy(copied.shapes, sc.shapes)
+	return nil
+}
+
+// copyDrawing gives the copy of a sheet its own drawing. Images are shared with
+// the source drawing, while charts are copied and pointed at the copied sheet.
+func (e *sheetProcessor) copyDrawing(source, target string, ws *xmlstructs.Worksheet) error {
+	srcPath, srcDr, srcRels, err := e.sheetDrawing(source)
+	if err != nil {
+		return err
+	}
+	data, err := xmlstructs.MarshalPrefixed(srcDr, srcDr.Prefixes()...)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", srcPath, err)
+	}
+	dr := &xmlstructs.WsDr{}
+	if err := xml.Unmarshal(data, dr); err != nil {
+		return fmt.Errorf("copy drawing %s: %w", srcPath, err)
+	}
+	drPath := e.nextPartPath("xl/drawings/drawing%d.xml")
+	e.drawings[drPath] = dr
+
+	// Relationship IDs are kept so that the anchors still refer to their parts.
+	drRels := &xmlstructs.Relationships{}
+	for _, rel := range srcRels.Rels {
+		if rel.Type == chartRelType && rel.TargetMode == "" {
+			chartPath, err := e.copyChart(resolveTarget(srcPath, rel.Target), source, target)
+			if err != nil {
+				return err
+			}
+			rel.Target = "../charts/" + path.Base(chartPath)
+		}
+		drRels.Rels = append(drRels.Rels, rel)
+	}
+	e.sheetRels[relsPath(drPath)] = drRels
+
+	rID := e.sheetRels[target].AddRelationship(drawingRelType, "../drawings/"+path.Base(drPath))
+	ws.Drawing = &xmlstructs.WsDrawing{RID: rID}
+	return nil
+}
+
+// copyChart copies a chart part as text, moving its references from sheet source to target.
+func (e *sheetProcessor) copyChart(name, source, target string) (string, error) {
+	var data []byte
+	if cs, ok := e.charts[name]; ok {
+		out, err := xmlstructs.MarshalPrefixed(cs, cs.Prefixes()...)
+		if err != nil {
+			return "", fmt.Errorf("marshal %s: %w", name, err)
+		}
+		data = append([]byte(xml.Header), out...)
+	} else {
+		var err error
+		if data, err = e.partData(name); err != nil {
+			return "", err
+		}
+	}
+
+	chartPath := e.nextPartPath("xl/charts/chart%d.xml")
+	e.patched[chartPath] = []byte(rewriteChartRefs(string(data), func(f string) string {
+		return rewriteFormula(f, retarget(source, target))
+	}))
+	if rels, err := e.partData(relsPath(name)); err == nil {
+		e.patched[relsPath(chartPath)] = rels
+	}
+	e.contentTypes.AddOverride("/"+chartPath, chartContentType)
+	return chartPath, nil
+}
+
+// copyTables copies the tables of a sheet under new names, as Excel does.
+func (e *sheetProcessor) copyTables(source, target string, ws *xmlstructs.Worksheet) error {
+	tables, err := e.sheetTables(source)
+	if err != nil || len(tables) == 0 {
+		return err
+	}
+	names, id, err := e.tableNames()
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		id++
+		name := uniqueTableName(t.name, names)
+		names[strings.ToUpper(name)] = true
+		tablePath := e.nextPartPath("xl/tables/table%d.xml")
+
+		if t.table != nil {
+			c := *t.table
+			c.ID, c.Name, c.DisplayName = id, name, name
+			c.TableColumns.Items = slices.Clone(c.TableColumns.Items)
+			if c.AutoFilter != nil {
+				af := *c.AutoFilter
+				c.AutoFilter = &af
+			}
+			if c.TableStyleInfo != nil {
+				si := *c.TableStyleInfo
+				c.TableStyleInfo = &si
+			}
+			e.tables[tablePath] = &c
+		} else {
+			data, err := e.partData(t.path)
+			if err != nil {
+				return err
+			}
+			text := string(data)
+			loc := tableStartPattern.FindStringIndex(text)
+			if loc == nil {
+				return fmt.Errorf("copy table %s: no table element", t.path)
+			}
+			tag := tableAttrPattern.ReplaceAllStringFunc(text[loc[0]:loc[1]], func(m string) string {
+				parts := tableAttrPattern.FindStringSubmatch(m)
+				if strings.TrimSpace(parts[1]) == `id="` {
+					return parts[1] + strconv.Itoa(id) + parts[3]
+				}
+				return parts[1] + xmlEscapeReplacer.Replace(name) + parts[3]
+			})
+			e.patched[tablePath] = []byte(text[:loc[0]] + tag + text[loc[1]:])
+			e.contentTypes.AddOverride("/"+tablePath, tableContentType)
+		}
+
+		rID := e.sheetRels[target].AddRelationship(tableRelType, "../tables/"+path.Base(tablePath))
+		if ws.TableParts == nil {
+			ws.TableParts = &xmlstructs.TableParts{}
+		}
+		ws.TableParts.Items = append(ws.TableParts.Items, xmlstructs.TablePart{RID: rID})
+		ws.TableParts.Count = len(ws.TableParts.Items)
+	}
+	return nil
+}
+
+// tableNames returns the upper-cased names of the workbook's tables and the highest table ID.
+func (e *state) tableNames() (map[string]bool, int, error) {
+	names := make(map[string]bool)
+	maxID := 0
+	for _, t := range e.tables {
+		names[strings.ToUpper(t.Name)] = true
+		maxID = max(maxID, t.ID)
+	}
+	for _, name := range textParts(e, "xl/tables/table", e.tables) {
+		data, err := e.partData(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		var t xmlstructs.Table
+		if err := xml.Unmarshal(data, &t); err != nil {
+			return nil, 0, fmt.Errorf("load table %s: %w", name, err)
+		}
+		names[strings.ToUpper(t.Name)] = true
+		maxID = max(maxID, t.ID)
+	}
+	return names, maxID, nil
+}
+
+// uniqueTableName numbers a copied table name, e.g. "Sales" becomes "Sales2".
+func uniqueTableName(name string, used map[string]bool) string {
+	base := strings.TrimRight(name, "0123456789")
+	if base == "" {
+		base = "Table"
+	}
+	for n := 2; ; n++ {
+		candidate := base + strconv.Itoa(n)
+		if !used[strings.ToUpper(candidate)] {
+			return candidate
+		}
+	}
+}
+
+func (e *sheetProcessor) moveSheet(name string, index int) error {
+	idx := e.getSheetIndex(name)
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, name)
+	}
+	if index < 0 || index >= len(e.workbook.Sheets) {
+		return fmt.Errorf("sheet position %d out of range", index)
+	}
+	if index == idx {
+		return nil
+	}
+	sheet := e.workbook.Sheets[idx]
+	e.workbook.Sheets = slices.Insert(slices.Delete(e.workbook.Sheets, idx, idx+1), index, sheet)
+
+	move := func(i int) int {
+		switch {
+		case i == idx:
+			return index
+		case idx < index && i > idx && i <= index:
+			return i - 1
+		case idx > index && i >= index && i < idx:
+			return i + 1
+		}
+		return i
+	}
+	e.remapLocalNames(func(i int) (int, bool) { return move(i), true })
+	views := e.workbookViews()
+	views.Items[0].ActiveTab = move(views.Items[0].ActiveTab)
+	return nil
+}
+
+func (e *sheetProcessor) hideSheet(name string, visibility document.SheetVisibility) error {
+	idx := e.getSheetIndex(name)
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, name)
+	}
+	switch visibility {
+	case document.SheetVisible, document.SheetHidden, document.SheetVeryHidden:
+	default:
+		return fmt.Errorf("unknown sheet visibility %q", visibility)
+	}
+	sheet := &e.workbook.Sheets[idx]
+	if visibility == document.SheetVisible {
+		sheet.State = ""
+		return nil
+	}
+	if sheet.State == "" && e.visibleSheets() == 1 {
+		return fmt.Errorf("cannot hide %s, the last visible sheet", name)
+	}
+	sheet.State = string(visibility)
+	if e.workbookViews().Items[0].ActiveTab == idx {
+		e.selectSheet(e.nearestVisible(idx))
+	}
+	return nil
+}
+
+func (e *sheetProcessor) setActiveSheet(name string) error {
+	idx := e.getSheetIndex(name)
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", document.ErrSheetNotFound, name)
+	}
+	if e.workbook.Sheets[idx].State != "" {
+		return fmt.Errorf("cannot activate hidden sheet %s", name)
+	}
+	e.selectSheet(idx)
+	return nil
+}
+
+// selectSheet makes sheet i the active tab. Only decoded sheets and the previously
+// active sheet are updated, so that the rest of the workbook is not decoded.
+func (e *sheetProcessor) selectSheet(i int) {
+	views := e.workbookViews()
+	prev := views.Items[0].ActiveTab
+	views.Items[0].ActiveTab = i
+	for j, s := range e.workbook.Sheets {
+		ws, ok := e.sheets[s.Name]
+		if !ok && (j == i || j == prev) {
+			ws, ok = e.worksheet(s.Name)
+		}
+		if ok {
+			setTabSelected(ws, j == i)
+		}
+	}
+}
+
+func setTabSelected(ws *xmlstructs.Worksheet, selected bool) {
+	if ws.SheetViews == nil || len(ws.SheetViews.Items) == 0 {
+		if !selected {
+			return
+		}
+		ws.SheetViews = &xmlstructs.SheetViews{Items: []xmlstructs.SheetView{{}}}
+	}
+	ws.SheetViews.Items[0].TabSelected = boolToInt(selected)
+}
+
+func (e *state) workbookViews() *xmlstructs.WorkbookViews {
+	if e.workbook.WorkbookViews == nil || len(e.workbook.WorkbookViews.Items) == 0 {
+		e.workbook.WorkbookViews = &xmlstructs.WorkbookViews{Items: []xmlstructs.WorkbookView{{}}}
+	}
+	return e.workbook.WorkbookViews
+}
+
+func (e *state) visibleSheets() int {
+	n := 0
+	for _, s := range e.workbook.Sheets {
+		if s.State == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// nearestVisible returns the first visible sheet from index i on, or else the last one before it.
+func (e *state) nearestVisible(i int) int {
+	for j := i; j < len(e.workbook.Sheets); j++ {
+		if e.workbook.Sheets[j].State == "" {
+			return j
+		}
+	}
+	for j := i - 1; j >= 0; j-- {
+		if e.workbook.Sheets[j].State == "" {
+			return j
+		}
+	}
+	return 0
+}
+
+// remapLocalNames moves the defined names scoped to a sheet along with it. Names
+// for which fn reports false are removed.
+func (e *state) remapLocalNames(fn func(int) (int, bool)) {
+	if e.workbook.DefinedNames == nil {
+		return
+	}
+	items := e.workbook.DefinedNames.Items[:0]
+	for _, dn := range e.workbook.DefinedNames.Items {
+		if dn.LocalSheetID != nil {
+			i, ok := fn(*dn.LocalSheetID)
+			if !ok {
+				continue
+			}
+			dn.LocalSheetID = &i
+		}
+		items = append(items, dn)
+	}
+	e.workbook.DefinedNames.Items = items
+	if len(items) == 0 {
+		e.workbook.DefinedNames = nil
+	}
+}
+
+// rewriteReferences applies fn to the references of every formula in the workbook:
+// cell formulas, conditional formats, data validations, internal hyperlinks,
+// defined names and chart series. Sheets written through a stream writer are skipped.
+func (e *sheetProcessor) rewriteReferences(fn func(formula.Reference) formula.Reference) error {
+	return e.rewriteSheetReferences(func(string) func(formula.Reference) formula.Reference { return fn })
+}
+
+// rewriteSheetReferences is rewriteReferences with a rewriter per sheet, for
+// rewrites that resolve unqualified references against the sheet of the formula.
+// Defined names and chart series get the rewriter of sheet "".
+func (e *sheetProcessor) rewriteSheetReferences(rewriter func(home string) func(formula.Reference) formula.Reference) error {
+	for _, sh := range e.workbook.Sheets {
+		if _, streamed := e.streams[sh.Name]; streamed {
+			continue
+		}
+		ws, ok := e.worksheet(sh.Name)
+		if !ok {
+			if e.hasSheet(sh.Name) {
+				return fmt.Errorf("load sheet %s", sh.Name)
+			}
+			continue
+		}
+		fn := rewriter(sh.Name)
+		for i := range ws.SheetData.Rows {
+			for j := range ws.SheetData.Rows[i].Cells {
+				if f := ws.SheetData.Rows[i].Cells[j].F; f != nil {
+					f.Text = rewriteFormula(f.Text, fn)
+				}
+			}
+		}
+		for i := range ws.ConditionalFormatting {
+			for j := range ws.ConditionalFormatting[i].CfRule {
+				rule := &ws.ConditionalFormatting[i].CfRule[j]
+				for k, f := range rule.Formula {
+					rule.Formula[k] = rewriteFormula(f, fn)
+				}
+			}
+		}
+		if dvs := ws.DataValidations; dvs != nil {
+			for i := range dvs.Items {
+				dvs.Items[i].Formula1 = rewriteFormula(dvs.Items[i].Formula1, fn)
+				dvs.Items[i].Formula2 = rewriteFormula(dvs.Items[i].Formula2, fn)
+			}
+		}
+		if hl := ws.Hyperlinks; hl != nil {
+			for i := range hl.Items {
+				hl.Items[i].Location = rewriteFormula(hl.Items[i].Location, fn)
+			}
+		}
+		if groups := sparklineGroups(ws, false); groups != nil {
+			for i := range groups.Items {
+				for j := range groups.Items[i].Sparklines.Items {
+					sp := &groups.Items[i].Sparklines.Items[j]
+					sp.F = rewriteFormula(sp.F, fn)
+				}
+			}
+		}
+	}
+	fn := rewriter("")
+	if e.workbook.DefinedNames != nil {
+		for i := range e.workbook.DefinedNames.Items {
+			dn := &e.workbook.DefinedNames.Items[i]
+			dn.Ref = rewriteFormula(dn.Ref, fn)
+		}
+	}
+	for _, cs := range e.charts {
+		for _, f := range cs.Formulas() {
+			*f = rewriteFormula(*f, fn)
+		}
+	}
+	for _, name := range textParts(e.state, "xl/charts/chart", e.charts) {
+		err := e.patchPart(name, func(text string) string {
+			return rewriteChartRefs(text, func(f string) string { return rewriteFormula(f, fn) })
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteFormula applies fn to the references of a formula, leaving text that
+// does not parse unchanged.
+func rewriteFormula(text string, fn func(formula.Reference) formula.Reference) string {
+	if text == "" {
+		return text
+	}
+	out, err := formula.RewriteReferences(text, fn)
+	if err != nil {
+		return text
+	}
+	return out
+}
+
+// retarget returns a reference rewriter that moves references from one sheet to another.
+func retarget(from, to string) func(formula.Reference) formula.Reference {
+	return func(r formula.Reference) formula.Reference {
+		if strings.EqualFold(r.Sheet, from) {
+			r.Sheet = to
+		}
+		return r
+	}
+}
+
+// dropPart removes a part from the package together with the parts it owns, as
+// listed by rels. Images and pivot caches may be shared with other parts and are kept.
+func (e *state) dropPart(name string, rels *xmlstructs.Relationships) {
+	if name == "" || e.removed[name] {
+		return
+	}
+	e.removed[name], e.removed[relsPath(name)] = true, true
+	delete(e.drawings, name)
+	delete(e.tables, name)
+	delete(e.charts, name)
+	delete(e.pivotTables, name)
+	delete(e.patched, name)
+	delete(e.patched, relsPath(name))
+	delete(e.sheetRels, relsPath(name))
+	e.contentTypes.RemoveOverride("/" + name)
+	if rels == nil {
+		return
+	}
+	for _, rel := range rels.Rels {
+		if rel.TargetMode == "External" || rel.Type == imageRelType || rel.Type == pivotCacheRelType {
+			continue
+		}
+		target := resolveTarget(name, rel.Target)
+		e.dropPart(target, e.partRels(target))
+	}
+}
+
+// partRels returns the relationships of a part, or nil when it has none.
+func (e *state) partRels(name string) *xmlstructs.Relationships {
+	if rels, ok := e.sheetRels[relsPath(name)]; ok {
+		return rels
+	}
+	data, err := e.partData(relsPath(name))
+	if err != nil {
+		return nil
+	}
+	var rels xmlstructs.Relationships
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil
+	}
+	return &rels
+}
+
+// dropCalcChain removes the calculation chain, which lists formula cells by sheet.
+// Excel rebuilds it when it is missing.
+func (e *state) dropCalcChain() {
+	for _, rel := range slices.Clone(e.workbookRels.Rels) {
+		if rel.Type == calcChainRelType {
+			e.dropPart(resolveTarget("xl/workbook.xml", rel.Target), nil)
+			e.workbookRels.RemoveRelationship(rel.ID)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package excel
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gsoultan/thoth/document"
+)
+
+func newSalesWorkbook(t *testing.T) *Document {
+	t.Helper()
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+
+	data, _ := doc.Sheet("Data")
+	data.Cell("A1").Set("Item")
+	data.Cell("B1").Set("Qty")
+	for i, v := range []int{3, 4, 5} {
+		row := string(rune('2' + i))
+		data.Cell("A" + row).Set("x")
+		data.Cell("B" + row).Set(v)
+	}
+	data.Cell("A5").Set("note").Comment("check")
+	data.AddTable("A1:B4", "Items")
+	data.SetPrintArea("A1:B4")
+	data.AddChart(document.ChartSpec{Range: "D2:H10", Series: []document.ChartSeries{{Categories: "A2:A4", Values: "B2:B4"}}})
+
+	summary, _ := doc.Sheet("Summary")
+	summary.Cell("A1").Formula("SUM(Data!B2:B4)")
+	summary.Cell("A2").Hyperlink("https://example.com")
+	if err := doc.SetNamedRange("Total", "Data!$B$2:$B$4"); err != nil {
+		t.Fatalf("SetNamedRange failed: %v", err)
+	}
+	if err := firstErr(data, summary); err != nil {
+		t.Fatalf("Building workbook failed: %v", err)
+	}
+	return doc
+}
+
+func firstErr(sheets ...document.Sheet) error {
+	for _, s := range sheets {
+		if err := s.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSheetManagement(t *testing.T) {
+	ctx := t.Context()
+	doc := newSalesWorkbook(t)
+	defer doc.Close()
+
+	if err := doc.RenameSheet("Data", "Q1 Data"); err != nil {
+		t.Fatalf("RenameSheet failed: %v", err)
+	}
+	if err := doc.CopySheet("Q1 Data", "Q2 Data"); err != nil {
+		t.Fatalf("CopySheet failed: %v", err)
+	}
+	if err := doc.MoveSheet("Q2 Data", 0); err != nil {
+		t.Fatalf("MoveSheet failed: %v", err)
+	}
+	if err := doc.SetActiveSheet("Summary"); err != nil {
+		t.Fatalf("SetActiveSheet failed: %v", err)
+	}
+	if err := doc.HideSheet("Q1 Data", document.SheetVeryHidden); err != nil {
+		t.Fatalf("HideSheet failed: %v", err)
+	}
+
+	sheets, _ := doc.GetSheets()
+	if want := []string{"Q2 Data", "Q1 Data", "Summary"}; !slices.Equal(sheets, want) {
+		t.Errorf("Expected sheets %v, got %v", want, sheets)
+	}
+	copied, _ := doc.Sheet("Q2 Data")
+	if got, _ := copied.GetCellValue("B4"); got != "5" {
+		t.Errorf("Expected the copy to keep B4, got %q", got)
+	}
+	if c, _ := copied.Cell("A5").GetComment(); c == nil || c.Text != "check" {
+		t.Errorf("Expected the copy to keep the comment, got %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	for part, wants := range map[string][]string{
+		"xl/workbook.xml": {
+			`<sheet name="Q2 Data" sheetId="3" r:id="rId3"></sheet><sheet name="Q1 Data" sheetId="1" state="veryHidden" r:id="rId1"></sheet><sheet name="Summary" sheetId="2" r:id="rId2"></sheet>`,
+			`<workbookView activeTab="2">`,
+			`<definedName name="_xlnm.Print_Area" localSheetId="1">&#39;Q1 Data&#39;!A1:B4</definedName>`,
+			`<definedName name="Total">&#39;Q1 Data&#39;!$B$2:$B$4</definedName>`,
+			`<definedName name="_xlnm.Print_Area" localSheetId="0">&#39;Q2 Data&#39;!A1:B4</definedName>`,
+		},
+		"xl/worksheets/sheet2.xml": {`<f>SUM(&#39;Q1 Data&#39;!B2:B4)</f>`, `<sheetView tabSelected="1"`},
+		"xl/worksheets/sheet3.xml": {`<tableParts count="1">`, `<legacyDrawing r:id=`, `<drawing r:id=`},
+		"xl/tables/table2.xml":     {`id="2" name="Items2" displayName="Items2" ref="A1:B4"`},
+		"xl/charts/chart1.xml":     {`<c:f>&#39;Q1 Data&#39;!$B$2:$B$4</c:f>`},
+		"xl/charts/chart2.xml":     {`<c:f>&apos;Q2 Data&apos;!$B$2:$B$4</c:f>`},
+		"xl/comments2.xml":         {`<comment ref="A5"`},
+		"[Content_Types].xml":      {`PartName="/xl/charts/chart2.xml"`, `PartName="/xl/worksheets/sheet3.xml"`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(parts[part], want) {
+				t.Errorf("Expected %s in %s", want, part)
+			}
+		}
+	}
+	if strings.Contains(parts["xl/worksheets/sheet1.xml"], `tabSelected="1"`) {
+		t.Error("Expected the previously active sheet to be deselected")
+	}
+}
+
+func TestDeleteSheet(t *testing.T) {
+	ctx := t.Context()
+	src := newSalesWorkbook(t)
+	defer src.Close()
+	var buf bytes.Buffer
+	if err := src.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	doc := NewDocument().(*Document)
+	defer doc.Close()
+	if err := doc.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := doc.CopySheet("Data", "Copy"); err != nil {
+		t.Fatalf("CopySheet failed: %v", err)
+	}
+	if err := doc.DeleteSheet("Data"); err != nil {
+		t.Fatalf("DeleteSheet failed: %v", err)
+	}
+	summary, _ := doc.Sheet("Summary")
+	if got, _ := summary.GetCellValue("A1"); got != "#REF!" {
+		t.Errorf("Expected a reference to the deleted sheet to evaluate to #REF!, got %q", got)
+	}
+
+	buf.Reset()
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	parts := zipParts(t, buf.Bytes())
+	for _, gone := range []string{"xl/worksheets/sheet1.xml", "xl/worksheets/_rels/sheet1.xml.rels", "xl/tables/table1.xml", "xl/charts/chart1.xml", "xl/drawings/drawing1.xml", "xl/comments1.xml"} {
+		if _, ok := parts[gone]; ok {
+			t.Errorf("Expected %s to be removed", gone)
+		}
+		if strings.Contains(parts["[Content_Types].xml"], `"/`+gone+`"`) {
+			t.Errorf("Expected the content type override of %s to be removed", gone)
+		}
+	}
+	for part, wants := range map[string][]string{
+		"xl/workbook.xml": {
+			`<sheet name="Summary" sheetId="2" r:id="rId2"></sheet><sheet name="Copy" sheetId="3"`,
+			`<definedName name="Total">#REF!</definedName>`,
+			`<definedName name="_xlnm.Print_Area" localSheetId="1">Copy!A1:B4</definedName>`,
+		},
+		"xl/worksheets/sheet2.xml": {`<f>SUM(#REF!)</f>`, `<sheetView tabSelected="1"`},
+		"xl/tables/table2.xml":     {`id="2" name="Items2" displayName="Items2"`},
+		"xl/charts/chart2.xml":     {`<c:f>Copy!$B$2:$B$4</c:f>`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(parts[part], want) {
+				t.Errorf("Expected %s in %s", want, part)
+			}
+		}
+	}
+	if strings.Contains(parts["xl/_rels/workbook.xml.rels"], "worksheets/sheet1.xml") {
+		t.Error("Expected the workbook relationship of the deleted sheet to be removed")
+	}
+}
+
+func TestSheetManagement_Invalid(t *testing.T) {
+	doc := NewDocument().(*Document)
+	doc.SetContext(t.Context())
+	defer doc.Close()
+	doc.Sheet("One")
+	doc.Sheet("Two")
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"duplicate name", func() error { return doc.RenameSheet("One", "two") }},
+		{"invalid character", func() error { return doc.RenameSheet("One", "A/B") }},
+		{"long name", func() error { return doc.CopySheet("One", strings.Repeat("x", 32)) }},
+		{"missing sheet", func() error { return doc.DeleteSheet("Three") }},
+		{"position out of range", func() error { return doc.MoveSheet("One", 2) }},
+		{"unknown visibility", func() error { return doc.HideSheet("One", "faded") }},
+		{"last visible sheet", func() error {
+			doc.HideSheet("Two", document.SheetHidden)
+			return doc.HideSheet("One", document.SheetHidden)
+		}},
+		{"delete last visible sheet", func() error { return doc.DeleteSheet("One") }},
+		{"activate hidden sheet", func() error { return doc.SetActiveSheet("Two") }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if err := doc.RenameSheet("One", "ONE"); err != nil {
+		t.Errorf("Expected a change of case to be allowed, got %v", err)
+	}
+}
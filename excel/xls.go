@@ -0,0 +1,234 @@
+package excel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/biff"
+	"github.com/gsoultan/thoth/excel/internal/cfb"
+	"github.com/gsoultan/thoth/excel/internal/formula"
+	"github.com/gsoultan/thoth/excel/internal/xmlstructs"
+)
+
+// xlsColorAuto is the color index of the automatic font color.
+const xlsColorAuto = 0x7FFF
+
+// Names of the BIFF8 border styles, fill patterns and alignments by their codes.
+var (
+	xlsBorderStyles = []string{"", "thin", "medium", "dashed", "dotted", "thick", "double", "hair",
+		"mediumDashed", "dashDot", "mediumDashDot", "dashDotDot", "mediumDashDotDot", "slantDashDot"}
+	xlsPatterns = []string{"none", "solid", "mediumGray", "darkGray", "lightGray", "darkHorizontal",
+		"darkVertical", "darkDown", "darkUp", "darkGrid", "darkTrellis", "lightHorizontal", "lightVertical",
+		"lightDown", "lightUp", "lightGrid", "lightTrellis", "gray125", "gray0625"}
+	xlsHorizontal = []string{"", "left", "center", "right", "fill", "justify", "centerContinuous", "distributed"}
+	xlsVertical   = []string{"top", "center", "", "justify", "distributed"}
+)
+
+// loadXLS replaces the state with the content of a legacy .xls workbook read
+// from a compound file. Cell values, formula results, styles, merged cells,
+// column widths and row heights are converted; formulas keep only their results.
+func (e *state) loadXLS(ctx context.Context, cf *cfb.Reader) error {
+	stream, err := cf.Stream("Workbook")
+	if errors.Is(err, cfb.ErrNotFound) {
+		if _, bookErr := cf.Stream("Book"); bookErr == nil {
+			return fmt.Errorf("%w: Excel 5.0/95 workbook", document.ErrUnsupportedFormat)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", document.ErrInvalidFormat, err)
+	}
+	wb, err := biff.Parse(stream)
+	switch {
+	case errors.Is(err, biff.ErrEncrypted):
+		return document.ErrEncryptedDocument
+	case errors.Is(err, biff.ErrVersion):
+		return fmt.Errorf("%w: %v", document.ErrUnsupportedFormat, err)
+	case err != nil:
+		return fmt.Errorf("%w: %v", document.ErrInvalidFormat, err)
+	}
+	if len(wb.Sheets) == 0 {
+		return fmt.Errorf("%w: workbook has no worksheets", document.ErrInvalidFormat)
+	}
+
+	fresh := newState()
+	fresh.ctx, fresh.exportFunc, fresh.tempFile, fresh.password = e.ctx, e.exportFunc, e.tempFile, e.password
+	fresh.fontMetrics = e.fontMetrics
+	*e = *fresh
+	if wb.Date1904 {
+		e.workbook.WorkbookPr.Date1904 = 1
+	}
+	// The first font is the workbook's default font.
+	if f, ok := wb.Font(0); ok {
+		font := xlsFont(wb, f)
+		e.styles.Fonts.Items[0] = font
+		e.fontsIndex[fontKey(font)] = 0
+	}
+
+	styles := make(map[int]int)
+	sheets := &sheetProcessor{e}
+	for i, sheet := range wb.Sheets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := sheets.addSheet(sheet.Name); err != nil {
+			return err
+		}
+		switch sheet.Visibility {
+		case 1:
+			e.workbook.Sheets[i].State = "hidden"
+		case 2:
+			e.workbook.Sheets[i].State = "veryHidden"
+		}
+		ws := e.sheets[sheet.Name]
+
+		cells := make(map[int][]xmlstructs.Cell)
+		for _, c := range sheet.Cells {
+			cell := xmlstructs.Cell{R: formula.CellName(c.Col+1, c.Row+1), S: e.xlsStyle(wb, c.XF, styles)}
+			switch v := c.Value.(type) {
+			case nil:
+				if cell.S == 0 {
+					continue
+				}
+			case biff.Error:
+				cell.T, cell.V = "e", string(v)
+			default:
+				if err := e.writeCellValue(&cell, v); err != nil {
+					return err
+				}
+			}
+			cells[c.Row] = append(cells[c.Row], cell)
+		}
+		for row := range sheet.Heights {
+			if _, ok := cells[row]; !ok {
+				cells[row] = nil
+			}
+		}
+		for _, row := range slices.Sorted(maps.Keys(cells)) {
+			r := xmlstructs.Row{R: row + 1, Cells: cells[row]}
+			if ht, ok := sheet.Heights[row]; ok {
+				r.Ht, r.CustomHeight = ht, 1
+			}
+			ws.SheetData.Rows = append(ws.SheetData.Rows, r)
+		}
+
+		for _, col := range sheet.Columns {
+			if ws.Cols == nil {
+				ws.Cols = &xmlstructs.Cols{}
+			}
+			ws.Cols.Items = append(ws.Cols.Items, xmlstructs.Col{
+				Min:         col.First + 1,
+				Max:         min(col.Last+1, formula.MaxColumns),
+				Width:       col.Width,
+				CustomWidth: 1,
+			})
+		}
+		for _, m := range sheet.Merged {
+			if ws.MergeCells == nil {
+				ws.MergeCells = &xmlstructs.MergeCells{}
+			}
+			ref := formula.CellName(m.FirstCol+1, m.FirstRow+1) + ":" + formula.CellName(m.LastCol+1, m.LastRow+1)
+			ws.MergeCells.Items = append(ws.MergeCells.Items, xmlstructs.MergeCell{Ref: ref})
+			ws.MergeCells.Count = len(ws.MergeCells.Items)
+		}
+	}
+	return nil
+}
+
+// xlsStyle returns the cellXfs index for the XF at index, registering its font,
+// fill, border and number format. Resolved indexes are kept in cache.
+func (e *state) xlsStyle(wb *biff.Workbook, index int, cache map[int]int) int {
+	if id, ok := cache[index]; ok {
+		return id
+	}
+	if index < 0 || index >= len(wb.XFs) {
+		return 0
+	}
+	x := wb.XFs[index]
+	sp := &styleProcessor{e}
+
+	xf := xmlstructs.Xf{}
+	if f, ok := wb.Font(x.Font); ok {
+		xf.FontID = sp.getFontID(xlsFont(wb, f))
+	}
+	if x.Pattern > 0 && x.Pattern < len(xlsPatterns) {
+		xf.FillID = sp.getFillID(xmlstructs.Fill{PatternFill: &xmlstructs.PatternFill{
+			PatternType: xlsPatterns[x.Pattern],
+			FgColor:     xlsColorRef(wb, x.Foreground),
+			BgColor:     xlsColorRef(wb, x.Background),
+		}})
+	}
+	var border xmlstructs.Border
+	edges := []*xmlstructs.BorderEdge{&border.Left, &border.Right, &border.Top, &border.Bottom}
+	hasBorder := false
+	for i, b := range x.Borders {
+		if b.Style > 0 && b.Style < len(xlsBorderStyles) {
+			*edges[i] = xmlstructs.BorderEdge{Style: xlsBorderStyles[b.Style], Color: xlsColorRef(wb, b.Color)}
+			hasBorder = true
+		}
+	}
+	if hasBorder {
+		xf.BorderID = sp.getBorderID(border)
+	}
+	if _, builtin := builtinNumFmts[x.Format]; builtin {
+		xf.NumFmtID = x.Format
+	} else if code, ok := wb.Formats[x.Format]; ok {
+		xf.NumFmtID = sp.getNumFmtID(code)
+	}
+
+	align := xmlstructs.Alignment{Indent: x.Indent, WrapText: int(boolToInt(x.Wrap))}
+	if x.HAlign < len(xlsHorizontal) {
+		align.Horizontal = xlsHorizontal[x.HAlign]
+	}
+	if x.VAlign < len(xlsVertical) {
+		align.Vertical = xlsVertical[x.VAlign]
+	}
+	if align != (xmlstructs.Alignment{}) {
+		xf.Alignment = &align
+		xf.ApplyAlignment = 1
+	}
+	xf.ApplyNumberFormat = int(boolToInt(xf.NumFmtID > 0))
+	xf.ApplyFont = int(boolToInt(xf.FontID > 0))
+	xf.ApplyFill = int(boolToInt(xf.FillID > 0))
+	xf.ApplyBorder = int(boolToInt(xf.BorderID > 0))
+
+	id := 0
+	if xf != (xmlstructs.Xf{}) {
+		id = sp.getXfID(xf)
+	}
+	cache[index] = id
+	return id
+}
+
+func xlsFont(wb *biff.Workbook, f biff.Font) xmlstructs.Font {
+	font := xmlstructs.Font{
+		Size: &xmlstructs.ValInt{Val: int(math.Round(float64(f.Height) / 20))},
+		Name: &xmlstructs.ValString{Val: f.Name},
+	}
+	if f.Bold {
+		font.Bold = new(struct{}{})
+	}
+	if f.Italic {
+		font.Italic = new(struct{}{})
+	}
+	if f.Color != xlsColorAuto {
+		font.Color = xlsColorRef(wb, f.Color)
+	}
+	return font
+}
+
+// xlsColorRef returns the color at a color index of the workbook palette, or
+// nil for the system colors.
+func xlsColorRef(wb *biff.Workbook, index int) *xmlstructs.Color {
+	switch {
+	case index >= 8 && index-8 < len(wb.Palette):
+		return &xmlstructs.Color{RGB: wb.Palette[index-8]}
+	case index >= 0 && index < 64:
+		return &xmlstructs.Color{RGB: indexedColors[index]}
+	}
+	return nil
+}
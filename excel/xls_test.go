@@ -0,0 +1,305 @@
+package excel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/excel/internal/cfb"
+)
+
+// biffRecord encodes a BIFF record from little-endian fields.
+func biffRecord(id uint16, fields ...any) []byte {
+	var data bytes.Buffer
+	for _, f := range fields {
+		binary.Write(&data, binary.LittleEndian, f)
+	}
+	out := binary.LittleEndian.AppendUint16(nil, id)
+	out = binary.LittleEndian.AppendUint16(out, uint16(data.Len()))
+	return append(out, data.Bytes()...)
+}
+
+// biffString encodes an XLUnicodeString, or a ShortXLUnicodeString when short is set.
+func biffString(s string, short bool) []byte {
+	units := utf16.Encode([]rune(s))
+	var out []byte
+	if short {
+		out = []byte{byte(len(units))}
+	} else {
+		out = binary.LittleEndian.AppendUint16(nil, uint16(len(units)))
+	}
+	out = append(out, 1)
+	for _, u := range units {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return out
+}
+
+// xlsWorkbook builds a BIFF8 workbook stream from the records of the workbook
+// globals, without BOF and EOF, and the records of each sheet.
+func xlsWorkbook(globals [][]byte, sheets map[string][][]byte, order []string) []byte {
+	build := func(offsets []uint32) []byte {
+		var out []byte
+		out = append(out, biffRecord(0x0809, uint16(0x0600), uint16(0x0005), uint32(0), uint32(0), uint32(0))...)
+		for _, rec := range globals {
+			out = append(out, rec...)
+		}
+		for i, name := range order {
+			out = append(out, biffRecord(0x0085, offsets[i], uint8(0), uint8(0), biffString(name, true))...)
+		}
+		return append(out, biffRecord(0x000A)...)
+	}
+	offsets := make([]uint32, len(order))
+	out := build(offsets)
+	var body []byte
+	for i, name := range order {
+		offsets[i] = uint32(len(out) + len(body))
+		body = append(body, biffRecord(0x0809, uint16(0x0600), uint16(0x0010), uint32(0), uint32(0), uint32(0))...)
+		for _, rec := range sheets[name] {
+			body = append(body, rec...)
+		}
+		body = append(body, biffRecord(0x000A)...)
+	}
+	return append(build(offsets), body...)
+}
+
+// compoundFile stores streams in a compound file.
+func compoundFile(t *testing.T, streams map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := cfb.Write(&buf, streams); err != nil {
+		t.Fatalf("Writing compound file failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func xlsTestWorkbook() []byte {
+	// The second string switches from single bytes to UTF-16 in a CONTINUE record.
+	sst := binary.LittleEndian.AppendUint32(nil, 3)
+	sst = binary.LittleEndian.AppendUint32(sst, 3)
+	sst = append(sst, 4, 0, 0)
+	sst = append(sst, "Name"...)
+	sst = append(sst, 11, 0, 0)
+	sst = append(sst, "H\xe9l"...)
+	cont := []byte{1}
+	for _, u := range utf16.Encode([]rune("lo wörld")) {
+		cont = binary.LittleEndian.AppendUint16(cont, u)
+	}
+	cont = append(cont, 4, 0, 0x08, 1, 0)
+	cont = append(cont, "Rich"...)
+	cont = append(cont, 0, 0, 1, 0)
+
+	font := func(height, weight uint16, color uint16, name string) []byte {
+		return biffRecord(0x0031, height, uint16(0), color, weight, uint16(0), uint8(0), uint8(0), uint8(0), uint8(0), biffString(name, true))
+	}
+	xf := func(font, format uint16, align uint8, border1, border2 uint32, colors uint16) []byte {
+		return biffRecord(0x00E0, font, format, uint16(0), align, uint8(0), uint8(0), uint8(0), border1, border2, colors)
+	}
+	globals := [][]byte{
+		font(200, 400, 0x7FFF, "Arial"),
+		font(200, 700, 0x7FFF, "Arial"),
+		font(200, 400, 0x7FFF, "Arial"),
+		font(200, 400, 0x7FFF, "Arial"),
+		font(280, 400, 10, "Verdana"), // Font index 5
+		biffRecord(0x041E, uint16(164), biffString("0.000", false)),
+		xf(0, 0, 0x20, 0, 0, 0x20C0),                                 // 0: default
+		xf(1, 0, 0x22, 0, 0, 0x20C0),                                 // 1: bold, centered
+		xf(0, 14, 0x20, 0, 0, 0x20C0),                                // 2: date
+		xf(5, 164, 0x28, 0x00080101, 0x04000000|0x0C<<7, 0x41<<7|13), // 3: red Verdana, wrapped, borders, yellow fill
+		biffRecord(0x00FC, sst),
+		biffRecord(0x003C, cont),
+	}
+
+	formula := func(row, col uint16, result []byte) []byte {
+		return biffRecord(0x0006, row, col, uint16(0), result, uint16(0), uint32(0), uint16(0))
+	}
+	special := func(kind, value byte) []byte { return []byte{kind, 0, value, 0, 0, 0, 0xFF, 0xFF} }
+	number := make([]byte, 8)
+	binary.LittleEndian.PutUint64(number, 0x4045000000000000) // 42
+	sheets := map[string][][]byte{
+		"Data": {
+			biffRecord(0x007D, uint16(1), uint16(2), uint16(20*256), uint16(0), uint16(0), uint16(0)),
+			biffRecord(0x0208, uint16(2), uint16(0), uint16(4), uint16(600), uint16(0), uint16(0), uint8(0x40), uint8(0), uint16(0x0F)),
+			biffRecord(0x00FD, uint16(0), uint16(0), uint16(1), uint32(0)),
+			biffRecord(0x00FD, uint16(0), uint16(1), uint16(0), uint32(1)),
+			biffRecord(0x00FD, uint16(0), uint16(2), uint16(0), uint32(2)),
+			biffRecord(0x0203, uint16(1), uint16(0), uint16(3), 3.14159),
+			biffRecord(0x027E, uint16(1), uint16(1), uint16(2), uint32(45000<<2|0x02)),
+			biffRecord(0x027E, uint16(1), uint16(2), uint16(0), uint32(1234<<2|0x03)),
+			biffRecord(0x00BD, uint16(2), uint16(0), uint16(0), uint32(7<<2|0x02), uint16(0), uint32(8<<2|0x02), uint16(1)),
+			biffRecord(0x00BE, uint16(2), uint16(2), uint16(3), uint16(3), uint16(3)),
+			biffRecord(0x0205, uint16(3), uint16(0), uint16(0), uint8(1), uint8(0)),
+			biffRecord(0x0205, uint16(3), uint16(1), uint16(0), uint8(0x07), uint8(1)),
+			formula(4, 0, number),
+			formula(4, 1, special(0, 0)),
+			biffRecord(0x0207, biffString("from formula", false)),
+			formula(4, 2, special(1, 1)),
+			formula(4, 3, special(2, 0x2A)),
+			biffRecord(0x0204, uint16(5), uint16(0), uint16(0), biffString("Label ✓", false)),
+			biffRecord(0x00E5, uint16(1), uint16(6), uint16(6), uint16(0), uint16(2)),
+		},
+		"Hidden": {
+			biffRecord(0x0203, uint16(0), uint16(0), uint16(0), 1.0),
+		},
+	}
+	stream := xlsWorkbook(globals, sheets, []string{"Data", "Hidden"})
+	// Hide the second sheet.
+	i := bytes.Index(stream, biffString("Hidden", true))
+	stream[i-2] = 1
+	return stream
+}
+
+func TestOpenXLS(t *testing.T) {
+	ctx := t.Context()
+	data := compoundFile(t, map[string][]byte{"Workbook": xlsTestWorkbook()})
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	if err := doc.Open(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	check := func(doc *Document) {
+		t.Helper()
+		sheet, err := doc.Sheet("Data")
+		if err != nil {
+			t.Fatalf("Sheet failed: %v", err)
+		}
+		values := map[string]string{
+			"A1": "Name", "B1": "Héllo wörld", "C1": "Rich",
+			"A2": "3.14159", "B2": "45000", "C2": "12.34",
+			"A3": "7", "B3": "8", "C3": "", "A4": "1", "B4": "#DIV/0!",
+			"A5": "42", "B5": "from formula", "C5": "1", "D5": "#N/A",
+			"A6": "Label ✓",
+		}
+		for axis, want := range values {
+			got, err := sheet.Cell(axis).Get()
+			if err != nil || fmt.Sprint(got) != want {
+				t.Errorf("%s = %v (%v), want %q", axis, got, err, want)
+			}
+		}
+		if got, _ := sheet.Cell("B2").Formatted(); got != "03-15-23" {
+			t.Errorf("B2 formatted = %q, want 03-15-23", got)
+		}
+		if got, _ := sheet.Cell("A2").Formatted(); got != "3.142" {
+			t.Errorf("A2 formatted = %q, want 3.142", got)
+		}
+
+		style, err := sheet.Cell("A2").GetStyle()
+		if err != nil {
+			t.Fatalf("GetStyle failed: %v", err)
+		}
+		if style.Font != "Verdana" || style.Size != 14 || style.Color != "FF0000" || !style.WrapText ||
+			style.Background != "FFFF00" || !style.BorderLeft || !style.BorderTop || style.BorderRight {
+			t.Errorf("A2 style = %+v", style)
+		}
+		if style, _ := sheet.Cell("A1").GetStyle(); !style.Bold || style.Horizontal != "center" {
+			t.Errorf("A1 style = %+v", style)
+		}
+		if style, _ := sheet.Cell("C3").GetStyle(); style.Font != "Verdana" {
+			t.Errorf("blank C3 style = %+v", style)
+		}
+
+		if merged, _ := sheet.MergedCells(); len(merged) != 1 || merged[0] != "A7:C7" {
+			t.Errorf("MergedCells = %v", merged)
+		}
+		if width, _ := sheet.ColumnWidth(3); width != 20 {
+			t.Errorf("ColumnWidth(3) = %v, want 20", width)
+		}
+		if height, _ := sheet.RowHeight(3); height != 30 {
+			t.Errorf("RowHeight(3) = %v, want 30", height)
+		}
+		if doc.workbook.Sheets[1].Name != "Hidden" || doc.workbook.Sheets[1].State != "hidden" {
+			t.Errorf("second sheet = %+v", doc.workbook.Sheets[1])
+		}
+	}
+	check(doc)
+
+	results, err := doc.Search([]string{"wörld"})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Search = %v, %v", results, err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened := NewDocument().(*Document)
+	reopened.SetContext(ctx)
+	defer reopened.Close()
+	if err := reopened.Open(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Reopening the converted workbook failed: %v", err)
+	}
+	check(reopened)
+}
+
+func TestOpenXLS_LargeStream(t *testing.T) {
+	ctx := t.Context()
+	var records [][]byte
+	for row := range 2000 {
+		records = append(records, biffRecord(0x0203, uint16(row), uint16(0), uint16(0), float64(row)))
+	}
+	stream := xlsWorkbook(nil, map[string][][]byte{"Sheet1": records}, []string{"Sheet1"})
+	data := compoundFile(t, map[string][]byte{"Workbook": stream})
+
+	doc := NewDocument().(*Document)
+	doc.SetContext(ctx)
+	defer doc.Close()
+	if err := doc.Open(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	sheet, _ := doc.Sheet("Sheet1")
+	if got, err := sheet.Cell("A2000").Get(); err != nil || fmt.Sprint(got) != "1999" {
+		t.Errorf("A2000 = %v, %v", got, err)
+	}
+}
+
+func TestOpenXLS_Invalid(t *testing.T) {
+	encrypted := biffRecord(0x002F, uint16(1), make([]byte, 52))
+	biff5 := append(biffRecord(0x0809, uint16(0x0500), uint16(0x0005), uint32(0)), biffRecord(0x000A)...)
+	tests := []struct {
+		name    string
+		streams map[string][]byte
+		want    error
+	}{
+		{"encrypted", map[string][]byte{"Workbook": xlsWorkbook([][]byte{encrypted}, nil, nil)}, document.ErrEncryptedDocument},
+		{"biff5", map[string][]byte{"Workbook": biff5}, document.ErrUnsupportedFormat},
+		{"book stream", map[string][]byte{"Book": biff5}, document.ErrUnsupportedFormat},
+		{"no workbook", map[string][]byte{"Other": []byte("x")}, document.ErrInvalidFormat},
+		{"truncated", map[string][]byte{"Workbook": xlsTestWorkbook()[:300]}, document.ErrInvalidFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := NewDocument().(*Document)
+			defer doc.Close()
+			err := doc.Open(t.Context(), bytes.NewReader(compoundFile(t, tt.streams)))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Open error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	doc := NewDocument().(*Document)
+	defer doc.Close()
+	if err := doc.Open(t.Context(), bytes.NewReader(compoundFile(t, nil)[:600])); err == nil {
+		t.Error("expected an error for a truncated compound file")
+	}
+
+	// A directory entry that links back to itself must not hang the reader.
+	le := binary.LittleEndian
+	cyclic := compoundFile(t, map[string][]byte{"Workbook": xlsTestWorkbook()})
+	dir := (le.Uint32(cyclic[48:]) + 1) * 512
+	child := le.Uint32(cyclic[dir+76:])
+	le.PutUint32(cyclic[dir+child*128+68:], child)
+	cutoff := compoundFile(t, map[string][]byte{"Workbook": xlsTestWorkbook()})
+	le.PutUint32(cutoff[56:], 0xFFFFFFFF)
+	for name, data := range map[string][]byte{"cyclic directory": cyclic, "mini stream cutoff": cutoff} {
+		if err := doc.Open(t.Context(), bytes.NewReader(data)); !errors.Is(err, document.ErrInvalidFormat) {
+			t.Errorf("%s: Open error = %v, want %v", name, err, document.ErrInvalidFormat)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package pdf
+package fontmetrics
 
 import (
 	"encoding/binary"
@@ -6,26 +6,32 @@ import (
 	"os"
 )
 
-type fontMetrics struct {
-	name             string
-	unitsPerEm       uint16
-	ascent           int16
-	descent          int16
-	capHeight        int16
-	italicAngle      float64
-	isFixedPitch     bool
+// Metrics are the metrics of a TrueType font read by ParseTTF.
+type Metrics struct {
+	Name             string // PostScript name
+	UnitsPerEm       uint16
+	Ascent           int16
+	Descent          int16
+	CapHeight        int16
+	ItalicAngle      float64
+	IsFixedPitch     bool
+	Widths           map[rune]uint16 // Advance widths in font units
 	numberOfHMetrics uint16
 	hmtx             []byte
 	cmap             []byte
-	widths           map[rune]uint16
 }
 
-func parseTTF(path string) (*fontMetrics, error) {
+// ParseTTF reads the metrics of a TrueType font file.
+func ParseTTF(path string) (*Metrics, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return Parse(data)
+}
 
+// Parse reads the metrics of a TrueType font.
+func Parse(data []byte) (*Metrics, error) {
 	if len(data) < 12 {
 		return nil, fmt.Errorf("invalid TTF: data too short")
 	}
@@ -47,17 +53,17 @@ func parseTTF(path string) (*fontMetrics, error) {
 		}
 	}
 
-	metrics := &fontMetrics{widths: make(map[rune]uint16)}
+	metrics := &Metrics{Widths: make(map[rune]uint16)}
 
 	// head table
 	if head, ok := tables["head"]; ok && len(head) >= 54 {
-		metrics.unitsPerEm = binary.BigEndian.Uint16(head[18:20])
+		metrics.UnitsPerEm = binary.BigEndian.Uint16(head[18:20])
 	}
 
 	// hhea table
 	if hhea, ok := tables["hhea"]; ok && len(hhea) >= 36 {
-		metrics.ascent = int16(binary.BigEndian.Uint16(hhea[4:6]))
-		metrics.descent = int16(binary.BigEndian.Uint16(hhea[6:8]))
+		metrics.Ascent = int16(binary.BigEndian.Uint16(hhea[4:6]))
+		metrics.Descent = int16(binary.BigEndian.Uint16(hhea[6:8]))
 		metrics.numberOfHMetrics = binary.BigEndian.Uint16(hhea[34:36])
 	}
 
@@ -86,7 +92,7 @@ func parseTTF(path string) (*fontMetrics, error) {
 				length := binary.BigEndian.Uint16(name[off+8 : off+10])
 				offset := binary.BigEndian.Uint16(name[off+10 : off+12])
 				if int(stringOffset+offset+length) <= len(name) {
-					metrics.name = string(name[stringOffset+offset : stringOffset+offset+length])
+					metrics.Name = string(name[stringOffset+offset : stringOffset+offset+length])
 				}
 				break
 			}
@@ -96,7 +102,7 @@ func parseTTF(path string) (*fontMetrics, error) {
 	return metrics, nil
 }
 
-func (m *fontMetrics) parseCmap() {
+func (m *Metrics) parseCmap() {
 	if len(m.cmap) < 4 {
 		return
 	}
@@ -118,7 +124,7 @@ func (m *fontMetrics) parseCmap() {
 	}
 }
 
-func (m *fontMetrics) parseCmapTable(offset int) {
+func (m *Metrics) parseCmapTable(offset int) {
 	if offset+6 > len(m.cmap) {
 		return
 	}
@@ -128,7 +134,7 @@ func (m *fontMetrics) parseCmapTable(offset int) {
 	}
 }
 
-func (m *fontMetrics) parseCmapFormat4(offset int) {
+func (m *Metrics) parseCmapFormat4(offset int) {
 	data := m.cmap[offset:]
 	if len(data) < 14 {
 		return
@@ -160,13 +166,13 @@ func (m *fontMetrics) parseCmapFormat4(offset int) {
 				}
 			}
 			if gid != 0 {
-				m.widths[rune(r)] = m.getGlyphWidth(gid)
+				m.Widths[rune(r)] = m.getGlyphWidth(gid)
 			}
 		}
 	}
 }
 
-func (m *fontMetrics) getGlyphWidth(gid uint16) uint16 {
+func (m *Metrics) getGlyphWidth(gid uint16) uint16 {
 	if int(gid) < int(m.numberOfHMetrics) {
 		off := int(gid) * 4
 		if off+2 <= len(m.hmtx) {
@@ -180,3 +186,21 @@ func (m *fontMetrics) getGlyphWidth(gid uint16) uint16 {
 	}
 	return 0
 }
+
+// Width returns the width of text set at size, in the unit of size. Characters
+// missing from the font count as half an em.
+func (m *Metrics) Width(text string, size float64) float64 {
+	unitsPerEm := int(m.UnitsPerEm)
+	if unitsPerEm == 0 {
+		unitsPerEm = 1000
+	}
+	total := 0
+	for _, r := range text {
+		if w, ok := m.Widths[r]; ok {
+			total += int(w)
+		} else {
+			total += unitsPerEm / 2
+		}
+	}
+	return float64(total) / float64(unitsPerEm) * size
+}
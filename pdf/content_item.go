@@ -17,6 +17,7 @@ type contentItem struct {
 	cols         int
 	HeaderRows   int
 	colWidths    []float64
+	rowHeights   []float64 // Fixed row heights; 0 sizes a row to its content
 	cells        [][][]cellItem
 	isPageBreak  bool
 	isShape      bool
@@ -164,3 +164,59 @@ func TestDocument_Highlight(t *testing.T) {
 		t.Errorf("Expected rectangle fill command (re f), not found in decompressed output: %s", content)
 	}
 }
+
+func TestTable_RowHeights(t *testing.T) {
+	layout := func(heights ...float64) float64 {
+		t.Helper()
+		doc := NewDocument().(*Document)
+		tbl, err := doc.AddTable(2, 1)
+		if err != nil {
+			t.Fatalf("AddTable failed: %v", err)
+		}
+		tbl.Row(0).Cell(0).AddParagraph("first")
+		tbl.Row(1).Cell(0).AddParagraph("second")
+		if err := tbl.(*tableHandle).SetRowHeights(heights...).Err(); err != nil {
+			t.Fatalf("SetRowHeights failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := doc.Save(t.Context(), &buf); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		data := buf.Bytes()
+		start := bytes.Index(data, []byte("stream\n")) + len("stream\n")
+		end := bytes.Index(data[start:], []byte("\nendstream"))
+		r, err := zlib.NewReader(bytes.NewReader(data[start : start+end]))
+		if err != nil {
+			t.Fatalf("Failed to create zlib reader: %v", err)
+		}
+		content, _ := io.ReadAll(r)
+		y := func(text string) float64 {
+			var x, y float64
+			for line := range strings.SplitSeq(string(content), "\n") {
+				if i := strings.Index(line, " Td ("+text+") Tj"); i >= 0 {
+					fields := strings.Fields(line[:i])
+					fmt.Sscan(fields[len(fields)-2], &x)
+					fmt.Sscan(fields[len(fields)-1], &y)
+					return y
+				}
+			}
+			t.Fatalf("%s not found in %s", text, content)
+			return 0
+		}
+		return y("first") - y("second")
+	}
+
+	natural := layout()
+	if got := layout(100); got < 99 || got > 101 {
+		t.Errorf("first row height = %.2f, want 100", got)
+	}
+	if got := layout(0, 100); got != natural {
+		t.Errorf("row with height 0 = %.2f, want the natural %.2f", got, natural)
+	}
+
+	doc := NewDocument().(*Document)
+	tbl, _ := doc.AddTable(1, 1)
+	if err := tbl.(*tableHandle).SetRowHeights(-1).Err(); err == nil {
+		t.Error("expected an error for a negative row height")
+	}
+}
@@ -61,7 +61,7 @@ func (p *pageRenderer) finalizePages(ctx *renderingContext) {
 		sb := getSB()
 
 		// Render header and footer into a new builder
-		p.renderHeader(ctx, sb)
+		p.renderHeader(ctx, sb, pageNum, total)
 		p.renderFooter(ctx, sb, pageNum, total)
 		p.renderWatermark(ctx, sb)
 
@@ -569,8 +569,8 @@ func (p *pageRenderer) renderList(ctx *renderingContext, sb *strings.Builder, it
 	return (&textRenderer{p.state}).renderList(ctx, sb, items, ordered, style, x, y, maxWidth, fontSize)
 }
 
-func (p *pageRenderer) renderHeader(ctx *renderingContext, sb *strings.Builder) {
-	(&textRenderer{p.state}).renderHeader(ctx, sb)
+func (p *pageRenderer) renderHeader(ctx *renderingContext, sb *strings.Builder, pageNum, totalPages int) {
+	(&textRenderer{p.state}).renderHeader(ctx, sb, pageNum, totalPages)
 }
 
 func (p *pageRenderer) renderFooter(ctx *renderingContext, sb *strings.Builder, pageNum, totalPages int) {
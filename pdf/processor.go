@@ -306,6 +306,19 @@ func (p *processor) setTableColumnWidths(tbl *contentItem, widths ...float64) er
 	return nil
 }
 
+func (p *processor) setTableRowHeights(tbl *contentItem, heights ...float64) error {
+	if tbl == nil {
+		return fmt.Errorf("table is nil")
+	}
+	for _, h := range heights {
+		if h < 0 {
+			return fmt.Errorf("row height must not be negative")
+		}
+	}
+	tbl.rowHeights = heights
+	return nil
+}
+
 func (p *processor) setTableHeaderRows(tbl *contentItem, count int) error {
 	if tbl == nil {
 		return fmt.Errorf("table is nil")
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/gsoultan/thoth/document"
+	"github.com/gsoultan/thoth/internal/fontmetrics"
 	"github.com/gsoultan/thoth/pdf/internal/objects"
 )
 
@@ -21,6 +22,8 @@ func (p *renderer) newRenderingContext() *renderingContext {
 		w, h = 595, 842
 	case document.PaperLetter:
 		w, h = 612, 792
+	case document.PaperLegal:
+		w, h = 612, 1008
 	}
 	if p.pageSettings.Orientation == document.OrientationLandscape {
 		w, h = h, w
@@ -129,7 +132,7 @@ func (p *renderer) ensureFontInContext(ctx *renderingContext, name, path string)
 	if _, ok := ctx.fontRefs[name]; ok {
 		return
 	}
-	metrics, err := parseTTF(path)
+	metrics, err := fontmetrics.ParseTTF(path)
 	if err != nil {
 		fmt.Printf("Warning: failed to parse font %s: %v\n", name, err)
 		return
@@ -148,15 +151,15 @@ func (p *renderer) ensureFontInContext(ctx *renderingContext, name, path string)
 	fontFileRef := ctx.mgr.AddObject(fontFileStream)
 
 	// 2. FontDescriptor
-	ascent := float64(metrics.ascent) * 1000.0 / float64(metrics.unitsPerEm)
-	descent := float64(metrics.descent) * 1000.0 / float64(metrics.unitsPerEm)
+	ascent := float64(metrics.Ascent) * 1000.0 / float64(metrics.UnitsPerEm)
+	descent := float64(metrics.Descent) * 1000.0 / float64(metrics.UnitsPerEm)
 
 	descriptor := objects.Dictionary{
 		"Type":        objects.Name("FontDescriptor"),
-		"FontName":    objects.Name(metrics.name),
+		"FontName":    objects.Name(metrics.Name),
 		"Flags":       objects.Integer(32), // Non-symbolic
 		"FontBBox":    objects.Array{objects.Integer(-1000), objects.Integer(int(descent)), objects.Integer(3000), objects.Integer(int(ascent))},
-		"ItalicAngle": objects.Float(metrics.italicAngle),
+		"ItalicAngle": objects.Float(metrics.ItalicAngle),
 		"Ascent":      objects.Float(ascent),
 		"Descent":     objects.Float(descent),
 		"CapHeight":   objects.Float(ascent), // Simplified
@@ -169,7 +172,7 @@ func (p *renderer) ensureFontInContext(ctx *renderingContext, name, path string)
 	font := objects.Dictionary{
 		"Type":           objects.Name("Font"),
 		"Subtype":        objects.Name("TrueType"),
-		"BaseFont":       objects.Name(metrics.name),
+		"BaseFont":       objects.Name(metrics.Name),
 		"FontDescriptor": descriptorRef,
 		"FirstChar":      objects.Integer(32),
 		"LastChar":       objects.Integer(255),
@@ -186,8 +189,8 @@ func (p *renderer) ensureFontInContext(ctx *renderingContext, name, path string)
 	fontRef := ctx.mgr.AddObject(font)
 	ctx.fontRefs[name] = fontRef
 	ctx.fontNames[name] = fmt.Sprintf("CF%d", len(ctx.fontNames)+1)
-	ctx.customWidths[name] = metrics.widths
-	ctx.unitsPerEm[name] = metrics.unitsPerEm
+	ctx.customWidths[name] = metrics.Widths
+	ctx.unitsPerEm[name] = metrics.UnitsPerEm
 }
 
 func (p *renderer) ensureImportInContext(ctx *renderingContext, path string, pageNum int) {
@@ -39,6 +39,16 @@ func (t *tableHandle) SetColumnWidths(widths ...float64) document.Table {
 	return t
 }
 
+// SetRowHeights fixes the height of each row in points; 0 leaves a row sized
+// to its content. It is specific to PDF tables and not part of document.Table.
+func (t *tableHandle) SetRowHeights(heights ...float64) document.Table {
+	if t.err != nil {
+		return t
+	}
+	t.err = (&processor{t.state}).setTableRowHeights(t.tbl, heights...)
+	return t
+}
+
 func (t *tableHandle) SetHeaderRows(count int) document.Table {
 	if t.err != nil {
 		return t
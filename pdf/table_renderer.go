@@ -309,6 +309,10 @@ func (p *tableRenderer) renderTable(ctx *renderingContext, item *contentItem, x,
 
 	rowHeights := make([]float64, item.rows)
 	for r := range item.rows {
+		if r < len(item.rowHeights) && item.rowHeights[r] > 0 {
+			rowHeights[r] = item.rowHeights[r]
+			continue
+		}
 		rowHeights[r] = p.calculateRowHeight(ctx, item, r, colWidths)
 	}
 
@@ -333,10 +337,10 @@ func (p *tableRenderer) renderTable(ctx *renderingContext, item *contentItem, x,
 					currentTotalH += rowHeights[r+i]
 				}
 
-				if cellH > currentTotalH {
-					extra := cellH - currentTotalH
-					lastRow := min(r+rs-1, item.rows-1)
-					rowHeights[lastRow] += extra
+				lastRow := min(r+rs-1, item.rows-1)
+				fixed := lastRow < len(item.rowHeights) && item.rowHeights[lastRow] > 0
+				if cellH > currentTotalH && !fixed {
+					rowHeights[lastRow] += cellH - currentTotalH
 				}
 			}
 		}
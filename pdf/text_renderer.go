@@ -420,12 +420,13 @@ func concatSpanText(spans []document.TextSpan) string {
 	return b.String()
 }
 
-func (p *textRenderer) renderHeader(ctx *renderingContext, sb *strings.Builder) {
+func (p *textRenderer) renderHeader(ctx *renderingContext, sb *strings.Builder, pageNum, totalPages int) {
 	m := p.getMargins()
 	currY := float64(ctx.h) - (m.Top / 2.0)
 	for _, item := range p.header {
 		if item.isParagraph {
-			currY -= p.renderParagraph(ctx, sb, item.text, item.style, m.Left, currY, float64(ctx.w)-(m.Left+m.Right), 10)
+			text := pageNumbers(item.text, pageNum, totalPages)
+			currY -= p.renderParagraph(ctx, sb, text, item.style, m.Left, currY, float64(ctx.w)-(m.Left+m.Right), 10)
 		}
 	}
 }
@@ -435,14 +436,20 @@ func (p *textRenderer) renderFooter(ctx *renderingContext, sb *strings.Builder,
 	currY := m.Bottom / 2.0
 	for _, item := range p.footer {
 		if item.isParagraph {
-			text := strings.ReplaceAll(item.text, "{n}", fmt.Sprintf("%d", pageNum))
-			text = strings.ReplaceAll(text, "{nb}", fmt.Sprintf("%d", totalPages))
+			text := pageNumbers(item.text, pageNum, totalPages)
 			p.renderParagraph(ctx, sb, text, item.style, m.Left, currY, float64(ctx.w)-(m.Left+m.Right), 10)
 			currY -= 12
 		}
 	}
 }
 
+// pageNumbers replaces {n} in header and footer text with the page number and
+// {nb} with the page count.
+func pageNumbers(text string, pageNum, totalPages int) string {
+	text = strings.ReplaceAll(text, "{n}", strconv.Itoa(pageNum))
+	return strings.ReplaceAll(text, "{nb}", strconv.Itoa(totalPages))
+}
+
 func (p *textRenderer) renderWatermark(ctx *renderingContext, sb *strings.Builder) {
 	if p.watermark == nil {
 		return